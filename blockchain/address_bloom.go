@@ -0,0 +1,152 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// Address bloom parameters: every address sets AddressBloomHashes of the AddressBloomBits bits,
+// each picked by two bytes of the address's SHA-256
+const (
+	AddressBloomBits   = 2048
+	AddressBloomHashes = 3
+)
+
+// AddressBloom is a bloom filter over the addresses of a block's transactions: their senders,
+// recipients and fee sponsors. A wallet tests its addresses against the filters of block headers
+// and only downloads the blocks that match. Matches can be false positives, more often in full
+// blocks; misses are certain.
+type AddressBloom [AddressBloomBits / 8]byte
+
+// ParseAddressBloom decodes a bloom from its hex string
+func ParseAddressBloom(s string) (AddressBloom, error) {
+	var bloom AddressBloom
+	if len(s) != 2*len(bloom) {
+		return bloom, fmt.Errorf("address bloom has %d hex digits, expected %d", len(s), 2*len(bloom))
+	}
+	if _, err := hex.Decode(bloom[:], []byte(s)); err != nil {
+		return bloom, fmt.Errorf("invalid address bloom: %w", err)
+	}
+	return bloom, nil
+}
+
+// String returns the bloom as a hex string
+func (f *AddressBloom) String() string {
+	return hex.EncodeToString(f[:])
+}
+
+// Add sets the bits of an address
+func (f *AddressBloom) Add(address string) {
+	for _, bit := range addressBloomBits(address) {
+		f[bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// MightContain reports whether the bits of an address are all set
+func (f *AddressBloom) MightContain(address string) bool {
+	for _, bit := range addressBloomBits(address) {
+		if f[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// addressBloomBits returns the bits an address sets
+func addressBloomBits(address string) [AddressBloomHashes]uint16 {
+	hash := sha256.Sum256([]byte(address))
+	var bits [AddressBloomHashes]uint16
+	for i := range bits {
+		bits[i] = binary.BigEndian.Uint16(hash[2*i:]) % AddressBloomBits
+	}
+	return bits
+}
+
+// calculateAddressBloom returns the bloom over the addresses of transactions ("" if none)
+func calculateAddressBloom(transactions []Transaction) string {
+	if len(transactions) == 0 {
+		return ""
+	}
+	var bloom AddressBloom
+	for i := range transactions {
+		tx := &transactions[i]
+		bloom.Add(tx.From)
+		bloom.Add(tx.To)
+		if tx.Sponsor != "" {
+			bloom.Add(tx.Sponsor)
+		}
+	}
+	return bloom.String()
+}
+
+// validateBloom checks the address bloom a block carries matches its transactions. The bloom
+// isn't covered by the block hash, so it is checked like the body; blocks stored before blooms
+// were introduced carry none.
+func (b *Block) validateBloom() error {
+	if b.Bloom != "" && b.Bloom != calculateAddressBloom(b.Transactions) {
+		return errors.New("invalid address bloom")
+	}
+	return nil
+}
+
+// MightContainAddress reports whether the block may have a transaction from, to or sponsored
+// by an address. A block without a bloom is tested against its transactions, and a pruned one
+// without a bloom always matches.
+func (b *Block) MightContainAddress(address string) bool {
+	if b.Bloom == "" && !b.Pruned {
+		for i := range b.Transactions {
+			tx := &b.Transactions[i]
+			if tx.From == address || tx.To == address || tx.Sponsor == address {
+				return true
+			}
+		}
+		return false
+	}
+	header := b.Header()
+	return header.MightContainAddress(address)
+}
+
+// MightContainAddress reports whether the header's block may have a transaction from, to or
+// sponsored by an address, from the bloom it carries. A header without a bloom matches unless
+// its block has no transactions. The bloom isn't covered by the hash, so a node serving the
+// header could hide a block from a wallet this way, but not forge one.
+func (h *BlockHeader) MightContainAddress(address string) bool {
+	if h.Bloom == "" {
+		return h.MerkleRoot != ""
+	}
+	bloom, err := ParseAddressBloom(h.Bloom)
+	return err != nil || bloom.MightContain(address)
+}
+
+// relevantBlocks returns the blocks between two heights, inclusive, that might hold a
+// transaction of an address
+func relevantBlocks(chain []*Block, address string, from, to int64) ([]*Block, error) {
+	if from < 0 || from > to {
+		return nil, errors.New("invalid height range")
+	}
+	to = min(to, int64(len(chain))-1)
+	var blocks []*Block
+	for height := from; height <= to; height++ {
+		if chain[height].MightContainAddress(address) {
+			blocks = append(blocks, chain[height])
+		}
+	}
+	return blocks, nil
+}
+
+// GetBlocksRelevantToAddress returns the blocks between two heights, inclusive, whose address
+// bloom matches an address: every block with a transaction of the address and a few false
+// positives
+func (bc *Blockchain) GetBlocksRelevantToAddress(address string, from, to int64) ([]*Block, error) {
+	return relevantBlocks(bc.Chain, address, from, to)
+}
+
+// GetBlocksRelevantToAddress returns the blocks between two heights, inclusive, whose address
+// bloom matches an address: every block with a transaction of the address and a few false
+// positives. Pruned blocks keep their bloom and are returned without their body.
+func (pbc *PersistentBlockchain) GetBlocksRelevantToAddress(address string, from, to int64) ([]*Block, error) {
+	return relevantBlocks(pbc.Chain, address, from, to)
+}
@@ -0,0 +1,337 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// ClusterHeuristic is a common-ownership heuristic linking addresses into clusters
+type ClusterHeuristic string
+
+const (
+	// HeuristicCoSpend links the senders funding one payment. Payments are single-input, so a
+	// multi-address payment is one transaction per funding address (see HDWallet.BuildSpend):
+	// several senders paying the same recipient in one block, emptying their balances except
+	// for the last one.
+	HeuristicCoSpend ClusterHeuristic = "co-spend"
+
+	// HeuristicChange links a sender to the change address it moves its whole balance to.
+	// Balances are per account, so change shows up as an address emptied into an address
+	// that had never appeared on the chain.
+	HeuristicChange ClusterHeuristic = "change"
+)
+
+// clusterDust is the balance below which an address counts as emptied, absorbing floating point residue
+const clusterDust = 1e-9
+
+// ClusterConfig configures address clustering
+type ClusterConfig struct {
+	Heuristics []ClusterHeuristic // Heuristics applied (empty applies all)
+}
+
+// ClusterSource is the chain surface addresses are clustered from.
+// Both *Blockchain and *PersistentBlockchain implement it.
+type ClusterSource interface {
+	GetLatestBlock() *Block
+	GetBlockByIndex(index int64) (*Block, error)
+}
+
+// ClusterLink is the evidence that two addresses have a common owner
+type ClusterLink struct {
+	From       string           `json:"from"`
+	To         string           `json:"to"`
+	Heuristic  ClusterHeuristic `json:"heuristic"`
+	TxHash     string           `json:"txHash"` // Transaction of To the link was inferred from
+	BlockIndex int64            `json:"blockIndex"`
+}
+
+// AddressCluster is a set of addresses presumed to have a common owner
+type AddressCluster struct {
+	ID        string        `json:"id"` // The cluster's first address to appear on the chain
+	Addresses []string      `json:"addresses"`
+	Balance   float64       `json:"balance"`
+	Links     []ClusterLink `json:"links"`
+}
+
+// AddressClusters groups the chain's addresses by presumed common ownership, for analytics and
+// forensics on permissioned deployments. Clusters are inferred, not proven: heuristics link
+// addresses that merely look related. The index follows the chain incrementally, starting
+// over after a reorg; it needs every block body, so it can't run on a pruned chain.
+type AddressClusters struct {
+	source     ClusterSource
+	lock       sync.Locker // Serializes chain access with other users such as the miner
+	heuristics map[ClusterHeuristic]bool
+
+	mu       sync.Mutex
+	height   int64 // Height of the last indexed block, -1 if none
+	tipHash  string
+	parent   map[string]string // Union-find forest over every address seen
+	order    map[string]int    // Order in which addresses first appeared
+	balances map[string]float64
+	links    []ClusterLink
+}
+
+// NewAddressClusters creates an address clustering index over a chain.
+// The lock must be the one guarding the chain (nil uses an internal mutex).
+func NewAddressClusters(source ClusterSource, lock sync.Locker, config ClusterConfig) (*AddressClusters, error) {
+	if lock == nil {
+		lock = &sync.Mutex{}
+	}
+	heuristics := make(map[ClusterHeuristic]bool)
+	if len(config.Heuristics) == 0 {
+		config.Heuristics = []ClusterHeuristic{HeuristicCoSpend, HeuristicChange}
+	}
+	for _, heuristic := range config.Heuristics {
+		switch heuristic {
+		case HeuristicCoSpend, HeuristicChange:
+			heuristics[heuristic] = true
+		default:
+			return nil, fmt.Errorf("unknown clustering heuristic %q", heuristic)
+		}
+	}
+
+	ac := &AddressClusters{source: source, lock: lock, heuristics: heuristics}
+	ac.reset()
+	return ac, nil
+}
+
+// reset discards the index
+func (ac *AddressClusters) reset() {
+	ac.height = -1
+	ac.tipHash = ""
+	ac.parent = make(map[string]string)
+	ac.order = make(map[string]int)
+	ac.balances = make(map[string]float64)
+	ac.links = nil
+}
+
+// Update indexes the blocks added since the last update, starting over if the chain reorganized
+func (ac *AddressClusters) Update() error {
+	ac.lock.Lock()
+	defer ac.lock.Unlock()
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	tip := ac.source.GetLatestBlock()
+	if ac.height >= 0 {
+		indexed, err := ac.source.GetBlockByIndex(ac.height)
+		if ac.height > tip.Index || err != nil || indexed.Hash != ac.tipHash {
+			ac.reset()
+		}
+	}
+
+	for height := ac.height + 1; height <= tip.Index; height++ {
+		block, err := ac.source.GetBlockByIndex(height)
+		if err != nil {
+			if errors.Is(err, ErrPruned) {
+				return fmt.Errorf("address clustering needs every block body: %w", err)
+			}
+			return fmt.Errorf("failed to load block %d: %w", height, err)
+		}
+		ac.addBlock(block)
+		ac.height = block.Index
+		ac.tipHash = block.Hash
+	}
+	return nil
+}
+
+// addBlock applies a block's transactions to the balances and links the addresses the
+// heuristics relate, judging senders by their balances after the block (caller must hold mu)
+func (ac *AddressClusters) addBlock(block *Block) {
+	var recipients []string                     // Recipients of the block's payments, in block order
+	payments := make(map[string][]*Transaction) // Payments by recipient
+	fresh := make(map[string]bool)              // Recipients first seen in the block
+	for i := range block.Transactions {
+		tx := &block.Transactions[i]
+		// The coinbase sender and the system recipients are not owned by anyone
+		switch TransactionSource(tx) {
+		case SourceCoinbase:
+			ac.see(tx.To)
+			ac.balances[tx.To] += tx.Amount
+			continue
+		case SourceSystem:
+			ac.see(tx.From)
+			ac.pay(tx)
+			continue
+		}
+
+		ac.see(tx.From)
+		if ac.see(tx.To) {
+			fresh[tx.To] = true
+		}
+		ac.pay(tx)
+		ac.balances[tx.To] += tx.Amount
+		if tx.From == tx.To {
+			continue
+		}
+		if payments[tx.To] == nil {
+			recipients = append(recipients, tx.To)
+		}
+		payments[tx.To] = append(payments[tx.To], tx)
+	}
+
+	for _, to := range recipients {
+		// Only the last funding address of a payment keeps a balance; when several senders
+		// do, none of them can be told apart from unrelated payers
+		var senders, kept []*Transaction
+		for _, tx := range payments[to] {
+			from := func(other *Transaction) bool { return other.From == tx.From }
+			if slices.ContainsFunc(senders, from) || slices.ContainsFunc(kept, from) {
+				continue
+			}
+			if ac.balances[tx.From] > clusterDust {
+				kept = append(kept, tx)
+			} else {
+				senders = append(senders, tx)
+			}
+		}
+
+		switch {
+		case len(senders)+len(kept) == 1:
+			// A lone sender emptied into a new address is moving its change
+			if ac.heuristics[HeuristicChange] && len(senders) == 1 && fresh[to] {
+				tx := senders[0]
+				ac.link(ClusterLink{From: tx.From, To: to, Heuristic: HeuristicChange, TxHash: tx.Hash, BlockIndex: block.Index})
+			}
+		case ac.heuristics[HeuristicCoSpend]:
+			if len(kept) == 1 {
+				senders = append(senders, kept[0])
+			}
+			for _, tx := range senders[min(1, len(senders)):] {
+				ac.link(ClusterLink{From: senders[0].From, To: tx.From, Heuristic: HeuristicCoSpend, TxHash: tx.Hash, BlockIndex: block.Index})
+			}
+		}
+	}
+}
+
+// pay debits a transaction from its sender and sponsor (caller must hold mu)
+func (ac *AddressClusters) pay(tx *Transaction) {
+	ac.balances[tx.From] -= tx.debit(tx.From)
+	if tx.Sponsor != "" {
+		ac.see(tx.Sponsor)
+		ac.balances[tx.Sponsor] -= tx.Fee
+	}
+}
+
+// see registers an address, reporting whether it is new (caller must hold mu)
+func (ac *AddressClusters) see(address string) bool {
+	if _, seen := ac.parent[address]; seen {
+		return false
+	}
+	ac.parent[address] = address
+	ac.order[address] = len(ac.order)
+	return true
+}
+
+// find returns the root of an address's cluster, compressing the path (caller must hold mu)
+func (ac *AddressClusters) find(address string) string {
+	root := address
+	for ac.parent[root] != root {
+		root = ac.parent[root]
+	}
+	for address != root {
+		next := ac.parent[address]
+		ac.parent[address] = root
+		address = next
+	}
+	return root
+}
+
+// link merges the clusters of two addresses, keeping the earlier address as the root (caller must hold mu)
+func (ac *AddressClusters) link(link ClusterLink) {
+	a, b := ac.find(link.From), ac.find(link.To)
+	ac.links = append(ac.links, link)
+	if a == b {
+		return
+	}
+	if ac.order[b] < ac.order[a] {
+		a, b = b, a
+	}
+	ac.parent[b] = a
+}
+
+// Cluster returns the cluster of an address, indexing new blocks first
+func (ac *AddressClusters) Cluster(address string) (*AddressCluster, error) {
+	if err := ac.Update(); err != nil {
+		return nil, err
+	}
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	if _, seen := ac.parent[address]; !seen {
+		return nil, errors.New("address not found on the chain")
+	}
+	return ac.clusters(ac.find(address))[0], nil
+}
+
+// Linked reports whether two addresses are in the same cluster
+func (ac *AddressClusters) Linked(a, b string) (bool, error) {
+	if err := ac.Update(); err != nil {
+		return false, err
+	}
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	_, seenA := ac.parent[a]
+	_, seenB := ac.parent[b]
+	return seenA && seenB && ac.find(a) == ac.find(b), nil
+}
+
+// Clusters returns the clusters of at least minSize addresses, largest first
+func (ac *AddressClusters) Clusters(minSize int) ([]*AddressCluster, error) {
+	if err := ac.Update(); err != nil {
+		return nil, err
+	}
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	var clusters []*AddressCluster
+	for _, cluster := range ac.clusters("") {
+		if len(cluster.Addresses) >= minSize {
+			clusters = append(clusters, cluster)
+		}
+	}
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return len(clusters[i].Addresses) > len(clusters[j].Addresses)
+	})
+	return clusters, nil
+}
+
+// clusters assembles the cluster with the given root, or every cluster for "", in order of
+// first appearance (caller must hold mu)
+func (ac *AddressClusters) clusters(root string) []*AddressCluster {
+	byRoot := make(map[string]*AddressCluster)
+	for address := range ac.parent {
+		r := ac.find(address)
+		if root != "" && r != root {
+			continue
+		}
+		cluster := byRoot[r]
+		if cluster == nil {
+			cluster = &AddressCluster{ID: r}
+			byRoot[r] = cluster
+		}
+		cluster.Addresses = append(cluster.Addresses, address)
+		cluster.Balance += ac.balances[address]
+	}
+	for _, link := range ac.links {
+		if cluster := byRoot[ac.find(link.From)]; cluster != nil {
+			cluster.Links = append(cluster.Links, link)
+		}
+	}
+
+	clusters := make([]*AddressCluster, 0, len(byRoot))
+	for _, cluster := range byRoot {
+		sort.Slice(cluster.Addresses, func(i, j int) bool {
+			return ac.order[cluster.Addresses[i]] < ac.order[cluster.Addresses[j]]
+		})
+		clusters = append(clusters, cluster)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return ac.order[clusters[i].ID] < ac.order[clusters[j].ID]
+	})
+	return clusters
+}
@@ -0,0 +1,325 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// ErrInvalidAddress is returned for addresses that don't parse in their format
+var ErrInvalidAddress = errors.New("invalid address")
+
+// AddressFormat derives addresses from public keys and parses them back. A network picks one
+// format for all its addresses in its ChainParams.
+type AddressFormat interface {
+	Name() string
+	// Address derives the address of a raw public key, as returned by Signer.PublicKey
+	Address(scheme SignatureScheme, publicKey []byte) (string, error)
+	// Scheme checks an address is well formed and returns the signature scheme of its key
+	Scheme(address string) (SignatureScheme, error)
+}
+
+// addressFormats are the built-in formats by name. Their version byte and prefix keep
+// addresses from passing for Bitcoin ones; networks sharing a format should set their own.
+var addressFormats = map[string]AddressFormat{
+	"sha256-hex":  HexAddressFormat{},
+	"base58check": Base58CheckAddressFormat{Version: 0x19},
+	"bech32":      Bech32AddressFormat{HRP: "blk"},
+	"keccak":      KeccakAddressFormat{},
+}
+
+// AddressFormatByName returns a built-in address format
+func AddressFormatByName(name string) (AddressFormat, error) {
+	format, exists := addressFormats[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown address format %q", name)
+	}
+	return format, nil
+}
+
+// HexAddressFormat is the original format: the hex SHA-256 of the public key,
+// untagged for P-256 and prefixed with the scheme tag otherwise
+type HexAddressFormat struct{}
+
+// Name returns "sha256-hex"
+func (HexAddressFormat) Name() string {
+	return "sha256-hex"
+}
+
+// Address derives the hex address of a public key
+func (HexAddressFormat) Address(scheme SignatureScheme, publicKey []byte) (string, error) {
+	if scheme != SchemeP256 {
+		if _, exists := verifiers[scheme]; !exists {
+			return "", fmt.Errorf("unsupported signature scheme %s", scheme)
+		}
+		return schemeAddress(scheme, publicKey), nil
+	}
+	x, y := elliptic.UnmarshalCompressed(elliptic.P256(), publicKey)
+	if x == nil {
+		return "", errors.New("invalid P-256 public key")
+	}
+	return generateAddress(&ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}), nil
+}
+
+// Scheme parses a hex address
+func (HexAddressFormat) Scheme(address string) (SignatureScheme, error) {
+	if _, err := hex.DecodeString(address); err != nil {
+		return 0, fmt.Errorf("%w: malformed", ErrInvalidAddress)
+	}
+	return AddressScheme(address)
+}
+
+// Base58CheckAddressFormat encodes the version byte, the scheme tag and the SHA-256 of the
+// public key in base58 with a double SHA-256 checksum, so typos are caught before sending
+type Base58CheckAddressFormat struct {
+	Version byte // Distinguishes networks sharing the format
+}
+
+// Name returns "base58check"
+func (Base58CheckAddressFormat) Name() string {
+	return "base58check"
+}
+
+// Address derives the base58check address of a public key
+func (f Base58CheckAddressFormat) Address(scheme SignatureScheme, publicKey []byte) (string, error) {
+	if _, exists := verifiers[scheme]; !exists {
+		return "", fmt.Errorf("unsupported signature scheme %s", scheme)
+	}
+	hash := sha256.Sum256(publicKey)
+	payload := append([]byte{f.Version, byte(scheme)}, hash[:]...)
+	return base58Encode(append(payload, base58Checksum(payload)...)), nil
+}
+
+// Scheme parses a base58check address
+func (f Base58CheckAddressFormat) Scheme(address string) (SignatureScheme, error) {
+	data, err := base58Decode(address)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) != 2+sha256.Size+4 {
+		return 0, fmt.Errorf("%w: malformed length", ErrInvalidAddress)
+	}
+	payload, checksum := data[:len(data)-4], data[len(data)-4:]
+	if !bytes.Equal(checksum, base58Checksum(payload)) {
+		return 0, fmt.Errorf("%w: checksum mismatch", ErrInvalidAddress)
+	}
+	if payload[0] != f.Version {
+		return 0, fmt.Errorf("address is for network version 0x%02x, not 0x%02x", payload[0], f.Version)
+	}
+	return tagScheme(payload[1])
+}
+
+// Bech32AddressFormat encodes the scheme tag and the SHA-256 of the public key in bech32 (BIP 173)
+// behind a human-readable network prefix. The checksum detects up to four character errors.
+type Bech32AddressFormat struct {
+	HRP string // Human-readable prefix naming the network, lowercase
+}
+
+// Name returns "bech32"
+func (Bech32AddressFormat) Name() string {
+	return "bech32"
+}
+
+// Address derives the bech32 address of a public key
+func (f Bech32AddressFormat) Address(scheme SignatureScheme, publicKey []byte) (string, error) {
+	if _, exists := verifiers[scheme]; !exists {
+		return "", fmt.Errorf("unsupported signature scheme %s", scheme)
+	}
+	hash := sha256.Sum256(publicKey)
+	data := append([]byte{byte(scheme)}, convertBits(hash[:], 8, 5, true)...)
+	return bech32Encode(f.HRP, data), nil
+}
+
+// Scheme parses a bech32 address
+func (f Bech32AddressFormat) Scheme(address string) (SignatureScheme, error) {
+	hrp, data, err := bech32Decode(address)
+	if err != nil {
+		return 0, err
+	}
+	if hrp != f.HRP {
+		return 0, fmt.Errorf("address is for network %q, not %q", hrp, f.HRP)
+	}
+	if len(data) < 1 {
+		return 0, fmt.Errorf("%w: malformed length", ErrInvalidAddress)
+	}
+	if hash := convertBits(data[1:], 5, 8, false); len(hash) != sha256.Size {
+		return 0, fmt.Errorf("%w: malformed length", ErrInvalidAddress)
+	}
+	return tagScheme(data[0])
+}
+
+// KeccakAddressFormat derives Ethereum-style addresses: "0x" and the last 20 bytes of the
+// Keccak-256 of the uncompressed public key, with EIP-55 mixed-case checksum. The address
+// carries no scheme tag, so only secp256k1 keys are supported, matching Ethereum.
+type KeccakAddressFormat struct{}
+
+// Name returns "keccak"
+func (KeccakAddressFormat) Name() string {
+	return "keccak"
+}
+
+// Address derives the Ethereum-style address of a secp256k1 public key
+func (KeccakAddressFormat) Address(scheme SignatureScheme, publicKey []byte) (string, error) {
+	if scheme != SchemeSecp256k1 {
+		return "", fmt.Errorf("keccak addresses only support secp256k1 keys, not %s", scheme)
+	}
+	point, err := secp256k1Decompress(publicKey)
+	if err != nil {
+		return "", err
+	}
+	hash := keccak256(secp256k1Uncompressed(point)[1:])
+	return eip55Checksum(hex.EncodeToString(hash[12:])), nil
+}
+
+// Scheme parses an Ethereum-style address. All-lowercase and all-uppercase addresses
+// carry no checksum; mixed-case ones must match it.
+func (KeccakAddressFormat) Scheme(address string) (SignatureScheme, error) {
+	digits, found := strings.CutPrefix(address, "0x")
+	if !found || len(digits) != 40 {
+		return 0, fmt.Errorf("%w: malformed", ErrInvalidAddress)
+	}
+	if _, err := hex.DecodeString(digits); err != nil {
+		return 0, fmt.Errorf("%w: malformed", ErrInvalidAddress)
+	}
+	if digits != strings.ToLower(digits) && digits != strings.ToUpper(digits) && eip55Checksum(strings.ToLower(digits)) != address {
+		return 0, fmt.Errorf("%w: checksum mismatch", ErrInvalidAddress)
+	}
+	return SchemeSecp256k1, nil
+}
+
+// tagScheme returns the supported scheme of a tag byte
+func tagScheme(tag byte) (SignatureScheme, error) {
+	scheme := SignatureScheme(tag)
+	if _, exists := verifiers[scheme]; !exists {
+		return 0, fmt.Errorf("unsupported signature scheme %s", scheme)
+	}
+	return scheme, nil
+}
+
+// keccak256 returns the legacy Keccak-256 hash used by Ethereum
+func keccak256(data []byte) []byte {
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(data)
+	return hash.Sum(nil)
+}
+
+// eip55Checksum capitalizes the hex letters of an address whose hash nibble is 8 or more
+func eip55Checksum(lowerHex string) string {
+	hash := hex.EncodeToString(keccak256([]byte(lowerHex)))
+	encoded := []byte(lowerHex)
+	for i, c := range encoded {
+		if c >= 'a' && hash[i] >= '8' {
+			encoded[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(encoded)
+}
+
+// bech32Charset maps 5-bit values to bech32 characters
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32Polymod computes the BCH checksum of BIP 173
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+// bech32HRPExpand spreads the prefix into the checksum input
+func bech32HRPExpand(hrp string) []byte {
+	expanded := make([]byte, 0, 2*len(hrp)+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+	return expanded
+}
+
+// bech32Encode encodes 5-bit values behind a prefix with a 6-character checksum
+func bech32Encode(hrp string, data []byte) string {
+	values := append(bech32HRPExpand(hrp), data...)
+	polymod := bech32Polymod(append(values, 0, 0, 0, 0, 0, 0)) ^ 1
+
+	var encoded strings.Builder
+	encoded.WriteString(hrp)
+	encoded.WriteByte('1')
+	for _, v := range data {
+		encoded.WriteByte(bech32Charset[v])
+	}
+	for i := 0; i < 6; i++ {
+		encoded.WriteByte(bech32Charset[(polymod>>uint(5*(5-i)))&31])
+	}
+	return encoded.String()
+}
+
+// bech32Decode splits a bech32 string into its prefix and 5-bit values, checking the checksum
+func bech32Decode(s string) (string, []byte, error) {
+	if len(s) > 90 {
+		return "", nil, errors.New("bech32 string too long")
+	}
+	if s != strings.ToLower(s) && s != strings.ToUpper(s) {
+		return "", nil, errors.New("bech32 string has mixed case")
+	}
+	s = strings.ToLower(s)
+	separator := strings.LastIndexByte(s, '1')
+	if separator < 1 || separator+7 > len(s) {
+		return "", nil, errors.New("malformed bech32 string")
+	}
+
+	hrp := s[:separator]
+	data := make([]byte, 0, len(s)-separator-1)
+	for i := separator + 1; i < len(s); i++ {
+		v := strings.IndexByte(bech32Charset, s[i])
+		if v < 0 {
+			return "", nil, fmt.Errorf("invalid bech32 character %q", s[i])
+		}
+		data = append(data, byte(v))
+	}
+	if bech32Polymod(append(bech32HRPExpand(hrp), data...)) != 1 {
+		return "", nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidAddress)
+	}
+	return hrp, data[:len(data)-6], nil
+}
+
+// convertBits regroups bits, e.g. bytes into 5-bit values. Without padding, leftover
+// bits must be zero and fewer than from, or nil is returned.
+func convertBits(data []byte, from, to uint, pad bool) []byte {
+	var acc, bits uint
+	maxValue := uint(1)<<to - 1
+	converted := make([]byte, 0, len(data)*int(from)/int(to)+1)
+	for _, v := range data {
+		acc = acc<<from | uint(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			converted = append(converted, byte(acc>>bits&maxValue))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			converted = append(converted, byte(acc<<(to-bits)&maxValue))
+		}
+	} else if bits >= from || acc<<(to-bits)&maxValue != 0 {
+		return nil
+	}
+	return converted
+}
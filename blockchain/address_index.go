@@ -0,0 +1,150 @@
+package blockchain
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"time"
+)
+
+// balanceTolerance absorbs the floating point residue of summing balances in a different order
+const balanceTolerance = 1e-9
+
+// AddressIndexReport summarizes an address index rebuild
+type AddressIndexReport struct {
+	Addresses int `json:"addresses"` // Addresses in the rebuilt index
+	Repaired  int `json:"repaired"`  // Addresses whose stored balance or count was wrong, or that shouldn't exist
+}
+
+// addressTotals accumulates the balance and the number of balance updates of every address
+type addressTotals struct {
+	balances map[string]float64
+	counts   map[string]int64
+}
+
+// newAddressTotals starts from what the pruned blocks left behind (nil if none were pruned)
+func newAddressTotals(state *PruneState) *addressTotals {
+	totals := &addressTotals{balances: make(map[string]float64), counts: make(map[string]int64)}
+	if state != nil {
+		maps.Copy(totals.balances, state.Balances)
+		maps.Copy(totals.counts, state.Counts)
+	}
+	return totals
+}
+
+// addBlock applies the transactions of a stored block; pruned blocks are covered by the prune state
+func (t *addressTotals) addBlock(block *Block) {
+	if block.Pruned {
+		return
+	}
+	for _, tx := range block.Transactions {
+		t.balances[tx.From] -= tx.debit(tx.From)
+		t.counts[tx.From]++
+		t.balances[tx.To] += tx.Amount
+		t.counts[tx.To]++
+		if tx.Sponsor != "" {
+			t.balances[tx.Sponsor] -= tx.Fee
+			t.counts[tx.Sponsor]++
+		}
+	}
+}
+
+// RebuildAddressIndex recomputes every address balance and transaction count from the stored
+// blocks and replaces the addresses table with them in one transaction, repairing drift left
+// by a partially failed block save. Addresses keep their first seen time.
+func (d *Database) RebuildAddressIndex() (*AddressIndexReport, error) {
+	if err := d.Flush(); err != nil {
+		return nil, err
+	}
+	state, err := d.LoadPruneState()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	totals := newAddressTotals(state)
+	rows, err := tx.Query("SELECT block_data FROM blocks ORDER BY block_index ASC")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var blockData string
+		if err := rows.Scan(&blockData); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		block, err := decodeStoredBlock(blockData)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		totals.addBlock(block)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	type storedAddress struct {
+		balance   float64
+		count     int64
+		firstSeen int64
+	}
+	stored := make(map[string]storedAddress)
+	rows, err = tx.Query("SELECT address, COALESCE(balance, 0), COALESCE(transaction_count, 0), first_seen FROM addresses")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var address string
+		var row storedAddress
+		if err := rows.Scan(&address, &row.balance, &row.count, &row.firstSeen); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stored[address] = row
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM addresses"); err != nil {
+		return nil, fmt.Errorf("failed to clear addresses: %w", err)
+	}
+	report := &AddressIndexReport{Addresses: len(totals.balances)}
+	now := time.Now().Unix()
+	for address, balance := range totals.balances {
+		count := totals.counts[address]
+		firstSeen := now
+		if row, exists := stored[address]; exists {
+			firstSeen = row.firstSeen
+			if math.Abs(row.balance-balance) > balanceTolerance || row.count != count {
+				report.Repaired++
+			}
+		} else {
+			report.Repaired++
+		}
+		_, err := tx.Exec(d.rebind(`
+			INSERT INTO addresses (address, balance, transaction_count, first_seen, last_updated)
+			VALUES (?, ?, ?, ?, ?)`), address, balance, count, firstSeen, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save address %s: %w", address, err)
+		}
+	}
+	for address := range stored {
+		if _, exists := totals.balances[address]; !exists {
+			report.Repaired++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
@@ -0,0 +1,281 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// AdmissionReason is the reason code of a mempool admission decision
+type AdmissionReason string
+
+const (
+	AdmissionAccepted            AdmissionReason = "accepted"
+	AdmissionReplaced            AdmissionReason = "replaced" // Accepted, replacing a pending transaction
+	AdmissionInvalid             AdmissionReason = "invalid"
+	AdmissionFeeTooLow           AdmissionReason = "fee_too_low"
+	AdmissionFeeRateTooLow       AdmissionReason = "fee_rate_too_low" // Below the pool's minimum fee per byte
+	AdmissionDust                AdmissionReason = "dust"
+	AdmissionSenderLimit         AdmissionReason = "sender_limit" // Sender at its maximum of pending transactions
+	AdmissionDuplicate           AdmissionReason = "duplicate"
+	AdmissionAlreadyConfirmed    AdmissionReason = "already_confirmed"
+	AdmissionNonceUsed           AdmissionReason = "nonce_used"
+	AdmissionConflict            AdmissionReason = "conflict"
+	AdmissionInsufficientBalance AdmissionReason = "insufficient_balance"
+	AdmissionPoolFull            AdmissionReason = "pool_full"
+	AdmissionPolicyViolation     AdmissionReason = "policy_violation"
+	AdmissionBadSignature        AdmissionReason = "bad_signature"
+	AdmissionBadAddress          AdmissionReason = "bad_address"
+	AdmissionHalted              AdmissionReason = "halted"
+	AdmissionExpired             AdmissionReason = "expired"
+	AdmissionClosed              AdmissionReason = "closed" // Not accepting external transactions, e.g. during maintenance
+)
+
+// AdmissionSourceLocal is the source of transactions submitted in-process
+const AdmissionSourceLocal = "local"
+
+// Errors of the transactions the pool refuses. A rejection matches the error of its reason
+// with errors.Is, e.g. errors.Is(err, ErrPoolFull).
+var (
+	ErrInvalidTransaction  = errors.New("invalid transaction")
+	ErrDuplicateTx         = errors.New("transaction already exists in pool")
+	ErrAlreadyConfirmed    = errors.New("transaction already confirmed in chain")
+	ErrNonceUsed           = errors.New("transaction nonce already used")
+	ErrTxConflict          = errors.New("transaction conflicts with a pending transaction")
+	ErrInsufficientBalance = errors.New("insufficient available balance")
+	ErrFeeTooLow           = errors.New("transaction fee below the minimum")
+	ErrPoolFull            = errors.New("transaction pool is full")
+	ErrPolicyViolation     = errors.New("transaction violates a spend policy")
+	ErrInvalidSignature    = errors.New("invalid transaction signature")
+	ErrPoolClosed          = errors.New("node is not accepting transactions")
+)
+
+// Invalid transactions, matching ErrInvalidTransaction too
+var (
+	ErrMissingAddress = fmt.Errorf("%w: missing from/to address", ErrInvalidTransaction)
+	ErrInvalidAmount  = fmt.Errorf("%w: amount must be positive", ErrInvalidTransaction)
+	ErrNegativeFee    = fmt.Errorf("%w: fee cannot be negative", ErrInvalidTransaction)
+)
+
+// admissionErrors are the errors rejections match by reason
+var admissionErrors = map[AdmissionReason]error{
+	AdmissionInvalid:             ErrInvalidTransaction,
+	AdmissionFeeTooLow:           ErrFeeTooLow,
+	AdmissionFeeRateTooLow:       ErrFeeRateTooLow,
+	AdmissionDust:                ErrDustAmount,
+	AdmissionSenderLimit:         ErrSenderLimit,
+	AdmissionDuplicate:           ErrDuplicateTx,
+	AdmissionAlreadyConfirmed:    ErrAlreadyConfirmed,
+	AdmissionNonceUsed:           ErrNonceUsed,
+	AdmissionConflict:            ErrTxConflict,
+	AdmissionInsufficientBalance: ErrInsufficientBalance,
+	AdmissionPoolFull:            ErrPoolFull,
+	AdmissionPolicyViolation:     ErrPolicyViolation,
+	AdmissionBadSignature:        ErrInvalidSignature,
+	AdmissionBadAddress:          ErrInvalidAddress,
+	AdmissionHalted:              ErrChainHalted,
+	AdmissionExpired:             ErrTxExpired,
+	AdmissionClosed:              ErrPoolClosed,
+}
+
+// AdmissionError is a pool rejection carrying its reason code
+type AdmissionError struct {
+	Reason AdmissionReason
+	Err    error
+}
+
+func (e *AdmissionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *AdmissionError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the error of the rejection's reason
+func (e *AdmissionError) Is(target error) bool {
+	return target != nil && admissionErrors[e.Reason] == target
+}
+
+// reject tags a pool rejection with its reason code
+func reject(reason AdmissionReason, err error) error {
+	return &AdmissionError{Reason: reason, Err: err}
+}
+
+// AdmissionReasonOf returns the reason code of a pool admission result
+func AdmissionReasonOf(err error) AdmissionReason {
+	if err == nil {
+		return AdmissionAccepted
+	}
+	var admissionErr *AdmissionError
+	if errors.As(err, &admissionErr) {
+		return admissionErr.Reason
+	}
+	return AdmissionInvalid
+}
+
+// PeerSource returns the admission source of a transaction relayed by a peer
+func PeerSource(address string) string {
+	return "peer:" + address
+}
+
+// AdmissionRecord is one accept or reject decision of the transaction pool
+type AdmissionRecord struct {
+	TxHash    string          `json:"txHash"`
+	From      string          `json:"from"`
+	Accepted  bool            `json:"accepted"`
+	Reason    AdmissionReason `json:"reason"`
+	Detail    string          `json:"detail,omitempty"` // Rejection message, or the hash of a replaced transaction
+	Source    string          `json:"source"`           // Where the transaction came from, e.g. local, peer:<addr> or api:<addr>
+	Timestamp int64           `json:"timestamp"`
+}
+
+// AdmissionStore persists admission records beyond the in-memory ring buffer
+type AdmissionStore interface {
+	SaveAdmission(record *AdmissionRecord) error
+}
+
+// AdmissionLog keeps the most recent pool admission decisions, so support can answer
+// why a transaction was not accepted without reproducing the submission
+type AdmissionLog struct {
+	records []AdmissionRecord
+	next    int  // Slot of the next record
+	full    bool // Whether the buffer has wrapped around
+	store   AdmissionStore
+	mu      sync.RWMutex
+}
+
+// NewAdmissionLog creates a log keeping the last capacity decisions in memory,
+// also saving every decision to the store if one is given
+func NewAdmissionLog(capacity int, store AdmissionStore) *AdmissionLog {
+	if capacity <= 0 {
+		capacity = 10000
+	}
+	return &AdmissionLog{
+		records: make([]AdmissionRecord, capacity),
+		store:   store,
+	}
+}
+
+// Record appends a decision, overwriting the oldest one once the buffer is full
+func (al *AdmissionLog) Record(record AdmissionRecord) {
+	al.mu.Lock()
+	al.records[al.next] = record
+	al.next = (al.next + 1) % len(al.records)
+	if al.next == 0 {
+		al.full = true
+	}
+	al.mu.Unlock()
+
+	if al.store != nil {
+		if err := al.store.SaveAdmission(&record); err != nil {
+			log.Printf("Warning: failed to persist admission record for %s: %v", record.TxHash, err)
+		}
+	}
+}
+
+// Records returns the buffered decisions, newest first
+func (al *AdmissionLog) Records() []AdmissionRecord {
+	if al == nil {
+		return nil
+	}
+	al.mu.RLock()
+	defer al.mu.RUnlock()
+
+	count := al.next
+	if al.full {
+		count = len(al.records)
+	}
+	records := make([]AdmissionRecord, 0, count)
+	for i := 1; i <= count; i++ {
+		records = append(records, al.records[(al.next-i+len(al.records))%len(al.records)])
+	}
+	return records
+}
+
+// Lookup returns the buffered decisions about a transaction, newest first
+func (al *AdmissionLog) Lookup(txHash string) []AdmissionRecord {
+	var matches []AdmissionRecord
+	for _, record := range al.Records() {
+		if record.TxHash == txHash {
+			matches = append(matches, record)
+		}
+	}
+	return matches
+}
+
+// record logs the outcome of an admission attempt (coinbase transactions are not audited)
+func (al *AdmissionLog) record(tx *Transaction, source string, replaced *Transaction, err error) {
+	if al == nil || tx.IsCoinbase() {
+		return
+	}
+	if source == "" {
+		source = AdmissionSourceLocal
+	}
+
+	record := AdmissionRecord{
+		TxHash:    tx.Hash,
+		From:      tx.From,
+		Accepted:  err == nil,
+		Reason:    AdmissionReasonOf(err),
+		Source:    source,
+		Timestamp: time.Now().Unix(),
+	}
+	switch {
+	case err != nil:
+		record.Detail = err.Error()
+	case replaced != nil:
+		record.Reason = AdmissionReplaced
+		record.Detail = replaced.Hash
+	}
+	al.Record(record)
+}
+
+// SaveAdmission stores an admission record
+func (d *Database) SaveAdmission(record *AdmissionRecord) error {
+	_, err := d.db.Exec(d.rebind(`
+		INSERT INTO admissions (tx_hash, from_address, accepted, reason, detail, source, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`),
+		record.TxHash, record.From, record.Accepted, string(record.Reason), record.Detail, record.Source, record.Timestamp)
+	if err != nil {
+		return fmt.Errorf("failed to save admission record: %w", err)
+	}
+	return nil
+}
+
+// GetAdmissions returns the stored decisions about a transaction, newest first
+func (d *Database) GetAdmissions(txHash string, limit int) ([]*AdmissionRecord, error) {
+	rows, err := d.db.Query(d.rebind(`
+		SELECT tx_hash, from_address, accepted, reason, detail, source, timestamp
+		FROM admissions WHERE tx_hash = ? ORDER BY id DESC LIMIT ?`), txHash, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*AdmissionRecord
+	for rows.Next() {
+		var record AdmissionRecord
+		var reason string
+		if err := rows.Scan(&record.TxHash, &record.From, &record.Accepted, &reason, &record.Detail,
+			&record.Source, &record.Timestamp); err != nil {
+			return nil, err
+		}
+		record.Reason = AdmissionReason(reason)
+		records = append(records, &record)
+	}
+
+	return records, rows.Err()
+}
+
+// PruneAdmissions deletes stored decisions older than a Unix time, returning how many were removed
+func (d *Database) PruneAdmissions(before int64) (int, error) {
+	result, err := d.db.Exec(d.rebind("DELETE FROM admissions WHERE timestamp < ?"), before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune admission records: %w", err)
+	}
+	removed, err := result.RowsAffected()
+	return int(removed), err
+}
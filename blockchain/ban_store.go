@@ -0,0 +1,72 @@
+package blockchain
+
+import (
+	"fmt"
+	"time"
+)
+
+// BanRecord describes a banned peer host
+type BanRecord struct {
+	Host        string `json:"host"`
+	Reason      string `json:"reason"`
+	BannedAt    int64  `json:"bannedAt"`
+	BannedUntil int64  `json:"bannedUntil"`
+}
+
+// IsActive checks if the ban is still in effect
+func (br *BanRecord) IsActive(now int64) bool {
+	return now < br.BannedUntil
+}
+
+// BanStore persists the ban list of the P2P node
+type BanStore interface {
+	SaveBan(record *BanRecord) error
+	RemoveBan(host string) error
+	GetActiveBans() ([]*BanRecord, error)
+}
+
+// SaveBan stores or extends a ban
+func (d *Database) SaveBan(record *BanRecord) error {
+	_, err := d.db.Exec(d.rebind(`
+		INSERT INTO bans (host, reason, banned_at, banned_until) VALUES (?, ?, ?, ?)
+		ON CONFLICT(host) DO UPDATE SET reason = excluded.reason,
+			banned_at = excluded.banned_at, banned_until = excluded.banned_until`),
+		record.Host, record.Reason, record.BannedAt, record.BannedUntil)
+	if err != nil {
+		return fmt.Errorf("failed to save ban: %w", err)
+	}
+	return nil
+}
+
+// RemoveBan lifts the ban of a host
+func (d *Database) RemoveBan(host string) error {
+	if _, err := d.db.Exec(d.rebind("DELETE FROM bans WHERE host = ?"), host); err != nil {
+		return fmt.Errorf("failed to remove ban: %w", err)
+	}
+	return nil
+}
+
+// GetActiveBans returns bans that have not expired yet, pruning expired ones
+func (d *Database) GetActiveBans() ([]*BanRecord, error) {
+	now := time.Now().Unix()
+	if _, err := d.db.Exec(d.rebind("DELETE FROM bans WHERE banned_until <= ?"), now); err != nil {
+		return nil, fmt.Errorf("failed to prune expired bans: %w", err)
+	}
+
+	rows, err := d.db.Query(d.rebind("SELECT host, reason, banned_at, banned_until FROM bans ORDER BY banned_at"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bans []*BanRecord
+	for rows.Next() {
+		var record BanRecord
+		if err := rows.Scan(&record.Host, &record.Reason, &record.BannedAt, &record.BannedUntil); err != nil {
+			return nil, err
+		}
+		bans = append(bans, &record)
+	}
+
+	return bans, rows.Err()
+}
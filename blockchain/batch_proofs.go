@@ -0,0 +1,114 @@
+package blockchain
+
+import (
+	"runtime"
+	"sync"
+
+	"blockchain/blockchain/verify"
+)
+
+// ProofWithRoot is a transaction inclusion proof with the Merkle root it claims to hash up to,
+// e.g. one payment certificate of an export
+type ProofWithRoot struct {
+	Proof *MerkleProof `json:"proof"`
+	Root  string       `json:"root"`
+}
+
+// ProofResult is the outcome of verifying one proof of a batch
+type ProofResult struct {
+	Index int    `json:"index"` // Position in the batch
+	Hash  string `json:"hash"`  // Transaction the proof is for
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+// ProofReport summarizes a batch proof verification
+type ProofReport struct {
+	Results []ProofResult `json:"results"` // In batch order
+	Valid   int           `json:"valid"`
+	Invalid int           `json:"invalid"`
+}
+
+// AllValid reports whether every proof of the batch verified
+func (r *ProofReport) AllValid() bool {
+	return r.Invalid == 0
+}
+
+// VerifyProofs verifies a batch of Merkle proofs in parallel and reports every result.
+// Proofs against the same root are verified by the same worker, which reuses the inner node
+// hashes their paths share, so proofs from one block cost little more than their leaf levels.
+func VerifyProofs(proofs []ProofWithRoot) *ProofReport {
+	report := &ProofReport{Results: make([]ProofResult, len(proofs))}
+
+	// Group the proofs by root, in order of first appearance
+	var groups [][]int
+	byRoot := make(map[string]int)
+	for i, p := range proofs {
+		group, exists := byRoot[p.Root]
+		if !exists {
+			group = len(groups)
+			byRoot[p.Root] = group
+			groups = append(groups, nil)
+		}
+		groups[group] = append(groups[group], i)
+	}
+
+	work := make(chan []int)
+	var wg sync.WaitGroup
+	for range min(runtime.GOMAXPROCS(0), len(groups)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range work {
+				hashes := make(map[[2]string]string) // Node hashes by children, shared by the group
+				for _, i := range group {
+					report.Results[i] = verifyBatchProof(i, proofs[i], hashes)
+				}
+			}
+		}()
+	}
+	for _, group := range groups {
+		work <- group
+	}
+	close(work)
+	wg.Wait()
+
+	for _, result := range report.Results {
+		if result.Valid {
+			report.Valid++
+		} else {
+			report.Invalid++
+		}
+	}
+	return report
+}
+
+// verifyBatchProof verifies one proof of a batch, memoizing node hashes in hashes
+func verifyBatchProof(index int, p ProofWithRoot, hashes map[[2]string]string) ProofResult {
+	result := ProofResult{Index: index}
+	if err := CheckProofLimits(p.Proof); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Hash = p.Proof.Hash
+
+	current := p.Proof.Hash
+	for i, sibling := range p.Proof.Hashes {
+		children := [2]string{current, sibling}
+		if p.Proof.IsLeft[i] {
+			children = [2]string{sibling, current}
+		}
+		hash, exists := hashes[children]
+		if !exists {
+			hash = verify.NodeHash(children[0], children[1])
+			hashes[children] = hash
+		}
+		current = hash
+	}
+	if current != p.Root {
+		result.Error = "proof does not hash up to the root"
+		return result
+	}
+	result.Valid = true
+	return result
+}
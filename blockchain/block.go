@@ -1,14 +1,24 @@
 package blockchain
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
-	"encoding/json"
+	"bytes"
+	"errors"
 	"time"
+
+	"blockchain/blockchain/verify"
+)
+
+// Errors of the blocks a chain refuses to connect
+var (
+	ErrBlockKnown   = errors.New("block already known")
+	ErrNotChainTip  = errors.New("block does not extend the current chain tip")
+	ErrInvalidBlock = errors.New("invalid block") // Wraps the consensus rule the block breaks
+	ErrOrphanBlock  = errors.New("block held until its parent arrives")
 )
 
 // Block represents a block in the blockchain
 type Block struct {
+	Version      int32         `json:"version,omitempty"`
 	Index        int64         `json:"index"`
 	Timestamp    int64         `json:"timestamp"`
 	Transactions []Transaction `json:"transactions"`
@@ -16,7 +26,15 @@ type Block struct {
 	Hash         string        `json:"hash"`
 	Nonce        int64         `json:"nonce"`
 	MerkleRoot   string        `json:"merkleRoot"`
+	KVRoot       string        `json:"kvRoot,omitempty"` // Root of the key-value entries set in the block
+	MMRRoot      string        `json:"mmrRoot,omitempty"`
+	ExtRoot      string        `json:"extRoot,omitempty"`
+	Extensions   []Extension   `json:"extensions,omitempty"` // Extension area, committed by ExtRoot
+	StateRoot    string        `json:"stateRoot,omitempty"`
+	ReceiptRoot  string        `json:"receiptRoot,omitempty"` // Root of the transactions' receipts, from ReceiptRootBlockVersion on
+	Bloom        string        `json:"bloom,omitempty"`       // Address bloom of the transactions, not covered by the hash
 	MerkleTree   *MerkleTree   `json:"-"`
+	Pruned       bool          `json:"pruned,omitempty"` // Body dropped by pruning, only the header is kept
 }
 
 // Transaction represents a transaction in the blockchain
@@ -25,9 +43,26 @@ type Transaction struct {
 	To     string  `json:"to"`
 	Amount float64 `json:"amount"`
 	Fee    float64 `json:"fee"`
+	Nonce  int64   `json:"nonce,omitempty"` // Per-sender sequence number (0 for unsequenced transactions)
+	Data   []byte  `json:"data,omitempty"`  // Application payload, e.g. a key-value entry, base64 in JSON
 	Hash   string  `json:"hash"`
+
+	// Authorization by the sender, not covered by the hash (see VerifyTransactionSignature)
+	PublicKey string `json:"publicKey,omitempty"` // Encoded by EncodePublicKey
+	Signature string `json:"signature,omitempty"`
+
+	// Fee sponsorship: the sponsor is covered by the hash, its authorization is not (see AttachSponsorSignature)
+	Sponsor          string `json:"sponsor,omitempty"` // Pays the fee instead of the sender
+	SponsorKey       string `json:"sponsorKey,omitempty"`
+	SponsorSignature string `json:"sponsorSignature,omitempty"`
+
+	// Validity window, covered by the hash and the sender's signature (see ExpiredAt)
+	ValidUntil int64 `json:"validUntil,omitempty"` // Last block height, or Unix time, the transaction can confirm at
 }
 
+// CoinbaseSender is the sender address used for mining reward transactions
+const CoinbaseSender = "network"
+
 // NewBlock creates a new block with Merkle tree integration
 func NewBlock(index int64, transactions []Transaction, prevHash string) *Block {
 	merkleTree := NewMerkleTree(transactions)
@@ -36,6 +71,9 @@ func NewBlock(index int64, transactions []Transaction, prevHash string) *Block {
 		merkleRoot = merkleTree.GetMerkleRoot()
 	}
 
+	// Malformed entries are rejected by the pool, a block built from them fails validation
+	kvRoot, _ := calculateKVRoot(transactions)
+
 	return &Block{
 		Index:        index,
 		Timestamp:    time.Now().Unix(),
@@ -44,6 +82,8 @@ func NewBlock(index int64, transactions []Transaction, prevHash string) *Block {
 		Nonce:        0,
 		Hash:         "",
 		MerkleRoot:   merkleRoot,
+		KVRoot:       kvRoot,
+		Bloom:        calculateAddressBloom(transactions),
 		MerkleTree:   merkleTree,
 	}
 }
@@ -60,93 +100,243 @@ func NewTransaction(from, to string, amount, fee float64) *Transaction {
 	return tx
 }
 
+// NewTransactionWithNonce creates a new transaction carrying a sender nonce,
+// so that conflicting spends from the same sender can be detected
+func NewTransactionWithNonce(from, to string, amount, fee float64, nonce int64) *Transaction {
+	tx := &Transaction{
+		From:   from,
+		To:     to,
+		Amount: amount,
+		Fee:    fee,
+		Nonce:  nonce,
+	}
+	tx.Hash = tx.calculateHash()
+	return tx
+}
+
+// IsCoinbase checks if the transaction is a mining reward
+func (tx *Transaction) IsCoinbase() bool {
+	return tx.From == CoinbaseSender
+}
+
 // calculateHash calculates the hash of the block (now includes Merkle root)
 func (b *Block) calculateHash() string {
-	data := struct {
-		Index      int64
-		Timestamp  int64
-		MerkleRoot string
-		PrevHash   string
-		Nonce      int64
-	}{
-		Index:      b.Index,
-		Timestamp:  b.Timestamp,
-		MerkleRoot: b.MerkleRoot,
-		PrevHash:   b.PrevHash,
-		Nonce:      b.Nonce,
-	}
-	blockBytes, err := json.Marshal(data)
-	if err != nil {
-		return ""
-	}
-	hash := sha256.Sum256(blockBytes)
-	return hex.EncodeToString(hash[:])
+	return hashEncoding(b.encodeHeader())
+}
+
+// encodeHeader returns the canonical encoding hashed into the block hash.
+// Legacy blocks encode without the version field so chains stored before versioning stay valid.
+func (b *Block) encodeHeader() []byte {
+	header := b.Header()
+	return verify.EncodeHeader(header.verifyHeader())
 }
 
 // calculateHash calculates the hash of the transaction
 func (tx *Transaction) calculateHash() string {
-	data := struct {
-		From   string
-		To     string
-		Amount float64
-		Fee    float64
-	}{
-		From:   tx.From,
-		To:     tx.To,
-		Amount: tx.Amount,
-		Fee:    tx.Fee,
+	return hashEncoding(tx.encode())
+}
+
+// encode returns the canonical encoding hashed into the transaction hash.
+// Unsequenced transactions encode without the nonce, and unsponsored ones without the
+// sponsor, so existing hashes are unchanged.
+func (tx *Transaction) encode() []byte {
+	return verify.EncodeTransaction(&verify.Transaction{
+		From:    tx.From,
+		To:      tx.To,
+		Amount:  tx.Amount,
+		Fee:     tx.Fee,
+		Nonce:   tx.Nonce,
+		Data:    tx.Data,
+		Sponsor: tx.Sponsor,
+
+		ValidUntil: tx.ValidUntil,
+	})
+}
+
+// hashEncoding returns the hex-encoded SHA-256 of a canonical encoding
+func hashEncoding(data []byte) string {
+	return verify.HashEncoding(data)
+}
+
+// BlockHeader is a block without its transactions, enough to verify proof of work and chain linkage
+type BlockHeader struct {
+	Version     int32  `json:"version,omitempty"`
+	Index       int64  `json:"index"`
+	Timestamp   int64  `json:"timestamp"`
+	PrevHash    string `json:"prevHash"`
+	Hash        string `json:"hash"`
+	Nonce       int64  `json:"nonce"`
+	MerkleRoot  string `json:"merkleRoot"`
+	KVRoot      string `json:"kvRoot,omitempty"`
+	MMRRoot     string `json:"mmrRoot,omitempty"` // History root, set on blocks at multiples of ChainParams.HistoryCommit
+	ExtRoot     string `json:"extRoot,omitempty"`
+	StateRoot   string `json:"stateRoot,omitempty"`   // State trie root, set on blocks from ChainParams.StateCommit on
+	ReceiptRoot string `json:"receiptRoot,omitempty"` // Receipt root, set on blocks from ReceiptRootBlockVersion on
+	Bloom       string `json:"bloom,omitempty"`       // Address bloom (see AddressBloom), not covered by the hash
+}
+
+// Header returns the header of the block
+func (b *Block) Header() BlockHeader {
+	return BlockHeader{
+		Version:     b.Version,
+		Index:       b.Index,
+		Timestamp:   b.Timestamp,
+		PrevHash:    b.PrevHash,
+		Hash:        b.Hash,
+		Nonce:       b.Nonce,
+		MerkleRoot:  b.MerkleRoot,
+		KVRoot:      b.KVRoot,
+		MMRRoot:     b.MMRRoot,
+		ExtRoot:     b.ExtRoot,
+		StateRoot:   b.StateRoot,
+		ReceiptRoot: b.ReceiptRoot,
+		Bloom:       b.Bloom,
 	}
-	txBytes, err := json.Marshal(data)
-	if err != nil {
-		return ""
+}
+
+// verifyHeader converts the header for the verify package
+func (h *BlockHeader) verifyHeader() *verify.Header {
+	return &verify.Header{
+		Version:     h.Version,
+		Index:       h.Index,
+		Timestamp:   h.Timestamp,
+		PrevHash:    h.PrevHash,
+		Hash:        h.Hash,
+		Nonce:       h.Nonce,
+		MerkleRoot:  h.MerkleRoot,
+		KVRoot:      h.KVRoot,
+		MMRRoot:     h.MMRRoot,
+		ExtRoot:     h.ExtRoot,
+		StateRoot:   h.StateRoot,
+		ReceiptRoot: h.ReceiptRoot,
 	}
-	hash := sha256.Sum256(txBytes)
-	return hex.EncodeToString(hash[:])
+}
+
+// calculateHash calculates the block hash from the header fields
+func (h *BlockHeader) calculateHash() string {
+	return verify.HeaderHash(h.verifyHeader())
+}
+
+// validateAgainstParent checks that the header correctly extends the given parent header
+func (h *BlockHeader) validateAgainstParent(parent *BlockHeader, difficulty int) error {
+	return verify.ValidateHeader(h.verifyHeader(), parent.verifyHeader(), difficulty)
 }
 
 // MineBlock mines the block with a given difficulty
 func (b *Block) MineBlock(difficulty int) {
-	target := make([]byte, difficulty)
-	for i := 0; i < difficulty; i++ {
-		target[i] = '0'
-	}
-	targetStr := string(target)
+	target := bytes.Repeat([]byte{'0'}, difficulty)
 
+	// Each attempt re-encodes the header into pooled scratch space, allocating nothing
+	// until a hash meets the target
+	scratch := getHashScratch()
+	defer putHashScratch(scratch)
+	header := b.Header()
+	encoded := header.verifyHeader()
 	for {
 		b.Nonce++
-		b.Hash = b.calculateHash()
-		if b.Hash[:difficulty] == targetStr {
+		encoded.Nonce = b.Nonce
+		scratch.buf = verify.AppendHeader(scratch.buf[:0], encoded)
+		if hash := scratch.sum(); bytes.HasPrefix(hash, target) {
+			b.Hash = string(hash)
 			break
 		}
 	}
 }
 
-// ValidateTransactions validates all transactions in the block using Merkle tree
-func (b *Block) ValidateTransactions() bool {
-	if b.MerkleTree == nil {
-		b.MerkleTree = NewMerkleTree(b.Transactions)
-		if b.MerkleTree.Root != nil {
-			b.MerkleRoot = b.MerkleTree.GetMerkleRoot()
-		}
+// validateAgainstParent checks that the block correctly extends the given parent block
+func (b *Block) validateAgainstParent(parent *Block, difficulty int) error {
+	if b.Index != parent.Index+1 {
+		return errors.New("invalid block index")
+	}
+
+	if b.PrevHash != parent.Hash {
+		return errors.New("block does not link to parent hash")
+	}
+
+	if b.Hash != b.calculateHash() {
+		return errors.New("invalid block hash")
+	}
+
+	if !verify.MeetsDifficulty(b.Hash, difficulty) {
+		return errors.New("block hash does not meet difficulty target")
 	}
 
-	calculatedRoot := ""
-	if b.MerkleTree.Root != nil {
-		calculatedRoot = b.MerkleTree.GetMerkleRoot()
+	if !b.ValidateTransactions() {
+		return errors.New("invalid Merkle root")
 	}
 
-	return b.MerkleRoot == calculatedRoot
+	if err := b.validateKVRoot(); err != nil {
+		return err
+	}
+
+	if err := b.validateReceiptRoot(); err != nil {
+		return err
+	}
+
+	if err := b.validateBloom(); err != nil {
+		return err
+	}
+
+	if err := b.validateExtensions(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// MerkleScheme returns the scheme the block's version commits its transactions under
+func (h *BlockHeader) MerkleScheme() MerkleScheme {
+	return merkleSchemeOf(h.Version)
+}
+
+// MerkleScheme returns the scheme the block's version commits its transactions under
+func (b *Block) MerkleScheme() MerkleScheme {
+	return merkleSchemeOf(b.Version)
+}
+
+// merkleSchemeOf returns the transaction Merkle scheme of a block version
+func merkleSchemeOf(version int32) MerkleScheme {
+	if version >= TaggedMerkleBlockVersion {
+		return TaggedMerkleScheme
+	}
+	return LegacyMerkleScheme
+}
+
+// setVersion sets the version of a new block, recommitting its transactions if the version
+// changes their Merkle scheme, and its receipts if the version commits them
+func (b *Block) setVersion(version int32) {
+	b.Version = version
+	b.MerkleRoot = b.merkleTree().GetMerkleRoot()
+	b.ReceiptRoot = b.receiptCommitment()
+}
+
+// merkleTree returns the block's transaction tree, building it under the block's scheme if
+// the block has none yet or one under another scheme
+func (b *Block) merkleTree() *MerkleTree {
+	if b.MerkleTree == nil || b.MerkleTree.scheme != b.MerkleScheme() {
+		b.MerkleTree = NewSchemeMerkleTree(b.Transactions, b.MerkleScheme())
+	}
+	return b.MerkleTree
+}
+
+// ValidateTransactions validates all transactions in the block using Merkle tree
+func (b *Block) ValidateTransactions() bool {
+	// Blocks received from peers or loaded from storage have no tree yet; build it from
+	// the transactions and compare against the claimed root rather than adopting it
+	return b.MerkleRoot == b.merkleTree().GetMerkleRoot()
 }
 
 // GenerateTransactionProof generates a Merkle proof for a specific transaction
 func (b *Block) GenerateTransactionProof(txHash string) (*MerkleProof, error) {
-	if b.MerkleTree == nil {
-		b.MerkleTree = NewMerkleTree(b.Transactions)
-	}
-	return b.MerkleTree.GenerateProof(txHash)
+	return b.merkleTree().GenerateProof(txHash)
+}
+
+// GenerateTransactionProofByIndex generates a Merkle proof for the transaction at a position in the block
+func (b *Block) GenerateTransactionProofByIndex(index int) (*MerkleProof, error) {
+	return b.merkleTree().GenerateProofByIndex(index)
 }
 
 // VerifyTransactionProof verifies that a transaction exists in this block
 func (b *Block) VerifyTransactionProof(proof *MerkleProof) bool {
-	return VerifyProof(proof, b.MerkleRoot)
+	return VerifyProof(proof, b.MerkleRoot, b.MerkleScheme())
 }
@@ -0,0 +1,287 @@
+package blockchain
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression codecs for the block_data column (DatabaseConfig.Compression)
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// Compressed block_data is stored as "<codec>:" followed by the base64 compressed JSON, so it
+// fits the TEXT column of every dialect and is told apart from plain JSON, which starts with '{'.
+// Rows of any codec can be read whatever the database is configured with.
+const compressedSeparator = ":"
+
+// zstd coders are safe for concurrent EncodeAll and DecodeAll calls, one of each is shared
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+// zstdCoders returns the shared zstd encoder and decoder
+func zstdCoders() (*zstd.Encoder, *zstd.Decoder, error) {
+	zstdOnce.Do(func() {
+		zstdEncoder, zstdErr = zstd.NewWriter(nil)
+		if zstdErr != nil {
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(MaxBlockSize+1))
+	})
+	return zstdEncoder, zstdDecoder, zstdErr
+}
+
+// normalizeCompression validates a codec name, returning CompressionNone for ""
+func normalizeCompression(codec string) (string, error) {
+	switch codec {
+	case "", CompressionNone:
+		return CompressionNone, nil
+	case CompressionGzip, CompressionZstd:
+		return codec, nil
+	}
+	return "", fmt.Errorf("unsupported block compression: %s", codec)
+}
+
+// compressBlockData encodes serialized block JSON for the block_data column
+func compressBlockData(codec string, data []byte) (string, error) {
+	var compressed []byte
+	switch codec {
+	case "", CompressionNone:
+		return string(data), nil
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return "", err
+		}
+		if err := w.Close(); err != nil {
+			return "", err
+		}
+		compressed = buf.Bytes()
+	case CompressionZstd:
+		encoder, _, err := zstdCoders()
+		if err != nil {
+			return "", err
+		}
+		compressed = encoder.EncodeAll(data, nil)
+	default:
+		return "", fmt.Errorf("unsupported block compression: %s", codec)
+	}
+	return codec + compressedSeparator + base64.StdEncoding.EncodeToString(compressed), nil
+}
+
+// storedCompression returns the codec a block_data value is stored with
+func storedCompression(stored string) string {
+	codec, _, found := strings.Cut(stored, compressedSeparator)
+	if !found || strings.HasPrefix(stored, "{") {
+		return CompressionNone
+	}
+	return codec
+}
+
+// decompressBlockData returns the block JSON of a block_data value. Output is cut off past
+// MaxBlockSize, so DecodeBlock rejects oversized blocks instead of inflating them in full.
+func decompressBlockData(stored string) ([]byte, error) {
+	codec := storedCompression(stored)
+	if codec == CompressionNone {
+		return []byte(stored), nil
+	}
+	compressed, err := base64.StdEncoding.DecodeString(stored[len(codec)+len(compressedSeparator):])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s block data: %w", codec, err)
+	}
+
+	switch codec {
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress block data: %w", err)
+		}
+		data, err := io.ReadAll(io.LimitReader(r, MaxBlockSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress block data: %w", err)
+		}
+		return data, nil
+	case CompressionZstd:
+		_, decoder, err := zstdCoders()
+		if err != nil {
+			return nil, err
+		}
+		data, err := decoder.DecodeAll(compressed, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress block data: %w", err)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("unsupported block compression: %s", codec)
+}
+
+// decodeStoredBlock decodes a block_data value of any codec
+func decodeStoredBlock(stored string) (*Block, error) {
+	data, err := decompressBlockData(stored)
+	if err != nil {
+		return nil, &DecodeError{Kind: "block", Err: err}
+	}
+	return DecodeBlock(data)
+}
+
+// encodeBlockData serializes a block for the block_data column with the database's codec
+func (d *Database) encodeBlockData(block *Block) (string, error) {
+	data, err := json.Marshal(block)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize block: %w", err)
+	}
+	return compressBlockData(d.compression, data)
+}
+
+// CompressionStats reports how the block_data column is stored
+type CompressionStats struct {
+	Blocks      int64            `json:"blocks"`
+	Codecs      map[string]int64 `json:"codecs"`      // Blocks per codec
+	StoredBytes int64            `json:"storedBytes"` // Size of the block_data column
+	RawBytes    int64            `json:"rawBytes"`    // Size of the block JSON uncompressed
+}
+
+// Saved returns the bytes compression saves
+func (s *CompressionStats) Saved() int64 {
+	return s.RawBytes - s.StoredBytes
+}
+
+// Ratio returns the stored size as a fraction of the uncompressed size
+func (s *CompressionStats) Ratio() float64 {
+	if s.RawBytes == 0 {
+		return 1
+	}
+	return float64(s.StoredBytes) / float64(s.RawBytes)
+}
+
+// CompressionStats measures the block_data column, decompressing every block
+func (d *Database) CompressionStats() (*CompressionStats, error) {
+	if err := d.Flush(); err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.Query("SELECT block_data FROM blocks")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := &CompressionStats{Codecs: make(map[string]int64)}
+	for rows.Next() {
+		var stored string
+		if err := rows.Scan(&stored); err != nil {
+			return nil, err
+		}
+		data, err := decompressBlockData(stored)
+		if err != nil {
+			return nil, err
+		}
+		stats.Blocks++
+		stats.Codecs[storedCompression(stored)]++
+		stats.StoredBytes += int64(len(stored))
+		stats.RawBytes += int64(len(data))
+	}
+	return stats, rows.Err()
+}
+
+// CompressBlocks rewrites the stored blocks that aren't in the database's codec, batchSize
+// blocks per transaction, and returns how many were rewritten. With no compression configured
+// it decompresses them. It can be interrupted and run again.
+func (d *Database) CompressBlocks(batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	if err := d.Flush(); err != nil {
+		return 0, err
+	}
+
+	var rewritten int64
+	next := int64(0)
+	for {
+		count, last, err := d.compressBlockBatch(next, batchSize)
+		rewritten += count
+		if err != nil {
+			return rewritten, err
+		}
+		if last < next {
+			break
+		}
+		log.Printf("Compressed blocks up to %d (%d rewritten)", last, rewritten)
+		next = last + 1
+	}
+	return rewritten, nil
+}
+
+// compressBlockBatch rewrites the blocks of a batch starting at a height, returning the blocks
+// rewritten and the last height of the batch (below from when no block is left)
+func (d *Database) compressBlockBatch(from int64, batchSize int) (int64, int64, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(d.rebind("SELECT block_index, block_data FROM blocks WHERE block_index >= ? ORDER BY block_index ASC LIMIT ?"), from, batchSize)
+	if err != nil {
+		return 0, 0, err
+	}
+	type storedBlock struct {
+		index int64
+		data  string
+	}
+	var batch []storedBlock
+	for rows.Next() {
+		var block storedBlock
+		if err := rows.Scan(&block.index, &block.data); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		batch = append(batch, block)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+	if len(batch) == 0 {
+		return 0, from - 1, nil
+	}
+
+	var rewritten int64
+	for _, block := range batch {
+		if storedCompression(block.data) == d.compression {
+			continue
+		}
+		data, err := decompressBlockData(block.data)
+		if err != nil {
+			return 0, 0, fmt.Errorf("block %d: %w", block.index, err)
+		}
+		stored, err := compressBlockData(d.compression, data)
+		if err != nil {
+			return 0, 0, fmt.Errorf("block %d: %w", block.index, err)
+		}
+		if _, err := tx.Exec(d.rebind("UPDATE blocks SET block_data = ? WHERE block_index = ?"), stored, block.index); err != nil {
+			return 0, 0, fmt.Errorf("failed to rewrite block %d: %w", block.index, err)
+		}
+		rewritten++
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return rewritten, batch[len(batch)-1].index, nil
+}
@@ -0,0 +1,188 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"blockchain/blockchain/verify"
+)
+
+// Extension is consensus data a block carries in its extension area, e.g. validator
+// attestations, bridge roots or oracle digests. The header commits every extension of the
+// block through ExtRoot, a Merkle root over the extensions in type order.
+type Extension struct {
+	Type string `json:"type"`
+	Data string `json:"data"`
+}
+
+// ExtensionHandler defines a registered extension type
+type ExtensionHandler struct {
+	// Produce returns the data a miner places in a new block, or false to leave the extension
+	// out. The block has its transactions and header fields but is not mined yet.
+	Produce func(block *Block) (string, bool)
+
+	// Validate checks the data a connected block carries (nil accepts any data)
+	Validate func(block *Block, data string) error
+
+	// Required rejects blocks that don't carry the extension
+	Required bool
+}
+
+// ExtensionRegistry holds the extension types a network agreed on. Blocks may carry extensions
+// of types the registry doesn't know: they are committed and relayed but not interpreted, so
+// nodes that haven't registered a newer type keep following the chain.
+type ExtensionRegistry struct {
+	handlers map[string]ExtensionHandler
+}
+
+// NewExtensionRegistry creates an empty registry
+func NewExtensionRegistry() *ExtensionRegistry {
+	return &ExtensionRegistry{handlers: make(map[string]ExtensionHandler)}
+}
+
+// Register adds an extension type
+func (r *ExtensionRegistry) Register(extType string, handler ExtensionHandler) error {
+	if err := validateExtensionType(extType); err != nil {
+		return err
+	}
+	if _, exists := r.handlers[extType]; exists {
+		return fmt.Errorf("extension %q is already registered", extType)
+	}
+	r.handlers[extType] = handler
+	return nil
+}
+
+// Types returns the registered extension types in order
+func (r *ExtensionRegistry) Types() []string {
+	if r == nil {
+		return nil
+	}
+	types := make([]string, 0, len(r.handlers))
+	for extType := range r.handlers {
+		types = append(types, extType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// validateExtensionType checks an extension type is a short name of lowercase letters,
+// digits, dots, dashes and underscores, e.g. "bridge.root"
+func validateExtensionType(extType string) error {
+	if extType == "" || len(extType) > MaxExtensionTypeLength {
+		return fmt.Errorf("extension type must be 1 to %d characters", MaxExtensionTypeLength)
+	}
+	for _, c := range extType {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '.' && c != '-' && c != '_' {
+			return fmt.Errorf("invalid extension type %q", extType)
+		}
+	}
+	return nil
+}
+
+// calculateExtRoot returns the root committing a block's extensions ("" if none)
+func calculateExtRoot(extensions []Extension) string {
+	leaves := make([]string, len(extensions))
+	for i, ext := range extensions {
+		leaves[i] = verify.ExtensionLeafHash(ext.Type, ext.Data)
+	}
+	return verify.MerkleRoot(leaves)
+}
+
+// validateExtensions checks the block's extensions are well formed, in strictly increasing
+// type order, and committed by its header
+func (b *Block) validateExtensions() error {
+	for i, ext := range b.Extensions {
+		if err := validateExtensionType(ext.Type); err != nil {
+			return err
+		}
+		if i > 0 && ext.Type <= b.Extensions[i-1].Type {
+			return errors.New("block extensions are not in strictly increasing type order")
+		}
+	}
+	if calculateExtRoot(b.Extensions) != b.ExtRoot {
+		return errors.New("invalid extension root")
+	}
+	return nil
+}
+
+// Extension returns the data of one of the block's extensions
+func (b *Block) Extension(extType string) (string, bool) {
+	i := sort.Search(len(b.Extensions), func(i int) bool { return b.Extensions[i].Type >= extType })
+	if i < len(b.Extensions) && b.Extensions[i].Type == extType {
+		return b.Extensions[i].Data, true
+	}
+	return "", false
+}
+
+// produce fills the extension area of a new block from the registered producers and commits it
+func (r *ExtensionRegistry) produce(block *Block) {
+	block.Extensions = nil
+	for _, extType := range r.Types() {
+		handler := r.handlers[extType]
+		if handler.Produce == nil {
+			continue
+		}
+		if data, ok := handler.Produce(block); ok {
+			block.Extensions = append(block.Extensions, Extension{Type: extType, Data: data})
+		}
+	}
+	block.ExtRoot = calculateExtRoot(block.Extensions)
+}
+
+// ValidateBlock checks the block carries the required extensions and that the registered
+// ones validate. Extensions of unknown types are accepted unread.
+func (r *ExtensionRegistry) ValidateBlock(block *Block) error {
+	if r == nil {
+		return nil
+	}
+	for _, extType := range r.Types() {
+		handler := r.handlers[extType]
+		data, ok := block.Extension(extType)
+		if !ok {
+			if handler.Required {
+				return fmt.Errorf("block is missing the %s extension", extType)
+			}
+			continue
+		}
+		if handler.Validate != nil {
+			if err := handler.Validate(block, data); err != nil {
+				return fmt.Errorf("invalid %s extension: %w", extType, err)
+			}
+		}
+	}
+	return nil
+}
+
+// SetExtensions registers the block extensions the network agreed on: mined blocks carry what
+// their producers return and connected blocks must satisfy their validators (nil registers none)
+func (bc *Blockchain) SetExtensions(registry *ExtensionRegistry) {
+	bc.extensions = registry
+}
+
+// SetExtensions registers the block extensions the network agreed on: mined blocks carry what
+// their producers return and connected blocks must satisfy their validators (nil registers none)
+func (pbc *PersistentBlockchain) SetExtensions(registry *ExtensionRegistry) {
+	pbc.extensions = registry
+}
+
+// GenerateExtensionProof proves one of the block's extensions against its ExtRoot, so a client
+// holding only the header can read it
+func (b *Block) GenerateExtensionProof(extType string) (*MerkleProof, error) {
+	data, ok := b.Extension(extType)
+	if !ok {
+		return nil, fmt.Errorf("block %d has no %s extension", b.Index, extType)
+	}
+	leaves := make([]string, len(b.Extensions))
+	for i, ext := range b.Extensions {
+		leaves[i] = verify.ExtensionLeafHash(ext.Type, ext.Data)
+	}
+	return newMerkleTreeFromHashes(leaves, LegacyMerkleScheme).GenerateProof(verify.ExtensionLeafHash(extType, data))
+}
+
+// VerifyExtensionProof checks a proof that the block of a header carries an extension with
+// the given data
+func VerifyExtensionProof(proof *MerkleProof, header BlockHeader, extType, data string) bool {
+	return proof != nil && proof.Hash == verify.ExtensionLeafHash(extType, data) &&
+		VerifyProof(proof, header.ExtRoot, LegacyMerkleScheme)
+}
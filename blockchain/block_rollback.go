@@ -0,0 +1,104 @@
+package blockchain
+
+import (
+	"fmt"
+	"log"
+)
+
+// AdmissionSourceRollback is the source of transactions returned to the pool from blocks
+// removed from the chain
+const AdmissionSourceRollback = "rollback"
+
+// ReinsertBlocks returns the non-coinbase transactions of blocks removed from the chain to the
+// pool, in block order, through the pool's validation against the current chain: transactions
+// the chain still confirms or that are no longer valid are dropped. It returns the transactions
+// reinserted and dropped.
+func (tp *Mempool) ReinsertBlocks(blocks []*Block) (reinserted, dropped int) {
+	var entries []MempoolEntry
+	for _, block := range blocks {
+		for i := range block.Transactions {
+			if block.Transactions[i].IsCoinbase() {
+				continue
+			}
+			tx := block.Transactions[i]
+			entries = append(entries, MempoolEntry{Transaction: &tx})
+		}
+	}
+
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	return tp.readmitLocked(entries, AdmissionSourceRollback)
+}
+
+// disconnectedBlocks returns the blocks of a chain that aren't part of its replacement, in
+// height order
+func disconnectedBlocks(old, replacement []*Block) []*Block {
+	kept := make(map[string]bool, len(replacement))
+	for _, block := range replacement {
+		kept[block.Hash] = true
+	}
+	var disconnected []*Block
+	for _, block := range old {
+		if !kept[block.Hash] {
+			disconnected = append(disconnected, block)
+		}
+	}
+	return disconnected
+}
+
+// RollbackReport describes the blocks a rollback removed and what became of their transactions
+type RollbackReport struct {
+	Removed    []*Block // Removed blocks in height order
+	Reinserted int      // Transactions returned to the pool
+	Dropped    int      // Transactions no longer valid against the rolled back chain
+}
+
+// checkRollback checks a chain with a tip can be rolled back to a height
+func checkRollback(height, tip int64, checkpoints []Checkpoint) error {
+	if height < 0 || height > tip {
+		return fmt.Errorf("rollback height %d outside the chain (tip %d)", height, tip)
+	}
+	for _, checkpoint := range checkpoints {
+		if checkpoint.Height > height {
+			return fmt.Errorf("cannot roll back below the checkpoint at height %d", checkpoint.Height)
+		}
+	}
+	return nil
+}
+
+// RollbackTo removes the blocks above a height from the chain and returns their transactions
+// to the pool after revalidation. Blocks at or below a checkpoint can't be removed.
+func (bc *Blockchain) RollbackTo(height int64) (*RollbackReport, error) {
+	if err := checkRollback(height, bc.GetLatestBlock().Index, bc.checkpoints); err != nil {
+		return nil, err
+	}
+
+	report := &RollbackReport{Removed: append([]*Block(nil), bc.Chain[height+1:]...)}
+	bc.Chain = bc.Chain[:height+1]
+	report.Reinserted, report.Dropped = bc.TransactionPool.ReinsertBlocks(report.Removed)
+	return report, nil
+}
+
+// RollbackTo removes the blocks above a height from the chain, rebuilding the database from the
+// remaining blocks, and returns their transactions to the pool after revalidation. Blocks at or
+// below a checkpoint can't be removed. Rolling back is supported on unpruned SQLite and LevelDB
+// databases.
+func (pbc *PersistentBlockchain) RollbackTo(height int64) (*RollbackReport, error) {
+	if pbc.dbConfig.Driver != "sqlite3" && pbc.dbConfig.Driver != "leveldb" {
+		return nil, fmt.Errorf("rolling back a %s database is not supported", pbc.dbConfig.Driver)
+	}
+	if err := checkRollback(height, pbc.GetLatestBlock().Index, pbc.checkpoints); err != nil {
+		return nil, err
+	}
+	if pbc.PrunedHeight() > 0 {
+		return nil, fmt.Errorf("%w, rolling back a pruned chain is not supported", ErrPruned)
+	}
+
+	report, err := pbc.replaceChain(append([]*Block(nil), pbc.Chain[:height+1]...))
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Rolled back %d blocks to height %d: %d transactions returned to the pool, %d dropped",
+		len(report.Removed), height, report.Reinserted, report.Dropped)
+	return report, nil
+}
@@ -0,0 +1,365 @@
+package blockchain
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// insertBatchRows is the most rows one INSERT statement writes, keeping the bound parameters
+// well within SQLite's limit of 999 per statement
+const insertBatchRows = 64
+
+// WriteConfig configures how a SQL database persists blocks
+type WriteConfig struct {
+	// Async makes SaveBlock queue blocks for a background writer instead of committing them
+	// before returning, taking the commit out of the mining path. Queued blocks are lost if
+	// the process dies: OnDurable reports when each one is committed. GetAddressBalance and
+	// the maintenance operations wait for the queue, other queries may lag behind it.
+	Async bool
+
+	MaxBatch  int // Queued blocks committed per database transaction (default 32)
+	QueueSize int // Blocks queued before SaveBlock waits for the writer (default 256)
+
+	// OnDurable is called by the writer once a queued block is committed, or with the error
+	// that stopped the writer; after a failure nothing more is written and SaveBlock fails.
+	// It must not call back into the database's write methods.
+	OnDurable func(block *Block, err error)
+}
+
+// SetWriteConfig switches between synchronous and asynchronous block saves. Blocks queued
+// under the previous configuration are written first; if that fails the error is returned
+// and the database is left synchronous.
+func (d *Database) SetWriteConfig(config WriteConfig) error {
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+
+	if d.writer != nil {
+		err := d.writer.stop()
+		d.writer = nil
+		if err != nil {
+			return err
+		}
+	}
+	if config.Async {
+		if config.MaxBatch <= 0 {
+			config.MaxBatch = 32
+		}
+		if config.QueueSize <= 0 {
+			config.QueueSize = 256
+		}
+		d.writer = newBlockWriter(d, config)
+	}
+	return nil
+}
+
+// Flush waits until every block queued by SaveBlock is committed, returning the error that
+// stopped the writer if any. Synchronous databases have nothing to flush.
+func (d *Database) Flush() error {
+	d.writeMu.RLock()
+	defer d.writeMu.RUnlock()
+	if d.writer == nil {
+		return nil
+	}
+	return d.writer.flush()
+}
+
+// blockRows are the rows a block adds to the database. They are built when the block is
+// saved, so a queued block is written as it was even if the chain later prunes it in memory.
+type blockRows struct {
+	block        *Block
+	hash         string
+	index        int64
+	txCount      int
+	blockRow     []interface{} // Row of the blocks table, nil when only derived rows are rebuilt
+	transactions [][]interface{}
+	ledger       [][]interface{}
+	addresses    [][]interface{} // Balance change and update count per address, in order of appearance
+	executed     []interface{}   // Hashes of the non-coinbase transactions, enhanced ones among them
+}
+
+// newBlockRows builds the rows of a block, including its row in the blocks table if withBlock
+func (d *Database) newBlockRows(block *Block, withBlock bool) (*blockRows, error) {
+	now := time.Now().Unix()
+	rows := &blockRows{block: block, hash: block.Hash, index: block.Index, txCount: len(block.Transactions)}
+
+	if withBlock {
+		blockData, err := d.encodeBlockData(block)
+		if err != nil {
+			return nil, err
+		}
+		rows.blockRow = []interface{}{block.Index, block.Hash, block.PrevHash, block.MerkleRoot,
+			block.Timestamp, block.Nonce, 4, // difficulty hardcoded for now
+			len(block.Transactions), blockData}
+	}
+
+	// Balance changes are summed per address, one update per address instead of per transaction
+	type addressChange struct {
+		change float64
+		count  int64
+	}
+	var order []string
+	changes := make(map[string]*addressChange)
+	credit := func(address string, amount float64) {
+		if changes[address] == nil {
+			changes[address] = &addressChange{}
+			order = append(order, address)
+		}
+		changes[address].change += amount
+		changes[address].count++
+	}
+
+	for i := range block.Transactions {
+		tx := &block.Transactions[i]
+		txData, err := json.Marshal(tx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize transaction: %w", err)
+		}
+		rows.transactions = append(rows.transactions, []interface{}{tx.Hash, block.Hash, block.Index, i,
+			tx.From, tx.To, tx.Amount, tx.Fee, now, string(txData)})
+		if !tx.IsCoinbase() {
+			rows.executed = append(rows.executed, tx.Hash)
+		}
+
+		// Record the balance changes by origin
+		for _, entry := range LedgerEntriesOf(tx, block.Index, i) {
+			rows.ledger = append(rows.ledger, []interface{}{entry.Address, entry.TxHash, entry.BlockIndex,
+				entry.TxIndex, string(entry.Source), entry.Amount, now})
+		}
+
+		credit(tx.From, -tx.debit(tx.From))
+		credit(tx.To, tx.Amount)
+		if tx.Sponsor != "" {
+			credit(tx.Sponsor, -tx.Fee)
+		}
+	}
+	for _, address := range order {
+		rows.addresses = append(rows.addresses, []interface{}{address, changes[address].change, changes[address].count, now, now})
+	}
+	return rows, nil
+}
+
+// writeBlockRows writes the rows of a block within a transaction
+func (d *Database) writeBlockRows(tx *sql.Tx, rows *blockRows) error {
+	if rows.blockRow != nil {
+		_, err := tx.Exec(d.rebind(`
+			INSERT INTO blocks (block_index, hash, previous_hash, merkle_root, timestamp, nonce, difficulty, transaction_count, block_data)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`), rows.blockRow...)
+		if err != nil {
+			return fmt.Errorf("failed to insert block: %w", err)
+		}
+	}
+
+	// Identical coinbase rewards share a hash, only the first is stored
+	err := d.insertRows(tx, "transactions (hash, block_hash, block_index, tx_index, from_address, to_address, amount, fee, timestamp, transaction_data)",
+		"ON CONFLICT(hash) DO NOTHING", rows.transactions)
+	if err != nil {
+		return fmt.Errorf("failed to save transactions: %w", err)
+	}
+	err = d.insertRows(tx, "ledger_entries (address, tx_hash, block_index, tx_index, source, amount, timestamp)",
+		"", rows.ledger)
+	if err != nil {
+		return fmt.Errorf("failed to save ledger entries: %w", err)
+	}
+	err = d.insertRows(tx, "addresses (address, balance, transaction_count, first_seen, last_updated)", `
+		ON CONFLICT(address) DO UPDATE SET
+			balance = addresses.balance + excluded.balance,
+			transaction_count = addresses.transaction_count + excluded.transaction_count,
+			last_updated = excluded.last_updated`, rows.addresses)
+	if err != nil {
+		return fmt.Errorf("failed to update address balances: %w", err)
+	}
+	if err := d.markEnhancedExecuted(tx, rows.executed); err != nil {
+		return fmt.Errorf("failed to mark enhanced transactions executed: %w", err)
+	}
+
+	if err := d.updateBlockchainState(tx, rows.hash, rows.index, rows.txCount); err != nil {
+		return fmt.Errorf("failed to update blockchain state: %w", err)
+	}
+	return nil
+}
+
+// saveBlockRows writes the rows of blocks in one transaction
+func (d *Database) saveBlockRows(blocks []*blockRows) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, rows := range blocks {
+		if err := d.writeBlockRows(tx, rows); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// insertRows inserts rows into a table with multi-row INSERT statements of up to
+// insertBatchRows rows, followed by suffix (e.g. a conflict clause)
+func (d *Database) insertRows(tx *sql.Tx, into, suffix string, rows [][]interface{}) error {
+	for start := 0; start < len(rows); start += insertBatchRows {
+		batch := rows[start:min(start+insertBatchRows, len(rows))]
+
+		placeholders := "(?" + strings.Repeat(", ?", len(batch[0])-1) + ")"
+		query := "INSERT INTO " + into + " VALUES " + placeholders + strings.Repeat(", "+placeholders, len(batch)-1) + " " + suffix
+		stmt, err := d.prepared(tx, d.rebind(query))
+		if err != nil {
+			return err
+		}
+
+		args := make([]interface{}, 0, len(batch)*len(batch[0]))
+		for _, row := range batch {
+			args = append(args, row...)
+		}
+		if _, err := stmt.Exec(args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepared returns a statement bound to a transaction, preparing the query once per database
+// so repeated block saves skip parsing it
+func (d *Database) prepared(tx *sql.Tx, query string) (*sql.Stmt, error) {
+	d.stmtMu.Lock()
+	defer d.stmtMu.Unlock()
+
+	stmt, exists := d.stmts[query]
+	if !exists {
+		var err error
+		if stmt, err = d.db.Prepare(query); err != nil {
+			return nil, err
+		}
+		if d.stmts == nil {
+			d.stmts = make(map[string]*sql.Stmt)
+		}
+		d.stmts[query] = stmt
+	}
+	return tx.Stmt(stmt), nil
+}
+
+// closeStatements closes the prepared statements
+func (d *Database) closeStatements() {
+	d.stmtMu.Lock()
+	defer d.stmtMu.Unlock()
+	for _, stmt := range d.stmts {
+		stmt.Close()
+	}
+	d.stmts = nil
+}
+
+// blockWrite is a queued block, or a flush marker closed once the blocks before it are written
+type blockWrite struct {
+	rows    *blockRows
+	flushed chan struct{}
+}
+
+// blockWriter commits queued blocks in the background, several per database transaction
+type blockWriter struct {
+	d      *Database
+	config WriteConfig
+	queue  chan blockWrite
+	done   chan struct{}
+
+	mu  sync.Mutex
+	err error // Failure that stopped the writer
+}
+
+// newBlockWriter starts a writer
+func newBlockWriter(d *Database, config WriteConfig) *blockWriter {
+	w := &blockWriter{
+		d:      d,
+		config: config,
+		queue:  make(chan blockWrite, config.QueueSize),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// enqueue queues a block, failing if the writer has stopped on an error
+func (w *blockWriter) enqueue(rows *blockRows) error {
+	if err := w.failure(); err != nil {
+		return fmt.Errorf("block writer stopped: %w", err)
+	}
+	w.queue <- blockWrite{rows: rows}
+	return nil
+}
+
+// flush waits for the blocks queued so far
+func (w *blockWriter) flush() error {
+	flushed := make(chan struct{})
+	w.queue <- blockWrite{flushed: flushed}
+	<-flushed
+	return w.failure()
+}
+
+// stop writes the queued blocks and ends the writer
+func (w *blockWriter) stop() error {
+	close(w.queue)
+	<-w.done
+	return w.failure()
+}
+
+// failure returns the error that stopped the writer
+func (w *blockWriter) failure() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// run commits queued blocks, batching those already waiting into one transaction
+func (w *blockWriter) run() {
+	defer close(w.done)
+
+	for write := range w.queue {
+		batch := []blockWrite{write}
+	collect:
+		for len(batch) < w.config.MaxBatch {
+			select {
+			case next, ok := <-w.queue:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, next)
+			default:
+				break collect
+			}
+		}
+		w.write(batch)
+	}
+}
+
+// write commits the blocks of a batch and releases its flush markers
+func (w *blockWriter) write(batch []blockWrite) {
+	var blocks []*blockRows
+	for _, write := range batch {
+		if write.rows != nil {
+			blocks = append(blocks, write.rows)
+		}
+	}
+
+	err := w.failure()
+	if err == nil && len(blocks) > 0 {
+		if err = w.d.saveBlockRows(blocks); err != nil {
+			w.mu.Lock()
+			w.err = err
+			w.mu.Unlock()
+		}
+	}
+
+	if w.config.OnDurable != nil {
+		for _, rows := range blocks {
+			w.config.OnDurable(rows.block, err)
+		}
+	}
+	for _, write := range batch {
+		if write.flushed != nil {
+			close(write.flushed)
+		}
+	}
+}
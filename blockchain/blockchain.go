@@ -2,6 +2,8 @@ package blockchain
 
 import (
 	"errors"
+	"fmt"
+	"time"
 )
 
 // Blockchain represents the blockchain
@@ -9,8 +11,25 @@ type Blockchain struct {
 	Chain            []*Block
 	Difficulty       int
 	TransactionPool  *TransactionPool
+	OrphanPool       *OrphanPool
+	Upgrades         *UpgradeSchedule
+	NetworkTime      *NetworkTime      // Optional peer-adjusted clock for block timestamps
+	Recorder         *WorkloadRecorder // Optional log of accepted transactions and blocks for replay
 	MiningReward     float64
 	MiningRewardAddr string
+	txIndex          *txIndex
+	policyIndex      *spendPolicyIndex
+	haltIndex        *haltIndex
+	history          *historyMMR
+	state            *stateTrie
+	filterHeaders    *filterHeaderChain
+	haltPolicy       *HaltPolicy
+	rewardPolicy     *RewardPolicy
+	feePolicy        *FeePolicy
+	extensions       *ExtensionRegistry
+	checkpoints      []Checkpoint
+	signedOnly       bool
+	params           *ChainParams
 }
 
 // NewBlockchain creates a new blockchain
@@ -19,9 +38,15 @@ func NewBlockchain(difficulty int, miningRewardAddr string) *Blockchain {
 		Chain:            []*Block{createGenesisBlock()},
 		Difficulty:       difficulty,
 		TransactionPool:  NewTransactionPool(1000), // Max 1000 pending transactions
+		OrphanPool:       NewOrphanPool(100, 20*time.Minute),
+		Upgrades:         &UpgradeSchedule{},
 		MiningReward:     10.0,
 		MiningRewardAddr: miningRewardAddr,
 	}
+	bc.TransactionPool.SetChainView(bc)
+	bc.TransactionPool.SetSpendPolicyView(bc)
+	bc.TransactionPool.SetHaltView(bc)
+	bc.TransactionPool.SetTipView(bc)
 	return bc
 }
 
@@ -37,12 +62,24 @@ func (bc *Blockchain) GetLatestBlock() *Block {
 
 // MinePendingTransactions mines pending transactions
 func (bc *Blockchain) MinePendingTransactions() {
-	// Create mining reward transaction
-	rewardTx := NewTransaction("network", bc.MiningRewardAddr, bc.MiningReward, 0)
-	bc.TransactionPool.AddTransaction(rewardTx)
+	// While the chain is halted only a block resuming it may be produced
+	_, halted := bc.GetChainHalt()
+	if halted && !hasResumeTransaction(bc.TransactionPool.GetTransactions()) {
+		return
+	}
+
+	// Create mining reward transaction (and cold storage sweeps)
+	addRewardTransactions(bc.TransactionPool, bc.rewardTransactions())
 
-	// Get transactions from pool
-	pendingTxs := bc.TransactionPool.GetTransactions()
+	// Drop transactions whose validity window ends before this block
+	timestamp := bc.adjustedTime()
+	bc.TransactionPool.DropExpiredAt(int64(len(bc.Chain)), timestamp)
+
+	// Get transactions from pool, each after the pending transactions it depends on
+	pendingTxs := bc.TransactionPool.SelectTransactions(MaxBlockTransactions)
+	if halted {
+		pendingTxs = haltBlockTransactions(pendingTxs)
+	}
 
 	// Convert []*Transaction to []Transaction
 	transactions := make([]Transaction, len(pendingTxs))
@@ -57,6 +94,12 @@ func (bc *Blockchain) MinePendingTransactions() {
 		bc.GetLatestBlock().Hash,
 	)
 
+	block.setVersion(bc.Upgrades.VersionAt(block.Index))
+	block.Timestamp = timestamp
+	block.MMRRoot = historyCommitment(bc.params, bc.historyRange(), block.Index)
+	block.StateRoot = stateCommitment(bc.params, bc.currentState(), block)
+	bc.extensions.produce(block)
+
 	// Mine the block
 	block.MineBlock(bc.Difficulty)
 
@@ -65,22 +108,197 @@ func (bc *Blockchain) MinePendingTransactions() {
 
 	// Remove mined transactions from pool
 	bc.TransactionPool.RemoveTransactions(pendingTxs)
+
+	if bc.Recorder != nil {
+		bc.Recorder.RecordBlock(block)
+	}
+}
+
+// AddBlock adds an externally mined block to the chain.
+// Blocks whose parent is not known yet are held in the orphan pool, reported with
+// ErrOrphanBlock, and connected automatically once the missing parent arrives.
+func (bc *Blockchain) AddBlock(block *Block) error {
+	if bc.hasBlock(block.Hash) || bc.OrphanPool.HasOrphan(block.Hash) {
+		return ErrBlockKnown
+	}
+
+	if !bc.hasBlock(block.PrevHash) {
+		if err := bc.OrphanPool.AddOrphan(block, bc.Difficulty); err != nil {
+			return err
+		}
+		return ErrOrphanBlock
+	}
+
+	if err := bc.connectBlock(block); err != nil {
+		return err
+	}
+
+	// Connect any orphans that were waiting on this block
+	parents := []string{block.Hash}
+	for len(parents) > 0 {
+		parentHash := parents[0]
+		parents = parents[1:]
+
+		for _, orphan := range bc.OrphanPool.TakeChildren(parentHash) {
+			if err := bc.connectBlock(orphan); err != nil {
+				continue
+			}
+			parents = append(parents, orphan.Hash)
+		}
+	}
+
+	return nil
+}
+
+// connectBlock validates a block against the chain tip and appends it
+func (bc *Blockchain) connectBlock(block *Block) error {
+	latest := bc.GetLatestBlock()
+	if block.PrevHash != latest.Hash {
+		return ErrNotChainTip
+	}
+
+	if err := bc.validateBlock(block, latest); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidBlock, err)
+	}
+
+	bc.Chain = append(bc.Chain, block)
+
+	// Drop transactions confirmed by this block from the pool
+	confirmed := make([]*Transaction, len(block.Transactions))
+	for i := range block.Transactions {
+		confirmed[i] = &block.Transactions[i]
+	}
+	bc.TransactionPool.RemoveTransactions(confirmed)
+
+	if bc.Recorder != nil {
+		bc.Recorder.RecordBlock(block)
+	}
+
+	return nil
+}
+
+// validateBlock checks a block extending the chain tip against the consensus rules
+func (bc *Blockchain) validateBlock(block, latest *Block) error {
+	if err := CheckBlockLimits(block); err != nil {
+		return err
+	}
+
+	if err := block.validateAgainstParent(latest, bc.Difficulty); err != nil {
+		return err
+	}
+
+	if block.Timestamp > bc.adjustedTime()+MaxFutureBlockTime {
+		return errors.New("block timestamp is too far in the future")
+	}
+
+	if err := bc.Upgrades.ValidateBlock(block); err != nil {
+		return err
+	}
+
+	if err := checkMMRRoot(block, bc.params, bc.historyRange()); err != nil {
+		return err
+	}
+
+	if err := checkStateRoot(block, bc.params, bc.currentState()); err != nil {
+		return err
+	}
+
+	if err := bc.extensions.ValidateBlock(block); err != nil {
+		return err
+	}
+
+	if err := bc.feePolicy.ValidateBlock(block); err != nil {
+		return err
+	}
+
+	if err := checkBlockAddresses(block, bc.params); err != nil {
+		return err
+	}
+
+	if err := checkBlockSignatures(block, bc.signedOnly, bc.Upgrades, bc.params); err != nil {
+		return err
+	}
+
+	if err := checkBlockExpiry(block); err != nil {
+		return err
+	}
+
+	if err := checkDoubleSpends(block, bc); err != nil {
+		return err
+	}
+
+	if err := bc.spendPolicies().checkBlock(block); err != nil {
+		return err
+	}
+
+	if err := bc.halts().checkBlock(block, bc.haltPolicy); err != nil {
+		return err
+	}
+	return nil
+}
+
+// adjustedTime returns the current time, corrected by the network time offset if available
+func (bc *Blockchain) adjustedTime() int64 {
+	if bc.NetworkTime != nil {
+		return bc.NetworkTime.Now()
+	}
+	return time.Now().Unix()
+}
+
+// hasBlock checks whether a block with the given hash is part of the chain
+func (bc *Blockchain) hasBlock(hash string) bool {
+	for i := len(bc.Chain) - 1; i >= 0; i-- {
+		if bc.Chain[i].Hash == hash {
+			return true
+		}
+	}
+	return false
 }
 
 // AddTransaction adds a new transaction to the transaction pool
 func (bc *Blockchain) AddTransaction(tx *Transaction) error {
-	return bc.TransactionPool.AddTransaction(tx)
+	return bc.AddTransactionFrom(tx, AdmissionSourceLocal)
+}
+
+// AddTransactionFrom adds a new transaction to the transaction pool, recording its source in the admission log
+func (bc *Blockchain) AddTransactionFrom(tx *Transaction, source string) error {
+	if err := bc.TransactionPool.AddTransactionFrom(tx, source); err != nil {
+		return err
+	}
+	if bc.Recorder != nil {
+		bc.Recorder.RecordTransaction(tx)
+	}
+	return nil
+}
+
+// AddTransactions adds a batch of transactions to the transaction pool, returning the outcome
+// of every transaction at its index (nil for those accepted)
+func (bc *Blockchain) AddTransactions(txs []*Transaction) []error {
+	return bc.AddTransactionsFrom(txs, AdmissionSourceLocal)
+}
+
+// AddTransactionsFrom adds a batch of transactions to the transaction pool in one critical
+// section, recording their source in the admission log
+func (bc *Blockchain) AddTransactionsFrom(txs []*Transaction, source string) []error {
+	errs := bc.TransactionPool.AddTransactionsFrom(txs, source)
+	if bc.Recorder != nil {
+		for i, tx := range txs {
+			if errs[i] == nil {
+				bc.Recorder.RecordTransaction(tx)
+			}
+		}
+	}
+	return errs
 }
 
-// GetBalance calculates the balance of an address
+// GetBalance calculates the balance of an address: what it received minus what it sent and
+// the fees it paid, like the state trie
 func (bc *Blockchain) GetBalance(address string) float64 {
 	var balance float64
 
 	for _, block := range bc.Chain {
 		for _, tx := range block.Transactions {
-			if tx.From == address {
-				balance -= tx.Amount
-			}
+			balance -= tx.debit(address)
 			if tx.To == address {
 				balance += tx.Amount
 			}
@@ -90,8 +308,34 @@ func (bc *Blockchain) GetBalance(address string) float64 {
 	return balance
 }
 
+// GetPendingTransaction returns a transaction waiting in the pool
+func (bc *Blockchain) GetPendingTransaction(hash string) (*Transaction, bool) {
+	return bc.TransactionPool.GetTransaction(hash)
+}
+
+// GetReservedBalance returns the amount an address has committed to pending transactions
+func (bc *Blockchain) GetReservedBalance(address string) float64 {
+	return bc.TransactionPool.GetReservedBalance(address)
+}
+
+// GetAvailableBalance returns the balance of an address minus its pending spends
+func (bc *Blockchain) GetAvailableBalance(address string) float64 {
+	return bc.GetBalance(address) - bc.TransactionPool.GetReservedBalance(address)
+}
+
 // IsChainValid verifies if the blockchain is valid (now includes Merkle tree validation)
 func (bc *Blockchain) IsChainValid() bool {
+	spends := newSpendTracker()
+	policies := newSpendPolicyIndex()
+	history := newHistoryMMR()
+	state := newStateTrie(nil)
+	if len(bc.Chain) > 0 {
+		spends.addBlock(bc.Chain[0])
+		policies.addBlock(bc.Chain[0])
+		history.append(bc.Chain[0].Hash)
+		state.addBlock(bc.Chain[0])
+	}
+
 	for i := 1; i < len(bc.Chain); i++ {
 		currentBlock := bc.Chain[i]
 		previousBlock := bc.Chain[i-1]
@@ -106,19 +350,129 @@ func (bc *Blockchain) IsChainValid() bool {
 			return false
 		}
 
+		// Verify the committed history root
+		if checkMMRRoot(currentBlock, bc.params, history) != nil {
+			return false
+		}
+		history.append(currentBlock.Hash)
+
 		// Verify Merkle tree integrity
 		if !currentBlock.ValidateTransactions() {
 			return false
 		}
+		if currentBlock.validateKVRoot() != nil {
+			return false
+		}
+		if currentBlock.validateReceiptRoot() != nil {
+			return false
+		}
+		if currentBlock.validateBloom() != nil {
+			return false
+		}
+		if currentBlock.validateExtensions() != nil {
+			return false
+		}
+
+		// Verify protocol upgrade rules
+		if bc.Upgrades.ValidateBlock(currentBlock) != nil {
+			return false
+		}
+
+		// Verify attached signatures, required from a RequireSignatures upgrade on (blocks predating
+		// SetRequireSignatures may hold unsigned transactions)
+		if checkBlockSignatures(currentBlock, false, bc.Upgrades, bc.params) != nil {
+			return false
+		}
+
+		// Verify no transaction confirmed past its validity window
+		if checkBlockExpiry(currentBlock) != nil {
+			return false
+		}
+
+		// Verify no transaction is replayed or reuses a sender nonce
+		if checkDoubleSpends(currentBlock, spends) != nil {
+			return false
+		}
+		spends.addBlock(currentBlock)
+
+		// Verify the committed state root
+		if checkStateRoot(currentBlock, bc.params, state) != nil {
+			return false
+		}
+		state.addBlock(currentBlock)
+
+		// Verify spends respect the spend policies in force
+		if policies.checkBlock(currentBlock) != nil {
+			return false
+		}
+		policies.addBlock(currentBlock)
 	}
 
 	return true
 }
 
+// IsTransactionConfirmed checks if a non-coinbase transaction is included in the chain
+func (bc *Blockchain) IsTransactionConfirmed(hash string) bool {
+	tx, _, err := bc.GetTransaction(hash)
+	return err == nil && !tx.IsCoinbase()
+}
+
+// IsAddressUsed checks if an address has sent or received a confirmed transaction
+func (bc *Blockchain) IsAddressUsed(address string) bool {
+	for _, block := range bc.Chain {
+		for _, tx := range block.Transactions {
+			if tx.From == address || tx.To == address {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetConfirmedNonce returns the highest nonce an address has used in the chain
+func (bc *Blockchain) GetConfirmedNonce(address string) int64 {
+	var nonce int64
+	for _, block := range bc.Chain {
+		for _, tx := range block.Transactions {
+			if tx.From == address && tx.Nonce > nonce {
+				nonce = tx.Nonce
+			}
+		}
+	}
+	return nonce
+}
+
+// GetBlockByIndex returns the block at the given height
+func (bc *Blockchain) GetBlockByIndex(index int64) (*Block, error) {
+	if index < 0 || index >= int64(len(bc.Chain)) {
+		return nil, ErrBlockNotFound
+	}
+	return bc.Chain[index], nil
+}
+
+// GetHeader returns the header of the block at the given height
+func (bc *Blockchain) GetHeader(index int64) (BlockHeader, error) {
+	block, err := bc.GetBlockByIndex(index)
+	if err != nil {
+		return BlockHeader{}, err
+	}
+	return block.Header(), nil
+}
+
+// GetBlockByHash returns the block with the given hash
+func (bc *Blockchain) GetBlockByHash(hash string) (*Block, error) {
+	for _, block := range bc.Chain {
+		if block.Hash == hash {
+			return block, nil
+		}
+	}
+	return nil, ErrBlockNotFound
+}
+
 // GetTransactionProof generates a Merkle proof for a transaction in a specific block
 func (bc *Blockchain) GetTransactionProof(blockIndex int, txHash string) (*MerkleProof, error) {
 	if blockIndex < 0 || blockIndex >= len(bc.Chain) {
-		return nil, errors.New("invalid block index")
+		return nil, fmt.Errorf("invalid block index: %w", ErrBlockNotFound)
 	}
 
 	block := bc.Chain[blockIndex]
@@ -134,3 +488,22 @@ func (bc *Blockchain) VerifyTransactionInBlock(blockIndex int, proof *MerkleProo
 	block := bc.Chain[blockIndex]
 	return block.VerifyTransactionProof(proof)
 }
+
+// GetKVProof proves the latest value set for a key of a namespace, from the block that set it
+func (bc *Blockchain) GetKVProof(namespace, key string) (*KVProof, error) {
+	for i := len(bc.Chain) - 1; i >= 0; i-- {
+		if proof, err := bc.Chain[i].GenerateKVProof(namespace, key); err == nil {
+			return proof, nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+// GetKVValue returns the latest value set for a key of a namespace
+func (bc *Blockchain) GetKVValue(namespace, key string) (string, bool) {
+	proof, err := bc.GetKVProof(namespace, key)
+	if err != nil {
+		return "", false
+	}
+	return proof.Entry.Value, true
+}
@@ -0,0 +1,21 @@
+package blockchain
+
+import "testing"
+
+func TestGetBalanceCountsFees(t *testing.T) {
+	bc := NewBlockchain(1, "miner")
+	bc.MinePendingTransactions()
+	if err := bc.AddTransaction(NewTransaction("miner", "bob", 2, 0.5)); err != nil {
+		t.Fatal(err)
+	}
+	bc.MinePendingTransactions()
+
+	for address, expected := range map[string]float64{"miner": 2*bc.MiningReward - 2.5, "bob": 2} {
+		if balance := bc.GetBalance(address); balance != expected {
+			t.Errorf("%s has balance %v, expected %v", address, balance, expected)
+		}
+		if account := bc.currentState().accounts[address]; account.Balance != expected {
+			t.Errorf("%s has state balance %v, expected %v", address, account.Balance, expected)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+)
+
+// maxPooledBuffer is the largest buffer returned to a pool; the rare huge message shouldn't
+// pin its memory for the life of the process
+const maxPooledBuffer = 1 << 20
+
+// hashScratch is reusable space for hashing: the bytes being hashed and the hex digest
+type hashScratch struct {
+	buf []byte
+	hex [2 * sha256.Size]byte
+}
+
+// hashScratches pools hashing scratch space for the miner and the Merkle builder
+var hashScratches = sync.Pool{
+	New: func() interface{} { return &hashScratch{buf: make([]byte, 0, 512)} },
+}
+
+// getHashScratch takes scratch space from the pool
+func getHashScratch() *hashScratch {
+	return hashScratches.Get().(*hashScratch)
+}
+
+// putHashScratch returns scratch space to the pool
+func putHashScratch(s *hashScratch) {
+	if cap(s.buf) <= maxPooledBuffer {
+		hashScratches.Put(s)
+	}
+}
+
+// sum hashes the scratch buffer, returning the hex digest in the scratch space
+func (s *hashScratch) sum() []byte {
+	digest := sha256.Sum256(s.buf)
+	hex.Encode(s.hex[:], digest[:])
+	return s.hex[:]
+}
+
+// nodeHash computes verify.NodeHash without building the concatenated children
+func (s *hashScratch) nodeHash(leftHash, rightHash string) string {
+	s.buf = append(append(s.buf[:0], leftHash...), rightHash...)
+	return string(s.sum())
+}
+
+// taggedNodeHash computes verify.TaggedNodeHash without building the concatenated children
+func (s *hashScratch) taggedNodeHash(leftHash, rightHash string) string {
+	s.buf = append(append(append(s.buf[:0], 1), leftHash...), rightHash...)
+	return string(s.sum())
+}
+
+// encodeBuffer is a reusable buffer with a JSON encoder writing into it
+type encodeBuffer struct {
+	bytes.Buffer
+	encoder *json.Encoder
+}
+
+// encodeBuffers pools the buffers P2P messages are encoded into
+var encodeBuffers = sync.Pool{
+	New: func() interface{} {
+		b := &encodeBuffer{}
+		b.encoder = json.NewEncoder(&b.Buffer)
+		return b
+	},
+}
+
+// getEncodeBuffer takes an empty buffer from the pool
+func getEncodeBuffer() *encodeBuffer {
+	b := encodeBuffers.Get().(*encodeBuffer)
+	b.Reset()
+	return b
+}
+
+// putEncodeBuffer returns a buffer to the pool
+func putEncodeBuffer(b *encodeBuffer) {
+	if b.Cap() <= maxPooledBuffer {
+		encodeBuffers.Put(b)
+	}
+}
+
+// encodeMessage appends a message to the buffer as one line of JSON, as a json.Encoder
+// writes a Message, without copying the payload through an intermediate RawMessage
+func (b *encodeBuffer) encodeMessage(msgType string, payload interface{}) error {
+	b.WriteString(`{"type":`)
+	if err := b.value(msgType); err != nil {
+		return err
+	}
+	if payload != nil {
+		b.WriteString(`,"payload":`)
+		if err := b.value(payload); err != nil {
+			return err
+		}
+	}
+	b.WriteString("}\n")
+	return nil
+}
+
+// value appends a JSON value, dropping the newline the encoder ends it with
+func (b *encodeBuffer) value(v interface{}) error {
+	if err := b.encoder.Encode(v); err != nil {
+		return err
+	}
+	b.Truncate(b.Len() - 1)
+	return nil
+}
@@ -0,0 +1,211 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BackupBlockchain writes a consistent snapshot of the chain to a path: a JSON array of the
+// blocks, readable by ImportBlocks and RestoreFromBackup, if the path ends in ".json", and a
+// copy of the database otherwise (a SQLite file, or a directory for LevelDB)
+func (pbc *PersistentBlockchain) BackupBlockchain(backupPath string) error {
+	if strings.HasSuffix(backupPath, ".json") {
+		if err := writeBlocksFile(backupPath, pbc.Chain); err != nil {
+			return fmt.Errorf("failed to export blocks: %w", err)
+		}
+	} else if err := pbc.Database.Backup(backupPath); err != nil {
+		return err
+	}
+	log.Printf("Backed up %d blocks to %s", len(pbc.Chain), backupPath)
+	return nil
+}
+
+// RestoreFromBackup replaces the chain with one read from a backup written by BackupBlockchain
+// or Backup. The restored chain must share the genesis block and pass validation before the
+// database is rebuilt from its blocks and swapped in. Peer, ban and admission records are not
+// restored. Restoring is supported on SQLite and LevelDB databases.
+func (pbc *PersistentBlockchain) RestoreFromBackup(backupPath string) error {
+	if pbc.dbConfig.Driver != "sqlite3" && pbc.dbConfig.Driver != "leveldb" {
+		return fmt.Errorf("restoring a %s database is not supported", pbc.dbConfig.Driver)
+	}
+
+	chain, err := readBackupBlocks(backupPath)
+	if err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+	if err := pbc.validateRestoredChain(chain); err != nil {
+		return fmt.Errorf("backup rejected: %w", err)
+	}
+
+	report, err := pbc.replaceChain(chain)
+	if err != nil {
+		return err
+	}
+	if len(report.Removed) > 0 {
+		log.Printf("Returned %d transactions of %d blocks missing from the backup to the pool, %d dropped",
+			report.Reinserted, len(report.Removed), report.Dropped)
+	}
+
+	log.Printf("Restored blockchain with %d blocks from %s", len(chain), backupPath)
+	return nil
+}
+
+// replaceChain rebuilds the database from a validated chain, swaps it in and updates the pool:
+// transactions the chain confirms leave it, those of the blocks it lacks are returned to it
+func (pbc *PersistentBlockchain) replaceChain(chain []*Block) (*RollbackReport, error) {
+	// Build the restored database next to the live one, so a failure leaves the live one untouched
+	staging := pbc.dbConfig
+	staging.Path = pbc.dbConfig.Path + ".restore"
+	if err := removeDatabaseFiles(staging.Path); err != nil {
+		return nil, err
+	}
+	storage, err := OpenStorage(staging)
+	if err != nil {
+		return nil, err
+	}
+	for _, block := range chain {
+		if err := storage.SaveBlock(block); err != nil {
+			storage.Close()
+			removeDatabaseFiles(staging.Path)
+			return nil, fmt.Errorf("failed to save restored block %d: %w", block.Index, err)
+		}
+	}
+	if err := storage.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := pbc.Database.Close(); err != nil {
+		log.Printf("Warning: failed to close database before restore: %v", err)
+	}
+	if err := removeDatabaseFiles(pbc.dbConfig.Path); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(staging.Path, pbc.dbConfig.Path); err != nil {
+		return nil, fmt.Errorf("failed to swap in restored database: %w", err)
+	}
+	storage, err = OpenStorage(pbc.dbConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reopen restored database: %w", err)
+	}
+
+	disconnected := disconnectedBlocks(pbc.Chain, chain)
+	pbc.Database = storage
+	pbc.Chain = chain
+
+	// Drop pending transactions the chain confirms
+	var confirmed []*Transaction
+	for _, block := range chain {
+		for i := range block.Transactions {
+			confirmed = append(confirmed, &block.Transactions[i])
+		}
+	}
+	pbc.TransactionPool.RemoveTransactions(confirmed)
+
+	// Return the transactions of blocks the chain lacks to the pool
+	report := &RollbackReport{Removed: disconnected}
+	report.Reinserted, report.Dropped = pbc.TransactionPool.ReinsertBlocks(disconnected)
+	return report, nil
+}
+
+// validateRestoredChain checks a chain read from a backup can replace the current one
+func (pbc *PersistentBlockchain) validateRestoredChain(chain []*Block) error {
+	if len(chain) == 0 {
+		return errors.New("no blocks found in backup")
+	}
+	if chain[0].Hash != pbc.Chain[0].Hash {
+		return errors.New("backup is of a different chain (genesis block mismatch)")
+	}
+	for i, block := range chain {
+		if block.Index != int64(i) {
+			return fmt.Errorf("block %d stored at position %d", block.Index, i)
+		}
+		if block.Pruned {
+			return fmt.Errorf("block %d: %w, restoring a pruned backup is not supported", block.Index, ErrPruned)
+		}
+	}
+
+	tempBC := &PersistentBlockchain{Chain: chain, Upgrades: pbc.Upgrades, params: pbc.params}
+	if !tempBC.IsChainValid() {
+		return errors.New("restored blockchain is invalid")
+	}
+	return nil
+}
+
+// readBackupBlocks loads the blocks of a backup: a JSON array of blocks, a LevelDB directory or a SQLite file
+func readBackupBlocks(path string) ([]*Block, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasSuffix(path, ".json") {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var raw []json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("malformed block export: %w", err)
+		}
+		blocks := make([]*Block, len(raw))
+		for i, data := range raw {
+			if blocks[i], err = DecodeBlock(data); err != nil {
+				return nil, fmt.Errorf("block #%d: %w", i, err)
+			}
+		}
+		return blocks, nil
+	}
+
+	config := DatabaseConfig{Driver: "sqlite3", Path: path}
+	if info.IsDir() {
+		config.Driver = "leveldb"
+	}
+	storage, err := OpenStorage(config)
+	if err != nil {
+		return nil, err
+	}
+	defer storage.Close()
+	return storage.LoadBlockchain()
+}
+
+// writeBlocksFile writes blocks as a JSON array, replacing the file atomically
+func writeBlocksFile(path string, blocks []*Block) error {
+	data, err := json.Marshal(blocks)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// removeDatabaseFiles deletes a SQLite database with its journal files, or a LevelDB directory
+func removeDatabaseFiles(path string) error {
+	for _, name := range []string{path, path + "-wal", path + "-shm", path + "-journal"} {
+		if err := os.RemoveAll(name); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package blockchain
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// chainExportMagic starts a binary chain export; its last byte is the format version
+var chainExportMagic = []byte("BCHAIN\x00\x01")
+
+// ChainImportReport summarizes a chain import
+type ChainImportReport struct {
+	Imported int `json:"imported"` // Blocks validated and connected
+	Known    int `json:"known"`    // Blocks the chain already had
+}
+
+// ExportChain writes the chain from genesis as a portable binary stream: a magic header, then
+// every block in JSON prefixed by its length as a big-endian uint32. ImportChain reads it back.
+func (bc *Blockchain) ExportChain(w io.Writer) error {
+	return exportChain(w, bc.Chain, false)
+}
+
+// ExportChainJSONL writes the chain from genesis as JSON lines, one block per line
+func (bc *Blockchain) ExportChainJSONL(w io.Writer) error {
+	return exportChain(w, bc.Chain, true)
+}
+
+// ImportChain reads a chain export in either format and connects the blocks the chain doesn't
+// have yet, validating each like a block from a peer. Blocks it already has are skipped; the
+// import stops at the first block that conflicts with the chain or fails validation.
+func (bc *Blockchain) ImportChain(r io.Reader) (*ChainImportReport, error) {
+	return importChain(r, bc.Chain, bc.connectBlock)
+}
+
+// ExportChain writes the chain from genesis as a portable binary stream: a magic header, then
+// every block in JSON prefixed by its length as a big-endian uint32. ImportChain reads it back.
+// A pruned chain can't be exported, its pruned blocks have no transactions to validate.
+func (pbc *PersistentBlockchain) ExportChain(w io.Writer) error {
+	return exportChain(w, pbc.Chain, false)
+}
+
+// ExportChainJSONL writes the chain from genesis as JSON lines, one block per line
+func (pbc *PersistentBlockchain) ExportChainJSONL(w io.Writer) error {
+	return exportChain(w, pbc.Chain, true)
+}
+
+// ImportChain reads a chain export in either format and connects and persists the blocks the
+// chain doesn't have yet, validating each like a block from a peer, e.g. to seed a fresh
+// database. Blocks it already has are skipped; the import stops at the first block that
+// conflicts with the chain or fails validation.
+func (pbc *PersistentBlockchain) ImportChain(r io.Reader) (*ChainImportReport, error) {
+	return importChain(r, pbc.Chain, pbc.connectBlock)
+}
+
+// exportChain writes blocks in the binary or JSON lines format
+func exportChain(w io.Writer, chain []*Block, jsonl bool) error {
+	for _, block := range chain {
+		if block.Pruned {
+			return fmt.Errorf("cannot export block %d: %w", block.Index, ErrPruned)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	if !jsonl {
+		if _, err := bw.Write(chainExportMagic); err != nil {
+			return err
+		}
+	}
+	for _, block := range chain {
+		data, err := json.Marshal(block)
+		if err != nil {
+			return fmt.Errorf("failed to serialize block %d: %w", block.Index, err)
+		}
+		if jsonl {
+			data = append(data, '\n')
+		} else {
+			var length [4]byte
+			binary.BigEndian.PutUint32(length[:], uint32(len(data)))
+			if _, err := bw.Write(length[:]); err != nil {
+				return err
+			}
+		}
+		if _, err := bw.Write(data); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// importChain connects the blocks of an export that extend the chain. chain is the local chain
+// before the import; connect validates a block against the tip and appends it.
+func importChain(r io.Reader, chain []*Block, connect func(*Block) error) (*ChainImportReport, error) {
+	report := &ChainImportReport{}
+	reader, err := newChainReader(r)
+	if err != nil {
+		return report, err
+	}
+
+	for {
+		block, err := reader.next()
+		if err == io.EOF {
+			return report, nil
+		}
+		if err != nil {
+			return report, err
+		}
+
+		if block.Index >= 0 && block.Index < int64(len(chain)) {
+			if local := chain[block.Index]; local.Hash != block.Hash {
+				if block.Index == 0 {
+					return report, errors.New("export is of a different chain (genesis block mismatch)")
+				}
+				return report, fmt.Errorf("block %d conflicts with the local chain", block.Index)
+			}
+			report.Known++
+			continue
+		}
+		if err := connect(block); err != nil {
+			return report, fmt.Errorf("block %d rejected: %w", block.Index, err)
+		}
+		report.Imported++
+	}
+}
+
+// chainReader reads the blocks of a chain export, detecting its format
+type chainReader struct {
+	binary  bool
+	reader  *bufio.Reader
+	scanner *bufio.Scanner
+}
+
+// newChainReader detects the format of an export from its first bytes
+func newChainReader(r io.Reader) (*chainReader, error) {
+	reader := bufio.NewReader(r)
+	head, err := reader.Peek(len(chainExportMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if bytes.Equal(head, chainExportMagic) {
+		reader.Discard(len(chainExportMagic))
+		return &chainReader{binary: true, reader: reader}, nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxBlockSize+1)
+	return &chainReader{scanner: scanner}, nil
+}
+
+// next returns the next block, or io.EOF at the end of the export
+func (cr *chainReader) next() (*Block, error) {
+	if !cr.binary {
+		for cr.scanner.Scan() {
+			if line := bytes.TrimSpace(cr.scanner.Bytes()); len(line) > 0 {
+				return DecodeBlock(line)
+			}
+		}
+		if err := cr.scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read export: %w", err)
+		}
+		return nil, io.EOF
+	}
+
+	var length [4]byte
+	if _, err := io.ReadFull(cr.reader, length[:]); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, errors.New("export is truncated")
+	}
+	size := binary.BigEndian.Uint32(length[:])
+	if size > MaxBlockSize {
+		return nil, &DecodeError{Kind: "block", Err: &LimitError{Field: "block", Size: int(size), Limit: MaxBlockSize}}
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(cr.reader, data); err != nil {
+		return nil, errors.New("export is truncated")
+	}
+	return DecodeBlock(data)
+}
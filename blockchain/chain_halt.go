@@ -0,0 +1,398 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// HaltAddress is the recipient of transactions halting or resuming the chain
+const HaltAddress = "halt"
+
+// Chain halt actions
+const (
+	HaltActionHalt   = "halt"
+	HaltActionResume = "resume"
+)
+
+// ErrChainHalted is returned when a block or transaction is refused because the chain is halted
+var ErrChainHalted = errors.New("chain is halted")
+
+// HaltPolicy names the authorities that may halt and resume the chain, e.g. the operators
+// of a consortium. Every node of the network must configure the same policy, since halt
+// transactions in blocks are validated against it.
+type HaltPolicy struct {
+	Authorities []string // Hex PKIX public keys allowed to sign halts and resumes
+	Required    int      // Signatures needed to halt or resume
+}
+
+// Validate checks the policy configuration
+func (hp *HaltPolicy) Validate() error {
+	if hp.Required < 1 {
+		return errors.New("halt policy must require at least one signature")
+	}
+	if hp.Required > len(hp.Authorities) {
+		return errors.New("halt policy requires more signatures than it has authorities")
+	}
+
+	seen := make(map[string]bool)
+	for _, authority := range hp.Authorities {
+		if _, err := parsePublicKeyHex(authority); err != nil {
+			return fmt.Errorf("invalid halt authority key: %w", err)
+		}
+		if seen[authority] {
+			return errors.New("duplicate halt authority key")
+		}
+		seen[authority] = true
+	}
+	return nil
+}
+
+// ChainHalt is an emergency halt recorded on chain. From its height on, no block is produced
+// and no transaction admitted until a resume transaction is mined or the resume time passes.
+type ChainHalt struct {
+	Height   int64  `json:"height"`             // First height refused
+	ResumeAt int64  `json:"resumeAt,omitempty"` // Unix time production resumes on its own (0 waits for a resume transaction)
+	Reason   string `json:"reason,omitempty"`
+	Block    int64  `json:"block"` // Height of the block that recorded the halt
+}
+
+// haltsAt reports whether the halt stops a block at a height with a timestamp
+func (h *ChainHalt) haltsAt(height, timestamp int64) bool {
+	return h != nil && height >= h.Height && (h.ResumeAt == 0 || timestamp < h.ResumeAt)
+}
+
+// haltPayload is the transaction payload of a halt or resume
+type haltPayload struct {
+	Action     string   `json:"action"`
+	Height     int64    `json:"height,omitempty"`
+	ResumeAt   int64    `json:"resumeAt,omitempty"`
+	Reason     string   `json:"reason,omitempty"`
+	Signatures []string `json:"signatures"` // Authority signatures (hex ASN.1)
+}
+
+// NewHaltTransaction creates a transaction halting the chain at a height, signed by halt authorities.
+// A height at or below the block that records it halts the chain from the next block.
+func NewHaltTransaction(from string, halt ChainHalt, authorities []*Wallet, fee float64, nonce int64) (*Transaction, error) {
+	payload := haltPayload{Action: HaltActionHalt, Height: halt.Height, ResumeAt: halt.ResumeAt, Reason: halt.Reason}
+	return newHaltTransaction(from, payload, authorities, fee, nonce)
+}
+
+// NewResumeTransaction creates a transaction lifting the active halt, signed by halt authorities
+func NewResumeTransaction(from string, authorities []*Wallet, fee float64, nonce int64) (*Transaction, error) {
+	return newHaltTransaction(from, haltPayload{Action: HaltActionResume}, authorities, fee, nonce)
+}
+
+// newHaltTransaction signs a halt payload and wraps it in a transaction
+func newHaltTransaction(from string, payload haltPayload, authorities []*Wallet, fee float64, nonce int64) (*Transaction, error) {
+	if nonce <= 0 {
+		return nil, errors.New("halt transactions require a nonce")
+	}
+	message := haltMessage(from, nonce, &payload)
+	for _, authority := range authorities {
+		signature, err := signPolicyMessage(authority, message)
+		if err != nil {
+			return nil, err
+		}
+		payload.Signatures = append(payload.Signatures, signature)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	tx := &Transaction{
+		From:  from,
+		To:    HaltAddress,
+		Fee:   fee,
+		Nonce: nonce,
+		Data:  data,
+	}
+	if err := validateHaltTransaction(tx); err != nil {
+		return nil, err
+	}
+	tx.Hash = tx.calculateHash()
+	return tx, nil
+}
+
+// parseHaltPayload decodes and structurally checks the payload of a halt transaction
+func parseHaltPayload(tx *Transaction) (*haltPayload, error) {
+	if tx.Amount != 0 {
+		return nil, fmt.Errorf("%w: halt transactions cannot transfer an amount", ErrInvalidTransaction)
+	}
+	if tx.Nonce <= 0 {
+		return nil, fmt.Errorf("%w: halt transactions require a nonce", ErrInvalidTransaction)
+	}
+
+	var payload haltPayload
+	if err := json.Unmarshal(tx.Data, &payload); err != nil {
+		return nil, fmt.Errorf("invalid halt payload: %w", err)
+	}
+
+	switch payload.Action {
+	case HaltActionHalt:
+		if payload.Height < 0 || payload.ResumeAt < 0 {
+			return nil, errors.New("invalid halt payload: height and resume time cannot be negative")
+		}
+	case HaltActionResume:
+		if payload.Height != 0 || payload.ResumeAt != 0 || payload.Reason != "" {
+			return nil, errors.New("invalid halt payload: resumption carries no halt")
+		}
+	default:
+		return nil, fmt.Errorf("invalid halt payload: unknown action %q", payload.Action)
+	}
+	if len(payload.Signatures) == 0 {
+		return nil, errors.New("invalid halt payload: no authority signatures")
+	}
+	return &payload, nil
+}
+
+// validateHaltTransaction checks the structure of a halt transaction
+func validateHaltTransaction(tx *Transaction) error {
+	_, err := parseHaltPayload(tx)
+	return err
+}
+
+// isResumeTransaction reports whether a transaction resumes the chain
+func isResumeTransaction(tx *Transaction) bool {
+	if tx.To != HaltAddress {
+		return false
+	}
+	payload, err := parseHaltPayload(tx)
+	return err == nil && payload.Action == HaltActionResume
+}
+
+// haltMessage returns the bytes signed by halt authorities. The sender and nonce
+// keep a signed halt from being replayed in another transaction.
+func haltMessage(from string, nonce int64, payload *haltPayload) []byte {
+	return []byte("chain-halt:" + payload.Action + ":" + from + ":" + strconv.FormatInt(nonce, 10) + ":" +
+		strconv.FormatInt(payload.Height, 10) + ":" + strconv.FormatInt(payload.ResumeAt, 10) + ":" + payload.Reason)
+}
+
+// haltIndex tracks the halt recorded on chain.
+// Like the spend policy index it follows the chain incrementally and is rebuilt after a reorg.
+type haltIndex struct {
+	halt    *ChainHalt // Latest halt, nil if none or resumed
+	height  int64      // Height of the last indexed block (-1 if empty)
+	tipHash string
+}
+
+// newHaltIndex creates an empty halt index
+func newHaltIndex() *haltIndex {
+	return &haltIndex{height: -1}
+}
+
+// sync brings the index up to date with the chain
+func (hi *haltIndex) sync(chain []*Block, policy *HaltPolicy) {
+	length := int64(len(chain))
+	if hi.height >= 0 && (hi.height >= length || chain[hi.height].Hash != hi.tipHash) {
+		hi.halt = nil
+		hi.height = -1
+		hi.tipHash = ""
+	}
+
+	for height := hi.height + 1; height < length; height++ {
+		hi.addBlock(chain[height], policy)
+	}
+}
+
+// addBlock applies the halt transactions of the next block. Blocks are validated
+// before they are indexed, so transactions that don't apply are skipped.
+func (hi *haltIndex) addBlock(block *Block, policy *HaltPolicy) {
+	for i := range block.Transactions {
+		hi.apply(&block.Transactions[i], policy, block.Index)
+	}
+	hi.height = block.Index
+	hi.tipHash = block.Hash
+}
+
+// check validates a halt transaction without applying it
+func (hi *haltIndex) check(tx *Transaction, policy *HaltPolicy) error {
+	if policy == nil {
+		return errors.New("chain halts are not enabled on this network")
+	}
+	payload, err := parseHaltPayload(tx)
+	if err != nil {
+		return err
+	}
+	if payload.Action == HaltActionResume && hi.halt == nil {
+		return errors.New("chain is not halted")
+	}
+
+	message := haltMessage(tx.From, tx.Nonce, payload)
+	signed := make(map[string]bool)
+	for _, signature := range payload.Signatures {
+		for _, authority := range policy.Authorities {
+			if !signed[authority] && verifyPolicySignature(authority, message, signature) {
+				signed[authority] = true
+				break
+			}
+		}
+	}
+	if len(signed) < policy.Required {
+		return fmt.Errorf("%s has %d valid authority signatures, %d required", payload.Action, len(signed), policy.Required)
+	}
+	return nil
+}
+
+// apply validates a transaction of the block at a height and updates the halt
+func (hi *haltIndex) apply(tx *Transaction, policy *HaltPolicy, height int64) error {
+	if tx.To != HaltAddress {
+		return nil
+	}
+	if err := hi.check(tx, policy); err != nil {
+		return err
+	}
+
+	payload, _ := parseHaltPayload(tx)
+	if payload.Action == HaltActionResume {
+		hi.halt = nil
+		return nil
+	}
+	hi.halt = &ChainHalt{
+		Height:   max(payload.Height, height+1),
+		ResumeAt: payload.ResumeAt,
+		Reason:   payload.Reason,
+		Block:    height,
+	}
+	return nil
+}
+
+// checkBlock validates a block against the halt without modifying the index. A block the halt
+// stops may only carry its coinbase and a resumption.
+func (hi *haltIndex) checkBlock(block *Block, policy *HaltPolicy) error {
+	scratch := &haltIndex{halt: hi.halt}
+	halted := scratch.halt.haltsAt(block.Index, block.Timestamp)
+
+	for i := range block.Transactions {
+		tx := &block.Transactions[i]
+		if halted && !tx.IsCoinbase() && !isResumeTransaction(tx) {
+			return fmt.Errorf("block %d carries transaction %s while the chain is halted", block.Index, tx.Hash)
+		}
+		if err := scratch.apply(tx, policy, block.Index); err != nil {
+			return fmt.Errorf("transaction %s: %w", tx.Hash, err)
+		}
+	}
+	if halted && scratch.halt != nil {
+		return fmt.Errorf("block %d: %w", block.Index, ErrChainHalted)
+	}
+	return nil
+}
+
+// checkTransaction validates a transaction for admission while the next block is at a height
+// with a timestamp: a halt refuses everything but its resumption and the coinbase of the block carrying it
+func (hi *haltIndex) checkTransaction(tx *Transaction, policy *HaltPolicy, height, timestamp int64) error {
+	if hi.halt.haltsAt(height, timestamp) && !tx.IsCoinbase() && !isResumeTransaction(tx) {
+		return ErrChainHalted
+	}
+	if tx.To == HaltAddress {
+		return hi.check(tx, policy)
+	}
+	return nil
+}
+
+// haltBlockTransactions returns the pending transactions a block may carry while the chain
+// is halted: the coinbase and a single resumption
+func haltBlockTransactions(pending []*Transaction) []*Transaction {
+	var allowed []*Transaction
+	resumed := false
+	for _, tx := range pending {
+		switch {
+		case tx.IsCoinbase():
+			allowed = append(allowed, tx)
+		case !resumed && isResumeTransaction(tx):
+			allowed = append(allowed, tx)
+			resumed = true
+		}
+	}
+	return allowed
+}
+
+// hasResumeTransaction reports whether a resumption is pending
+func hasResumeTransaction(pending []*Transaction) bool {
+	for _, tx := range pending {
+		if isResumeTransaction(tx) {
+			return true
+		}
+	}
+	return false
+}
+
+// HaltView gives the transaction pool read access to the halt recorded on chain
+type HaltView interface {
+	CheckHalt(tx *Transaction) error
+}
+
+// SetHaltView makes the pool refuse transactions while the chain is halted
+func (tp *Mempool) SetHaltView(halts HaltView) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	tp.halts = halts
+}
+
+// SetHaltPolicy lets the policy's authorities halt and resume the chain with signed
+// transactions (nil disables halts). Every node of the network must set the same policy.
+func (bc *Blockchain) SetHaltPolicy(policy *HaltPolicy) error {
+	if policy != nil {
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+	}
+	bc.haltPolicy = policy
+	bc.haltIndex = nil
+	return nil
+}
+
+// halts returns the halt index, caught up with the chain
+func (bc *Blockchain) halts() *haltIndex {
+	if bc.haltIndex == nil {
+		bc.haltIndex = newHaltIndex()
+	}
+	bc.haltIndex.sync(bc.Chain, bc.haltPolicy)
+	return bc.haltIndex
+}
+
+// GetChainHalt returns the halt recorded on chain and whether it stops the next block
+func (bc *Blockchain) GetChainHalt() (*ChainHalt, bool) {
+	halt := bc.halts().halt
+	return halt, halt.haltsAt(bc.GetLatestBlock().Index+1, bc.adjustedTime())
+}
+
+// CheckHalt checks a transaction may enter the pool given the halt recorded on chain
+func (bc *Blockchain) CheckHalt(tx *Transaction) error {
+	return bc.halts().checkTransaction(tx, bc.haltPolicy, bc.GetLatestBlock().Index+1, bc.adjustedTime())
+}
+
+// SetHaltPolicy lets the policy's authorities halt and resume the chain with signed
+// transactions (nil disables halts). Every node of the network must set the same policy.
+func (pbc *PersistentBlockchain) SetHaltPolicy(policy *HaltPolicy) error {
+	if policy != nil {
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+	}
+	pbc.haltPolicy = policy
+	pbc.haltIndex = nil
+	return nil
+}
+
+// halts returns the halt index, caught up with the chain
+func (pbc *PersistentBlockchain) halts() *haltIndex {
+	if pbc.haltIndex == nil {
+		pbc.haltIndex = newHaltIndex()
+	}
+	pbc.haltIndex.sync(pbc.Chain, pbc.haltPolicy)
+	return pbc.haltIndex
+}
+
+// GetChainHalt returns the halt recorded on chain and whether it stops the next block
+func (pbc *PersistentBlockchain) GetChainHalt() (*ChainHalt, bool) {
+	halt := pbc.halts().halt
+	return halt, halt.haltsAt(pbc.GetLatestBlock().Index+1, pbc.adjustedTime())
+}
+
+// CheckHalt checks a transaction may enter the pool given the halt recorded on chain
+func (pbc *PersistentBlockchain) CheckHalt(tx *Transaction) error {
+	return pbc.halts().checkTransaction(tx, pbc.haltPolicy, pbc.GetLatestBlock().Index+1, pbc.adjustedTime())
+}
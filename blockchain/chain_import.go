@@ -0,0 +1,362 @@
+package blockchain
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ImportMapping tells the importer where block and transaction fields live in foreign JSON.
+// Paths are dot-separated object keys and array indexes, e.g. "vin.0.prevout.scriptPubKey.address".
+// An empty path leaves the field at its zero value.
+type ImportMapping struct {
+	// Block fields
+	Index        string `json:"index"`
+	Hash         string `json:"hash"`
+	PrevHash     string `json:"prevHash"`
+	Timestamp    string `json:"timestamp"`
+	Nonce        string `json:"nonce"`
+	Version      string `json:"version"`
+	MerkleRoot   string `json:"merkleRoot"`
+	Transactions string `json:"transactions"`
+
+	// Transaction fields, relative to a transaction object
+	TxHash  string `json:"txHash"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Amount  string `json:"amount"`
+	Fee     string `json:"fee"`
+	TxNonce string `json:"txNonce"`
+
+	// Optional output list for UTXO-style transactions: each output becomes one transaction
+	// paying its address, with the hash suffixed by ":<output index>"
+	Outputs       string `json:"outputs"`
+	OutputAddress string `json:"outputAddress"`
+	OutputAmount  string `json:"outputAmount"`
+}
+
+// NativeImportMapping reads blocks in this package's own JSON format
+func NativeImportMapping() ImportMapping {
+	return ImportMapping{
+		Index: "index", Hash: "hash", PrevHash: "prevHash", Timestamp: "timestamp", Nonce: "nonce",
+		Version: "version", MerkleRoot: "merkleRoot", Transactions: "transactions",
+		TxHash: "hash", From: "from", To: "to", Amount: "amount", Fee: "fee", TxNonce: "nonce",
+	}
+}
+
+// BitcoinImportMapping reads blocks as printed by bitcoind's getblock with verbosity 3
+// (transactions with their previous outputs), spreading each transaction over its outputs
+func BitcoinImportMapping() ImportMapping {
+	return ImportMapping{
+		Index: "height", Hash: "hash", PrevHash: "previousblockhash", Timestamp: "time", Nonce: "nonce",
+		Version: "version", MerkleRoot: "merkleroot", Transactions: "tx",
+		TxHash: "txid", From: "vin.0.prevout.scriptPubKey.address", Fee: "fee",
+		Outputs: "vout", OutputAddress: "scriptPubKey.address", OutputAmount: "value",
+	}
+}
+
+// ImportReport summarizes a chain import
+type ImportReport struct {
+	Blocks       int      `json:"blocks"`
+	Transactions int      `json:"transactions"`
+	Skipped      int      `json:"skipped"` // Blocks that could not be converted or stored
+	Errors       []string `json:"errors,omitempty"`
+}
+
+// maxImportErrors bounds the errors kept in an import report
+const maxImportErrors = 100
+
+// ImportConfig configures a chain import
+type ImportConfig struct {
+	Mapping         ImportMapping
+	ContinueOnError bool // Skip blocks that fail instead of aborting the import
+}
+
+// ImportBlocks stores externally produced blocks in the database for analysis.
+// Input is either a JSON array of blocks or a stream of JSON block objects.
+// Blocks are stored as given: hashes, linkage and proof of work are not validated,
+// so an imported database must not be used as a consensus node's chain.
+func (d *Database) ImportBlocks(r io.Reader, config ImportConfig) (*ImportReport, error) {
+	reader := bufio.NewReader(r)
+	report := &ImportReport{}
+	fail := func(index int, err error) error {
+		report.Skipped++
+		if len(report.Errors) < maxImportErrors {
+			report.Errors = append(report.Errors, fmt.Sprintf("block #%d: %v", index, err))
+		}
+		if config.ContinueOnError {
+			return nil
+		}
+		return fmt.Errorf("import failed at block #%d: %w", index, err)
+	}
+
+	// Peek at the first significant byte to tell an array from a stream of objects
+	var first byte
+	for {
+		b, err := reader.ReadByte()
+		if err == io.EOF {
+			return report, nil
+		}
+		if err != nil {
+			return report, fmt.Errorf("failed to read import data: %w", err)
+		}
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			first = b
+			reader.UnreadByte()
+			break
+		}
+	}
+	if first != '[' && first != '{' {
+		return report, errors.New("input must be a JSON array or a stream of block objects")
+	}
+
+	decoder := json.NewDecoder(reader)
+	decoder.UseNumber()
+	array := first == '['
+	if array {
+		// Step into the array so blocks are decoded one at a time
+		if _, err := decoder.Token(); err != nil {
+			return report, fmt.Errorf("failed to read import data: %w", err)
+		}
+	}
+
+	for i := 0; ; i++ {
+		if array && !decoder.More() {
+			break
+		}
+
+		var raw map[string]interface{}
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return report, fmt.Errorf("failed to decode block #%d: %w", i, err)
+		}
+
+		block, err := config.Mapping.convertBlock(raw)
+		if err == nil {
+			err = CheckBlockLimits(block)
+		}
+		if err != nil {
+			if ferr := fail(i, err); ferr != nil {
+				return report, ferr
+			}
+			continue
+		}
+
+		if err := d.SaveBlock(block); err != nil {
+			if ferr := fail(i, err); ferr != nil {
+				return report, ferr
+			}
+			continue
+		}
+
+		report.Blocks++
+		report.Transactions += len(block.Transactions)
+	}
+
+	return report, nil
+}
+
+// convertBlock maps a foreign block object to a block
+func (m *ImportMapping) convertBlock(raw map[string]interface{}) (*Block, error) {
+	block := &Block{}
+	var err error
+
+	if block.Index, err = lookupInt(raw, m.Index); err != nil {
+		return nil, err
+	}
+	if block.Hash, err = lookupString(raw, m.Hash); err != nil {
+		return nil, err
+	}
+	if block.Hash == "" {
+		return nil, errors.New("block has no hash")
+	}
+	if block.PrevHash, err = lookupString(raw, m.PrevHash); err != nil {
+		return nil, err
+	}
+	if block.Timestamp, err = lookupInt(raw, m.Timestamp); err != nil {
+		return nil, err
+	}
+	if block.Nonce, err = lookupInt(raw, m.Nonce); err != nil {
+		return nil, err
+	}
+	version, err := lookupInt(raw, m.Version)
+	if err != nil {
+		return nil, err
+	}
+	block.Version = int32(version)
+	if block.MerkleRoot, err = lookupString(raw, m.MerkleRoot); err != nil {
+		return nil, err
+	}
+
+	txList, _ := lookupPath(raw, m.Transactions)
+	if txList == nil {
+		return block, nil
+	}
+	items, ok := txList.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s is not a list", m.Transactions)
+	}
+
+	for i, item := range items {
+		txs, err := m.convertTransaction(item)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+		block.Transactions = append(block.Transactions, txs...)
+	}
+	return block, nil
+}
+
+// convertTransaction maps a foreign transaction object to one transaction, or one per output
+func (m *ImportMapping) convertTransaction(raw interface{}) ([]Transaction, error) {
+	// Some formats list bare transaction hashes instead of objects
+	if hash, ok := raw.(string); ok {
+		return []Transaction{{Hash: hash}}, nil
+	}
+
+	var tx Transaction
+	var err error
+	if tx.Hash, err = lookupString(raw, m.TxHash); err != nil {
+		return nil, err
+	}
+	if tx.From, err = lookupString(raw, m.From); err != nil {
+		return nil, err
+	}
+	// Transactions without a sender (such as Bitcoin coinbases) mint new coins
+	if tx.From == "" {
+		tx.From = CoinbaseSender
+	}
+	if tx.Fee, err = lookupFloat(raw, m.Fee); err != nil {
+		return nil, err
+	}
+	if tx.Nonce, err = lookupInt(raw, m.TxNonce); err != nil {
+		return nil, err
+	}
+
+	if m.Outputs == "" {
+		if tx.To, err = lookupString(raw, m.To); err != nil {
+			return nil, err
+		}
+		if tx.Amount, err = lookupFloat(raw, m.Amount); err != nil {
+			return nil, err
+		}
+		return []Transaction{tx}, nil
+	}
+
+	outputs, _ := lookupPath(raw, m.Outputs)
+	items, ok := outputs.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s is not a list", m.Outputs)
+	}
+
+	txs := make([]Transaction, 0, len(items))
+	for i, output := range items {
+		out := tx
+		out.Hash = fmt.Sprintf("%s:%d", tx.Hash, i)
+		if out.To, err = lookupString(output, m.OutputAddress); err != nil {
+			return nil, err
+		}
+		if out.Amount, err = lookupFloat(output, m.OutputAmount); err != nil {
+			return nil, err
+		}
+		// The fee is paid once per transaction
+		if i > 0 {
+			out.Fee = 0
+		}
+		txs = append(txs, out)
+	}
+	return txs, nil
+}
+
+// lookupPath resolves a dot-separated path of object keys and array indexes
+func lookupPath(value interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return nil, false
+	}
+
+	for _, part := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			next, exists := v[part]
+			if !exists {
+				return nil, false
+			}
+			value = next
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// lookupString resolves a path to a string (numbers are formatted, missing values are empty)
+func lookupString(value interface{}, path string) (string, error) {
+	found, ok := lookupPath(value, path)
+	if !ok || found == nil {
+		return "", nil
+	}
+	switch v := found.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	default:
+		return "", fmt.Errorf("%s is not a string", path)
+	}
+}
+
+// lookupInt resolves a path to an integer (numeric strings are accepted, missing values are 0)
+func lookupInt(value interface{}, path string) (int64, error) {
+	found, ok := lookupPath(value, path)
+	if !ok || found == nil {
+		return 0, nil
+	}
+	var text string
+	switch v := found.(type) {
+	case json.Number:
+		text = v.String()
+	case string:
+		text = v
+	default:
+		return 0, fmt.Errorf("%s is not a number", path)
+	}
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not an integer: %w", path, err)
+	}
+	return n, nil
+}
+
+// lookupFloat resolves a path to a number (numeric strings are accepted, missing values are 0)
+func lookupFloat(value interface{}, path string) (float64, error) {
+	found, ok := lookupPath(value, path)
+	if !ok || found == nil {
+		return 0, nil
+	}
+	var text string
+	switch v := found.(type) {
+	case json.Number:
+		text = v.String()
+	case string:
+		text = v
+	default:
+		return 0, fmt.Errorf("%s is not a number", path)
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a number: %w", path, err)
+	}
+	return f, nil
+}
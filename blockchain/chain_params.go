@@ -0,0 +1,147 @@
+package blockchain
+
+import (
+	"fmt"
+)
+
+// ChainParams are the network-wide settings wallets and validation must agree on
+type ChainParams struct {
+	ChainID       string
+	AddressFormat AddressFormat // Default HexAddressFormat, the format of chains predating the setting
+	HistoryCommit int64         // Blocks at multiples of this height commit the history root (0 disables)
+	StateCommit   int64         // Blocks from this height on commit the state root (0 disables)
+}
+
+// DefaultChainParams are the parameters of existing chains
+var DefaultChainParams = &ChainParams{AddressFormat: HexAddressFormat{}}
+
+// Format returns the address format of the network
+func (p *ChainParams) Format() AddressFormat {
+	if p == nil || p.AddressFormat == nil {
+		return HexAddressFormat{}
+	}
+	return p.AddressFormat
+}
+
+// historyInterval returns the number of blocks between history commitments (0 if disabled)
+func (p *ChainParams) historyInterval() int64 {
+	if p == nil || p.HistoryCommit < 0 {
+		return 0
+	}
+	return p.HistoryCommit
+}
+
+// stateActivation returns the first height whose blocks commit the state root (0 if disabled)
+func (p *ChainParams) stateActivation() int64 {
+	if p == nil || p.StateCommit < 0 {
+		return 0
+	}
+	return p.StateCommit
+}
+
+// AddressFromPublicKey derives the network address of a public key encoded by EncodePublicKey
+func (p *ChainParams) AddressFromPublicKey(encoded string) (string, error) {
+	scheme, publicKey, err := DecodePublicKey(encoded)
+	if err != nil {
+		return "", err
+	}
+	return p.Format().Address(scheme, publicKey)
+}
+
+// ValidateAddress checks an address is in the network's format. The system addresses
+// (coinbase sender, key-value namespace, spend policies) are valid on every network.
+func (p *ChainParams) ValidateAddress(address string) error {
+	switch address {
+	case CoinbaseSender, KVNamespaceAddress, SpendPolicyAddress, HaltAddress:
+		return nil
+	}
+	if _, err := p.Format().Scheme(address); err != nil {
+		return fmt.Errorf("invalid %s address %q: %w", p.Format().Name(), address, err)
+	}
+	return nil
+}
+
+// NewWallet creates a wallet with a fresh key of a signature scheme, addressed in the network's format
+func (p *ChainParams) NewWallet(scheme SignatureScheme) (*Wallet, error) {
+	wallet, err := NewWalletWithScheme(scheme)
+	if err != nil {
+		return nil, err
+	}
+	return p.BindWallet(wallet)
+}
+
+// BindWallet returns a copy of a wallet addressed in the network's format, e.g. one restored
+// from a keystore, a WIF key or an HD seed, which derive the original hex addresses
+func (p *ChainParams) BindWallet(w *Wallet) (*Wallet, error) {
+	address, err := p.AddressFromPublicKey(w.EncodedPublicKey())
+	if err != nil {
+		return nil, err
+	}
+	bound := *w
+	bound.Address = address
+	return &bound, nil
+}
+
+// checkBlockAddresses rejects blocks with addresses outside the network's format
+func checkBlockAddresses(block *Block, params *ChainParams) error {
+	if params == nil {
+		return nil
+	}
+	for i := range block.Transactions {
+		tx := &block.Transactions[i]
+		if err := checkTransactionAddresses(tx, params); err != nil {
+			return fmt.Errorf("transaction %s: %w", tx.Hash, err)
+		}
+	}
+	return nil
+}
+
+// checkTransactionAddresses checks the sender and recipient are in the network's format
+func checkTransactionAddresses(tx *Transaction, params *ChainParams) error {
+	if err := params.ValidateAddress(tx.From); err != nil {
+		return err
+	}
+	if tx.Sponsor != "" {
+		if err := params.ValidateAddress(tx.Sponsor); err != nil {
+			return err
+		}
+	}
+	return params.ValidateAddress(tx.To)
+}
+
+// SetChainParams makes pool admission and newly connected blocks require addresses in the
+// network's format and verify signatures against addresses derived in it. Without parameters
+// addresses aren't checked and signatures use the original hex addresses. The mining reward
+// address must be valid on the network.
+func (bc *Blockchain) SetChainParams(params *ChainParams) error {
+	if params != nil {
+		if err := params.ValidateAddress(bc.MiningRewardAddr); err != nil {
+			return fmt.Errorf("mining reward address: %w", err)
+		}
+	}
+	bc.params = params
+	bc.TransactionPool.SetChainParams(params)
+	return nil
+}
+
+// SetChainParams makes pool admission and newly connected blocks require addresses in the
+// network's format and verify signatures against addresses derived in it. Without parameters
+// addresses aren't checked and signatures use the original hex addresses. The mining reward
+// address must be valid on the network.
+func (pbc *PersistentBlockchain) SetChainParams(params *ChainParams) error {
+	if params != nil {
+		if err := params.ValidateAddress(pbc.MiningRewardAddr); err != nil {
+			return fmt.Errorf("mining reward address: %w", err)
+		}
+	}
+	pbc.params = params
+	pbc.TransactionPool.SetChainParams(params)
+	return nil
+}
+
+// SetChainParams makes the pool reject addresses outside the network's format
+func (tp *Mempool) SetChainParams(params *ChainParams) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	tp.params = params
+}
@@ -0,0 +1,182 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// SpendNode is the node surface wallets build transactions against.
+// Both *Blockchain and *PersistentBlockchain implement it.
+type SpendNode interface {
+	GetAvailableBalance(address string) float64
+	NextNonce(address string) int64
+	EstimateFee(targetBlocks int) FeeEstimate
+}
+
+// CoinSelection is the order in which funding addresses are drawn on
+type CoinSelection string
+
+const (
+	SelectLargestFirst  CoinSelection = "largest-first"  // Fewest transactions and fees
+	SelectSmallestFirst CoinSelection = "smallest-first" // Empties small balances first
+	SelectInOrder       CoinSelection = "in-order"       // The order of SpendRequest.Inputs, or of the wallet
+)
+
+// SpendRequest describes a payment for a wallet to build
+type SpendRequest struct {
+	To           string
+	Amount       float64
+	Inputs       []string      // Addresses allowed to fund the payment (coin control); empty allows every address
+	Selection    CoinSelection // Default SelectLargestFirst
+	TargetBlocks int           // Confirmation target passed to the fee estimator (default DefaultFeeTarget)
+	Fee          float64       // Fee per transaction overriding the estimate, 0 to estimate
+}
+
+// SpendInput is an address funding part of a payment. Balances are per account, so the
+// change is not sent anywhere: it is what stays on the address.
+type SpendInput struct {
+	Address   string  `json:"address"`
+	Available float64 `json:"available"`
+	Amount    float64 `json:"amount"`
+	Fee       float64 `json:"fee"`
+	Change    float64 `json:"change"`
+}
+
+// SpendPlan is a built payment: one signed transaction per funding address
+type SpendPlan struct {
+	Inputs       []SpendInput   `json:"inputs"`
+	Transactions []*Transaction `json:"transactions"`
+	Amount       float64        `json:"amount"`
+	Fee          float64        `json:"fee"` // Sum of the transaction fees
+	Change       float64        `json:"change"`
+	Estimate     FeeEstimate    `json:"estimate"`
+}
+
+// ErrInsufficientFunds is returned when the funding addresses can't cover a payment and its fees
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// BuildTransaction builds and signs a payment from the wallet, with the fee suggested by the node
+func (w *Wallet) BuildTransaction(node SpendNode, to string, amount float64, targetBlocks int) (*Transaction, error) {
+	plan, err := buildSpend([]*Wallet{w}, node, SpendRequest{To: to, Amount: amount, TargetBlocks: targetBlocks})
+	if err != nil {
+		return nil, err
+	}
+	return plan.Transactions[0], nil
+}
+
+// BuildSpend builds and signs a payment funded by the wallet's addresses. Each funding address
+// sends its share in its own transaction and pays its own fee.
+func (hw *HDWallet) BuildSpend(node SpendNode, req SpendRequest) (*SpendPlan, error) {
+	return buildSpend(hw.Wallets, node, req)
+}
+
+// buildSpend selects funding addresses among the wallets and builds their transactions
+func buildSpend(wallets []*Wallet, node SpendNode, req SpendRequest) (*SpendPlan, error) {
+	if req.Amount <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+	if req.To == "" {
+		return nil, errors.New("recipient is required")
+	}
+	if req.Fee < 0 {
+		return nil, errors.New("fee cannot be negative")
+	}
+
+	candidates, err := spendCandidates(wallets, req.Inputs)
+	if err != nil {
+		return nil, err
+	}
+	available := make(map[string]float64, len(candidates))
+	for _, wallet := range candidates {
+		available[wallet.Address] = node.GetAvailableBalance(wallet.Address)
+	}
+	switch req.Selection {
+	case "", SelectLargestFirst:
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return available[candidates[i].Address] > available[candidates[j].Address]
+		})
+	case SelectSmallestFirst:
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return available[candidates[i].Address] < available[candidates[j].Address]
+		})
+	case SelectInOrder:
+	default:
+		return nil, fmt.Errorf("unknown coin selection %q", req.Selection)
+	}
+
+	plan := &SpendPlan{Estimate: node.EstimateFee(req.TargetBlocks)}
+	remaining := req.Amount
+	for _, wallet := range candidates {
+		if remaining <= 0 {
+			break
+		}
+		balance := available[wallet.Address]
+		if balance <= 0 {
+			continue
+		}
+
+		// The fee depends on the amount's encoding and the amount on the fee when the balance runs short
+		nonce := node.NextNonce(wallet.Address)
+		fee, amount := req.Fee, remaining
+		for i := 0; i < 3; i++ {
+			if req.Fee == 0 {
+				fee = plan.Estimate.FeeForTransaction(Transaction{From: wallet.Address, To: req.To, Amount: amount, Nonce: nonce})
+			}
+			next := min(remaining, balance-fee)
+			if next == amount {
+				break
+			}
+			amount = next
+		}
+		if amount <= 0 {
+			continue
+		}
+		for amount+fee > balance {
+			amount = math.Nextafter(amount, 0)
+		}
+
+		tx := NewTransactionWithNonce(wallet.Address, req.To, amount, fee, nonce)
+		if err := wallet.AttachSignature(tx); err != nil {
+			return nil, err
+		}
+		input := SpendInput{Address: wallet.Address, Available: balance, Amount: amount, Fee: fee, Change: max(balance-amount-fee, 0)}
+		plan.Inputs = append(plan.Inputs, input)
+		plan.Transactions = append(plan.Transactions, tx)
+		plan.Amount += amount
+		plan.Fee += fee
+		plan.Change += input.Change
+		remaining -= amount
+	}
+
+	if remaining > 0 {
+		return nil, fmt.Errorf("%w: %.8f short of %.8f after fees", ErrInsufficientFunds, remaining, req.Amount)
+	}
+	return plan, nil
+}
+
+// spendCandidates returns the wallets allowed to fund a payment, in the order of inputs if given
+func spendCandidates(wallets []*Wallet, inputs []string) ([]*Wallet, error) {
+	if len(inputs) == 0 {
+		return append([]*Wallet(nil), wallets...), nil
+	}
+
+	byAddress := make(map[string]*Wallet, len(wallets))
+	for _, wallet := range wallets {
+		byAddress[wallet.Address] = wallet
+	}
+	candidates := make([]*Wallet, 0, len(inputs))
+	seen := make(map[string]bool, len(inputs))
+	for _, address := range inputs {
+		wallet, exists := byAddress[address]
+		if !exists {
+			return nil, fmt.Errorf("no key for input address %s", address)
+		}
+		if !seen[address] {
+			seen[address] = true
+			candidates = append(candidates, wallet)
+		}
+	}
+	return candidates, nil
+}
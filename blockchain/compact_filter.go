@@ -0,0 +1,347 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"slices"
+	"strings"
+
+	"blockchain/blockchain/verify"
+)
+
+// CompactFilterExtension is the block extension committing the hash of the block's compact
+// filter (see CompactFiltersHandler)
+const CompactFilterExtension = "filter.compact"
+
+// Golomb-Rice parameters of compact filters, those of BIP158: items hash into a range of
+// CompactFilterM values per item and deltas are coded with CompactFilterP remainder bits,
+// for a false positive rate of about 1 in 784931 per queried address
+const (
+	CompactFilterP = 19
+	CompactFilterM = 784931
+)
+
+// maxCompactFilterItems bounds the item count a filter may claim: the sender, recipient and
+// sponsor of every transaction of a full block
+const maxCompactFilterItems = 3 * MaxBlockTransactions
+
+// genesisFilterHeader is the filter header before the genesis block
+var genesisFilterHeader = strings.Repeat("0", 64)
+
+// CompactFilter is a block's compact filter: a Golomb-coded set of the addresses of its
+// transactions that a light client tests its own addresses against locally, so unlike a bloom
+// query it reveals nothing about them to the node. Filters are deterministic, so nodes agree on
+// them byte for byte; Header chains them from genesis (BIP157) for clients to compare between
+// nodes, and Proof ties the filter to the block header when the block commits it.
+type CompactFilter struct {
+	Height    int64        `json:"height"`
+	BlockHash string       `json:"blockHash"`
+	Data      []byte       `json:"data"`
+	Header    string       `json:"header"`          // Filter header: hash of the filter hash and the previous filter header
+	Proof     *MerkleProof `json:"proof,omitempty"` // Proof of the block's CompactFilterExtension, nil if it has none
+}
+
+// compactFilterItems returns the distinct addresses of transactions in sorted order
+func compactFilterItems(transactions []Transaction) []string {
+	items := make([]string, 0, 2*len(transactions))
+	for i := range transactions {
+		tx := &transactions[i]
+		items = append(items, tx.From, tx.To)
+		if tx.Sponsor != "" {
+			items = append(items, tx.Sponsor)
+		}
+	}
+	slices.Sort(items)
+	return slices.Compact(items)
+}
+
+// compactFilterValue hashes an item into [0, modulus), keyed by the filter key so the same
+// address lands on unrelated values in different blocks
+func compactFilterValue(key, item string, modulus uint64) uint64 {
+	hash := sha256.Sum256([]byte(key + "\x00" + item))
+	value, _ := bits.Mul64(binary.BigEndian.Uint64(hash[:8]), modulus)
+	return value
+}
+
+// buildCompactFilter encodes items as a Golomb-coded set: the item count as a uvarint, then
+// the sorted hashed values as Golomb-Rice coded deltas, bits most significant first
+func buildCompactFilter(key string, items []string) []byte {
+	data := binary.AppendUvarint(nil, uint64(len(items)))
+	if len(items) == 0 {
+		return data
+	}
+
+	modulus := uint64(len(items)) * CompactFilterM
+	values := make([]uint64, len(items))
+	for i, item := range items {
+		values[i] = compactFilterValue(key, item, modulus)
+	}
+	slices.Sort(values)
+
+	w := bitWriter{data: data}
+	var last uint64
+	for _, value := range values {
+		delta := value - last
+		last = value
+		for q := delta >> CompactFilterP; q > 0; q-- {
+			w.writeBit(1)
+		}
+		w.writeBit(0)
+		w.writeBits(delta, CompactFilterP)
+	}
+	return w.data
+}
+
+// MatchCompactFilter reports whether a filter built with a key may hold any of the addresses.
+// A match can be a false positive; a miss is certain.
+func MatchCompactFilter(data []byte, key string, addresses ...string) (bool, error) {
+	count, n := binary.Uvarint(data)
+	if n <= 0 {
+		return false, errors.New("compact filter has no item count")
+	}
+	if count > maxCompactFilterItems {
+		return false, &LimitError{Field: "filter items", Size: int(min(count, 1<<31)), Limit: maxCompactFilterItems}
+	}
+	if count == 0 || len(addresses) == 0 {
+		return false, nil
+	}
+
+	modulus := count * CompactFilterM
+	targets := make([]uint64, len(addresses))
+	for i, address := range addresses {
+		targets[i] = compactFilterValue(key, address, modulus)
+	}
+	slices.Sort(targets)
+
+	r := bitReader{data: data[n:]}
+	var value uint64
+	for i := uint64(0); i < count; i++ {
+		var q uint64
+		for {
+			bit, ok := r.readBit()
+			if !ok {
+				return false, errors.New("compact filter is truncated")
+			}
+			if bit == 0 {
+				break
+			}
+			q++
+			if q > modulus>>CompactFilterP {
+				return false, errors.New("compact filter value out of range")
+			}
+		}
+		remainder, ok := r.readBits(CompactFilterP)
+		if !ok {
+			return false, errors.New("compact filter is truncated")
+		}
+		value += q<<CompactFilterP | remainder
+
+		for len(targets) > 0 && targets[0] < value {
+			targets = targets[1:]
+		}
+		if len(targets) == 0 {
+			return false, nil
+		}
+		if targets[0] == value {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// bitWriter appends bits to a byte slice, most significant first
+type bitWriter struct {
+	data []byte
+	used uint // Bits used in the last byte, 0 if it is full
+}
+
+// writeBit appends one bit
+func (w *bitWriter) writeBit(bit byte) {
+	if w.used == 0 {
+		w.data = append(w.data, 0)
+	}
+	w.data[len(w.data)-1] |= bit << (7 - w.used)
+	w.used = (w.used + 1) % 8
+}
+
+// writeBits appends the low n bits of a value
+func (w *bitWriter) writeBits(value uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		w.writeBit(byte(value>>i) & 1)
+	}
+}
+
+// bitReader reads bits written by a bitWriter
+type bitReader struct {
+	data []byte
+	pos  int // Bits read
+}
+
+// readBit reads one bit
+func (r *bitReader) readBit() (byte, bool) {
+	if r.pos >= 8*len(r.data) {
+		return 0, false
+	}
+	bit := r.data[r.pos/8] >> (7 - r.pos%8) & 1
+	r.pos++
+	return bit, true
+}
+
+// readBits reads n bits as a value
+func (r *bitReader) readBits(n int) (uint64, bool) {
+	var value uint64
+	for i := 0; i < n; i++ {
+		bit, ok := r.readBit()
+		if !ok {
+			return 0, false
+		}
+		value = value<<1 | uint64(bit)
+	}
+	return value, true
+}
+
+// CompactFilter returns the block's compact filter, keyed by its previous hash since the
+// filter is committed before the block has a hash of its own
+func (b *Block) CompactFilter() ([]byte, error) {
+	if b.Pruned {
+		return nil, fmt.Errorf("block %d: %w", b.Index, ErrPruned)
+	}
+	return buildCompactFilter(b.PrevHash, compactFilterItems(b.Transactions)), nil
+}
+
+// compactFilterHash returns the hash of a block's compact filter: the one it commits if it
+// does, "" if its body was pruned without one
+func (b *Block) compactFilterHash() string {
+	if hash, ok := b.Extension(CompactFilterExtension); ok {
+		return hash
+	}
+	data, err := b.CompactFilter()
+	if err != nil {
+		return ""
+	}
+	return verify.HashEncoding(data)
+}
+
+// CompactFiltersHandler returns the handler of CompactFilterExtension: mined blocks commit the
+// hash of their compact filter and connected blocks must carry the right one, so a light client
+// can check a filter against the block header. Required rejects blocks without the extension.
+func CompactFiltersHandler(required bool) ExtensionHandler {
+	return ExtensionHandler{
+		Produce: func(block *Block) (string, bool) {
+			data, err := block.CompactFilter()
+			return verify.HashEncoding(data), err == nil
+		},
+		Validate: func(block *Block, data string) error {
+			filter, err := block.CompactFilter()
+			if err != nil {
+				return err
+			}
+			if data != verify.HashEncoding(filter) {
+				return errors.New("compact filter hash does not match the transactions")
+			}
+			return nil
+		},
+		Required: required,
+	}
+}
+
+// VerifyCompactFilter checks a filter is the one committed by the block of a header the caller
+// trusts and that its filter header extends the header of the filter below it. The previous
+// filter header is ignored at height 0 and unchecked if "", e.g. for the first filter of a range.
+func VerifyCompactFilter(filter *CompactFilter, header BlockHeader, prevFilterHeader string) error {
+	if filter == nil {
+		return errors.New("missing filter")
+	}
+	if filter.Height != header.Index || filter.BlockHash != header.Hash {
+		return fmt.Errorf("filter is for block %d, not %d", filter.Height, header.Index)
+	}
+	hash := verify.HashEncoding(filter.Data)
+	if filter.Height == 0 {
+		prevFilterHeader = genesisFilterHeader
+	}
+	if prevFilterHeader != "" && filter.Header != verify.NodeHash(hash, prevFilterHeader) {
+		return errors.New("filter header does not chain to the previous one")
+	}
+	if !VerifyExtensionProof(filter.Proof, header, CompactFilterExtension, hash) {
+		return fmt.Errorf("block %d does not commit the filter", header.Index)
+	}
+	return nil
+}
+
+// filterHeaderChain holds the filter header of every block. Like the history range it follows
+// the chain incrementally and is rebuilt after a reorg. A pruned block without a committed
+// filter breaks the chain: its header and every later one are "".
+type filterHeaderChain struct {
+	headers []string
+	tipHash string
+}
+
+// sync brings the header chain up to date with the chain
+func (c *filterHeaderChain) sync(chain []*Block) {
+	size := len(c.headers)
+	if size > 0 && (size > len(chain) || chain[size-1].Hash != c.tipHash) {
+		c.headers = nil
+		size = 0
+	}
+	for _, block := range chain[size:] {
+		prev := genesisFilterHeader
+		if len(c.headers) > 0 {
+			prev = c.headers[len(c.headers)-1]
+		}
+		header := ""
+		if hash := block.compactFilterHash(); hash != "" && prev != "" {
+			header = verify.NodeHash(hash, prev)
+		}
+		c.headers = append(c.headers, header)
+		c.tipHash = block.Hash
+	}
+}
+
+// compactFilters returns the filters of the blocks between two heights, inclusive
+func compactFilters(chain []*Block, headers *filterHeaderChain, from, to int64) ([]CompactFilter, error) {
+	if from < 0 || from > to {
+		return nil, errors.New("invalid height range")
+	}
+	headers.sync(chain)
+	to = min(to, int64(len(chain))-1)
+
+	var filters []CompactFilter
+	for height := from; height <= to; height++ {
+		block := chain[height]
+		data, err := block.CompactFilter()
+		if err != nil {
+			return nil, err
+		}
+		filter := CompactFilter{Height: height, BlockHash: block.Hash, Data: data, Header: headers.headers[height]}
+		if filter.Header == "" {
+			return nil, fmt.Errorf("filter header of block %d: %w", height, ErrPruned)
+		}
+		if _, ok := block.Extension(CompactFilterExtension); ok {
+			if filter.Proof, err = block.GenerateExtensionProof(CompactFilterExtension); err != nil {
+				return nil, err
+			}
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+// GetCompactFilters returns the compact filters of the blocks between two heights, inclusive
+func (bc *Blockchain) GetCompactFilters(from, to int64) ([]CompactFilter, error) {
+	if bc.filterHeaders == nil {
+		bc.filterHeaders = &filterHeaderChain{}
+	}
+	return compactFilters(bc.Chain, bc.filterHeaders, from, to)
+}
+
+// GetCompactFilters returns the compact filters of the blocks between two heights, inclusive.
+// Pruned blocks have no filter.
+func (pbc *PersistentBlockchain) GetCompactFilters(from, to int64) ([]CompactFilter, error) {
+	if pbc.filterHeaders == nil {
+		pbc.filterHeaders = &filterHeaderChain{}
+	}
+	return compactFilters(pbc.Chain, pbc.filterHeaders, from, to)
+}
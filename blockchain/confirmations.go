@@ -0,0 +1,177 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// confirmationPollInterval is how often WaitForConfirmation re-checks the chain
+const confirmationPollInterval = time.Second
+
+// Checkpoint pins the hash of the block at a height. Once the node's chain contains a
+// checkpointed block, it and every block below it are final regardless of their depth.
+type Checkpoint struct {
+	Height int64  `json:"height"`
+	Hash   string `json:"hash"`
+}
+
+// ConfirmationStatus tells how settled a transaction is on the node's current chain.
+// It is computed from the chain at the time of the call, so a reorg that drops the
+// containing block is reflected the next time it is requested.
+type ConfirmationStatus struct {
+	Hash          string `json:"hash"`
+	Found         bool   `json:"found"`      // In the chain or the pool
+	Pending       bool   `json:"pending"`    // In the pool only
+	BlockIndex    int64  `json:"blockIndex"` // -1 unless confirmed
+	BlockHash     string `json:"blockHash,omitempty"`
+	Confirmations int64  `json:"confirmations"` // Blocks from the containing block to the tip, inclusive
+	Final         bool   `json:"final"`         // At or below the highest checkpoint on the chain
+}
+
+// Confirmed reports whether the transaction is final or has at least depth confirmations.
+// A depth below 1 counts as 1, i.e. included in a block.
+func (cs *ConfirmationStatus) Confirmed(depth int64) bool {
+	if cs.BlockIndex < 0 {
+		return false
+	}
+	return cs.Final || cs.Confirmations >= max(depth, 1)
+}
+
+// ConfirmationSource is the node surface confirmations are read from.
+// Both *Blockchain and *PersistentBlockchain implement it.
+type ConfirmationSource interface {
+	GetConfirmations(txHash string) *ConfirmationStatus
+}
+
+// WaitForConfirmation blocks until a transaction is final or has depth confirmations and
+// returns its status. The chain is re-read under the lock every poll, so confirmations lost
+// to a reorg are waited for again. It fails once the context ends, returning the last status.
+// The lock must be the one guarding the chain (nil if the node isn't shared).
+func WaitForConfirmation(ctx context.Context, node ConfirmationSource, lock sync.Locker, txHash string, depth int64) (*ConfirmationStatus, error) {
+	if lock == nil {
+		lock = &sync.Mutex{}
+	}
+
+	ticker := time.NewTicker(confirmationPollInterval)
+	defer ticker.Stop()
+
+	for {
+		lock.Lock()
+		status := node.GetConfirmations(txHash)
+		lock.Unlock()
+
+		if status.Confirmed(depth) {
+			return status, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, fmt.Errorf("transaction %s not confirmed: %v", txHash, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// validateCheckpoints checks checkpoints and returns them sorted by height
+func validateCheckpoints(checkpoints []Checkpoint) ([]Checkpoint, error) {
+	sorted := append([]Checkpoint(nil), checkpoints...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Height < sorted[j].Height
+	})
+	for i, checkpoint := range sorted {
+		if checkpoint.Height < 0 {
+			return nil, fmt.Errorf("checkpoint height %d cannot be negative", checkpoint.Height)
+		}
+		if checkpoint.Hash == "" && checkpoint.Height != 0 {
+			return nil, fmt.Errorf("checkpoint at height %d has no hash", checkpoint.Height)
+		}
+		if i > 0 && sorted[i-1].Height == checkpoint.Height {
+			return nil, errors.New("duplicate checkpoint height")
+		}
+	}
+	return sorted, nil
+}
+
+// finalizedHeight returns the height of the highest checkpoint the chain contains (-1 if none)
+func finalizedHeight(chain []*Block, checkpoints []Checkpoint) int64 {
+	for i := len(checkpoints) - 1; i >= 0; i-- {
+		checkpoint := checkpoints[i]
+		if checkpoint.Height < int64(len(chain)) && chain[checkpoint.Height].Hash == checkpoint.Hash {
+			return checkpoint.Height
+		}
+	}
+	return -1
+}
+
+// confirmationStatus computes the confirmation status of a transaction
+func confirmationStatus(chain []*Block, index *txIndex, pool *TransactionPool, checkpoints []Checkpoint, txHash string) *ConfirmationStatus {
+	status := &ConfirmationStatus{Hash: txHash, BlockIndex: -1}
+	if location, exists := index.lookup(txHash); exists {
+		block := chain[location.BlockIndex]
+		status.Found = true
+		status.BlockIndex = block.Index
+		status.BlockHash = block.Hash
+		status.Confirmations = int64(len(chain)) - block.Index
+		status.Final = block.Index <= finalizedHeight(chain, checkpoints)
+		return status
+	}
+	if _, pending := pool.GetTransaction(txHash); pending {
+		status.Found = true
+		status.Pending = true
+	}
+	return status
+}
+
+// SetFinalityCheckpoints replaces the checkpoints blocks are considered final at (nil clears them)
+func (bc *Blockchain) SetFinalityCheckpoints(checkpoints []Checkpoint) error {
+	sorted, err := validateCheckpoints(checkpoints)
+	if err != nil {
+		return err
+	}
+	bc.checkpoints = sorted
+	return nil
+}
+
+// FinalizedHeight returns the height up to which the chain is final (-1 if no checkpoint is reached)
+func (bc *Blockchain) FinalizedHeight() int64 {
+	return finalizedHeight(bc.Chain, bc.checkpoints)
+}
+
+// GetConfirmations returns the confirmation status of a transaction
+func (bc *Blockchain) GetConfirmations(txHash string) *ConfirmationStatus {
+	return confirmationStatus(bc.Chain, bc.transactionIndex(), bc.TransactionPool, bc.checkpoints, txHash)
+}
+
+// IsConfirmed reports whether a transaction is final or has at least depth confirmations
+func (bc *Blockchain) IsConfirmed(txHash string, depth int64) bool {
+	return bc.GetConfirmations(txHash).Confirmed(depth)
+}
+
+// SetFinalityCheckpoints replaces the checkpoints blocks are considered final at (nil clears them)
+func (pbc *PersistentBlockchain) SetFinalityCheckpoints(checkpoints []Checkpoint) error {
+	sorted, err := validateCheckpoints(checkpoints)
+	if err != nil {
+		return err
+	}
+	pbc.checkpoints = sorted
+	return nil
+}
+
+// FinalizedHeight returns the height up to which the chain is final (-1 if no checkpoint is reached)
+func (pbc *PersistentBlockchain) FinalizedHeight() int64 {
+	return finalizedHeight(pbc.Chain, pbc.checkpoints)
+}
+
+// GetConfirmations returns the confirmation status of a transaction
+func (pbc *PersistentBlockchain) GetConfirmations(txHash string) *ConfirmationStatus {
+	return confirmationStatus(pbc.Chain, pbc.transactionIndex(), pbc.TransactionPool, pbc.checkpoints, txHash)
+}
+
+// IsConfirmed reports whether a transaction is final or has at least depth confirmations
+func (pbc *PersistentBlockchain) IsConfirmed(txHash string, depth int64) bool {
+	return pbc.GetConfirmations(txHash).Confirmed(depth)
+}
@@ -0,0 +1,494 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"blockchain/blockchain/verify"
+)
+
+// ConformanceSuiteVersion is the version of the conformance vector format
+const ConformanceSuiteVersion = 1
+
+// ConformanceSuite holds consensus test vectors for fixed inputs, so alternative
+// implementations can check they encode and hash exactly like this one
+type ConformanceSuite struct {
+	Version      int                 `json:"version"`
+	Description  string              `json:"description"`
+	Transactions []TransactionVector `json:"transactions"`
+	Blocks       []BlockVector       `json:"blocks"`
+	MerkleRoots  []MerkleRootVector  `json:"merkleRoots"`
+	MerkleProofs []MerkleProofVector `json:"merkleProofs"`
+	Addresses    []AddressVector     `json:"addresses"`
+	Signatures   []SignatureVector   `json:"signatures"`
+}
+
+// TransactionVector pins the canonical encoding and hash of a transaction
+type TransactionVector struct {
+	Name        string      `json:"name"`
+	Transaction Transaction `json:"transaction"` // Hash field is ignored on input
+	Encoding    string      `json:"encoding"`    // Exact bytes hashed, as a string
+	Hash        string      `json:"hash"`
+}
+
+// BlockVector pins the canonical header encoding and hash of a block
+type BlockVector struct {
+	Name     string      `json:"name"`
+	Header   BlockHeader `json:"header"` // Hash field is ignored on input
+	Encoding string      `json:"encoding"`
+	Hash     string      `json:"hash"`
+}
+
+// MerkleRootVector pins the Merkle root of a list of transaction hashes
+type MerkleRootVector struct {
+	Name   string       `json:"name"`
+	Leaves []string     `json:"leaves"`
+	Scheme MerkleScheme `json:"scheme,omitempty"`
+	Root   string       `json:"root"`
+}
+
+// MerkleProofVector checks proof verification, including proofs that must be rejected
+type MerkleProofVector struct {
+	Name    string      `json:"name"`
+	Leaves  []string    `json:"leaves"`
+	Root    string      `json:"root"`
+	Proof   MerkleProof `json:"proof"`
+	Encoded string      `json:"encoded,omitempty"` // EncodeString form of a valid proof
+	Valid   bool        `json:"valid"`
+}
+
+// AddressVector pins the address derived from a public key
+type AddressVector struct {
+	Name       string `json:"name"`
+	PublicKeyX string `json:"publicKeyX"` // Hex, big-endian
+	PublicKeyY string `json:"publicKeyY"`
+	Address    string `json:"address"`
+}
+
+// SignatureVector checks transaction signature verification with a fixed P-256 key
+type SignatureVector struct {
+	Name        string      `json:"name"`
+	PublicKeyX  string      `json:"publicKeyX"`
+	PublicKeyY  string      `json:"publicKeyY"`
+	Transaction Transaction `json:"transaction"`
+	Message     string      `json:"message"`   // Exact bytes signed (SHA-256 is applied before signing)
+	Signature   string      `json:"signature"` // Hex r||s
+	Valid       bool        `json:"valid"`
+}
+
+// ConformanceResult is the outcome of a single vector
+type ConformanceResult struct {
+	Category string `json:"category"`
+	Name     string `json:"name"`
+	Passed   bool   `json:"passed"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// ConformanceReport summarizes a conformance run
+type ConformanceReport struct {
+	Results []ConformanceResult `json:"results"`
+	Passed  int                 `json:"passed"`
+	Failed  int                 `json:"failed"`
+}
+
+// add records the outcome of a vector
+func (cr *ConformanceReport) add(category, name, detail string) {
+	result := ConformanceResult{Category: category, Name: name, Passed: detail == "", Detail: detail}
+	cr.Results = append(cr.Results, result)
+	if result.Passed {
+		cr.Passed++
+	} else {
+		cr.Failed++
+	}
+}
+
+// String returns a human-readable summary listing the failed vectors
+func (cr *ConformanceReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Conformance: %d passed, %d failed\n", cr.Passed, cr.Failed)
+	for _, result := range cr.Results {
+		if !result.Passed {
+			fmt.Fprintf(&sb, "  FAIL %s/%s: %s\n", result.Category, result.Name, result.Detail)
+		}
+	}
+	return sb.String()
+}
+
+// LoadConformanceSuite reads a JSON conformance suite
+func LoadConformanceSuite(r io.Reader) (*ConformanceSuite, error) {
+	var suite ConformanceSuite
+	if err := json.NewDecoder(r).Decode(&suite); err != nil {
+		return nil, fmt.Errorf("failed to decode conformance suite: %w", err)
+	}
+	if suite.Version != ConformanceSuiteVersion {
+		return nil, fmt.Errorf("unsupported conformance suite version %d", suite.Version)
+	}
+	return &suite, nil
+}
+
+// RunConformance validates this implementation against every vector of a suite
+func RunConformance(suite *ConformanceSuite) *ConformanceReport {
+	report := &ConformanceReport{}
+
+	for _, v := range suite.Transactions {
+		tx := v.Transaction
+		report.add("transactions", v.Name, firstMismatch(
+			"encoding", string(tx.encode()), v.Encoding,
+			"hash", tx.calculateHash(), v.Hash))
+	}
+
+	for _, v := range suite.Blocks {
+		block := Block{
+			Version:    v.Header.Version,
+			Index:      v.Header.Index,
+			Timestamp:  v.Header.Timestamp,
+			PrevHash:   v.Header.PrevHash,
+			Nonce:      v.Header.Nonce,
+			MerkleRoot: v.Header.MerkleRoot,
+		}
+		report.add("blocks", v.Name, firstMismatch(
+			"encoding", string(block.encodeHeader()), v.Encoding,
+			"hash", block.calculateHash(), v.Hash))
+	}
+
+	for _, v := range suite.MerkleRoots {
+		report.add("merkleRoots", v.Name, firstMismatch("root", merkleRootOf(v.Leaves, v.Scheme), v.Root))
+	}
+
+	for _, v := range suite.MerkleProofs {
+		proof := v.Proof
+		detail := ""
+		if VerifyProof(&proof, v.Root, proof.Scheme) != v.Valid {
+			detail = fmt.Sprintf("verification returned %v, expected %v", !v.Valid, v.Valid)
+		} else if v.Valid {
+			// Valid proofs must also be the ones this implementation generates
+			generated, err := merkleTreeOf(v.Leaves, proof.Scheme).GenerateProof(proof.Hash)
+			if err != nil {
+				detail = fmt.Sprintf("failed to generate proof: %v", err)
+			} else {
+				got, _ := json.Marshal(generated)
+				want, _ := json.Marshal(proof)
+				detail = firstMismatch("generated proof", string(got), string(want))
+			}
+			if detail == "" {
+				detail = checkProofEncoding(&proof, v.Encoded)
+			}
+		}
+		report.add("merkleProofs", v.Name, detail)
+	}
+
+	for _, v := range suite.Addresses {
+		publicKey, err := parseVectorPublicKey(v.PublicKeyX, v.PublicKeyY)
+		if err != nil {
+			report.add("addresses", v.Name, err.Error())
+			continue
+		}
+		report.add("addresses", v.Name, firstMismatch("address", generateAddress(publicKey), v.Address))
+	}
+
+	for _, v := range suite.Signatures {
+		publicKey, err := parseVectorPublicKey(v.PublicKeyX, v.PublicKeyY)
+		if err != nil {
+			report.add("signatures", v.Name, err.Error())
+			continue
+		}
+		detail := firstMismatch("message", string(signingMessage(v.Transaction)), v.Message)
+		if detail == "" && verifyTransactionSignature(publicKey, v.Transaction, v.Signature) != v.Valid {
+			detail = fmt.Sprintf("verification returned %v, expected %v", !v.Valid, v.Valid)
+		}
+		report.add("signatures", v.Name, detail)
+	}
+
+	return report
+}
+
+// firstMismatch compares (name, got, want) triples and describes the first difference
+func firstMismatch(fields ...string) string {
+	for i := 0; i+2 < len(fields); i += 3 {
+		if fields[i+1] != fields[i+2] {
+			return fmt.Sprintf("%s mismatch: got %q, want %q", fields[i], fields[i+1], fields[i+2])
+		}
+	}
+	return ""
+}
+
+// checkProofEncoding checks a valid proof has the pinned string form, if any, and decodes
+// back from it unchanged
+func checkProofEncoding(proof *MerkleProof, pinned string) string {
+	encoded, err := proof.EncodeString()
+	if err != nil {
+		return fmt.Sprintf("failed to encode proof: %v", err)
+	}
+	if pinned != "" {
+		if detail := firstMismatch("encoded proof", encoded, pinned); detail != "" {
+			return detail
+		}
+	}
+	decoded, err := DecodeProofString(encoded)
+	if err != nil {
+		return fmt.Sprintf("failed to decode proof: %v", err)
+	}
+	got, _ := json.Marshal(decoded)
+	want, _ := json.Marshal(proof)
+	return firstMismatch("decoded proof", string(got), string(want))
+}
+
+// merkleTreeOf builds a Merkle tree over transaction hashes
+func merkleTreeOf(leaves []string, scheme MerkleScheme) *MerkleTree {
+	transactions := make([]Transaction, len(leaves))
+	for i, leaf := range leaves {
+		transactions[i].Hash = leaf
+	}
+	return NewSchemeMerkleTree(transactions, scheme)
+}
+
+// merkleRootOf returns the Merkle root of transaction hashes
+func merkleRootOf(leaves []string, scheme MerkleScheme) string {
+	return merkleTreeOf(leaves, scheme).GetMerkleRoot()
+}
+
+// merkleProofVectors generates a valid proof vector for every leaf of a tree
+func merkleProofVectors(nameFormat string, leaves []string, scheme MerkleScheme) ([]MerkleProofVector, error) {
+	tree := merkleTreeOf(leaves, scheme)
+	vectors := make([]MerkleProofVector, 0, len(leaves))
+	for i := range leaves {
+		proof, err := tree.GenerateProofByIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := proof.EncodeString()
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, MerkleProofVector{
+			Name: fmt.Sprintf(nameFormat, i), Leaves: leaves, Root: tree.GetMerkleRoot(), Proof: *proof, Encoded: encoded, Valid: true,
+		})
+	}
+	return vectors, nil
+}
+
+// parseVectorPublicKey decodes a P-256 public key given as hex coordinates
+func parseVectorPublicKey(xHex, yHex string) (*ecdsa.PublicKey, error) {
+	x, okX := new(big.Int).SetString(xHex, 16)
+	y, okY := new(big.Int).SetString(yHex, 16)
+	if !okX || !okY {
+		return nil, fmt.Errorf("malformed public key coordinates")
+	}
+	if !elliptic.P256().IsOnCurve(x, y) {
+		return nil, fmt.Errorf("public key is not on the P-256 curve")
+	}
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+}
+
+// conformanceKey derives a fixed P-256 key from a seed, so generated vectors use stable keys
+func conformanceKey(seed string) *ecdsa.PrivateKey {
+	curve := elliptic.P256()
+	digest := sha256.Sum256([]byte(seed))
+	d := new(big.Int).SetBytes(digest[:])
+	d.Mod(d, new(big.Int).Sub(curve.Params().N, big.NewInt(1)))
+	d.Add(d, big.NewInt(1))
+
+	key := &ecdsa.PrivateKey{D: d}
+	key.PublicKey.Curve = curve
+	key.PublicKey.X, key.PublicKey.Y = curve.ScalarBaseMult(d.Bytes())
+	return key
+}
+
+// GenerateConformanceSuite computes the conformance vectors of this implementation.
+// Inputs are fixed; only signatures change between runs since ECDSA signing is randomized.
+func GenerateConformanceSuite() (*ConformanceSuite, error) {
+	suite := &ConformanceSuite{
+		Version:     ConformanceSuiteVersion,
+		Description: "Consensus encodings, hashes, Merkle trees, addresses and signatures for fixed inputs",
+	}
+
+	txInputs := []struct {
+		name string
+		tx   Transaction
+	}{
+		{"unsequenced", Transaction{From: "alice", To: "bob", Amount: 10, Fee: 0.1}},
+		{"sequenced", Transaction{From: "alice", To: "bob", Amount: 10, Fee: 0.1, Nonce: 1}},
+		{"coinbase", Transaction{From: CoinbaseSender, To: "miner", Amount: 10}},
+		{"zero-fee", Transaction{From: "alice", To: "bob", Amount: 1}},
+		{"fractional-amount", Transaction{From: "alice", To: "bob", Amount: 0.30000000000000004, Fee: 0.0001}},
+		{"tiny-amount-exponent", Transaction{From: "alice", To: "bob", Amount: 1e-7, Fee: 1e-8}},
+		{"huge-amount-exponent", Transaction{From: "alice", To: "bob", Amount: 1e21, Fee: 123456789.125}},
+		{"large-nonce", Transaction{From: "alice", To: "bob", Amount: 5, Fee: 0.5, Nonce: 9007199254740993}},
+		{"html-escaped-address", Transaction{From: "<alice&co>", To: "bob", Amount: 1, Fee: 0}},
+		{"unicode-address", Transaction{From: "ålice", To: "bøb\u2028", Amount: 2, Fee: 0.2}},
+		{"valid-until-height", Transaction{From: "alice", To: "bob", Amount: 3, Fee: 0.1, ValidUntil: 1000}},
+		{"valid-until-time", Transaction{From: "alice", To: "bob", Amount: 3, Fee: 0.1, Nonce: 2, ValidUntil: 1700003600}},
+	}
+	var leaves []string
+	for _, input := range txInputs {
+		tx := input.tx
+		tx.Hash = tx.calculateHash()
+		leaves = append(leaves, tx.Hash)
+
+		vector := TransactionVector{Name: input.name, Transaction: tx, Encoding: string(tx.encode()), Hash: tx.Hash}
+		vector.Transaction.Hash = ""
+		suite.Transactions = append(suite.Transactions, vector)
+	}
+
+	blockInputs := []struct {
+		name   string
+		header BlockHeader
+	}{
+		{"genesis-like", BlockHeader{Index: 0, Timestamp: 1700000000, PrevHash: "0"}},
+		{"legacy", BlockHeader{Index: 1, Timestamp: 1700000060, PrevHash: strings.Repeat("ab", 32), Nonce: 42, MerkleRoot: leaves[0]}},
+		{"versioned", BlockHeader{Version: 2, Index: 2, Timestamp: 1700000120, PrevHash: strings.Repeat("cd", 32), Nonce: 7, MerkleRoot: leaves[1]}},
+		{"negative-version", BlockHeader{Version: -1, Index: 3, Timestamp: 1700000180, PrevHash: strings.Repeat("ef", 32), Nonce: 1, MerkleRoot: leaves[2]}},
+	}
+	for _, input := range blockInputs {
+		block := Block{
+			Version:    input.header.Version,
+			Index:      input.header.Index,
+			Timestamp:  input.header.Timestamp,
+			PrevHash:   input.header.PrevHash,
+			Nonce:      input.header.Nonce,
+			MerkleRoot: input.header.MerkleRoot,
+		}
+		suite.Blocks = append(suite.Blocks, BlockVector{
+			Name:     input.name,
+			Header:   block.Header(),
+			Encoding: string(block.encodeHeader()),
+			Hash:     block.calculateHash(),
+		})
+		suite.Blocks[len(suite.Blocks)-1].Header.Hash = ""
+	}
+
+	for _, count := range []int{1, 2, 3, 4, 5, 7, 10} {
+		suite.MerkleRoots = append(suite.MerkleRoots, MerkleRootVector{
+			Name:   fmt.Sprintf("%d-leaves", count),
+			Leaves: leaves[:count],
+			Root:   merkleRootOf(leaves[:count], LegacyMerkleScheme),
+		})
+	}
+
+	proofLeaves := leaves[:5]
+	proofRoot := merkleRootOf(proofLeaves, LegacyMerkleScheme)
+	proofs, err := merkleProofVectors("leaf-%d-of-5", proofLeaves, LegacyMerkleScheme)
+	if err != nil {
+		return nil, err
+	}
+	suite.MerkleProofs = append(suite.MerkleProofs, proofs...)
+	flipped := suite.MerkleProofs[1].Proof
+	flipped.IsLeft = append([]bool(nil), flipped.IsLeft...)
+	flipped.IsLeft[0] = !flipped.IsLeft[0]
+	foreign := suite.MerkleProofs[2].Proof
+	foreign.Hash = leaves[6]
+	suite.MerkleProofs = append(suite.MerkleProofs,
+		MerkleProofVector{Name: "flipped-direction", Leaves: proofLeaves, Root: proofRoot, Proof: flipped, Valid: false},
+		MerkleProofVector{Name: "foreign-leaf", Leaves: proofLeaves, Root: proofRoot, Proof: foreign, Valid: false},
+	)
+
+	// Tagged trees, including a list ending in a repeated leaf, which a legacy tree can't tell apart
+	for _, count := range []int{1, 2, 3, 5, 7} {
+		suite.MerkleRoots = append(suite.MerkleRoots, MerkleRootVector{
+			Name:   fmt.Sprintf("%d-leaves-tagged", count),
+			Leaves: leaves[:count],
+			Scheme: TaggedMerkleScheme,
+			Root:   merkleRootOf(leaves[:count], TaggedMerkleScheme),
+		})
+	}
+	repeated := append(leaves[:3:3], leaves[2])
+	for _, scheme := range []MerkleScheme{LegacyMerkleScheme, TaggedMerkleScheme} {
+		name := "repeated-last-leaf"
+		if scheme == TaggedMerkleScheme {
+			name += "-tagged"
+		}
+		suite.MerkleRoots = append(suite.MerkleRoots, MerkleRootVector{
+			Name: name, Leaves: repeated, Scheme: scheme, Root: merkleRootOf(repeated, scheme),
+		})
+	}
+	proofs, err = merkleProofVectors("tagged-leaf-%d-of-5", proofLeaves, TaggedMerkleScheme)
+	if err != nil {
+		return nil, err
+	}
+	suite.MerkleProofs = append(suite.MerkleProofs, proofs...)
+	// An inner node passed off as a leaf proves nothing in a tagged tree
+	inner := proofs[0].Proof
+	inner.Hash = verify.TaggedNodeHash(verify.TaggedLeafHash(proofLeaves[0]), inner.Hashes[0])
+	inner.Hashes, inner.IsLeft = inner.Hashes[1:], inner.IsLeft[1:]
+	suite.MerkleProofs = append(suite.MerkleProofs, MerkleProofVector{
+		Name: "tagged-inner-node", Leaves: proofLeaves, Root: proofs[0].Root, Proof: inner, Valid: false,
+	})
+
+	for i := 1; i <= 3; i++ {
+		key := conformanceKey(fmt.Sprintf("conformance-key-%d", i))
+		suite.Addresses = append(suite.Addresses, AddressVector{
+			Name:       fmt.Sprintf("key-%d", i),
+			PublicKeyX: key.X.Text(16),
+			PublicKeyY: key.Y.Text(16),
+			Address:    generateAddress(&key.PublicKey),
+		})
+	}
+
+	key := conformanceKey("conformance-key-1")
+	wallet := &Wallet{PrivateKey: key, PublicKey: &key.PublicKey, Address: generateAddress(&key.PublicKey)}
+	signed := Transaction{From: wallet.Address, To: "bob", Amount: 12.5, Fee: 0.1}
+	signature, err := wallet.SignTransaction(signed)
+	if err != nil {
+		return nil, err
+	}
+	tampered := signed
+	tampered.Amount = 125
+	renonced := signed
+	renonced.Nonce = 1
+	refeed := signed
+	refeed.Fee = 0.2
+	windowed := signed
+	windowed.ValidUntil = 1000
+
+	sigBytes, _ := hex.DecodeString(signature)
+	sigBytes[len(sigBytes)-1] ^= 0x01
+
+	otherKey := conformanceKey("conformance-key-2")
+	otherSignature, err := (&Wallet{PrivateKey: otherKey, PublicKey: &otherKey.PublicKey}).SignTransaction(signed)
+	if err != nil {
+		return nil, err
+	}
+
+	derSignature, err := SignatureToDER(signature)
+	if err != nil {
+		return nil, err
+	}
+	r, lowS, _ := parseSignatureHex(signature)
+	highS := hex.EncodeToString(encodeSignature(r, new(big.Int).Sub(elliptic.P256().Params().N, lowS)))
+
+	signatureVector := func(name string, tx Transaction, signature string, valid bool) SignatureVector {
+		return SignatureVector{
+			Name:        name,
+			PublicKeyX:  key.X.Text(16),
+			PublicKeyY:  key.Y.Text(16),
+			Transaction: tx,
+			Message:     string(signingMessage(tx)),
+			Signature:   signature,
+			Valid:       valid,
+		}
+	}
+	suite.Signatures = []SignatureVector{
+		signatureVector("valid", signed, signature, true),
+		signatureVector("tampered-amount", tampered, signature, false),
+		signatureVector("tampered-nonce", renonced, signature, false),
+		signatureVector("tampered-fee", refeed, signature, false),
+		signatureVector("tampered-valid-until", windowed, signature, false),
+		signatureVector("corrupted-signature", signed, hex.EncodeToString(sigBytes), false),
+		signatureVector("wrong-key", signed, otherSignature, false),
+		signatureVector("der-encoded", signed, derSignature, true),
+		signatureVector("high-s", signed, highS, false),
+	}
+
+	// Guard against generating a suite this implementation would not pass itself
+	if report := RunConformance(suite); report.Failed > 0 {
+		return nil, fmt.Errorf("generated suite fails self-check:\n%s", report)
+	}
+	return suite, nil
+}
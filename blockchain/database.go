@@ -2,11 +2,12 @@ package blockchain
 
 import (
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -14,6 +15,15 @@ import (
 type Database struct {
 	db   *sql.DB
 	path string
+	pg   bool // PostgreSQL: $n placeholders and its column types (see sql_dialect.go)
+
+	compression string // Codec of new block_data values (see block_compression.go)
+
+	// Block write pipeline (see block_writer.go)
+	writeMu sync.RWMutex
+	writer  *blockWriter // Background writer in async mode
+	stmtMu  sync.Mutex
+	stmts   map[string]*sql.Stmt
 }
 
 // DatabaseConfig holds database configuration
@@ -25,6 +35,13 @@ type DatabaseConfig struct {
 	User     string
 	Password string
 	DBName   string
+	Writes   WriteConfig // How SQL databases persist blocks (see SetWriteConfig)
+
+	// Compression compresses the block_data column of SQL databases: "gzip", "zstd" or
+	// "none" (the default). Blocks are read whatever codec they were stored with, so it can
+	// be changed at any time; CompressBlocks converts the existing rows. LevelDB compresses
+	// its tables itself and ignores it.
+	Compression string
 }
 
 // NewDatabase creates a new database connection
@@ -44,24 +61,39 @@ func NewDatabase(config DatabaseConfig) (*Database, error) {
 	}
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %v", err)
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	compression, err := normalizeCompression(config.Compression)
+	if err != nil {
+		db.Close()
+		return nil, err
 	}
 
 	database := &Database{
-		db:   db,
-		path: config.Path,
+		db:          db,
+		path:        config.Path,
+		pg:          config.Driver == "postgres",
+		compression: compression,
 	}
 
 	// Initialize database schema
 	if err := database.initSchema(); err != nil {
-		return nil, fmt.Errorf("failed to initialize schema: %v", err)
+		return nil, fmt.Errorf("failed to initialize schema: %w", err)
 	}
 
+	if err := database.SetWriteConfig(config.Writes); err != nil {
+		return nil, err
+	}
 	return database, nil
 }
 
 // Close closes the database connection
 func (d *Database) Close() error {
+	if err := d.SetWriteConfig(WriteConfig{}); err != nil {
+		log.Printf("Failed to write queued blocks: %v", err)
+	}
+	d.closeStatements()
 	return d.db.Close()
 }
 
@@ -148,6 +180,106 @@ func (d *Database) initSchema() error {
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);`
 
+	// Create peers table for the P2P peer database
+	peersTable := `
+	CREATE TABLE IF NOT EXISTS peers (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		address TEXT UNIQUE NOT NULL,
+		first_seen INTEGER NOT NULL,
+		last_seen INTEGER NOT NULL,
+		last_connected INTEGER DEFAULT 0,
+		last_failure INTEGER DEFAULT 0,
+		attempts INTEGER DEFAULT 0,
+		successes INTEGER DEFAULT 0,
+		failures INTEGER DEFAULT 0,
+		total_uptime INTEGER DEFAULT 0,
+		score REAL DEFAULT 0.0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Create bans table for misbehaving peers
+	bansTable := `
+	CREATE TABLE IF NOT EXISTS bans (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		host TEXT UNIQUE NOT NULL,
+		reason TEXT NOT NULL,
+		banned_at INTEGER NOT NULL,
+		banned_until INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Create admissions table for the mempool admission audit log
+	admissionsTable := `
+	CREATE TABLE IF NOT EXISTS admissions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tx_hash TEXT NOT NULL,
+		from_address TEXT NOT NULL,
+		accepted BOOLEAN NOT NULL,
+		reason TEXT NOT NULL,
+		detail TEXT NOT NULL,
+		source TEXT NOT NULL,
+		timestamp INTEGER NOT NULL
+	);`
+
+	// Create ledger entries table tagging every balance change with its origin
+	ledgerEntriesTable := `
+	CREATE TABLE IF NOT EXISTS ledger_entries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		address TEXT NOT NULL,
+		tx_hash TEXT NOT NULL,
+		block_index INTEGER NOT NULL,
+		tx_index INTEGER NOT NULL,
+		source TEXT NOT NULL,
+		amount REAL NOT NULL,
+		timestamp INTEGER NOT NULL
+	);`
+
+	// Create derived index table tracking the version and reindex progress of derived tables
+	derivedIndexTable := `
+	CREATE TABLE IF NOT EXISTS derived_index (
+		id INTEGER PRIMARY KEY,
+		version INTEGER NOT NULL,
+		reindex_next_height INTEGER,
+		reindex_started_at INTEGER
+	);`
+
+	// Create prune state table summarizing the blocks whose bodies were pruned
+	pruneStateTable := `
+	CREATE TABLE IF NOT EXISTS prune_state (
+		id INTEGER PRIMARY KEY,
+		height INTEGER NOT NULL,
+		state TEXT NOT NULL,
+		last_updated INTEGER NOT NULL
+	);`
+
+	// Create legal hold table tagging data that pruning must keep
+	legalHoldsTable := `
+	CREATE TABLE IF NOT EXISTS legal_holds (
+		kind TEXT NOT NULL,
+		id TEXT NOT NULL,
+		block_index INTEGER NOT NULL,
+		reason TEXT NOT NULL,
+		placed_at INTEGER NOT NULL,
+		PRIMARY KEY (kind, id)
+	);`
+
+	// Create state snapshot table holding signed balance snapshots for fast sync
+	stateSnapshotsTable := `
+	CREATE TABLE IF NOT EXISTS state_snapshots (
+		height INTEGER PRIMARY KEY,
+		hash TEXT NOT NULL,
+		snapshot_data TEXT NOT NULL,
+		created_at INTEGER NOT NULL
+	);`
+
+	// Create mempool table, the pending transactions saved at shutdown
+	mempoolTable := `
+	CREATE TABLE IF NOT EXISTS mempool (
+		hash TEXT PRIMARY KEY,
+		transaction_data TEXT NOT NULL,
+		added_at INTEGER NOT NULL
+	);`
+
 	// Create indexes for better query performance
 	indexes := []string{
 		"CREATE INDEX IF NOT EXISTS idx_blocks_index ON blocks(block_index);",
@@ -162,17 +294,32 @@ func (d *Database) initSchema() error {
 		"CREATE INDEX IF NOT EXISTS idx_enhanced_transactions_from ON enhanced_transactions(from_address);",
 		"CREATE INDEX IF NOT EXISTS idx_enhanced_transactions_to ON enhanced_transactions(to_address);",
 		"CREATE INDEX IF NOT EXISTS idx_addresses_address ON addresses(address);",
+		"CREATE INDEX IF NOT EXISTS idx_peers_score ON peers(score);",
+		"CREATE INDEX IF NOT EXISTS idx_bans_until ON bans(banned_until);",
+		"CREATE INDEX IF NOT EXISTS idx_admissions_tx_hash ON admissions(tx_hash);",
+		"CREATE INDEX IF NOT EXISTS idx_admissions_timestamp ON admissions(timestamp);",
+		"CREATE INDEX IF NOT EXISTS idx_ledger_entries_address ON ledger_entries(address, source);",
+		"CREATE INDEX IF NOT EXISTS idx_legal_holds_block ON legal_holds(block_index);",
 	}
 
 	// Execute table creation statements
-	tables := []string{blocksTable, transactionsTable, enhancedTransactionsTable, addressesTable, blockchainStateTable}
+	tables := []string{blocksTable, transactionsTable, enhancedTransactionsTable, addressesTable, blockchainStateTable, peersTable, bansTable, admissionsTable, ledgerEntriesTable, derivedIndexTable, pruneStateTable, legalHoldsTable, stateSnapshotsTable, mempoolTable}
 
 	for _, table := range tables {
-		if _, err := d.db.Exec(table); err != nil {
-			return fmt.Errorf("failed to create table: %v", err)
+		if _, err := d.db.Exec(d.schema(table)); err != nil {
+			return fmt.Errorf("failed to create table: %w", err)
 		}
 	}
 
+	// A new database builds its derived tables at the current version as blocks are saved
+	_, err := d.db.Exec(d.rebind(`
+		INSERT INTO derived_index (id, version)
+		SELECT 1, CAST(? AS INTEGER) WHERE NOT EXISTS (SELECT 1 FROM blocks) AND NOT EXISTS (SELECT 1 FROM derived_index)`),
+		DerivedIndexVersion)
+	if err != nil {
+		return fmt.Errorf("failed to initialize derived index version: %w", err)
+	}
+
 	// Create indexes
 	for _, index := range indexes {
 		if _, err := d.db.Exec(index); err != nil {
@@ -183,116 +330,36 @@ func (d *Database) initSchema() error {
 	return nil
 }
 
-// SaveBlock saves a block to the database
+// SaveBlock saves a block with its transactions, ledger entries and address balances, using
+// multi-row inserts and prepared statements. In async mode (see WriteConfig) the block is
+// queued and SaveBlock returns before it is committed.
 func (d *Database) SaveBlock(block *Block) error {
-	tx, err := d.db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %v", err)
-	}
-	defer tx.Rollback()
-
-	// Serialize block data
-	blockData, err := json.Marshal(block)
-	if err != nil {
-		return fmt.Errorf("failed to serialize block: %v", err)
-	}
-
-	// Insert block
-	_, err = tx.Exec(`
-		INSERT INTO blocks (block_index, hash, previous_hash, merkle_root, timestamp, nonce, difficulty, transaction_count, block_data)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		block.Index, block.Hash, block.PrevHash, block.MerkleRoot,
-		block.Timestamp, block.Nonce, 4, // difficulty hardcoded for now
-		len(block.Transactions), string(blockData))
-
-	if err != nil {
-		return fmt.Errorf("failed to insert block: %v", err)
-	}
-
-	// Save transactions
-	for i, transaction := range block.Transactions {
-		if err := d.saveTransaction(tx, &transaction, block.Hash, block.Index, i); err != nil {
-			return fmt.Errorf("failed to save transaction: %v", err)
-		}
-	}
-
-	// Update blockchain state
-	if err := d.updateBlockchainState(tx, block); err != nil {
-		return fmt.Errorf("failed to update blockchain state: %v", err)
-	}
-
-	return tx.Commit()
-}
-
-// saveTransaction saves a transaction to the database (internal helper)
-func (d *Database) saveTransaction(tx *sql.Tx, transaction *Transaction, blockHash string, blockIndex int64, txIndex int) error {
-	// Serialize transaction data
-	txData, err := json.Marshal(transaction)
-	if err != nil {
-		return fmt.Errorf("failed to serialize transaction: %v", err)
-	}
-
-	// Insert transaction
-	_, err = tx.Exec(`
-		INSERT INTO transactions (hash, block_hash, block_index, tx_index, from_address, to_address, amount, fee, timestamp, transaction_data)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		transaction.Hash, blockHash, blockIndex, txIndex,
-		transaction.From, transaction.To, transaction.Amount, transaction.Fee,
-		time.Now().Unix(), string(txData))
-
-	if err != nil {
-		return err
-	}
-
-	// Update address balances
-	if err := d.updateAddressBalance(tx, transaction.From, -transaction.Amount-transaction.Fee); err != nil {
-		return err
-	}
-	if err := d.updateAddressBalance(tx, transaction.To, transaction.Amount); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-// updateAddressBalance updates the balance for an address
-func (d *Database) updateAddressBalance(tx *sql.Tx, address string, change float64) error {
-	now := time.Now().Unix()
-
-	// Try to update existing address
-	result, err := tx.Exec(`
-		UPDATE addresses SET balance = balance + ?, transaction_count = transaction_count + 1, last_updated = ?
-		WHERE address = ?`, change, now, address)
+	rows, err := d.newBlockRows(block, true)
 	if err != nil {
 		return err
 	}
 
-	// If no rows affected, insert new address
-	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
-		_, err = tx.Exec(`
-			INSERT INTO addresses (address, balance, transaction_count, first_seen, last_updated)
-			VALUES (?, ?, 1, ?, ?)`, address, change, now, now)
-		if err != nil {
-			return err
-		}
+	d.writeMu.RLock()
+	defer d.writeMu.RUnlock()
+	if d.writer != nil {
+		return d.writer.enqueue(rows)
 	}
-
-	return nil
+	return d.saveBlockRows([]*blockRows{rows})
 }
 
 // updateBlockchainState updates the blockchain state
-func (d *Database) updateBlockchainState(tx *sql.Tx, block *Block) error {
+func (d *Database) updateBlockchainState(tx *sql.Tx, hash string, index int64, transactions int) error {
 	now := time.Now().Unix()
 
 	// Try to update existing state
-	result, err := tx.Exec(`
+	result, err := tx.Exec(d.rebind(`
 		UPDATE blockchain_state SET 
 			latest_block_hash = ?, 
 			latest_block_index = ?, 
 			total_blocks = total_blocks + 1, 
 			total_transactions = total_transactions + ?, 
 			last_updated = ?
-		WHERE id = 1`, block.Hash, block.Index, len(block.Transactions), now)
+		WHERE id = 1`), hash, index, transactions, now)
 
 	if err != nil {
 		return err
@@ -300,10 +367,10 @@ func (d *Database) updateBlockchainState(tx *sql.Tx, block *Block) error {
 
 	// If no rows affected, insert initial state
 	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
-		_, err = tx.Exec(`
+		_, err = tx.Exec(d.rebind(`
 			INSERT INTO blockchain_state (id, latest_block_hash, latest_block_index, total_blocks, total_transactions, difficulty, mining_reward, last_updated)
-			VALUES (1, ?, ?, 1, ?, 4, 10.0, ?)`,
-			block.Hash, block.Index, len(block.Transactions), now)
+			VALUES (1, ?, ?, 1, ?, 4, 10.0, ?)`),
+			hash, index, transactions, now)
 	}
 
 	return err
@@ -312,55 +379,58 @@ func (d *Database) updateBlockchainState(tx *sql.Tx, block *Block) error {
 // GetBlock retrieves a block by hash
 func (d *Database) GetBlock(hash string) (*Block, error) {
 	var blockData string
-	err := d.db.QueryRow("SELECT block_data FROM blocks WHERE hash = ?", hash).Scan(&blockData)
+	err := d.db.QueryRow(d.rebind("SELECT block_data FROM blocks WHERE hash = ?"), hash).Scan(&blockData)
 	if err != nil {
-		return nil, err
+		return nil, blockNotFound(err)
 	}
 
-	var block Block
-	if err := json.Unmarshal([]byte(blockData), &block); err != nil {
-		return nil, fmt.Errorf("failed to deserialize block: %v", err)
-	}
-
-	return &block, nil
+	return unpruned(decodeStoredBlock(blockData))
 }
 
 // GetBlockByIndex retrieves a block by index
 func (d *Database) GetBlockByIndex(index int64) (*Block, error) {
 	var blockData string
-	err := d.db.QueryRow("SELECT block_data FROM blocks WHERE block_index = ?", index).Scan(&blockData)
+	err := d.db.QueryRow(d.rebind("SELECT block_data FROM blocks WHERE block_index = ?"), index).Scan(&blockData)
 	if err != nil {
-		return nil, err
-	}
-
-	var block Block
-	if err := json.Unmarshal([]byte(blockData), &block); err != nil {
-		return nil, fmt.Errorf("failed to deserialize block: %v", err)
+		return nil, blockNotFound(err)
 	}
 
-	return &block, nil
+	return unpruned(decodeStoredBlock(blockData))
 }
 
 // GetLatestBlock retrieves the latest block
 func (d *Database) GetLatestBlock() (*Block, error) {
 	var blockData string
-	err := d.db.QueryRow("SELECT block_data FROM blocks ORDER BY block_index DESC LIMIT 1").Scan(&blockData)
+	err := d.db.QueryRow(d.rebind("SELECT block_data FROM blocks ORDER BY block_index DESC LIMIT 1")).Scan(&blockData)
 	if err != nil {
-		return nil, err
+		return nil, blockNotFound(err)
 	}
 
-	var block Block
-	if err := json.Unmarshal([]byte(blockData), &block); err != nil {
-		return nil, fmt.Errorf("failed to deserialize block: %v", err)
+	block, err := decodeStoredBlock(blockData)
+	if err != nil {
+		return nil, err
 	}
 
-	return &block, nil
+	return block, nil
+}
+
+// blockNotFound maps the no-rows error of a block query to ErrBlockNotFound
+func blockNotFound(err error) error {
+	if err == sql.ErrNoRows {
+		return ErrBlockNotFound
+	}
+	return err
 }
 
 // GetAddressBalance retrieves the balance for an address
 func (d *Database) GetAddressBalance(address string) (float64, error) {
+	// Balances read their own writes: wait for blocks queued in async mode
+	if err := d.Flush(); err != nil {
+		return 0, err
+	}
+
 	var balance float64
-	err := d.db.QueryRow("SELECT COALESCE(balance, 0) FROM addresses WHERE address = ?", address).Scan(&balance)
+	err := d.db.QueryRow(d.rebind("SELECT COALESCE(balance, 0) FROM addresses WHERE address = ?"), address).Scan(&balance)
 	if err != nil && err != sql.ErrNoRows {
 		return 0, err
 	}
@@ -378,10 +448,10 @@ func (d *Database) GetBlockchainStats() (map[string]interface{}, error) {
 	var miningReward float64
 	var lastUpdated int64
 
-	err := d.db.QueryRow(`
+	err := d.db.QueryRow(d.rebind(`
 		SELECT latest_block_hash, latest_block_index, total_blocks, total_transactions, 
 		       difficulty, mining_reward, last_updated 
-		FROM blockchain_state WHERE id = 1`).Scan(
+		FROM blockchain_state WHERE id = 1`)).Scan(
 		&latestBlockHash, &latestBlockIndex, &totalBlocks, &totalTransactions,
 		&difficulty, &miningReward, &lastUpdated)
 
@@ -399,7 +469,7 @@ func (d *Database) GetBlockchainStats() (map[string]interface{}, error) {
 
 	// Get additional stats
 	var addressCount int64
-	d.db.QueryRow("SELECT COUNT(*) FROM addresses").Scan(&addressCount)
+	d.db.QueryRow(d.rebind("SELECT COUNT(*) FROM addresses")).Scan(&addressCount)
 	stats["total_addresses"] = addressCount
 
 	return stats, nil
@@ -407,26 +477,41 @@ func (d *Database) GetBlockchainStats() (map[string]interface{}, error) {
 
 // LoadBlockchain loads the entire blockchain from database
 func (d *Database) LoadBlockchain() ([]*Block, error) {
-	rows, err := d.db.Query("SELECT block_data FROM blocks ORDER BY block_index ASC")
+	var blocks []*Block
+	err := d.IterateBlocks(func(block *Block) error {
+		blocks = append(blocks, block)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return blocks, nil
+}
+
+// IterateBlocks streams the stored blocks to fn in height order, decoding one row at a time,
+// and stops at the first error fn returns
+func (d *Database) IterateBlocks(fn func(*Block) error) error {
+	rows, err := d.db.Query(d.rebind("SELECT block_data FROM blocks ORDER BY block_index ASC"))
+	if err != nil {
+		return err
+	}
 	defer rows.Close()
 
-	var blocks []*Block
 	for rows.Next() {
 		var blockData string
 		if err := rows.Scan(&blockData); err != nil {
-			return nil, err
+			return err
 		}
 
-		var block Block
-		if err := json.Unmarshal([]byte(blockData), &block); err != nil {
-			return nil, fmt.Errorf("failed to deserialize block: %v", err)
+		block, err := decodeStoredBlock(blockData)
+		if err != nil {
+			return err
 		}
 
-		blocks = append(blocks, &block)
+		if err := fn(block); err != nil {
+			return err
+		}
 	}
 
-	return blocks, nil
+	return rows.Err()
 }
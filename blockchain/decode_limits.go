@@ -0,0 +1,233 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Limits on input decoded from peers, API clients and the database. They sit well above
+// anything an honest node produces, so only malformed or hostile input runs into them.
+const (
+	MaxBlockSize           = maxMessageSize // Encoded block
+	MaxBlockTransactions   = 10000
+	MaxTransactionDataSize = 64 * 1024 // Application payload of a transaction
+	MaxFieldLength         = 1024      // Addresses, hashes, public keys and signatures
+	MaxProofDepth          = 64        // Merkle proof siblings, enough for 2^64 leaves
+	MaxBlockExtensions     = 64
+	MaxExtensionDataSize   = 16 * 1024 // Data of a block extension
+	MaxExtensionTypeLength = 64
+)
+
+// LimitError reports input rejected for exceeding a decoding limit
+type LimitError struct {
+	Field string // Offending field, e.g. "transactions[3].data"
+	Size  int    // Bytes or entries found
+	Limit int
+}
+
+// Error implements error
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s exceeds limit: %d > %d", e.Field, e.Size, e.Limit)
+}
+
+// DecodeError reports input that could not be decoded or failed its limits
+type DecodeError struct {
+	Kind string // What was being decoded, e.g. "block"
+	Err  error  // Underlying syntax or *LimitError
+}
+
+// Error implements error
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to deserialize %s: %v", e.Kind, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeBlock decodes a JSON block, whether stored or received, enforcing the block limits
+func DecodeBlock(data []byte) (*Block, error) {
+	if len(data) > MaxBlockSize {
+		return nil, &DecodeError{Kind: "block", Err: &LimitError{Field: "block", Size: len(data), Limit: MaxBlockSize}}
+	}
+
+	var block Block
+	if err := json.Unmarshal(data, &block); err != nil {
+		return nil, &DecodeError{Kind: "block", Err: err}
+	}
+	if err := CheckBlockLimits(&block); err != nil {
+		return nil, &DecodeError{Kind: "block", Err: err}
+	}
+	return &block, nil
+}
+
+// CheckBlockLimits checks a block's transaction count and the sizes of its fields
+func CheckBlockLimits(block *Block) error {
+	if len(block.Transactions) > MaxBlockTransactions {
+		return &LimitError{Field: "transactions", Size: len(block.Transactions), Limit: MaxBlockTransactions}
+	}
+	if err := checkFieldLengths([][2]string{
+		{"prevHash", block.PrevHash},
+		{"hash", block.Hash},
+		{"merkleRoot", block.MerkleRoot},
+		{"kvRoot", block.KVRoot},
+		{"mmrRoot", block.MMRRoot},
+		{"extRoot", block.ExtRoot},
+		{"stateRoot", block.StateRoot},
+		{"receiptRoot", block.ReceiptRoot},
+		{"bloom", block.Bloom},
+	}); err != nil {
+		return err
+	}
+
+	if len(block.Extensions) > MaxBlockExtensions {
+		return &LimitError{Field: "extensions", Size: len(block.Extensions), Limit: MaxBlockExtensions}
+	}
+	for i, ext := range block.Extensions {
+		if len(ext.Data) > MaxExtensionDataSize {
+			return &LimitError{Field: fmt.Sprintf("extensions[%d].data", i), Size: len(ext.Data), Limit: MaxExtensionDataSize}
+		}
+		if len(ext.Type) > MaxExtensionTypeLength {
+			return &LimitError{Field: fmt.Sprintf("extensions[%d].type", i), Size: len(ext.Type), Limit: MaxExtensionTypeLength}
+		}
+	}
+
+	for i := range block.Transactions {
+		if err := CheckTransactionLimits(&block.Transactions[i]); err != nil {
+			var limitErr *LimitError
+			if errors.As(err, &limitErr) {
+				return &LimitError{Field: fmt.Sprintf("transactions[%d].%s", i, limitErr.Field), Size: limitErr.Size, Limit: limitErr.Limit}
+			}
+			return fmt.Errorf("transaction %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// CheckTransactionLimits checks the sizes of a transaction's fields and that its amounts are
+// finite numbers. NaN compares false against every bound, so it would slip through range checks.
+func CheckTransactionLimits(tx *Transaction) error {
+	if len(tx.Data) > MaxTransactionDataSize {
+		return &LimitError{Field: "data", Size: len(tx.Data), Limit: MaxTransactionDataSize}
+	}
+	if err := checkFieldLengths([][2]string{
+		{"from", tx.From},
+		{"to", tx.To},
+		{"hash", tx.Hash},
+		{"publicKey", tx.PublicKey},
+		{"signature", tx.Signature},
+		{"sponsor", tx.Sponsor},
+		{"sponsorKey", tx.SponsorKey},
+		{"sponsorSignature", tx.SponsorSignature},
+	}); err != nil {
+		return err
+	}
+
+	if math.IsNaN(tx.Amount) || math.IsInf(tx.Amount, 0) {
+		return fmt.Errorf("%w: amount is not a finite number", ErrInvalidTransaction)
+	}
+	if math.IsNaN(tx.Fee) || math.IsInf(tx.Fee, 0) {
+		return fmt.Errorf("%w: fee is not a finite number", ErrInvalidTransaction)
+	}
+	if tx.ValidUntil < 0 {
+		return fmt.Errorf("%w: valid until cannot be negative", ErrInvalidTransaction)
+	}
+	return nil
+}
+
+// CheckProofLimits checks a Merkle proof is well formed and within MaxProofDepth
+func CheckProofLimits(proof *MerkleProof) error {
+	if proof == nil {
+		return errors.New("missing proof")
+	}
+	if len(proof.Hashes) > MaxProofDepth {
+		return &LimitError{Field: "hashes", Size: len(proof.Hashes), Limit: MaxProofDepth}
+	}
+	if len(proof.IsLeft) != len(proof.Hashes) {
+		return errors.New("proof has mismatched hashes and directions")
+	}
+	if proof.Index < 0 {
+		return errors.New("proof has a negative leaf index")
+	}
+	if err := checkMerkleScheme(proof.Scheme); err != nil {
+		return err
+	}
+	if err := checkFieldLength("hash", proof.Hash); err != nil {
+		return err
+	}
+	for i, hash := range proof.Hashes {
+		if err := checkFieldLength(fmt.Sprintf("hashes[%d]", i), hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckMultiProofLimits checks a multi-leaf Merkle proof is well formed and proves leaves of
+// a tree no larger than a block
+func CheckMultiProofLimits(proof *MultiProof) error {
+	if proof == nil {
+		return errors.New("missing proof")
+	}
+	if proof.LeafCount < 1 || proof.LeafCount > MaxBlockTransactions {
+		return &LimitError{Field: "leafCount", Size: proof.LeafCount, Limit: MaxBlockTransactions}
+	}
+	if len(proof.Leaves) > proof.LeafCount {
+		return &LimitError{Field: "leaves", Size: len(proof.Leaves), Limit: proof.LeafCount}
+	}
+	// Every sibling is a distinct node of the tree, of which there are fewer than twice the leaves
+	if len(proof.Hashes) > 2*proof.LeafCount {
+		return &LimitError{Field: "hashes", Size: len(proof.Hashes), Limit: 2 * proof.LeafCount}
+	}
+	if len(proof.Indices) != len(proof.Leaves) {
+		return errors.New("proof has mismatched leaves and indices")
+	}
+	if err := checkMerkleScheme(proof.Scheme); err != nil {
+		return err
+	}
+	if err := checkHashList(proof.Leaves); err != nil {
+		return err
+	}
+	return checkHashList(proof.Hashes)
+}
+
+// checkMerkleScheme checks a proof names a known Merkle scheme
+func checkMerkleScheme(scheme MerkleScheme) error {
+	if scheme != LegacyMerkleScheme && scheme != TaggedMerkleScheme {
+		return fmt.Errorf("unknown Merkle scheme %d", scheme)
+	}
+	return nil
+}
+
+// checkHashList checks the hashes of a peer request fit MaxFieldLength, so they can be
+// remembered without letting one message pin megabytes of memory
+func checkHashList(hashes []string) error {
+	for i, hash := range hashes {
+		if err := checkFieldLength(fmt.Sprintf("hashes[%d]", i), hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkFieldLengths checks named fields, as name and value pairs, against MaxFieldLength in
+// order, so input with several oversized fields always reports the first
+func checkFieldLengths(fields [][2]string) error {
+	for _, field := range fields {
+		if err := checkFieldLength(field[0], field[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkFieldLength checks a field against MaxFieldLength
+func checkFieldLength(name, value string) error {
+	if len(value) > MaxFieldLength {
+		return &LimitError{Field: name, Size: len(value), Limit: MaxFieldLength}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// seedBlocks returns encoded blocks covering the optional fields of the block format
+func seedBlocks(t testing.TB) [][]byte {
+	tx := NewSponsoredTransaction("alice", "bob", 1.5, 0.1, 3, "carol")
+	tx.SetValidUntil(100)
+	kv, err := NewKVTransaction("alice", "key", "value", 0.1, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := NewBlock(1, []Transaction{*tx, *kv}, strings.Repeat("ab", 32))
+	block.Extensions = []Extension{{Type: "bridge.root", Data: "root"}}
+	block.ExtRoot = calculateExtRoot(block.Extensions)
+
+	var seeds [][]byte
+	for _, b := range []*Block{createGenesisBlock(), block} {
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seeds = append(seeds, data)
+	}
+	return seeds
+}
+
+func FuzzDecodeBlock(f *testing.F) {
+	for _, seed := range seedBlocks(f) {
+		f.Add(seed)
+	}
+	f.Add([]byte(`{"transactions":[{"amount":1e309}]}`))
+	f.Add([]byte(`{"index":-1,"extensions":[{"type":"","data":""}]}`))
+	f.Add([]byte(`null`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		block, err := DecodeBlock(data)
+		if err != nil {
+			var decodeErr *DecodeError
+			if !errors.As(err, &decodeErr) {
+				t.Fatalf("error is not a *DecodeError: %v", err)
+			}
+			return
+		}
+		if err := CheckBlockLimits(block); err != nil {
+			t.Fatalf("decoded block fails its limits: %v", err)
+		}
+
+		// Whatever decodes must survive the checks a connecting block goes through
+		block.ValidateTransactions()
+		block.validateExtensions()
+		block.validateBloom()
+		header := block.Header()
+		header.MarshalCompact()
+
+		encoded, err := json.Marshal(block)
+		if err != nil {
+			t.Fatalf("decoded block does not encode: %v", err)
+		}
+		if _, err := DecodeBlock(encoded); err != nil {
+			t.Fatalf("re-encoded block does not decode: %v", err)
+		}
+	})
+}
+
+func TestCheckBlockLimitsReportsFirstField(t *testing.T) {
+	long := strings.Repeat("a", MaxFieldLength+1)
+	tx := Transaction{From: long, To: long, Signature: long}
+	block := &Block{PrevHash: long, Hash: long, Bloom: long}
+
+	for i := 0; i < 20; i++ {
+		var limitErr *LimitError
+		if err := CheckTransactionLimits(&tx); !errors.As(err, &limitErr) || limitErr.Field != "from" {
+			t.Fatalf("transaction limits reported %v, expected the from field", err)
+		}
+		if err := CheckBlockLimits(block); !errors.As(err, &limitErr) || limitErr.Field != "prevHash" {
+			t.Fatalf("block limits reported %v, expected the prevHash field", err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package blockchain
+
+import (
+	"fmt"
+)
+
+// ChainView gives the transaction pool read access to confirmed chain state
+type ChainView interface {
+	IsTransactionConfirmed(hash string) bool
+	GetConfirmedNonce(address string) int64
+}
+
+// SpendPolicyView gives the transaction pool access to confirmed spend policies
+type SpendPolicyView interface {
+	CheckSpendPolicy(tx *Transaction) error
+}
+
+// BalanceView gives the transaction pool read access to confirmed balances
+type BalanceView interface {
+	GetBalance(address string) float64
+}
+
+// spendTracker records confirmed transaction hashes and the highest nonce per sender
+type spendTracker struct {
+	confirmed map[string]bool
+	nonces    map[string]int64
+}
+
+// newSpendTracker creates an empty spend tracker
+func newSpendTracker() *spendTracker {
+	return &spendTracker{
+		confirmed: make(map[string]bool),
+		nonces:    make(map[string]int64),
+	}
+}
+
+// IsTransactionConfirmed checks if a transaction hash was seen in a tracked block
+func (st *spendTracker) IsTransactionConfirmed(hash string) bool {
+	return st.confirmed[hash]
+}
+
+// GetConfirmedNonce returns the highest nonce used by an address in tracked blocks
+func (st *spendTracker) GetConfirmedNonce(address string) int64 {
+	return st.nonces[address]
+}
+
+// addBlock records the transactions of a block
+func (st *spendTracker) addBlock(block *Block) {
+	for _, tx := range block.Transactions {
+		if tx.IsCoinbase() {
+			continue
+		}
+		st.confirmed[tx.Hash] = true
+		if tx.Nonce > st.nonces[tx.From] {
+			st.nonces[tx.From] = tx.Nonce
+		}
+	}
+}
+
+// checkDoubleSpends rejects blocks that replay confirmed transactions or reuse sender nonces,
+// either against the chain or within the block itself.
+// Coinbase transactions are exempt since identical rewards legitimately share a hash.
+func checkDoubleSpends(block *Block, view ChainView) error {
+	seenHashes := make(map[string]bool)
+	seenNonces := make(map[string]bool)
+
+	for i := range block.Transactions {
+		tx := &block.Transactions[i]
+		if tx.IsCoinbase() {
+			continue
+		}
+
+		if seenHashes[tx.Hash] {
+			return fmt.Errorf("transaction %s appears twice in block", tx.Hash)
+		}
+		seenHashes[tx.Hash] = true
+
+		if view.IsTransactionConfirmed(tx.Hash) {
+			return fmt.Errorf("transaction %s is already confirmed", tx.Hash)
+		}
+
+		if tx.Nonce == 0 {
+			continue
+		}
+
+		// Mined blocks take pool transactions in no particular order, so nonces need not be ascending
+		key := senderSeqKey(tx)
+		if seenNonces[key] || tx.Nonce <= view.GetConfirmedNonce(tx.From) {
+			return fmt.Errorf("transaction %s reuses nonce %d of sender %s", tx.Hash, tx.Nonce, tx.From)
+		}
+		seenNonces[key] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReplayedSignatureWithNewNonce(t *testing.T) {
+	wallet, err := NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc := NewBlockchain(1, wallet.Address)
+	bc.SetRequireSignatures(true)
+	bc.MinePendingTransactions()
+
+	tx := NewSponsoredTransaction(wallet.Address, "bob", 3, 0.1, 1, "")
+	if err := wallet.AttachSignature(tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.AddTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Bumping the fee of the pending payment needs a fresh signature
+	bumped := *tx
+	bumped.Fee = 0.5
+	bumped.Hash = bumped.calculateHash()
+	if err := bc.AddTransaction(&bumped); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("fee-bumped copy was admitted with the original signature: %v", err)
+	}
+	bc.MinePendingTransactions()
+
+	// Once confirmed, the next nonce is free, but the old signature can't claim it
+	replayed := *tx
+	replayed.Nonce = 2
+	replayed.Hash = replayed.calculateHash()
+	if err := bc.AddTransaction(&replayed); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("re-nonced copy was admitted: %v", err)
+	}
+	block := NewBlock(int64(len(bc.Chain)), []Transaction{replayed}, bc.GetLatestBlock().Hash)
+	if err := checkBlockSignatures(block, true, bc.Upgrades, bc.params); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("block with a re-nonced copy passed signature checks: %v", err)
+	}
+	if balance := bc.GetBalance("bob"); balance != 3 {
+		t.Fatalf("bob holds %v after the replay", balance)
+	}
+}
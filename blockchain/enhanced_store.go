@@ -0,0 +1,118 @@
+package blockchain
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// EnhancedTransactionStore persists the enhanced transactions of a pool as they are added and
+// signed. *Database implements it; blocks written to it mark their enhanced transactions executed.
+type EnhancedTransactionStore interface {
+	SaveEnhancedTransaction(tx *EnhancedTransaction) error
+}
+
+// StoredEnhancedTransaction is an enhanced transaction read back from the database
+type StoredEnhancedTransaction struct {
+	*EnhancedTransaction
+	CurrentSigs int  `json:"currentSigs"`
+	Executed    bool `json:"executed"` // Included in a stored block
+}
+
+// enhancedColumns are the columns an enhanced transaction is read back from
+const enhancedColumns = "transaction_data, current_sigs, is_executed"
+
+// SaveEnhancedTransaction stores an enhanced transaction, updating its signatures and metadata
+// if it is already stored
+func (d *Database) SaveEnhancedTransaction(tx *EnhancedTransaction) error {
+	data, err := json.Marshal(tx)
+	if err != nil {
+		return fmt.Errorf("failed to serialize enhanced transaction: %w", err)
+	}
+	var metadata interface{}
+	if len(tx.Metadata) > 0 {
+		encoded, err := json.Marshal(tx.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to serialize metadata: %w", err)
+		}
+		metadata = string(encoded)
+	}
+
+	_, err = d.db.Exec(d.rebind(`
+		INSERT INTO enhanced_transactions (transaction_id, hash, type, from_address, to_address, amount, fee,
+			timestamp, required_sigs, current_sigs, lock_time, transaction_data, metadata)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(hash) DO UPDATE SET
+			current_sigs = excluded.current_sigs,
+			transaction_data = excluded.transaction_data,
+			metadata = excluded.metadata`),
+		tx.ID, tx.Hash, string(tx.Type), tx.From, tx.To, tx.Amount, tx.Fee, tx.Timestamp,
+		tx.RequiredSigs, len(tx.Signatures), tx.LockTime, string(data), metadata)
+	if err != nil {
+		return fmt.Errorf("failed to save enhanced transaction: %w", err)
+	}
+	return nil
+}
+
+// markEnhancedExecuted flags the stored enhanced transactions among the transactions of a block
+// as executed (they go into blocks in their standard form, under the same hash)
+func (d *Database) markEnhancedExecuted(tx *sql.Tx, hashes []interface{}) error {
+	for start := 0; start < len(hashes); start += insertBatchRows {
+		batch := hashes[start:min(start+insertBatchRows, len(hashes))]
+		query := "UPDATE enhanced_transactions SET is_executed = TRUE WHERE hash IN (?" + strings.Repeat(", ?", len(batch)-1) + ")"
+		if _, err := tx.Exec(d.rebind(query), batch...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetEnhancedTransactionFromDB returns a stored enhanced transaction by hash
+func (d *Database) GetEnhancedTransactionFromDB(hash string) (*StoredEnhancedTransaction, error) {
+	txs, err := d.queryEnhanced("SELECT "+enhancedColumns+" FROM enhanced_transactions WHERE hash = ?", hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(txs) == 0 {
+		return nil, ErrTransactionNotFound
+	}
+	return txs[0], nil
+}
+
+// GetPendingMultiSigFromDB returns the stored multi-sig transactions sent from an address that
+// are not executed yet, oldest first
+func (d *Database) GetPendingMultiSigFromDB(address string) ([]*StoredEnhancedTransaction, error) {
+	return d.queryEnhanced("SELECT "+enhancedColumns+` FROM enhanced_transactions
+		WHERE from_address = ? AND type = ? AND is_executed = FALSE ORDER BY timestamp, id`, address, string(MultiSigTx))
+}
+
+// GetEnhancedTransactionsByType returns up to limit stored enhanced transactions of a type,
+// newest first
+func (d *Database) GetEnhancedTransactionsByType(txType TransactionType, limit int) ([]*StoredEnhancedTransaction, error) {
+	return d.queryEnhanced("SELECT "+enhancedColumns+` FROM enhanced_transactions
+		WHERE type = ? ORDER BY timestamp DESC, id DESC LIMIT ?`, string(txType), limit)
+}
+
+// queryEnhanced runs a query selecting enhancedColumns
+func (d *Database) queryEnhanced(query string, args ...interface{}) ([]*StoredEnhancedTransaction, error) {
+	rows, err := d.db.Query(d.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query enhanced transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var txs []*StoredEnhancedTransaction
+	for rows.Next() {
+		var data string
+		stored := &StoredEnhancedTransaction{EnhancedTransaction: &EnhancedTransaction{}}
+		if err := rows.Scan(&data, &stored.CurrentSigs, &stored.Executed); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(data), stored.EnhancedTransaction); err != nil {
+			return nil, fmt.Errorf("failed to deserialize enhanced transaction: %w", err)
+		}
+		txs = append(txs, stored)
+	}
+	return txs, rows.Err()
+}
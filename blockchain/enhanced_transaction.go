@@ -1,7 +1,6 @@
 package blockchain
 
 import (
-	"crypto/ecdsa"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -17,6 +16,9 @@ const (
 	MultiSigTx TransactionType = "multisig"
 	TimeLockTx TransactionType = "timelock"
 	ContractTx TransactionType = "contract"
+
+	// DataAnchorTx is a key-value entry anchored in the chain, see NewKVTransaction
+	DataAnchorTx TransactionType = "data_anchor"
 )
 
 // EnhancedTransaction represents an enhanced transaction with additional features
@@ -47,7 +49,7 @@ type EnhancedTransaction struct {
 
 // TransactionSignature represents a signature with the signer's public key
 type TransactionSignature struct {
-	PublicKey string `json:"publicKey"`
+	PublicKey string `json:"publicKey"` // Scheme-tagged hex key (see EncodePublicKey)
 	Signature string `json:"signature"`
 	Signer    string `json:"signer"`
 }
@@ -226,11 +228,14 @@ func (tx *EnhancedTransaction) IsExecutable() bool {
 	return true
 }
 
-// verifySignature verifies a signature against the transaction
+// verifySignature verifies a signature against the transaction: the public key must derive
+// the signer address and the signature must be valid under the key's scheme
 func (tx *EnhancedTransaction) verifySignature(sig TransactionSignature) bool {
-	// This is a simplified verification - in a real implementation,
-	// you would use the actual ECDSA verification with the public key
-	return len(sig.Signature) > 0 && len(sig.PublicKey) > 0 && len(sig.Signer) > 0
+	address, err := AddressFromPublicKey(sig.PublicKey)
+	if err != nil || address != sig.Signer {
+		return false
+	}
+	return VerifySignature(sig.PublicKey, signingMessage(tx.ToStandardTransaction()), sig.Signature)
 }
 
 // GetMetadata retrieves metadata value by key
@@ -252,15 +257,20 @@ func (tx *EnhancedTransaction) SetMetadata(key string, value interface{}) {
 	tx.Hash = tx.calculateHash()
 }
 
-// ToStandardTransaction converts enhanced transaction to standard transaction for backward compatibility
+// ToStandardTransaction converts enhanced transaction to standard transaction for backward compatibility.
+// Contract calls keep their payload behind ContractDataPrefix so they can be told apart on chain.
 func (tx *EnhancedTransaction) ToStandardTransaction() Transaction {
-	return Transaction{
+	standard := Transaction{
 		From:   tx.From,
 		To:     tx.To,
 		Amount: tx.Amount,
 		Fee:    tx.Fee,
 		Hash:   tx.Hash,
 	}
+	if tx.Type == ContractTx {
+		standard.Data = []byte(ContractDataPrefix + tx.ContractData)
+	}
+	return standard
 }
 
 // SignTransactionEnhanced signs an enhanced transaction with a wallet
@@ -273,15 +283,10 @@ func (w *Wallet) SignTransactionEnhanced(tx *EnhancedTransaction) (*TransactionS
 
 	// Create transaction signature
 	txSig := &TransactionSignature{
-		PublicKey: publicKeyToString(w.PublicKey),
+		PublicKey: w.EncodedPublicKey(),
 		Signature: signature,
 		Signer:    w.Address,
 	}
 
 	return txSig, nil
 }
-
-// Helper function to convert public key to string (simplified)
-func publicKeyToString(pubKey *ecdsa.PublicKey) string {
-	return pubKey.X.String() + ":" + pubKey.Y.String()
-}
@@ -2,81 +2,105 @@ package blockchain
 
 import (
 	"errors"
-	"sync"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"sort"
 	"time"
 )
 
-// EnhancedTransactionPool manages enhanced transactions with additional validation
-type EnhancedTransactionPool struct {
-	standardTxs map[string]*Transaction         // Standard transactions
-	enhancedTxs map[string]*EnhancedTransaction // Enhanced transactions
-	mu          sync.RWMutex
-	maxSize     int
-}
+// EnhancedTransactionPool is the mempool under the name of its enhanced transaction side
+type EnhancedTransactionPool = Mempool
 
 // NewEnhancedTransactionPool creates a new enhanced transaction pool
 func NewEnhancedTransactionPool(maxSize int) *EnhancedTransactionPool {
-	return &EnhancedTransactionPool{
-		standardTxs: make(map[string]*Transaction),
-		enhancedTxs: make(map[string]*EnhancedTransaction),
-		maxSize:     maxSize,
-	}
+	return NewMempool(maxSize)
 }
 
-// AddStandardTransaction adds a standard transaction to the pool
-func (etp *EnhancedTransactionPool) AddStandardTransaction(tx *Transaction) error {
-	etp.mu.Lock()
-	defer etp.mu.Unlock()
+// SetStore makes the pool save enhanced transactions as they are added and signed (nil disables).
+// A failed save is logged; the transaction stays in the pool.
+func (tp *Mempool) SetStore(store EnhancedTransactionStore) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	tp.enhancedDB = store
+}
 
-	// Check pool size
-	if len(etp.standardTxs)+len(etp.enhancedTxs) >= etp.maxSize {
-		return errors.New("transaction pool is full")
+// snapshotLocked copies an enhanced transaction for persistEnhanced, nil if the pool has no store
+// (caller must hold the lock)
+func (tp *Mempool) snapshotLocked(tx *EnhancedTransaction) (EnhancedTransactionStore, *EnhancedTransaction) {
+	if tp.enhancedDB == nil {
+		return nil, nil
 	}
+	snapshot := *tx
+	snapshot.Signatures = slices.Clone(tx.Signatures)
+	snapshot.Signers = slices.Clone(tx.Signers)
+	snapshot.Metadata = maps.Clone(tx.Metadata)
+	return tp.enhancedDB, &snapshot
+}
 
-	// Validate transaction
-	if err := etp.validateStandardTransaction(tx); err != nil {
-		return err
+// persistEnhanced saves a snapshot taken by snapshotLocked. It runs after the pool's lock is
+// released, so a slow store doesn't hold up the pool.
+func persistEnhanced(store EnhancedTransactionStore, snapshot *EnhancedTransaction) {
+	if store == nil {
+		return
 	}
+	if err := store.SaveEnhancedTransaction(snapshot); err != nil {
+		log.Printf("Warning: failed to store enhanced transaction %s: %v", snapshot.Hash, err)
+	}
+}
 
-	// Add transaction to pool
-	etp.standardTxs[tx.Hash] = tx
-	return nil
+// AddStandardTransaction adds a standard transaction to the pool, see AddTransaction
+func (tp *Mempool) AddStandardTransaction(tx *Transaction) error {
+	return tp.AddTransaction(tx)
 }
 
 // AddEnhancedTransaction adds an enhanced transaction to the pool
-func (etp *EnhancedTransactionPool) AddEnhancedTransaction(tx *EnhancedTransaction) error {
-	etp.mu.Lock()
-	defer etp.mu.Unlock()
+func (tp *Mempool) AddEnhancedTransaction(tx *EnhancedTransaction) error {
+	store, snapshot, err := tp.addEnhancedTransaction(tx)
+	if err != nil {
+		return err
+	}
+	persistEnhanced(store, snapshot)
+	return nil
+}
+
+// addEnhancedTransaction adds an enhanced transaction to the pool and snapshots it for persistEnhanced
+func (tp *Mempool) addEnhancedTransaction(tx *EnhancedTransaction) (EnhancedTransactionStore, *EnhancedTransaction, error) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
 
 	// Check pool size
-	if len(etp.standardTxs)+len(etp.enhancedTxs) >= etp.maxSize {
-		return errors.New("transaction pool is full")
+	if tp.sizeLocked() >= tp.maxSize {
+		return nil, nil, ErrPoolFull
 	}
 
 	// Validate enhanced transaction
-	if err := etp.validateEnhancedTransaction(tx); err != nil {
-		return err
+	if err := tp.validateEnhancedTransaction(tx); err != nil {
+		return nil, nil, err
 	}
 
 	// Add transaction to pool
-	etp.enhancedTxs[tx.Hash] = tx
-	return nil
+	tp.enhanced[tx.Hash] = tx
+	tp.publishEnhancedLocked(PoolEventAdd, tx, "", "")
+	store, snapshot := tp.snapshotLocked(tx)
+	return store, snapshot, nil
 }
 
 // GetExecutableTransactions returns all transactions that can be executed
-func (etp *EnhancedTransactionPool) GetExecutableTransactions() ([]*Transaction, []*EnhancedTransaction) {
-	etp.mu.RLock()
-	defer etp.mu.RUnlock()
+func (tp *Mempool) GetExecutableTransactions() ([]*Transaction, []*EnhancedTransaction) {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
 
 	// Get all standard transactions
-	standardTxs := make([]*Transaction, 0, len(etp.standardTxs))
-	for _, tx := range etp.standardTxs {
+	standardTxs := make([]*Transaction, 0, len(tp.transactions))
+	for _, tx := range tp.transactions {
 		standardTxs = append(standardTxs, tx)
 	}
 
 	// Get executable enhanced transactions
 	enhancedTxs := make([]*EnhancedTransaction, 0)
-	for _, tx := range etp.enhancedTxs {
+	for _, tx := range tp.enhanced {
 		if tx.IsExecutable() {
 			enhancedTxs = append(enhancedTxs, tx)
 		}
@@ -86,19 +110,19 @@ func (etp *EnhancedTransactionPool) GetExecutableTransactions() ([]*Transaction,
 }
 
 // GetAllTransactions returns all transactions for backward compatibility
-func (etp *EnhancedTransactionPool) GetAllTransactions() []*Transaction {
-	etp.mu.RLock()
-	defer etp.mu.RUnlock()
+func (tp *Mempool) GetAllTransactions() []*Transaction {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
 
-	allTxs := make([]*Transaction, 0, len(etp.standardTxs)+len(etp.enhancedTxs))
+	allTxs := make([]*Transaction, 0, len(tp.transactions)+len(tp.enhanced))
 
 	// Add standard transactions
-	for _, tx := range etp.standardTxs {
+	for _, tx := range tp.transactions {
 		allTxs = append(allTxs, tx)
 	}
 
 	// Add executable enhanced transactions converted to standard format
-	for _, tx := range etp.enhancedTxs {
+	for _, tx := range tp.enhanced {
 		if tx.IsExecutable() {
 			standardTx := tx.ToStandardTransaction()
 			allTxs = append(allTxs, &standardTx)
@@ -108,33 +132,31 @@ func (etp *EnhancedTransactionPool) GetAllTransactions() []*Transaction {
 	return allTxs
 }
 
-// RemoveStandardTransactions removes standard transactions from the pool
-func (etp *EnhancedTransactionPool) RemoveStandardTransactions(txs []*Transaction) {
-	etp.mu.Lock()
-	defer etp.mu.Unlock()
-
-	for _, tx := range txs {
-		delete(etp.standardTxs, tx.Hash)
-	}
+// RemoveStandardTransactions removes standard transactions from the pool, see RemoveTransactions
+func (tp *Mempool) RemoveStandardTransactions(txs []*Transaction) {
+	tp.RemoveTransactions(txs)
 }
 
 // RemoveEnhancedTransactions removes enhanced transactions from the pool
-func (etp *EnhancedTransactionPool) RemoveEnhancedTransactions(txs []*EnhancedTransaction) {
-	etp.mu.Lock()
-	defer etp.mu.Unlock()
+func (tp *Mempool) RemoveEnhancedTransactions(txs []*EnhancedTransaction) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
 
 	for _, tx := range txs {
-		delete(etp.enhancedTxs, tx.Hash)
+		if pending, exists := tp.enhanced[tx.Hash]; exists {
+			delete(tp.enhanced, tx.Hash)
+			tp.publishEnhancedLocked(PoolEventRemove, pending, DropConfirmed, "")
+		}
 	}
 }
 
 // GetPendingMultiSigTransactions returns multi-sig transactions pending signatures
-func (etp *EnhancedTransactionPool) GetPendingMultiSigTransactions() []*EnhancedTransaction {
-	etp.mu.RLock()
-	defer etp.mu.RUnlock()
+func (tp *Mempool) GetPendingMultiSigTransactions() []*EnhancedTransaction {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
 
 	pending := make([]*EnhancedTransaction, 0)
-	for _, tx := range etp.enhancedTxs {
+	for _, tx := range tp.enhanced {
 		if tx.Type == MultiSigTx && !tx.IsFullySigned() {
 			pending = append(pending, tx)
 		}
@@ -144,11 +166,11 @@ func (etp *EnhancedTransactionPool) GetPendingMultiSigTransactions() []*Enhanced
 }
 
 // GetTimeLockTransactions returns time-locked transactions (both ready and pending)
-func (etp *EnhancedTransactionPool) GetTimeLockTransactions() (ready []*EnhancedTransaction, pending []*EnhancedTransaction) {
-	etp.mu.RLock()
-	defer etp.mu.RUnlock()
+func (tp *Mempool) GetTimeLockTransactions() (ready []*EnhancedTransaction, pending []*EnhancedTransaction) {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
 
-	for _, tx := range etp.enhancedTxs {
+	for _, tx := range tp.enhanced {
 		if tx.Type == TimeLockTx {
 			if tx.IsExecutable() {
 				ready = append(ready, tx)
@@ -161,47 +183,51 @@ func (etp *EnhancedTransactionPool) GetTimeLockTransactions() (ready []*Enhanced
 	return ready, pending
 }
 
-// validateStandardTransaction validates a standard transaction
-func (etp *EnhancedTransactionPool) validateStandardTransaction(tx *Transaction) error {
-	// Basic validation
-	if tx.From == "" || tx.To == "" {
-		return errors.New("invalid transaction: missing from/to address")
-	}
-
-	if tx.Amount <= 0 {
-		return errors.New("invalid transaction: amount must be positive")
-	}
-
-	if tx.Fee < 0 {
-		return errors.New("invalid transaction: fee cannot be negative")
-	}
-
-	// Check if transaction already exists
-	if _, exists := etp.standardTxs[tx.Hash]; exists {
-		return errors.New("transaction already exists in pool")
+// GetEnhancedTransactionsFrom returns copies of the enhanced transactions sent from an address,
+// oldest first. Copies keep signatures added later from racing with the caller.
+func (tp *Mempool) GetEnhancedTransactionsFrom(address string) []EnhancedTransaction {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+
+	var txs []EnhancedTransaction
+	for _, tx := range tp.enhanced {
+		if tx.From == address {
+			copied := *tx
+			copied.Signatures = append([]TransactionSignature(nil), tx.Signatures...)
+			txs = append(txs, copied)
+		}
 	}
-
-	return nil
+	sort.Slice(txs, func(i, j int) bool {
+		if txs[i].Timestamp != txs[j].Timestamp {
+			return txs[i].Timestamp < txs[j].Timestamp
+		}
+		return txs[i].Hash < txs[j].Hash
+	})
+	return txs
 }
 
 // validateEnhancedTransaction validates an enhanced transaction
-func (etp *EnhancedTransactionPool) validateEnhancedTransaction(tx *EnhancedTransaction) error {
+func (tp *Mempool) validateEnhancedTransaction(tx *EnhancedTransaction) error {
 	// Basic validation
 	if tx.From == "" || tx.To == "" {
-		return errors.New("invalid transaction: missing from/to address")
+		return ErrMissingAddress
 	}
 
 	if tx.Amount <= 0 {
-		return errors.New("invalid transaction: amount must be positive")
+		return ErrInvalidAmount
 	}
 
 	if tx.Fee < 0 {
-		return errors.New("invalid transaction: fee cannot be negative")
+		return ErrNegativeFee
+	}
+
+	if err := tp.feePolicy.CheckEnhancedTransaction(tx); err != nil {
+		return err
 	}
 
 	// Check if transaction already exists
-	if _, exists := etp.enhancedTxs[tx.Hash]; exists {
-		return errors.New("transaction already exists in pool")
+	if _, exists := tp.enhanced[tx.Hash]; exists {
+		return ErrDuplicateTx
 	}
 
 	// Type-specific validation
@@ -226,32 +252,46 @@ func (etp *EnhancedTransactionPool) validateEnhancedTransaction(tx *EnhancedTran
 }
 
 // AddSignatureToTransaction adds a signature to a transaction in the pool
-func (etp *EnhancedTransactionPool) AddSignatureToTransaction(txHash string, signature TransactionSignature) error {
-	etp.mu.Lock()
-	defer etp.mu.Unlock()
+func (tp *Mempool) AddSignatureToTransaction(txHash string, signature TransactionSignature) error {
+	store, snapshot, err := tp.addSignature(txHash, signature)
+	if err != nil {
+		return err
+	}
+	persistEnhanced(store, snapshot)
+	return nil
+}
 
-	tx, exists := etp.enhancedTxs[txHash]
+// addSignature adds a signature to a transaction in the pool and snapshots it for persistEnhanced
+func (tp *Mempool) addSignature(txHash string, signature TransactionSignature) (EnhancedTransactionStore, *EnhancedTransaction, error) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	tx, exists := tp.enhanced[txHash]
 	if !exists {
-		return errors.New("transaction not found in pool")
+		return nil, nil, fmt.Errorf("%w in pool", ErrTransactionNotFound)
 	}
 
-	return tx.AddSignature(signature)
+	if err := tx.AddSignature(signature); err != nil {
+		return nil, nil, err
+	}
+	store, snapshot := tp.snapshotLocked(tx)
+	return store, snapshot, nil
 }
 
 // GetTransactionStats returns statistics about the transaction pool
-func (etp *EnhancedTransactionPool) GetTransactionStats() map[string]int {
-	etp.mu.RLock()
-	defer etp.mu.RUnlock()
+func (tp *Mempool) GetTransactionStats() map[string]int {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
 
 	stats := map[string]int{
-		"standard_transactions": len(etp.standardTxs),
-		"enhanced_transactions": len(etp.enhancedTxs),
-		"total_transactions":    len(etp.standardTxs) + len(etp.enhancedTxs),
+		"standard_transactions": len(tp.transactions),
+		"enhanced_transactions": len(tp.enhanced),
+		"total_transactions":    len(tp.transactions) + len(tp.enhanced),
 	}
 
 	// Count enhanced transaction types
 	multisig, timelock, contract, standard := 0, 0, 0, 0
-	for _, tx := range etp.enhancedTxs {
+	for _, tx := range tp.enhanced {
 		switch tx.Type {
 		case MultiSigTx:
 			multisig++
@@ -0,0 +1,48 @@
+package blockchain
+
+import (
+	"testing"
+	"time"
+)
+
+// poolReadingStore is a store that reads the pool while saving, which deadlocks if the pool
+// saves under its lock
+type poolReadingStore struct {
+	pool  *Mempool
+	saved []*EnhancedTransaction
+}
+
+func (s *poolReadingStore) SaveEnhancedTransaction(tx *EnhancedTransaction) error {
+	s.pool.GetTransactionStats()
+	s.saved = append(s.saved, tx)
+	return nil
+}
+
+func TestMempoolPersistsOutsideLock(t *testing.T) {
+	pool := NewMempool(10)
+	store := &poolReadingStore{pool: pool}
+	pool.SetStore(store)
+
+	tx := NewMultiSigTransaction("alice", "bob", 1, 0.1, 1, []string{"alice"}, map[string]interface{}{"memo": "rent"})
+	done := make(chan error, 1)
+	go func() { done <- pool.AddEnhancedTransaction(tx) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("store was called with the pool locked")
+	}
+
+	if len(store.saved) != 1 || store.saved[0].Hash != tx.Hash {
+		t.Fatalf("store saved %v", store.saved)
+	}
+	if store.saved[0] == tx {
+		t.Fatal("store was handed the pooled transaction instead of a snapshot")
+	}
+	tx.Metadata["memo"] = "changed"
+	if store.saved[0].Metadata["memo"] != "rent" {
+		t.Fatal("snapshot shares its metadata with the pooled transaction")
+	}
+}
@@ -0,0 +1,117 @@
+package blockchain
+
+import (
+	"bytes"
+	"strings"
+)
+
+// EntrySource is the origin of a balance change
+type EntrySource string
+
+const (
+	SourceCoinbase EntrySource = "coinbase" // Mining reward
+	SourceFee      EntrySource = "fee"      // Fee paid by the sender of a transaction
+	SourceTransfer EntrySource = "transfer" // Payment between addresses
+	SourceContract EntrySource = "contract" // Value sent with a contract call
+	SourceSystem   EntrySource = "system"   // Payload entries to system addresses (key-value anchors, spend policies)
+)
+
+// ContractDataPrefix marks the payload of contract calls in chain transactions
+const ContractDataPrefix = "contract:"
+
+// TransactionSource classifies the balance changes of a transaction by origin.
+// Fees are always SourceFee, see LedgerEntriesOf.
+func TransactionSource(tx *Transaction) EntrySource {
+	switch {
+	case tx.IsCoinbase():
+		return SourceCoinbase
+	case tx.To == KVNamespaceAddress || tx.To == SpendPolicyAddress || tx.To == HaltAddress:
+		return SourceSystem
+	case bytes.HasPrefix(tx.Data, []byte(ContractDataPrefix)):
+		return SourceContract
+	default:
+		return SourceTransfer
+	}
+}
+
+// LedgerEntry is a single balance change of an address
+type LedgerEntry struct {
+	Address    string      `json:"address"`
+	TxHash     string      `json:"txHash"`
+	BlockIndex int64       `json:"blockIndex"`
+	TxIndex    int         `json:"txIndex"`
+	Source     EntrySource `json:"source"`
+	Amount     float64     `json:"amount"` // Negative for debits
+}
+
+// LedgerEntriesOf splits a confirmed transaction into the balance changes it causes:
+// the amount debited from the sender and credited to the recipient under the transaction's
+// source, and the fee debited from the sender (or its sponsor) as SourceFee
+func LedgerEntriesOf(tx *Transaction, blockIndex int64, txIndex int) []LedgerEntry {
+	source := TransactionSource(tx)
+	entry := func(address string, source EntrySource, amount float64) LedgerEntry {
+		return LedgerEntry{Address: address, TxHash: tx.Hash, BlockIndex: blockIndex, TxIndex: txIndex, Source: source, Amount: amount}
+	}
+
+	var entries []LedgerEntry
+	if tx.Amount != 0 {
+		entries = append(entries, entry(tx.From, source, -tx.Amount), entry(tx.To, source, tx.Amount))
+	}
+	if tx.Fee != 0 {
+		entries = append(entries, entry(tx.FeePayer(), SourceFee, -tx.Fee))
+	}
+	return entries
+}
+
+// GetLedgerEntries returns the balance changes of an address in chain order,
+// optionally only those from some sources
+func (d *Database) GetLedgerEntries(address string, sources ...EntrySource) ([]LedgerEntry, error) {
+	query := "SELECT address, tx_hash, block_index, tx_index, source, amount FROM ledger_entries WHERE address = ?"
+	args := []interface{}{address}
+	if len(sources) > 0 {
+		query += " AND source IN (?" + strings.Repeat(", ?", len(sources)-1) + ")"
+		for _, source := range sources {
+			args = append(args, string(source))
+		}
+	}
+	query += " ORDER BY block_index, tx_index, id"
+
+	rows, err := d.db.Query(d.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []LedgerEntry
+	for rows.Next() {
+		var entry LedgerEntry
+		var source string
+		if err := rows.Scan(&entry.Address, &entry.TxHash, &entry.BlockIndex, &entry.TxIndex, &source, &entry.Amount); err != nil {
+			return nil, err
+		}
+		entry.Source = EntrySource(source)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// GetBalanceBySource returns the net balance change of an address per source,
+// e.g. to separate mining income from payments received
+func (d *Database) GetBalanceBySource(address string) (map[EntrySource]float64, error) {
+	rows, err := d.db.Query(d.rebind("SELECT source, SUM(amount) FROM ledger_entries WHERE address = ? GROUP BY source"), address)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make(map[EntrySource]float64)
+	for rows.Next() {
+		var source string
+		var total float64
+		if err := rows.Scan(&source, &total); err != nil {
+			return nil, err
+		}
+		totals[EntrySource(source)] = total
+	}
+	return totals, rows.Err()
+}
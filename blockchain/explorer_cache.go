@@ -0,0 +1,338 @@
+package blockchain
+
+import (
+	"iter"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ExplorerSource is the chain surface the explorer cache reads from
+type ExplorerSource interface {
+	GetLatestBlock() *Block
+	GetBlockByIndex(index int64) (*Block, error)
+	GetBalance(address string) float64
+	FindTransactions(match TransactionMatcher, fromHeight, toHeight int64) iter.Seq2[*Transaction, *Block]
+}
+
+// ExplorerCacheConfig configures explorer cache warming
+type ExplorerCacheConfig struct {
+	PollInterval        time.Duration // How often the chain tip is checked for new blocks
+	LatestBlocks        int           // Blocks listed on the latest blocks page
+	DetailBlocks        int           // Most recent blocks whose detail pages are kept warm
+	AddressTransactions int           // Most recent transactions listed on an address page
+}
+
+// BlockSummary is a block as listed on the latest blocks page
+type BlockSummary struct {
+	Index            int64  `json:"index"`
+	Hash             string `json:"hash"`
+	PrevHash         string `json:"prevHash"`
+	Timestamp        int64  `json:"timestamp"`
+	TransactionCount int    `json:"transactionCount"`
+}
+
+// BlocksPage lists the latest blocks, newest first
+type BlocksPage struct {
+	Height int64          `json:"height"`
+	Blocks []BlockSummary `json:"blocks"`
+}
+
+// BlockDetail is the detail page of a block
+type BlockDetail struct {
+	BlockSummary
+	Version      int32         `json:"version,omitempty"`
+	Nonce        int64         `json:"nonce"`
+	MerkleRoot   string        `json:"merkleRoot"`
+	KVRoot       string        `json:"kvRoot,omitempty"`
+	MMRRoot      string        `json:"mmrRoot,omitempty"`
+	ExtRoot      string        `json:"extRoot,omitempty"`
+	StateRoot    string        `json:"stateRoot,omitempty"`
+	ReceiptRoot  string        `json:"receiptRoot,omitempty"`
+	Extensions   []Extension   `json:"extensions,omitempty"`
+	Transactions []Transaction `json:"transactions"`
+}
+
+// AddressTransaction is a transaction listed on an address page
+type AddressTransaction struct {
+	Transaction
+	BlockIndex int64 `json:"blockIndex"`
+	Timestamp  int64 `json:"timestamp"`
+}
+
+// AddressPage is the page of an address: its balance and latest transactions, newest first
+type AddressPage struct {
+	Address          string               `json:"address"`
+	Balance          float64              `json:"balance"`
+	TransactionCount int                  `json:"transactionCount"`
+	Transactions     []AddressTransaction `json:"transactions"`
+}
+
+// ExplorerSnapshot holds explorer pages computed at a single chain tip, so pages served
+// together never mix chain states. Snapshots are immutable once published.
+type ExplorerSnapshot struct {
+	TipHash   string
+	Height    int64
+	latest    *BlocksPage
+	blocks    map[string]*BlockDetail // Keyed by hash
+	heights   map[int64]string        // Block hash at each cached height
+	addresses map[string]*AddressPage
+}
+
+// LatestBlocks returns the latest blocks page
+func (s *ExplorerSnapshot) LatestBlocks() *BlocksPage {
+	return s.latest
+}
+
+// Block returns the cached detail page of a block by hash
+func (s *ExplorerSnapshot) Block(hash string) (*BlockDetail, bool) {
+	detail, exists := s.blocks[hash]
+	return detail, exists
+}
+
+// BlockAt returns the cached detail page of the block at a height
+func (s *ExplorerSnapshot) BlockAt(height int64) (*BlockDetail, bool) {
+	hash, exists := s.heights[height]
+	if !exists {
+		return nil, false
+	}
+	return s.Block(hash)
+}
+
+// Address returns the cached page of an address involved in a recent block
+func (s *ExplorerSnapshot) Address(address string) (*AddressPage, bool) {
+	if s == nil {
+		return nil, false
+	}
+	page, exists := s.addresses[address]
+	return page, exists
+}
+
+// ExplorerCache precomputes the pages explorers request most after each block:
+// the latest blocks page, recent block details and the pages of addresses those blocks touch.
+// Warming runs in the background; a reorg discards the cached pages and rebuilds them.
+type ExplorerCache struct {
+	source   ExplorerSource
+	lock     sync.Locker // Serializes chain access with other users such as the miner
+	config   ExplorerCacheConfig
+	snapshot atomic.Pointer[ExplorerSnapshot]
+	wake     chan struct{}
+	quit     chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+// NewExplorerCache creates an explorer cache over a chain.
+// The lock must be the one guarding the chain (nil uses an internal mutex).
+func NewExplorerCache(source ExplorerSource, lock sync.Locker, config ExplorerCacheConfig) *ExplorerCache {
+	if lock == nil {
+		lock = &sync.Mutex{}
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	if config.LatestBlocks <= 0 {
+		config.LatestBlocks = 20
+	}
+	if config.DetailBlocks <= 0 {
+		config.DetailBlocks = config.LatestBlocks
+	}
+	if config.AddressTransactions <= 0 {
+		config.AddressTransactions = 50
+	}
+
+	return &ExplorerCache{
+		source: source,
+		lock:   lock,
+		config: config,
+		wake:   make(chan struct{}, 1),
+		quit:   make(chan struct{}),
+	}
+}
+
+// Start begins warming the cache whenever the chain tip changes
+func (ec *ExplorerCache) Start() {
+	ec.wg.Add(1)
+	go ec.loop()
+}
+
+// Stop ends cache warming and waits for a running pass to finish
+func (ec *ExplorerCache) Stop() {
+	ec.once.Do(func() { close(ec.quit) })
+	ec.wg.Wait()
+}
+
+// Notify asks for the cache to be warmed now, e.g. right after a block is added.
+// Notifications arriving during a pass are coalesced into one more pass.
+func (ec *ExplorerCache) Notify() {
+	select {
+	case ec.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Snapshot returns the latest published snapshot, or nil before the first pass completes
+func (ec *ExplorerCache) Snapshot() *ExplorerSnapshot {
+	return ec.snapshot.Load()
+}
+
+// loop warms the cache on notifications and tip changes until stopped
+func (ec *ExplorerCache) loop() {
+	defer ec.wg.Done()
+
+	ticker := time.NewTicker(ec.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		ec.Warm()
+
+		select {
+		case <-ec.quit:
+			return
+		case <-ec.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Warm brings the cache up to date with the chain tip and publishes a new snapshot if it moved
+func (ec *ExplorerCache) Warm() {
+	ec.lock.Lock()
+	defer ec.lock.Unlock()
+
+	tip := ec.source.GetLatestBlock()
+	previous := ec.snapshot.Load()
+	if previous != nil && previous.TipHash == tip.Hash {
+		return
+	}
+
+	// Extend the previous snapshot while it is still on the chain, otherwise start over
+	from := max(tip.Index-int64(ec.config.DetailBlocks)+1, 0)
+	next := &ExplorerSnapshot{
+		TipHash:   tip.Hash,
+		Height:    tip.Index,
+		blocks:    make(map[string]*BlockDetail),
+		heights:   make(map[int64]string),
+		addresses: make(map[string]*AddressPage),
+	}
+	var base *ExplorerSnapshot
+	if previous != nil && ec.extends(previous, tip) {
+		base = previous
+		for height := max(previous.Height-int64(ec.config.DetailBlocks)+1, from); height <= previous.Height; height++ {
+			hash := previous.heights[height]
+			next.heights[height] = hash
+			next.blocks[hash] = previous.blocks[hash]
+		}
+		from = max(previous.Height+1, from)
+	} else if previous != nil {
+		log.Printf("Explorer cache: chain no longer extends block %d (%s), rebuilding", previous.Height, previous.TipHash)
+	}
+
+	// Block details and the addresses touched by the new blocks
+	touched := make(map[string]bool)
+	for height := from; height <= tip.Index; height++ {
+		block, err := ec.source.GetBlockByIndex(height)
+		if err != nil {
+			log.Printf("Explorer cache: failed to load block %d: %v", height, err)
+			return
+		}
+		next.blocks[block.Hash] = newBlockDetail(block)
+		next.heights[height] = block.Hash
+		for _, tx := range block.Transactions {
+			touched[tx.From] = true
+			touched[tx.To] = true
+		}
+	}
+
+	// Pages are kept for addresses of the cached blocks; untouched ones are still current
+	for _, detail := range next.blocks {
+		for _, tx := range detail.Transactions {
+			for _, address := range []string{tx.From, tx.To} {
+				if _, done := next.addresses[address]; done {
+					continue
+				}
+				if page, cached := base.Address(address); cached && !touched[address] {
+					next.addresses[address] = page
+				} else {
+					next.addresses[address] = ec.addressPage(address, tip.Index)
+				}
+			}
+		}
+	}
+
+	next.latest = ec.latestBlocks(tip)
+	ec.snapshot.Store(next)
+}
+
+// extends checks the chain still contains the tip of a snapshot (caller must hold the lock)
+func (ec *ExplorerCache) extends(snapshot *ExplorerSnapshot, tip *Block) bool {
+	if snapshot.Height > tip.Index {
+		return false
+	}
+	block, err := ec.source.GetBlockByIndex(snapshot.Height)
+	return err == nil && block.Hash == snapshot.TipHash
+}
+
+// latestBlocks builds the latest blocks page (caller must hold the lock)
+func (ec *ExplorerCache) latestBlocks(tip *Block) *BlocksPage {
+	page := &BlocksPage{Height: tip.Index}
+	for height := tip.Index; height >= 0 && len(page.Blocks) < ec.config.LatestBlocks; height-- {
+		block, err := ec.source.GetBlockByIndex(height)
+		if err != nil {
+			break
+		}
+		page.Blocks = append(page.Blocks, summarizeBlock(block))
+	}
+	return page
+}
+
+// addressPage builds the page of an address (caller must hold the lock)
+func (ec *ExplorerCache) addressPage(address string, height int64) *AddressPage {
+	page := &AddressPage{Address: address, Balance: ec.source.GetBalance(address)}
+
+	involved := func(tx *Transaction, block *Block) bool {
+		return tx.From == address || tx.To == address
+	}
+	var all []AddressTransaction
+	for tx, block := range ec.source.FindTransactions(involved, 0, height) {
+		all = append(all, AddressTransaction{Transaction: *tx, BlockIndex: block.Index, Timestamp: block.Timestamp})
+	}
+
+	page.TransactionCount = len(all)
+	for i := len(all) - 1; i >= 0 && len(page.Transactions) < ec.config.AddressTransactions; i-- {
+		page.Transactions = append(page.Transactions, all[i])
+	}
+	return page
+}
+
+// summarizeBlock returns the summary of a block
+func summarizeBlock(block *Block) BlockSummary {
+	return BlockSummary{
+		Index:            block.Index,
+		Hash:             block.Hash,
+		PrevHash:         block.PrevHash,
+		Timestamp:        block.Timestamp,
+		TransactionCount: len(block.Transactions),
+	}
+}
+
+// newBlockDetail returns the detail page of a block
+func newBlockDetail(block *Block) *BlockDetail {
+	transactions := make([]Transaction, len(block.Transactions))
+	copy(transactions, block.Transactions)
+
+	return &BlockDetail{
+		BlockSummary: summarizeBlock(block),
+		Version:      block.Version,
+		Nonce:        block.Nonce,
+		MerkleRoot:   block.MerkleRoot,
+		KVRoot:       block.KVRoot,
+		MMRRoot:      block.MMRRoot,
+		ExtRoot:      block.ExtRoot,
+		StateRoot:    block.StateRoot,
+		ReceiptRoot:  block.ReceiptRoot,
+		Extensions:   block.Extensions,
+		Transactions: transactions,
+	}
+}
@@ -0,0 +1,117 @@
+package blockchain
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// FeeEstimateWindow is the number of recent blocks whose fees are sampled
+	FeeEstimateWindow = 50
+
+	// DefaultFeeTarget is the confirmation target used when none is given
+	DefaultFeeTarget = 6
+
+	// FallbackFee is suggested while recent blocks hold no fee-paying transactions
+	FallbackFee = 0.1
+)
+
+// FeeEstimate is a suggested fee for a transaction to be mined within TargetBlocks blocks.
+// Fees scale with the size of a transaction's canonical encoding: use FeeFor to price one.
+type FeeEstimate struct {
+	TargetBlocks int     `json:"targetBlocks"`
+	FeeRate      float64 `json:"feeRate"`    // Fee per byte paid by recent transactions at the target's percentile
+	MinFee       float64 `json:"minFee"`     // Flat minimum of the fee policy for transfers
+	MinFeeRate   float64 `json:"minFeeRate"` // Per-byte minimum of the fee policy for transfers
+	Samples      int     `json:"samples"`    // Transactions the estimate is based on
+	Fallback     bool    `json:"fallback"`   // No recent fees were seen; FeeFor suggests at least FallbackFee
+
+	// Surcharge of the fee policy per byte of a transaction's Data, see FeeForTransaction
+	DataByteFee float64 `json:"dataByteFee"`
+}
+
+// FeeFor returns the suggested fee of a transfer with an encoded size of size bytes,
+// never below the fee policy minimum
+func (fe FeeEstimate) FeeFor(size int) float64 {
+	fee := max(fe.FeeRate*float64(size), fe.MinFee+fe.MinFeeRate*float64(size))
+	if fe.Fallback {
+		fee = max(fee, FallbackFee)
+	}
+	// Round up to 8 decimals so the fee doesn't bloat the encoding it pays for
+	return math.Ceil(fee*1e8-1e-6) / 1e8
+}
+
+// FeeForTransaction returns the suggested fee of a transaction, with the surcharge on its Data.
+// The fee is part of the encoding it pays for, so it is recomputed until the size settles.
+func (fe FeeEstimate) FeeForTransaction(tx Transaction) float64 {
+	surcharge := fe.DataByteFee * float64(len(tx.Data))
+	feeFor := func() float64 {
+		return math.Ceil((fe.FeeFor(len(tx.encode()))+surcharge)*1e8-1e-6) / 1e8
+	}
+
+	tx.Fee = 0
+	fee := feeFor()
+	for i := 0; i < 3; i++ {
+		tx.Fee = fee
+		next := feeFor()
+		if next <= fee {
+			break
+		}
+		fee = next
+	}
+	return fee
+}
+
+// feePercentile returns the percentile of recent fee rates paid to be mined within a number of blocks:
+// the 90th for the next block, easing by 10 points per block down to the median
+func feePercentile(targetBlocks int) float64 {
+	return max(0.5, 1-0.1*float64(targetBlocks))
+}
+
+// estimateFee estimates the fee rate for a confirmation target from the fee rates paid
+// by the transactions of the last FeeEstimateWindow blocks
+func estimateFee(chain []*Block, policy *FeePolicy, targetBlocks int) FeeEstimate {
+	if targetBlocks <= 0 {
+		targetBlocks = DefaultFeeTarget
+	}
+	estimate := FeeEstimate{TargetBlocks: targetBlocks}
+	if policy != nil {
+		rule := policy.Rule(StandardTx)
+		estimate.MinFee = rule.MinFee
+		estimate.MinFeeRate = rule.Weight * policy.ByteFee
+		estimate.DataByteFee = policy.DataByteFee
+	}
+
+	var rates []float64
+	for i := max(len(chain)-FeeEstimateWindow, 1); i < len(chain); i++ {
+		for j := range chain[i].Transactions {
+			tx := &chain[i].Transactions[j]
+			if tx.IsCoinbase() || tx.Fee <= 0 {
+				continue
+			}
+			rates = append(rates, tx.Fee/float64(len(tx.encode())))
+		}
+	}
+
+	estimate.Samples = len(rates)
+	if len(rates) == 0 {
+		estimate.Fallback = true
+		return estimate
+	}
+	sort.Float64s(rates)
+	index := int(math.Ceil(feePercentile(targetBlocks)*float64(len(rates)))) - 1
+	estimate.FeeRate = rates[max(index, 0)]
+	return estimate
+}
+
+// EstimateFee suggests a fee for a transaction to be mined within targetBlocks blocks,
+// based on the fees paid in recent blocks (0 or less uses DefaultFeeTarget)
+func (bc *Blockchain) EstimateFee(targetBlocks int) FeeEstimate {
+	return estimateFee(bc.Chain, bc.feePolicy, targetBlocks)
+}
+
+// EstimateFee suggests a fee for a transaction to be mined within targetBlocks blocks,
+// based on the fees paid in recent blocks (0 or less uses DefaultFeeTarget)
+func (pbc *PersistentBlockchain) EstimateFee(targetBlocks int) FeeEstimate {
+	return estimateFee(pbc.Chain, pbc.feePolicy, targetBlocks)
+}
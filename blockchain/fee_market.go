@@ -0,0 +1,225 @@
+package blockchain
+
+import (
+	"math"
+	"sort"
+)
+
+// feeHistogramBounds are the lower fee rate bounds of the histogram buckets: 0 then a 1-2-5
+// series of fees per byte from 1e-8 up to 1
+var feeHistogramBounds = func() []float64 {
+	bounds := []float64{0}
+	for exp := -8; exp < 0; exp++ {
+		scale := math.Pow10(exp)
+		bounds = append(bounds, scale, 2*scale, 5*scale)
+	}
+	return append(bounds, 1)
+}()
+
+// FeeHistogramBucket counts the pending transactions paying a fee rate in a range
+type FeeHistogramBucket struct {
+	MinFeeRate float64 `json:"minFeeRate"`
+	MaxFeeRate float64 `json:"maxFeeRate"` // Exclusive, 0 for the open-ended top bucket
+	Count      int     `json:"count"`
+	Size       int     `json:"size"` // Encoded bytes of the transactions
+	TotalFee   float64 `json:"totalFee"`
+}
+
+// BlockFeeStats summarizes the fees paid by the non-coinbase transactions of recent blocks
+type BlockFeeStats struct {
+	Blocks        int     `json:"blocks"`
+	Transactions  int     `json:"transactions"`
+	MedianFee     float64 `json:"medianFee"`
+	MedianFeeRate float64 `json:"medianFeeRate"` // Fee per byte of canonical encoding
+	MinFee        float64 `json:"minFee"`
+	MaxFee        float64 `json:"maxFee"`
+	TotalFees     float64 `json:"totalFees"`
+}
+
+// FeeMarket describes the fee market for wallet fee estimation
+type FeeMarket struct {
+	Histogram  []FeeHistogramBucket `json:"histogram"`  // Pending transactions, highest fee rates first
+	Recent     BlockFeeStats        `json:"recent"`     // Fees of recent blocks
+	Projection FeeEstimate          `json:"projection"` // Fee to confirm within a target given the pool
+}
+
+// feeSample is the fee and encoded size of a transaction
+type feeSample struct {
+	fee  float64
+	size int
+}
+
+// rate returns the fee per byte of the sample
+func (s feeSample) rate() float64 {
+	return s.fee / float64(s.size)
+}
+
+// pendingFeesLocked samples the pending transactions that can join a block, highest fee rate
+// first (caller must hold the lock)
+func (tp *Mempool) pendingFeesLocked() []feeSample {
+	samples := make([]feeSample, 0, len(tp.transactions))
+	for _, tx := range tp.transactions {
+		if !tx.IsCoinbase() {
+			samples = append(samples, feeSample{fee: tx.Fee, size: len(tx.encode())})
+		}
+	}
+	for _, tx := range tp.executableLocked() {
+		samples = append(samples, feeSample{fee: tx.Fee, size: len(tx.encode())})
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i].rate() > samples[j].rate()
+	})
+	return samples
+}
+
+// FeeHistogram returns the pending transactions that can join a block grouped by fee rate,
+// highest rates first. Empty buckets are left out.
+func (tp *Mempool) FeeHistogram() []FeeHistogramBucket {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+
+	var histogram []FeeHistogramBucket
+	for _, sample := range tp.pendingFeesLocked() {
+		// Samples come highest rate first, so a sample joins the last bucket or opens a lower one
+		if n := len(histogram); n == 0 || sample.rate() < histogram[n-1].MinFeeRate {
+			i := sort.Search(len(feeHistogramBounds), func(i int) bool {
+				return feeHistogramBounds[i] > sample.rate()
+			}) - 1
+			bucket := FeeHistogramBucket{MinFeeRate: feeHistogramBounds[i]}
+			if i+1 < len(feeHistogramBounds) {
+				bucket.MaxFeeRate = feeHistogramBounds[i+1]
+			}
+			histogram = append(histogram, bucket)
+		}
+		bucket := &histogram[len(histogram)-1]
+		bucket.Count++
+		bucket.Size += sample.size
+		bucket.TotalFee += sample.fee
+	}
+	return histogram
+}
+
+// ProjectFee projects the fee rate for a transaction to confirm within targetBlocks blocks
+// given the pending transactions (0 or less uses DefaultFeeTarget). While the pool holds more
+// transactions than the target's blocks take, a new transaction has to match the rate of the
+// last one they would take; otherwise the fee policy minimum is enough. Samples counts the
+// pending transactions.
+func (tp *Mempool) ProjectFee(targetBlocks int) FeeEstimate {
+	if targetBlocks <= 0 {
+		targetBlocks = DefaultFeeTarget
+	}
+
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+
+	projection := FeeEstimate{TargetBlocks: targetBlocks}
+	if tp.feePolicy != nil {
+		rule := tp.feePolicy.Rule(StandardTx)
+		projection.MinFee = rule.MinFee
+		projection.MinFeeRate = rule.Weight * tp.feePolicy.ByteFee
+		projection.DataByteFee = tp.feePolicy.DataByteFee
+	}
+
+	samples := tp.pendingFeesLocked()
+	projection.Samples = len(samples)
+	if capacity := targetBlocks * MaxBlockTransactions; len(samples) >= capacity {
+		projection.FeeRate = samples[capacity-1].rate()
+	}
+	return projection
+}
+
+// recentFeeStats summarizes the fees of the last blocks blocks of a chain, the genesis block
+// excluded (0 or less uses FeeEstimateWindow)
+func recentFeeStats(chain []*Block, blocks int) BlockFeeStats {
+	if blocks <= 0 {
+		blocks = FeeEstimateWindow
+	}
+
+	var stats BlockFeeStats
+	var fees, rates []float64
+	for i := max(len(chain)-blocks, 1); i < len(chain); i++ {
+		stats.Blocks++
+		for j := range chain[i].Transactions {
+			tx := &chain[i].Transactions[j]
+			if tx.IsCoinbase() {
+				continue
+			}
+			fees = append(fees, tx.Fee)
+			rates = append(rates, tx.Fee/float64(len(tx.encode())))
+			stats.TotalFees += tx.Fee
+		}
+	}
+
+	stats.Transactions = len(fees)
+	if len(fees) == 0 {
+		return stats
+	}
+	sort.Float64s(fees)
+	sort.Float64s(rates)
+	stats.MedianFee = median(fees)
+	stats.MedianFeeRate = median(rates)
+	stats.MinFee = fees[0]
+	stats.MaxFee = fees[len(fees)-1]
+	return stats
+}
+
+// median returns the median of sorted values
+func median(sorted []float64) float64 {
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// FeeHistogram returns the pending transactions grouped by fee rate, highest rates first
+func (bc *Blockchain) FeeHistogram() []FeeHistogramBucket {
+	return bc.TransactionPool.FeeHistogram()
+}
+
+// RecentFeeStats summarizes the fees paid in the last blocks blocks (0 or less uses FeeEstimateWindow)
+func (bc *Blockchain) RecentFeeStats(blocks int) BlockFeeStats {
+	return recentFeeStats(bc.Chain, blocks)
+}
+
+// ProjectFee projects the fee rate for a transaction to confirm within targetBlocks blocks
+// given the pending transactions
+func (bc *Blockchain) ProjectFee(targetBlocks int) FeeEstimate {
+	return bc.TransactionPool.ProjectFee(targetBlocks)
+}
+
+// FeeMarket describes the pending fee rates, the fees of the last blocks blocks and the fee
+// projected to confirm within targetBlocks blocks
+func (bc *Blockchain) FeeMarket(blocks, targetBlocks int) FeeMarket {
+	return FeeMarket{
+		Histogram:  bc.FeeHistogram(),
+		Recent:     bc.RecentFeeStats(blocks),
+		Projection: bc.ProjectFee(targetBlocks),
+	}
+}
+
+// FeeHistogram returns the pending transactions grouped by fee rate, highest rates first
+func (pbc *PersistentBlockchain) FeeHistogram() []FeeHistogramBucket {
+	return pbc.TransactionPool.FeeHistogram()
+}
+
+// RecentFeeStats summarizes the fees paid in the last blocks blocks (0 or less uses FeeEstimateWindow)
+func (pbc *PersistentBlockchain) RecentFeeStats(blocks int) BlockFeeStats {
+	return recentFeeStats(pbc.Chain, blocks)
+}
+
+// ProjectFee projects the fee rate for a transaction to confirm within targetBlocks blocks
+// given the pending transactions
+func (pbc *PersistentBlockchain) ProjectFee(targetBlocks int) FeeEstimate {
+	return pbc.TransactionPool.ProjectFee(targetBlocks)
+}
+
+// FeeMarket describes the pending fee rates, the fees of the last blocks blocks and the fee
+// projected to confirm within targetBlocks blocks
+func (pbc *PersistentBlockchain) FeeMarket(blocks, targetBlocks int) FeeMarket {
+	return FeeMarket{
+		Histogram:  pbc.FeeHistogram(),
+		Recent:     pbc.RecentFeeStats(blocks),
+		Projection: pbc.ProjectFee(targetBlocks),
+	}
+}
@@ -0,0 +1,170 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// FeeRule is the fee charged for one transaction type
+type FeeRule struct {
+	MinFee float64 // Flat minimum fee
+	Weight float64 // Multiplier of the per-byte fee, reflecting the resource cost of the type
+}
+
+// FeePolicy sets distinct minimum fees per transaction type. A transaction must pay
+// its type's MinFee plus Weight * ByteFee for every byte of its canonical encoding, plus
+// DataByteFee for every byte of its Data. Types without a rule use the standard rule, or pay
+// nothing if that is missing too.
+type FeePolicy struct {
+	ByteFee     float64
+	Rules       map[TransactionType]FeeRule
+	DataByteFee float64 // Surcharge per byte of Data, on top of the type's fee
+	MaxDataSize int     // Most bytes of Data a transaction may carry (0 leaves MaxTransactionDataSize)
+}
+
+// Validate checks the policy configuration
+func (fp *FeePolicy) Validate() error {
+	if fp.ByteFee < 0 {
+		return errors.New("byte fee cannot be negative")
+	}
+	if fp.DataByteFee < 0 {
+		return errors.New("data byte fee cannot be negative")
+	}
+	if fp.MaxDataSize < 0 || fp.MaxDataSize > MaxTransactionDataSize {
+		return fmt.Errorf("max data size must be between 0 and %d", MaxTransactionDataSize)
+	}
+	for txType, rule := range fp.Rules {
+		switch txType {
+		case StandardTx, MultiSigTx, TimeLockTx, ContractTx, DataAnchorTx:
+		default:
+			return fmt.Errorf("unknown transaction type %q", txType)
+		}
+		if rule.MinFee < 0 || rule.Weight < 0 {
+			return fmt.Errorf("fee rule for %s cannot be negative", txType)
+		}
+	}
+	return nil
+}
+
+// Rule returns the fee rule applying to a transaction type
+func (fp *FeePolicy) Rule(txType TransactionType) FeeRule {
+	if rule, exists := fp.Rules[txType]; exists {
+		return rule
+	}
+	return fp.Rules[StandardTx]
+}
+
+// RequiredFee returns the minimum fee of a transaction of the given type and encoded size
+func (fp *FeePolicy) RequiredFee(txType TransactionType, size int) float64 {
+	rule := fp.Rule(txType)
+	return rule.MinFee + rule.Weight*fp.ByteFee*float64(size)
+}
+
+// CheckTransaction rejects a transaction carrying more data than the policy allows or paying
+// less than its type and data require. Coinbase transactions are exempt.
+func (fp *FeePolicy) CheckTransaction(tx *Transaction) error {
+	if fp == nil || tx.IsCoinbase() {
+		return nil
+	}
+	if err := fp.CheckData(tx); err != nil {
+		return err
+	}
+	txType := TransactionTypeOf(tx)
+	required := fp.RequiredFee(txType, len(tx.encode())) + fp.DataByteFee*float64(len(tx.Data))
+	return checkFee(tx.Hash, txType, tx.Fee, required)
+}
+
+// CheckData rejects a transaction carrying more data than MaxDataSize
+func (fp *FeePolicy) CheckData(tx *Transaction) error {
+	if fp == nil || fp.MaxDataSize == 0 || len(tx.Data) <= fp.MaxDataSize {
+		return nil
+	}
+	return fmt.Errorf("%w: %d bytes, limit %d", ErrDataTooLarge, len(tx.Data), fp.MaxDataSize)
+}
+
+// CheckEnhancedTransaction rejects an enhanced transaction paying less than its type requires
+func (fp *FeePolicy) CheckEnhancedTransaction(tx *EnhancedTransaction) error {
+	if fp == nil {
+		return nil
+	}
+	return checkFee(tx.Hash, tx.Type, tx.Fee, fp.RequiredFee(tx.Type, tx.encodedSize()))
+}
+
+// ValidateBlock checks every transaction of a block against the policy
+func (fp *FeePolicy) ValidateBlock(block *Block) error {
+	if fp == nil {
+		return nil
+	}
+	for i := range block.Transactions {
+		if err := fp.CheckTransaction(&block.Transactions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkFee compares a paid fee with the required one, returning ErrFeeTooLow if it falls short
+func checkFee(hash string, txType TransactionType, paid, required float64) error {
+	if paid < required {
+		return fmt.Errorf("%w: %s transaction %s pays %.8f, minimum %.8f", ErrFeeTooLow, txType, hash, paid, required)
+	}
+	return nil
+}
+
+// TransactionTypeOf classifies a chain transaction for fee purposes.
+// Enhanced transactions lose their type once converted, so only data anchors and contract calls are told apart.
+func TransactionTypeOf(tx *Transaction) TransactionType {
+	switch {
+	case tx.To == KVNamespaceAddress:
+		return DataAnchorTx
+	case bytes.HasPrefix(tx.Data, []byte(ContractDataPrefix)):
+		return ContractTx
+	}
+	return StandardTx
+}
+
+// encodedSize returns the size of the transaction's JSON encoding without its signatures
+func (tx *EnhancedTransaction) encodedSize() int {
+	unsigned := *tx
+	unsigned.Signatures = nil
+	data, err := json.Marshal(unsigned)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
+
+// RequireFeePolicy returns a rule enforcing a fee policy on every transaction of a block,
+// for policies activated at a height through the upgrade schedule
+func RequireFeePolicy(policy *FeePolicy) ConsensusRule {
+	return ConsensusRule{
+		Name:  "fee-policy",
+		Check: policy.ValidateBlock,
+	}
+}
+
+// SetFeePolicy enforces per-type minimum fees on pool admission and newly connected blocks (nil disables)
+func (bc *Blockchain) SetFeePolicy(policy *FeePolicy) error {
+	if policy != nil {
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+	}
+	bc.feePolicy = policy
+	bc.TransactionPool.SetFeePolicy(policy)
+	return nil
+}
+
+// SetFeePolicy enforces per-type minimum fees on pool admission and newly connected blocks (nil disables)
+func (pbc *PersistentBlockchain) SetFeePolicy(policy *FeePolicy) error {
+	if policy != nil {
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+	}
+	pbc.feePolicy = policy
+	pbc.TransactionPool.SetFeePolicy(policy)
+	return nil
+}
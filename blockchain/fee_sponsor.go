@@ -0,0 +1,114 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ErrUnsignedSponsorship is returned for sponsored transactions without the sponsor's signature
+var ErrUnsignedSponsorship = errors.New("sponsored transaction is not signed by its sponsor")
+
+// NewSponsoredTransaction creates a transaction whose fee is paid by a sponsor: the sender
+// only pays the amount, so an address holding no coins for fees can still transact. The
+// sender signs it as usual and the sponsor co-signs it with AttachSponsorSignature.
+func NewSponsoredTransaction(from, to string, amount, fee float64, nonce int64, sponsor string) *Transaction {
+	tx := &Transaction{
+		From:    from,
+		To:      to,
+		Amount:  amount,
+		Fee:     fee,
+		Nonce:   nonce,
+		Sponsor: sponsor,
+	}
+	tx.Hash = tx.calculateHash()
+	return tx
+}
+
+// FeePayer returns the address debited for the fee: the sponsor if any, otherwise the sender
+func (tx *Transaction) FeePayer() string {
+	if tx.Sponsor != "" {
+		return tx.Sponsor
+	}
+	return tx.From
+}
+
+// payers returns the addresses a transaction debits
+func (tx *Transaction) payers() []string {
+	if tx.Sponsor != "" {
+		return []string{tx.From, tx.Sponsor}
+	}
+	return []string{tx.From}
+}
+
+// debit returns what a transaction takes from an address's balance
+func (tx *Transaction) debit(address string) float64 {
+	var debit float64
+	if tx.From == address {
+		debit += tx.Amount
+	}
+	if tx.FeePayer() == address {
+		debit += tx.Fee
+	}
+	return debit
+}
+
+// sponsorMessage returns the bytes the sponsor signs: the whole hashed encoding, so the
+// signature commits to the fee and can't be moved to another transaction
+func sponsorMessage(tx Transaction) []byte {
+	return append([]byte("sponsor:"), tx.encode()...)
+}
+
+// AttachSponsorSignature co-signs a transaction sponsored by the wallet, agreeing to pay its fee
+func (w *Wallet) AttachSponsorSignature(tx *Transaction) error {
+	if tx.Sponsor != w.Address {
+		return fmt.Errorf("transaction is sponsored by %q, not by this wallet", tx.Sponsor)
+	}
+	if w.signer == nil && w.PrivateKey == nil {
+		return ErrNoPrivateKey
+	}
+	signature, err := w.keySigner().Sign(sponsorMessage(*tx))
+	if err != nil {
+		return err
+	}
+	tx.SponsorKey = w.EncodedPublicKey()
+	tx.SponsorSignature = hex.EncodeToString(signature)
+	return nil
+}
+
+// VerifySponsorSignature checks the sponsor's signature of a sponsored transaction, deriving
+// the sponsor address in the network's format
+func (p *ChainParams) VerifySponsorSignature(tx Transaction) error {
+	if tx.SponsorKey == "" || tx.SponsorSignature == "" {
+		return ErrUnsignedSponsorship
+	}
+	address, err := p.AddressFromPublicKey(tx.SponsorKey)
+	if err != nil {
+		return fmt.Errorf("invalid sponsor public key: %w", err)
+	}
+	if address != tx.Sponsor {
+		return fmt.Errorf("public key belongs to %s, not to sponsor %s", address, tx.Sponsor)
+	}
+	if !VerifySignature(tx.SponsorKey, sponsorMessage(tx), tx.SponsorSignature) {
+		return errors.New("invalid sponsor signature")
+	}
+	return nil
+}
+
+// checkSponsorship validates the sponsorship of a transaction. The sponsor's signature is
+// required even when sender signatures aren't, since it authorizes spending the sponsor's coins.
+func checkSponsorship(tx *Transaction, params *ChainParams) error {
+	if tx.Sponsor == "" {
+		if tx.SponsorKey != "" || tx.SponsorSignature != "" {
+			return errors.New("sponsor signature on a transaction without a sponsor")
+		}
+		return nil
+	}
+	if tx.IsCoinbase() {
+		return errors.New("mining rewards cannot be sponsored")
+	}
+	if tx.Sponsor == tx.From {
+		return errors.New("transaction cannot be sponsored by its sender")
+	}
+	return params.VerifySponsorSignature(*tx)
+}
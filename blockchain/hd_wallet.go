@@ -0,0 +1,384 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// HardenedKeyStart is the first hardened child index; hardened children can't be derived from a public key
+const HardenedKeyStart uint32 = 0x80000000
+
+// DefaultHDAccountPath is the derivation path of the receiving addresses of the first account (BIP44 layout)
+const DefaultHDAccountPath = "m/44'/0'/0'/0"
+
+// DefaultGapLimit is how many consecutive unused addresses end a wallet restore scan
+const DefaultGapLimit = 20
+
+// hdSeedKey is the HMAC key deriving a P-256 master key from a seed (SLIP-10)
+var hdSeedKey = []byte("Nist256p1 seed")
+
+// NewMnemonic generates a seed phrase from fresh entropy of the given size (128 to 256 bits, a multiple of 32)
+func NewMnemonic(bits int) (string, error) {
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return "", errors.New("entropy must be 128 to 256 bits in steps of 32")
+	}
+
+	entropy := make([]byte, bits/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return "", err
+	}
+	return entropyToMnemonic(entropy), nil
+}
+
+// entropyToMnemonic encodes entropy followed by its checksum as words of 11 bits each
+func entropyToMnemonic(entropy []byte) string {
+	checksum := sha256.Sum256(entropy)
+	checksumBits := len(entropy) / 4
+
+	data := new(big.Int).SetBytes(entropy)
+	data.Lsh(data, uint(checksumBits))
+	data.Or(data, big.NewInt(int64(checksum[0]>>(8-checksumBits))))
+
+	count := (len(entropy)*8 + checksumBits) / 11
+	words := make([]string, count)
+	mask := big.NewInt(2047)
+	for i := count - 1; i >= 0; i-- {
+		index := new(big.Int).And(data, mask).Int64()
+		words[i] = bip39Words[index]
+		data.Rsh(data, 11)
+	}
+	return strings.Join(words, " ")
+}
+
+// ValidateMnemonic checks a seed phrase uses known words and carries a valid checksum
+func ValidateMnemonic(mnemonic string) error {
+	words := strings.Fields(mnemonic)
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return fmt.Errorf("invalid mnemonic length: %d words", len(words))
+	}
+
+	data := new(big.Int)
+	for _, word := range words {
+		index, found := bip39Index(word)
+		if !found {
+			return fmt.Errorf("unknown mnemonic word %q", word)
+		}
+		data.Lsh(data, 11)
+		data.Or(data, big.NewInt(int64(index)))
+	}
+
+	checksumBits := len(words) / 3
+	checksum := new(big.Int).And(data, big.NewInt(int64(1)<<checksumBits-1)).Int64()
+	data.Rsh(data, uint(checksumBits))
+
+	entropy := make([]byte, checksumBits*4)
+	data.FillBytes(entropy)
+	expected := sha256.Sum256(entropy)
+	if int64(expected[0]>>(8-checksumBits)) != checksum {
+		return errors.New("invalid mnemonic checksum")
+	}
+	return nil
+}
+
+// bip39Index finds a word in the sorted word list
+func bip39Index(word string) (int, bool) {
+	low, high := 0, len(bip39Words)
+	for low < high {
+		mid := (low + high) / 2
+		if bip39Words[mid] < word {
+			low = mid + 1
+		} else {
+			high = mid
+		}
+	}
+	return low, low < len(bip39Words) && bip39Words[low] == word
+}
+
+// MnemonicToSeed stretches a seed phrase and optional passphrase into a 64-byte wallet seed (BIP39).
+// Phrases are expected in normalized form; the English word list is plain ASCII.
+func MnemonicToSeed(mnemonic, passphrase string) []byte {
+	normalized := strings.Join(strings.Fields(mnemonic), " ")
+	return pbkdf2SHA512([]byte(normalized), []byte("mnemonic"+passphrase), 2048, 64)
+}
+
+// pbkdf2SHA512 derives a key with PBKDF2 using HMAC-SHA512 (RFC 8018)
+func pbkdf2SHA512(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha512.New, password)
+	var key []byte
+	block := make([]byte, 4)
+
+	for i := uint32(1); len(key) < keyLen; i++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(block, i)
+		prf.Write(block)
+		u := prf.Sum(nil)
+
+		t := make([]byte, len(u))
+		copy(t, u)
+		for n := 1; n < iterations; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
+
+// HDKey is a node of a hierarchical deterministic key tree (BIP32 derivation on P-256, as in SLIP-10)
+type HDKey struct {
+	PrivateKey *ecdsa.PrivateKey
+	ChainCode  []byte
+	Depth      uint8
+	Index      uint32
+}
+
+// NewMasterKey derives the root key of a tree from a wallet seed
+func NewMasterKey(seed []byte) (*HDKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, errors.New("seed must be 16 to 64 bytes")
+	}
+
+	mac := hmac.New(sha512.New, hdSeedKey)
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	// An out-of-range key is rehashed until it is usable
+	n := elliptic.P256().Params().N
+	for {
+		d := new(big.Int).SetBytes(sum[:32])
+		if d.Sign() > 0 && d.Cmp(n) < 0 {
+			return &HDKey{PrivateKey: privateKeyFromScalar(d), ChainCode: sum[32:]}, nil
+		}
+		mac = hmac.New(sha512.New, hdSeedKey)
+		mac.Write(sum)
+		sum = mac.Sum(nil)
+	}
+}
+
+// privateKeyFromScalar builds a P-256 key pair from its private scalar
+func privateKeyFromScalar(d *big.Int) *ecdsa.PrivateKey {
+	curve := elliptic.P256()
+	key := &ecdsa.PrivateKey{D: d}
+	key.PublicKey.Curve = curve
+	key.PublicKey.X, key.PublicKey.Y = curve.ScalarBaseMult(d.FillBytes(make([]byte, 32)))
+	return key
+}
+
+// Child derives the child key at an index (hardened from HardenedKeyStart upwards)
+func (k *HDKey) Child(index uint32) (*HDKey, error) {
+	if k.Depth == 255 {
+		return nil, errors.New("maximum derivation depth reached")
+	}
+
+	curve := elliptic.P256()
+	n := curve.Params().N
+
+	data := make([]byte, 0, 37)
+	if index >= HardenedKeyStart {
+		data = append(data, 0)
+		data = append(data, k.PrivateKey.D.FillBytes(make([]byte, 32))...)
+	} else {
+		data = append(data, elliptic.MarshalCompressed(curve, k.PrivateKey.X, k.PrivateKey.Y)...)
+	}
+	data = binary.BigEndian.AppendUint32(data, index)
+
+	for {
+		mac := hmac.New(sha512.New, k.ChainCode)
+		mac.Write(data)
+		sum := mac.Sum(nil)
+
+		tweak := new(big.Int).SetBytes(sum[:32])
+		d := new(big.Int).Add(tweak, k.PrivateKey.D)
+		d.Mod(d, n)
+		if tweak.Cmp(n) < 0 && d.Sign() > 0 {
+			return &HDKey{
+				PrivateKey: privateKeyFromScalar(d),
+				ChainCode:  sum[32:],
+				Depth:      k.Depth + 1,
+				Index:      index,
+			}, nil
+		}
+
+		// Invalid keys are skipped by rehashing with the right half of the output
+		data = append([]byte{1}, sum[32:]...)
+		data = binary.BigEndian.AppendUint32(data, index)
+	}
+}
+
+// DerivePath derives a descendant along a path such as "m/44'/0'/0'/0/5" (' or h marks hardened steps)
+func (k *HDKey) DerivePath(path string) (*HDKey, error) {
+	indexes, err := ParseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key := k
+	for _, index := range indexes {
+		if key, err = key.Child(index); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// ParseDerivationPath converts a path such as "m/44'/0'/0'/0" into child indexes
+func ParseDerivationPath(path string) ([]uint32, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if parts[0] != "m" {
+		return nil, fmt.Errorf("derivation path %q must start with m", path)
+	}
+
+	indexes := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		hardened := strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h")
+		if hardened {
+			part = part[:len(part)-1]
+		}
+		index, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || uint32(index) >= HardenedKeyStart {
+			return nil, fmt.Errorf("invalid derivation path element %q", part)
+		}
+		if hardened {
+			index += uint64(HardenedKeyStart)
+		}
+		indexes = append(indexes, uint32(index))
+	}
+	return indexes, nil
+}
+
+// Wallet returns a wallet for the key
+func (k *HDKey) Wallet() *Wallet {
+	return &Wallet{
+		PrivateKey: k.PrivateKey,
+		PublicKey:  &k.PrivateKey.PublicKey,
+		Address:    generateAddress(&k.PrivateKey.PublicKey),
+	}
+}
+
+// AddressActivity tells whether an address has ever appeared in the chain
+type AddressActivity interface {
+	IsAddressUsed(address string) bool
+}
+
+// HDWallet derives any number of wallets from a single seed phrase, so one backup restores them all
+type HDWallet struct {
+	Mnemonic    string
+	AccountPath string
+	Wallets     []*Wallet // Derived addresses in index order
+	account     *HDKey
+}
+
+// NewHDWallet creates a wallet from a fresh 24-word seed phrase
+func NewHDWallet(passphrase string) (*HDWallet, error) {
+	mnemonic, err := NewMnemonic(256)
+	if err != nil {
+		return nil, err
+	}
+	return OpenHDWallet(mnemonic, passphrase, DefaultHDAccountPath)
+}
+
+// OpenHDWallet opens the account of a seed phrase at a derivation path, without deriving any address yet
+func OpenHDWallet(mnemonic, passphrase, accountPath string) (*HDWallet, error) {
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+
+	master, err := NewMasterKey(MnemonicToSeed(mnemonic, passphrase))
+	if err != nil {
+		return nil, err
+	}
+	account, err := master.DerivePath(accountPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HDWallet{
+		Mnemonic:    strings.Join(strings.Fields(mnemonic), " "),
+		AccountPath: accountPath,
+		account:     account,
+	}, nil
+}
+
+// RestoreHDWallet recovers every address of a seed phrase that was used on the chain.
+// Addresses are derived in order until gapLimit consecutive ones have no activity.
+func RestoreHDWallet(mnemonic, passphrase string, chain AddressActivity, gapLimit int) (*HDWallet, error) {
+	if gapLimit <= 0 {
+		gapLimit = DefaultGapLimit
+	}
+
+	hw, err := OpenHDWallet(mnemonic, passphrase, DefaultHDAccountPath)
+	if err != nil {
+		return nil, err
+	}
+
+	used := 0
+	for index := 0; index-used < gapLimit; index++ {
+		wallet, err := hw.DeriveWallet(uint32(index))
+		if err != nil {
+			return nil, err
+		}
+		if chain.IsAddressUsed(wallet.Address) {
+			used = index + 1
+		}
+	}
+
+	// Keep the used addresses and the first fresh one to receive with
+	hw.Wallets = hw.Wallets[:used+1]
+	return hw, nil
+}
+
+// DeriveWallet returns the wallet at an index of the account, deriving all wallets up to it
+func (hw *HDWallet) DeriveWallet(index uint32) (*Wallet, error) {
+	if index >= HardenedKeyStart {
+		return nil, errors.New("wallet index out of range")
+	}
+	for uint32(len(hw.Wallets)) <= index {
+		key, err := hw.account.Child(uint32(len(hw.Wallets)))
+		if err != nil {
+			return nil, err
+		}
+		hw.Wallets = append(hw.Wallets, key.Wallet())
+	}
+	return hw.Wallets[index], nil
+}
+
+// NextWallet derives a new address after the last one
+func (hw *HDWallet) NextWallet() (*Wallet, error) {
+	return hw.DeriveWallet(uint32(len(hw.Wallets)))
+}
+
+// Addresses returns the derived addresses in index order
+func (hw *HDWallet) Addresses() []string {
+	addresses := make([]string, len(hw.Wallets))
+	for i, wallet := range hw.Wallets {
+		addresses[i] = wallet.Address
+	}
+	return addresses
+}
+
+// Balances returns the balance of every derived address, and their total
+func (hw *HDWallet) Balances(view BalanceView) (map[string]float64, float64) {
+	balances := make(map[string]float64, len(hw.Wallets))
+	total := 0.0
+	for _, wallet := range hw.Wallets {
+		balance := view.GetBalance(wallet.Address)
+		balances[wallet.Address] = balance
+		total += balance
+	}
+	return balances, total
+}
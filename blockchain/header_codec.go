@@ -0,0 +1,166 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// CompactHeaderSize is the size of a compact binary block header. Headers committing a
+// history root, extensions, a state root or receipts are 32 bytes longer for each; see CompactHeaderLength.
+const CompactHeaderSize = 1 + 4 + 8 + 8 + 8 + 4*32
+
+// CompactHeaderPrefix is the length of the flags and version leading a compact header, which
+// together give its length
+const CompactHeaderPrefix = 1 + 4
+
+// Compact header flags. Bits 0-3 mark which of the previous hash, hash, Merkle root
+// and key-value root are present; the unmined genesis block has no hash and blocks
+// without transactions or key-value entries have empty roots.
+const (
+	compactHashFields      = 4
+	compactGenesisPrevious = 1 << compactHashFields // The previous hash is the genesis placeholder "0"
+
+	// compactHistoryRoot, compactExtRoot and compactStateRoot mark the history, extension and
+	// state roots appended, in that order, after the fixed layout
+	compactHistoryRoot = 1 << (compactHashFields + 1)
+	compactExtRoot     = 1 << (compactHashFields + 2)
+	compactStateRoot   = 1 << (compactHashFields + 3)
+)
+
+// compactRootFlags are the flags of the appended roots in their order
+var compactRootFlags = [...]byte{compactHistoryRoot, compactExtRoot, compactStateRoot}
+
+// genesisPrevHash is the previous hash of the genesis block
+const genesisPrevHash = "0"
+
+// MarshalCompact encodes the header in a fixed-size big-endian layout for constrained clients:
+// flags (1), version (4), index (8), timestamp (8), nonce (8), then the previous hash,
+// hash, Merkle root and key-value root as 32 raw bytes each (zero when absent). Headers
+// committing a history root, extensions or a state root append those roots as 32 more bytes each.
+// The flags byte is full, so the receipt root is appended last by every header from
+// ReceiptRootBlockVersion on (zero for a block without transactions).
+func (h *BlockHeader) MarshalCompact() ([]byte, error) {
+	buf := make([]byte, CompactHeaderSize)
+	binary.BigEndian.PutUint32(buf[1:], uint32(h.Version))
+	binary.BigEndian.PutUint64(buf[5:], uint64(h.Index))
+	binary.BigEndian.PutUint64(buf[13:], uint64(h.Timestamp))
+	binary.BigEndian.PutUint64(buf[21:], uint64(h.Nonce))
+
+	var flags byte
+	names := [compactHashFields]string{"previous hash", "hash", "merkle root", "kv root"}
+	for i, value := range [compactHashFields]string{h.PrevHash, h.Hash, h.MerkleRoot, h.KVRoot} {
+		if value == "" {
+			continue
+		}
+		if i == 0 && value == genesisPrevHash {
+			flags |= compactGenesisPrevious
+			continue
+		}
+		decoded, err := hex.DecodeString(value)
+		if err != nil || len(decoded) != 32 {
+			return nil, fmt.Errorf("header %s is not a 32-byte hex hash", names[i])
+		}
+		copy(buf[29+i*32:], decoded)
+		flags |= 1 << i
+	}
+	for _, root := range []struct {
+		name  string
+		value string
+		flag  byte
+	}{
+		{"history root", h.MMRRoot, compactHistoryRoot},
+		{"extension root", h.ExtRoot, compactExtRoot},
+		{"state root", h.StateRoot, compactStateRoot},
+	} {
+		if root.value == "" {
+			continue
+		}
+		decoded, err := hex.DecodeString(root.value)
+		if err != nil || len(decoded) != 32 {
+			return nil, fmt.Errorf("header %s is not a 32-byte hex hash", root.name)
+		}
+		buf = append(buf, decoded...)
+		flags |= root.flag
+	}
+	if h.Version >= ReceiptRootBlockVersion {
+		receiptRoot := make([]byte, 32)
+		if h.ReceiptRoot != "" {
+			decoded, err := hex.DecodeString(h.ReceiptRoot)
+			if err != nil || len(decoded) != 32 {
+				return nil, errors.New("header receipt root is not a 32-byte hex hash")
+			}
+			copy(receiptRoot, decoded)
+		}
+		buf = append(buf, receiptRoot...)
+	} else if h.ReceiptRoot != "" {
+		return nil, fmt.Errorf("header version %d does not commit receipts", h.Version)
+	}
+	buf[0] = flags
+	return buf, nil
+}
+
+// CompactHeaderLength returns the length of a compact header from its first
+// CompactHeaderPrefix bytes, the flags and version
+func CompactHeaderLength(prefix []byte) int {
+	length := CompactHeaderSize
+	for _, flag := range compactRootFlags {
+		if prefix[0]&flag != 0 {
+			length += 32
+		}
+	}
+	if int32(binary.BigEndian.Uint32(prefix[1:])) >= ReceiptRootBlockVersion {
+		length += 32
+	}
+	return length
+}
+
+// UnmarshalCompactHeader decodes a header encoded by MarshalCompact
+func UnmarshalCompactHeader(data []byte) (*BlockHeader, error) {
+	if len(data) < CompactHeaderSize || len(data) != CompactHeaderLength(data) {
+		return nil, fmt.Errorf("compact header has the wrong length: %d bytes", len(data))
+	}
+	flags := data[0]
+	if flags&compactGenesisPrevious != 0 && flags&1 != 0 {
+		return nil, errors.New("compact header has both a previous hash and the genesis placeholder")
+	}
+
+	var hashes [compactHashFields]string
+	for i := range hashes {
+		if flags&(1<<i) != 0 {
+			hashes[i] = hex.EncodeToString(data[29+i*32 : 29+(i+1)*32])
+		}
+	}
+	if flags&compactGenesisPrevious != 0 {
+		hashes[0] = genesisPrevHash
+	}
+	var roots [len(compactRootFlags)]string
+	offset := CompactHeaderSize
+	for i, flag := range compactRootFlags {
+		if flags&flag != 0 {
+			roots[i] = hex.EncodeToString(data[offset : offset+32])
+			offset += 32
+		}
+	}
+	version := int32(binary.BigEndian.Uint32(data[1:]))
+	var receiptRoot string
+	if version >= ReceiptRootBlockVersion && !bytes.Equal(data[offset:], make([]byte, 32)) {
+		receiptRoot = hex.EncodeToString(data[offset:])
+	}
+	return &BlockHeader{
+		Version:     version,
+		Index:       int64(binary.BigEndian.Uint64(data[5:])),
+		Timestamp:   int64(binary.BigEndian.Uint64(data[13:])),
+		PrevHash:    hashes[0],
+		Hash:        hashes[1],
+		Nonce:       int64(binary.BigEndian.Uint64(data[21:])),
+		MerkleRoot:  hashes[2],
+		KVRoot:      hashes[3],
+		MMRRoot:     roots[0],
+		ExtRoot:     roots[1],
+		StateRoot:   roots[2],
+		ReceiptRoot: receiptRoot,
+	}, nil
+}
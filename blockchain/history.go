@@ -0,0 +1,245 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+
+	"blockchain/blockchain/verify"
+)
+
+// historyMMR is a Merkle mountain range over the hashes of the chain's blocks, in height order.
+// Appending a block only adds the nodes it completes, and every earlier range stays embedded
+// in it, so the root and proofs of any past height come from the same nodes. Like the
+// transaction index it follows the chain incrementally and is rebuilt after a reorg.
+type historyMMR struct {
+	levels  [][]string // levels[0] holds the block hashes, levels[k] the roots of aligned subtrees of 2^k blocks
+	tipHash string
+}
+
+// newHistoryMMR creates an empty range
+func newHistoryMMR() *historyMMR {
+	return &historyMMR{levels: [][]string{{}}}
+}
+
+// size returns the number of blocks in the range
+func (m *historyMMR) size() int64 {
+	return int64(len(m.levels[0]))
+}
+
+// sync brings the range up to date with the chain
+func (m *historyMMR) sync(chain []*Block) {
+	size := m.size()
+	if size > 0 && (size > int64(len(chain)) || chain[size-1].Hash != m.tipHash) {
+		m.levels = [][]string{{}}
+		size = 0
+	}
+	for _, block := range chain[size:] {
+		m.append(block.Hash)
+	}
+}
+
+// append adds the next block hash, hashing up every subtree it completes
+func (m *historyMMR) append(hash string) {
+	m.levels[0] = append(m.levels[0], hash)
+	m.tipHash = hash
+	for level := 0; len(m.levels[level])%2 == 0; level++ {
+		nodes := m.levels[level]
+		parent := verify.NodeHash(nodes[len(nodes)-2], nodes[len(nodes)-1])
+		if level+1 == len(m.levels) {
+			m.levels = append(m.levels, nil)
+		}
+		m.levels[level+1] = append(m.levels[level+1], parent)
+	}
+}
+
+// peaks returns the peaks of the range over the first size blocks, left to right
+func (m *historyMMR) peaks(size int64) []string {
+	var peaks []string
+	var offset int64
+	for level := len(m.levels) - 1; level >= 0; level-- {
+		if size&(1<<level) != 0 {
+			peaks = append(peaks, m.levels[level][offset>>level])
+			offset += 1 << level
+		}
+	}
+	return peaks
+}
+
+// root returns the root of the range over the first size blocks
+func (m *historyMMR) root(size int64) string {
+	return verify.MMRRoot(m.peaks(size))
+}
+
+// proof returns the siblings leading from a block's hash to its peak in the range over the
+// first size blocks, with the peaks of that range
+func (m *historyMMR) proof(index, size int64) ([]string, []string) {
+	var siblings []string
+	var offset int64
+	for level := len(m.levels) - 1; level >= 0; level-- {
+		if size&(1<<level) == 0 {
+			continue
+		}
+		if index < offset+1<<level {
+			for j := 0; j < level; j++ {
+				siblings = append(siblings, m.levels[j][(index>>j)^1])
+			}
+			break
+		}
+		offset += 1 << level
+	}
+	return siblings, m.peaks(size)
+}
+
+// historyCommitment returns the history root a block at a height must commit: the root over
+// every earlier block at multiples of the commit interval, "" elsewhere. The range must hold
+// exactly the blocks below the height.
+func historyCommitment(params *ChainParams, history *historyMMR, height int64) string {
+	interval := params.historyInterval()
+	if interval == 0 || height == 0 || height%interval != 0 {
+		return ""
+	}
+	return history.root(height)
+}
+
+// checkMMRRoot checks a block commits the history root expected at its height
+func checkMMRRoot(block *Block, params *ChainParams, history *historyMMR) error {
+	expected := historyCommitment(params, history, block.Index)
+	if block.MMRRoot == expected {
+		return nil
+	}
+	if expected == "" {
+		return errors.New("block commits a history root out of turn")
+	}
+	return errors.New("block commits the wrong history root")
+}
+
+// AncestorProof proves a block is an ancestor of a head block without the headers in between:
+// a Merkle mountain range proof that the ancestor's hash is in the history root committed by a
+// block at or below the head, and the few headers linking that block to the head. An ancestor
+// newer than the last commitment is linked to the head by headers alone.
+type AncestorProof struct {
+	Ancestor BlockHeader   `json:"ancestor"`
+	Commit   *BlockHeader  `json:"commit,omitempty"` // Block whose history root holds the ancestor
+	Siblings []string      `json:"siblings,omitempty"`
+	Peaks    []string      `json:"peaks,omitempty"`
+	Headers  []BlockHeader `json:"headers"` // Headers after the commit (or the ancestor) up to the head
+}
+
+// ancestorProof builds the proof that the block at the ancestor height is an ancestor of the head
+func ancestorProof(chain []*Block, params *ChainParams, history *historyMMR, ancestor, head int64) (*AncestorProof, error) {
+	interval := params.historyInterval()
+	if interval == 0 {
+		return nil, errors.New("history commitments are disabled")
+	}
+	if head < 0 || head >= int64(len(chain)) {
+		return nil, errors.New("invalid head index")
+	}
+	if ancestor <= 0 || ancestor >= head {
+		return nil, errors.New("ancestor must be between the genesis block and the head")
+	}
+
+	proof := &AncestorProof{Ancestor: chain[ancestor].Header()}
+	from := ancestor
+	if commit := head / interval * interval; commit > ancestor {
+		header := chain[commit].Header()
+		proof.Commit = &header
+		proof.Siblings, proof.Peaks = history.proof(ancestor, commit)
+		from = commit
+	}
+	for height := from + 1; height <= head; height++ {
+		proof.Headers = append(proof.Headers, chain[height].Header())
+	}
+	return proof, nil
+}
+
+// VerifyAncestorProof checks a proof that a block is an ancestor of a trusted head header.
+// Every header in the proof is checked against its hash, so no other chain data is needed.
+func VerifyAncestorProof(proof *AncestorProof, head BlockHeader) error {
+	if proof == nil {
+		return errors.New("missing proof")
+	}
+	if len(proof.Siblings) > MaxProofDepth || len(proof.Peaks) > MaxProofDepth {
+		return &LimitError{Field: "siblings", Size: max(len(proof.Siblings), len(proof.Peaks)), Limit: MaxProofDepth}
+	}
+
+	ancestor := proof.Ancestor
+	if ancestor.Index <= 0 || ancestor.Index >= head.Index {
+		return errors.New("block is not below the head")
+	}
+	if ancestor.Hash != ancestor.calculateHash() {
+		return errors.New("ancestor header does not match its hash")
+	}
+
+	link := ancestor
+	if commit := proof.Commit; commit != nil {
+		if commit.Hash != commit.calculateHash() {
+			return errors.New("commit header does not match its hash")
+		}
+		if commit.MMRRoot == "" || commit.Index <= ancestor.Index {
+			return errors.New("commit header does not commit the ancestor's history")
+		}
+		if !verify.VerifyMMRProof(ancestor.Hash, ancestor.Index, commit.Index, proof.Siblings, proof.Peaks, commit.MMRRoot) {
+			return errors.New("ancestor is not in the committed history")
+		}
+		link = *commit
+	}
+
+	for _, header := range proof.Headers {
+		if header.Index != link.Index+1 || header.PrevHash != link.Hash {
+			return fmt.Errorf("header %d does not link to its parent", header.Index)
+		}
+		if header.Hash != header.calculateHash() {
+			return fmt.Errorf("header %d does not match its hash", header.Index)
+		}
+		link = header
+	}
+	if link.Index != head.Index || link.Hash != head.Hash {
+		return errors.New("proof does not reach the head")
+	}
+	return nil
+}
+
+// historyRange returns the history range, caught up with the chain
+func (bc *Blockchain) historyRange() *historyMMR {
+	if bc.history == nil {
+		bc.history = newHistoryMMR()
+	}
+	bc.history.sync(bc.Chain)
+	return bc.history
+}
+
+// HistoryRoot returns the root of the Merkle mountain range over the hashes of the first size blocks
+func (bc *Blockchain) HistoryRoot(size int64) (string, error) {
+	if size < 0 || size > int64(len(bc.Chain)) {
+		return "", errors.New("invalid history size")
+	}
+	return bc.historyRange().root(size), nil
+}
+
+// GetAncestorProof proves the block at the ancestor height is an ancestor of the head block
+func (bc *Blockchain) GetAncestorProof(ancestor, head int64) (*AncestorProof, error) {
+	return ancestorProof(bc.Chain, bc.params, bc.historyRange(), ancestor, head)
+}
+
+// historyRange returns the history range, caught up with the chain
+func (pbc *PersistentBlockchain) historyRange() *historyMMR {
+	if pbc.history == nil {
+		pbc.history = newHistoryMMR()
+	}
+	pbc.history.sync(pbc.Chain)
+	return pbc.history
+}
+
+// HistoryRoot returns the root of the Merkle mountain range over the hashes of the first size blocks
+func (pbc *PersistentBlockchain) HistoryRoot(size int64) (string, error) {
+	if size < 0 || size > int64(len(pbc.Chain)) {
+		return "", errors.New("invalid history size")
+	}
+	return pbc.historyRange().root(size), nil
+}
+
+// GetAncestorProof proves the block at the ancestor height is an ancestor of the head block.
+// Headers outlive pruning, so proofs cover pruned blocks too.
+func (pbc *PersistentBlockchain) GetAncestorProof(ancestor, head int64) (*AncestorProof, error) {
+	return ancestorProof(pbc.Chain, pbc.params, pbc.historyRange(), ancestor, head)
+}
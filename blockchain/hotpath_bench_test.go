@@ -0,0 +1,111 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// benchmarkTransactions is the block size of the hot path benchmarks
+const benchmarkTransactions = 1000
+
+// benchmarkBlock returns a block of benchmarkTransactions transactions with their hashes
+func benchmarkBlock() (*Block, []string) {
+	transactions := make([]Transaction, benchmarkTransactions)
+	hashes := make([]string, len(transactions))
+	for i := range transactions {
+		transactions[i] = *NewTransaction(fmt.Sprintf("sender-%d", i), fmt.Sprintf("recipient-%d", i), float64(i+1), 0.1)
+		hashes[i] = transactions[i].Hash
+	}
+	return NewBlock(1, transactions, strings.Repeat("0", 64)), hashes
+}
+
+// benchmarkPair runs a pooled hot path and the allocating baseline it replaced as sub-benchmarks
+func benchmarkPair(b *testing.B, pooled, baseline func()) {
+	for _, bench := range []struct {
+		name string
+		op   func()
+	}{{"pooled", pooled}, {"baseline", baseline}} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				bench.op()
+			}
+		})
+	}
+}
+
+func BenchmarkMine(b *testing.B) {
+	block, _ := benchmarkBlock()
+
+	// Mining restarts from the same nonce, so both sides try the same headers
+	const difficulty = 2
+	benchmarkPair(b,
+		func() {
+			block.Nonce = 0
+			block.MineBlock(difficulty)
+		},
+		func() {
+			block.Nonce = 0
+			for {
+				block.Nonce++
+				if hash := block.calculateHash(); strings.HasPrefix(hash, strings.Repeat("0", difficulty)) {
+					block.Hash = hash
+					break
+				}
+			}
+		})
+}
+
+func BenchmarkMerkle(b *testing.B) {
+	_, hashes := benchmarkBlock()
+	benchmarkPair(b,
+		func() { newMerkleTreeFromHashes(hashes, LegacyMerkleScheme) },
+		func() { legacyMerkleTree(hashes) })
+}
+
+func BenchmarkEncode(b *testing.B) {
+	block, _ := benchmarkBlock()
+
+	// The encoder sends the block as a sync response
+	payload := blocksPayload{Blocks: []*Block{block}}
+	benchmarkPair(b,
+		func() {
+			buf := getEncodeBuffer()
+			buf.encodeMessage(MsgBlocks, payload)
+			io.Discard.Write(buf.Bytes())
+			putEncodeBuffer(buf)
+		},
+		func() {
+			data, _ := json.Marshal(payload)
+			json.NewEncoder(io.Discard).Encode(&Message{Type: MsgBlocks, Payload: data})
+		})
+}
+
+// legacyMerkleTree builds a Merkle tree allocating every node and level separately, the
+// baseline of BenchmarkMerkle
+func legacyMerkleTree(hashes []string) *MerkleTree {
+	var nodes []*MerkleNode
+	for _, hash := range hashes {
+		nodes = append(nodes, &MerkleNode{Hash: hash, Data: []byte(hash)})
+	}
+	for {
+		if len(nodes)%2 != 0 {
+			nodes = append(nodes, nodes[len(nodes)-1])
+		}
+		var level []*MerkleNode
+		for i := 0; i < len(nodes); i += 2 {
+			level = append(level, &MerkleNode{
+				Left:  nodes[i],
+				Right: nodes[i+1],
+				Hash:  calculateNodeHash(nodes[i].Hash, nodes[i+1].Hash),
+			})
+		}
+		nodes = level
+		if len(nodes) == 1 {
+			return &MerkleTree{Root: nodes[0]}
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package blockchain
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Kinds of inconsistency reported by VerifyIntegrity
+const (
+	IssueUndecodableBlock    = "undecodable_block"    // block_data isn't a valid block
+	IssueBlockColumns        = "block_columns"        // A blocks column disagrees with block_data
+	IssueBlockHash           = "block_hash"           // The stored hash isn't the hash of the header
+	IssueChainLinkage        = "chain_linkage"        // A gap in the heights or a broken previous hash
+	IssueMerkleRoot          = "merkle_root"          // The transactions don't hash to the Merkle root
+	IssueTransactionHash     = "transaction_hash"     // A transaction's hash isn't the hash of its fields
+	IssueMissingTransaction  = "missing_transaction"  // A block's transaction has no transactions row
+	IssueTransactionMismatch = "transaction_mismatch" // A transactions row disagrees with its block
+	IssueOrphanTransaction   = "orphan_transaction"   // A transactions row no stored block holds
+	IssueBlockchainState     = "blockchain_state"     // The blockchain_state row disagrees with the blocks
+)
+
+// IntegrityIssue is one inconsistency found in the database
+type IntegrityIssue struct {
+	Kind       string `json:"kind"`
+	BlockIndex int64  `json:"blockIndex"`     // Block the issue was found at, -1 for the chain state
+	Hash       string `json:"hash,omitempty"` // Block or transaction concerned
+	Detail     string `json:"detail"`
+}
+
+// IntegrityReport summarizes a database integrity check
+type IntegrityReport struct {
+	Blocks       int              `json:"blocks"`       // Blocks checked
+	Transactions int              `json:"transactions"` // Transactions checked
+	Issues       []IntegrityIssue `json:"issues"`
+}
+
+// OK reports whether no inconsistency was found
+func (r *IntegrityReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// add records an issue
+func (r *IntegrityReport) add(kind string, blockIndex int64, hash, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, IntegrityIssue{Kind: kind, BlockIndex: blockIndex, Hash: hash, Detail: fmt.Sprintf(format, args...)})
+}
+
+// storedTransaction is a row of the transactions table
+type storedTransaction struct {
+	blockHash  string
+	blockIndex int64
+	txIndex    int
+	from, to   string
+	amount     float64
+	fee        float64
+	seen       bool // Matched to the block holding it
+}
+
+// VerifyIntegrity checks the stored chain instead of trusting it: every block's hash, Merkle
+// root and transaction hashes are recomputed from block_data and compared with the columns of
+// the blocks table, the chain linkage is followed, the transactions table is cross-checked
+// against the blocks, and the blockchain_state row against the chain. Nothing is repaired;
+// the inconsistencies found are reported. Pruned blocks are checked by header only.
+func (d *Database) VerifyIntegrity() (*IntegrityReport, error) {
+	if err := d.Flush(); err != nil {
+		return nil, err
+	}
+
+	// Read everything in one transaction for a consistent view
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stored, err := loadStoredTransactions(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &IntegrityReport{}
+	rows, err := tx.Query(`
+		SELECT block_index, hash, previous_hash, merkle_root, timestamp, nonce, transaction_count, block_data
+		FROM blocks ORDER BY block_index ASC`)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		prev       *Block
+		lastIndex  int64 = -1
+		lastHash   string
+		txCountSum int64
+		pruned     bool // A block before the current one is pruned
+	)
+	for rows.Next() {
+		var index, timestamp, nonce, txCount int64
+		var hash, prevHash, merkleRoot, blockData string
+		if err := rows.Scan(&index, &hash, &prevHash, &merkleRoot, &timestamp, &nonce, &txCount, &blockData); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		report.Blocks++
+		txCountSum += txCount
+		if index != lastIndex+1 {
+			report.add(IssueChainLinkage, index, hash, "expected block %d, found block %d", lastIndex+1, index)
+			prev = nil
+		}
+		lastIndex, lastHash = index, hash
+
+		block, err := decodeStoredBlock(blockData)
+		if err != nil {
+			report.add(IssueUndecodableBlock, index, hash, "%v", err)
+			prev = nil
+			continue
+		}
+		report.checkBlockColumns(block, index, hash, prevHash, merkleRoot, timestamp, nonce, txCount)
+		report.checkBlock(block, prev)
+		if !block.Pruned {
+			report.Transactions += len(block.Transactions)
+			report.checkBlockTransactions(block, stored, pruned)
+		}
+		pruned = pruned || block.Pruned
+		prev = block
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for hash, row := range stored {
+		if !row.seen {
+			report.add(IssueOrphanTransaction, row.blockIndex, hash, "no stored block %s holds it at position %d", row.blockHash, row.txIndex)
+		}
+	}
+
+	if err := report.checkBlockchainState(tx, lastIndex, lastHash, txCountSum); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// loadStoredTransactions loads the rows of the transactions table by hash
+func loadStoredTransactions(tx *sql.Tx) (map[string]*storedTransaction, error) {
+	rows, err := tx.Query("SELECT hash, block_hash, block_index, tx_index, from_address, to_address, amount, fee FROM transactions")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stored := make(map[string]*storedTransaction)
+	for rows.Next() {
+		var hash string
+		row := &storedTransaction{}
+		if err := rows.Scan(&hash, &row.blockHash, &row.blockIndex, &row.txIndex, &row.from, &row.to, &row.amount, &row.fee); err != nil {
+			return nil, err
+		}
+		stored[hash] = row
+	}
+	return stored, rows.Err()
+}
+
+// checkBlockColumns compares the columns of a blocks row with its decoded block_data
+func (r *IntegrityReport) checkBlockColumns(block *Block, index int64, hash, prevHash, merkleRoot string, timestamp, nonce, txCount int64) {
+	mismatch := func(column string, stored, decoded interface{}) {
+		r.add(IssueBlockColumns, index, hash, "%s column is %v, block_data has %v", column, stored, decoded)
+	}
+	if block.Index != index {
+		mismatch("block_index", index, block.Index)
+	}
+	if block.Hash != hash {
+		mismatch("hash", hash, block.Hash)
+	}
+	if block.PrevHash != prevHash {
+		mismatch("previous_hash", prevHash, block.PrevHash)
+	}
+	if block.MerkleRoot != merkleRoot {
+		mismatch("merkle_root", merkleRoot, block.MerkleRoot)
+	}
+	if block.Timestamp != timestamp {
+		mismatch("timestamp", timestamp, block.Timestamp)
+	}
+	if block.Nonce != nonce {
+		mismatch("nonce", nonce, block.Nonce)
+	}
+	// Pruning drops the transactions but keeps their count
+	if !block.Pruned && int64(len(block.Transactions)) != txCount {
+		mismatch("transaction_count", txCount, len(block.Transactions))
+	}
+}
+
+// checkBlock recomputes the hashes of a block and checks that it links to the previous one
+// (nil if unknown). The genesis block isn't mined and has no hash to check.
+func (r *IntegrityReport) checkBlock(block, prev *Block) {
+	if calculated := block.calculateHash(); block.Index > 0 && calculated != block.Hash {
+		r.add(IssueBlockHash, block.Index, block.Hash, "header hashes to %s", calculated)
+	}
+	if prev != nil && block.PrevHash != prev.Hash {
+		r.add(IssueChainLinkage, block.Index, block.Hash, "previous hash %s, block %d is %s", block.PrevHash, prev.Index, prev.Hash)
+	}
+	if block.Pruned {
+		return
+	}
+	for i := range block.Transactions {
+		tx := &block.Transactions[i]
+		if calculated := tx.calculateHash(); calculated != tx.Hash {
+			r.add(IssueTransactionHash, block.Index, tx.Hash, "transaction %d hashes to %s", i, calculated)
+		}
+	}
+	if !block.ValidateTransactions() {
+		r.add(IssueMerkleRoot, block.Index, block.Hash, "transactions hash to %s, not %s", block.MerkleTree.GetMerkleRoot(), block.MerkleRoot)
+	}
+}
+
+// checkBlockTransactions cross-checks the transactions of a block with the transactions table.
+// afterPruned tells that earlier blocks are pruned.
+func (r *IntegrityReport) checkBlockTransactions(block *Block, stored map[string]*storedTransaction, afterPruned bool) {
+	for i := range block.Transactions {
+		tx := &block.Transactions[i]
+		row, exists := stored[tx.Hash]
+		if !exists {
+			// Identical coinbase rewards share a row, deleted with the first block holding it when pruned
+			if tx.IsCoinbase() && afterPruned {
+				continue
+			}
+			r.add(IssueMissingTransaction, block.Index, tx.Hash, "transaction %d has no transactions row", i)
+			continue
+		}
+		if row.blockHash != block.Hash {
+			// Identical coinbase rewards share a hash, only the first is stored
+			if tx.IsCoinbase() && row.blockIndex < block.Index {
+				continue
+			}
+			r.add(IssueTransactionMismatch, block.Index, tx.Hash, "row belongs to block %d (%s)", row.blockIndex, row.blockHash)
+			continue
+		}
+		row.seen = true
+		if row.blockIndex != block.Index || row.txIndex != i || row.from != tx.From || row.to != tx.To ||
+			row.amount != tx.Amount || row.fee != tx.Fee {
+			r.add(IssueTransactionMismatch, block.Index, tx.Hash,
+				"row has block %d position %d %s -> %s amount %v fee %v, block_data has position %d %s -> %s amount %v fee %v",
+				row.blockIndex, row.txIndex, row.from, row.to, row.amount, row.fee, i, tx.From, tx.To, tx.Amount, tx.Fee)
+		}
+	}
+}
+
+// checkBlockchainState compares the blockchain_state row with the stored blocks
+func (r *IntegrityReport) checkBlockchainState(tx *sql.Tx, lastIndex int64, lastHash string, txCount int64) error {
+	var latestHash string
+	var latestIndex, totalBlocks, totalTransactions int64
+	err := tx.QueryRow(`
+		SELECT latest_block_hash, latest_block_index, total_blocks, total_transactions
+		FROM blockchain_state WHERE id = 1`).Scan(&latestHash, &latestIndex, &totalBlocks, &totalTransactions)
+	if err == sql.ErrNoRows {
+		if r.Blocks > 0 {
+			r.add(IssueBlockchainState, -1, "", "no blockchain_state row for %d stored blocks", r.Blocks)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if latestIndex != lastIndex || latestHash != lastHash {
+		r.add(IssueBlockchainState, -1, latestHash, "latest block is %d (%s), blocks end at %d (%s)", latestIndex, latestHash, lastIndex, lastHash)
+	}
+	if totalBlocks != int64(r.Blocks) {
+		r.add(IssueBlockchainState, -1, "", "total_blocks is %d, %d blocks are stored", totalBlocks, r.Blocks)
+	}
+	if totalTransactions != txCount {
+		r.add(IssueBlockchainState, -1, "", "total_transactions is %d, the blocks hold %d", totalTransactions, txCount)
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// WIFVersion is the leading byte of exported private keys, as in Bitcoin's mainnet WIF
+const WIFVersion byte = 0x80
+
+// base58Alphabet is the Bitcoin base58 alphabet, without 0, O, I and l
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var (
+	oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidSecp256k1      = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+)
+
+// ExportWIF encodes the private key in Wallet Import Format style: base58 of the version byte,
+// the 32-byte key, the scheme tag (where Bitcoin puts its compression flag) and a 4-byte
+// double SHA-256 checksum. Anyone holding the string controls the funds.
+func (w *Wallet) ExportWIF() (string, error) {
+	privateKey, err := w.privateKeyBytes()
+	if err != nil {
+		return "", err
+	}
+	payload := append([]byte{WIFVersion}, privateKey...)
+	payload = append(payload, byte(w.Scheme()))
+	return base58Encode(append(payload, base58Checksum(payload)...)), nil
+}
+
+// WalletFromWIF restores a wallet from a key exported by ExportWIF
+func WalletFromWIF(wif string) (*Wallet, error) {
+	data, err := base58Decode(strings.TrimSpace(wif))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != 1+32+1+4 {
+		return nil, errors.New("malformed WIF key length")
+	}
+	payload, checksum := data[:len(data)-4], data[len(data)-4:]
+	if !bytes.Equal(checksum, base58Checksum(payload)) {
+		return nil, errors.New("WIF key checksum mismatch")
+	}
+	if payload[0] != WIFVersion {
+		return nil, fmt.Errorf("unsupported WIF version 0x%02x", payload[0])
+	}
+	return walletFromPrivateKey(SignatureScheme(payload[33]), payload[1:33])
+}
+
+// ExportPrivateKeyDER encodes the private key as PKCS #8 DER, readable by OpenSSL and crypto/x509
+func (w *Wallet) ExportPrivateKeyDER() ([]byte, error) {
+	privateKey, err := w.privateKeyBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	switch w.Scheme() {
+	case SchemeP256:
+		return x509.MarshalPKCS8PrivateKey(w.PrivateKey)
+	case SchemeEd25519:
+		return x509.MarshalPKCS8PrivateKey(ed25519.NewKeyFromSeed(privateKey))
+	case SchemeSecp256k1:
+		// crypto/x509 doesn't know the curve, so the structures are built by hand (RFC 5208, RFC 5915)
+		public, err := secp256k1Decompress(w.keySigner().PublicKey())
+		if err != nil {
+			return nil, err
+		}
+		inner, err := asn1.Marshal(ecPrivateKey{
+			Version:    1,
+			PrivateKey: privateKey,
+			PublicKey:  asn1.BitString{Bytes: secp256k1Uncompressed(public), BitLength: 65 * 8},
+		})
+		if err != nil {
+			return nil, err
+		}
+		curve, _ := asn1.Marshal(oidSecp256k1)
+		return asn1.Marshal(pkcs8PrivateKey{
+			Algorithm:  pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: curve}},
+			PrivateKey: inner,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported signature scheme %s", w.Scheme())
+	}
+}
+
+// ExportPrivateKeyPEM encodes the private key as a PKCS #8 "PRIVATE KEY" PEM block
+func (w *Wallet) ExportPrivateKeyPEM() ([]byte, error) {
+	der, err := w.ExportPrivateKeyDER()
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// ExportPublicKeyPEM encodes the public key as a PKIX "PUBLIC KEY" PEM block,
+// e.g. to check signatures with standard tooling
+func (w *Wallet) ExportPublicKeyPEM() ([]byte, error) {
+	var der []byte
+	var err error
+	switch w.Scheme() {
+	case SchemeP256:
+		der, err = x509.MarshalPKIXPublicKey(w.PublicKey)
+	case SchemeEd25519:
+		der, err = x509.MarshalPKIXPublicKey(ed25519.PublicKey(w.keySigner().PublicKey()))
+	case SchemeSecp256k1:
+		var public secp256k1Point
+		if public, err = secp256k1Decompress(w.keySigner().PublicKey()); err != nil {
+			return nil, err
+		}
+		curve, _ := asn1.Marshal(oidSecp256k1)
+		der, err = asn1.Marshal(subjectPublicKeyInfo{
+			Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: curve}},
+			PublicKey: asn1.BitString{Bytes: secp256k1Uncompressed(public), BitLength: 65 * 8},
+		})
+	default:
+		err = fmt.Errorf("unsupported signature scheme %s", w.Scheme())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// WalletFromPEM restores a wallet from a "PRIVATE KEY" (PKCS #8) or "EC PRIVATE KEY" (SEC 1) PEM block.
+// Encrypted PEM keys must be decrypted with the tool that wrote them first.
+func WalletFromPEM(data []byte) (*Wallet, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	switch block.Type {
+	case "PRIVATE KEY", "EC PRIVATE KEY":
+		return WalletFromDER(block.Bytes)
+	case "ENCRYPTED PRIVATE KEY":
+		return nil, errors.New("encrypted PEM keys are not supported")
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+// WalletFromDER restores a wallet from a PKCS #8 or SEC 1 DER private key
+// of a supported scheme (P-256, Ed25519 or secp256k1)
+func WalletFromDER(der []byte) (*Wallet, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		switch key := key.(type) {
+		case *ecdsa.PrivateKey:
+			if key.Curve != elliptic.P256() {
+				return nil, fmt.Errorf("unsupported curve %s", key.Curve.Params().Name)
+			}
+			return walletFromPrivateKey(SchemeP256, key.D.FillBytes(make([]byte, 32)))
+		case ed25519.PrivateKey:
+			return walletFromPrivateKey(SchemeEd25519, key.Seed())
+		default:
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		if key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("unsupported curve %s", key.Curve.Params().Name)
+		}
+		return walletFromPrivateKey(SchemeP256, key.D.FillBytes(make([]byte, 32)))
+	}
+
+	// secp256k1 keys, which crypto/x509 rejects
+	var wrapped pkcs8PrivateKey
+	if rest, err := asn1.Unmarshal(der, &wrapped); err == nil && len(rest) == 0 && wrapped.Algorithm.Algorithm.Equal(oidPublicKeyECDSA) {
+		var curve asn1.ObjectIdentifier
+		if _, err := asn1.Unmarshal(wrapped.Algorithm.Parameters.FullBytes, &curve); err != nil || !curve.Equal(oidSecp256k1) {
+			return nil, errors.New("unsupported elliptic curve")
+		}
+		der = wrapped.PrivateKey
+	}
+	var key ecPrivateKey
+	if rest, err := asn1.Unmarshal(der, &key); err != nil || len(rest) != 0 {
+		return nil, errors.New("unrecognized private key encoding")
+	}
+	if len(key.NamedCurveOID) > 0 && !key.NamedCurveOID.Equal(oidSecp256k1) {
+		return nil, errors.New("unsupported elliptic curve")
+	}
+	privateKey := make([]byte, 32)
+	if len(key.PrivateKey) > len(privateKey) {
+		return nil, errors.New("invalid secp256k1 private key")
+	}
+	copy(privateKey[32-len(key.PrivateKey):], key.PrivateKey)
+	return walletFromPrivateKey(SchemeSecp256k1, privateKey)
+}
+
+// PaperWallet is everything needed to print a wallet for cold storage
+type PaperWallet struct {
+	Address    string `json:"address"`
+	Scheme     string `json:"scheme"`
+	PublicKey  string `json:"publicKey"`
+	PrivateKey string `json:"privateKey"` // WIF
+}
+
+// PaperWallet exports the wallet for printing
+func (w *Wallet) PaperWallet() (*PaperWallet, error) {
+	wif, err := w.ExportWIF()
+	if err != nil {
+		return nil, err
+	}
+	return &PaperWallet{Address: w.Address, Scheme: w.Scheme().String(), PublicKey: w.EncodedPublicKey(), PrivateKey: wif}, nil
+}
+
+// String formats the paper wallet for printing
+func (pw *PaperWallet) String() string {
+	return fmt.Sprintf("Address:     %s\nScheme:      %s\nPublic key:  %s\nPrivate key: %s\n",
+		pw.Address, pw.Scheme, pw.PublicKey, pw.PrivateKey)
+}
+
+// pkcs8PrivateKey is a PKCS #8 PrivateKeyInfo (RFC 5208)
+type pkcs8PrivateKey struct {
+	Version    int
+	Algorithm  pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+// ecPrivateKey is a SEC 1 ECPrivateKey (RFC 5915)
+type ecPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+// subjectPublicKeyInfo is a PKIX public key (RFC 5280)
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// secp256k1Uncompressed encodes a point as 0x04 || X || Y
+func secp256k1Uncompressed(pt secp256k1Point) []byte {
+	encoded := make([]byte, 65)
+	encoded[0] = 4
+	pt.X.FillBytes(encoded[1:33])
+	pt.Y.FillBytes(encoded[33:])
+	return encoded
+}
+
+// base58Checksum returns the first 4 bytes of the double SHA-256 of a payload
+func base58Checksum(payload []byte) []byte {
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	return second[:4]
+}
+
+// base58Encode encodes bytes in base58, keeping leading zero bytes as '1'
+func base58Encode(data []byte) string {
+	n := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+
+	var encoded []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		encoded = append(encoded, base58Alphabet[mod.Int64()])
+	}
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		encoded = append(encoded, base58Alphabet[0])
+	}
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+	return string(encoded)
+}
+
+// base58Decode decodes a base58 string
+func base58Decode(s string) ([]byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	zeros := 0
+	for i, c := range s {
+		digit := strings.IndexRune(base58Alphabet, c)
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", c)
+		}
+		if digit == 0 && zeros == i {
+			zeros++
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(digit)))
+	}
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
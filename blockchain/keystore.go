@@ -0,0 +1,259 @@
+package blockchain
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// KeystoreVersion is the version of the keystore file format
+const KeystoreVersion = 1
+
+// Default scrypt cost parameters of new keystore files
+const (
+	keystoreScryptN = 1 << 18
+	keystoreScryptR = 8
+	keystoreScryptP = 1
+	keystoreKeyLen  = 32
+)
+
+// ErrWrongPassphrase is returned when a keystore cannot be decrypted with the given passphrase
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted keystore")
+
+// keystoreFile is the JSON layout of an encrypted wallet file
+type keystoreFile struct {
+	Version int            `json:"version"`
+	Address string         `json:"address"`
+	Scheme  string         `json:"scheme,omitempty"` // Signature scheme name, empty for P-256
+	Crypto  keystoreCrypto `json:"crypto"`
+}
+
+// keystoreCrypto holds the encrypted private key and how to derive its decryption key
+type keystoreCrypto struct {
+	Cipher     string       `json:"cipher"`
+	CipherText string       `json:"ciphertext"`
+	Nonce      string       `json:"nonce"`
+	KDF        string       `json:"kdf"`
+	KDFParams  scryptParams `json:"kdfparams"`
+}
+
+// scryptParams are the scrypt parameters used to derive the encryption key from the passphrase
+type scryptParams struct {
+	N      int    `json:"n"`
+	R      int    `json:"r"`
+	P      int    `json:"p"`
+	KeyLen int    `json:"dklen"`
+	Salt   string `json:"salt"`
+}
+
+// SaveToFile writes the wallet to a keystore file, its private key encrypted with
+// AES-256-GCM under a key derived from the passphrase with scrypt
+func (w *Wallet) SaveToFile(path, passphrase string) error {
+	keystore, err := encryptWallet(w, passphrase)
+	if err != nil {
+		return err
+	}
+	return writeKeystore(path, keystore)
+}
+
+// LoadWalletFromFile reads a keystore file and decrypts its wallet with the passphrase
+func LoadWalletFromFile(path, passphrase string) (*Wallet, error) {
+	keystore, err := readKeystore(path)
+	if err != nil {
+		return nil, err
+	}
+	return decryptWallet(keystore, passphrase)
+}
+
+// ChangeKeystorePassphrase re-encrypts a keystore file under a new passphrase
+func ChangeKeystorePassphrase(path, oldPassphrase, newPassphrase string) error {
+	wallet, err := LoadWalletFromFile(path, oldPassphrase)
+	if err != nil {
+		return err
+	}
+	return wallet.SaveToFile(path, newPassphrase)
+}
+
+// encryptWallet encrypts the private key of a wallet with a fresh salt and nonce
+func encryptWallet(w *Wallet, passphrase string) (*keystoreFile, error) {
+	plaintext, err := w.privateKeyBytes()
+	if err != nil {
+		return nil, err
+	}
+	var scheme string
+	if w.Scheme() != SchemeP256 {
+		scheme = w.Scheme().String()
+	}
+
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	params := scryptParams{
+		N:      keystoreScryptN,
+		R:      keystoreScryptR,
+		P:      keystoreScryptP,
+		KeyLen: keystoreKeyLen,
+		Salt:   hex.EncodeToString(salt),
+	}
+
+	aead, err := keystoreCipher(passphrase, params)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	// The address is authenticated so a key cannot be swapped under another address
+	ciphertext := aead.Seal(nil, nonce, plaintext, []byte(w.Address))
+
+	return &keystoreFile{
+		Version: KeystoreVersion,
+		Address: w.Address,
+		Scheme:  scheme,
+		Crypto: keystoreCrypto{
+			Cipher:     "aes-256-gcm",
+			CipherText: hex.EncodeToString(ciphertext),
+			Nonce:      hex.EncodeToString(nonce),
+			KDF:        "scrypt",
+			KDFParams:  params,
+		},
+	}, nil
+}
+
+// decryptWallet recovers the wallet of a keystore and checks it matches the stored address
+func decryptWallet(keystore *keystoreFile, passphrase string) (*Wallet, error) {
+	if keystore.Version != KeystoreVersion {
+		return nil, fmt.Errorf("unsupported keystore version %d", keystore.Version)
+	}
+	if keystore.Crypto.Cipher != "aes-256-gcm" || keystore.Crypto.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported keystore cipher %s with kdf %s", keystore.Crypto.Cipher, keystore.Crypto.KDF)
+	}
+
+	nonce, err := hex.DecodeString(keystore.Crypto.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("malformed keystore nonce: %w", err)
+	}
+	ciphertext, err := hex.DecodeString(keystore.Crypto.CipherText)
+	if err != nil {
+		return nil, fmt.Errorf("malformed keystore ciphertext: %w", err)
+	}
+
+	aead, err := keystoreCipher(passphrase, keystore.Crypto.KDFParams)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("malformed keystore nonce")
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(keystore.Address))
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	scheme := SchemeP256
+	if keystore.Scheme != "" {
+		if scheme, err = ParseSignatureScheme(keystore.Scheme); err != nil {
+			return nil, err
+		}
+	}
+	wallet, err := walletFromPrivateKey(scheme, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	if wallet.Address != keystore.Address {
+		// Wallets bound to another address format keep their address, which the cipher authenticates
+		if _, err := (HexAddressFormat{}).Scheme(keystore.Address); err == nil {
+			return nil, errors.New("keystore key does not match its address")
+		}
+		wallet.Address = keystore.Address
+	}
+	return wallet, nil
+}
+
+// privateKeyBytes returns the raw private key of the wallet's scheme
+func (w *Wallet) privateKeyBytes() ([]byte, error) {
+	switch holder := w.keySigner().(type) {
+	case p256Signer:
+		if w.PrivateKey == nil {
+			return nil, ErrNoPrivateKey
+		}
+		return holder.privateKeyBytes(), nil
+	case keyHolder:
+		return holder.privateKeyBytes(), nil
+	default:
+		return nil, errors.New("wallet signer does not expose its private key")
+	}
+}
+
+// keystoreCipher derives the AES-GCM cipher of a passphrase
+func keystoreCipher(passphrase string, params scryptParams) (cipher.AEAD, error) {
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("malformed keystore salt: %w", err)
+	}
+	if params.KeyLen != keystoreKeyLen {
+		return nil, fmt.Errorf("unsupported keystore key length %d", params.KeyLen)
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), salt, params.N, params.R, params.P, params.KeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore kdf parameters: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// readKeystore parses a keystore file
+func readKeystore(path string) (*keystoreFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keystore keystoreFile
+	if err := json.Unmarshal(data, &keystore); err != nil {
+		return nil, fmt.Errorf("malformed keystore: %w", err)
+	}
+	return &keystore, nil
+}
+
+// writeKeystore writes a keystore file readable only by its owner. The file is replaced
+// atomically so a crash during a passphrase change never loses the key.
+func writeKeystore(path string, keystore *keystoreFile) error {
+	data, err := json.MarshalIndent(keystore, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".keystore-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
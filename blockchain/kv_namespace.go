@@ -0,0 +1,199 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"blockchain/blockchain/verify"
+)
+
+// KVNamespaceAddress is the recipient of transactions setting key-value entries.
+// Each sender writes to its own namespace, so applications can't overwrite each other's keys.
+const KVNamespaceAddress = "kv"
+
+// ErrKeyNotFound is returned for keys no confirmed transaction set
+var ErrKeyNotFound = errors.New("key not found")
+
+// Key-value entry size limits, keeping the commitment area small
+const (
+	maxKVKeyLength   = 64
+	maxKVValueLength = 256
+)
+
+// KVEntry is a key-value pair set by a transaction
+type KVEntry struct {
+	Namespace string `json:"namespace"` // Sender address
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+}
+
+// kvPayload is the transaction payload of a key-value entry
+type kvPayload struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// KVProof proves that a block committed a key-value entry
+type KVProof struct {
+	BlockIndex int64        `json:"blockIndex"`
+	BlockHash  string       `json:"blockHash"`
+	Entry      KVEntry      `json:"entry"`
+	Proof      *MerkleProof `json:"proof"`
+}
+
+// NewKVTransaction creates a transaction setting a key in the sender's namespace
+func NewKVTransaction(from, key, value string, fee float64, nonce int64) (*Transaction, error) {
+	data, err := json.Marshal(kvPayload{Key: key, Value: value})
+	if err != nil {
+		return nil, err
+	}
+
+	tx := &Transaction{
+		From:  from,
+		To:    KVNamespaceAddress,
+		Fee:   fee,
+		Nonce: nonce,
+		Data:  data,
+	}
+	if err := validateKVTransaction(tx); err != nil {
+		return nil, err
+	}
+	tx.Hash = tx.calculateHash()
+	return tx, nil
+}
+
+// parseKVEntry decodes the key-value entry carried by a transaction
+func parseKVEntry(tx *Transaction) (KVEntry, error) {
+	var payload kvPayload
+	if err := json.Unmarshal(tx.Data, &payload); err != nil {
+		return KVEntry{}, fmt.Errorf("invalid key-value payload: %w", err)
+	}
+	return KVEntry{Namespace: tx.From, Key: payload.Key, Value: payload.Value}, nil
+}
+
+// validateKVTransaction checks a transaction setting a key-value entry
+func validateKVTransaction(tx *Transaction) error {
+	if tx.Amount != 0 {
+		return fmt.Errorf("%w: key-value entries cannot transfer an amount", ErrInvalidTransaction)
+	}
+
+	entry, err := parseKVEntry(tx)
+	if err != nil {
+		return err
+	}
+	if entry.Key == "" {
+		return fmt.Errorf("%w: key-value entry has no key", ErrInvalidTransaction)
+	}
+	if len(entry.Key) > maxKVKeyLength {
+		return fmt.Errorf("invalid transaction: key longer than %d bytes", maxKVKeyLength)
+	}
+	if len(entry.Value) > maxKVValueLength {
+		return fmt.Errorf("invalid transaction: value longer than %d bytes", maxKVValueLength)
+	}
+	return nil
+}
+
+// blockKVEntries returns the entries set by a list of transactions, ordered by namespace and key.
+// When a key is set more than once, the last transaction wins.
+func blockKVEntries(transactions []Transaction) ([]KVEntry, error) {
+	latest := make(map[[2]string]KVEntry)
+	for i := range transactions {
+		tx := &transactions[i]
+		if tx.To != KVNamespaceAddress {
+			continue
+		}
+		if err := validateKVTransaction(tx); err != nil {
+			return nil, err
+		}
+		entry, _ := parseKVEntry(tx)
+		latest[[2]string{entry.Namespace, entry.Key}] = entry
+	}
+
+	entries := make([]KVEntry, 0, len(latest))
+	for _, entry := range latest {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Namespace != entries[j].Namespace {
+			return entries[i].Namespace < entries[j].Namespace
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	return entries, nil
+}
+
+// leafHash returns the Merkle leaf committing an entry
+func (e KVEntry) leafHash() string {
+	return verify.KVLeafHash(e.Namespace, e.Key, e.Value)
+}
+
+// kvTree builds the Merkle tree over the entries set by a list of transactions
+func kvTree(transactions []Transaction) (*MerkleTree, []KVEntry, error) {
+	entries, err := blockKVEntries(transactions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make([]string, len(entries))
+	for i, entry := range entries {
+		hashes[i] = entry.leafHash()
+	}
+	return newMerkleTreeFromHashes(hashes, LegacyMerkleScheme), entries, nil
+}
+
+// calculateKVRoot returns the root committing the entries set by a list of transactions ("" if none)
+func calculateKVRoot(transactions []Transaction) (string, error) {
+	tree, _, err := kvTree(transactions)
+	if err != nil {
+		return "", err
+	}
+	return tree.GetMerkleRoot(), nil
+}
+
+// validateKVRoot checks the block commits exactly the entries its transactions set
+func (b *Block) validateKVRoot() error {
+	root, err := calculateKVRoot(b.Transactions)
+	if err != nil {
+		return err
+	}
+	if root != b.KVRoot {
+		return errors.New("invalid key-value root")
+	}
+	return nil
+}
+
+// KVEntries returns the key-value entries committed by the block
+func (b *Block) KVEntries() []KVEntry {
+	entries, _ := blockKVEntries(b.Transactions)
+	return entries
+}
+
+// GenerateKVProof proves the value the block committed for a key of a namespace
+func (b *Block) GenerateKVProof(namespace, key string) (*KVProof, error) {
+	tree, entries, err := kvTree(b.Transactions)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.Namespace != namespace || entry.Key != key {
+			continue
+		}
+		proof, err := tree.GenerateProof(entry.leafHash())
+		if err != nil {
+			return nil, err
+		}
+		return &KVProof{BlockIndex: b.Index, BlockHash: b.Hash, Entry: entry, Proof: proof}, nil
+	}
+	return nil, errors.New("key not set in block")
+}
+
+// VerifyKVProof checks a key-value proof against the KV root of a block header
+func VerifyKVProof(proof *KVProof, kvRoot string) bool {
+	if proof == nil || proof.Proof == nil || kvRoot == "" {
+		return false
+	}
+	return proof.Proof.Hash == proof.Entry.leafHash() && VerifyProof(proof.Proof, kvRoot, LegacyMerkleScheme)
+}
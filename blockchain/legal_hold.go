@@ -0,0 +1,172 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Kinds of data a legal hold can be placed on
+const (
+	HoldBlock       = "block"
+	HoldTransaction = "transaction"
+)
+
+var (
+	// ErrLegalHold is returned when pruning would remove data under a legal hold
+	ErrLegalHold = errors.New("data is under legal hold")
+
+	// ErrHoldNotFound is returned when releasing a hold that isn't placed
+	ErrHoldNotFound = errors.New("legal hold not found")
+)
+
+// LegalHold tags a block or transaction that pruning and retention must keep, e.g. while it is
+// subject to e-discovery. A held transaction keeps the body of the block containing it.
+type LegalHold struct {
+	Kind       string `json:"kind"`             // HoldBlock or HoldTransaction
+	ID         string `json:"id"`               // Block or transaction hash
+	BlockIndex int64  `json:"blockIndex"`       // Height of the block kept for the hold
+	Reason     string `json:"reason,omitempty"` // e.g. the matter or case reference
+	PlacedAt   int64  `json:"placedAt"`
+}
+
+// heldHeights returns the heights of the blocks a set of holds keeps
+func heldHeights(holds []LegalHold) map[int64]bool {
+	heights := make(map[int64]bool, len(holds))
+	for _, hold := range holds {
+		heights[hold.BlockIndex] = true
+	}
+	return heights
+}
+
+// SaveLegalHold stores a hold, replacing the reason of an existing hold on the same data
+func (d *Database) SaveLegalHold(hold LegalHold) error {
+	_, err := d.db.Exec(d.rebind(`
+		INSERT INTO legal_holds (kind, id, block_index, reason, placed_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(kind, id) DO UPDATE SET reason = excluded.reason`),
+		hold.Kind, hold.ID, hold.BlockIndex, hold.Reason, hold.PlacedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save legal hold: %w", err)
+	}
+	return nil
+}
+
+// DeleteLegalHold removes a hold, returning ErrHoldNotFound if it isn't stored
+func (d *Database) DeleteLegalHold(kind, id string) error {
+	result, err := d.db.Exec(d.rebind("DELETE FROM legal_holds WHERE kind = ? AND id = ?"), kind, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete legal hold: %w", err)
+	}
+	if removed, _ := result.RowsAffected(); removed == 0 {
+		return ErrHoldNotFound
+	}
+	return nil
+}
+
+// LoadLegalHolds returns the stored holds, oldest first
+func (d *Database) LoadLegalHolds() ([]LegalHold, error) {
+	rows, err := d.db.Query("SELECT kind, id, block_index, reason, placed_at FROM legal_holds ORDER BY placed_at, kind, id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load legal holds: %w", err)
+	}
+	defer rows.Close()
+
+	var holds []LegalHold
+	for rows.Next() {
+		var hold LegalHold
+		if err := rows.Scan(&hold.Kind, &hold.ID, &hold.BlockIndex, &hold.Reason, &hold.PlacedAt); err != nil {
+			return nil, err
+		}
+		holds = append(holds, hold)
+	}
+	return holds, rows.Err()
+}
+
+// PlaceLegalHold places a hold on a block or a transaction, identified by hash, so pruning never
+// removes it. Placing a hold again updates its reason. Data that is already pruned can't be held.
+func (pbc *PersistentBlockchain) PlaceLegalHold(kind, id, reason string) (*LegalHold, error) {
+	hold := &LegalHold{Kind: kind, ID: id, Reason: reason, PlacedAt: time.Now().Unix()}
+	switch kind {
+	case HoldBlock:
+		block, err := pbc.findBlock(id)
+		if err != nil {
+			return nil, err
+		}
+		if block.Pruned {
+			return nil, fmt.Errorf("block %d: %w", block.Index, ErrPruned)
+		}
+		hold.BlockIndex = block.Index
+	case HoldTransaction:
+		_, location, err := pbc.GetTransaction(id)
+		if err != nil {
+			return nil, err
+		}
+		hold.BlockIndex = location.BlockIndex
+	default:
+		return nil, fmt.Errorf("unknown legal hold kind %q", kind)
+	}
+
+	if err := pbc.Database.SaveLegalHold(*hold); err != nil {
+		return nil, err
+	}
+	log.Printf("Placed legal hold on %s %s (block %d)", kind, id, hold.BlockIndex)
+	return hold, nil
+}
+
+// ReleaseLegalHold releases a hold. A block below the pruned height that no other hold or
+// system transaction keeps is pruned right away, as pruning would have done without the hold.
+func (pbc *PersistentBlockchain) ReleaseLegalHold(kind, id string) error {
+	holds, err := pbc.Database.LoadLegalHolds()
+	if err != nil {
+		return err
+	}
+	var released *LegalHold
+	for i := range holds {
+		if holds[i].Kind == kind && holds[i].ID == id {
+			released = &holds[i]
+		}
+	}
+	if released == nil {
+		return ErrHoldNotFound
+	}
+
+	if err := pbc.Database.DeleteLegalHold(kind, id); err != nil {
+		return err
+	}
+	log.Printf("Released legal hold on %s %s", kind, id)
+
+	for _, hold := range holds {
+		if hold.BlockIndex == released.BlockIndex && hold != *released {
+			return nil
+		}
+	}
+	height := released.BlockIndex
+	state := pbc.prunedState()
+	if pbc.pruneKeep == 0 || height >= state.Height || height >= int64(len(pbc.Chain)) {
+		return nil
+	}
+	block := pbc.Chain[height]
+	if block.Pruned || keepsBody(block) {
+		return nil
+	}
+	next := state.clone()
+	next.addBlock(block)
+	return pbc.pruneHeights([]int64{height}, next)
+}
+
+// LegalHolds returns the holds placed on the chain's data, oldest first
+func (pbc *PersistentBlockchain) LegalHolds() ([]LegalHold, error) {
+	return pbc.Database.LoadLegalHolds()
+}
+
+// findBlock returns the block of the chain with a hash
+func (pbc *PersistentBlockchain) findBlock(hash string) (*Block, error) {
+	for _, block := range pbc.Chain {
+		if block.Hash == hash {
+			return block, nil
+		}
+	}
+	return nil, ErrBlockNotFound
+}
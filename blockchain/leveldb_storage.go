@@ -0,0 +1,788 @@
+package blockchain
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Key layout of the LevelDB storage
+var (
+	levelBlockPrefix   = []byte("b/") // hash -> block JSON
+	levelHeightPrefix  = []byte("h/") // 8-byte big-endian height -> hash
+	levelBalancePrefix = []byte("a/") // address -> float64 bits
+	levelStateKey      = []byte("m/state")
+	levelVersionKey    = []byte("m/version") // derived state version
+	levelReindexKey    = []byte("m/reindex") // next height of an interrupted reindex
+	levelPruneKey      = []byte("m/prune")   // prune state JSON
+	levelHoldPrefix    = []byte("l/")        // kind/id -> legal hold JSON
+	levelSnapPrefix    = []byte("s/")        // 8-byte big-endian height -> state snapshot JSON
+	levelPoolPrefix    = []byte("p/")        // hash -> saved mempool entry JSON
+)
+
+// levelState is the chain summary kept by the LevelDB storage, like the SQL blockchain_state table
+type levelState struct {
+	LatestHash        string `json:"latestHash"`
+	LatestIndex       int64  `json:"latestIndex"`
+	TotalBlocks       int64  `json:"totalBlocks"`
+	TotalTransactions int64  `json:"totalTransactions"`
+	TotalAddresses    int64  `json:"totalAddresses"`
+	LastUpdated       int64  `json:"lastUpdated"`
+}
+
+// LevelDBStorage stores blocks by hash and height in LevelDB, with address balances and chain
+// statistics derived as blocks are saved. Every block is written in one atomic batch, avoiding
+// the per-row overhead of SQL on the mining path. Queries beyond the Storage interface (history,
+// ledger entries, peers) need the SQL database.
+type LevelDBStorage struct {
+	db *leveldb.DB
+	mu sync.Mutex // Serializes the read-modify-write of derived state
+}
+
+// NewLevelDBStorage opens or creates a LevelDB storage in a directory
+func NewLevelDBStorage(path string) (*LevelDBStorage, error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return newLevelDBStorage(db)
+}
+
+// NewMemoryStorage creates an empty LevelDB storage held in memory, the "memory" driver. It is
+// pure Go and touches no disk, for tests and simulations; its data is lost when it is closed.
+// Backup writes a LevelDB directory that the "leveldb" driver can open.
+func NewMemoryStorage() (*LevelDBStorage, error) {
+	db, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return newLevelDBStorage(db)
+}
+
+// newLevelDBStorage wraps an open LevelDB database
+func newLevelDBStorage(db *leveldb.DB) (*LevelDBStorage, error) {
+	storage := &LevelDBStorage{db: db}
+
+	// A new database builds its derived state at the current version as blocks are saved
+	empty, err := storage.isEmpty()
+	if err == nil && empty {
+		err = db.Put(levelVersionKey, []byte(strconv.Itoa(DerivedIndexVersion)), nil)
+	}
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize derived index version: %w", err)
+	}
+	return storage, nil
+}
+
+// Close closes the database
+func (s *LevelDBStorage) Close() error {
+	return s.db.Close()
+}
+
+// isEmpty reports whether no block is stored
+func (s *LevelDBStorage) isEmpty() (bool, error) {
+	iter := s.db.NewIterator(util.BytesPrefix(levelHeightPrefix), nil)
+	defer iter.Release()
+	return !iter.First(), iter.Error()
+}
+
+// heightKey returns the key of a height
+func heightKey(index int64) []byte {
+	key := make([]byte, len(levelHeightPrefix)+8)
+	copy(key, levelHeightPrefix)
+	binary.BigEndian.PutUint64(key[len(levelHeightPrefix):], uint64(index))
+	return key
+}
+
+// prefixedKey appends a string to a key prefix
+func prefixedKey(prefix []byte, value string) []byte {
+	return append(append([]byte{}, prefix...), value...)
+}
+
+// SaveBlock stores a block and updates the balances and statistics derived from it.
+// Like the SQL storage, a height or hash is only stored once.
+func (s *LevelDBStorage) SaveBlock(block *Block) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range [][]byte{heightKey(block.Index), prefixedKey(levelBlockPrefix, block.Hash)} {
+		exists, err := s.db.Has(key, nil)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("failed to insert block: block %d (%s) is already stored", block.Index, block.Hash)
+		}
+	}
+
+	blockData, err := json.Marshal(block)
+	if err != nil {
+		return fmt.Errorf("failed to serialize block: %w", err)
+	}
+
+	batch := new(leveldb.Batch)
+	batch.Put(prefixedKey(levelBlockPrefix, block.Hash), blockData)
+	batch.Put(heightKey(block.Index), []byte(block.Hash))
+	if err := s.applyBlock(batch, []*Block{block}); err != nil {
+		return err
+	}
+	return s.db.Write(batch, nil)
+}
+
+// applyBlock adds the balance and statistics updates of blocks to a batch
+func (s *LevelDBStorage) applyBlock(batch *leveldb.Batch, blocks []*Block) error {
+	state, err := s.state()
+	if err != nil {
+		return err
+	}
+
+	balances := make(map[string]float64)
+	change := func(address string, amount float64) error {
+		balance, seen := balances[address]
+		if !seen {
+			data, err := s.db.Get(prefixedKey(levelBalancePrefix, address), nil)
+			switch err {
+			case nil:
+				balance = math.Float64frombits(binary.BigEndian.Uint64(data))
+			case leveldb.ErrNotFound:
+				state.TotalAddresses++
+			default:
+				return err
+			}
+		}
+		balances[address] = balance + amount
+		return nil
+	}
+
+	for _, block := range blocks {
+		for _, tx := range block.Transactions {
+			if err := change(tx.From, -tx.debit(tx.From)); err != nil {
+				return err
+			}
+			if err := change(tx.To, tx.Amount); err != nil {
+				return err
+			}
+			if tx.Sponsor != "" {
+				if err := change(tx.Sponsor, -tx.Fee); err != nil {
+					return err
+				}
+			}
+		}
+		state.LatestHash = block.Hash
+		state.LatestIndex = block.Index
+		state.TotalBlocks++
+		state.TotalTransactions += int64(len(block.Transactions))
+	}
+	state.LastUpdated = time.Now().Unix()
+
+	for address, balance := range balances {
+		data := make([]byte, 8)
+		binary.BigEndian.PutUint64(data, math.Float64bits(balance))
+		batch.Put(prefixedKey(levelBalancePrefix, address), data)
+	}
+	stateData, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	batch.Put(levelStateKey, stateData)
+	return nil
+}
+
+// state returns the stored chain summary
+func (s *LevelDBStorage) state() (*levelState, error) {
+	var state levelState
+	data, err := s.db.Get(levelStateKey, nil)
+	if err == leveldb.ErrNotFound {
+		return &state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to deserialize chain state: %w", err)
+	}
+	return &state, nil
+}
+
+// GetBlock retrieves a block by hash
+func (s *LevelDBStorage) GetBlock(hash string) (*Block, error) {
+	return unpruned(s.loadBlock(hash))
+}
+
+// loadBlock retrieves a stored block by hash, pruned or not
+func (s *LevelDBStorage) loadBlock(hash string) (*Block, error) {
+	data, err := s.db.Get(prefixedKey(levelBlockPrefix, hash), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, ErrBlockNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeBlock(data)
+}
+
+// GetBlockByIndex retrieves a block by index
+func (s *LevelDBStorage) GetBlockByIndex(index int64) (*Block, error) {
+	hash, err := s.db.Get(heightKey(index), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, ErrBlockNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.GetBlock(string(hash))
+}
+
+// GetLatestBlock retrieves the block at the greatest height
+func (s *LevelDBStorage) GetLatestBlock() (*Block, error) {
+	iter := s.db.NewIterator(util.BytesPrefix(levelHeightPrefix), nil)
+	defer iter.Release()
+	if !iter.Last() {
+		if err := iter.Error(); err != nil {
+			return nil, err
+		}
+		return nil, ErrBlockNotFound
+	}
+	return s.loadBlock(string(iter.Value()))
+}
+
+// GetAddressBalance retrieves the balance for an address
+func (s *LevelDBStorage) GetAddressBalance(address string) (float64, error) {
+	data, err := s.db.Get(prefixedKey(levelBalancePrefix, address), nil)
+	if err == leveldb.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.BigEndian.Uint64(data)), nil
+}
+
+// GetBlockchainStats retrieves blockchain statistics, with the keys of the SQL storage
+func (s *LevelDBStorage) GetBlockchainStats() (map[string]interface{}, error) {
+	state, err := s.state()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"latest_block_hash":  state.LatestHash,
+		"latest_block_index": state.LatestIndex,
+		"total_blocks":       state.TotalBlocks,
+		"total_transactions": state.TotalTransactions,
+		"difficulty":         4,
+		"mining_reward":      10.0,
+		"last_updated":       state.LastUpdated,
+		"total_addresses":    state.TotalAddresses,
+	}, nil
+}
+
+// LoadBlockchain loads the entire blockchain in height order
+func (s *LevelDBStorage) LoadBlockchain() ([]*Block, error) {
+	return s.blocksFrom(0, 0)
+}
+
+// IterateBlocks streams the stored blocks to fn in height order, stopping at the first error
+// fn returns
+func (s *LevelDBStorage) IterateBlocks(fn func(*Block) error) error {
+	iter := s.db.NewIterator(util.BytesPrefix(levelHeightPrefix), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		block, err := s.loadBlock(string(iter.Value()))
+		if err != nil {
+			return err
+		}
+		if err := fn(block); err != nil {
+			return err
+		}
+	}
+	return iter.Error()
+}
+
+// blocksFrom loads up to limit stored blocks from a height on (0 for no limit)
+func (s *LevelDBStorage) blocksFrom(height int64, limit int) ([]*Block, error) {
+	iter := s.db.NewIterator(&util.Range{Start: heightKey(height), Limit: util.BytesPrefix(levelHeightPrefix).Limit}, nil)
+	defer iter.Release()
+
+	var blocks []*Block
+	for iter.Next() {
+		block, err := s.loadBlock(string(iter.Value()))
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+		if limit > 0 && len(blocks) == limit {
+			break
+		}
+	}
+	return blocks, iter.Error()
+}
+
+// NeedsReindex reports whether the derived state predates DerivedIndexVersion
+// or a reindex was interrupted
+func (s *LevelDBStorage) NeedsReindex() (bool, error) {
+	version, reindexing, _, err := s.derivedIndexState()
+	if err != nil {
+		return false, err
+	}
+	return version < DerivedIndexVersion || reindexing, nil
+}
+
+// derivedIndexState returns the derived state version and the next height of an interrupted reindex
+func (s *LevelDBStorage) derivedIndexState() (int64, bool, int64, error) {
+	var version, next int64
+	data, err := s.db.Get(levelVersionKey, nil)
+	if err != nil && err != leveldb.ErrNotFound {
+		return 0, false, 0, err
+	}
+	if err == nil {
+		if version, err = strconv.ParseInt(string(data), 10, 64); err != nil {
+			return 0, false, 0, fmt.Errorf("malformed derived index version: %w", err)
+		}
+	}
+
+	data, err = s.db.Get(levelReindexKey, nil)
+	if err == leveldb.ErrNotFound {
+		return version, false, 0, nil
+	}
+	if err != nil {
+		return 0, false, 0, err
+	}
+	if next, err = strconv.ParseInt(string(data), 10, 64); err != nil {
+		return 0, false, 0, fmt.Errorf("malformed reindex progress: %w", err)
+	}
+	return version, true, next, nil
+}
+
+// Reindex rebuilds the balances and statistics from the stored blocks. Progress is written
+// with every batch, so an interrupted reindex resumes where it stopped the next time Reindex
+// runs. Blocks must not be saved while reindexing.
+func (s *LevelDBStorage) Reindex(config ReindexConfig) (*ReindexReport, error) {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 500
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if pruned, err := s.db.Has(levelPruneKey, nil); err != nil {
+		return nil, err
+	} else if pruned {
+		return nil, fmt.Errorf("cannot reindex a pruned database: %w", ErrPruned)
+	}
+
+	_, reindexing, next, err := s.derivedIndexState()
+	if err != nil {
+		return nil, err
+	}
+	report := &ReindexReport{}
+	if reindexing && !config.Restart {
+		report.Resumed = true
+		report.ResumedAt = next
+	} else {
+		if err := s.startReindex(); err != nil {
+			return nil, err
+		}
+		next = 0
+	}
+
+	for {
+		blocks, err := s.blocksFrom(next, config.BatchSize)
+		if err != nil {
+			return report, fmt.Errorf("failed to load blocks: %w", err)
+		}
+		if len(blocks) == 0 {
+			break
+		}
+
+		batch := new(leveldb.Batch)
+		if err := s.applyBlock(batch, blocks); err != nil {
+			return report, err
+		}
+		next = blocks[len(blocks)-1].Index + 1
+		batch.Put(levelReindexKey, []byte(strconv.FormatInt(next, 10)))
+		if err := s.db.Write(batch, nil); err != nil {
+			return report, fmt.Errorf("failed to record reindex progress: %w", err)
+		}
+
+		report.Blocks += len(blocks)
+		for _, block := range blocks {
+			report.Transactions += len(block.Transactions)
+		}
+		if config.Progress != nil {
+			state, err := s.state()
+			if err != nil {
+				return report, err
+			}
+			config.Progress(ReindexProgress{Height: next - 1, Target: max(state.LatestIndex, next-1)})
+		}
+	}
+
+	batch := new(leveldb.Batch)
+	batch.Put(levelVersionKey, []byte(strconv.Itoa(DerivedIndexVersion)))
+	batch.Delete(levelReindexKey)
+	if err := s.db.Write(batch, nil); err != nil {
+		return report, fmt.Errorf("failed to record reindex completion: %w", err)
+	}
+	return report, nil
+}
+
+// startReindex clears the derived state and records a reindex from genesis
+func (s *LevelDBStorage) startReindex() error {
+	batch := new(leveldb.Batch)
+	iter := s.db.NewIterator(util.BytesPrefix(levelBalancePrefix), nil)
+	for iter.Next() {
+		batch.Delete(append([]byte{}, iter.Key()...))
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	batch.Delete(levelStateKey)
+	batch.Put(levelReindexKey, []byte("0"))
+	return s.db.Write(batch, nil)
+}
+
+// PruneBlocks replaces the blocks at the given heights with their headers and saves the prune state, in one batch.
+// Blocks under a legal hold fail with ErrLegalHold.
+func (s *LevelDBStorage) PruneBlocks(heights []int64, state *PruneState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stateData, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to serialize prune state: %w", err)
+	}
+	holds, err := s.LoadLegalHolds()
+	if err != nil {
+		return err
+	}
+	held := heldHeights(holds)
+
+	batch := new(leveldb.Batch)
+	for _, height := range heights {
+		if held[height] {
+			return fmt.Errorf("cannot prune block %d: %w", height, ErrLegalHold)
+		}
+		hash, err := s.db.Get(heightKey(height), nil)
+		if err != nil {
+			return fmt.Errorf("failed to load block %d: %w", height, err)
+		}
+		block, err := s.loadBlock(string(hash))
+		if err != nil {
+			return fmt.Errorf("failed to load block %d: %w", height, err)
+		}
+		block.prune()
+		blockData, err := json.Marshal(block)
+		if err != nil {
+			return fmt.Errorf("failed to serialize block: %w", err)
+		}
+		batch.Put(prefixedKey(levelBlockPrefix, block.Hash), blockData)
+	}
+	batch.Put(levelPruneKey, stateData)
+	return s.db.Write(batch, nil)
+}
+
+// RebuildAddressIndex recomputes every address balance from the stored blocks and replaces
+// the stored balances with them in one batch
+func (s *LevelDBStorage) RebuildAddressIndex() (*AddressIndexReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.LoadPruneState()
+	if err != nil {
+		return nil, err
+	}
+	totals := newAddressTotals(state)
+	for height := int64(0); ; {
+		blocks, err := s.blocksFrom(height, 500)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load blocks from height %d: %w", height, err)
+		}
+		if len(blocks) == 0 {
+			break
+		}
+		for _, block := range blocks {
+			totals.addBlock(block)
+		}
+		height = blocks[len(blocks)-1].Index + 1
+	}
+
+	report := &AddressIndexReport{Addresses: len(totals.balances)}
+	batch := new(leveldb.Batch)
+	stored := make(map[string]bool)
+	iter := s.db.NewIterator(util.BytesPrefix(levelBalancePrefix), nil)
+	for iter.Next() {
+		address := string(iter.Key()[len(levelBalancePrefix):])
+		stored[address] = true
+		balance, exists := totals.balances[address]
+		if !exists || len(iter.Value()) != 8 ||
+			math.Abs(math.Float64frombits(binary.BigEndian.Uint64(iter.Value()))-balance) > balanceTolerance {
+			report.Repaired++
+		}
+		batch.Delete(append([]byte{}, iter.Key()...))
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	for address, balance := range totals.balances {
+		if !stored[address] {
+			report.Repaired++
+		}
+		data := make([]byte, 8)
+		binary.BigEndian.PutUint64(data, math.Float64bits(balance))
+		batch.Put(prefixedKey(levelBalancePrefix, address), data)
+	}
+
+	chainState, err := s.state()
+	if err != nil {
+		return nil, err
+	}
+	chainState.TotalAddresses = int64(len(totals.balances))
+	chainState.LastUpdated = time.Now().Unix()
+	stateData, err := json.Marshal(chainState)
+	if err != nil {
+		return nil, err
+	}
+	batch.Put(levelStateKey, stateData)
+
+	if err := s.db.Write(batch, nil); err != nil {
+		return nil, fmt.Errorf("failed to save balances: %w", err)
+	}
+	return report, nil
+}
+
+// LoadPruneState returns the prune state, or nil if the database was never pruned
+func (s *LevelDBStorage) LoadPruneState() (*PruneState, error) {
+	data, err := s.db.Get(levelPruneKey, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := newPruneState()
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to deserialize prune state: %w", err)
+	}
+	return state, nil
+}
+
+// holdKey returns the key of a legal hold
+func holdKey(kind, id string) []byte {
+	return prefixedKey(levelHoldPrefix, kind+"/"+id)
+}
+
+// SaveLegalHold stores a hold, replacing the reason of an existing hold on the same data
+func (s *LevelDBStorage) SaveLegalHold(hold LegalHold) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if data, err := s.db.Get(holdKey(hold.Kind, hold.ID), nil); err == nil {
+		var existing LegalHold
+		if err := json.Unmarshal(data, &existing); err == nil {
+			hold.PlacedAt = existing.PlacedAt
+		}
+	} else if err != leveldb.ErrNotFound {
+		return fmt.Errorf("failed to load legal hold: %w", err)
+	}
+
+	data, err := json.Marshal(hold)
+	if err != nil {
+		return fmt.Errorf("failed to serialize legal hold: %w", err)
+	}
+	if err := s.db.Put(holdKey(hold.Kind, hold.ID), data, nil); err != nil {
+		return fmt.Errorf("failed to save legal hold: %w", err)
+	}
+	return nil
+}
+
+// DeleteLegalHold removes a hold, returning ErrHoldNotFound if it isn't stored
+func (s *LevelDBStorage) DeleteLegalHold(kind, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := holdKey(kind, id)
+	if exists, err := s.db.Has(key, nil); err != nil {
+		return fmt.Errorf("failed to load legal hold: %w", err)
+	} else if !exists {
+		return ErrHoldNotFound
+	}
+	if err := s.db.Delete(key, nil); err != nil {
+		return fmt.Errorf("failed to delete legal hold: %w", err)
+	}
+	return nil
+}
+
+// LoadLegalHolds returns the stored holds, oldest first
+func (s *LevelDBStorage) LoadLegalHolds() ([]LegalHold, error) {
+	iter := s.db.NewIterator(util.BytesPrefix(levelHoldPrefix), nil)
+	defer iter.Release()
+
+	var holds []LegalHold
+	for iter.Next() {
+		var hold LegalHold
+		if err := json.Unmarshal(iter.Value(), &hold); err != nil {
+			return nil, fmt.Errorf("failed to deserialize legal hold: %w", err)
+		}
+		holds = append(holds, hold)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to load legal holds: %w", err)
+	}
+	sort.SliceStable(holds, func(i, j int) bool { return holds[i].PlacedAt < holds[j].PlacedAt })
+	return holds, nil
+}
+
+// Backup writes a consistent copy of the database to a new LevelDB directory
+func (s *LevelDBStorage) Backup(path string) error {
+	snapshot, err := s.db.GetSnapshot()
+	if err != nil {
+		return fmt.Errorf("failed to flush database: %w", err)
+	}
+	defer snapshot.Release()
+
+	backup, err := leveldb.OpenFile(path, &opt.Options{ErrorIfExist: true})
+	if err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	defer backup.Close()
+
+	iter := snapshot.NewIterator(nil, nil)
+	defer iter.Release()
+	batch := new(leveldb.Batch)
+	for iter.Next() {
+		batch.Put(append([]byte{}, iter.Key()...), append([]byte{}, iter.Value()...))
+		if batch.Len() >= 1000 {
+			if err := backup.Write(batch, nil); err != nil {
+				return fmt.Errorf("failed to back up database: %w", err)
+			}
+			batch.Reset()
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	if err := backup.Write(batch, nil); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
+
+// snapshotKey returns the key of the snapshot at a height
+func snapshotKey(index int64) []byte {
+	key := make([]byte, len(levelSnapPrefix)+8)
+	copy(key, levelSnapPrefix)
+	binary.BigEndian.PutUint64(key[len(levelSnapPrefix):], uint64(index))
+	return key
+}
+
+// SaveSnapshot stores a snapshot, keeping only the keep latest ones
+func (s *LevelDBStorage) SaveSnapshot(snapshot *StateSnapshot, keep int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to serialize snapshot: %w", err)
+	}
+	if err := s.db.Put(snapshotKey(snapshot.Header.Index), data, nil); err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+
+	// Keys sort by height, so the snapshots to delete are all but the last keep
+	iter := s.db.NewIterator(util.BytesPrefix(levelSnapPrefix), nil)
+	var keys [][]byte
+	for iter.Next() {
+		keys = append(keys, append([]byte{}, iter.Key()...))
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	batch := new(leveldb.Batch)
+	for i := 0; i < len(keys)-keep; i++ {
+		batch.Delete(keys[i])
+	}
+	if err := s.db.Write(batch, nil); err != nil {
+		return fmt.Errorf("failed to delete old snapshots: %w", err)
+	}
+	return nil
+}
+
+// LatestSnapshot returns the most recent snapshot, or nil if none was taken
+func (s *LevelDBStorage) LatestSnapshot() (*StateSnapshot, error) {
+	iter := s.db.NewIterator(util.BytesPrefix(levelSnapPrefix), nil)
+	defer iter.Release()
+	if !iter.Last() {
+		return nil, iter.Error()
+	}
+
+	snapshot := &StateSnapshot{}
+	if err := json.Unmarshal(iter.Value(), snapshot); err != nil {
+		return nil, fmt.Errorf("failed to deserialize snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+// SaveMempool replaces the saved pending transactions
+func (s *LevelDBStorage) SaveMempool(entries []MempoolEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batch := new(leveldb.Batch)
+	iter := s.db.NewIterator(util.BytesPrefix(levelPoolPrefix), nil)
+	for iter.Next() {
+		batch.Delete(append([]byte{}, iter.Key()...))
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("failed to clear mempool: %w", err)
+	}
+
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to serialize transaction: %w", err)
+		}
+		batch.Put(prefixedKey(levelPoolPrefix, entry.Transaction.Hash), data)
+	}
+	if err := s.db.Write(batch, nil); err != nil {
+		return fmt.Errorf("failed to save mempool: %w", err)
+	}
+	return nil
+}
+
+// LoadMempool returns the saved pending transactions, oldest first
+func (s *LevelDBStorage) LoadMempool() ([]MempoolEntry, error) {
+	iter := s.db.NewIterator(util.BytesPrefix(levelPoolPrefix), nil)
+	defer iter.Release()
+
+	var entries []MempoolEntry
+	for iter.Next() {
+		var entry MempoolEntry
+		if err := json.Unmarshal(iter.Value(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to deserialize transaction: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to load mempool: %w", err)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].AddedAt < entries[j].AddedAt })
+	return entries, nil
+}
@@ -0,0 +1,269 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Errors of the light client
+var (
+	ErrHeaderNotSynced = errors.New("block header not synced")
+	ErrInvalidProof    = errors.New("invalid transaction proof")
+)
+
+// LightSource is the full node surface a light client reads headers, transaction locations
+// and proofs from. Both *Blockchain and *PersistentBlockchain implement it; a client reading
+// one in the same process holds the lock guarding the chain around each call.
+type LightSource interface {
+	GetHeader(index int64) (BlockHeader, error)
+	GetTransaction(hash string) (*Transaction, TxLocation, error)
+	GetTransactionProof(blockIndex int, txHash string) (*MerkleProof, error)
+}
+
+// StateSource is the full node surface a light client reads state proofs from. Both
+// *Blockchain and *PersistentBlockchain implement it.
+type StateSource interface {
+	GetStateProof(address string) (*StateProof, error)
+}
+
+// ReceiptSource is the full node surface a light client reads receipt proofs from. Both
+// *Blockchain and *PersistentBlockchain implement it.
+type ReceiptSource interface {
+	GetReceiptProof(txHash string) (*ReceiptProof, error)
+}
+
+// FilterSource is the full node surface a light client reads compact filters from. Both
+// *Blockchain and *PersistentBlockchain implement it.
+type FilterSource interface {
+	GetCompactFilters(from, to int64) ([]CompactFilter, error)
+}
+
+// LightClient follows a chain by its headers alone (SPV). It checks the proof of work and
+// linkage of every header it accepts and answers whether a transaction is confirmed from
+// a Merkle proof against a header, never holding block bodies. The full node is trusted
+// for nothing but availability: a header or proof it gets wrong is rejected.
+type LightClient struct {
+	Difficulty int
+
+	mu      sync.RWMutex
+	headers []BlockHeader // From the trusted genesis header up to the tip
+}
+
+// NewLightClient creates a light client trusting a genesis header, e.g. one shipped with
+// the wallet or read once from a node it trusts
+func NewLightClient(genesis BlockHeader, difficulty int) (*LightClient, error) {
+	if genesis.Index != 0 {
+		return nil, fmt.Errorf("genesis header has index %d", genesis.Index)
+	}
+	return &LightClient{Difficulty: difficulty, headers: []BlockHeader{genesis}}, nil
+}
+
+// Height returns the height of the tip header
+func (lc *LightClient) Height() int64 {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return int64(len(lc.headers)) - 1
+}
+
+// Tip returns the tip header
+func (lc *LightClient) Tip() BlockHeader {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return lc.headers[len(lc.headers)-1]
+}
+
+// Header returns the synced header at a height
+func (lc *LightClient) Header(index int64) (BlockHeader, error) {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	if index < 0 || index >= int64(len(lc.headers)) {
+		return BlockHeader{}, ErrHeaderNotSynced
+	}
+	return lc.headers[index], nil
+}
+
+// AddHeaders appends headers extending the tip, in order, checking each against its parent.
+// It stops at the first invalid header and returns how many were added with the error.
+func (lc *LightClient) AddHeaders(headers ...BlockHeader) (int, error) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	for i := range headers {
+		tip := &lc.headers[len(lc.headers)-1]
+		if err := headers[i].validateAgainstParent(tip, lc.Difficulty); err != nil {
+			return i, fmt.Errorf("invalid header %d: %w", headers[i].Index, err)
+		}
+		lc.headers = append(lc.headers, headers[i])
+	}
+	return len(headers), nil
+}
+
+// Sync catches up with a full node's chain and returns the new tip height. If the node has
+// switched to a fork, the client follows it only once the fork is longer than the headers it
+// holds, then rewinds to the fork point; every header is validated before it replaces one.
+func (lc *LightClient) Sync(source LightSource) (int64, error) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	// Find the highest held header the node still agrees with
+	fork := int64(len(lc.headers)) - 1
+	for ; fork > 0; fork-- {
+		header, err := source.GetHeader(fork)
+		if err == nil && header.Hash == lc.headers[fork].Hash {
+			break
+		}
+		if err != nil && !errors.Is(err, ErrBlockNotFound) {
+			return lc.height(), err
+		}
+	}
+
+	var branch []BlockHeader
+	tip := lc.headers[fork]
+	for index := fork + 1; ; index++ {
+		header, err := source.GetHeader(index)
+		if errors.Is(err, ErrBlockNotFound) {
+			break
+		}
+		if err != nil {
+			return lc.height(), err
+		}
+		if err := header.validateAgainstParent(&tip, lc.Difficulty); err != nil {
+			return lc.height(), fmt.Errorf("invalid header %d: %w", header.Index, err)
+		}
+		branch = append(branch, header)
+		tip = header
+	}
+
+	if fork+int64(len(branch)) > lc.height() {
+		lc.headers = append(lc.headers[:fork+1], branch...)
+	}
+	return lc.height(), nil
+}
+
+// height returns the tip height; the caller holds the lock
+func (lc *LightClient) height() int64 {
+	return int64(len(lc.headers)) - 1
+}
+
+// Confirmations returns how many synced headers confirm a transaction, from its block to the
+// tip inclusive (0 if the node has not confirmed it). The node locates the transaction and
+// proves it; the proof is verified against the synced header, so a node cannot claim a
+// confirmation the headers don't commit.
+func (lc *LightClient) Confirmations(source LightSource, txHash string) (int64, error) {
+	_, location, err := source.GetTransaction(txHash)
+	if errors.Is(err, ErrTransactionNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	header, err := lc.Header(location.BlockIndex)
+	if err != nil {
+		return 0, fmt.Errorf("block %d: %w", location.BlockIndex, err)
+	}
+	proof, err := source.GetTransactionProof(int(location.BlockIndex), txHash)
+	if err != nil {
+		return 0, err
+	}
+	if proof.Hash != txHash || !VerifyProof(proof, header.MerkleRoot, header.MerkleScheme()) {
+		return 0, fmt.Errorf("transaction %s in block %d: %w", txHash, location.BlockIndex, ErrInvalidProof)
+	}
+	return lc.Height() - location.BlockIndex + 1, nil
+}
+
+// IsConfirmed reports whether a transaction has at least depth confirmations on the synced
+// headers. A depth below 1 counts as 1, i.e. included in a block.
+func (lc *LightClient) IsConfirmed(source LightSource, txHash string, depth int64) (bool, error) {
+	confirmations, err := lc.Confirmations(source, txHash)
+	if err != nil {
+		return false, err
+	}
+	return confirmations >= max(depth, 1), nil
+}
+
+// Account returns the state of an address after the latest block of the node, nil if it has
+// none. The node proves it against the state root of that block, which must be synced.
+func (lc *LightClient) Account(source StateSource, address string) (*AccountState, error) {
+	proof, err := source.GetStateProof(address)
+	if err != nil {
+		return nil, err
+	}
+	if proof.Address != address {
+		return nil, fmt.Errorf("address %s: %w", address, ErrInvalidStateProof)
+	}
+	return lc.VerifyState(proof)
+}
+
+// VerifyState checks a state proof against the synced header of the block it names and returns
+// the state it proves
+func (lc *LightClient) VerifyState(proof *StateProof) (*AccountState, error) {
+	if proof == nil {
+		return nil, errors.New("missing proof")
+	}
+	header, err := lc.Header(proof.Height)
+	if err != nil {
+		return nil, fmt.Errorf("block %d: %w", proof.Height, err)
+	}
+	return VerifyStateProof(proof, header)
+}
+
+// Receipt returns the receipt of a confirmed transaction, proven against the synced header of
+// its block. Only blocks from ReceiptRootBlockVersion on commit their receipts.
+func (lc *LightClient) Receipt(source ReceiptSource, txHash string) (*Receipt, error) {
+	proof, err := source.GetReceiptProof(txHash)
+	if err != nil {
+		return nil, err
+	}
+	if proof == nil || proof.Receipt.TxHash != txHash {
+		return nil, fmt.Errorf("transaction %s: %w", txHash, ErrInvalidReceiptProof)
+	}
+	header, err := lc.Header(proof.BlockIndex)
+	if err != nil {
+		return nil, fmt.Errorf("block %d: %w", proof.BlockIndex, err)
+	}
+	if err := VerifyReceiptProof(proof, header); err != nil {
+		return nil, err
+	}
+	return &proof.Receipt, nil
+}
+
+// RelevantBlocks returns the heights between two synced heights, inclusive, of the blocks that
+// may hold a transaction of one of the addresses. It matches the addresses against the compact
+// filters of the blocks locally, so the node learns nothing of them. Every filter must be
+// committed by its block (see CompactFiltersHandler) and chain to the filter before it.
+func (lc *LightClient) RelevantBlocks(source FilterSource, addresses []string, from, to int64) ([]int64, error) {
+	if from < 0 || from > to || to > lc.Height() {
+		return nil, errors.New("invalid height range")
+	}
+	filters, err := source.GetCompactFilters(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(filters)) != to-from+1 {
+		return nil, fmt.Errorf("expected %d filters, got %d", to-from+1, len(filters))
+	}
+
+	var heights []int64
+	prev := ""
+	for i := range filters {
+		filter := &filters[i]
+		header, err := lc.Header(from + int64(i))
+		if err != nil {
+			return nil, err
+		}
+		if err := VerifyCompactFilter(filter, header, prev); err != nil {
+			return nil, err
+		}
+		prev = filter.Header
+
+		match, err := MatchCompactFilter(filter.Data, header.PrevHash, addresses...)
+		if err != nil {
+			return nil, fmt.Errorf("filter of block %d: %w", filter.Height, err)
+		}
+		if match {
+			heights = append(heights, filter.Height)
+		}
+	}
+	return heights, nil
+}
@@ -0,0 +1,375 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LoadTarget is the node surface exercised by the load generator
+type LoadTarget interface {
+	AddTransaction(tx *Transaction) error
+	GetLatestBlock() *Block
+}
+
+// EnhancedLoadTarget is implemented by targets that also accept enhanced (contract) transactions
+type EnhancedLoadTarget interface {
+	AddEnhancedTransaction(tx *EnhancedTransaction) error
+}
+
+// Amount distributions supported by the load generator
+const (
+	DistributionFixed       = "fixed"
+	DistributionUniform     = "uniform"
+	DistributionExponential = "exponential"
+)
+
+// LoadConfig configures a load generation run
+type LoadConfig struct {
+	Wallets           int           // Number of wallets to create
+	TargetTPS         float64       // Transactions submitted per second
+	Duration          time.Duration // Run length (ignored if TotalTransactions is set)
+	TotalTransactions int           // Stop after this many submissions
+
+	AmountDistribution string  // fixed, uniform or exponential
+	MinAmount          float64 // Lower bound (uniform) or fixed amount
+	MaxAmount          float64 // Upper bound (uniform) or cap (exponential)
+	MeanAmount         float64 // Mean for the exponential distribution
+	Fee                float64
+
+	FanOut            int     // Recipients per payment burst from one sender
+	ContractCallRatio float64 // Share of contract call transactions (0..1)
+
+	MineInterval time.Duration // How often Mine is called (0 disables mining)
+	Mine         func() error
+
+	Seed int64 // Random seed, making the workload reproducible
+}
+
+// LoadReport summarizes a load generation run
+type LoadReport struct {
+	Submitted        int            `json:"submitted"`
+	Accepted         int            `json:"accepted"`
+	Rejected         int            `json:"rejected"`
+	Skipped          int            `json:"skipped"` // Contract calls the target cannot accept
+	RejectReasons    map[string]int `json:"rejectReasons"`
+	Elapsed          time.Duration  `json:"elapsed"`
+	AchievedTPS      float64        `json:"achievedTps"`
+	LatencyP50       time.Duration  `json:"latencyP50"`
+	LatencyP95       time.Duration  `json:"latencyP95"`
+	LatencyP99       time.Duration  `json:"latencyP99"`
+	LatencyMax       time.Duration  `json:"latencyMax"`
+	BlocksMined      int            `json:"blocksMined"`
+	TxsConfirmed     int            `json:"txsConfirmed"`
+	MiningThroughput float64        `json:"miningThroughput"` // Confirmed transactions per second
+	AvgBlockTime     time.Duration  `json:"avgBlockTime"`
+}
+
+// String formats the report for console output
+func (r *LoadReport) String() string {
+	return fmt.Sprintf(
+		"submitted=%d accepted=%d rejected=%d skipped=%d tps=%.1f latency(p50=%v p95=%v p99=%v max=%v) blocks=%d confirmed=%d mined_tps=%.1f avg_block=%v",
+		r.Submitted, r.Accepted, r.Rejected, r.Skipped, r.AchievedTPS,
+		r.LatencyP50, r.LatencyP95, r.LatencyP99, r.LatencyMax,
+		r.BlocksMined, r.TxsConfirmed, r.MiningThroughput, r.AvgBlockTime)
+}
+
+// loadWallet is a generated wallet with its next nonce
+type loadWallet struct {
+	wallet *Wallet
+	nonce  int64
+}
+
+// LoadGenerator streams a synthetic transaction workload against a node
+type LoadGenerator struct {
+	config  LoadConfig
+	target  LoadTarget
+	wallets []*loadWallet
+	rng     *rand.Rand
+
+	// Targets like Blockchain aren't safe for concurrent use, so submissions and mining are serialized
+	targetMu sync.Mutex
+}
+
+// NewLoadGenerator creates a load generator and its wallets
+func NewLoadGenerator(target LoadTarget, config LoadConfig) (*LoadGenerator, error) {
+	if config.Wallets < 2 {
+		return nil, errors.New("load generator needs at least 2 wallets")
+	}
+	if config.TargetTPS <= 0 {
+		return nil, errors.New("target TPS must be positive")
+	}
+	if config.Duration <= 0 && config.TotalTransactions <= 0 {
+		return nil, errors.New("either duration or total transactions must be set")
+	}
+	if config.FanOut <= 0 {
+		config.FanOut = 1
+	}
+	if config.AmountDistribution == "" {
+		config.AmountDistribution = DistributionUniform
+	}
+	if config.MinAmount <= 0 {
+		config.MinAmount = 0.01
+	}
+	if config.MaxAmount < config.MinAmount {
+		config.MaxAmount = config.MinAmount * 100
+	}
+	if config.MeanAmount <= 0 {
+		config.MeanAmount = (config.MinAmount + config.MaxAmount) / 2
+	}
+
+	lg := &LoadGenerator{
+		config: config,
+		target: target,
+		rng:    rand.New(rand.NewSource(config.Seed)),
+	}
+
+	for i := 0; i < config.Wallets; i++ {
+		wallet, err := NewWallet()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create wallet: %w", err)
+		}
+		lg.wallets = append(lg.wallets, &loadWallet{wallet: wallet})
+	}
+
+	return lg, nil
+}
+
+// Run streams transactions at the target rate until the duration or transaction count is reached
+func (lg *LoadGenerator) Run(ctx context.Context) (*LoadReport, error) {
+	if lg.config.Duration > 0 && lg.config.TotalTransactions <= 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, lg.config.Duration)
+		defer cancel()
+	}
+
+	report := &LoadReport{RejectReasons: make(map[string]int)}
+	var latencies []time.Duration
+
+	var miningWg sync.WaitGroup
+	miningDone := make(chan struct{})
+	var blockTimes []time.Duration
+	if lg.config.MineInterval > 0 && lg.config.Mine != nil {
+		miningWg.Add(1)
+		go func() {
+			defer miningWg.Done()
+			blockTimes = lg.mineLoop(miningDone, report)
+		}()
+	}
+
+	// Each tick submits a burst of FanOut payments
+	interval := time.Duration(float64(time.Second) * float64(lg.config.FanOut) / lg.config.TargetTPS)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	start := time.Now()
+
+loop:
+	for lg.config.TotalTransactions <= 0 || report.Submitted < lg.config.TotalTransactions {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+		}
+
+		for _, latency := range lg.submitNext(report) {
+			latencies = append(latencies, latency)
+		}
+	}
+
+	report.Elapsed = time.Since(start)
+	close(miningDone)
+	miningWg.Wait()
+
+	if report.Elapsed > 0 {
+		report.AchievedTPS = float64(report.Submitted) / report.Elapsed.Seconds()
+		report.MiningThroughput = float64(report.TxsConfirmed) / report.Elapsed.Seconds()
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	report.LatencyP50 = percentile(latencies, 0.50)
+	report.LatencyP95 = percentile(latencies, 0.95)
+	report.LatencyP99 = percentile(latencies, 0.99)
+	if len(latencies) > 0 {
+		report.LatencyMax = latencies[len(latencies)-1]
+	}
+
+	if len(blockTimes) > 0 {
+		var total time.Duration
+		for _, blockTime := range blockTimes {
+			total += blockTime
+		}
+		report.AvgBlockTime = total / time.Duration(len(blockTimes))
+	}
+
+	return report, nil
+}
+
+// submitNext builds and submits the next workload step, returning acceptance latencies
+func (lg *LoadGenerator) submitNext(report *LoadReport) []time.Duration {
+	sender := lg.wallets[lg.rng.Intn(len(lg.wallets))]
+
+	if lg.config.ContractCallRatio > 0 && lg.rng.Float64() < lg.config.ContractCallRatio {
+		latency, ok := lg.submitContractCall(sender, report)
+		if !ok {
+			return nil
+		}
+		return []time.Duration{latency}
+	}
+
+	// A payment burst from one sender to FanOut distinct recipients
+	var latencies []time.Duration
+	for i := 0; i < lg.config.FanOut; i++ {
+		if lg.config.TotalTransactions > 0 && report.Submitted >= lg.config.TotalTransactions {
+			break
+		}
+
+		recipient := lg.pickRecipient(sender)
+		sender.nonce++
+		tx := NewTransactionWithNonce(sender.wallet.Address, recipient.wallet.Address, lg.nextAmount(), lg.config.Fee, sender.nonce)
+
+		// Signing cost is part of a realistic client workload
+		if err := sender.wallet.AttachSignature(tx); err != nil {
+			report.Rejected++
+			report.RejectReasons["signing failed"]++
+			continue
+		}
+
+		lg.targetMu.Lock()
+		start := time.Now()
+		err := lg.target.AddTransaction(tx)
+		latency := time.Since(start)
+		lg.targetMu.Unlock()
+
+		lg.recordResult(report, err)
+		latencies = append(latencies, latency)
+	}
+	return latencies
+}
+
+// submitContractCall submits a signed contract call if the target supports enhanced transactions
+func (lg *LoadGenerator) submitContractCall(sender *loadWallet, report *LoadReport) (time.Duration, bool) {
+	enhancedTarget, ok := lg.target.(EnhancedLoadTarget)
+	if !ok {
+		report.Skipped++
+		return 0, false
+	}
+
+	recipient := lg.pickRecipient(sender)
+	tx := NewStandardTransaction(sender.wallet.Address, recipient.wallet.Address, lg.nextAmount(), lg.config.Fee, nil)
+	tx.Type = ContractTx
+	tx.ContractData = fmt.Sprintf("call:%d", lg.rng.Int63())
+	tx.Hash = tx.calculateHash()
+
+	signature, err := sender.wallet.SignTransactionEnhanced(tx)
+	if err == nil {
+		err = tx.AddSignature(*signature)
+	}
+	if err != nil {
+		report.Rejected++
+		report.RejectReasons["signing failed"]++
+		return 0, false
+	}
+
+	lg.targetMu.Lock()
+	start := time.Now()
+	err = enhancedTarget.AddEnhancedTransaction(tx)
+	latency := time.Since(start)
+	lg.targetMu.Unlock()
+
+	lg.recordResult(report, err)
+	return latency, true
+}
+
+// recordResult counts the outcome of a submission
+func (lg *LoadGenerator) recordResult(report *LoadReport, err error) {
+	report.Submitted++
+	if err != nil {
+		report.Rejected++
+		report.RejectReasons[err.Error()]++
+		return
+	}
+	report.Accepted++
+}
+
+// mineLoop periodically mines blocks and tracks confirmations until done is closed
+func (lg *LoadGenerator) mineLoop(done chan struct{}, report *LoadReport) []time.Duration {
+	ticker := time.NewTicker(lg.config.MineInterval)
+	defer ticker.Stop()
+
+	var blockTimes []time.Duration
+	for {
+		select {
+		case <-done:
+			return blockTimes
+		case <-ticker.C:
+		}
+
+		lg.targetMu.Lock()
+		before := lg.target.GetLatestBlock().Hash
+		start := time.Now()
+		err := lg.config.Mine()
+		elapsed := time.Since(start)
+		latest := lg.target.GetLatestBlock()
+		lg.targetMu.Unlock()
+
+		if err != nil || latest.Hash == before {
+			continue
+		}
+
+		blockTimes = append(blockTimes, elapsed)
+		report.BlocksMined++
+		for _, tx := range latest.Transactions {
+			if !tx.IsCoinbase() {
+				report.TxsConfirmed++
+			}
+		}
+	}
+}
+
+// pickRecipient chooses a random wallet other than the sender
+func (lg *LoadGenerator) pickRecipient(sender *loadWallet) *loadWallet {
+	for {
+		recipient := lg.wallets[lg.rng.Intn(len(lg.wallets))]
+		if recipient != sender {
+			return recipient
+		}
+	}
+}
+
+// nextAmount draws a payment amount from the configured distribution
+func (lg *LoadGenerator) nextAmount() float64 {
+	var amount float64
+	switch lg.config.AmountDistribution {
+	case DistributionFixed:
+		amount = lg.config.MinAmount
+	case DistributionExponential:
+		amount = math.Min(lg.rng.ExpFloat64()*lg.config.MeanAmount, lg.config.MaxAmount)
+	default:
+		amount = lg.config.MinAmount + lg.rng.Float64()*(lg.config.MaxAmount-lg.config.MinAmount)
+	}
+
+	// Round to 8 decimals and keep amounts strictly positive
+	amount = math.Round(amount*1e8) / 1e8
+	if amount < lg.config.MinAmount {
+		amount = lg.config.MinAmount
+	}
+	return amount
+}
+
+// percentile returns the p-th percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	index := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if index < 0 {
+		index = 0
+	}
+	return sorted[index]
+}
@@ -0,0 +1,382 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// MsgMaintenance announces a node's maintenance state to its peers
+const MsgMaintenance = "maintenance"
+
+// MaintenanceState is the phase of a maintenance window
+type MaintenanceState string
+
+const (
+	MaintenanceActive   MaintenanceState = "active"   // Normal operation
+	MaintenanceDraining MaintenanceState = "draining" // Refusing external transactions, letting the current block finish
+	MaintenanceBackup   MaintenanceState = "backup"   // Flushing and backing up the chain data
+	MaintenancePaused   MaintenanceState = "paused"   // Down for maintenance until resumed
+)
+
+// MaintenanceAnnouncement tells peers about a node's maintenance state
+type MaintenanceAnnouncement struct {
+	State  MaintenanceState `json:"state"`
+	Reason string           `json:"reason,omitempty"`
+	Until  int64            `json:"until,omitempty"` // Expected end in Unix seconds (0 if open-ended)
+}
+
+// MaintenanceWindow is a scheduled maintenance period
+type MaintenanceWindow struct {
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"` // 0 stays paused until Resume
+	Reason   string        `json:"reason"`
+}
+
+// MaintenanceStatus reports the state of a maintenance coordinator
+type MaintenanceStatus struct {
+	State     MaintenanceState   `json:"state"`
+	Since     time.Time          `json:"since"`
+	Window    *MaintenanceWindow `json:"window,omitempty"` // Scheduled or running window
+	Backup    string             `json:"backup,omitempty"` // Path of the last backup
+	LastError string             `json:"lastError,omitempty"`
+}
+
+// MaintenanceTarget is the chain surface a maintenance window operates on.
+// Both *Blockchain and *PersistentBlockchain implement it.
+type MaintenanceTarget interface {
+	SetAcceptExternalTransactions(accept bool)
+	Backup(dir string) (string, error)
+}
+
+// MaintenanceConfig configures a maintenance coordinator
+type MaintenanceConfig struct {
+	BackupDir    string        // Directory backups are written to (empty skips the backup)
+	PollInterval time.Duration // How often the schedule is checked (default 1s)
+}
+
+// Maintenance takes the node through scheduled maintenance windows: it stops accepting
+// external transactions, waits for the block being mined to finish by taking the chain lock,
+// backs up the chain data and stays paused until the window ends or Resume is called.
+// Every state change is announced to peers. Miners should skip mining while not Active.
+type Maintenance struct {
+	target  MaintenanceTarget
+	node    *Node       // Optional, for announcements
+	lock    sync.Locker // Serializes chain access with other users such as the miner
+	config  MaintenanceConfig
+	status  MaintenanceStatus
+	pending *MaintenanceWindow // Scheduled window that hasn't started yet
+	peers   map[string]MaintenanceAnnouncement
+	mu      sync.Mutex
+	quit    chan struct{}
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+// NewMaintenance creates a maintenance coordinator and registers its message handler.
+// The lock must be the one guarding the chain (nil uses an internal mutex); node may be nil.
+func NewMaintenance(target MaintenanceTarget, node *Node, lock sync.Locker, config MaintenanceConfig) *Maintenance {
+	if lock == nil {
+		lock = &sync.Mutex{}
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+
+	m := &Maintenance{
+		target: target,
+		node:   node,
+		lock:   lock,
+		config: config,
+		status: MaintenanceStatus{State: MaintenanceActive, Since: time.Now()},
+		peers:  make(map[string]MaintenanceAnnouncement),
+		quit:   make(chan struct{}),
+	}
+	if node != nil {
+		node.Handle(MsgMaintenance, m.handleAnnouncement)
+	}
+	return m
+}
+
+// Start begins watching the schedule
+func (m *Maintenance) Start() {
+	m.wg.Add(1)
+	go m.loop()
+}
+
+// Stop ends schedule watching
+func (m *Maintenance) Stop() {
+	m.once.Do(func() { close(m.quit) })
+	m.wg.Wait()
+}
+
+// loop starts and ends scheduled windows until stopped
+func (m *Maintenance) loop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.quit:
+			return
+		case now := <-ticker.C:
+			m.tick(now)
+		}
+	}
+}
+
+// tick starts a due window or resumes after a finished one
+func (m *Maintenance) tick(now time.Time) {
+	m.mu.Lock()
+	pending := m.pending
+	state, window := m.status.State, m.status.Window
+	m.mu.Unlock()
+
+	switch {
+	case state == MaintenanceActive && pending != nil && !now.Before(pending.Start):
+		if err := m.run(*pending); err != nil {
+			log.Printf("Maintenance window failed to start: %v", err)
+		}
+	case state == MaintenancePaused && window != nil && window.Duration > 0 && !now.Before(window.Start.Add(window.Duration)):
+		m.Resume()
+	}
+}
+
+// Schedule plans a maintenance window, replacing any window that hasn't started yet
+func (m *Maintenance) Schedule(window MaintenanceWindow) error {
+	if window.Duration < 0 {
+		return errors.New("maintenance duration cannot be negative")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.status.State != MaintenanceActive {
+		return errors.New("maintenance is already in progress")
+	}
+	m.pending = &window
+	m.status.Window = &window
+	log.Printf("Maintenance scheduled at %s for %v: %s", window.Start.Format(time.RFC3339), window.Duration, window.Reason)
+	return nil
+}
+
+// Cancel drops the scheduled window if it hasn't started yet
+func (m *Maintenance) Cancel() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.pending == nil {
+		return errors.New("no maintenance scheduled")
+	}
+	m.pending = nil
+	m.status.Window = nil
+	return nil
+}
+
+// Begin starts maintenance now, returning once the node is paused
+func (m *Maintenance) Begin(reason string, duration time.Duration) error {
+	if duration < 0 {
+		return errors.New("maintenance duration cannot be negative")
+	}
+	return m.run(MaintenanceWindow{Start: time.Now(), Duration: duration, Reason: reason})
+}
+
+// run drains the node, backs it up and pauses it
+func (m *Maintenance) run(window MaintenanceWindow) error {
+	m.mu.Lock()
+	if m.status.State != MaintenanceActive {
+		m.mu.Unlock()
+		return errors.New("maintenance is already in progress")
+	}
+	m.pending = nil
+	m.status.State = MaintenanceDraining
+	m.status.Since = time.Now()
+	m.status.Window = &window
+	m.status.LastError = ""
+	m.mu.Unlock()
+
+	log.Printf("Entering maintenance: %s", window.Reason)
+	m.announce()
+	m.target.SetAcceptExternalTransactions(false)
+
+	// Taking the chain lock waits for the block being mined to be finished
+	m.lock.Lock()
+	m.setState(MaintenanceBackup)
+	var backup string
+	var err error
+	if m.config.BackupDir != "" {
+		backup, err = m.target.Backup(m.config.BackupDir)
+	}
+	m.lock.Unlock()
+
+	m.mu.Lock()
+	if err != nil {
+		m.status.LastError = err.Error()
+	} else if backup != "" {
+		m.status.Backup = backup
+	}
+	m.mu.Unlock()
+
+	// A failed backup leaves the node paused so the operator can investigate before upgrading
+	m.setState(MaintenancePaused)
+	if err != nil {
+		log.Printf("Maintenance backup failed: %v", err)
+		return fmt.Errorf("backup failed: %w", err)
+	}
+	if backup != "" {
+		log.Printf("Maintenance backup written to %s", backup)
+	}
+	return nil
+}
+
+// Resume ends maintenance and accepts external transactions again
+func (m *Maintenance) Resume() error {
+	m.mu.Lock()
+	if m.status.State != MaintenancePaused {
+		m.mu.Unlock()
+		return fmt.Errorf("cannot resume while %s", m.status.State)
+	}
+	m.status.State = MaintenanceActive
+	m.status.Since = time.Now()
+	m.status.Window = nil
+	m.mu.Unlock()
+
+	m.target.SetAcceptExternalTransactions(true)
+	m.announce()
+	log.Printf("Maintenance finished, resuming normal operation")
+	return nil
+}
+
+// Active reports whether the node is operating normally
+func (m *Maintenance) Active() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status.State == MaintenanceActive
+}
+
+// Status returns the current maintenance status
+func (m *Maintenance) Status() MaintenanceStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := m.status
+	if status.Window != nil {
+		window := *status.Window
+		status.Window = &window
+	}
+	return status
+}
+
+// PeerStates returns the last maintenance state announced by each peer, keyed by node ID
+func (m *Maintenance) PeerStates() map[string]MaintenanceAnnouncement {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	states := make(map[string]MaintenanceAnnouncement, len(m.peers))
+	for id, announcement := range m.peers {
+		states[id] = announcement
+	}
+	return states
+}
+
+// setState records a state change and announces it to peers
+func (m *Maintenance) setState(state MaintenanceState) {
+	m.mu.Lock()
+	m.status.State = state
+	m.status.Since = time.Now()
+	m.mu.Unlock()
+	m.announce()
+}
+
+// announce broadcasts the current state to peers
+func (m *Maintenance) announce() {
+	m.mu.Lock()
+	announcement := MaintenanceAnnouncement{State: m.status.State}
+	if window := m.status.Window; window != nil && m.status.State != MaintenanceActive {
+		announcement.Reason = window.Reason
+		if window.Duration > 0 {
+			announcement.Until = window.Start.Add(window.Duration).Unix()
+		}
+	}
+	m.mu.Unlock()
+
+	if m.node != nil {
+		m.node.Broadcast(MsgMaintenance, announcement)
+	}
+}
+
+// handleAnnouncement records the maintenance state of a peer
+func (m *Maintenance) handleAnnouncement(peer *Peer, msg *Message) error {
+	var announcement MaintenanceAnnouncement
+	if err := json.Unmarshal(msg.Payload, &announcement); err != nil {
+		return fmt.Errorf("malformed maintenance announcement: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if announcement.State == MaintenanceActive {
+		delete(m.peers, peer.NodeID())
+	} else {
+		m.peers[peer.NodeID()] = announcement
+	}
+	log.Printf("Peer %s maintenance state: %s", peer.Address, announcement.State)
+	return nil
+}
+
+// backupName returns a timestamped backup file name
+func backupName(extension string) string {
+	return fmt.Sprintf("chain-backup-%s%s", time.Now().UTC().Format("20060102T150405.000Z"), extension)
+}
+
+// SetAcceptExternalTransactions opens or closes the pool to transactions from sources other than local
+func (bc *Blockchain) SetAcceptExternalTransactions(accept bool) {
+	bc.TransactionPool.SetAcceptExternal(accept)
+}
+
+// Backup writes the chain as a JSON array of blocks, readable by ImportBlocks, and returns its path
+func (bc *Blockchain) Backup(dir string) (string, error) {
+	path := filepath.Join(dir, backupName(".json"))
+	if err := writeBlocksFile(path, bc.Chain); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// SetAcceptExternalTransactions opens or closes the pool to transactions from sources other than local
+func (pbc *PersistentBlockchain) SetAcceptExternalTransactions(accept bool) {
+	pbc.TransactionPool.SetAcceptExternal(accept)
+}
+
+// Backup flushes the database and writes a consistent copy of it, returning its path
+func (pbc *PersistentBlockchain) Backup(dir string) (string, error) {
+	ext := ".db"
+	if _, ok := pbc.Database.(*LevelDBStorage); ok {
+		ext = ".leveldb"
+	}
+	path := filepath.Join(dir, backupName(ext))
+	if err := pbc.Database.Backup(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Backup checkpoints the write-ahead log and writes a consistent copy of the database to a new file
+func (d *Database) Backup(path string) error {
+	if d.pg {
+		return errors.New("backups of PostgreSQL databases are not supported, use pg_dump")
+	}
+	if err := d.Flush(); err != nil {
+		return err
+	}
+	if _, err := d.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("failed to flush database: %w", err)
+	}
+	if _, err := d.db.Exec("VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
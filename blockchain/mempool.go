@@ -0,0 +1,108 @@
+package blockchain
+
+import (
+	"fmt"
+	"sort"
+)
+
+// PoolTransaction is a transaction the mempool holds: a standard transaction, or an enhanced
+// transaction that joins blocks in standard form once executable
+type PoolTransaction interface {
+	TxHash() string
+	TxType() TransactionType
+	IsExecutable() bool
+	ToStandardTransaction() Transaction
+}
+
+// TxHash returns the hash of the transaction
+func (tx *Transaction) TxHash() string {
+	return tx.Hash
+}
+
+// TxType returns StandardTx
+func (tx *Transaction) TxType() TransactionType {
+	return StandardTx
+}
+
+// IsExecutable reports whether the transaction can join a block, always true for a standard transaction
+func (tx *Transaction) IsExecutable() bool {
+	return true
+}
+
+// ToStandardTransaction returns a copy of the transaction
+func (tx *Transaction) ToStandardTransaction() Transaction {
+	return *tx
+}
+
+// TxHash returns the hash of the transaction
+func (tx *EnhancedTransaction) TxHash() string {
+	return tx.Hash
+}
+
+// TxType returns the type of the transaction
+func (tx *EnhancedTransaction) TxType() TransactionType {
+	return tx.Type
+}
+
+// Add adds a standard or enhanced transaction submitted in-process to the pool if it's valid
+func (tp *Mempool) Add(tx PoolTransaction) error {
+	switch tx := tx.(type) {
+	case *Transaction:
+		return tp.AddTransaction(tx)
+	case *EnhancedTransaction:
+		return tp.AddEnhancedTransaction(tx)
+	default:
+		return fmt.Errorf("%w: unsupported transaction type %T", ErrInvalidTransaction, tx)
+	}
+}
+
+// Get returns a pending standard or enhanced transaction by hash
+func (tp *Mempool) Get(hash string) (PoolTransaction, bool) {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+
+	if tx, exists := tp.transactions[hash]; exists {
+		return tx, true
+	}
+	if tx, exists := tp.enhanced[hash]; exists {
+		return tx, true
+	}
+	return nil, false
+}
+
+// Size returns the number of pending standard and enhanced transactions
+func (tp *Mempool) Size() int {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+	return tp.sizeLocked()
+}
+
+// sizeLocked returns the number of pending transactions the pool size limit counts (caller
+// must hold the lock)
+func (tp *Mempool) sizeLocked() int {
+	return len(tp.transactions) + len(tp.enhanced)
+}
+
+// executableLocked returns the enhanced transactions ready to join a block in standard form,
+// highest fee first (caller must hold the lock)
+func (tp *Mempool) executableLocked() []*Transaction {
+	var ready []*EnhancedTransaction
+	for _, tx := range tp.enhanced {
+		if tx.IsExecutable() {
+			ready = append(ready, tx)
+		}
+	}
+	sort.Slice(ready, func(i, j int) bool {
+		if ready[i].Fee != ready[j].Fee {
+			return ready[i].Fee > ready[j].Fee
+		}
+		return ready[i].Hash < ready[j].Hash
+	})
+
+	txs := make([]*Transaction, len(ready))
+	for i, tx := range ready {
+		standard := tx.ToStandardTransaction()
+		txs[i] = &standard
+	}
+	return txs
+}
@@ -0,0 +1,187 @@
+package blockchain
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// DropRemoved is the reason of a transaction removed from the pool by an operator
+const DropRemoved DropReason = "removed"
+
+// PendingFilter selects pending transactions. Zero fields match every transaction.
+type PendingFilter struct {
+	Sender string
+	Type   TransactionType // StandardTx also matches transactions submitted in standard form
+	MinFee float64
+	MaxFee float64
+	Limit  int // Most transactions returned, oldest first
+}
+
+// matches reports whether a pending transaction passes the filter
+func (f *PendingFilter) matches(pending *PendingTransaction) bool {
+	if f.Sender != "" && pending.From != f.Sender {
+		return false
+	}
+	if f.Type != "" && pending.Type != f.Type {
+		return false
+	}
+	if pending.Fee < f.MinFee || f.MaxFee > 0 && pending.Fee > f.MaxFee {
+		return false
+	}
+	return true
+}
+
+// PendingTransaction describes a standard or enhanced transaction waiting in the pool
+type PendingTransaction struct {
+	Hash       string          `json:"hash"`
+	Type       TransactionType `json:"type"`
+	Enhanced   bool            `json:"enhanced"`
+	From       string          `json:"from"`
+	To         string          `json:"to"`
+	Amount     float64         `json:"amount"`
+	Fee        float64         `json:"fee"`
+	Nonce      int64           `json:"nonce,omitempty"`
+	AddedAt    int64           `json:"addedAt"`    // Unix time it entered the pool, creation time for enhanced transactions
+	Executable bool            `json:"executable"` // Can join the next block
+}
+
+// pendingLocked describes a pending transaction (caller must hold the lock)
+func (tp *Mempool) pendingLocked(hash string) (*PendingTransaction, bool) {
+	if tx, exists := tp.transactions[hash]; exists {
+		return &PendingTransaction{
+			Hash:       tx.Hash,
+			Type:       StandardTx,
+			From:       tx.From,
+			To:         tx.To,
+			Amount:     tx.Amount,
+			Fee:        tx.Fee,
+			Nonce:      tx.Nonce,
+			AddedAt:    tp.addedAt[hash],
+			Executable: true,
+		}, true
+	}
+	if tx, exists := tp.enhanced[hash]; exists {
+		return &PendingTransaction{
+			Hash:       tx.Hash,
+			Type:       tx.Type,
+			Enhanced:   true,
+			From:       tx.From,
+			To:         tx.To,
+			Amount:     tx.Amount,
+			Fee:        tx.Fee,
+			AddedAt:    tx.Timestamp,
+			Executable: tx.IsExecutable(),
+		}, true
+	}
+	return nil, false
+}
+
+// ListPending returns the pending transactions matching a filter, oldest first
+func (tp *Mempool) ListPending(filter PendingFilter) []PendingTransaction {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+
+	var matched []PendingTransaction
+	add := func(hash string) {
+		if pending, _ := tp.pendingLocked(hash); filter.matches(pending) {
+			matched = append(matched, *pending)
+		}
+	}
+	for hash := range tp.transactions {
+		add(hash)
+	}
+	for hash := range tp.enhanced {
+		add(hash)
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].AddedAt != matched[j].AddedAt {
+			return matched[i].AddedAt < matched[j].AddedAt
+		}
+		return matched[i].Hash < matched[j].Hash
+	})
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+	return matched
+}
+
+// GetPending describes a pending standard or enhanced transaction by hash
+func (tp *Mempool) GetPending(hash string) (*PendingTransaction, bool) {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+	return tp.pendingLocked(hash)
+}
+
+// RemovePending removes a pending transaction on an operator's request, with the pending
+// transactions depending on it. It returns ErrTransactionNotFound if the transaction isn't pending.
+func (tp *Mempool) RemovePending(hash string) ([]PoolDropEvent, error) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	now := time.Now().Unix()
+	if tx, exists := tp.enhanced[hash]; exists {
+		delete(tp.enhanced, hash)
+		tp.publishEnhancedLocked(PoolEventRemove, tx, DropRemoved, "removed by operator")
+		standard := tx.ToStandardTransaction()
+		return []PoolDropEvent{{Transaction: &standard, Reason: DropRemoved, Detail: "removed by operator", Time: now}}, nil
+	}
+
+	tx, exists := tp.transactions[hash]
+	if !exists {
+		return nil, fmt.Errorf("%w in pool", ErrTransactionNotFound)
+	}
+	dependents := tp.dependentsLocked(hash)
+	tp.removeLocked(tx)
+	events := []PoolDropEvent{{Transaction: tx, Reason: DropRemoved, Detail: "removed by operator", Time: now}}
+	for _, dependent := range dependents {
+		tp.removeLocked(dependent)
+		events = append(events, PoolDropEvent{Transaction: dependent, Reason: DropParentDropped,
+			Detail: fmt.Sprintf("depends on dropped transaction %s", hash), Time: now})
+	}
+	tp.publishDropsLocked(events)
+	return events, nil
+}
+
+// ListPendingTransactions returns the pending transactions matching a filter, oldest first
+func (bc *Blockchain) ListPendingTransactions(filter PendingFilter) []PendingTransaction {
+	return bc.TransactionPool.ListPending(filter)
+}
+
+// InspectPendingTransaction describes a pending standard or enhanced transaction by hash
+func (bc *Blockchain) InspectPendingTransaction(hash string) (*PendingTransaction, error) {
+	if pending, exists := bc.TransactionPool.GetPending(hash); exists {
+		return pending, nil
+	}
+	return nil, fmt.Errorf("%w in pool", ErrTransactionNotFound)
+}
+
+// RemovePendingTransaction removes a pending transaction and those depending on it
+func (bc *Blockchain) RemovePendingTransaction(hash string) ([]PoolDropEvent, error) {
+	return bc.TransactionPool.RemovePending(hash)
+}
+
+// ListPendingTransactions returns the pending transactions matching a filter, oldest first
+func (pbc *PersistentBlockchain) ListPendingTransactions(filter PendingFilter) []PendingTransaction {
+	return pbc.TransactionPool.ListPending(filter)
+}
+
+// InspectPendingTransaction describes a pending standard or enhanced transaction by hash
+func (pbc *PersistentBlockchain) InspectPendingTransaction(hash string) (*PendingTransaction, error) {
+	if pending, exists := pbc.TransactionPool.GetPending(hash); exists {
+		return pending, nil
+	}
+	return nil, fmt.Errorf("%w in pool", ErrTransactionNotFound)
+}
+
+// RemovePendingTransaction removes a pending transaction and those depending on it, logging
+// every removal
+func (pbc *PersistentBlockchain) RemovePendingTransaction(hash string) ([]PoolDropEvent, error) {
+	events, err := pbc.TransactionPool.RemovePending(hash)
+	for _, event := range events {
+		log.Printf("Dropped pending transaction %s (%s): %s", event.Transaction.Hash, event.Reason, event.Detail)
+	}
+	return events, err
+}
@@ -0,0 +1,160 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// AdmissionSourceRestore is the source of transactions re-admitted from a saved pool
+const AdmissionSourceRestore = "restore"
+
+// MempoolEntry is a pending transaction saved with the time it entered the pool
+type MempoolEntry struct {
+	Transaction *Transaction `json:"transaction"`
+	AddedAt     int64        `json:"addedAt"`
+}
+
+// MempoolStore saves and loads the pending transactions of a pool. Storage implements it.
+type MempoolStore interface {
+	SaveMempool(entries []MempoolEntry) error
+	LoadMempool() ([]MempoolEntry, error)
+}
+
+// Persist saves the pending transactions, replacing those saved before. Coinbase transactions
+// are left out: the miner creates them again for the next block.
+func (tp *Mempool) Persist(store MempoolStore) error {
+	tp.mu.RLock()
+	entries := make([]MempoolEntry, 0, len(tp.transactions))
+	for hash, tx := range tp.transactions {
+		if !tx.IsCoinbase() {
+			entries = append(entries, MempoolEntry{Transaction: tx, AddedAt: tp.addedAt[hash]})
+		}
+	}
+	tp.mu.RUnlock()
+
+	return store.SaveMempool(entries)
+}
+
+// Restore re-admits saved transactions through the pool's validation, oldest first, so those
+// confirmed or no longer valid against the current chain are dropped. Restored transactions
+// keep the time they first entered the pool. It returns the transactions restored and dropped.
+func (tp *Mempool) Restore(store MempoolStore) (restored, dropped int, err error) {
+	entries, err := store.LoadMempool()
+	if err != nil {
+		return 0, 0, err
+	}
+	valid := entries[:0]
+	for _, entry := range entries {
+		if entry.Transaction != nil {
+			valid = append(valid, entry)
+		}
+	}
+	dropped = len(entries) - len(valid)
+
+	// Transactions that entered the pool together are admitted in nonce order
+	sort.SliceStable(valid, func(i, j int) bool {
+		a, b := valid[i], valid[j]
+		if a.AddedAt != b.AddedAt {
+			return a.AddedAt < b.AddedAt
+		}
+		if a.Transaction.From != b.Transaction.From {
+			return a.Transaction.From < b.Transaction.From
+		}
+		return a.Transaction.Nonce < b.Transaction.Nonce
+	})
+
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	restored, refused := tp.readmitLocked(valid, AdmissionSourceRestore)
+	return restored, dropped + refused, nil
+}
+
+// readmitLocked admits transactions that left the pool, in order, recording the decisions
+// against a source. Entries with an AddedAt keep it. It returns the transactions admitted and
+// refused (caller must hold the lock).
+func (tp *Mempool) readmitLocked(entries []MempoolEntry, source string) (admitted, refused int) {
+	for len(entries) > 0 {
+		// Transactions spending what other pending transactions credit are retried while
+		// admitting the others lets more of them in
+		var deferred []MempoolEntry
+		var errs []error
+		progress := false
+		for _, entry := range entries {
+			tx := entry.Transaction
+			replaced, err := tp.addLocked(tx)
+			if AdmissionReasonOf(err) == AdmissionInsufficientBalance {
+				deferred, errs = append(deferred, entry), append(errs, err)
+				continue
+			}
+			tp.admissions.record(tx, source, replaced, err)
+			if err != nil {
+				refused++
+				continue
+			}
+			if entry.AddedAt > 0 {
+				tp.addedAt[tx.Hash] = entry.AddedAt
+			}
+			admitted++
+			progress = true
+		}
+		if !progress {
+			for i, entry := range deferred {
+				tp.admissions.record(entry.Transaction, source, nil, errs[i])
+			}
+			refused += len(deferred)
+			break
+		}
+		entries = deferred
+	}
+	return admitted, refused
+}
+
+// SaveMempool replaces the saved pending transactions
+func (d *Database) SaveMempool(entries []MempoolEntry) error {
+	rows := make([][]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		data, err := json.Marshal(entry.Transaction)
+		if err != nil {
+			return fmt.Errorf("failed to serialize transaction: %w", err)
+		}
+		rows = append(rows, []interface{}{entry.Transaction.Hash, string(data), entry.AddedAt})
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM mempool"); err != nil {
+		return fmt.Errorf("failed to clear mempool: %w", err)
+	}
+	if err := d.insertRows(tx, "mempool (hash, transaction_data, added_at)", "", rows); err != nil {
+		return fmt.Errorf("failed to save mempool: %w", err)
+	}
+	return tx.Commit()
+}
+
+// LoadMempool returns the saved pending transactions, oldest first
+func (d *Database) LoadMempool() ([]MempoolEntry, error) {
+	rows, err := d.db.Query("SELECT transaction_data, added_at FROM mempool ORDER BY added_at, hash")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load mempool: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []MempoolEntry
+	for rows.Next() {
+		var data string
+		entry := MempoolEntry{Transaction: &Transaction{}}
+		if err := rows.Scan(&data, &entry.AddedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(data), entry.Transaction); err != nil {
+			return nil, fmt.Errorf("failed to deserialize transaction: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
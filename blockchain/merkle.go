@@ -1,14 +1,28 @@
 package blockchain
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
 	"errors"
+	"fmt"
+
+	"blockchain/blockchain/verify"
+)
+
+// MerkleScheme selects how a Merkle tree hashes its leaves and pairs the nodes of odd levels
+type MerkleScheme int
+
+// Merkle tree schemes
+const (
+	LegacyMerkleScheme MerkleScheme = verify.MerkleLegacy // Leaves hashed like inner nodes, odd levels duplicate their last node
+	TaggedMerkleScheme MerkleScheme = verify.MerkleTagged // Leaves and inner nodes domain-separated, odd levels promote their last node
 )
 
 // MerkleTree represents a Merkle tree
 type MerkleTree struct {
-	Root *MerkleNode
+	Root   *MerkleNode
+	scheme MerkleScheme
+	sorted bool           // Leaves in increasing order without duplicates (see NewSortedMerkleTree)
+	leaves []string       // Leaf hashes as given, before any tagging
+	levels [][]MerkleNode // Nodes of every level in order, from the leaves up to the root
 }
 
 // MerkleNode represents a node in the Merkle tree
@@ -21,52 +35,103 @@ type MerkleNode struct {
 
 // NewMerkleTree creates a new Merkle tree from transaction data
 func NewMerkleTree(transactions []Transaction) *MerkleTree {
+	return NewSchemeMerkleTree(transactions, LegacyMerkleScheme)
+}
+
+// NewSchemeMerkleTree creates a Merkle tree from transaction data under a scheme
+func NewSchemeMerkleTree(transactions []Transaction, scheme MerkleScheme) *MerkleTree {
 	if len(transactions) == 0 {
-		return &MerkleTree{Root: nil}
+		return &MerkleTree{Root: nil, scheme: scheme}
 	}
 
-	// Create leaf nodes from transactions
-	var nodes []*MerkleNode
-	for _, tx := range transactions {
-		node := &MerkleNode{
-			Hash: tx.Hash,
-			Data: []byte(tx.Hash),
-		}
-		nodes = append(nodes, node)
+	hashes := make([]string, len(transactions))
+	for i, tx := range transactions {
+		hashes[i] = tx.Hash
 	}
+	return newMerkleTreeFromHashes(hashes, scheme)
+}
 
-	// If odd number of transactions, duplicate the last one
-	if len(nodes)%2 != 0 {
-		nodes = append(nodes, nodes[len(nodes)-1])
+// newMerkleTreeFromHashes creates a Merkle tree over precomputed leaf hashes
+func newMerkleTreeFromHashes(hashes []string, scheme MerkleScheme) *MerkleTree {
+	if len(hashes) == 0 {
+		return &MerkleTree{Root: nil, scheme: scheme}
 	}
+	tagged := scheme == TaggedMerkleScheme
+
+	// Every node of the tree comes from one allocation: the leaves, then each level's parents
+	slab := make([]MerkleNode, merkleNodeCount(len(hashes), scheme))
+	nodes := make([]*MerkleNode, len(hashes), len(hashes)+1)
 
-	// Build the tree bottom-up
-	for len(nodes) > 1 {
-		var nextLevel []*MerkleNode
+	// Create leaf nodes from the hashes
+	for i, hash := range hashes {
+		slab[i] = MerkleNode{Hash: hash, Data: []byte(hash)}
+		if tagged {
+			slab[i].Hash = verify.TaggedLeafHash(hash)
+		}
+		nodes[i] = &slab[i]
+	}
+	levels := [][]MerkleNode{slab[:len(hashes)]}
+	slab = slab[len(hashes):]
+
+	scratch := getHashScratch()
+	defer putHashScratch(scratch)
+
+	// Build the tree bottom-up, each level overwriting the one below it in nodes.
+	// A lone leaf still gets a parent hashing it with itself, unless the tree is tagged.
+	for !tagged || len(nodes) > 1 {
+		width := (len(nodes) + 1) / 2
+		// If odd number of nodes, duplicate the last one, or promote it to a copy in the next level
+		if len(nodes)%2 != 0 {
+			last := nodes[len(nodes)-1]
+			if tagged {
+				slab[width-1] = *last
+			}
+			nodes = append(nodes, last)
+		}
 
 		for i := 0; i < len(nodes); i += 2 {
 			left := nodes[i]
 			right := nodes[i+1]
 
-			parent := &MerkleNode{
-				Left:  left,
-				Right: right,
-				Hash:  calculateNodeHash(left.Hash, right.Hash),
+			parent := &slab[i/2]
+			switch {
+			case !tagged:
+				*parent = MerkleNode{Left: left, Right: right, Hash: scratch.nodeHash(left.Hash, right.Hash)}
+			case left != right:
+				*parent = MerkleNode{Left: left, Right: right, Hash: scratch.taggedNodeHash(left.Hash, right.Hash)}
 			}
-			nextLevel = append(nextLevel, parent)
+			nodes[i/2] = parent
 		}
 
-		nodes = nextLevel
+		levels = append(levels, slab[:width])
+		slab = slab[width:]
+		nodes = nodes[:width]
+		if len(nodes) == 1 {
+			break
+		}
 	}
+	return &MerkleTree{Root: nodes[0], scheme: scheme, leaves: hashes, levels: levels}
+}
 
-	return &MerkleTree{Root: nodes[0]}
+// merkleNodeCount returns the number of distinct nodes in a tree over some leaves: the leaves
+// and the parents of every level, up to the root
+func merkleNodeCount(leaves int, scheme MerkleScheme) int {
+	total := leaves
+	if scheme == TaggedMerkleScheme && leaves == 1 {
+		return total
+	}
+	for width := leaves; ; {
+		width = (width + 1) / 2
+		total += width
+		if width == 1 {
+			return total
+		}
+	}
 }
 
 // calculateNodeHash calculates the hash of two child nodes
 func calculateNodeHash(leftHash, rightHash string) string {
-	data := leftHash + rightHash
-	hash := sha256.Sum256([]byte(data))
-	return hex.EncodeToString(hash[:])
+	return verify.NodeHash(leftHash, rightHash)
 }
 
 // GetMerkleRoot returns the root hash of the Merkle tree
@@ -79,114 +144,80 @@ func (mt *MerkleTree) GetMerkleRoot() string {
 
 // MerkleProof represents a proof that a transaction exists in the tree
 type MerkleProof struct {
-	Hash   string   `json:"hash"`
-	Hashes []string `json:"hashes"`
-	IsLeft []bool   `json:"isLeft"` // Changed from Indices to IsLeft for clarity
+	Hash   string       `json:"hash"`
+	Index  int          `json:"index"` // Position of the leaf, whose bits from the lowest up match IsLeft in a legacy tree
+	Hashes []string     `json:"hashes"`
+	IsLeft []bool       `json:"isLeft"`           // Changed from Indices to IsLeft for clarity
+	Scheme MerkleScheme `json:"scheme,omitempty"` // Scheme of the tree, which verification follows
 }
 
-// GenerateProof generates a Merkle proof for a given transaction hash
+// GenerateProof generates a Merkle proof for a given transaction hash. A hash appearing
+// more than once is proven at its first position; use GenerateProofByIndex for the others.
 func (mt *MerkleTree) GenerateProof(txHash string) (*MerkleProof, error) {
 	if mt.Root == nil {
 		return nil, errors.New("empty tree")
 	}
 
-	proof := &MerkleProof{
-		Hash:   txHash,
-		Hashes: make([]string, 0),
-		IsLeft: make([]bool, 0),
-	}
-
-	found := mt.buildProof(mt.Root, txHash, proof)
-	if !found {
-		return nil, errors.New("transaction not found in tree")
+	for i, leaf := range mt.leaves {
+		if leaf == txHash {
+			return mt.GenerateProofByIndex(i)
+		}
 	}
-
-	return proof, nil
+	return nil, errors.New("transaction not found in tree")
 }
 
-// buildProof builds the proof path from leaf to root
-func (mt *MerkleTree) buildProof(node *MerkleNode, txHash string, proof *MerkleProof) bool {
-	if node == nil {
-		return false
-	}
-
-	// If this is a leaf node, check if it matches our target
-	if node.Left == nil && node.Right == nil {
-		return node.Hash == txHash
+// GenerateProofByIndex generates a Merkle proof for the leaf at a position, walking one node
+// per level up to the root
+func (mt *MerkleTree) GenerateProofByIndex(index int) (*MerkleProof, error) {
+	if mt.Root == nil {
+		return nil, errors.New("empty tree")
 	}
-
-	// Check left subtree
-	if node.Left != nil && mt.buildProof(node.Left, txHash, proof) {
-		// Found in left subtree, add right sibling to proof
-		if node.Right != nil {
-			proof.Hashes = append(proof.Hashes, node.Right.Hash)
-			proof.IsLeft = append(proof.IsLeft, false) // Right sibling, we are left
-		}
-		return true
+	if index < 0 || index >= len(mt.leaves) {
+		return nil, fmt.Errorf("leaf index %d out of range (%d leaves)", index, len(mt.leaves))
 	}
 
-	// Check right subtree
-	if node.Right != nil && mt.buildProof(node.Right, txHash, proof) {
-		// Found in right subtree, add left sibling to proof
-		if node.Left != nil {
-			proof.Hashes = append(proof.Hashes, node.Left.Hash)
-			proof.IsLeft = append(proof.IsLeft, true) // Left sibling, we are right
+	depth := len(mt.levels) - 1
+	proof := &MerkleProof{
+		Hash:   mt.leaves[index],
+		Index:  index,
+		Hashes: make([]string, 0, depth),
+		IsLeft: make([]bool, 0, depth),
+		Scheme: mt.scheme,
+	}
+	position := index
+	for _, level := range mt.levels[:depth] {
+		sibling := position ^ 1
+		if sibling >= len(level) {
+			// The last node of an odd level is promoted, or paired with itself in a legacy tree
+			if mt.scheme == TaggedMerkleScheme {
+				position /= 2
+				continue
+			}
+			sibling = position
 		}
-		return true
+		proof.Hashes = append(proof.Hashes, level[sibling].Hash)
+		proof.IsLeft = append(proof.IsLeft, position%2 == 1)
+		position /= 2
 	}
-
-	return false
+	return proof, nil
 }
 
-// VerifyProof verifies a Merkle proof against the root hash
-func VerifyProof(proof *MerkleProof, rootHash string) bool {
-	if len(proof.Hashes) != len(proof.IsLeft) {
+// VerifyProof verifies a Merkle proof against the root hash of a tree built under a scheme.
+// The scheme comes from what the caller trusts, e.g. the version of a block header: a proof
+// naming the other one is rejected, since a legacy proof can pass an inner node off as a leaf.
+func VerifyProof(proof *MerkleProof, rootHash string, scheme MerkleScheme) bool {
+	if CheckProofLimits(proof) != nil || proof.Scheme != scheme {
 		return false
 	}
-
-	currentHash := proof.Hash
-
-	// Reconstruct the path to root (bottom-up)
-	for i := 0; i < len(proof.Hashes); i++ {
-		siblingHash := proof.Hashes[i]
-		isLeft := proof.IsLeft[i]
-
-		if isLeft {
-			// Sibling is left, we are right
-			currentHash = calculateNodeHash(siblingHash, currentHash)
-		} else {
-			// Sibling is right, we are left
-			currentHash = calculateNodeHash(currentHash, siblingHash)
-		}
-	}
-
-	return currentHash == rootHash
+	return verify.VerifySchemeProof(int(scheme), proof.Hash, proof.Hashes, proof.IsLeft, rootHash)
 }
 
 // GetTransactionHashes returns all transaction hashes in the tree (for debugging)
 func (mt *MerkleTree) GetTransactionHashes() []string {
-	if mt.Root == nil {
-		return []string{}
-	}
-
-	var hashes []string
-	mt.collectLeafHashes(mt.Root, &hashes)
-	return hashes
+	return append([]string{}, mt.leaves...)
 }
 
-// collectLeafHashes recursively collects all leaf node hashes
-func (mt *MerkleTree) collectLeafHashes(node *MerkleNode, hashes *[]string) {
-	if node == nil {
-		return
-	}
-
-	// If this is a leaf node
-	if node.Left == nil && node.Right == nil {
-		*hashes = append(*hashes, node.Hash)
-		return
-	}
-
-	// Recursively collect from children
-	mt.collectLeafHashes(node.Left, hashes)
-	mt.collectLeafHashes(node.Right, hashes)
+// Scheme returns the scheme the tree was built under
+func (mt *MerkleTree) Scheme() MerkleScheme {
+	return mt.scheme
 }
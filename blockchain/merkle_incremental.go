@@ -0,0 +1,131 @@
+package blockchain
+
+import (
+	"fmt"
+
+	"blockchain/blockchain/verify"
+)
+
+// IncrementalMerkleTree computes the Merkle root of a growing list of leaves, e.g. the
+// transactions of a block template as a miner fills it. It keeps the hash of every subtree
+// whose leaves are all known, so adding a leaf hashes at most one node per level and the
+// root folds the incomplete right edge in one step per level, where building a MerkleTree
+// rehashes everything. Its root matches NewSchemeMerkleTree over the same leaves.
+type IncrementalMerkleTree struct {
+	scheme MerkleScheme
+	leaves []string
+	levels [][]string // levels[h] holds the subtrees of 2^h leaves completed so far, left to right
+}
+
+// NewIncrementalMerkleTree creates an empty incremental tree under a scheme
+func NewIncrementalMerkleTree(scheme MerkleScheme) *IncrementalMerkleTree {
+	return &IncrementalMerkleTree{scheme: scheme}
+}
+
+// Len returns the number of leaves
+func (t *IncrementalMerkleTree) Len() int {
+	return len(t.leaves)
+}
+
+// Leaves returns the leaf hashes in order
+func (t *IncrementalMerkleTree) Leaves() []string {
+	return append([]string{}, t.leaves...)
+}
+
+// AddLeaf appends a leaf hash, completing the subtrees it closes
+func (t *IncrementalMerkleTree) AddLeaf(hash string) {
+	t.leaves = append(t.leaves, hash)
+	node := hash
+	if t.scheme == TaggedMerkleScheme {
+		node = verify.TaggedLeafHash(hash)
+	}
+	for height := 0; ; height++ {
+		if height == len(t.levels) {
+			t.levels = append(t.levels, nil)
+		}
+		t.levels[height] = append(t.levels[height], node)
+		level := t.levels[height]
+		if len(level)%2 != 0 {
+			return
+		}
+		node = t.nodeHash(level[len(level)-2], level[len(level)-1])
+	}
+}
+
+// AddTransactions appends the hashes of transactions
+func (t *IncrementalMerkleTree) AddTransactions(transactions []Transaction) {
+	for i := range transactions {
+		t.AddLeaf(transactions[i].Hash)
+	}
+}
+
+// Truncate drops the leaves from position n on, e.g. transactions evicted from a template.
+// The subtrees left of n stay cached, so it costs no hashing.
+func (t *IncrementalMerkleTree) Truncate(n int) error {
+	if n < 0 || n > len(t.leaves) {
+		return fmt.Errorf("cannot truncate %d leaves to %d", len(t.leaves), n)
+	}
+	t.leaves = t.leaves[:n]
+	for height := range t.levels {
+		t.levels[height] = t.levels[height][:n>>height]
+	}
+	return nil
+}
+
+// Root returns the Merkle root over the leaves ("" for none)
+func (t *IncrementalMerkleTree) Root() string {
+	if len(t.leaves) == 0 {
+		return ""
+	}
+
+	// Carry the node over the leaves right of the last complete subtree up the levels
+	carry, carried := "", false
+	for height := 0; ; height++ {
+		var level []string
+		if height < len(t.levels) {
+			level = t.levels[height]
+		}
+		width := len(level)
+		if carried {
+			width++
+		}
+		if width == 1 && (height > 0 || t.scheme == TaggedMerkleScheme) {
+			if carried {
+				return carry
+			}
+			return level[0]
+		}
+
+		switch {
+		case len(level)%2 != 0 && carried:
+			carry = t.nodeHash(level[len(level)-1], carry)
+		case len(level)%2 != 0:
+			carry, carried = t.lastNode(level[len(level)-1]), true
+		case carried:
+			carry = t.lastNode(carry)
+		}
+	}
+}
+
+// lastNode returns the parent of the last node of an odd level: the node itself promoted in
+// a tagged tree, or hashed with itself in a legacy one
+func (t *IncrementalMerkleTree) lastNode(node string) string {
+	if t.scheme == TaggedMerkleScheme {
+		return node
+	}
+	return t.nodeHash(node, node)
+}
+
+// nodeHash returns the hash of an inner node under the tree's scheme
+func (t *IncrementalMerkleTree) nodeHash(leftHash, rightHash string) string {
+	if t.scheme == TaggedMerkleScheme {
+		return verify.TaggedNodeHash(leftHash, rightHash)
+	}
+	return verify.NodeHash(leftHash, rightHash)
+}
+
+// Tree builds the full Merkle tree over the leaves, e.g. to prove transactions of the
+// finished block
+func (t *IncrementalMerkleTree) Tree() *MerkleTree {
+	return newMerkleTreeFromHashes(t.Leaves(), t.scheme)
+}
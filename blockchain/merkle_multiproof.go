@@ -0,0 +1,116 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"blockchain/blockchain/verify"
+)
+
+// MultiProof proves several leaves of one Merkle tree at once. Siblings shared by the paths
+// of the leaves, and nodes computed from the leaves themselves, appear once or not at all, so
+// it is smaller than the separate proofs and verified in one pass.
+type MultiProof struct {
+	Leaves    []string `json:"leaves"`    // Proven hashes, in leaf order
+	Indices   []int    `json:"indices"`   // Position of each proven leaf, strictly increasing
+	LeafCount int      `json:"leafCount"` // Leaves of the tree, which fixes where odd levels pair a node with itself
+	Hashes    []string `json:"hashes"`    // Sibling hashes in the order a level-by-level walk from the leaves consumes them
+
+	Scheme MerkleScheme `json:"scheme,omitempty"` // Scheme of the tree, which verification follows
+}
+
+// GenerateMultiProof generates one proof for several transaction hashes. A hash appearing
+// more than once in the tree is proven at its first position.
+func (mt *MerkleTree) GenerateMultiProof(txHashes []string) (*MultiProof, error) {
+	if mt.Root == nil {
+		return nil, errors.New("empty tree")
+	}
+	if len(txHashes) == 0 {
+		return nil, errors.New("no transactions to prove")
+	}
+
+	first := make(map[string]int, len(mt.leaves))
+	for i := len(mt.leaves) - 1; i >= 0; i-- {
+		first[mt.leaves[i]] = i
+	}
+	indices := make([]int, 0, len(txHashes))
+	for _, txHash := range txHashes {
+		index, exists := first[txHash]
+		if !exists {
+			return nil, fmt.Errorf("transaction %s not found in tree", txHash)
+		}
+		indices = append(indices, index)
+	}
+	return mt.GenerateMultiProofByIndex(indices)
+}
+
+// GenerateMultiProofByIndex generates one proof for the leaves at several positions, given in
+// any order. Repeated positions are proven once.
+func (mt *MerkleTree) GenerateMultiProofByIndex(indices []int) (*MultiProof, error) {
+	if mt.Root == nil {
+		return nil, errors.New("empty tree")
+	}
+	if len(indices) == 0 {
+		return nil, errors.New("no transactions to prove")
+	}
+
+	positions := slices.Clone(indices)
+	slices.Sort(positions)
+	positions = slices.Compact(positions)
+	leafCount := len(mt.leaves)
+	if positions[0] < 0 || positions[len(positions)-1] >= leafCount {
+		return nil, fmt.Errorf("leaf index out of range (%d leaves)", leafCount)
+	}
+
+	proof := &MultiProof{
+		Leaves:    make([]string, len(positions)),
+		Indices:   append([]int(nil), positions...),
+		LeafCount: leafCount,
+		Hashes:    make([]string, 0),
+		Scheme:    mt.scheme,
+	}
+	for i, position := range positions {
+		proof.Leaves[i] = mt.leaves[position]
+	}
+
+	// Walk the levels as VerifyMultiProof does, recording the siblings it cannot compute
+	for _, level := range mt.levels[:len(mt.levels)-1] {
+		next := positions[:0:0]
+		for i := 0; i < len(positions); i++ {
+			position := positions[i]
+			switch {
+			case position%2 == 1:
+				proof.Hashes = append(proof.Hashes, level[position-1].Hash)
+			case position+1 == len(level):
+				// The last node of an odd level is promoted, or paired with itself in a legacy tree
+			case i+1 < len(positions) && positions[i+1] == position+1:
+				i++
+			default:
+				proof.Hashes = append(proof.Hashes, level[position+1].Hash)
+			}
+			next = append(next, position/2)
+		}
+		positions = next
+	}
+	return proof, nil
+}
+
+// VerifyMultiProof verifies a multi-leaf Merkle proof against the root hash of a tree built
+// under a scheme the caller trusts, like VerifyProof
+func VerifyMultiProof(proof *MultiProof, rootHash string, scheme MerkleScheme) bool {
+	if CheckMultiProofLimits(proof) != nil || proof.Scheme != scheme {
+		return false
+	}
+	return verify.VerifyMultiProof(int(scheme), proof.Leaves, proof.Indices, proof.LeafCount, proof.Hashes, rootHash)
+}
+
+// GenerateTransactionMultiProof generates one Merkle proof for several transactions of the block
+func (b *Block) GenerateTransactionMultiProof(txHashes []string) (*MultiProof, error) {
+	return b.merkleTree().GenerateMultiProof(txHashes)
+}
+
+// VerifyTransactionMultiProof verifies that several transactions exist in this block
+func (b *Block) VerifyTransactionMultiProof(proof *MultiProof) bool {
+	return VerifyMultiProof(proof, b.MerkleRoot, b.MerkleScheme())
+}
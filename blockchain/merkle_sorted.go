@@ -0,0 +1,150 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"blockchain/blockchain/verify"
+)
+
+// SortedTransactionsExtension is the block extension committing the sorted Merkle root of the
+// block's transaction hashes (see SortedTransactionsHandler)
+const SortedTransactionsExtension = "txs.sorted"
+
+// AbsenceProof proves a hash is not a leaf of a sorted Merkle tree by proving the leaves on
+// either side of where it would sit, at adjacent positions. Next to the first or last leaf
+// only one side is proven, and an empty tree needs no leaves.
+type AbsenceProof struct {
+	Hash      string       `json:"hash"` // Hash proven absent
+	LeafCount int          `json:"leafCount"`
+	Left      *MerkleProof `json:"left,omitempty"`  // Greatest leaf below Hash, nil if Hash sorts first
+	Right     *MerkleProof `json:"right,omitempty"` // Least leaf above Hash, nil if Hash sorts last
+}
+
+// NewSortedMerkleTree creates a Merkle tree over hashes in increasing order with duplicates
+// dropped, whose proofs of adjacent leaves show that no hash sits between them. Sorted trees
+// are always domain-separated: in a legacy tree an inner node passes for a leaf, so a proof
+// could name one as the neighbour of a hash that is present.
+func NewSortedMerkleTree(hashes []string) *MerkleTree {
+	leaves := slices.Clone(hashes)
+	slices.Sort(leaves)
+	leaves = slices.Compact(leaves)
+	tree := newMerkleTreeFromHashes(leaves, TaggedMerkleScheme)
+	tree.sorted = true
+	return tree
+}
+
+// GenerateAbsenceProof proves a hash is not a leaf of a sorted tree
+func (mt *MerkleTree) GenerateAbsenceProof(hash string) (*AbsenceProof, error) {
+	if !mt.sorted {
+		return nil, errors.New("absence can only be proven in a sorted tree")
+	}
+
+	i := sort.SearchStrings(mt.leaves, hash)
+	if i < len(mt.leaves) && mt.leaves[i] == hash {
+		return nil, fmt.Errorf("hash %s is in the tree", hash)
+	}
+
+	proof := &AbsenceProof{Hash: hash, LeafCount: len(mt.leaves)}
+	var err error
+	if i > 0 {
+		if proof.Left, err = mt.GenerateProofByIndex(i - 1); err != nil {
+			return nil, err
+		}
+	}
+	if i < len(mt.leaves) {
+		if proof.Right, err = mt.GenerateProofByIndex(i); err != nil {
+			return nil, err
+		}
+	}
+	return proof, nil
+}
+
+// VerifyAbsenceProof verifies that a hash is not a leaf of the sorted tree with the given root.
+// Leaf proofs must be domain-separated, like every sorted tree.
+func VerifyAbsenceProof(proof *AbsenceProof, rootHash string) bool {
+	if proof == nil || proof.LeafCount < 0 {
+		return false
+	}
+	if proof.LeafCount == 0 {
+		return rootHash == "" && proof.Left == nil && proof.Right == nil
+	}
+	if proof.Left == nil && proof.Right == nil {
+		return false
+	}
+
+	if proof.Left != nil {
+		// The left leaf sorts below the hash and is the last leaf unless a right one follows
+		if !verifyPositionedProof(proof.Left, proof.LeafCount, rootHash) || proof.Left.Hash >= proof.Hash {
+			return false
+		}
+		if proof.Right == nil && proof.Left.Index != proof.LeafCount-1 {
+			return false
+		}
+	}
+	if proof.Right != nil {
+		if !verifyPositionedProof(proof.Right, proof.LeafCount, rootHash) || proof.Right.Hash <= proof.Hash {
+			return false
+		}
+		if proof.Left == nil && proof.Right.Index != 0 {
+			return false
+		}
+	}
+	if proof.Left != nil && proof.Right != nil {
+		return proof.Left.Index+1 == proof.Right.Index
+	}
+	return true
+}
+
+// verifyPositionedProof verifies a proof is the path of the leaf at its index in a sorted tree
+// of leafCount leaves
+func verifyPositionedProof(proof *MerkleProof, leafCount int, rootHash string) bool {
+	if proof.Scheme != TaggedMerkleScheme || CheckProofLimits(proof) != nil {
+		return false
+	}
+	return verify.VerifyPositionedProof(verify.MerkleTagged, proof.Hash, proof.Index, leafCount, proof.Hashes, proof.IsLeft, rootHash)
+}
+
+// sortedTransactionTree builds the sorted tree over the block's transaction hashes
+func (b *Block) sortedTransactionTree() *MerkleTree {
+	hashes := make([]string, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		hashes[i] = tx.Hash
+	}
+	return NewSortedMerkleTree(hashes)
+}
+
+// SortedTransactionsHandler returns the handler of SortedTransactionsExtension: mined blocks
+// commit the sorted Merkle root of their transaction hashes and connected blocks must carry the
+// right one, so a node can prove a transaction is not in a block. Required rejects blocks
+// without the extension.
+func SortedTransactionsHandler(required bool) ExtensionHandler {
+	return ExtensionHandler{
+		Produce: func(block *Block) (string, bool) {
+			return block.sortedTransactionTree().GetMerkleRoot(), true
+		},
+		Validate: func(block *Block, data string) error {
+			if data != block.sortedTransactionTree().GetMerkleRoot() {
+				return errors.New("sorted transaction root does not match the transactions")
+			}
+			return nil
+		},
+		Required: required,
+	}
+}
+
+// GenerateTransactionAbsenceProof proves a transaction is not in the block, against the sorted
+// root its SortedTransactionsExtension commits
+func (b *Block) GenerateTransactionAbsenceProof(txHash string) (*AbsenceProof, error) {
+	root, ok := b.Extension(SortedTransactionsExtension)
+	if !ok {
+		return nil, fmt.Errorf("block %d does not commit its sorted transactions", b.Index)
+	}
+	tree := b.sortedTransactionTree()
+	if tree.GetMerkleRoot() != root {
+		return nil, fmt.Errorf("block %d commits a different sorted transaction root", b.Index)
+	}
+	return tree.GenerateAbsenceProof(txHash)
+}
@@ -0,0 +1,98 @@
+package blockchain
+
+import (
+	"fmt"
+	"testing"
+
+	"blockchain/blockchain/verify"
+)
+
+// sortedLeaves returns distinct leaf hashes for a test tree
+func sortedLeaves(seed, count int) []string {
+	leaves := make([]string, count)
+	for i := range leaves {
+		leaves[i] = verify.HashEncoding([]byte(fmt.Sprintf("leaf-%d-%d", seed, i)))
+	}
+	return leaves
+}
+
+func TestAbsenceProof(t *testing.T) {
+	for count := 0; count <= 9; count++ {
+		tree := NewSortedMerkleTree(sortedLeaves(count, count))
+		root := tree.GetMerkleRoot()
+
+		for _, leaf := range tree.leaves {
+			if _, err := tree.GenerateAbsenceProof(leaf); err == nil {
+				t.Fatalf("%d leaves: proved a present leaf absent", count)
+			}
+		}
+		for i := 0; i < 20; i++ {
+			hash := verify.HashEncoding([]byte(fmt.Sprintf("absent-%d", i)))
+			proof, err := tree.GenerateAbsenceProof(hash)
+			if err != nil {
+				t.Fatalf("%d leaves: %v", count, err)
+			}
+			if !VerifyAbsenceProof(proof, root) {
+				t.Fatalf("%d leaves: absence proof of %s does not verify", count, hash)
+			}
+		}
+	}
+}
+
+// TestAbsenceProofForgedNeighbours checks a proof naming the inner nodes of a tree as adjacent
+// leaves of a smaller one doesn't prove a present leaf absent. In a legacy tree the forgery
+// verifies, which is why sorted trees are domain-separated.
+func TestAbsenceProofForgedNeighbours(t *testing.T) {
+	forged := 0
+	for seed := 0; seed < 200; seed++ {
+		leaves := sortedLeaves(seed, 4)
+		for _, scheme := range []MerkleScheme{LegacyMerkleScheme, TaggedMerkleScheme} {
+			tree := newMerkleTreeFromHashes(NewSortedMerkleTree(leaves).leaves, scheme)
+			left, right := tree.levels[1][0].Hash, tree.levels[1][1].Hash
+			for _, present := range tree.leaves {
+				if left >= present || present >= right {
+					continue
+				}
+				proof := &AbsenceProof{
+					Hash:      present,
+					LeafCount: 2,
+					Left:      &MerkleProof{Hash: left, Index: 0, Hashes: []string{right}, IsLeft: []bool{false}, Scheme: scheme},
+					Right:     &MerkleProof{Hash: right, Index: 1, Hashes: []string{left}, IsLeft: []bool{true}, Scheme: scheme},
+				}
+				if scheme == LegacyMerkleScheme &&
+					!verify.VerifyPositionedProof(verify.MerkleLegacy, left, 0, 2, []string{right}, []bool{false}, tree.GetMerkleRoot()) {
+					t.Fatal("legacy forgery does not hash to the root")
+				}
+				if VerifyAbsenceProof(proof, tree.GetMerkleRoot()) {
+					t.Fatalf("seed %d: forged proof under scheme %d proves present leaf %s absent", seed, scheme, present)
+				}
+				forged++
+			}
+		}
+	}
+	if forged == 0 {
+		t.Fatal("no forgery attempted")
+	}
+}
+
+func TestSortedTransactionsHandler(t *testing.T) {
+	txs := []Transaction{*NewTransaction("alice", "bob", 1, 0.1), *NewTransaction("bob", "carol", 2, 0.1)}
+	block := NewBlock(1, txs, "0")
+	handler := SortedTransactionsHandler(true)
+	root, ok := handler.Produce(block)
+	if !ok {
+		t.Fatal("no sorted root produced")
+	}
+	block.Extensions = []Extension{{Type: SortedTransactionsExtension, Data: root}}
+
+	proof, err := block.GenerateTransactionAbsenceProof(verify.HashEncoding([]byte("missing")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyAbsenceProof(proof, root) {
+		t.Fatal("transaction absence proof does not verify")
+	}
+	if _, err := block.GenerateTransactionAbsenceProof(txs[0].Hash); err == nil {
+		t.Fatal("proved a confirmed transaction absent")
+	}
+}
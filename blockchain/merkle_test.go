@@ -0,0 +1,43 @@
+package blockchain
+
+import "testing"
+
+func TestVerifyProofScheme(t *testing.T) {
+	leaves := sortedLeaves(0, 5)
+	for _, scheme := range []MerkleScheme{LegacyMerkleScheme, TaggedMerkleScheme} {
+		other := LegacyMerkleScheme
+		if scheme == LegacyMerkleScheme {
+			other = TaggedMerkleScheme
+		}
+		tree := newMerkleTreeFromHashes(leaves, scheme)
+		proof, err := tree.GenerateProofByIndex(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !VerifyProof(proof, tree.GetMerkleRoot(), scheme) {
+			t.Fatalf("scheme %d: proof does not verify", scheme)
+		}
+		if VerifyProof(proof, tree.GetMerkleRoot(), other) {
+			t.Fatalf("scheme %d: proof verifies under scheme %d", scheme, other)
+		}
+
+		// Claiming the expected scheme doesn't help a proof built under the other one
+		relabelled := *proof
+		relabelled.Scheme = other
+		if VerifyProof(&relabelled, tree.GetMerkleRoot(), other) {
+			t.Fatalf("scheme %d: relabelled proof verifies under scheme %d", scheme, other)
+		}
+	}
+
+	// An inner node passes for a leaf of a legacy tree, but not when the caller expects a
+	// domain-separated one
+	tree := newMerkleTreeFromHashes(leaves[:4], LegacyMerkleScheme)
+	left, right := tree.levels[1][0].Hash, tree.levels[1][1].Hash
+	forged := &MerkleProof{Hash: left, Index: 0, Hashes: []string{right}, IsLeft: []bool{false}, Scheme: LegacyMerkleScheme}
+	if !VerifyProof(forged, tree.GetMerkleRoot(), LegacyMerkleScheme) {
+		t.Fatal("legacy forgery does not hash to the root")
+	}
+	if VerifyProof(forged, tree.GetMerkleRoot(), TaggedMerkleScheme) {
+		t.Fatal("legacy proof verifies where a tagged one is expected")
+	}
+}
@@ -0,0 +1,77 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MessagePrefix separates signed messages from transactions. The leading 0x18 byte can't
+// start a transaction signing message (those begin with an address), and the length that
+// follows the prefix keeps one message from being read as another with a different split.
+const MessagePrefix = "\x18Blockchain Signed Message:\n"
+
+// messageSigningBytes returns the bytes signed for a message
+func messageSigningBytes(message []byte) []byte {
+	data := make([]byte, 0, len(MessagePrefix)+20+len(message))
+	data = append(data, MessagePrefix...)
+	data = strconv.AppendInt(data, int64(len(message)), 10)
+	data = append(data, ':')
+	return append(data, message...)
+}
+
+// SignMessage signs an arbitrary payload to prove ownership of the wallet address off-chain,
+// e.g. to an exchange or in a governance vote. The result carries the public key, as
+// "<encoded public key>.<signature>" in hex, so it can be checked with only the address.
+func (w *Wallet) SignMessage(message []byte) (string, error) {
+	if w.signer == nil && w.PrivateKey == nil {
+		return "", ErrNoPrivateKey
+	}
+	signature, err := w.keySigner().Sign(messageSigningBytes(message))
+	if err != nil {
+		return "", err
+	}
+	return w.EncodedPublicKey() + "." + hex.EncodeToString(signature), nil
+}
+
+// VerifyMessage checks a signature made by SignMessage: the embedded public key must
+// derive the address and the signature must cover the message
+func VerifyMessage(address string, message []byte, signature string) error {
+	return DefaultChainParams.VerifyMessage(address, message, signature)
+}
+
+// VerifyMessage checks a signature made by SignMessage, deriving the signer address in the network's format
+func (p *ChainParams) VerifyMessage(address string, message []byte, signature string) error {
+	publicKey, sig, found := strings.Cut(signature, ".")
+	if !found {
+		return errors.New("malformed message signature")
+	}
+	signer, err := p.AddressFromPublicKey(publicKey)
+	if err != nil {
+		return fmt.Errorf("invalid public key: %w", err)
+	}
+	if signer != address {
+		return fmt.Errorf("message was signed by %s, not by %s", signer, address)
+	}
+	if !VerifySignature(publicKey, messageSigningBytes(message), sig) {
+		return errors.New("invalid message signature")
+	}
+	return nil
+}
+
+// SignMessage signs a message with the key of an address held by the daemon
+func (wd *WalletDaemon) SignMessage(address string, message []byte) (string, error) {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+
+	if wd.unlocked == nil {
+		return "", ErrWalletLocked
+	}
+	wallet, exists := wd.unlocked[address]
+	if !exists {
+		return "", fmt.Errorf("no key for address %s", address)
+	}
+	return wallet.SignMessage(message)
+}
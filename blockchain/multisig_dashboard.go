@@ -0,0 +1,107 @@
+package blockchain
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// MultiSigDashboard is the treasury view of a multi-signature address: its balance, the spends
+// waiting for signatures in the enhanced pool, where signatures are collected, and the time
+// left on time-locked spends
+type MultiSigDashboard struct {
+	Address     string            `json:"address"`
+	Balance     float64           `json:"balance"`               // Confirmed balance
+	Reserved    float64           `json:"reserved"`              // Committed to pending standard transactions
+	Pending     []PendingMultiSig `json:"pending"`               // Multi-sig spends, oldest first
+	Locks       []PendingTimeLock `json:"locks"`                 // Time-locked spends, oldest first
+	SpendPolicy *SpendPolicy      `json:"spendPolicy,omitempty"` // Active spend policy of the address
+	Height      int64             `json:"height"`                // Chain height the view was taken at
+	GeneratedAt int64             `json:"generatedAt"`
+}
+
+// PendingMultiSig is a multi-sig spend in the pool and the state of its signatures
+type PendingMultiSig struct {
+	Hash         string   `json:"hash"`
+	To           string   `json:"to"`
+	Amount       float64  `json:"amount"`
+	Fee          float64  `json:"fee"`
+	CreatedAt    int64    `json:"createdAt"`
+	RequiredSigs int      `json:"requiredSigs"`
+	Signed       []string `json:"signed"`  // Signers that signed, in signing order
+	Missing      []string `json:"missing"` // Signers that haven't signed
+	Ready        bool     `json:"ready"`   // Enough signatures, waiting to be mined
+}
+
+// PendingTimeLock is a time-locked spend in the pool
+type PendingTimeLock struct {
+	Hash      string  `json:"hash"`
+	To        string  `json:"to"`
+	Amount    float64 `json:"amount"`
+	Fee       float64 `json:"fee"`
+	LockTime  int64   `json:"lockTime"`  // Unix time the spend becomes executable
+	Remaining int64   `json:"remaining"` // Seconds until LockTime, 0 once it passed
+	Signed    bool    `json:"signed"`
+}
+
+// MultiSigDashboard aggregates the chain and pool state of a multi-signature address for
+// treasury operators
+func (pbc *PersistentBlockchain) MultiSigDashboard(address string) (*MultiSigDashboard, error) {
+	if address == "" {
+		return nil, errors.New("address is required")
+	}
+
+	now := time.Now().Unix()
+	dashboard := &MultiSigDashboard{
+		Address:     address,
+		Balance:     pbc.GetBalance(address),
+		Reserved:    pbc.GetReservedBalance(address),
+		Pending:     []PendingMultiSig{},
+		Locks:       []PendingTimeLock{},
+		Height:      pbc.GetLatestBlock().Index,
+		GeneratedAt: now,
+	}
+	dashboard.SpendPolicy, _ = pbc.GetSpendPolicy(address)
+
+	for _, tx := range pbc.EnhancedPool.GetEnhancedTransactionsFrom(address) {
+		switch tx.Type {
+		case MultiSigTx:
+			dashboard.Pending = append(dashboard.Pending, pendingMultiSig(&tx))
+		case TimeLockTx:
+			dashboard.Locks = append(dashboard.Locks, PendingTimeLock{
+				Hash:      tx.Hash,
+				To:        tx.To,
+				Amount:    tx.Amount,
+				Fee:       tx.Fee,
+				LockTime:  tx.LockTime,
+				Remaining: max(tx.LockTime-now, 0),
+				Signed:    tx.IsFullySigned(),
+			})
+		}
+	}
+	return dashboard, nil
+}
+
+// pendingMultiSig reports the signatures of a multi-sig spend
+func pendingMultiSig(tx *EnhancedTransaction) PendingMultiSig {
+	pending := PendingMultiSig{
+		Hash:         tx.Hash,
+		To:           tx.To,
+		Amount:       tx.Amount,
+		Fee:          tx.Fee,
+		CreatedAt:    tx.Timestamp,
+		RequiredSigs: tx.RequiredSigs,
+		Signed:       []string{},
+		Missing:      []string{},
+		Ready:        tx.IsFullySigned(),
+	}
+	for _, sig := range tx.Signatures {
+		pending.Signed = append(pending.Signed, sig.Signer)
+	}
+	for _, signer := range tx.Signers {
+		if !slices.Contains(pending.Signed, signer) {
+			pending.Missing = append(pending.Missing, signer)
+		}
+	}
+	return pending
+}
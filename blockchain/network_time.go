@@ -0,0 +1,118 @@
+package blockchain
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MaxFutureBlockTime is how far (in seconds) a block timestamp may be ahead of network-adjusted time
+const MaxFutureBlockTime = 2 * 60 * 60
+
+// NetworkTime tracks clock offsets reported by peers and derives a network-adjusted time
+type NetworkTime struct {
+	offsets       map[string]int64 // Offset in seconds (peer time - local time) keyed by node ID
+	offset        int64            // Currently applied offset
+	mu            sync.RWMutex
+	minSamples    int   // Samples required before any adjustment is applied
+	maxAdjustment int64 // Offsets beyond this are not applied, only warned about
+	warnThreshold int64 // Skew that triggers a clock warning
+	warned        bool
+}
+
+// NewNetworkTime creates a network time tracker.
+// Offsets larger than maxAdjustment are never applied since they indicate a badly misconfigured clock.
+func NewNetworkTime(maxAdjustment, warnThreshold time.Duration) *NetworkTime {
+	return &NetworkTime{
+		offsets:       make(map[string]int64),
+		minSamples:    5,
+		maxAdjustment: int64(maxAdjustment / time.Second),
+		warnThreshold: int64(warnThreshold / time.Second),
+	}
+}
+
+// AddSample records the time advertised by a peer
+func (nt *NetworkTime) AddSample(nodeID string, peerTime int64) {
+	nt.mu.Lock()
+	defer nt.mu.Unlock()
+
+	nt.offsets[nodeID] = peerTime - time.Now().Unix()
+	nt.recalculateLocked()
+}
+
+// RemoveSample forgets the offset of a disconnected peer
+func (nt *NetworkTime) RemoveSample(nodeID string) {
+	nt.mu.Lock()
+	defer nt.mu.Unlock()
+
+	if _, exists := nt.offsets[nodeID]; !exists {
+		return
+	}
+	delete(nt.offsets, nodeID)
+	nt.recalculateLocked()
+}
+
+// recalculateLocked updates the applied offset from the median of all samples (caller must hold the lock)
+func (nt *NetworkTime) recalculateLocked() {
+	if len(nt.offsets) < nt.minSamples {
+		nt.offset = 0
+		return
+	}
+
+	samples := make([]int64, 0, len(nt.offsets))
+	for _, offset := range nt.offsets {
+		samples = append(samples, offset)
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	median := samples[len(samples)/2]
+
+	if abs64(median) > nt.warnThreshold {
+		if !nt.warned {
+			log.Printf("Warning: local clock differs from network median by %d seconds, please check your system time", median)
+			nt.warned = true
+		}
+	} else {
+		nt.warned = false
+	}
+
+	if abs64(median) > nt.maxAdjustment {
+		nt.offset = 0
+		return
+	}
+	nt.offset = median
+}
+
+// Offset returns the currently applied clock offset in seconds
+func (nt *NetworkTime) Offset() int64 {
+	nt.mu.RLock()
+	defer nt.mu.RUnlock()
+	return nt.offset
+}
+
+// SampleCount returns the number of peers contributing time samples
+func (nt *NetworkTime) SampleCount() int {
+	nt.mu.RLock()
+	defer nt.mu.RUnlock()
+	return len(nt.offsets)
+}
+
+// Now returns the network-adjusted Unix time
+func (nt *NetworkTime) Now() int64 {
+	return time.Now().Unix() + nt.Offset()
+}
+
+// IsSkewed reports whether the local clock is beyond the warning threshold
+func (nt *NetworkTime) IsSkewed() bool {
+	nt.mu.RLock()
+	defer nt.mu.RUnlock()
+	return nt.warned
+}
+
+// abs64 returns the absolute value of an int64
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
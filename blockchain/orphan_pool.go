@@ -0,0 +1,176 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"blockchain/blockchain/verify"
+)
+
+// orphanBlock is a block waiting for its parent, with the time it was received
+type orphanBlock struct {
+	block      *Block
+	receivedAt int64
+}
+
+// OrphanPool holds blocks that arrived before their parent block
+type OrphanPool struct {
+	orphans  map[string]*orphanBlock // Orphans keyed by block hash
+	byParent map[string][]string     // Orphan hashes keyed by PrevHash
+	mu       sync.Mutex
+	maxSize  int
+	ttl      time.Duration
+}
+
+// NewOrphanPool creates a new orphan block pool
+func NewOrphanPool(maxSize int, ttl time.Duration) *OrphanPool {
+	return &OrphanPool{
+		orphans:  make(map[string]*orphanBlock),
+		byParent: make(map[string][]string),
+		maxSize:  maxSize,
+		ttl:      ttl,
+	}
+}
+
+// AddOrphan stores a block whose parent is not known yet.
+// Only the checks that need no parent can run, but they keep a peer from filling the pool
+// with blocks that cost nothing to make: the block must be within the size limits, its hash
+// must match its header and meet the difficulty target. Expired orphans are dropped first;
+// if the pool is still full the oldest orphan is evicted.
+func (op *OrphanPool) AddOrphan(block *Block, difficulty int) error {
+	if err := checkOrphan(block, difficulty); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidBlock, err)
+	}
+
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	if _, exists := op.orphans[block.Hash]; exists {
+		return nil
+	}
+
+	op.expireLocked(time.Now().Unix())
+
+	if len(op.orphans) >= op.maxSize {
+		op.evictOldestLocked()
+	}
+
+	op.orphans[block.Hash] = &orphanBlock{block: block, receivedAt: time.Now().Unix()}
+	op.byParent[block.PrevHash] = append(op.byParent[block.PrevHash], block.Hash)
+	return nil
+}
+
+// checkOrphan runs the consensus checks of a block that don't depend on its parent
+func checkOrphan(block *Block, difficulty int) error {
+	if err := CheckBlockLimits(block); err != nil {
+		return err
+	}
+	if block.Hash != block.calculateHash() {
+		return errors.New("invalid block hash")
+	}
+	if !verify.MeetsDifficulty(block.Hash, difficulty) {
+		return errors.New("block hash does not meet difficulty target")
+	}
+	return nil
+}
+
+// TakeChildren removes and returns all orphans whose parent is the given block hash
+func (op *OrphanPool) TakeChildren(parentHash string) []*Block {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	hashes := op.byParent[parentHash]
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	children := make([]*Block, 0, len(hashes))
+	for _, hash := range hashes {
+		if orphan, exists := op.orphans[hash]; exists {
+			children = append(children, orphan.block)
+			delete(op.orphans, hash)
+		}
+	}
+	delete(op.byParent, parentHash)
+
+	return children
+}
+
+// HasOrphan checks if a block is held in the orphan pool
+func (op *OrphanPool) HasOrphan(hash string) bool {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	_, exists := op.orphans[hash]
+	return exists
+}
+
+// Size returns the number of orphan blocks in the pool
+func (op *OrphanPool) Size() int {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	return len(op.orphans)
+}
+
+// ExpireOrphans removes orphans older than the pool's TTL and returns how many were dropped
+func (op *OrphanPool) ExpireOrphans() int {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	return op.expireLocked(time.Now().Unix())
+}
+
+// expireLocked drops expired orphans (caller must hold the lock)
+func (op *OrphanPool) expireLocked(now int64) int {
+	if op.ttl <= 0 {
+		return 0
+	}
+
+	cutoff := now - int64(op.ttl/time.Second)
+	removed := 0
+	for hash, orphan := range op.orphans {
+		if orphan.receivedAt < cutoff {
+			op.removeLocked(hash)
+			removed++
+		}
+	}
+	return removed
+}
+
+// evictOldestLocked drops the orphan that was received first (caller must hold the lock)
+func (op *OrphanPool) evictOldestLocked() {
+	oldestHash := ""
+	var oldestTime int64
+	for hash, orphan := range op.orphans {
+		if oldestHash == "" || orphan.receivedAt < oldestTime {
+			oldestHash = hash
+			oldestTime = orphan.receivedAt
+		}
+	}
+	if oldestHash != "" {
+		op.removeLocked(oldestHash)
+	}
+}
+
+// removeLocked removes an orphan and its parent index entry (caller must hold the lock)
+func (op *OrphanPool) removeLocked(hash string) {
+	orphan, exists := op.orphans[hash]
+	if !exists {
+		return
+	}
+	delete(op.orphans, hash)
+
+	siblings := op.byParent[orphan.block.PrevHash]
+	for i, sibling := range siblings {
+		if sibling == hash {
+			siblings = append(siblings[:i], siblings[i+1:]...)
+			break
+		}
+	}
+	if len(siblings) == 0 {
+		delete(op.byParent, orphan.block.PrevHash)
+	} else {
+		op.byParent[orphan.block.PrevHash] = siblings
+	}
+}
@@ -0,0 +1,48 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+
+	"blockchain/blockchain/verify"
+)
+
+func TestAddBlockOrphans(t *testing.T) {
+	source := NewBlockchain(1, "miner")
+	source.MinePendingTransactions()
+	source.MinePendingTransactions()
+
+	target := NewBlockchain(1, "miner")
+	target.Chain = []*Block{source.Chain[0]}
+
+	// Orphans get the checks that need no parent before they are stored
+	badHash := *source.Chain[2]
+	badHash.Nonce++
+	weak := *source.Chain[2]
+	for weak.Nonce++; ; weak.Nonce++ {
+		if weak.Hash = weak.calculateHash(); !verify.MeetsDifficulty(weak.Hash, target.Difficulty) {
+			break
+		}
+	}
+	for name, block := range map[string]*Block{"bad hash": &badHash, "weak hash": &weak} {
+		if err := target.AddBlock(block); !errors.Is(err, ErrInvalidBlock) {
+			t.Fatalf("%s: AddBlock returned %v", name, err)
+		}
+	}
+	if size := target.OrphanPool.Size(); size != 0 {
+		t.Fatalf("pool holds %d invalid orphans", size)
+	}
+
+	if err := target.AddBlock(source.Chain[2]); !errors.Is(err, ErrOrphanBlock) {
+		t.Fatalf("orphan returned %v", err)
+	}
+	if err := target.AddBlock(source.Chain[2]); !errors.Is(err, ErrBlockKnown) {
+		t.Fatalf("known orphan returned %v", err)
+	}
+	if err := target.AddBlock(source.Chain[1]); err != nil {
+		t.Fatal(err)
+	}
+	if target.GetLatestBlock().Hash != source.Chain[2].Hash || target.OrphanPool.Size() != 0 {
+		t.Fatal("orphan was not connected after its parent")
+	}
+}
@@ -0,0 +1,264 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"strconv"
+	"time"
+)
+
+// MsgAddr advertises addresses peers can be reached at
+const MsgAddr = "addr"
+
+// Address advertisement limits
+const (
+	maxAddrPerMessage = 1000
+	maxAddrRelayBatch = 10   // Only small announcements are relayed, bulk lists are kept locally
+	addrRelayFanout   = 2    // Peers each new address is relayed to
+	maxKnownAddresses = 5000 // Addresses remembered to avoid relaying them twice
+)
+
+// addrPayload lists reachable peer addresses (host:port)
+type addrPayload struct {
+	Addresses []string `json:"addresses"`
+}
+
+// advertiseAddress tells a newly identified peer where this node accepts connections
+func (n *Node) advertiseAddress(peer *Peer) {
+	address := n.AdvertisedAddressFor(peer)
+	if address == "" {
+		return
+	}
+	if err := peer.Send(MsgAddr, addrPayload{Addresses: []string{address}}); err != nil {
+		log.Printf("Failed to advertise address to peer %s: %v", peer.Address, err)
+	}
+}
+
+// handleAddr records advertised addresses and relays new ones to a few other peers
+func (n *Node) handleAddr(peer *Peer, msg *Message) error {
+	var payload addrPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return fmt.Errorf("malformed addr: %w", err)
+	}
+	if len(payload.Addresses) > maxAddrPerMessage {
+		return fmt.Errorf("too many addresses: %d", len(payload.Addresses))
+	}
+
+	var fresh []string
+	for _, address := range payload.Addresses {
+		if !isDialableAddress(address) || n.isOwnAddress(address) {
+			continue
+		}
+
+		n.mu.Lock()
+		known := n.knownAddrs[address]
+		if !known && len(n.knownAddrs) < maxKnownAddresses {
+			n.knownAddrs[address] = true
+		}
+		n.mu.Unlock()
+		if known {
+			continue
+		}
+
+		fresh = append(fresh, address)
+		if n.config.PeerStore != nil {
+			if err := n.config.PeerStore.RecordPeerAddress(address); err != nil {
+				log.Printf("Warning: failed to record advertised address %s: %v", address, err)
+			}
+		}
+	}
+
+	if len(fresh) > 0 && len(payload.Addresses) <= maxAddrRelayBatch {
+		n.relayAddresses(peer, fresh)
+	}
+	return nil
+}
+
+// relayAddresses forwards newly learned addresses to a few random peers other than the source
+func (n *Node) relayAddresses(source *Peer, addresses []string) {
+	var targets []*Peer
+	for _, peer := range n.GetPeers() {
+		if peer != source && peer.IsIdentified() {
+			targets = append(targets, peer)
+		}
+	}
+
+	rand.Shuffle(len(targets), func(i, j int) { targets[i], targets[j] = targets[j], targets[i] })
+	if len(targets) > addrRelayFanout {
+		targets = targets[:addrRelayFanout]
+	}
+
+	for _, peer := range targets {
+		if err := peer.Send(MsgAddr, addrPayload{Addresses: addresses}); err != nil {
+			log.Printf("Failed to relay addresses to peer %s: %v", peer.Address, err)
+		}
+	}
+}
+
+// KnownAddresses returns the addresses learned from peer advertisements
+func (n *Node) KnownAddresses() []string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	addresses := make([]string, 0, len(n.knownAddrs))
+	for address := range n.knownAddrs {
+		addresses = append(addresses, address)
+	}
+	return addresses
+}
+
+// isOwnAddress checks if an address is one this node advertises
+func (n *Node) isOwnAddress(address string) bool {
+	host, _, _ := net.SplitHostPort(address)
+	return n.AdvertisedAddress(AddressNetwork(host)) == address
+}
+
+// isDialableAddress checks an advertised address has a host and a valid port
+func isDialableAddress(address string) bool {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || host == "" {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		return false
+	}
+	portNum, err := strconv.Atoi(port)
+	return err == nil && portNum > 0 && portNum <= 65535
+}
+
+// recordObservedAddress stores the host an identified peer sees this node connecting from.
+// Only public addresses count: they reveal the external address of a node behind NAT.
+func (n *Node) recordObservedAddress(nodeID, observed string) {
+	host, _, err := net.SplitHostPort(observed)
+	if err != nil {
+		return
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return
+	}
+
+	n.mu.Lock()
+	n.observedAddrs[nodeID] = ip.String()
+	n.mu.Unlock()
+}
+
+// ExternalIP returns the public address of the node on a network, preferring the
+// address reported by the port mapper and falling back to the one most peers observe
+func (n *Node) ExternalIP(network string) string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if n.natIP != nil && AddressNetwork(n.natIP.String()) == network {
+		return n.natIP.String()
+	}
+
+	votes := make(map[string]int)
+	best := ""
+	for _, host := range n.observedAddrs {
+		if AddressNetwork(host) != network {
+			continue
+		}
+		votes[host]++
+		if best == "" || votes[host] > votes[best] || (votes[host] == votes[best] && host < best) {
+			best = host
+		}
+	}
+	return best
+}
+
+// maintainPortMappings maps the port of every IPv4 listener on the local router
+// and renews the mappings at half their lifetime until the node stops
+func (n *Node) maintainPortMappings() {
+	defer n.wg.Done()
+
+	mapper := n.config.NATMapper
+	if mapper == nil {
+		var err error
+		mapper, err = DiscoverPortMapper(n.config.NATGateway)
+		if err != nil {
+			log.Printf("NAT port mapping unavailable: %v", err)
+			return
+		}
+	}
+
+	ticker := time.NewTicker(n.config.NATLifetime / 2)
+	defer ticker.Stop()
+
+	for {
+		n.refreshPortMappings(mapper)
+
+		select {
+		case <-n.quit:
+			n.deletePortMappings(mapper)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshPortMappings (re)creates the mapping of every IPv4 listener
+func (n *Node) refreshPortMappings(mapper PortMapper) {
+	externalIP, err := mapper.ExternalIP()
+	if err != nil {
+		log.Printf("Failed to get external address from %s: %v", mapper.Name(), err)
+	}
+
+	for _, address := range n.ListenAddrs() {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil || AddressNetwork(host) != NetworkIPv4 {
+			continue
+		}
+		internalPort, _ := strconv.Atoi(port)
+
+		n.mu.RLock()
+		externalPort, mapped := n.natPorts[internalPort]
+		n.mu.RUnlock()
+		if !mapped {
+			externalPort = internalPort
+		}
+
+		externalPort, err = mapper.AddPortMapping(internalPort, externalPort, n.config.NATLifetime)
+		if err != nil {
+			log.Printf("Failed to map port %d via %s: %v", internalPort, mapper.Name(), err)
+			continue
+		}
+
+		n.mu.Lock()
+		n.natPorts[internalPort] = externalPort
+		if externalIP != nil {
+			n.natIP = externalIP
+		}
+		n.mu.Unlock()
+
+		if !mapped {
+			log.Printf("Mapped port %d to external port %d via %s (external address %v)",
+				internalPort, externalPort, mapper.Name(), externalIP)
+		}
+	}
+}
+
+// deletePortMappings removes the mappings created by the node
+func (n *Node) deletePortMappings(mapper PortMapper) {
+	n.mu.Lock()
+	ports := n.natPorts
+	n.natPorts = make(map[int]int)
+	n.mu.Unlock()
+
+	for internalPort, externalPort := range ports {
+		if err := mapper.DeletePortMapping(internalPort, externalPort); err != nil {
+			log.Printf("Failed to remove port mapping %d via %s: %v", externalPort, mapper.Name(), err)
+		}
+	}
+}
+
+// externalPort returns the router port mapped to a local listening port, if any
+func (n *Node) externalPort(internalPort int) (int, bool) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	port, mapped := n.natPorts[internalPort]
+	return port, mapped
+}
@@ -0,0 +1,165 @@
+package blockchain
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// AddressManagerConfig configures the peer address manager
+type AddressManagerConfig struct {
+	Interval     time.Duration // How often outbound connections are topped up from the address book
+	MaxAge       time.Duration // Peers not seen for this long are forgotten
+	MaxFailures  int64         // Peers failing this many times without ever connecting are forgotten
+	RetryBackoff time.Duration // Wait after a failed attempt, doubled for each further failure
+}
+
+// maxRetryBackoffShift caps the exponential retry backoff (64 times the base)
+const maxRetryBackoffShift = 6
+
+// AddressManager keeps the node connected to its target number of outbound peers,
+// choosing them from the persisted address book so connections survive restarts
+// without relying solely on seeds.
+type AddressManager struct {
+	node   *Node
+	store  PeerStore
+	config AddressManagerConfig
+	quit   chan struct{}
+	wg     sync.WaitGroup
+	once   sync.Once
+}
+
+// NewAddressManager creates an address manager over a peer store
+func NewAddressManager(node *Node, store PeerStore, config AddressManagerConfig) *AddressManager {
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+	if config.MaxAge <= 0 {
+		config.MaxAge = 30 * 24 * time.Hour
+	}
+	if config.MaxFailures <= 0 {
+		config.MaxFailures = 10
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = time.Minute
+	}
+
+	return &AddressManager{
+		node:   node,
+		store:  store,
+		config: config,
+		quit:   make(chan struct{}),
+	}
+}
+
+// Start connects to peers from the address book right away, then keeps topping up connections
+func (am *AddressManager) Start() {
+	am.wg.Add(1)
+	go am.loop()
+}
+
+// Stop ends the maintenance loop
+func (am *AddressManager) Stop() {
+	am.once.Do(func() { close(am.quit) })
+	am.wg.Wait()
+}
+
+// loop prunes the address book and fills outbound slots until stopped
+func (am *AddressManager) loop() {
+	defer am.wg.Done()
+
+	ticker := time.NewTicker(am.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		am.Prune()
+		am.Fill()
+
+		select {
+		case <-am.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Prune forgets stale and persistently unreachable peers
+func (am *AddressManager) Prune() {
+	cutoff := time.Now().Add(-am.config.MaxAge).Unix()
+	removed, err := am.store.PrunePeers(cutoff, am.config.MaxFailures)
+	if err != nil {
+		log.Printf("Warning: failed to prune address book: %v", err)
+		return
+	}
+	if removed > 0 {
+		log.Printf("Pruned %d stale peers from the address book", removed)
+	}
+}
+
+// Fill opens outbound connections until the node reaches its target, returning how many were opened.
+// Seeds are only tried once the address book runs out of usable candidates.
+func (am *AddressManager) Fill() int {
+	missing := am.node.config.TargetOutbound - am.node.outboundCount()
+	if missing <= 0 {
+		return 0
+	}
+
+	opened := 0
+	tried := make(map[string]bool)
+	for _, address := range append(am.SelectAddresses(missing*4), am.node.config.Seeds...) {
+		if opened >= missing {
+			break
+		}
+		if tried[address] || !am.dialable(address) {
+			continue
+		}
+		tried[address] = true
+		if _, err := am.node.Connect(address); err != nil {
+			log.Printf("Failed to connect to peer %s: %v", address, err)
+			continue
+		}
+		opened++
+	}
+	return opened
+}
+
+// SelectAddresses returns up to limit addresses from the address book, best first,
+// skipping peers that are connected, banned or waiting out a retry backoff
+func (am *AddressManager) SelectAddresses(limit int) []string {
+	records, err := am.store.GetBestPeers(limit * 4)
+	if err != nil {
+		log.Printf("Warning: failed to load peers from address book: %v", err)
+		return nil
+	}
+
+	now := time.Now()
+	var addresses []string
+	for _, record := range records {
+		if len(addresses) >= limit {
+			break
+		}
+		if am.inBackoff(record, now) || !am.dialable(record.Address) {
+			continue
+		}
+		addresses = append(addresses, record.Address)
+	}
+	return addresses
+}
+
+// inBackoff checks if a peer failed recently enough that it shouldn't be retried yet
+func (am *AddressManager) inBackoff(record *PeerRecord, now time.Time) bool {
+	if record.LastFailure == 0 || record.LastFailure < record.LastConnected {
+		return false
+	}
+	shift := min(record.Failures-1, maxRetryBackoffShift)
+	backoff := am.config.RetryBackoff << max(shift, 0)
+	return now.Before(time.Unix(record.LastFailure, 0).Add(backoff))
+}
+
+// dialable checks an address may be connected to now
+func (am *AddressManager) dialable(address string) bool {
+	if !isDialableAddress(address) || am.node.isConnected(address) || am.node.isOwnAddress(address) {
+		return false
+	}
+	return am.node.isStaticPeer(address) || !am.node.IsBanned(peerHost(address))
+}
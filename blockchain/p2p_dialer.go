@@ -0,0 +1,232 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DialerConfig holds the settings used for outbound peer connections
+type DialerConfig struct {
+	Timeout time.Duration // Connection timeout (defaults to 10 seconds)
+
+	// SOCKS5 proxy used for every outbound connection, e.g. Tor at 127.0.0.1:9050
+	ProxyAddr     string
+	ProxyUser     string
+	ProxyPassword string
+
+	// SOCKS5 proxy used only for .onion peers (falls back to ProxyAddr)
+	OnionProxyAddr string
+}
+
+// PeerDialer opens outbound connections to peers, optionally through a SOCKS5 proxy
+type PeerDialer struct {
+	config DialerConfig
+}
+
+// NewPeerDialer creates a new peer dialer
+func NewPeerDialer(config DialerConfig) *PeerDialer {
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	return &PeerDialer{config: config}
+}
+
+// Dial connects to a peer address (host:port)
+func (d *PeerDialer) Dial(address string) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.config.Timeout)
+	defer cancel()
+	return d.DialContext(ctx, address)
+}
+
+// DialContext connects to a peer address, routing through the configured proxy if needed
+func (d *PeerDialer) DialContext(ctx context.Context, address string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer address %q: %w", address, err)
+	}
+
+	proxyAddr := d.proxyFor(host)
+	if proxyAddr == "" {
+		if IsOnionAddress(host) {
+			return nil, fmt.Errorf("cannot dial %s: no SOCKS5 proxy configured for onion peers", address)
+		}
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "tcp", address)
+	}
+
+	return d.dialSOCKS5(ctx, proxyAddr, address)
+}
+
+// proxyFor returns the proxy address to use for the given host, or "" to dial directly
+func (d *PeerDialer) proxyFor(host string) string {
+	if IsOnionAddress(host) && d.config.OnionProxyAddr != "" {
+		return d.config.OnionProxyAddr
+	}
+	return d.config.ProxyAddr
+}
+
+// IsOnionAddress reports whether a host is a Tor hidden service address
+func IsOnionAddress(host string) bool {
+	return strings.HasSuffix(strings.ToLower(host), ".onion")
+}
+
+// SOCKS5 protocol constants (RFC 1928 / RFC 1929)
+const (
+	socks5Version         = 0x05
+	socks5AuthNone        = 0x00
+	socks5AuthPassword    = 0x02
+	socks5AuthNoAccept    = 0xff
+	socks5CmdConnect      = 0x01
+	socks5AddrIPv4        = 0x01
+	socks5AddrDomain      = 0x03
+	socks5AddrIPv6        = 0x04
+	socks5StatusSucceeded = 0x00
+)
+
+// dialSOCKS5 connects to the target through a SOCKS5 proxy.
+// Hostnames are passed to the proxy unresolved so .onion names and DNS stay private.
+func (d *PeerDialer) dialSOCKS5(ctx context.Context, proxyAddr, target string) (net.Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SOCKS5 proxy %s: %w", proxyAddr, err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if err := d.socks5Handshake(conn, target); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("SOCKS5 proxy %s: %w", proxyAddr, err)
+	}
+
+	// Clear the handshake deadline for the peer connection itself
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// socks5Handshake performs method negotiation, authentication and the CONNECT request
+func (d *PeerDialer) socks5Handshake(conn net.Conn, target string) error {
+	host, portStr, err := net.SplitHostPort(target)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("invalid port %q", portStr)
+	}
+
+	// Method negotiation
+	methods := []byte{socks5AuthNone}
+	if d.config.ProxyUser != "" {
+		methods = append(methods, socks5AuthPassword)
+	}
+	greeting := append([]byte{socks5Version, byte(len(methods))}, methods...)
+	if _, err := conn.Write(greeting); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != socks5Version {
+		return errors.New("unexpected protocol version")
+	}
+
+	switch reply[1] {
+	case socks5AuthNone:
+	case socks5AuthPassword:
+		if err := d.socks5Authenticate(conn); err != nil {
+			return err
+		}
+	case socks5AuthNoAccept:
+		return errors.New("no acceptable authentication method")
+	default:
+		return fmt.Errorf("unsupported authentication method %d", reply[1])
+	}
+
+	// CONNECT request
+	req := []byte{socks5Version, socks5CmdConnect, 0x00}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			req = append(req, socks5AddrIPv4)
+			req = append(req, ip4...)
+		} else {
+			req = append(req, socks5AddrIPv6)
+			req = append(req, ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return errors.New("hostname too long")
+		}
+		req = append(req, socks5AddrDomain, byte(len(host)))
+		req = append(req, host...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	// Read reply header, then skip the bound address
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[1] != socks5StatusSucceeded {
+		return fmt.Errorf("connect to %s failed with status %d", target, header[1])
+	}
+
+	var skip int
+	switch header[3] {
+	case socks5AddrIPv4:
+		skip = net.IPv4len
+	case socks5AddrIPv6:
+		skip = net.IPv6len
+	case socks5AddrDomain:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return err
+		}
+		skip = int(length[0])
+	default:
+		return fmt.Errorf("unknown bound address type %d", header[3])
+	}
+
+	_, err = io.ReadFull(conn, make([]byte, skip+2))
+	return err
+}
+
+// socks5Authenticate performs username/password authentication
+func (d *PeerDialer) socks5Authenticate(conn net.Conn) error {
+	user, password := d.config.ProxyUser, d.config.ProxyPassword
+	if len(user) > 255 || len(password) > 255 {
+		return errors.New("proxy credentials too long")
+	}
+
+	req := []byte{0x01, byte(len(user))}
+	req = append(req, user...)
+	req = append(req, byte(len(password)))
+	req = append(req, password...)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[1] != 0x00 {
+		return errors.New("authentication failed")
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package blockchain
+
+import (
+	"strings"
+)
+
+// FeatureFlags is a bitmask of optional protocol extensions a node supports
+type FeatureFlags uint64
+
+// Protocol extensions negotiated during the handshake
+const (
+	FeatureCompactBlocks FeatureFlags = 1 << iota
+	FeatureSnapshots
+	FeatureCompression
+	FeatureBloomFilter
+	FeatureCompactFilters
+)
+
+// featureNames maps each known feature to a readable name
+var featureNames = []struct {
+	flag FeatureFlags
+	name string
+}{
+	{FeatureCompactBlocks, "compact-blocks"},
+	{FeatureSnapshots, "snapshots"},
+	{FeatureCompression, "compression"},
+	{FeatureBloomFilter, "bloom-filter"},
+	{FeatureCompactFilters, "compact-filters"},
+}
+
+// Has checks if all the given feature bits are set
+func (f FeatureFlags) Has(flags FeatureFlags) bool {
+	return f&flags == flags
+}
+
+// Negotiate returns the features both sides support
+func (f FeatureFlags) Negotiate(remote FeatureFlags) FeatureFlags {
+	return f & remote
+}
+
+// String returns a readable list of the enabled features
+func (f FeatureFlags) String() string {
+	if f == 0 {
+		return "none"
+	}
+
+	var names []string
+	known := FeatureFlags(0)
+	for _, feature := range featureNames {
+		known |= feature.flag
+		if f.Has(feature.flag) {
+			names = append(names, feature.name)
+		}
+	}
+	// Bits from newer protocol versions are kept but shown as unknown
+	if f&^known != 0 {
+		names = append(names, "unknown")
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,97 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Compact filter messages
+const (
+	MsgGetFilters = "getcfilters"
+	MsgFilters    = "cfilters"
+)
+
+// maxFiltersPerMessage limits the filters of a single response
+const maxFiltersPerMessage = 1000
+
+// getFiltersPayload requests the compact filters of the blocks between two heights, inclusive
+type getFiltersPayload struct {
+	RequestID uint64 `json:"requestId"`
+	From      int64  `json:"from"`
+	To        int64  `json:"to"`
+}
+
+// filtersPayload answers getcfilters, with no filters if the peer can't serve the range
+type filtersPayload struct {
+	RequestID uint64          `json:"requestId"`
+	Filters   []CompactFilter `json:"filters"`
+}
+
+// GetCompactFilters downloads the compact filters of consecutive blocks from a peer and checks
+// each against its trusted header and the filter before it, so a client can match its addresses
+// locally (see MatchCompactFilter). A peer serving a filter its block doesn't commit is penalized.
+func (s *Syncer) GetCompactFilters(ctx context.Context, peer *Peer, headers []BlockHeader) ([]CompactFilter, error) {
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	if len(headers) > maxFiltersPerMessage {
+		return nil, fmt.Errorf("at most %d filters can be requested at once", maxFiltersPerMessage)
+	}
+	from := headers[0].Index
+
+	raw, err := s.request(ctx, peer, MsgGetFilters, func(id uint64) interface{} {
+		return getFiltersPayload{RequestID: id, From: from, To: from + int64(len(headers)) - 1}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var resp filtersPayload
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		err = fmt.Errorf("malformed filters: %w", err)
+		s.node.Misbehaving(peer, PenaltyMalformedMessage, err.Error())
+		return nil, err
+	}
+	if len(resp.Filters) != len(headers) {
+		// Peers may have pruned the blocks or lack the commitments
+		return nil, fmt.Errorf("expected %d filters, got %d", len(headers), len(resp.Filters))
+	}
+
+	prev := ""
+	for i := range resp.Filters {
+		filter := &resp.Filters[i]
+		if err := VerifyCompactFilter(filter, headers[i], prev); err != nil {
+			err = fmt.Errorf("filter of block %d: %w", headers[i].Index, err)
+			s.node.Misbehaving(peer, PenaltyInvalidBlock, err.Error())
+			return nil, err
+		}
+		prev = filter.Header
+	}
+	return resp.Filters, nil
+}
+
+// handleGetFilters serves the compact filters of a height range of the local chain, none if
+// the chain doesn't serve filters or has pruned a block of the range
+func (s *Syncer) handleGetFilters(peer *Peer, msg *Message) error {
+	var req getFiltersPayload
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		return fmt.Errorf("malformed getcfilters: %w", err)
+	}
+	if req.From < 0 || req.To < req.From {
+		return errors.New("malformed getcfilters: invalid height range")
+	}
+	req.To = min(req.To, req.From+maxFiltersPerMessage-1)
+
+	resp := filtersPayload{RequestID: req.RequestID, Filters: []CompactFilter{}}
+	if source, ok := s.chain.(FilterSource); ok {
+		s.lock.Lock()
+		filters, err := source.GetCompactFilters(req.From, req.To)
+		s.lock.Unlock()
+		if err == nil {
+			resp.Filters = filters
+		}
+	}
+	return peer.Send(MsgFilters, resp)
+}
@@ -0,0 +1,225 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Identification messages exchanged right after a connection opens
+const (
+	MsgHello    = "hello"
+	MsgHelloAck = "hello_ack"
+)
+
+// identifyTimeout is how long a peer has to prove its identity before being dropped
+const identifyTimeout = 30 * time.Second
+
+// helloPayload announces a node's identity and challenges the remote side
+type helloPayload struct {
+	NodeID    string `json:"nodeId"`
+	PublicKey string `json:"publicKey"`
+	Challenge string `json:"challenge"`
+	Timestamp int64  `json:"timestamp"` // Sender's clock, used for network time sanity checks
+	Features  uint64 `json:"features"`  // Protocol extensions supported by the sender
+
+	// Version information used by update checks
+	ProtocolVersion int                   `json:"protocolVersion"`
+	Upgrades        []UpgradeAnnouncement `json:"upgrades,omitempty"`
+
+	// Address the sender sees the receiver connecting from, used for external address discovery
+	ObservedAddress string `json:"observedAddress,omitempty"`
+
+	// Chain the sender follows, used to reject peers on other networks (protocol version 2)
+	ChainID     string `json:"chainId,omitempty"`
+	GenesisHash string `json:"genesisHash,omitempty"`
+	BestHeight  int64  `json:"bestHeight,omitempty"`
+}
+
+// helloAckPayload answers a hello challenge with a signature from the node key
+type helloAckPayload struct {
+	Signature string `json:"signature"`
+}
+
+// NodeIDFromPublicKey derives a node identity from its public key
+func NodeIDFromPublicKey(publicKey *ecdsa.PublicKey) string {
+	return generateAddress(publicKey)
+}
+
+// ID returns the node identity derived from the node key
+func (n *Node) ID() string {
+	return NodeIDFromPublicKey(&n.key.PublicKey)
+}
+
+// sendHello starts identification with a freshly connected peer
+func (n *Node) sendHello(peer *Peer) error {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return err
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(&n.key.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	peer.mu.Lock()
+	peer.challenge = challenge
+	peer.mu.Unlock()
+
+	genesisHash, bestHeight := n.chainStatus()
+	return peer.Send(MsgHello, helloPayload{
+		NodeID:    n.ID(),
+		PublicKey: hex.EncodeToString(publicKey),
+		Challenge: hex.EncodeToString(challenge),
+		Timestamp: time.Now().Unix(),
+		Features:  uint64(n.config.Features),
+
+		ProtocolVersion: ProtocolVersion,
+		Upgrades:        upgradeAnnouncements(n.config.Upgrades),
+
+		ObservedAddress: peer.Address,
+
+		ChainID:     n.config.ChainID,
+		GenesisHash: genesisHash,
+		BestHeight:  bestHeight,
+	})
+}
+
+// handleHello verifies the remote identity, applies the allowlist and answers the challenge
+func (n *Node) handleHello(peer *Peer, msg *Message) error {
+	var hello helloPayload
+	if err := json.Unmarshal(msg.Payload, &hello); err != nil {
+		return fmt.Errorf("malformed hello: %w", err)
+	}
+
+	keyBytes, err := hex.DecodeString(hello.PublicKey)
+	if err != nil {
+		return fmt.Errorf("malformed hello public key: %w", err)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(keyBytes)
+	if err != nil {
+		return fmt.Errorf("malformed hello public key: %w", err)
+	}
+	remoteKey, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return errors.New("hello public key is not an ECDSA key")
+	}
+
+	if NodeIDFromPublicKey(remoteKey) != hello.NodeID {
+		return errors.New("hello node ID does not match public key")
+	}
+	if hello.NodeID == n.ID() {
+		return errors.New("connected to self")
+	}
+	if !n.isAllowed(hello.NodeID) {
+		return fmt.Errorf("node %s is not on the allowlist", hello.NodeID)
+	}
+
+	if err := n.checkCompatibility(&hello); err != nil {
+		return err
+	}
+
+	remoteFeatures := FeatureFlags(hello.Features)
+	if !remoteFeatures.Has(n.config.RequiredFeatures) {
+		return fmt.Errorf("peer lacks required features %s", (n.config.RequiredFeatures &^ remoteFeatures).String())
+	}
+
+	challenge, err := hex.DecodeString(hello.Challenge)
+	if err != nil || len(challenge) != 32 {
+		return errors.New("malformed hello challenge")
+	}
+
+	peer.mu.Lock()
+	peer.remoteKey = remoteKey
+	peer.claimedID = hello.NodeID
+	peer.claimedTime = hello.Timestamp
+	peer.observed = hello.ObservedAddress
+	peer.features = n.config.Features.Negotiate(remoteFeatures)
+	peer.version = min(ProtocolVersion, hello.ProtocolVersion)
+	peer.versionInfo = PeerVersionInfo{
+		ProtocolVersion: hello.ProtocolVersion,
+		Features:        remoteFeatures,
+		Upgrades:        hello.Upgrades,
+		ChainID:         hello.ChainID,
+		GenesisHash:     hello.GenesisHash,
+		BestHeight:      hello.BestHeight,
+	}
+	peer.mu.Unlock()
+
+	hash := sha256.Sum256(challenge)
+	signature, err := ecdsa.SignASN1(rand.Reader, n.key, hash[:])
+	if err != nil {
+		return err
+	}
+
+	return peer.Send(MsgHelloAck, helloAckPayload{Signature: hex.EncodeToString(signature)})
+}
+
+// handleHelloAck checks the peer signed our challenge with the key it announced
+func (n *Node) handleHelloAck(peer *Peer, msg *Message) error {
+	var ack helloAckPayload
+	if err := json.Unmarshal(msg.Payload, &ack); err != nil {
+		return fmt.Errorf("malformed hello_ack: %w", err)
+	}
+
+	signature, err := hex.DecodeString(ack.Signature)
+	if err != nil {
+		return fmt.Errorf("malformed hello_ack signature: %w", err)
+	}
+
+	peer.mu.Lock()
+	remoteKey, challenge, claimedID, claimedTime := peer.remoteKey, peer.challenge, peer.claimedID, peer.claimedTime
+	observed := peer.observed
+	versionInfo := peer.versionInfo
+	peer.mu.Unlock()
+
+	if remoteKey == nil || challenge == nil {
+		return errors.New("hello_ack received before hello")
+	}
+
+	hash := sha256.Sum256(challenge)
+	if !ecdsa.VerifyASN1(remoteKey, hash[:], signature) {
+		return errors.New("invalid identity signature")
+	}
+
+	peer.mu.Lock()
+	peer.nodeID = claimedID
+	peer.mu.Unlock()
+
+	if n.config.NetworkTime != nil {
+		n.config.NetworkTime.AddSample(claimedID, claimedTime)
+	}
+	if n.config.UpdateChecker != nil {
+		n.config.UpdateChecker.RecordPeer(claimedID, versionInfo)
+	}
+
+	if observed != "" {
+		n.recordObservedAddress(claimedID, observed)
+	}
+
+	log.Printf("Peer %s identified as node %s (protocol: %d, height: %d, features: %s)",
+		peer.Address, claimedID, peer.ProtocolVersion(), versionInfo.BestHeight, peer.Features())
+	n.advertiseAddress(peer)
+	return nil
+}
+
+// isAllowed checks a node identity against the allowlist
+func (n *Node) isAllowed(nodeID string) bool {
+	if !n.config.AllowlistOnly {
+		return true
+	}
+	for _, allowed := range n.config.AllowedNodeIDs {
+		if allowed == nodeID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package blockchain
+
+import (
+	"errors"
+	"log"
+	"net"
+	"sort"
+	"time"
+)
+
+// Penalty points for peer misbehavior. A peer reaching the node's ban threshold
+// (100 by default) is disconnected and banned.
+const (
+	PenaltyRateLimit          = 1
+	PenaltyInvalidTransaction = 10
+	PenaltyMalformedMessage   = 20
+	PenaltyInvalidHeader      = 50
+	PenaltyInvalidBlock       = 100
+)
+
+// Misbehaving adds penalty points to a peer, banning its host once the threshold is reached.
+// Static peers are never banned since the node is configured to stay connected to them.
+func (n *Node) Misbehaving(peer *Peer, points int, reason string) {
+	peer.mu.Lock()
+	peer.penalty += points
+	score := peer.penalty
+	peer.mu.Unlock()
+
+	log.Printf("Peer %s misbehaving (+%d, score %d): %s", peer.Address, points, score, reason)
+
+	if score < n.config.BanThreshold || peer.Static {
+		return
+	}
+
+	host := peerHost(peer.Address)
+	if err := n.Ban(host, n.config.BanDuration, reason); err != nil {
+		log.Printf("Warning: failed to ban %s: %v", host, err)
+	}
+}
+
+// Ban refuses connections from a host for the given duration and disconnects its peers
+func (n *Node) Ban(host string, duration time.Duration, reason string) error {
+	if host == "" {
+		return errors.New("host is required")
+	}
+
+	now := time.Now()
+	record := &BanRecord{
+		Host:        host,
+		Reason:      reason,
+		BannedAt:    now.Unix(),
+		BannedUntil: now.Add(duration).Unix(),
+	}
+
+	n.mu.Lock()
+	n.bans[host] = record
+	var banned []*Peer
+	for _, peer := range n.peers {
+		if peerHost(peer.Address) == host && !peer.Static {
+			banned = append(banned, peer)
+		}
+	}
+	n.mu.Unlock()
+
+	for _, peer := range banned {
+		peer.Close()
+	}
+	log.Printf("Banned %s until %s: %s", host, time.Unix(record.BannedUntil, 0).Format(time.RFC3339), reason)
+
+	if n.config.BanStore != nil {
+		return n.config.BanStore.SaveBan(record)
+	}
+	return nil
+}
+
+// Unban lifts the ban of a host
+func (n *Node) Unban(host string) error {
+	n.mu.Lock()
+	delete(n.bans, host)
+	n.mu.Unlock()
+
+	if n.config.BanStore != nil {
+		return n.config.BanStore.RemoveBan(host)
+	}
+	return nil
+}
+
+// IsBanned checks if a host is currently banned
+func (n *Node) IsBanned(host string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	record, exists := n.bans[host]
+	if !exists {
+		return false
+	}
+	if !record.IsActive(time.Now().Unix()) {
+		delete(n.bans, host)
+		return false
+	}
+	return true
+}
+
+// GetBans returns the active bans, oldest first
+func (n *Node) GetBans() []BanRecord {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	now := time.Now().Unix()
+	bans := make([]BanRecord, 0, len(n.bans))
+	for _, record := range n.bans {
+		if record.IsActive(now) {
+			bans = append(bans, *record)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].BannedAt < bans[j].BannedAt })
+	return bans
+}
+
+// loadBans restores the persisted ban list
+func (n *Node) loadBans() {
+	if n.config.BanStore == nil {
+		return
+	}
+
+	records, err := n.config.BanStore.GetActiveBans()
+	if err != nil {
+		log.Printf("Warning: failed to load ban list: %v", err)
+		return
+	}
+
+	n.mu.Lock()
+	for _, record := range records {
+		n.bans[record.Host] = record
+	}
+	n.mu.Unlock()
+}
+
+// allowMessage applies the per-peer token bucket rate limit
+func (n *Node) allowMessage(peer *Peer) bool {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	now := time.Now()
+	if peer.lastRefill.IsZero() {
+		peer.tokens = float64(n.config.MessageBurst)
+	} else {
+		peer.tokens += now.Sub(peer.lastRefill).Seconds() * n.config.MessageRate
+		if peer.tokens > float64(n.config.MessageBurst) {
+			peer.tokens = float64(n.config.MessageBurst)
+		}
+	}
+	peer.lastRefill = now
+
+	if peer.tokens < 1 {
+		return false
+	}
+	peer.tokens--
+	return true
+}
+
+// peerHost returns the host part of a peer address
+func peerHost(address string) string {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	return host
+}
@@ -0,0 +1,390 @@
+package blockchain
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// PortMapper opens TCP ports on the local router so peers can reach a node behind NAT
+type PortMapper interface {
+	Name() string
+	ExternalIP() (net.IP, error)
+	// AddPortMapping maps an external port to a local one and returns the port actually mapped
+	AddPortMapping(internalPort, externalPort int, lifetime time.Duration) (int, error)
+	DeletePortMapping(internalPort, externalPort int) error
+}
+
+// natDiscoveryTimeout bounds the search for a port mapping capable router
+const natDiscoveryTimeout = 3 * time.Second
+
+// DiscoverPortMapper finds a router supporting UPnP, falling back to NAT-PMP.
+// The NAT-PMP gateway defaults to the system's default route when gateway is empty.
+func DiscoverPortMapper(gateway string) (PortMapper, error) {
+	upnpMapper, upnpErr := discoverUPnP(natDiscoveryTimeout)
+	if upnpErr == nil {
+		return upnpMapper, nil
+	}
+
+	var gatewayIP net.IP
+	if gateway != "" {
+		gatewayIP = net.ParseIP(gateway)
+		if gatewayIP == nil {
+			return nil, fmt.Errorf("invalid NAT gateway %q", gateway)
+		}
+	} else {
+		var err error
+		gatewayIP, err = defaultGateway()
+		if err != nil {
+			return nil, fmt.Errorf("no UPnP router found (%v) and no NAT-PMP gateway: %w", upnpErr, err)
+		}
+	}
+
+	pmp := &NATPMP{Gateway: gatewayIP}
+	if _, err := pmp.ExternalIP(); err != nil {
+		return nil, fmt.Errorf("no UPnP router found (%v) and NAT-PMP failed: %w", upnpErr, err)
+	}
+	return pmp, nil
+}
+
+// defaultGateway reads the IPv4 default route from the kernel routing table (Linux only)
+func defaultGateway() (net.IP, error) {
+	data, err := os.ReadFile("/proc/net/route")
+	if err != nil {
+		return nil, fmt.Errorf("cannot determine default gateway: %w", err)
+	}
+
+	for _, line := range strings.Split(string(data), "\n")[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+		raw, err := hex.DecodeString(fields[2])
+		if err != nil || len(raw) != 4 {
+			continue
+		}
+		// The routing table stores addresses in little-endian order
+		return net.IPv4(raw[3], raw[2], raw[1], raw[0]), nil
+	}
+	return nil, errors.New("no default route found")
+}
+
+// NAT-PMP (RFC 6886) constants
+const (
+	natPMPPort        = 5351
+	natPMPOpExternal  = 0
+	natPMPOpMapTCP    = 2
+	natPMPMaxAttempts = 4
+)
+
+// NATPMP maps ports through a NAT-PMP capable gateway
+type NATPMP struct {
+	Gateway net.IP
+}
+
+// Name identifies the mapping protocol
+func (p *NATPMP) Name() string {
+	return "NAT-PMP"
+}
+
+// ExternalIP asks the gateway for its public address
+func (p *NATPMP) ExternalIP() (net.IP, error) {
+	response, err := p.call([]byte{0, natPMPOpExternal}, 12)
+	if err != nil {
+		return nil, err
+	}
+	return net.IPv4(response[8], response[9], response[10], response[11]), nil
+}
+
+// AddPortMapping requests a TCP mapping from the gateway
+func (p *NATPMP) AddPortMapping(internalPort, externalPort int, lifetime time.Duration) (int, error) {
+	request := make([]byte, 12)
+	request[1] = natPMPOpMapTCP
+	binary.BigEndian.PutUint16(request[4:], uint16(internalPort))
+	binary.BigEndian.PutUint16(request[6:], uint16(externalPort))
+	binary.BigEndian.PutUint32(request[8:], uint32(lifetime.Seconds()))
+
+	response, err := p.call(request, 16)
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.BigEndian.Uint16(response[10:])), nil
+}
+
+// DeletePortMapping removes a TCP mapping by requesting a zero lifetime
+func (p *NATPMP) DeletePortMapping(internalPort, externalPort int) error {
+	request := make([]byte, 12)
+	request[1] = natPMPOpMapTCP
+	binary.BigEndian.PutUint16(request[4:], uint16(internalPort))
+
+	_, err := p.call(request, 16)
+	return err
+}
+
+// call sends a request to the gateway, retrying with exponential backoff as the RFC requires
+func (p *NATPMP) call(request []byte, responseSize int) ([]byte, error) {
+	conn, err := net.DialUDP("udp4", nil, &net.UDPAddr{IP: p.Gateway, Port: natPMPPort})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	response := make([]byte, 16)
+	timeout := 250 * time.Millisecond
+	for attempt := 0; attempt < natPMPMaxAttempts; attempt++ {
+		if _, err := conn.Write(request); err != nil {
+			return nil, err
+		}
+
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		n, err := conn.Read(response)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				timeout *= 2
+				continue
+			}
+			return nil, err
+		}
+
+		if n < responseSize || response[1] != request[1]+128 {
+			return nil, errors.New("unexpected NAT-PMP response")
+		}
+		if code := binary.BigEndian.Uint16(response[2:]); code != 0 {
+			return nil, fmt.Errorf("NAT-PMP request failed with result code %d", code)
+		}
+		return response[:n], nil
+	}
+	return nil, errors.New("NAT-PMP gateway did not respond")
+}
+
+// UPnP Internet Gateway Device discovery constants
+const (
+	ssdpAddress   = "239.255.255.250:1900"
+	upnpSearchIGD = "urn:schemas-upnp-org:device:InternetGatewayDevice:1"
+)
+
+// UPnP maps ports through a router's WANIPConnection or WANPPPConnection service
+type UPnP struct {
+	ControlURL  string
+	ServiceType string
+	LocalIP     net.IP // Address of this host on the router's network
+	client      *http.Client
+}
+
+// upnpDevice is the subset of a UPnP device description needed to find the WAN service
+type upnpDevice struct {
+	Services []struct {
+		ServiceType string `xml:"serviceType"`
+		ControlURL  string `xml:"controlURL"`
+	} `xml:"serviceList>service"`
+	Devices []upnpDevice `xml:"deviceList>device"`
+}
+
+// findWANService searches a device tree for a WAN connection service
+func (d *upnpDevice) findWANService() (string, string, bool) {
+	for _, service := range d.Services {
+		if strings.Contains(service.ServiceType, "WANIPConnection") || strings.Contains(service.ServiceType, "WANPPPConnection") {
+			return service.ServiceType, service.ControlURL, true
+		}
+	}
+	for i := range d.Devices {
+		if serviceType, controlURL, ok := d.Devices[i].findWANService(); ok {
+			return serviceType, controlURL, ok
+		}
+	}
+	return "", "", false
+}
+
+// discoverUPnP searches the local network for an Internet Gateway Device via SSDP
+func discoverUPnP(timeout time.Duration) (*UPnP, error) {
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	ssdp, err := net.ResolveUDPAddr("udp4", ssdpAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	search := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: " + ssdpAddress + "\r\n" +
+		"ST: " + upnpSearchIGD + "\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 2\r\n\r\n"
+	if _, err := conn.WriteTo([]byte(search), ssdp); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	conn.SetReadDeadline(deadline)
+	buf := make([]byte, 2048)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return nil, errors.New("no UPnP gateway responded")
+		}
+
+		response, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buf[:n])), nil)
+		if err != nil {
+			continue
+		}
+		location := response.Header.Get("Location")
+		response.Body.Close()
+		if location == "" {
+			continue
+		}
+
+		mapper, err := newUPnP(location, time.Until(deadline))
+		if err == nil {
+			return mapper, nil
+		}
+	}
+}
+
+// newUPnP reads a gateway's device description and locates its WAN connection service
+func newUPnP(location string, timeout time.Duration) (*UPnP, error) {
+	client := &http.Client{Timeout: timeout}
+	response, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	var description struct {
+		URLBase string     `xml:"URLBase"`
+		Device  upnpDevice `xml:"device"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(&description); err != nil {
+		return nil, fmt.Errorf("malformed UPnP device description: %w", err)
+	}
+
+	serviceType, controlPath, ok := description.Device.findWANService()
+	if !ok {
+		return nil, errors.New("gateway has no WAN connection service")
+	}
+
+	base := location
+	if description.URLBase != "" {
+		base = description.URLBase
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	controlURL, err := baseURL.Parse(controlPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// The local address routed towards the gateway is the one to map ports to
+	probe, err := net.Dial("udp4", controlURL.Host)
+	if err != nil {
+		return nil, err
+	}
+	localIP := probe.LocalAddr().(*net.UDPAddr).IP
+	probe.Close()
+
+	return &UPnP{
+		ControlURL:  controlURL.String(),
+		ServiceType: serviceType,
+		LocalIP:     localIP,
+		client:      &http.Client{Timeout: natDiscoveryTimeout},
+	}, nil
+}
+
+// Name identifies the mapping protocol
+func (u *UPnP) Name() string {
+	return "UPnP"
+}
+
+// ExternalIP asks the gateway for its public address
+func (u *UPnP) ExternalIP() (net.IP, error) {
+	var result struct {
+		IP string `xml:"Body>GetExternalIPAddressResponse>NewExternalIPAddress"`
+	}
+	if err := u.soap("GetExternalIPAddress", nil, &result); err != nil {
+		return nil, err
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(result.IP))
+	if ip == nil {
+		return nil, fmt.Errorf("gateway returned invalid external address %q", result.IP)
+	}
+	return ip, nil
+}
+
+// AddPortMapping requests a TCP mapping from the gateway
+func (u *UPnP) AddPortMapping(internalPort, externalPort int, lifetime time.Duration) (int, error) {
+	err := u.soap("AddPortMapping", [][2]string{
+		{"NewRemoteHost", ""},
+		{"NewExternalPort", fmt.Sprint(externalPort)},
+		{"NewProtocol", "TCP"},
+		{"NewInternalPort", fmt.Sprint(internalPort)},
+		{"NewInternalClient", u.LocalIP.String()},
+		{"NewEnabled", "1"},
+		{"NewPortMappingDescription", "blockchain node"},
+		{"NewLeaseDuration", fmt.Sprint(int64(lifetime.Seconds()))},
+	}, nil)
+	if err != nil {
+		return 0, err
+	}
+	return externalPort, nil
+}
+
+// DeletePortMapping removes a TCP mapping
+func (u *UPnP) DeletePortMapping(internalPort, externalPort int) error {
+	return u.soap("DeletePortMapping", [][2]string{
+		{"NewRemoteHost", ""},
+		{"NewExternalPort", fmt.Sprint(externalPort)},
+		{"NewProtocol", "TCP"},
+	}, nil)
+}
+
+// soap invokes an action of the WAN connection service, decoding the response into result if set
+func (u *UPnP) soap(action string, args [][2]string, result interface{}) error {
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" ` +
+		`s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`)
+	fmt.Fprintf(&body, `<u:%s xmlns:u="%s">`, action, u.ServiceType)
+	for _, arg := range args {
+		fmt.Fprintf(&body, "<%s>", arg[0])
+		xml.EscapeText(&body, []byte(arg[1]))
+		fmt.Fprintf(&body, "</%s>", arg[0])
+	}
+	fmt.Fprintf(&body, "</u:%s></s:Body></s:Envelope>", action)
+
+	request, err := http.NewRequest(http.MethodPost, u.ControlURL, &body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	request.Header.Set("SOAPAction", fmt.Sprintf(`"%s#%s"`, u.ServiceType, action))
+
+	response, err := u.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("UPnP %s failed: %s", action, response.Status)
+	}
+	if result == nil {
+		return nil
+	}
+	return xml.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(result)
+}
@@ -0,0 +1,664 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Network families a node can listen on and advertise
+const (
+	NetworkIPv4  = "ipv4"
+	NetworkIPv6  = "ipv6"
+	NetworkOnion = "onion"
+)
+
+// ListenConfig describes one address the node accepts inbound peers on
+type ListenConfig struct {
+	Address    string // Bind address, e.g. "0.0.0.0:8333" or "[::]:8333"
+	Advertised string // Address announced to peers of the same network (defaults to Address)
+}
+
+// NodeConfig holds the configuration of a P2P node
+type NodeConfig struct {
+	Listen         []ListenConfig
+	Dialer         DialerConfig
+	MaxPeers       int
+	TargetOutbound int       // Outbound connections to open on bootstrap
+	Seeds          []string  // Fallback peers used when the peer store has too few good peers
+	PeerStore      PeerStore // Optional persistent peer history
+
+	// Node identity key (a random key is generated if nil)
+	NodeKey *ecdsa.PrivateKey
+
+	// Static peers are always kept connected and don't count against MaxPeers
+	StaticPeers             []string
+	StaticReconnectInterval time.Duration
+
+	// Allowlist mode refuses peers whose node identity is not listed
+	AllowlistOnly  bool
+	AllowedNodeIDs []string
+
+	// Optional tracker fed with the clocks advertised by identified peers
+	NetworkTime *NetworkTime
+
+	// Protocol extensions offered to peers, and those a peer must support to stay connected
+	Features         FeatureFlags
+	RequiredFeatures FeatureFlags
+
+	// Local upgrade schedule announced to peers, and an optional checker comparing it with theirs
+	Upgrades      *UpgradeSchedule
+	UpdateChecker *UpdateChecker
+
+	// Peers reaching BanThreshold penalty points are banned for BanDuration
+	BanThreshold int
+	BanDuration  time.Duration
+	BanStore     BanStore // Optional persistent ban list
+
+	// Per-peer message rate limit: sustained messages per second and burst size
+	MessageRate  float64
+	MessageBurst int
+
+	// Port mapping on the local router (UPnP, then NAT-PMP) so a node behind NAT accepts inbound peers
+	NATMapping  bool
+	NATMapper   PortMapper    // Optional mapper, discovered on the local network if nil
+	NATGateway  string        // NAT-PMP gateway IP (defaults to the default route)
+	NATLifetime time.Duration // Lifetime of each mapping, renewed at half-life
+
+	// Chain announced in the handshake; peers on a different chain ID or genesis block are refused.
+	// Chain is read under ChainLock (nil uses an internal mutex).
+	ChainID   string
+	Chain     ChainStatus
+	ChainLock sync.Locker
+}
+
+// Node manages listeners and peer connections
+type Node struct {
+	config    NodeConfig
+	key       *ecdsa.PrivateKey
+	dialer    *PeerDialer
+	listeners []net.Listener
+	peers     map[string]*Peer
+	handlers  map[string]MessageHandler
+	bans      map[string]*BanRecord // Keyed by host
+	mu        sync.RWMutex
+	wg        sync.WaitGroup
+	quit      chan struct{}
+
+	// Address discovery state
+	knownAddrs    map[string]bool   // Addresses learned from peer advertisements
+	observedAddrs map[string]string // Node ID -> public host that peer sees us connecting from
+	natIP         net.IP            // External address reported by the port mapper
+	natPorts      map[int]int       // Local listening port -> mapped external port
+}
+
+// NewNode creates a new P2P node
+func NewNode(config NodeConfig) (*Node, error) {
+	if config.MaxPeers <= 0 {
+		config.MaxPeers = 125
+	}
+	if config.TargetOutbound <= 0 {
+		config.TargetOutbound = 8
+	}
+	if config.StaticReconnectInterval <= 0 {
+		config.StaticReconnectInterval = 30 * time.Second
+	}
+	if config.BanThreshold <= 0 {
+		config.BanThreshold = 100
+	}
+	if config.BanDuration <= 0 {
+		config.BanDuration = 24 * time.Hour
+	}
+	if config.MessageRate <= 0 {
+		config.MessageRate = 50
+	}
+	if config.MessageBurst <= 0 {
+		config.MessageBurst = 200
+	}
+	if config.NATLifetime <= 0 {
+		config.NATLifetime = 20 * time.Minute
+	}
+	if config.ChainLock == nil {
+		config.ChainLock = &sync.Mutex{}
+	}
+
+	key := config.NodeKey
+	if key == nil {
+		var err error
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate node key: %w", err)
+		}
+	}
+
+	node := &Node{
+		config:   config,
+		key:      key,
+		dialer:   NewPeerDialer(config.Dialer),
+		peers:    make(map[string]*Peer),
+		handlers: make(map[string]MessageHandler),
+		bans:     make(map[string]*BanRecord),
+		quit:     make(chan struct{}),
+
+		knownAddrs:    make(map[string]bool),
+		observedAddrs: make(map[string]string),
+		natPorts:      make(map[int]int),
+	}
+	node.handlers[MsgAddr] = node.handleAddr
+	node.loadBans()
+	return node, nil
+}
+
+// Handle registers a handler for a message type
+func (n *Node) Handle(msgType string, handler MessageHandler) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.handlers[msgType] = handler
+}
+
+// Start binds every configured listen address and begins accepting peers.
+// If any address fails to bind, the listeners opened so far are closed.
+func (n *Node) Start() error {
+	for _, lc := range n.config.Listen {
+		network, err := listenNetwork(lc.Address)
+		if err != nil {
+			n.closeListeners()
+			return err
+		}
+
+		listener, err := net.Listen(network, lc.Address)
+		if err != nil {
+			n.closeListeners()
+			return fmt.Errorf("failed to listen on %s: %w", lc.Address, err)
+		}
+
+		n.mu.Lock()
+		n.listeners = append(n.listeners, listener)
+		n.mu.Unlock()
+
+		log.Printf("P2P node listening on %s", listener.Addr())
+
+		n.wg.Add(1)
+		go n.acceptLoop(listener)
+	}
+
+	if len(n.config.StaticPeers) > 0 {
+		n.wg.Add(1)
+		go n.maintainStaticPeers()
+	}
+	if n.config.NATMapping {
+		n.wg.Add(1)
+		go n.maintainPortMappings()
+	}
+	return nil
+}
+
+// maintainStaticPeers reconnects to every static peer that is not connected
+func (n *Node) maintainStaticPeers() {
+	defer n.wg.Done()
+
+	ticker := time.NewTicker(n.config.StaticReconnectInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, address := range n.config.StaticPeers {
+			if n.isConnected(address) {
+				continue
+			}
+			if _, err := n.Connect(address); err != nil {
+				log.Printf("Failed to connect to static peer %s: %v", address, err)
+			}
+		}
+
+		select {
+		case <-n.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// isStaticPeer checks if an address is configured as a static peer
+func (n *Node) isStaticPeer(address string) bool {
+	for _, static := range n.config.StaticPeers {
+		if static == address {
+			return true
+		}
+	}
+	return false
+}
+
+// Stop closes all listeners and peer connections
+func (n *Node) Stop() {
+	select {
+	case <-n.quit:
+		return
+	default:
+		close(n.quit)
+	}
+
+	n.closeListeners()
+
+	n.mu.Lock()
+	for _, peer := range n.peers {
+		peer.Close()
+	}
+	n.mu.Unlock()
+
+	n.wg.Wait()
+}
+
+// closeListeners closes every open listener
+func (n *Node) closeListeners() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, listener := range n.listeners {
+		listener.Close()
+	}
+	n.listeners = nil
+}
+
+// ListenAddrs returns the addresses the node is actually bound to
+func (n *Node) ListenAddrs() []string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	addrs := make([]string, 0, len(n.listeners))
+	for _, listener := range n.listeners {
+		addrs = append(addrs, listener.Addr().String())
+	}
+	return addrs
+}
+
+// AdvertisedAddress returns the address to announce to peers on the given network,
+// or "" if the node has no listener for that network.
+// Listeners bound to a wildcard address announce the discovered external address
+// (and mapped port) when one is known.
+func (n *Node) AdvertisedAddress(network string) string {
+	for i, lc := range n.config.Listen {
+		advertised := lc.Advertised
+		if advertised == "" {
+			advertised = n.discoveredAddress(i, lc.Address, network)
+		}
+		host, _, err := net.SplitHostPort(advertised)
+		if err != nil {
+			continue
+		}
+		if AddressNetwork(host) == network {
+			return advertised
+		}
+	}
+	return ""
+}
+
+// discoveredAddress returns the reachable address of the i-th listener: its bind host (or the
+// discovered external address for wildcard binds) with the bound port (or its mapped external port)
+func (n *Node) discoveredAddress(i int, bind, network string) string {
+	host, _, err := net.SplitHostPort(bind)
+	if err != nil {
+		return bind
+	}
+
+	n.mu.RLock()
+	var bound string
+	if i < len(n.listeners) {
+		bound = n.listeners[i].Addr().String()
+	}
+	n.mu.RUnlock()
+
+	_, portStr, err := net.SplitHostPort(bound)
+	if err != nil {
+		return bind
+	}
+	if ip := net.ParseIP(host); host != "" && (ip == nil || !ip.IsUnspecified()) {
+		return net.JoinHostPort(host, portStr)
+	}
+
+	external := n.ExternalIP(network)
+	if external == "" {
+		return bind
+	}
+	port, _ := strconv.Atoi(portStr)
+	if mapped, ok := n.externalPort(port); ok {
+		port = mapped
+	}
+	return net.JoinHostPort(external, strconv.Itoa(port))
+}
+
+// AdvertisedAddressFor returns the address to announce to a specific peer,
+// matching the network family the peer is connected over
+func (n *Node) AdvertisedAddressFor(peer *Peer) string {
+	host, _, err := net.SplitHostPort(peer.Address)
+	if err != nil {
+		return ""
+	}
+	return n.AdvertisedAddress(AddressNetwork(host))
+}
+
+// AddressNetwork classifies a host as ipv4, ipv6 or onion
+func AddressNetwork(host string) string {
+	if IsOnionAddress(host) {
+		return NetworkOnion
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		return NetworkIPv6
+	}
+	return NetworkIPv4
+}
+
+// listenNetwork picks the socket family for a bind address so that IPv4 and IPv6
+// listeners on the same port don't collide on dual-stack hosts
+func listenNetwork(address string) (string, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid listen address %q: %w", address, err)
+	}
+
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return "tcp", nil
+	case ip.To4() != nil:
+		return "tcp4", nil
+	default:
+		return "tcp6", nil
+	}
+}
+
+// Connect dials a peer and starts handling its messages
+func (n *Node) Connect(address string) (*Peer, error) {
+	static := n.isStaticPeer(address)
+	if !static && n.IsBanned(peerHost(address)) {
+		return nil, errors.New("peer is banned")
+	}
+	if !static && n.dynamicPeerCount() >= n.config.MaxPeers {
+		return nil, errors.New("maximum number of peers reached")
+	}
+
+	conn, err := n.dialer.Dial(address)
+	n.recordAttempt(address, err == nil)
+	if err != nil {
+		return nil, err
+	}
+
+	peer := newPeer(conn, address, false)
+	peer.Static = static
+	if err := n.addPeer(peer); err != nil {
+		peer.Close()
+		return nil, err
+	}
+	return peer, nil
+}
+
+// recordAttempt stores the outcome of a connection attempt in the peer store
+func (n *Node) recordAttempt(address string, success bool) {
+	if n.config.PeerStore == nil {
+		return
+	}
+	if err := n.config.PeerStore.RecordPeerAttempt(address, success); err != nil {
+		log.Printf("Warning: failed to record attempt for peer %s: %v", address, err)
+	}
+}
+
+// Bootstrap opens outbound connections, preferring historically reliable peers
+// from the peer store and falling back to the configured seeds
+func (n *Node) Bootstrap() int {
+	var candidates []string
+	seen := make(map[string]bool)
+
+	if n.config.PeerStore != nil {
+		records, err := n.config.PeerStore.GetBestPeers(n.config.TargetOutbound * 4)
+		if err != nil {
+			log.Printf("Warning: failed to load peers from store: %v", err)
+		}
+		for _, record := range records {
+			candidates = append(candidates, record.Address)
+			seen[record.Address] = true
+		}
+	}
+
+	for _, seed := range n.config.Seeds {
+		if !seen[seed] {
+			candidates = append(candidates, seed)
+			seen[seed] = true
+		}
+	}
+
+	connected := n.outboundCount()
+	for _, address := range candidates {
+		if connected >= n.config.TargetOutbound {
+			break
+		}
+		if n.isConnected(address) {
+			continue
+		}
+		if _, err := n.Connect(address); err != nil {
+			log.Printf("Failed to connect to peer %s: %v", address, err)
+			continue
+		}
+		connected++
+	}
+
+	log.Printf("Bootstrap complete with %d outbound peers", connected)
+	return connected
+}
+
+// isConnected checks whether a peer with the given address is connected
+func (n *Node) isConnected(address string) bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	_, exists := n.peers[address]
+	return exists
+}
+
+// outboundCount returns the number of outbound peer connections
+func (n *Node) outboundCount() int {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	count := 0
+	for _, peer := range n.peers {
+		if !peer.Inbound {
+			count++
+		}
+	}
+	return count
+}
+
+// acceptLoop accepts inbound connections on a listener
+func (n *Node) acceptLoop(listener net.Listener) {
+	defer n.wg.Done()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-n.quit:
+				return
+			default:
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("Error accepting connection: %v", err)
+			continue
+		}
+
+		if n.dynamicPeerCount() >= n.config.MaxPeers || n.IsBanned(peerHost(conn.RemoteAddr().String())) {
+			conn.Close()
+			continue
+		}
+
+		peer := newPeer(conn, conn.RemoteAddr().String(), true)
+		if err := n.addPeer(peer); err != nil {
+			log.Printf("Rejected inbound peer %s: %v", peer.Address, err)
+			peer.Close()
+		}
+	}
+}
+
+// addPeer registers a peer and starts its read loop
+func (n *Node) addPeer(peer *Peer) error {
+	n.mu.Lock()
+	if _, exists := n.peers[peer.Address]; exists {
+		n.mu.Unlock()
+		return errors.New("already connected to peer")
+	}
+	n.peers[peer.Address] = peer
+	n.mu.Unlock()
+
+	n.wg.Add(1)
+	go n.handlePeer(peer)
+
+	if err := n.sendHello(peer); err != nil {
+		peer.Close()
+		return fmt.Errorf("failed to send hello: %w", err)
+	}
+
+	// Drop peers that never prove their identity
+	time.AfterFunc(identifyTimeout, func() {
+		if !peer.IsIdentified() {
+			peer.Close()
+		}
+	})
+	return nil
+}
+
+// handlePeer runs the read loop of a peer and removes it once disconnected
+func (n *Node) handlePeer(peer *Peer) {
+	defer n.wg.Done()
+
+	err := peer.readLoop(n.dispatch)
+	var syntaxErr *json.SyntaxError
+	if errors.Is(err, errMessageTooLarge) || errors.As(err, &syntaxErr) {
+		n.Misbehaving(peer, PenaltyMalformedMessage, err.Error())
+	}
+	peer.Close()
+
+	n.mu.Lock()
+	delete(n.peers, peer.Address)
+	n.mu.Unlock()
+
+	if peer.IsIdentified() {
+		n.mu.Lock()
+		delete(n.observedAddrs, peer.NodeID())
+		n.mu.Unlock()
+
+		if n.config.NetworkTime != nil {
+			n.config.NetworkTime.RemoveSample(peer.NodeID())
+		}
+		if n.config.UpdateChecker != nil {
+			n.config.UpdateChecker.RemovePeer(peer.NodeID())
+		}
+	}
+
+	// Only outbound addresses are dialable, inbound ones use ephemeral ports
+	if !peer.Inbound && n.config.PeerStore != nil {
+		uptime := time.Now().Unix() - peer.ConnectedAt
+		if err := n.config.PeerStore.RecordPeerUptime(peer.Address, uptime); err != nil {
+			log.Printf("Warning: failed to record uptime for peer %s: %v", peer.Address, err)
+		}
+	}
+
+	select {
+	case <-n.quit:
+	default:
+		log.Printf("Peer %s disconnected: %v", peer.Address, err)
+	}
+}
+
+// dispatch routes an incoming message to its registered handler
+func (n *Node) dispatch(peer *Peer, msg *Message) error {
+	switch msg.Type {
+	case MsgHello:
+		return n.handleHello(peer, msg)
+	case MsgHelloAck:
+		return n.handleHelloAck(peer, msg)
+	}
+
+	if !peer.IsIdentified() {
+		return fmt.Errorf("received %s before identification", msg.Type)
+	}
+
+	// Excess messages are dropped and slowly push the peer towards a ban
+	if !n.allowMessage(peer) {
+		n.Misbehaving(peer, PenaltyRateLimit, "message rate limit exceeded")
+		return nil
+	}
+
+	n.mu.RLock()
+	handler, exists := n.handlers[msg.Type]
+	n.mu.RUnlock()
+
+	if !exists {
+		log.Printf("Ignoring unknown message type %q from peer %s", msg.Type, peer.Address)
+		return nil
+	}
+
+	if err := handler(peer, msg); err != nil {
+		n.Misbehaving(peer, PenaltyMalformedMessage, err.Error())
+		return err
+	}
+	return nil
+}
+
+// RemovePeer disconnects a peer
+func (n *Node) RemovePeer(address string) {
+	n.mu.RLock()
+	peer, exists := n.peers[address]
+	n.mu.RUnlock()
+
+	if exists {
+		peer.Close()
+	}
+}
+
+// GetPeers returns all connected peers
+func (n *Node) GetPeers() []*Peer {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	peers := make([]*Peer, 0, len(n.peers))
+	for _, peer := range n.peers {
+		peers = append(peers, peer)
+	}
+	return peers
+}
+
+// PeerCount returns the number of connected peers
+func (n *Node) PeerCount() int {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return len(n.peers)
+}
+
+// dynamicPeerCount returns the number of connected peers that count against MaxPeers
+func (n *Node) dynamicPeerCount() int {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	count := 0
+	for _, peer := range n.peers {
+		if !peer.Static {
+			count++
+		}
+	}
+	return count
+}
+
+// Broadcast sends a message to every connected peer
+func (n *Node) Broadcast(msgType string, payload interface{}) {
+	for _, peer := range n.GetPeers() {
+		if err := peer.Send(msgType, payload); err != nil {
+			log.Printf("Error sending %s to peer %s: %v", msgType, peer.Address, err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package blockchain
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStaticPeersDoNotCountAgainstMaxPeers(t *testing.T) {
+	statics := []string{"192.0.2.1:8333", "192.0.2.2:8333"}
+	node, err := NewNode(NodeConfig{
+		Listen:      []ListenConfig{{Address: "127.0.0.1:0"}},
+		MaxPeers:    len(statics),
+		StaticPeers: statics,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := node.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer node.Stop()
+
+	// Fill the node with its static peers, as if maintainStaticPeers had connected them
+	for _, address := range statics {
+		conn, _ := net.Pipe()
+		peer := newPeer(conn, address, false)
+		peer.Static = true
+		node.mu.Lock()
+		node.peers[address] = peer
+		node.mu.Unlock()
+	}
+
+	inbound, err := net.Dial("tcp", node.ListenAddrs()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inbound.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for node.PeerCount() < len(statics)+1 {
+		if time.Now().After(deadline) {
+			t.Fatal("inbound peer was refused with only static peers connected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var outbound []string
+	for i := 0; i < 2; i++ {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		outbound = append(outbound, listener.Addr().String())
+	}
+	if _, err := node.Connect(outbound[0]); err != nil {
+		t.Fatalf("outbound peer was refused: %v", err)
+	}
+
+	// Both slots now hold dynamic peers
+	if _, err := node.Connect(outbound[1]); err == nil {
+		t.Fatal("connected past MaxPeers")
+	}
+}
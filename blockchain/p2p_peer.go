@@ -0,0 +1,186 @@
+package blockchain
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// Message represents a single P2P protocol message
+type Message struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// MessageHandler handles an incoming message from a peer
+type MessageHandler func(peer *Peer, msg *Message) error
+
+// maxMessageSize limits the size of a single encoded message (4 MB)
+const maxMessageSize = 4 * 1024 * 1024
+
+// errMessageTooLarge is returned when a peer sends a message beyond maxMessageSize
+var errMessageTooLarge = errors.New("message exceeds maximum size")
+
+// Peer represents a connection to another node
+type Peer struct {
+	Address     string
+	Inbound     bool
+	Static      bool
+	ConnectedAt int64
+
+	// Identity state established by the hello exchange
+	mu          sync.Mutex
+	nodeID      string
+	claimedID   string
+	claimedTime int64
+	observed    string // Our address as seen by the peer
+	features    FeatureFlags
+	version     int // Negotiated protocol version
+	versionInfo PeerVersionInfo
+	remoteKey   *ecdsa.PublicKey
+	challenge   []byte
+
+	// Misbehavior score and message rate limit state
+	penalty    int
+	tokens     float64
+	lastRefill time.Time
+
+	conn    net.Conn
+	writeMu sync.Mutex
+	closed  chan struct{}
+	once    sync.Once
+}
+
+// newPeer wraps a network connection in a peer
+func newPeer(conn net.Conn, address string, inbound bool) *Peer {
+	return &Peer{
+		Address:     address,
+		Inbound:     inbound,
+		ConnectedAt: time.Now().Unix(),
+		conn:        conn,
+		closed:      make(chan struct{}),
+	}
+}
+
+// Send encodes and writes a message to the peer, encoding into a pooled buffer
+func (p *Peer) Send(msgType string, payload interface{}) error {
+	buf := getEncodeBuffer()
+	defer putEncodeBuffer(buf)
+	if err := buf.encodeMessage(msgType, payload); err != nil {
+		return err
+	}
+
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+
+	select {
+	case <-p.closed:
+		return errors.New("peer connection closed")
+	default:
+	}
+
+	_, err := p.conn.Write(buf.Bytes())
+	return err
+}
+
+// Close closes the connection to the peer
+func (p *Peer) Close() error {
+	var err error
+	p.once.Do(func() {
+		close(p.closed)
+		err = p.conn.Close()
+	})
+	return err
+}
+
+// NodeID returns the verified identity of the peer, or "" until identification completes
+func (p *Peer) NodeID() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.nodeID
+}
+
+// Penalty returns the misbehavior points the peer has accumulated on this connection
+func (p *Peer) Penalty() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.penalty
+}
+
+// IsIdentified checks if the peer has proven its node identity
+func (p *Peer) IsIdentified() bool {
+	return p.NodeID() != ""
+}
+
+// Features returns the protocol extensions negotiated with the peer
+func (p *Peer) Features() FeatureFlags {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.features
+}
+
+// ProtocolVersion returns the protocol version negotiated with the peer (the lower of both sides)
+func (p *Peer) ProtocolVersion() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.version
+}
+
+// VersionInfo returns the version and chain information the peer advertised in its handshake
+func (p *Peer) VersionInfo() PeerVersionInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.versionInfo
+}
+
+// Supports checks if a protocol extension may be used with the peer
+func (p *Peer) Supports(feature FeatureFlags) bool {
+	return p.Features().Has(feature)
+}
+
+// LocalAddr returns the local address of the connection to the peer
+func (p *Peer) LocalAddr() net.Addr {
+	return p.conn.LocalAddr()
+}
+
+// readLoop reads messages from the peer and dispatches them until the connection closes
+func (p *Peer) readLoop(dispatch func(*Peer, *Message) error) error {
+	reader := bufio.NewReader(p.conn)
+	decoder := json.NewDecoder(&limitedLineReader{reader: reader, limit: maxMessageSize})
+
+	for {
+		var msg Message
+		if err := decoder.Decode(&msg); err != nil {
+			return err
+		}
+		if err := dispatch(p, &msg); err != nil {
+			return err
+		}
+	}
+}
+
+// limitedLineReader fails once a single message exceeds the size limit
+type limitedLineReader struct {
+	reader *bufio.Reader
+	limit  int
+	read   int
+}
+
+// Read implements io.Reader, resetting the counter on every newline
+func (l *limitedLineReader) Read(buf []byte) (int, error) {
+	n, err := l.reader.Read(buf)
+	for _, b := range buf[:n] {
+		l.read++
+		if b == '\n' {
+			l.read = 0
+		}
+	}
+	if l.read > l.limit {
+		return n, errMessageTooLarge
+	}
+	return n, err
+}
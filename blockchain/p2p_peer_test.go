@@ -0,0 +1,91 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+// fuzzConn is a connection reading a fixed input and discarding what is written to it
+type fuzzConn struct {
+	net.Conn
+	input io.Reader
+}
+
+func (c *fuzzConn) Read(buf []byte) (int, error)  { return c.input.Read(buf) }
+func (c *fuzzConn) Write(buf []byte) (int, error) { return len(buf), nil }
+func (c *fuzzConn) Close() error                  { return nil }
+
+// seedMessages returns newline-delimited messages of the types a synced, relaying node handles
+func seedMessages(t testing.TB) [][]byte {
+	tx := NewTransaction("alice", "bob", 1, 0.1)
+	block := NewBlock(1, []Transaction{*tx}, createGenesisBlock().Hash)
+	messages := []struct {
+		msgType string
+		payload interface{}
+	}{
+		{MsgGetHeaders, getHeadersPayload{RequestID: 1, FromIndex: 0, Count: 10}},
+		{MsgGetBlocks, getBlocksPayload{RequestID: 2, Hashes: []string{block.Hash}}},
+		{MsgBlocks, blocksPayload{RequestID: 2, Blocks: []*Block{block}}},
+		{MsgGetSnapshot, getSnapshotPayload{RequestID: 3}},
+		{MsgGetFilters, getFiltersPayload{RequestID: 4, From: 0, To: 5}},
+		{MsgInv, invPayload{Hashes: []string{tx.Hash}}},
+		{MsgGetData, invPayload{Hashes: []string{tx.Hash}}},
+		{MsgTx, txPayload{Transactions: []*Transaction{tx}}},
+		{MsgAddr, addrPayload{Addresses: []string{"127.0.0.1:8333"}}},
+	}
+
+	var seeds [][]byte
+	var all []byte
+	for _, m := range messages {
+		payload, err := json.Marshal(m.payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(Message{Type: m.msgType, Payload: payload})
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(data, '\n')
+		seeds = append(seeds, data)
+		all = append(all, data...)
+	}
+	return append(seeds, all)
+}
+
+// FuzzPeerMessages feeds a message stream from an identified peer through the node's
+// dispatch to the sync and relay handlers
+func FuzzPeerMessages(f *testing.F) {
+	for _, seed := range seedMessages(f) {
+		f.Add(seed)
+	}
+	f.Add([]byte(`{"type":"getheaders","payload":{"fromIndex":-9223372036854775808,"count":-1}}` + "\n"))
+	f.Add([]byte(`{"type":"tx","payload":{"transactions":[null]}}` + "\n"))
+	f.Add([]byte(`{"type":"getcfilters","payload":{"from":9223372036854775807,"to":-1}}` + "\n"))
+
+	log.SetOutput(io.Discard)
+	f.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		node, err := NewNode(NodeConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		chain := NewBlockchain(1, "miner")
+		NewSyncer(node, chain, nil, SyncConfig{RequestTimeout: time.Millisecond})
+		NewTxRelay(node, chain, nil, RelayConfig{RequestTimeout: time.Millisecond})
+
+		peer := newPeer(&fuzzConn{input: bytes.NewReader(data)}, "192.0.2.1:8333", true)
+		peer.nodeID = "fuzz"
+		peer.Static = true
+		peer.readLoop(func(peer *Peer, msg *Message) error {
+			node.dispatch(peer, msg)
+			return nil
+		})
+	})
+}
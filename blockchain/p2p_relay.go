@@ -0,0 +1,326 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Transaction relay messages: hashes are announced, bodies sent only on request
+const (
+	MsgInv     = "inv"
+	MsgGetData = "getdata"
+	MsgTx      = "tx"
+)
+
+// Transaction relay limits
+const (
+	maxInvPerMessage = 5000
+	maxTxPerMessage  = 1000
+	maxKnownPerPeer  = 10000 // Hashes remembered per peer to avoid re-announcing them
+	maxRecentlySeen  = 50000 // Hashes recently processed, accepted or not
+)
+
+// RelayTarget is the chain surface used by transaction relay
+type RelayTarget interface {
+	AddTransactionFrom(tx *Transaction, source string) error
+	GetPendingTransaction(hash string) (*Transaction, bool)
+}
+
+// RelayConfig configures transaction relay
+type RelayConfig struct {
+	RequestTimeout time.Duration // How long a requested transaction is awaited before asking another peer
+}
+
+// invPayload announces transaction hashes
+type invPayload struct {
+	Hashes []string `json:"hashes"`
+}
+
+// txPayload carries requested transaction bodies
+type txPayload struct {
+	Transactions []*Transaction `json:"transactions"`
+}
+
+// hashSet is a set of hashes bounded in size, forgetting the oldest entries first
+type hashSet struct {
+	items map[string]struct{}
+	order []string
+	limit int
+}
+
+// newHashSet creates an empty bounded hash set
+func newHashSet(limit int) *hashSet {
+	return &hashSet{items: make(map[string]struct{}), limit: limit}
+}
+
+// add inserts a hash, evicting the oldest one when full
+func (hs *hashSet) add(hash string) {
+	if _, exists := hs.items[hash]; exists {
+		return
+	}
+	if len(hs.order) >= hs.limit {
+		delete(hs.items, hs.order[0])
+		hs.order = hs.order[1:]
+	}
+	hs.items[hash] = struct{}{}
+	hs.order = append(hs.order, hash)
+}
+
+// has checks if a hash is in the set
+func (hs *hashSet) has(hash string) bool {
+	_, exists := hs.items[hash]
+	return exists
+}
+
+// TxRelay announces pending transactions to peers and fetches the ones they announce.
+// It tracks which transactions each peer already knows so bodies and announcements
+// are never sent twice over the same connection.
+type TxRelay struct {
+	node   *Node
+	chain  RelayTarget
+	lock   sync.Locker // Serializes chain access with other users such as the miner
+	config RelayConfig
+
+	known    map[*Peer]*hashSet   // Transactions each peer has announced or been sent
+	inFlight map[string]time.Time // Requested transactions and when they were requested
+	seen     *hashSet             // Transactions already processed, so they aren't requested again
+	mu       sync.Mutex
+}
+
+// NewTxRelay creates a transaction relay and registers its message handlers.
+// The lock must be the one guarding the chain (nil uses an internal mutex).
+func NewTxRelay(node *Node, chain RelayTarget, lock sync.Locker, config RelayConfig) *TxRelay {
+	if lock == nil {
+		lock = &sync.Mutex{}
+	}
+	if config.RequestTimeout <= 0 {
+		config.RequestTimeout = 30 * time.Second
+	}
+
+	r := &TxRelay{
+		node:     node,
+		chain:    chain,
+		lock:     lock,
+		config:   config,
+		known:    make(map[*Peer]*hashSet),
+		inFlight: make(map[string]time.Time),
+		seen:     newHashSet(maxRecentlySeen),
+	}
+
+	node.Handle(MsgInv, r.handleInv)
+	node.Handle(MsgGetData, r.handleGetData)
+	node.Handle(MsgTx, r.handleTx)
+	return r
+}
+
+// Announce advertises a pending transaction to every identified peer that doesn't know it yet
+func (r *TxRelay) Announce(tx *Transaction) {
+	r.announceExcept(tx.Hash, nil)
+}
+
+// announceExcept advertises a transaction hash to identified peers other than source
+func (r *TxRelay) announceExcept(hash string, source *Peer) {
+	peers := r.node.GetPeers()
+
+	r.mu.Lock()
+	r.seen.add(hash)
+	r.pruneDisconnected(peers)
+	var targets []*Peer
+	for _, peer := range peers {
+		if peer == source || !peer.IsIdentified() {
+			continue
+		}
+		known := r.knownBy(peer)
+		if known.has(hash) {
+			continue
+		}
+		known.add(hash)
+		targets = append(targets, peer)
+	}
+	r.mu.Unlock()
+
+	for _, peer := range targets {
+		if err := peer.Send(MsgInv, invPayload{Hashes: []string{hash}}); err != nil {
+			log.Printf("Failed to announce transaction to peer %s: %v", peer.Address, err)
+		}
+	}
+}
+
+// knownBy returns the known-transaction set of a peer (caller must hold the lock)
+func (r *TxRelay) knownBy(peer *Peer) *hashSet {
+	known, exists := r.known[peer]
+	if !exists {
+		known = newHashSet(maxKnownPerPeer)
+		r.known[peer] = known
+	}
+	return known
+}
+
+// pruneDisconnected forgets the known sets of peers that are gone (caller must hold the lock)
+func (r *TxRelay) pruneDisconnected(connected []*Peer) {
+	if len(r.known) <= len(connected) {
+		return
+	}
+	alive := make(map[*Peer]bool, len(connected))
+	for _, peer := range connected {
+		alive[peer] = true
+	}
+	for peer := range r.known {
+		if !alive[peer] {
+			delete(r.known, peer)
+		}
+	}
+}
+
+// handleInv requests the announced transactions this node doesn't have yet
+func (r *TxRelay) handleInv(peer *Peer, msg *Message) error {
+	var inv invPayload
+	if err := json.Unmarshal(msg.Payload, &inv); err != nil {
+		return fmt.Errorf("malformed inv: %w", err)
+	}
+	if len(inv.Hashes) > maxInvPerMessage {
+		return fmt.Errorf("too many inventory entries: %d", len(inv.Hashes))
+	}
+	if err := checkHashList(inv.Hashes); err != nil {
+		return fmt.Errorf("malformed inv: %w", err)
+	}
+
+	now := time.Now()
+	var candidates []string
+	r.mu.Lock()
+	r.pruneExpiredRequests(now)
+	known := r.knownBy(peer)
+	for _, hash := range inv.Hashes {
+		known.add(hash)
+		if r.seen.has(hash) {
+			continue
+		}
+		if requested, exists := r.inFlight[hash]; exists && now.Sub(requested) < r.config.RequestTimeout {
+			continue
+		}
+		candidates = append(candidates, hash)
+	}
+	r.mu.Unlock()
+
+	r.lock.Lock()
+	var wanted []string
+	for _, hash := range candidates {
+		if _, pending := r.chain.GetPendingTransaction(hash); !pending {
+			wanted = append(wanted, hash)
+		}
+	}
+	r.lock.Unlock()
+
+	if len(wanted) == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	for _, hash := range wanted {
+		r.inFlight[hash] = now
+	}
+	r.mu.Unlock()
+
+	return peer.Send(MsgGetData, invPayload{Hashes: wanted})
+}
+
+// pruneExpiredRequests forgets requests that were never answered (caller must hold the lock)
+func (r *TxRelay) pruneExpiredRequests(now time.Time) {
+	if len(r.inFlight) < maxInvPerMessage {
+		return
+	}
+	for hash, requested := range r.inFlight {
+		if now.Sub(requested) >= r.config.RequestTimeout {
+			delete(r.inFlight, hash)
+		}
+	}
+}
+
+// handleGetData sends the requested pending transactions
+func (r *TxRelay) handleGetData(peer *Peer, msg *Message) error {
+	var request invPayload
+	if err := json.Unmarshal(msg.Payload, &request); err != nil {
+		return fmt.Errorf("malformed getdata: %w", err)
+	}
+	if len(request.Hashes) > maxInvPerMessage {
+		return fmt.Errorf("too many getdata entries: %d", len(request.Hashes))
+	}
+	if err := checkHashList(request.Hashes); err != nil {
+		return fmt.Errorf("malformed getdata: %w", err)
+	}
+
+	var txs []*Transaction
+	r.lock.Lock()
+	for _, hash := range request.Hashes {
+		if tx, pending := r.chain.GetPendingTransaction(hash); pending {
+			txs = append(txs, tx)
+		}
+	}
+	r.lock.Unlock()
+
+	r.mu.Lock()
+	known := r.knownBy(peer)
+	for _, tx := range txs {
+		known.add(tx.Hash)
+	}
+	r.mu.Unlock()
+
+	for start := 0; start < len(txs); start += maxTxPerMessage {
+		end := min(start+maxTxPerMessage, len(txs))
+		if err := peer.Send(MsgTx, txPayload{Transactions: txs[start:end]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleTx adds received transactions to the pool and announces the accepted ones
+func (r *TxRelay) handleTx(peer *Peer, msg *Message) error {
+	var payload txPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return fmt.Errorf("malformed tx: %w", err)
+	}
+	if len(payload.Transactions) > maxTxPerMessage {
+		return fmt.Errorf("too many transactions: %d", len(payload.Transactions))
+	}
+
+	for _, tx := range payload.Transactions {
+		if tx == nil {
+			return fmt.Errorf("malformed tx: null transaction")
+		}
+		if err := CheckTransactionLimits(tx); err != nil {
+			return fmt.Errorf("malformed tx: %w", err)
+		}
+
+		// A body that doesn't match its hash must not mark the genuine transaction as seen
+		if tx.Hash != tx.calculateHash() {
+			r.node.Misbehaving(peer, PenaltyInvalidTransaction, "transaction hash mismatch")
+			continue
+		}
+
+		r.mu.Lock()
+		r.knownBy(peer).add(tx.Hash)
+		delete(r.inFlight, tx.Hash)
+		alreadySeen := r.seen.has(tx.Hash)
+		r.seen.add(tx.Hash)
+		r.mu.Unlock()
+
+		if alreadySeen || tx.IsCoinbase() {
+			continue
+		}
+
+		r.lock.Lock()
+		err := r.chain.AddTransactionFrom(tx, PeerSource(peer.Address))
+		r.lock.Unlock()
+
+		if err != nil {
+			log.Printf("Rejected relayed transaction %s from peer %s: %v", tx.Hash, peer.Address, err)
+			continue
+		}
+		r.announceExcept(tx.Hash, peer)
+	}
+	return nil
+}
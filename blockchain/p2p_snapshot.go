@@ -0,0 +1,142 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// Snapshot messages
+const (
+	MsgGetSnapshot = "getsnapshot"
+	MsgSnapshot    = "snapshot"
+)
+
+// SnapshotTarget is a sync target that serves state snapshots and can start from one.
+// *PersistentBlockchain implements it.
+type SnapshotTarget interface {
+	LatestSnapshot() (*StateSnapshot, error)
+	LoadSnapshot(snapshot *StateSnapshot, headers []BlockHeader, trustedKeys []string) error
+}
+
+// getSnapshotPayload requests the peer's latest snapshot
+type getSnapshotPayload struct {
+	RequestID uint64 `json:"requestId"`
+}
+
+// snapshotPayload answers getsnapshot, without a snapshot if the peer has none
+type snapshotPayload struct {
+	RequestID uint64         `json:"requestId"`
+	Snapshot  *StateSnapshot `json:"snapshot,omitempty"`
+}
+
+// FastSync bootstraps a fresh chain from the latest snapshot the peers offer that is signed by
+// one of SyncConfig.TrustedSnapshotKeys: it syncs the header chain, loads the snapshot over the
+// headers up to its block and then downloads and validates only the blocks after it. Without a
+// usable snapshot it falls back to a full Sync.
+func (s *Syncer) FastSync(ctx context.Context) error {
+	target, ok := s.chain.(SnapshotTarget)
+	if !ok {
+		return errors.New("chain does not support snapshots")
+	}
+	s.lock.Lock()
+	fresh := s.chain.GetLatestBlock().Index == 0
+	s.lock.Unlock()
+	if !fresh || len(s.config.TrustedSnapshotKeys) == 0 {
+		return s.Sync(ctx)
+	}
+
+	peers := s.identifiedPeers()
+	if len(peers) == 0 {
+		return errors.New("no identified peers to sync from")
+	}
+	snapshot := s.fetchSnapshot(ctx, peers)
+	if snapshot == nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Printf("No trusted snapshot offered by peers, syncing from genesis")
+		return s.Sync(ctx)
+	}
+
+	headers, err := s.syncHeaders(ctx, peers)
+	if err != nil {
+		return err
+	}
+	height := snapshot.Header.Index
+	if int64(len(headers)) < height || headers[height-1] != snapshot.Header {
+		return fmt.Errorf("snapshot at height %d is not on the peers' header chain", height)
+	}
+
+	s.lock.Lock()
+	err = target.LoadSnapshot(snapshot, headers[:height], s.config.TrustedSnapshotKeys)
+	s.lock.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to load snapshot: %w", err)
+	}
+	s.reportProgress(SyncProgress{Stage: SyncStageSnapshot, Height: height, TargetHeight: headers[len(headers)-1].Index})
+
+	if remaining := headers[height:]; len(remaining) > 0 {
+		log.Printf("Downloading %d blocks after the snapshot up to height %d", len(remaining), remaining[len(remaining)-1].Index)
+		return s.syncBlocks(ctx, peers, remaining)
+	}
+	return nil
+}
+
+// fetchSnapshot asks every peer for its latest snapshot and returns the highest one signed by
+// a trusted key, or nil if none is
+func (s *Syncer) fetchSnapshot(ctx context.Context, peers []*Peer) *StateSnapshot {
+	var best *StateSnapshot
+	for _, peer := range peers {
+		raw, err := s.request(ctx, peer, MsgGetSnapshot, func(id uint64) interface{} {
+			return getSnapshotPayload{RequestID: id}
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Printf("Snapshot request to peer %s failed: %v", peer.Address, err)
+			continue
+		}
+
+		var resp snapshotPayload
+		if err := json.Unmarshal(raw, &resp); err != nil {
+			s.node.Misbehaving(peer, PenaltyMalformedMessage, fmt.Sprintf("malformed snapshot: %v", err))
+			continue
+		}
+		snapshot := resp.Snapshot
+		if snapshot == nil || snapshot.Header.Index <= 0 {
+			continue
+		}
+		if err := snapshot.Verify(s.config.TrustedSnapshotKeys); err != nil {
+			log.Printf("Ignoring snapshot from peer %s: %v", peer.Address, err)
+			continue
+		}
+		if best == nil || snapshot.Header.Index > best.Header.Index {
+			best = snapshot
+		}
+	}
+	return best
+}
+
+// handleGetSnapshot serves the latest snapshot of the local chain
+func (s *Syncer) handleGetSnapshot(peer *Peer, msg *Message) error {
+	var req getSnapshotPayload
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		return fmt.Errorf("malformed getsnapshot: %w", err)
+	}
+
+	resp := snapshotPayload{RequestID: req.RequestID}
+	if target, ok := s.chain.(SnapshotTarget); ok {
+		s.lock.Lock()
+		snapshot, err := target.LatestSnapshot()
+		s.lock.Unlock()
+		if err != nil {
+			log.Printf("Failed to load snapshot for peer %s: %v", peer.Address, err)
+		}
+		resp.Snapshot = snapshot
+	}
+	return peer.Send(MsgSnapshot, resp)
+}
@@ -0,0 +1,531 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Synchronization messages
+const (
+	MsgGetHeaders = "getheaders"
+	MsgHeaders    = "headers"
+	MsgGetBlocks  = "getblocks"
+	MsgBlocks     = "blocks"
+)
+
+// Limits on a single synchronization response
+const (
+	maxHeadersPerMessage = 2000
+	maxBlocksPerMessage  = 64
+)
+
+// Synchronization stages reported through SyncProgress
+const (
+	SyncStageHeaders  = "headers"
+	SyncStageSnapshot = "snapshot"
+	SyncStageBlocks   = "blocks"
+)
+
+// SyncTarget is the chain a Syncer serves peers from and applies downloaded blocks to
+type SyncTarget interface {
+	GetLatestBlock() *Block
+	GetBlockByIndex(index int64) (*Block, error)
+	GetBlockByHash(hash string) (*Block, error)
+	GetHeader(index int64) (BlockHeader, error) // Available for pruned blocks too
+	AddBlock(block *Block) error
+}
+
+// SyncConfig configures block synchronization
+type SyncConfig struct {
+	Difficulty     int           // Proof of work required of synced headers
+	BatchSize      int           // Blocks requested per getblocks message
+	MaxParallel    int           // Peers downloading block bodies concurrently
+	RequestTimeout time.Duration // How long to wait for a single response
+	MaxAttempts    int           // Attempts per block batch before the sync fails
+	OnProgress     func(SyncProgress)
+
+	// Public keys whose snapshots FastSync trusts to skip validating the blocks they cover
+	TrustedSnapshotKeys []string
+}
+
+// SyncProgress reports how far a synchronization has come
+type SyncProgress struct {
+	Stage        string `json:"stage"`
+	Height       int64  `json:"height"`       // Last validated header or applied block
+	TargetHeight int64  `json:"targetHeight"` // Best header height known so far
+}
+
+// Syncer downloads missing blocks from peers, headers first, and serves sync requests from peers
+type Syncer struct {
+	node   *Node
+	chain  SyncTarget
+	lock   sync.Locker // Serializes chain access with other users such as the miner
+	config SyncConfig
+
+	nextID  uint64
+	pending map[uint64]*syncRequest
+	mu      sync.Mutex
+}
+
+// syncRequest is an outstanding request waiting for its response
+type syncRequest struct {
+	peer     *Peer
+	response chan json.RawMessage
+}
+
+// getHeadersPayload requests consecutive headers starting at a height
+type getHeadersPayload struct {
+	RequestID uint64 `json:"requestId"`
+	FromIndex int64  `json:"fromIndex"`
+	Count     int    `json:"count"`
+}
+
+// headersPayload answers getheaders
+type headersPayload struct {
+	RequestID uint64        `json:"requestId"`
+	Headers   []BlockHeader `json:"headers"`
+}
+
+// getBlocksPayload requests full blocks by hash
+type getBlocksPayload struct {
+	RequestID uint64   `json:"requestId"`
+	Hashes    []string `json:"hashes"`
+}
+
+// blocksPayload answers getblocks
+type blocksPayload struct {
+	RequestID uint64   `json:"requestId"`
+	Blocks    []*Block `json:"blocks"`
+}
+
+// NewSyncer creates a syncer and registers its message handlers on the node.
+// Pass the lock held while mutating the chain, or nil if the syncer is its only user.
+func NewSyncer(node *Node, chain SyncTarget, lock sync.Locker, config SyncConfig) *Syncer {
+	if lock == nil {
+		lock = &sync.Mutex{}
+	}
+	if config.BatchSize <= 0 || config.BatchSize > maxBlocksPerMessage {
+		config.BatchSize = 16
+	}
+	if config.MaxParallel <= 0 {
+		config.MaxParallel = 4
+	}
+	if config.RequestTimeout <= 0 {
+		config.RequestTimeout = 30 * time.Second
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 3
+	}
+
+	s := &Syncer{
+		node:    node,
+		chain:   chain,
+		lock:    lock,
+		config:  config,
+		pending: make(map[uint64]*syncRequest),
+	}
+
+	node.Handle(MsgGetHeaders, s.handleGetHeaders)
+	node.Handle(MsgHeaders, s.handleResponse)
+	node.Handle(MsgGetBlocks, s.handleGetBlocks)
+	node.Handle(MsgBlocks, s.handleResponse)
+	node.Handle(MsgGetSnapshot, s.handleGetSnapshot)
+	node.Handle(MsgSnapshot, s.handleResponse)
+	node.Handle(MsgGetFilters, s.handleGetFilters)
+	node.Handle(MsgFilters, s.handleResponse)
+	return s
+}
+
+// Sync catches up with the connected peers: it downloads and validates the header chain
+// beyond the local tip, then fetches block bodies in parallel batches and applies them in order
+func (s *Syncer) Sync(ctx context.Context) error {
+	peers := s.identifiedPeers()
+	if len(peers) == 0 {
+		return errors.New("no identified peers to sync from")
+	}
+
+	headers, err := s.syncHeaders(ctx, peers)
+	if err != nil {
+		return err
+	}
+	if len(headers) == 0 {
+		log.Printf("Chain is up to date with peers")
+		return nil
+	}
+
+	log.Printf("Downloading %d blocks up to height %d", len(headers), headers[len(headers)-1].Index)
+	return s.syncBlocks(ctx, peers, headers)
+}
+
+// identifiedPeers returns the connected peers that completed the identity handshake
+func (s *Syncer) identifiedPeers() []*Peer {
+	var peers []*Peer
+	for _, peer := range s.node.GetPeers() {
+		if peer.IsIdentified() {
+			peers = append(peers, peer)
+		}
+	}
+	return peers
+}
+
+// syncHeaders collects validated headers beyond the local tip, moving on to the next peer if one fails
+func (s *Syncer) syncHeaders(ctx context.Context, peers []*Peer) ([]BlockHeader, error) {
+	s.lock.Lock()
+	tip := s.chain.GetLatestBlock().Header()
+	s.lock.Unlock()
+
+	var headers []BlockHeader
+	for _, peer := range peers {
+		for {
+			raw, err := s.request(ctx, peer, MsgGetHeaders, func(id uint64) interface{} {
+				return getHeadersPayload{RequestID: id, FromIndex: tip.Index + 1, Count: maxHeadersPerMessage}
+			})
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				log.Printf("Header sync with peer %s failed: %v", peer.Address, err)
+				break
+			}
+
+			var resp headersPayload
+			if err := json.Unmarshal(raw, &resp); err != nil {
+				s.node.Misbehaving(peer, PenaltyMalformedMessage, fmt.Sprintf("malformed headers: %v", err))
+				break
+			}
+			if len(resp.Headers) > maxHeadersPerMessage {
+				s.node.Misbehaving(peer, PenaltyMalformedMessage, "too many headers")
+				break
+			}
+
+			valid := 0
+			for i := range resp.Headers {
+				if err := resp.Headers[i].validateAgainstParent(&tip, s.config.Difficulty); err != nil {
+					s.node.Misbehaving(peer, PenaltyInvalidHeader, fmt.Sprintf("invalid header %d: %v", resp.Headers[i].Index, err))
+					break
+				}
+				tip = resp.Headers[i]
+				headers = append(headers, tip)
+				valid++
+			}
+			if valid > 0 {
+				s.reportProgress(SyncProgress{Stage: SyncStageHeaders, Height: tip.Index, TargetHeight: tip.Index})
+			}
+
+			// A short or partly invalid batch means this peer has nothing more to offer
+			if valid < maxHeadersPerMessage {
+				break
+			}
+		}
+	}
+
+	return headers, nil
+}
+
+// syncBatch is a run of consecutive blocks downloaded together
+type syncBatch struct {
+	index    int
+	headers  []BlockHeader
+	blocks   []*Block
+	peer     *Peer // Peer the blocks were downloaded from
+	attempts int
+	err      error
+}
+
+// syncBlocks downloads block bodies for the headers from several peers and applies them in order
+func (s *Syncer) syncBlocks(ctx context.Context, peers []*Peer, headers []BlockHeader) error {
+	ctx, cancel := context.WithCancel(ctx)
+
+	var batches []*syncBatch
+	for start := 0; start < len(headers); start += s.config.BatchSize {
+		end := start + s.config.BatchSize
+		if end > len(headers) {
+			end = len(headers)
+		}
+		batches = append(batches, &syncBatch{index: len(batches), headers: headers[start:end]})
+	}
+
+	jobs := make(chan *syncBatch, len(batches))
+	results := make(chan *syncBatch, len(batches))
+	for _, batch := range batches {
+		jobs <- batch
+	}
+
+	workers := len(peers)
+	if workers > s.config.MaxParallel {
+		workers = s.config.MaxParallel
+	}
+
+	var wg sync.WaitGroup
+	for _, peer := range peers[:workers] {
+		wg.Add(1)
+		go func(peer *Peer) {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case batch := <-jobs:
+					batch.peer = peer
+					batch.blocks, batch.err = s.fetchBlocks(ctx, peer, batch.headers)
+					if batch.err != nil {
+						batch.err = fmt.Errorf("peer %s: %w", peer.Address, batch.err)
+					}
+					select {
+					case results <- batch:
+					case <-ctx.Done():
+						return
+					}
+
+					// A peer that failed once gets no further batches
+					if batch.err != nil {
+						return
+					}
+				}
+			}
+		}(peer)
+	}
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	alive := workers
+	ready := make(map[int]*syncBatch)
+	next := 0
+	target := headers[len(headers)-1].Index
+
+	for next < len(batches) {
+		var batch *syncBatch
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case batch = <-results:
+		}
+
+		if batch.err != nil {
+			alive--
+			batch.attempts++
+			log.Printf("Block download failed (attempt %d): %v", batch.attempts, batch.err)
+			if batch.attempts >= s.config.MaxAttempts {
+				return fmt.Errorf("failed to download blocks %d-%d: %v",
+					batch.headers[0].Index, batch.headers[len(batch.headers)-1].Index, batch.err)
+			}
+			if alive == 0 {
+				return fmt.Errorf("no peers left to download blocks from: %w", batch.err)
+			}
+			jobs <- batch
+			continue
+		}
+
+		ready[batch.index] = batch
+		for ready[next] != nil {
+			if err := s.applyBatch(ready[next]); err != nil {
+				return err
+			}
+			delete(ready, next)
+			next++
+
+			applied := batches[next-1].headers[len(batches[next-1].headers)-1].Index
+			s.reportProgress(SyncProgress{Stage: SyncStageBlocks, Height: applied, TargetHeight: target})
+		}
+	}
+
+	log.Printf("Sync complete at height %d", target)
+	return nil
+}
+
+// fetchBlocks downloads the bodies of a header batch and checks they match the headers
+func (s *Syncer) fetchBlocks(ctx context.Context, peer *Peer, headers []BlockHeader) ([]*Block, error) {
+	hashes := make([]string, len(headers))
+	for i, header := range headers {
+		hashes[i] = header.Hash
+	}
+
+	raw, err := s.request(ctx, peer, MsgGetBlocks, func(id uint64) interface{} {
+		return getBlocksPayload{RequestID: id, Hashes: hashes}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var resp blocksPayload
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		err = fmt.Errorf("malformed blocks: %w", err)
+		s.node.Misbehaving(peer, PenaltyMalformedMessage, err.Error())
+		return nil, err
+	}
+	if len(resp.Blocks) != len(headers) {
+		// Peers may legitimately lack blocks they announced headers for, e.g. after a reorg
+		return nil, fmt.Errorf("expected %d blocks, got %d", len(headers), len(resp.Blocks))
+	}
+
+	for i, block := range resp.Blocks {
+		if block == nil || block.Header() != headers[i] {
+			err := fmt.Errorf("block %d does not match its header", headers[i].Index)
+			s.node.Misbehaving(peer, PenaltyInvalidBlock, err.Error())
+			return nil, err
+		}
+		if err := CheckBlockLimits(block); err != nil {
+			err = fmt.Errorf("block %d: %w", headers[i].Index, err)
+			s.node.Misbehaving(peer, PenaltyInvalidBlock, err.Error())
+			return nil, err
+		}
+		if !block.ValidateTransactions() {
+			err := fmt.Errorf("block %d transactions do not match the Merkle root", headers[i].Index)
+			s.node.Misbehaving(peer, PenaltyInvalidBlock, err.Error())
+			return nil, err
+		}
+	}
+	return resp.Blocks, nil
+}
+
+// applyBatch adds downloaded blocks to the chain in order
+func (s *Syncer) applyBatch(batch *syncBatch) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, block := range batch.blocks {
+		// The block may have arrived through relay while downloading
+		if existing, err := s.chain.GetBlockByHash(block.Hash); err == nil && existing != nil {
+			continue
+		}
+		if err := s.chain.AddBlock(block); err != nil && !errors.Is(err, ErrOrphanBlock) {
+			s.node.Misbehaving(batch.peer, PenaltyInvalidBlock, fmt.Sprintf("invalid block %d: %v", block.Index, err))
+			return fmt.Errorf("failed to apply synced block %d: %w", block.Index, err)
+		}
+	}
+	return nil
+}
+
+// reportProgress logs progress and passes it to the configured callback
+func (s *Syncer) reportProgress(progress SyncProgress) {
+	log.Printf("Sync progress (%s): %d/%d", progress.Stage, progress.Height, progress.TargetHeight)
+	if s.config.OnProgress != nil {
+		s.config.OnProgress(progress)
+	}
+}
+
+// request sends a message built around a fresh request ID and waits for the matching response
+func (s *Syncer) request(ctx context.Context, peer *Peer, msgType string, build func(id uint64) interface{}) (json.RawMessage, error) {
+	id := atomic.AddUint64(&s.nextID, 1)
+	req := &syncRequest{peer: peer, response: make(chan json.RawMessage, 1)}
+
+	s.mu.Lock()
+	s.pending[id] = req
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, id)
+		s.mu.Unlock()
+	}()
+
+	if err := peer.Send(msgType, build(id)); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(s.config.RequestTimeout)
+	defer timer.Stop()
+
+	select {
+	case raw := <-req.response:
+		return raw, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("%s request timed out", msgType)
+	case <-peer.closed:
+		return nil, errors.New("peer disconnected")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// handleResponse delivers a headers, blocks, snapshot or filters response to the request waiting for it
+func (s *Syncer) handleResponse(peer *Peer, msg *Message) error {
+	var envelope struct {
+		RequestID uint64 `json:"requestId"`
+	}
+	if err := json.Unmarshal(msg.Payload, &envelope); err != nil {
+		return fmt.Errorf("malformed %s: %w", msg.Type, err)
+	}
+
+	s.mu.Lock()
+	req, exists := s.pending[envelope.RequestID]
+	s.mu.Unlock()
+
+	// Unsolicited or late responses are ignored
+	if !exists || req.peer != peer {
+		return nil
+	}
+
+	select {
+	case req.response <- msg.Payload:
+	default:
+	}
+	return nil
+}
+
+// handleGetHeaders serves consecutive headers from the local chain
+func (s *Syncer) handleGetHeaders(peer *Peer, msg *Message) error {
+	var req getHeadersPayload
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		return fmt.Errorf("malformed getheaders: %w", err)
+	}
+
+	count := req.Count
+	if count <= 0 || count > maxHeadersPerMessage {
+		count = maxHeadersPerMessage
+	}
+	if req.FromIndex < 0 {
+		req.FromIndex = 0
+	}
+
+	resp := headersPayload{RequestID: req.RequestID, Headers: []BlockHeader{}}
+
+	s.lock.Lock()
+	latest := s.chain.GetLatestBlock().Index
+	for index := req.FromIndex; index <= latest && len(resp.Headers) < count; index++ {
+		header, err := s.chain.GetHeader(index)
+		if err != nil {
+			break
+		}
+		resp.Headers = append(resp.Headers, header)
+	}
+	s.lock.Unlock()
+
+	return peer.Send(MsgHeaders, resp)
+}
+
+// handleGetBlocks serves full blocks by hash, stopping at the first unknown block
+func (s *Syncer) handleGetBlocks(peer *Peer, msg *Message) error {
+	var req getBlocksPayload
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		return fmt.Errorf("malformed getblocks: %w", err)
+	}
+	if len(req.Hashes) > maxBlocksPerMessage {
+		req.Hashes = req.Hashes[:maxBlocksPerMessage]
+	}
+	if err := checkHashList(req.Hashes); err != nil {
+		return fmt.Errorf("malformed getblocks: %w", err)
+	}
+
+	resp := blocksPayload{RequestID: req.RequestID, Blocks: []*Block{}}
+
+	s.lock.Lock()
+	for _, hash := range req.Hashes {
+		block, err := s.chain.GetBlockByHash(hash)
+		if err != nil {
+			break
+		}
+		resp.Blocks = append(resp.Blocks, block)
+	}
+	s.lock.Unlock()
+
+	return peer.Send(MsgBlocks, resp)
+}
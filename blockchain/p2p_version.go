@@ -0,0 +1,50 @@
+package blockchain
+
+import (
+	"fmt"
+)
+
+// ChainStatus is the chain surface announced in the handshake
+type ChainStatus interface {
+	GetLatestBlock() *Block
+	GetBlockByIndex(index int64) (*Block, error)
+}
+
+// chainStatus returns the genesis hash and best height of the local chain, if one is configured
+func (n *Node) chainStatus() (string, int64) {
+	if n.config.Chain == nil {
+		return "", 0
+	}
+
+	n.config.ChainLock.Lock()
+	defer n.config.ChainLock.Unlock()
+
+	// The header hash identifies the genesis block even where its Hash field was never filled in
+	genesisHash := ""
+	if genesis, err := n.config.Chain.GetBlockByIndex(0); err == nil {
+		genesisHash = genesis.calculateHash()
+	}
+	bestHeight := int64(0)
+	if latest := n.config.Chain.GetLatestBlock(); latest != nil {
+		bestHeight = latest.Index
+	}
+	return genesisHash, bestHeight
+}
+
+// checkCompatibility refuses peers speaking a protocol version or following a chain this node can't work with.
+// Chain fields are only compared when both sides announce them, so older peers are still accepted.
+func (n *Node) checkCompatibility(hello *helloPayload) error {
+	if hello.ProtocolVersion < MinProtocolVersion {
+		return fmt.Errorf("peer protocol version %d is older than the minimum %d", hello.ProtocolVersion, MinProtocolVersion)
+	}
+
+	if n.config.ChainID != "" && hello.ChainID != "" && hello.ChainID != n.config.ChainID {
+		return fmt.Errorf("peer is on chain %q, not %q", hello.ChainID, n.config.ChainID)
+	}
+
+	genesisHash, _ := n.chainStatus()
+	if genesisHash != "" && hello.GenesisHash != "" && hello.GenesisHash != genesisHash {
+		return fmt.Errorf("peer genesis block %s does not match %s", hello.GenesisHash, genesisHash)
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package blockchain
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// PeerRecord holds the connection history of a known peer
+type PeerRecord struct {
+	Address       string  `json:"address"`
+	FirstSeen     int64   `json:"firstSeen"`
+	LastSeen      int64   `json:"lastSeen"`
+	LastConnected int64   `json:"lastConnected"`
+	LastFailure   int64   `json:"lastFailure"`
+	Attempts      int64   `json:"attempts"`
+	Successes     int64   `json:"successes"`
+	Failures      int64   `json:"failures"`
+	TotalUptime   int64   `json:"totalUptime"` // Seconds spent connected
+	Score         float64 `json:"score"`
+}
+
+// PeerStore persists peer connection history for the P2P node
+type PeerStore interface {
+	RecordPeerAttempt(address string, success bool) error
+	RecordPeerUptime(address string, seconds int64) error
+	RecordPeerAddress(address string) error
+	GetBestPeers(limit int) ([]*PeerRecord, error)
+	PrunePeers(lastSeenBefore int64, maxFailures int64) (int, error)
+}
+
+// QualityScore rates a peer from its connection history.
+// Reliable, long-lived peers score high; recent failures pull the score down.
+func (pr *PeerRecord) QualityScore(now int64) float64 {
+	// Success ratio with a neutral prior so new peers start around 50
+	reliability := float64(pr.Successes+1) / float64(pr.Attempts+2) * 100
+
+	// Up to 25 bonus points for accumulated uptime (capped at one week)
+	uptimeHours := math.Min(float64(pr.TotalUptime)/3600, 168)
+	uptimeBonus := uptimeHours / 168 * 25
+
+	// Penalty for a failure within the last hour, fading to zero after it
+	failurePenalty := 0.0
+	if pr.LastFailure > 0 && now-pr.LastFailure < 3600 {
+		failurePenalty = 30 * float64(3600-(now-pr.LastFailure)) / 3600
+	}
+
+	return reliability + uptimeBonus - failurePenalty
+}
+
+// RecordPeerAttempt records a connection attempt to a peer and updates its score
+func (d *Database) RecordPeerAttempt(address string, success bool) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	record, err := d.getPeer(tx, address)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	record.LastSeen = now
+	record.Attempts++
+	if success {
+		record.Successes++
+		record.LastConnected = now
+	} else {
+		record.Failures++
+		record.LastFailure = now
+	}
+
+	if err := d.savePeer(tx, record, now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RecordPeerUptime adds connected time to a peer's history once it disconnects
+func (d *Database) RecordPeerUptime(address string, seconds int64) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	record, err := d.getPeer(tx, address)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	record.LastSeen = now
+	record.TotalUptime += seconds
+
+	if err := d.savePeer(tx, record, now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RecordPeerAddress remembers an address advertised by the network without connecting to it
+func (d *Database) RecordPeerAddress(address string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	record, err := d.getPeer(tx, address)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	record.LastSeen = now
+
+	if err := d.savePeer(tx, record, now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetBestPeers returns known peers ordered by quality score, best first
+func (d *Database) GetBestPeers(limit int) ([]*PeerRecord, error) {
+	// Stored scores keep the failure penalty they were saved with, so every peer is
+	// rescored before ranking rather than letting the query order and cut them
+	rows, err := d.db.Query(`
+		SELECT address, first_seen, last_seen, last_connected, last_failure,
+		       attempts, successes, failures, total_uptime, score
+		FROM peers ORDER BY last_connected DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := time.Now().Unix()
+	var peers []*PeerRecord
+	for rows.Next() {
+		var record PeerRecord
+		if err := rows.Scan(&record.Address, &record.FirstSeen, &record.LastSeen,
+			&record.LastConnected, &record.LastFailure, &record.Attempts,
+			&record.Successes, &record.Failures, &record.TotalUptime, &record.Score); err != nil {
+			return nil, err
+		}
+		record.Score = record.QualityScore(now)
+		peers = append(peers, &record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Stable, so equal scores keep the most recently connected peer first
+	sort.SliceStable(peers, func(i, j int) bool {
+		return peers[i].Score > peers[j].Score
+	})
+	if len(peers) > limit {
+		peers = peers[:limit]
+	}
+	return peers, nil
+}
+
+// PrunePeers forgets peers not seen since the given time, and peers that failed
+// maxFailures times without ever connecting. It returns the number of peers removed.
+func (d *Database) PrunePeers(lastSeenBefore int64, maxFailures int64) (int, error) {
+	result, err := d.db.Exec(d.rebind(`
+		DELETE FROM peers WHERE last_seen < ? OR (successes = 0 AND failures >= ?)`),
+		lastSeenBefore, maxFailures)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune peers: %w", err)
+	}
+	removed, _ := result.RowsAffected()
+	return int(removed), nil
+}
+
+// GetPeer retrieves the connection history of a single peer
+func (d *Database) GetPeer(address string) (*PeerRecord, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	return d.getPeer(tx, address)
+}
+
+// getPeer loads a peer record, returning a fresh record for unknown peers
+func (d *Database) getPeer(tx *sql.Tx, address string) (*PeerRecord, error) {
+	record := &PeerRecord{Address: address}
+	err := tx.QueryRow(d.rebind(`
+		SELECT first_seen, last_seen, last_connected, last_failure,
+		       attempts, successes, failures, total_uptime, score
+		FROM peers WHERE address = ?`), address).Scan(
+		&record.FirstSeen, &record.LastSeen, &record.LastConnected, &record.LastFailure,
+		&record.Attempts, &record.Successes, &record.Failures, &record.TotalUptime, &record.Score)
+
+	if err == sql.ErrNoRows {
+		record.FirstSeen = time.Now().Unix()
+		return record, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load peer: %w", err)
+	}
+	return record, nil
+}
+
+// savePeer writes a peer record with a freshly computed score
+func (d *Database) savePeer(tx *sql.Tx, record *PeerRecord, now int64) error {
+	record.Score = record.QualityScore(now)
+
+	result, err := tx.Exec(d.rebind(`
+		UPDATE peers SET last_seen = ?, last_connected = ?, last_failure = ?, attempts = ?,
+			successes = ?, failures = ?, total_uptime = ?, score = ?
+		WHERE address = ?`),
+		record.LastSeen, record.LastConnected, record.LastFailure, record.Attempts,
+		record.Successes, record.Failures, record.TotalUptime, record.Score, record.Address)
+	if err != nil {
+		return fmt.Errorf("failed to update peer: %w", err)
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		_, err = tx.Exec(d.rebind(`
+			INSERT INTO peers (address, first_seen, last_seen, last_connected, last_failure,
+				attempts, successes, failures, total_uptime, score)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+			record.Address, record.FirstSeen, record.LastSeen, record.LastConnected, record.LastFailure,
+			record.Attempts, record.Successes, record.Failures, record.TotalUptime, record.Score)
+		if err != nil {
+			return fmt.Errorf("failed to insert peer: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package blockchain
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGetBestPeersRanksByCurrentScore(t *testing.T) {
+	db, err := NewDatabase(DatabaseConfig{Driver: "sqlite3", Path: filepath.Join(t.TempDir(), "chain.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	// A reliable peer saved right after a failure, whose penalty has since worn off
+	for _, success := range []bool{true, true, true, false} {
+		if err := db.RecordPeerAttempt("reliable:8333", success); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.db.Exec(db.rebind(`UPDATE peers SET last_failure = ? WHERE address = ?`),
+		time.Now().Add(-2*time.Hour).Unix(), "reliable:8333"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RecordPeerAddress("unknown:8333"); err != nil {
+		t.Fatal(err)
+	}
+
+	peers, err := db.GetBestPeers(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 1 || peers[0].Address != "reliable:8333" {
+		t.Fatalf("best peers are %+v, want the reliable peer", peers)
+	}
+}
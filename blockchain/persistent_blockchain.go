@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"time"
 )
 
 // PersistentBlockchain represents a blockchain with database persistence
@@ -11,18 +12,58 @@ type PersistentBlockchain struct {
 	Chain            []*Block
 	Difficulty       int
 	TransactionPool  *TransactionPool
-	EnhancedPool     *EnhancedTransactionPool
+	EnhancedPool     *EnhancedTransactionPool // Same mempool as TransactionPool
+	OrphanPool       *OrphanPool
+	Upgrades         *UpgradeSchedule
+	NetworkTime      *NetworkTime      // Optional peer-adjusted clock for block timestamps
+	Recorder         *WorkloadRecorder // Optional log of accepted transactions and blocks for replay
 	MiningReward     float64
 	MiningRewardAddr string
-	Database         *Database
+	Database         Storage // SQL *Database or LevelDBStorage, chosen by DatabaseConfig.Driver
+	txIndex          *txIndex
+	policyIndex      *spendPolicyIndex
+	haltIndex        *haltIndex
+	history          *historyMMR
+	state            *stateTrie
+	filterHeaders    *filterHeaderChain
+	haltPolicy       *HaltPolicy
+	rewardPolicy     *RewardPolicy
+	feePolicy        *FeePolicy
+	extensions       *ExtensionRegistry
+	checkpoints      []Checkpoint
+	signedOnly       bool
+	params           *ChainParams
+	dbConfig         DatabaseConfig
+	pruneKeep        int64       // Blocks whose bodies are kept, 0 if pruning is off
+	pruneState       *PruneState // Summary of the pruned blocks
+	snapshotPolicy   *SnapshotPolicy
 }
 
 // NewPersistentBlockchain creates a new blockchain with database persistence
 func NewPersistentBlockchain(difficulty int, miningRewardAddr string, dbConfig DatabaseConfig) (*PersistentBlockchain, error) {
 	// Initialize database
-	db, err := NewDatabase(dbConfig)
+	db, err := OpenStorage(dbConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize database: %v", err)
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+
+	// Rebuild derived tables left behind by an older version or an interrupted reindex
+	if needed, err := db.NeedsReindex(); err != nil {
+		log.Printf("Warning: failed to check derived tables: %v", err)
+	} else if needed {
+		log.Printf("Reindexing derived tables from stored blocks")
+		report, err := db.Reindex(ReindexConfig{})
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to reindex database: %w", err)
+		}
+		log.Printf("Reindexed %d blocks (%d transactions)", report.Blocks, report.Transactions)
+	}
+
+	pruneState, err := db.LoadPruneState()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to load prune state: %w", err)
 	}
 
 	// Try to load existing blockchain from database
@@ -42,22 +83,46 @@ func NewPersistentBlockchain(difficulty int, miningRewardAddr string, dbConfig D
 		}
 	}
 
+	// Standard and enhanced transactions share one mempool
+	mempool := NewMempool(1000)
 	pbc := &PersistentBlockchain{
 		Chain:            chain,
 		Difficulty:       difficulty,
-		TransactionPool:  NewTransactionPool(1000),
-		EnhancedPool:     NewEnhancedTransactionPool(1000),
+		TransactionPool:  mempool,
+		EnhancedPool:     mempool,
+		OrphanPool:       NewOrphanPool(100, 20*time.Minute),
+		Upgrades:         &UpgradeSchedule{},
 		MiningReward:     10.0,
 		MiningRewardAddr: miningRewardAddr,
 		Database:         db,
+		dbConfig:         dbConfig,
+		pruneState:       pruneState,
+	}
+	pbc.TransactionPool.SetChainView(pbc)
+	pbc.TransactionPool.SetSpendPolicyView(pbc)
+	pbc.TransactionPool.SetHaltView(pbc)
+	pbc.TransactionPool.SetTipView(pbc)
+	// SQL databases keep the enhanced transactions; blocks saved to them mark those executed
+	if store, ok := db.(EnhancedTransactionStore); ok {
+		pbc.EnhancedPool.SetStore(store)
+	}
+
+	// Re-admit the transactions pending when the node last shut down
+	if restored, dropped, err := pbc.TransactionPool.Restore(db); err != nil {
+		log.Printf("Warning: failed to restore pending transactions: %v", err)
+	} else if restored+dropped > 0 {
+		log.Printf("Restored %d pending transactions (%d no longer valid)", restored, dropped)
 	}
 
 	log.Printf("Loaded blockchain with %d blocks from database", len(chain))
 	return pbc, nil
 }
 
-// Close closes the blockchain and database connections
+// Close saves the pending transactions and closes the blockchain and database connections
 func (pbc *PersistentBlockchain) Close() error {
+	if err := pbc.TransactionPool.Persist(pbc.Database); err != nil {
+		log.Printf("Warning: failed to save pending transactions: %v", err)
+	}
 	return pbc.Database.Close()
 }
 
@@ -68,20 +133,26 @@ func (pbc *PersistentBlockchain) GetLatestBlock() *Block {
 
 // MinePendingTransactions mines pending transactions and persists the new block
 func (pbc *PersistentBlockchain) MinePendingTransactions() error {
-	// Create mining reward transaction
-	rewardTx := NewTransaction("network", pbc.MiningRewardAddr, pbc.MiningReward, 0)
-	pbc.TransactionPool.AddTransaction(rewardTx)
+	// While the chain is halted only a block resuming it may be produced
+	_, halted := pbc.GetChainHalt()
+	if halted && !hasResumeTransaction(pbc.TransactionPool.GetTransactions()) {
+		return ErrChainHalted
+	}
 
-	// Get transactions from pool
-	pendingTxs := pbc.TransactionPool.GetTransactions()
+	// Create mining reward transaction (and cold storage sweeps)
+	addRewardTransactions(pbc.TransactionPool, pbc.rewardTransactions())
 
-	// Also get executable enhanced transactions
-	_, enhancedTxs := pbc.EnhancedPool.GetExecutableTransactions()
+	// Drop transactions whose validity window ends before this block
+	timestamp := pbc.adjustedTime()
+	for _, event := range pbc.TransactionPool.DropExpiredAt(int64(len(pbc.Chain)), timestamp) {
+		log.Printf("Dropped pending transaction %s (%s): %s", event.Transaction.Hash, event.Reason, event.Detail)
+	}
 
-	// Convert enhanced transactions to standard format for block inclusion
-	for _, eTx := range enhancedTxs {
-		standardTx := eTx.ToStandardTransaction()
-		pendingTxs = append(pendingTxs, &standardTx)
+	// Get transactions from pool, each after the pending transactions it depends on, with
+	// executable enhanced transactions in standard form
+	pendingTxs := pbc.TransactionPool.SelectTransactions(MaxBlockTransactions)
+	if halted {
+		pendingTxs = haltBlockTransactions(pendingTxs)
 	}
 
 	// Convert []*Transaction to []Transaction
@@ -97,6 +168,12 @@ func (pbc *PersistentBlockchain) MinePendingTransactions() error {
 		pbc.GetLatestBlock().Hash,
 	)
 
+	block.setVersion(pbc.Upgrades.VersionAt(block.Index))
+	block.Timestamp = timestamp
+	block.MMRRoot = historyCommitment(pbc.params, pbc.historyRange(), block.Index)
+	block.StateRoot = stateCommitment(pbc.params, pbc.currentState(), block)
+	pbc.extensions.produce(block)
+
 	// Mine the block
 	log.Printf("Mining block %d with %d transactions...", block.Index, len(transactions))
 	block.MineBlock(pbc.Difficulty)
@@ -107,27 +184,229 @@ func (pbc *PersistentBlockchain) MinePendingTransactions() error {
 	// Save block to database
 	if err := pbc.Database.SaveBlock(block); err != nil {
 		log.Printf("Error saving block to database: %v", err)
-		// Remove block from chain if database save failed
+		// Remove block from chain if database save failed; its transactions are still pending
 		pbc.Chain = pbc.Chain[:len(pbc.Chain)-1]
-		return fmt.Errorf("failed to persist block: %v", err)
+		return fmt.Errorf("failed to persist block: %w", err)
 	}
 
-	// Remove mined transactions from pools
+	// Remove mined transactions from pool
 	pbc.TransactionPool.RemoveTransactions(pendingTxs)
-	pbc.EnhancedPool.RemoveEnhancedTransactions(enhancedTxs)
+
+	if pbc.Recorder != nil {
+		pbc.Recorder.RecordBlock(block)
+	}
 
 	log.Printf("Block %d mined and persisted successfully", block.Index)
+
+	if err := pbc.prune(false); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	pbc.snapshot()
+	return nil
+}
+
+// AddBlock validates, persists and appends an externally mined block.
+// Blocks whose parent is not known yet are held in the orphan pool, reported with
+// ErrOrphanBlock, and connected automatically once the missing parent arrives.
+func (pbc *PersistentBlockchain) AddBlock(block *Block) error {
+	if pbc.hasBlock(block.Hash) || pbc.OrphanPool.HasOrphan(block.Hash) {
+		return ErrBlockKnown
+	}
+
+	if !pbc.hasBlock(block.PrevHash) {
+		if err := pbc.OrphanPool.AddOrphan(block, pbc.Difficulty); err != nil {
+			return err
+		}
+		log.Printf("Block %d arrived before its parent, holding as orphan", block.Index)
+		return ErrOrphanBlock
+	}
+
+	if err := pbc.connectBlock(block); err != nil {
+		return err
+	}
+
+	// Connect any orphans that were waiting on this block
+	parents := []string{block.Hash}
+	for len(parents) > 0 {
+		parentHash := parents[0]
+		parents = parents[1:]
+
+		for _, orphan := range pbc.OrphanPool.TakeChildren(parentHash) {
+			if err := pbc.connectBlock(orphan); err != nil {
+				log.Printf("Discarding orphan block %d: %v", orphan.Index, err)
+				continue
+			}
+			parents = append(parents, orphan.Hash)
+		}
+	}
+
+	return nil
+}
+
+// connectBlock validates a block against the chain tip, persists it and appends it
+func (pbc *PersistentBlockchain) connectBlock(block *Block) error {
+	latest := pbc.GetLatestBlock()
+	if block.PrevHash != latest.Hash {
+		return ErrNotChainTip
+	}
+
+	if err := pbc.validateBlock(block, latest); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidBlock, err)
+	}
+
+	if err := pbc.Database.SaveBlock(block); err != nil {
+		return fmt.Errorf("failed to persist block: %w", err)
+	}
+
+	pbc.Chain = append(pbc.Chain, block)
+
+	// Drop transactions confirmed by this block from the pool
+	confirmed := make([]*Transaction, len(block.Transactions))
+	for i := range block.Transactions {
+		confirmed[i] = &block.Transactions[i]
+	}
+	pbc.TransactionPool.RemoveTransactions(confirmed)
+
+	if pbc.Recorder != nil {
+		pbc.Recorder.RecordBlock(block)
+	}
+
+	log.Printf("Block %d connected and persisted successfully", block.Index)
+
+	if err := pbc.prune(false); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	pbc.snapshot()
+	return nil
+}
+
+// validateBlock checks a block extending the chain tip against the consensus rules
+func (pbc *PersistentBlockchain) validateBlock(block, latest *Block) error {
+	if block.Pruned {
+		return errors.New("block has no body")
+	}
+
+	if err := CheckBlockLimits(block); err != nil {
+		return err
+	}
+
+	if err := block.validateAgainstParent(latest, pbc.Difficulty); err != nil {
+		return err
+	}
+
+	if block.Timestamp > pbc.adjustedTime()+MaxFutureBlockTime {
+		return errors.New("block timestamp is too far in the future")
+	}
+
+	if err := pbc.Upgrades.ValidateBlock(block); err != nil {
+		return err
+	}
+
+	if err := checkMMRRoot(block, pbc.params, pbc.historyRange()); err != nil {
+		return err
+	}
+
+	if err := checkStateRoot(block, pbc.params, pbc.currentState()); err != nil {
+		return err
+	}
+
+	if err := pbc.extensions.ValidateBlock(block); err != nil {
+		return err
+	}
+
+	if err := pbc.feePolicy.ValidateBlock(block); err != nil {
+		return err
+	}
+
+	if err := checkBlockAddresses(block, pbc.params); err != nil {
+		return err
+	}
+
+	if err := checkBlockSignatures(block, pbc.signedOnly, pbc.Upgrades, pbc.params); err != nil {
+		return err
+	}
+
+	if err := checkBlockExpiry(block); err != nil {
+		return err
+	}
+
+	if err := checkDoubleSpends(block, pbc); err != nil {
+		return err
+	}
+
+	if err := pbc.spendPolicies().checkBlock(block); err != nil {
+		return err
+	}
+
+	if err := pbc.halts().checkBlock(block, pbc.haltPolicy); err != nil {
+		return err
+	}
 	return nil
 }
 
+// adjustedTime returns the current time, corrected by the network time offset if available
+func (pbc *PersistentBlockchain) adjustedTime() int64 {
+	if pbc.NetworkTime != nil {
+		return pbc.NetworkTime.Now()
+	}
+	return time.Now().Unix()
+}
+
+// hasBlock checks whether a block with the given hash is part of the chain
+func (pbc *PersistentBlockchain) hasBlock(hash string) bool {
+	for i := len(pbc.Chain) - 1; i >= 0; i-- {
+		if pbc.Chain[i].Hash == hash {
+			return true
+		}
+	}
+	return false
+}
+
 // AddTransaction adds a new transaction to the transaction pool
 func (pbc *PersistentBlockchain) AddTransaction(tx *Transaction) error {
-	return pbc.TransactionPool.AddTransaction(tx)
+	return pbc.AddTransactionFrom(tx, AdmissionSourceLocal)
+}
+
+// AddTransactionFrom adds a new transaction to the transaction pool, recording its source in the admission log
+func (pbc *PersistentBlockchain) AddTransactionFrom(tx *Transaction, source string) error {
+	if err := pbc.TransactionPool.AddTransactionFrom(tx, source); err != nil {
+		return err
+	}
+	if pbc.Recorder != nil {
+		pbc.Recorder.RecordTransaction(tx)
+	}
+	return nil
+}
+
+// AddTransactions adds a batch of transactions to the transaction pool, returning the outcome
+// of every transaction at its index (nil for those accepted)
+func (pbc *PersistentBlockchain) AddTransactions(txs []*Transaction) []error {
+	return pbc.AddTransactionsFrom(txs, AdmissionSourceLocal)
+}
+
+// AddTransactionsFrom adds a batch of transactions to the transaction pool in one critical
+// section, recording their source in the admission log
+func (pbc *PersistentBlockchain) AddTransactionsFrom(txs []*Transaction, source string) []error {
+	errs := pbc.TransactionPool.AddTransactionsFrom(txs, source)
+	if pbc.Recorder != nil {
+		for i, tx := range txs {
+			if errs[i] == nil {
+				pbc.Recorder.RecordTransaction(tx)
+			}
+		}
+	}
+	return errs
 }
 
 // AddEnhancedTransaction adds a new enhanced transaction to the enhanced pool
 func (pbc *PersistentBlockchain) AddEnhancedTransaction(tx *EnhancedTransaction) error {
-	return pbc.EnhancedPool.AddEnhancedTransaction(tx)
+	if err := pbc.EnhancedPool.AddEnhancedTransaction(tx); err != nil {
+		return err
+	}
+	if pbc.Recorder != nil {
+		pbc.Recorder.RecordEnhancedTransaction(tx)
+	}
+	return nil
 }
 
 // GetBalance calculates the balance of an address (from database for better performance)
@@ -142,15 +421,28 @@ func (pbc *PersistentBlockchain) GetBalance(address string) float64 {
 	return balance
 }
 
+// GetPendingTransaction returns a transaction waiting in the pool
+func (pbc *PersistentBlockchain) GetPendingTransaction(hash string) (*Transaction, bool) {
+	return pbc.TransactionPool.GetTransaction(hash)
+}
+
+// GetReservedBalance returns the amount an address has committed to pending transactions
+func (pbc *PersistentBlockchain) GetReservedBalance(address string) float64 {
+	return pbc.TransactionPool.GetReservedBalance(address)
+}
+
+// GetAvailableBalance returns the balance of an address minus its pending spends
+func (pbc *PersistentBlockchain) GetAvailableBalance(address string) float64 {
+	return pbc.GetBalance(address) - pbc.TransactionPool.GetReservedBalance(address)
+}
+
 // calculateBalanceFromChain calculates balance by iterating through the chain (fallback method)
 func (pbc *PersistentBlockchain) calculateBalanceFromChain(address string) float64 {
-	var balance float64
+	balance := pbc.prunedState().Balances[address]
 
 	for _, block := range pbc.Chain {
 		for _, tx := range block.Transactions {
-			if tx.From == address {
-				balance -= tx.Amount + tx.Fee
-			}
+			balance -= tx.debit(address)
 			if tx.To == address {
 				balance += tx.Amount
 			}
@@ -162,43 +454,194 @@ func (pbc *PersistentBlockchain) calculateBalanceFromChain(address string) float
 
 // IsChainValid verifies if the blockchain is valid
 func (pbc *PersistentBlockchain) IsChainValid() bool {
-	for i := 1; i < len(pbc.Chain); i++ {
-		currentBlock := pbc.Chain[i]
-		previousBlock := pbc.Chain[i-1]
-
-		// Verify current block's hash
-		if currentBlock.Hash != currentBlock.calculateHash() {
-			log.Printf("Invalid hash at block %d", i)
+	validator := pbc.newChainValidator()
+	for _, block := range pbc.Chain {
+		if err := validator.add(block); err != nil {
+			log.Printf("%v", err)
 			return false
 		}
+	}
+	return true
+}
 
-		// Verify chain linkage
-		if currentBlock.PrevHash != previousBlock.Hash {
-			log.Printf("Invalid chain linkage at block %d", i)
-			return false
+// ValidateStoredChain validates the chain in storage as it is streamed, holding one block at
+// a time rather than the chain, and returns the first problem found
+func (pbc *PersistentBlockchain) ValidateStoredChain() error {
+	validator := pbc.newChainValidator()
+	return pbc.Database.IterateBlocks(validator.add)
+}
+
+// chainValidator checks the blocks of a chain in height order, keeping only the state the
+// checks need: the previous block, the spends, the spend policies, the history range and the
+// state trie
+type chainValidator struct {
+	pbc      *PersistentBlockchain
+	pruned   *PruneState
+	spends   *spendTracker
+	policies *spendPolicyIndex
+	history  *historyMMR
+	state    *stateTrie
+	prev     *Block
+}
+
+// newChainValidator creates a validator for the chain from genesis
+func (pbc *PersistentBlockchain) newChainValidator() *chainValidator {
+	pruned := pbc.prunedState()
+	return &chainValidator{
+		pbc:      pbc,
+		pruned:   pruned,
+		spends:   pruned.tracker(),
+		policies: newSpendPolicyIndex(),
+		history:  newHistoryMMR(),
+		state:    newStateTrie(pruned),
+	}
+}
+
+// add validates the next block of the chain and applies it to the validator's state
+func (v *chainValidator) add(currentBlock *Block) error {
+	previousBlock := v.prev
+	v.prev = currentBlock
+	if previousBlock == nil {
+		v.spends.addBlock(currentBlock)
+		v.policies.addBlock(currentBlock)
+		v.history.append(currentBlock.Hash)
+		v.state.addBlock(currentBlock)
+		return nil
+	}
+	i := currentBlock.Index
+
+	// Verify current block's hash
+	if currentBlock.Hash != currentBlock.calculateHash() {
+		return fmt.Errorf("invalid hash at block %d", i)
+	}
+
+	// Verify chain linkage
+	if currentBlock.PrevHash != previousBlock.Hash || i != previousBlock.Index+1 {
+		return fmt.Errorf("invalid chain linkage at block %d", i)
+	}
+
+	// Verify the committed history root, which covers pruned blocks too
+	if err := checkMMRRoot(currentBlock, v.pbc.params, v.history); err != nil {
+		return fmt.Errorf("invalid block %d: %w", i, err)
+	}
+	v.history.append(currentBlock.Hash)
+
+	// Only the header of a pruned block is left to check
+	if currentBlock.Pruned {
+		return nil
+	}
+
+	// Verify Merkle tree integrity
+	if !currentBlock.ValidateTransactions() {
+		return fmt.Errorf("invalid Merkle tree at block %d", i)
+	}
+	if err := currentBlock.validateKVRoot(); err != nil {
+		return fmt.Errorf("invalid block %d: %w", i, err)
+	}
+	if err := currentBlock.validateReceiptRoot(); err != nil {
+		return fmt.Errorf("invalid block %d: %w", i, err)
+	}
+	if err := currentBlock.validateBloom(); err != nil {
+		return fmt.Errorf("invalid block %d: %w", i, err)
+	}
+	if err := currentBlock.validateExtensions(); err != nil {
+		return fmt.Errorf("invalid block %d: %w", i, err)
+	}
+
+	// Verify protocol upgrade rules
+	if err := v.pbc.Upgrades.ValidateBlock(currentBlock); err != nil {
+		return fmt.Errorf("invalid block %d: %w", i, err)
+	}
+
+	// Verify attached signatures, required from a RequireSignatures upgrade on (blocks predating
+	// SetRequireSignatures may hold unsigned transactions)
+	if err := checkBlockSignatures(currentBlock, false, v.pbc.Upgrades, v.pbc.params); err != nil {
+		return fmt.Errorf("invalid block %d: %w", i, err)
+	}
+
+	// Verify no transaction confirmed past its validity window
+	if err := checkBlockExpiry(currentBlock); err != nil {
+		return fmt.Errorf("invalid block %d: %w", i, err)
+	}
+
+	// Verify no transaction is replayed or reuses a sender nonce. Blocks kept below the pruned
+	// height were checked when connected; the tracker already holds later pruned spends.
+	if currentBlock.Index >= v.pruned.Height {
+		if err := checkDoubleSpends(currentBlock, v.spends); err != nil {
+			return fmt.Errorf("double spend in block %d: %w", i, err)
 		}
+	}
+	v.spends.addBlock(currentBlock)
 
-		// Verify Merkle tree integrity
-		if !currentBlock.ValidateTransactions() {
-			log.Printf("Invalid Merkle tree at block %d", i)
-			return false
+	// Verify the committed state root. The trie starts from the pruned state, so it only holds
+	// the state blocks from the pruned height on saw.
+	if currentBlock.Index >= v.pruned.Height {
+		if err := checkStateRoot(currentBlock, v.pbc.params, v.state); err != nil {
+			return fmt.Errorf("invalid block %d: %w", i, err)
 		}
 	}
+	v.state.addBlock(currentBlock)
 
-	return true
+	// Verify spends respect the spend policies in force
+	if err := v.policies.checkBlock(currentBlock); err != nil {
+		return fmt.Errorf("invalid block %d: %w", i, err)
+	}
+	v.policies.addBlock(currentBlock)
+	return nil
+}
+
+// IsTransactionConfirmed checks if a non-coinbase transaction is included in the chain
+func (pbc *PersistentBlockchain) IsTransactionConfirmed(hash string) bool {
+	if _, pruned := pbc.prunedState().Transactions[hash]; pruned {
+		return true
+	}
+	tx, _, err := pbc.GetTransaction(hash)
+	return err == nil && !tx.IsCoinbase()
+}
+
+// IsAddressUsed checks if an address has sent or received a confirmed transaction
+func (pbc *PersistentBlockchain) IsAddressUsed(address string) bool {
+	if _, used := pbc.prunedState().Balances[address]; used {
+		return true
+	}
+	for _, block := range pbc.Chain {
+		for _, tx := range block.Transactions {
+			if tx.From == address || tx.To == address {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetConfirmedNonce returns the highest nonce an address has used in the chain
+func (pbc *PersistentBlockchain) GetConfirmedNonce(address string) int64 {
+	nonce := pbc.prunedState().Nonces[address]
+	for _, block := range pbc.Chain {
+		for _, tx := range block.Transactions {
+			if tx.From == address && tx.Nonce > nonce {
+				nonce = tx.Nonce
+			}
+		}
+	}
+	return nonce
 }
 
 // GetTransactionProof generates a Merkle proof for a transaction in a specific block
 func (pbc *PersistentBlockchain) GetTransactionProof(blockIndex int, txHash string) (*MerkleProof, error) {
 	if blockIndex < 0 || blockIndex >= len(pbc.Chain) {
-		return nil, errors.New("invalid block index")
+		return nil, fmt.Errorf("invalid block index: %w", ErrBlockNotFound)
 	}
 
 	block := pbc.Chain[blockIndex]
+	if block.Pruned {
+		return nil, fmt.Errorf("block %d: %w", blockIndex, ErrPruned)
+	}
 	return block.GenerateTransactionProof(txHash)
 }
 
-// VerifyTransactionInBlock verifies that a transaction exists in a specific block
+// VerifyTransactionInBlock verifies that a transaction exists in a specific block.
+// It only needs the block header, so proofs still verify after the block is pruned.
 func (pbc *PersistentBlockchain) VerifyTransactionInBlock(blockIndex int, proof *MerkleProof) bool {
 	if blockIndex < 0 || blockIndex >= len(pbc.Chain) {
 		return false
@@ -208,17 +651,37 @@ func (pbc *PersistentBlockchain) VerifyTransactionInBlock(blockIndex int, proof
 	return block.VerifyTransactionProof(proof)
 }
 
+// GetKVProof proves the latest value set for a key of a namespace, from the block that set it
+func (pbc *PersistentBlockchain) GetKVProof(namespace, key string) (*KVProof, error) {
+	for i := len(pbc.Chain) - 1; i >= 0; i-- {
+		if proof, err := pbc.Chain[i].GenerateKVProof(namespace, key); err == nil {
+			return proof, nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+// GetKVValue returns the latest value set for a key of a namespace
+func (pbc *PersistentBlockchain) GetKVValue(namespace, key string) (string, bool) {
+	proof, err := pbc.GetKVProof(namespace, key)
+	if err != nil {
+		return "", false
+	}
+	return proof.Entry.Value, true
+}
+
 // GetBlockchainStats returns comprehensive blockchain statistics
 func (pbc *PersistentBlockchain) GetBlockchainStats() (map[string]interface{}, error) {
 	// Get stats from database
 	dbStats, err := pbc.Database.GetBlockchainStats()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get database stats: %v", err)
+		return nil, fmt.Errorf("failed to get database stats: %w", err)
 	}
 
 	// Add memory pool stats
 	dbStats["pending_transactions"] = len(pbc.TransactionPool.GetTransactions())
-	dbStats["pending_enhanced_transactions"] = len(pbc.EnhancedPool.GetAllTransactions())
+	_, executable := pbc.EnhancedPool.GetExecutableTransactions()
+	dbStats["pending_enhanced_transactions"] = len(executable)
 
 	// Add enhanced transaction pool stats
 	enhancedStats := pbc.EnhancedPool.GetTransactionStats()
@@ -229,28 +692,43 @@ func (pbc *PersistentBlockchain) GetBlockchainStats() (map[string]interface{}, e
 	// Add chain validation status
 	dbStats["chain_valid"] = pbc.IsChainValid()
 	dbStats["in_memory_blocks"] = len(pbc.Chain)
+	dbStats["pruned_height"] = pbc.PrunedHeight()
 
 	return dbStats, nil
 }
 
-// RecoverFromDatabase recovers the blockchain state from database
+// RecoverFromDatabase recovers the blockchain state from database. The stored blocks are
+// validated as they are streamed, so an invalid chain is rejected at its first bad block
+// without loading the rest.
 func (pbc *PersistentBlockchain) RecoverFromDatabase() error {
 	log.Println("Recovering blockchain from database...")
 
-	// Load blockchain from database
-	chain, err := pbc.Database.LoadBlockchain()
+	// Stream the stored blocks, validating each against the blocks before it
+	tempBC := &PersistentBlockchain{Upgrades: pbc.Upgrades, params: pbc.params, pruneState: pbc.pruneState}
+	validator := tempBC.newChainValidator()
+	var chain []*Block
+	err := pbc.Database.IterateBlocks(func(block *Block) error {
+		if err := validator.add(block); err != nil {
+			return fmt.Errorf("loaded blockchain is invalid: %w", err)
+		}
+		chain = append(chain, block)
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to load blockchain from database: %v", err)
+		return fmt.Errorf("failed to load blockchain from database: %w", err)
 	}
 
 	if len(chain) == 0 {
 		return errors.New("no blocks found in database")
 	}
 
-	// Validate the loaded chain
-	tempBC := &PersistentBlockchain{Chain: chain}
-	if !tempBC.IsChainValid() {
-		return errors.New("loaded blockchain is invalid")
+	// Recompute the address balances from the blocks, repairing drift left by a failed save
+	report, err := pbc.Database.RebuildAddressIndex()
+	if err != nil {
+		return fmt.Errorf("failed to rebuild address index: %w", err)
+	}
+	if report.Repaired > 0 {
+		log.Printf("Repaired the index entries of %d addresses", report.Repaired)
 	}
 
 	// Update the current blockchain
@@ -267,7 +745,7 @@ func (pbc *PersistentBlockchain) SyncWithDatabase() error {
 	// Get latest block from database
 	latestDBBlock, err := pbc.Database.GetLatestBlock()
 	if err != nil {
-		return fmt.Errorf("failed to get latest block from database: %v", err)
+		return fmt.Errorf("failed to get latest block from database: %w", err)
 	}
 
 	// Compare with in-memory chain
@@ -287,12 +765,12 @@ func (pbc *PersistentBlockchain) SyncWithDatabase() error {
 	return nil
 }
 
-// BackupBlockchain creates a backup of the current blockchain state
-func (pbc *PersistentBlockchain) BackupBlockchain(backupPath string) error {
-	// This would implement blockchain backup functionality
-	// For now, it's a placeholder
-	log.Printf("Backup functionality would save blockchain to: %s", backupPath)
-	return nil
+// GetHeader returns the header of the block at the given height, which outlives a pruned body
+func (pbc *PersistentBlockchain) GetHeader(index int64) (BlockHeader, error) {
+	if index < 0 || index >= int64(len(pbc.Chain)) {
+		return BlockHeader{}, ErrBlockNotFound
+	}
+	return pbc.Chain[index].Header(), nil
 }
 
 // GetBlockByHash retrieves a block by its hash (from database)
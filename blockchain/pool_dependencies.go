@@ -0,0 +1,279 @@
+package blockchain
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// DropParentDropped is the reason of a transaction removed with a pending transaction it
+// depends on
+const DropParentDropped DropReason = "parent_dropped"
+
+// A pending transaction depends on the pending transactions it can't be confirmed without: the
+// previous nonce of its sender, and the transactions crediting the funds it spends when the
+// confirmed balance of its sender or sponsor falls short. Blocks include a transaction only
+// after those it depends on; a transaction expired, evicted or dropped as invalid takes its
+// dependents with it, while one confirmed or replaced leaves them in the pool.
+
+// pendingCredits returns the pending transactions crediting an address, oldest first, until
+// their amounts cover shortfall, and the amount they credit. Transactions that depend or will
+// depend on tx are left out, so no dependency cycle forms (caller must hold the lock).
+func (tp *Mempool) pendingCredits(address string, shortfall float64, tx, replaced *Transaction) ([]string, float64) {
+	var roots []string
+	if replaced != nil {
+		roots = append(roots, replaced.Hash)
+	}
+	if tx.Nonce != 0 {
+		if next, exists := tp.bySenderSeq[fmt.Sprintf("%s:%d", tx.From, tx.Nonce+1)]; exists {
+			roots = append(roots, next)
+		}
+	}
+	excluded := tp.descendantsLocked(roots...)
+
+	var credits []*Transaction
+	for hash, pending := range tp.transactions {
+		if pending.To == address && pending.From != address && !excluded[hash] {
+			credits = append(credits, pending)
+		}
+	}
+	sort.Slice(credits, func(i, j int) bool {
+		if tp.addedAt[credits[i].Hash] != tp.addedAt[credits[j].Hash] {
+			return tp.addedAt[credits[i].Hash] < tp.addedAt[credits[j].Hash]
+		}
+		return credits[i].Hash < credits[j].Hash
+	})
+
+	var hashes []string
+	var amount float64
+	for _, credit := range credits {
+		if amount >= shortfall {
+			break
+		}
+		hashes = append(hashes, credit.Hash)
+		amount += credit.Amount
+	}
+	return hashes, amount
+}
+
+// linkLocked records the dependencies of a transaction entering the pool: on the transactions
+// crediting it and its previous nonce, and of the dependents it takes over and its next nonce
+// on it (caller must hold the lock)
+func (tp *Mempool) linkLocked(tx *Transaction, creditors, dependents []string) {
+	parents := creditors
+	if tx.Nonce > 1 {
+		if prev, exists := tp.bySenderSeq[fmt.Sprintf("%s:%d", tx.From, tx.Nonce-1)]; exists {
+			parents = append(parents, prev)
+		}
+	}
+	for _, parent := range parents {
+		tp.addLinkLocked(parent, tx.Hash)
+	}
+
+	if tx.Nonce != 0 {
+		if next, exists := tp.bySenderSeq[fmt.Sprintf("%s:%d", tx.From, tx.Nonce+1)]; exists {
+			dependents = append(dependents, next)
+		}
+	}
+	for _, dependent := range dependents {
+		if _, pending := tp.transactions[dependent]; pending {
+			tp.addLinkLocked(tx.Hash, dependent)
+		}
+	}
+}
+
+// addLinkLocked makes child depend on parent (caller must hold the lock)
+func (tp *Mempool) addLinkLocked(parent, child string) {
+	if parent == child || slices.Contains(tp.parents[child], parent) {
+		return
+	}
+	tp.parents[child] = append(tp.parents[child], parent)
+	tp.children[parent] = append(tp.children[parent], child)
+}
+
+// unlinkLocked removes the dependencies of a transaction and on it (caller must hold the lock)
+func (tp *Mempool) unlinkLocked(hash string) {
+	for _, parent := range tp.parents[hash] {
+		tp.children[parent] = slices.DeleteFunc(tp.children[parent], func(h string) bool { return h == hash })
+		if len(tp.children[parent]) == 0 {
+			delete(tp.children, parent)
+		}
+	}
+	for _, child := range tp.children[hash] {
+		tp.parents[child] = slices.DeleteFunc(tp.parents[child], func(h string) bool { return h == hash })
+		if len(tp.parents[child]) == 0 {
+			delete(tp.parents, child)
+		}
+	}
+	delete(tp.parents, hash)
+	delete(tp.children, hash)
+}
+
+// descendantsLocked returns the pending transactions depending, directly or not, on the given
+// ones, the given ones included (caller must hold the lock)
+func (tp *Mempool) descendantsLocked(hashes ...string) map[string]bool {
+	found := make(map[string]bool)
+	for len(hashes) > 0 {
+		hash := hashes[len(hashes)-1]
+		hashes = hashes[:len(hashes)-1]
+		if found[hash] {
+			continue
+		}
+		found[hash] = true
+		hashes = append(hashes, tp.children[hash]...)
+	}
+	return found
+}
+
+// dependentsLocked returns the pending transactions depending, directly or not, on a
+// transaction (caller must hold the lock)
+func (tp *Mempool) dependentsLocked(hash string) []*Transaction {
+	var dependents []*Transaction
+	for dependent := range tp.descendantsLocked(hash) {
+		if tx, pending := tp.transactions[dependent]; pending && dependent != hash {
+			dependents = append(dependents, tx)
+		}
+	}
+	return dependents
+}
+
+// parentCredits returns what the pending transactions a transaction depends on credit an
+// address (caller must hold the lock)
+func (tp *Mempool) parentCredits(tx *Transaction, address string) float64 {
+	var amount float64
+	for _, parent := range tp.parents[tx.Hash] {
+		if pending, exists := tp.transactions[parent]; exists && pending.To == address {
+			amount += pending.Amount
+		}
+	}
+	return amount
+}
+
+// packageLocked returns a pending transaction preceded by the pending transactions it depends
+// on, directly or not, that aren't in skip, in an order a block can include them in (caller must
+// hold the lock)
+func (tp *Mempool) packageLocked(hash string, skip map[string]bool) []*Transaction {
+	var pkg []*Transaction
+	visited := make(map[string]bool)
+	var visit func(hash string)
+	visit = func(hash string) {
+		if visited[hash] || skip[hash] {
+			return
+		}
+		visited[hash] = true
+		tx, pending := tp.transactions[hash]
+		if !pending {
+			return
+		}
+		for _, parent := range tp.parents[hash] {
+			visit(parent)
+		}
+		pkg = append(pkg, tx)
+	}
+	visit(hash)
+	return pkg
+}
+
+// packageFeeRate returns the fee per encoded byte of a package
+func packageFeeRate(pkg []*Transaction, sizes map[string]int) float64 {
+	var fee float64
+	var size int
+	for _, tx := range pkg {
+		if _, cached := sizes[tx.Hash]; !cached {
+			sizes[tx.Hash] = len(tx.encode())
+		}
+		fee += tx.Fee
+		size += sizes[tx.Hash]
+	}
+	if size == 0 {
+		return 0
+	}
+	return fee / float64(size)
+}
+
+// GetPackage returns a pending transaction preceded by the pending transactions it depends on,
+// in the order a block includes them, or nil if the transaction isn't pending
+func (tp *Mempool) GetPackage(hash string) []*Transaction {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+	return tp.packageLocked(hash, nil)
+}
+
+// PackageFeeRate returns the fee per encoded byte a pending transaction and the pending
+// transactions it depends on pay together
+func (tp *Mempool) PackageFeeRate(hash string) (float64, bool) {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+
+	pkg := tp.packageLocked(hash, nil)
+	if len(pkg) == 0 {
+		return 0, false
+	}
+	return packageFeeRate(pkg, make(map[string]int)), true
+}
+
+// SelectTransactions returns up to limit pending transactions for a block (0 for no limit),
+// every transaction after those it depends on. Coinbase transactions come first; the others
+// are taken by package, highest package fee rate first, so a transaction paying a high fee
+// pulls in the low-fee transactions it depends on (child pays for parent). Executable enhanced
+// transactions follow in standard form, highest fee first.
+func (tp *Mempool) SelectTransactions(limit int) []*Transaction {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+
+	var selected []*Transaction
+	included := make(map[string]bool)
+	candidates := make(map[string]bool)
+	for hash, tx := range tp.transactions {
+		if tx.IsCoinbase() {
+			selected = append(selected, tx)
+			included[hash] = true
+		} else {
+			candidates[hash] = true
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		return selected[i].Hash < selected[j].Hash
+	})
+
+	sizes := make(map[string]int)
+	for len(candidates) > 0 {
+		var best []*Transaction
+		var bestHash string
+		var bestRate float64
+		for hash := range candidates {
+			pkg := tp.packageLocked(hash, included)
+			rate := packageFeeRate(pkg, sizes)
+			if best == nil || rate > bestRate || rate == bestRate && tp.olderLocked(hash, bestHash) {
+				best, bestHash, bestRate = pkg, hash, rate
+			}
+		}
+
+		delete(candidates, bestHash)
+		if limit > 0 && len(selected)+len(best) > limit {
+			continue
+		}
+		for _, tx := range best {
+			selected = append(selected, tx)
+			included[tx.Hash] = true
+			delete(candidates, tx.Hash)
+		}
+	}
+
+	for _, tx := range tp.executableLocked() {
+		if limit > 0 && len(selected) >= limit {
+			break
+		}
+		selected = append(selected, tx)
+	}
+	return selected
+}
+
+// olderLocked reports whether a pending transaction entered the pool before another, by hash
+// when at the same time (caller must hold the lock)
+func (tp *Mempool) olderLocked(a, b string) bool {
+	if tp.addedAt[a] != tp.addedAt[b] {
+		return tp.addedAt[a] < tp.addedAt[b]
+	}
+	return a < b
+}
@@ -0,0 +1,128 @@
+package blockchain
+
+import "time"
+
+// PoolEventType is the kind of change a pool event describes
+type PoolEventType string
+
+const (
+	PoolEventAdd     PoolEventType = "add"
+	PoolEventRemove  PoolEventType = "remove"
+	PoolEventReplace PoolEventType = "replace"
+)
+
+// poolHookBuffer is the number of events a hook can fall behind before events are dropped
+const poolHookBuffer = 256
+
+// PoolEvent notifies subscribers of a change to the pool. Enhanced transactions are described in
+// standard form.
+type PoolEvent struct {
+	Type        PoolEventType `json:"type"`
+	Transaction *Transaction  `json:"transaction"`        // Added, removed or replacing transaction
+	Replaced    *Transaction  `json:"replaced,omitempty"` // Transaction a replacement took the place of
+	Enhanced    bool          `json:"enhanced"`
+	Reason      DropReason    `json:"reason,omitempty"` // Why a transaction was removed
+	Detail      string        `json:"detail,omitempty"`
+	Time        int64         `json:"time"`
+}
+
+// Subscribe returns a channel receiving an event for every change of the given types to the
+// pool (every change if none are given), and a function ending the subscription. Events are
+// sent without the pool waiting for the subscriber, so they are dropped while the channel
+// buffer is full.
+func (tp *Mempool) Subscribe(buffer int, types ...PoolEventType) (<-chan PoolEvent, func()) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	id := tp.nextSub
+	tp.nextSub++
+	ch := make(chan PoolEvent, buffer)
+	tp.subscribers[id] = poolSubscriber{ch: ch, types: types}
+
+	return ch, func() {
+		tp.mu.Lock()
+		defer tp.mu.Unlock()
+		if _, exists := tp.subscribers[id]; exists {
+			close(ch)
+			delete(tp.subscribers, id)
+		}
+	}
+}
+
+// OnAdd calls fn for every transaction added to the pool, and returns a function removing the hook
+func (tp *Mempool) OnAdd(fn func(PoolEvent)) func() {
+	return tp.hook(fn, PoolEventAdd)
+}
+
+// OnRemove calls fn for every transaction removed from the pool, and returns a function removing
+// the hook. A transaction replaced by another is reported to OnReplace hooks instead.
+func (tp *Mempool) OnRemove(fn func(PoolEvent)) func() {
+	return tp.hook(fn, PoolEventRemove)
+}
+
+// OnReplace calls fn for every pending transaction replaced by one paying a higher fee, and
+// returns a function removing the hook
+func (tp *Mempool) OnReplace(fn func(PoolEvent)) func() {
+	return tp.hook(fn, PoolEventReplace)
+}
+
+// hook calls fn, on its own goroutine and in order, for the events of a type. Hooks don't hold
+// the pool lock, so they can call back into the pool.
+func (tp *Mempool) hook(fn func(PoolEvent), eventType PoolEventType) func() {
+	events, cancel := tp.Subscribe(poolHookBuffer, eventType)
+	go func() {
+		for event := range events {
+			fn(event)
+		}
+	}()
+	return cancel
+}
+
+// poolSubscriber is the channel of a subscription and the event types it receives
+type poolSubscriber struct {
+	ch    chan PoolEvent
+	types []PoolEventType
+}
+
+// wants reports whether the subscriber receives events of a type
+func (s poolSubscriber) wants(eventType PoolEventType) bool {
+	if len(s.types) == 0 {
+		return true
+	}
+	for _, t := range s.types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// publishLocked sends an event to the subscribers that want it (caller must hold the lock)
+func (tp *Mempool) publishLocked(event PoolEvent) {
+	if event.Time == 0 {
+		event.Time = time.Now().Unix()
+	}
+	for _, sub := range tp.subscribers {
+		if !sub.wants(event.Type) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+		}
+	}
+}
+
+// publishDropsLocked reports removed standard transactions (caller must hold the lock)
+func (tp *Mempool) publishDropsLocked(drops []PoolDropEvent) {
+	for _, drop := range drops {
+		tp.publishLocked(PoolEvent{Type: PoolEventRemove, Transaction: drop.Transaction,
+			Reason: drop.Reason, Detail: drop.Detail, Time: drop.Time})
+	}
+}
+
+// publishEnhancedLocked reports an enhanced transaction added or removed (caller must hold the lock)
+func (tp *Mempool) publishEnhancedLocked(eventType PoolEventType, tx *EnhancedTransaction, reason DropReason, detail string) {
+	standard := tx.ToStandardTransaction()
+	tp.publishLocked(PoolEvent{Type: eventType, Transaction: &standard, Enhanced: true, Reason: reason, Detail: detail})
+}
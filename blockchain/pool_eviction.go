@@ -0,0 +1,118 @@
+package blockchain
+
+import (
+	"fmt"
+	"time"
+)
+
+// DropEvicted is the reason of a transaction evicted to make room for a higher-fee one
+const DropEvicted DropReason = "evicted"
+
+// EvictionPolicy lets a full pool make room instead of refusing every new transaction
+type EvictionPolicy struct {
+	// TTL expires transactions pending longer than this when the pool is full, and in the
+	// consistency passes of a PoolMaintainer without a MaxAge of its own (0 keeps them)
+	TTL time.Duration
+
+	// EvictLowestFee makes a full pool evict its lowest-fee transaction for a new one paying
+	// more. Only transactions no other pending transaction depends on are evicted, and never
+	// one of the new one's sender.
+	EvictLowestFee bool
+
+	// OnEvict is called, with the pool lock held, for every transaction expired or evicted
+	// when the pool is full, and those dropped with them. It must not call back into the pool.
+	OnEvict func(PoolDropEvent)
+}
+
+// EvictionStats counts the transactions a pool removed or refused for lack of room
+type EvictionStats struct {
+	Expired    int64   `json:"expired"`    // Pending longer than the TTL or the maintainer's MaxAge
+	Evicted    int64   `json:"evicted"`    // Evicted for a higher-fee transaction
+	Refused    int64   `json:"refused"`    // New transactions refused by the full pool
+	EvictedFee float64 `json:"evictedFee"` // Fees of the evicted transactions
+}
+
+// SetEvictionPolicy makes a full pool expire stale transactions and evict low-fee ones to
+// admit new transactions (nil refuses new transactions when full)
+func (tp *Mempool) SetEvictionPolicy(policy *EvictionPolicy) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	tp.eviction = policy
+}
+
+// EvictionStats returns the eviction counters of the pool
+func (tp *Mempool) EvictionStats() EvictionStats {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+	return tp.evictions
+}
+
+// makeRoomLocked frees a slot for a new transaction in a full pool under the eviction policy,
+// or refuses it (caller must hold the lock)
+func (tp *Mempool) makeRoomLocked(tx *Transaction) error {
+	if tp.eviction != nil && tp.eviction.TTL > 0 {
+		tp.expireLocked(tp.eviction.TTL)
+	}
+	if tp.sizeLocked() < tp.maxSize {
+		return nil
+	}
+
+	if tp.eviction != nil && tp.eviction.EvictLowestFee {
+		victim := tp.lowestFeeLocked(tx.From)
+		if victim != nil && tx.Fee > victim.Fee {
+			tp.removeLocked(victim)
+			tp.evictions.Evicted++
+			tp.evictions.EvictedFee += victim.Fee
+			tp.notifyEvicted(victim, DropEvicted, fmt.Sprintf("evicted for %s paying fee %.8f over %.8f", tx.Hash, tx.Fee, victim.Fee))
+			return nil
+		}
+		if victim != nil {
+			tp.evictions.Refused++
+			return reject(AdmissionPoolFull, fmt.Errorf("%w (fee must exceed %.8f)", ErrPoolFull, victim.Fee))
+		}
+	}
+
+	tp.evictions.Refused++
+	return reject(AdmissionPoolFull, ErrPoolFull)
+}
+
+// expireLocked removes the transactions pending longer than ttl (caller must hold the lock)
+func (tp *Mempool) expireLocked(ttl time.Duration) {
+	cutoff := time.Now().Unix() - int64(ttl.Seconds())
+	for hash, tx := range tp.transactions {
+		if !tx.IsCoinbase() && tp.addedAt[hash] < cutoff {
+			for _, dependent := range tp.dependentsLocked(hash) {
+				tp.removeLocked(dependent)
+				tp.notifyEvicted(dependent, DropParentDropped, fmt.Sprintf("depends on expired transaction %s", hash))
+			}
+			tp.removeLocked(tx)
+			tp.evictions.Expired++
+			tp.notifyEvicted(tx, DropExpired, fmt.Sprintf("pending for more than %s", ttl))
+		}
+	}
+}
+
+// lowestFeeLocked returns the lowest-fee transaction that can be evicted for one from sender: one
+// of another sender nothing depends on, the most recent on equal fees (caller must hold the lock)
+func (tp *Mempool) lowestFeeLocked(sender string) *Transaction {
+	var victim *Transaction
+	for hash, tx := range tp.transactions {
+		if tx.IsCoinbase() || tx.From == sender || len(tp.children[hash]) > 0 {
+			continue
+		}
+		if victim == nil || tx.Fee < victim.Fee ||
+			tx.Fee == victim.Fee && tp.addedAt[hash] > tp.addedAt[victim.Hash] {
+			victim = tx
+		}
+	}
+	return victim
+}
+
+// notifyEvicted reports a transaction removed to make room (caller must hold the lock)
+func (tp *Mempool) notifyEvicted(tx *Transaction, reason DropReason, detail string) {
+	event := PoolDropEvent{Transaction: tx, Reason: reason, Detail: detail, Time: time.Now().Unix()}
+	tp.publishDropsLocked([]PoolDropEvent{event})
+	if tp.eviction.OnEvict != nil {
+		tp.eviction.OnEvict(event)
+	}
+}
@@ -0,0 +1,83 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors of the transactions refused by the pool limits, wrapped in an AdmissionError
+var (
+	ErrSenderLimit   = errors.New("sender has too many pending transactions")
+	ErrDustAmount    = errors.New("amount is below the dust threshold")
+	ErrFeeRateTooLow = errors.New("fee rate is below the minimum")
+)
+
+// PoolLimits keeps a single address from filling the pool with many or worthless transactions.
+// Zero values disable a limit; coinbase transactions are exempt.
+type PoolLimits struct {
+	MaxPendingPerSender int     // Pending transactions a sender can have, a replacement counting once
+	DustThreshold       float64 // Smallest amount a value transfer can move
+	MinFeeRate          float64 // Smallest fee per byte of the transaction's canonical encoding
+}
+
+// Validate checks the limits configuration
+func (pl *PoolLimits) Validate() error {
+	if pl.MaxPendingPerSender < 0 {
+		return errors.New("max pending per sender cannot be negative")
+	}
+	if pl.DustThreshold < 0 || pl.MinFeeRate < 0 {
+		return errors.New("dust threshold and minimum fee rate cannot be negative")
+	}
+	return nil
+}
+
+// SetPoolLimits makes the pool refuse transactions exceeding the limits (nil disables)
+func (tp *Mempool) SetPoolLimits(limits *PoolLimits) error {
+	if limits != nil {
+		if err := limits.Validate(); err != nil {
+			return err
+		}
+	}
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	tp.limits = limits
+	return nil
+}
+
+// checkLimits refuses a transaction exceeding the pool limits. replaced is the pending
+// transaction it replaces, if any (caller must hold the lock).
+func (tp *Mempool) checkLimits(tx *Transaction, replaced *Transaction) error {
+	if tp.limits == nil || tx.IsCoinbase() {
+		return nil
+	}
+
+	// Key-value entries, spend policies and halts move no value and can't be dust
+	switch tx.To {
+	case KVNamespaceAddress, SpendPolicyAddress, HaltAddress:
+	default:
+		if tx.Amount < tp.limits.DustThreshold {
+			return reject(AdmissionDust, fmt.Errorf("%w: %.8f, threshold %.8f", ErrDustAmount, tx.Amount, tp.limits.DustThreshold))
+		}
+	}
+
+	if tp.limits.MinFeeRate > 0 {
+		size := len(tx.encode())
+		if rate := tx.Fee / float64(size); rate < tp.limits.MinFeeRate {
+			return reject(AdmissionFeeRateTooLow, fmt.Errorf("%w: %.8f per byte over %d bytes, minimum %.8f",
+				ErrFeeRateTooLow, rate, size, tp.limits.MinFeeRate))
+		}
+	}
+
+	if tp.limits.MaxPendingPerSender > 0 && replaced == nil {
+		pending := 0
+		for _, pendingTx := range tp.transactions {
+			if pendingTx.From == tx.From {
+				pending++
+			}
+		}
+		if pending >= tp.limits.MaxPendingPerSender {
+			return reject(AdmissionSenderLimit, fmt.Errorf("%w: %d pending, limit %d", ErrSenderLimit, pending, tp.limits.MaxPendingPerSender))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package blockchain
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DropReason explains why the pool dropped a pending transaction
+type DropReason string
+
+const (
+	DropConfirmed           DropReason = "confirmed"
+	DropNonceUsed           DropReason = "nonce_used"
+	DropInsufficientBalance DropReason = "insufficient_balance"
+	DropExpired             DropReason = "expired"
+)
+
+// PoolDropEvent describes a transaction removed by the pool consistency check
+type PoolDropEvent struct {
+	Transaction *Transaction `json:"transaction"`
+	Reason      DropReason   `json:"reason"`
+	Detail      string       `json:"detail"`
+	Time        int64        `json:"time"`
+}
+
+// CheckInvariants drops pending transactions that became invalid after chain changes:
+// already confirmed, nonce used by a confirmed transaction, no longer covered by the
+// sender's or sponsor's balance, past their validity window, or reserving funds for longer
+// than maxAge (0 falls back to the TTL of the eviction policy, if any). Transactions depending
+// on a dropped one that isn't confirmed are dropped with it. Balance reservations are
+// recomputed from the remaining transactions.
+func (tp *Mempool) CheckInvariants(maxAge time.Duration) []PoolDropEvent {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	if maxAge == 0 && tp.eviction != nil {
+		maxAge = tp.eviction.TTL
+	}
+
+	var height, timestamp int64
+	if tp.tip != nil {
+		height, timestamp = tp.tip.NextBlock()
+	}
+
+	now := time.Now().Unix()
+	var events []PoolDropEvent
+	var kept map[string]bool // Transactions reserved for, once reservations are recomputed
+	remove := func(tx *Transaction) {
+		// Removing releases a reservation, make the one not recomputed yet first
+		if kept != nil && !kept[tx.Hash] && !tx.IsCoinbase() {
+			tp.reserve(tx)
+		}
+		tp.removeLocked(tx)
+	}
+	drop := func(tx *Transaction, reason DropReason, detail string) {
+		var dependents []*Transaction
+		if reason != DropConfirmed {
+			dependents = tp.dependentsLocked(tx.Hash)
+		}
+		remove(tx)
+		events = append(events, PoolDropEvent{Transaction: tx, Reason: reason, Detail: detail, Time: now})
+		for _, dependent := range dependents {
+			remove(dependent)
+			events = append(events, PoolDropEvent{Transaction: dependent, Reason: DropParentDropped,
+				Detail: fmt.Sprintf("depends on dropped transaction %s", tx.Hash), Time: now})
+		}
+	}
+
+	bySender := make(map[string][]*Transaction)
+	for _, tx := range tp.transactions {
+		if tx.IsCoinbase() {
+			continue
+		}
+
+		if maxAge > 0 && now-tp.addedAt[tx.Hash] > int64(maxAge.Seconds()) {
+			drop(tx, DropExpired, fmt.Sprintf("pending for more than %s", maxAge))
+			tp.evictions.Expired++
+			continue
+		}
+
+		if tp.chain != nil {
+			if tp.chain.IsTransactionConfirmed(tx.Hash) {
+				drop(tx, DropConfirmed, "transaction already confirmed in chain")
+				continue
+			}
+			if confirmed := tp.chain.GetConfirmedNonce(tx.From); tx.Nonce != 0 && tx.Nonce <= confirmed {
+				drop(tx, DropNonceUsed, fmt.Sprintf("nonce %d already used (confirmed nonce %d)", tx.Nonce, confirmed))
+				continue
+			}
+		}
+
+		if tp.tip != nil {
+			if err := checkExpiry(tx, height, timestamp); err != nil {
+				drop(tx, DropValidityExpired, err.Error())
+				continue
+			}
+		}
+
+		bySender[tx.From] = append(bySender[tx.From], tx)
+	}
+
+	tp.reserved = make(map[string]float64)
+	kept = make(map[string]bool)
+	balances := make(map[string]float64) // Confirmed balances of the payers checked so far
+	for _, txs := range bySender {
+		// Keep the earliest spends of a sender: lowest nonce first, then arrival order
+		sort.Slice(txs, func(i, j int) bool {
+			if txs[i].Nonce != txs[j].Nonce {
+				return txs[i].Nonce < txs[j].Nonce
+			}
+			return tp.addedAt[txs[i].Hash] < tp.addedAt[txs[j].Hash]
+		})
+
+		for _, tx := range txs {
+			if _, pending := tp.transactions[tx.Hash]; !pending {
+				continue // Dropped with a transaction it depends on
+			}
+			if detail := tp.uncovered(tx, balances); detail != "" {
+				drop(tx, DropInsufficientBalance, detail)
+				continue
+			}
+			tp.reserve(tx)
+			kept[tx.Hash] = true
+		}
+	}
+
+	tp.publishDropsLocked(events)
+	return events
+}
+
+// uncovered describes how a transaction's sender or sponsor falls short of covering it on top
+// of the reservations made so far, or returns "" if both can. Pending transactions it depends on
+// count with what they credit. balances caches the confirmed balances (caller must hold the lock).
+func (tp *Mempool) uncovered(tx *Transaction, balances map[string]float64) string {
+	if tp.balances == nil {
+		return ""
+	}
+	for _, payer := range tx.payers() {
+		if _, cached := balances[payer]; !cached {
+			balances[payer] = tp.balances.GetBalance(payer)
+		}
+		available := balances[payer] + tp.parentCredits(tx, payer) - tp.reserved[payer]
+		if required := tx.debit(payer); required > available+reservationEpsilon {
+			detail := fmt.Sprintf("%.8f available, %.8f required", available, required)
+			if payer != tx.From {
+				detail = "sponsor " + detail
+			}
+			return detail
+		}
+	}
+	return ""
+}
+
+// PoolMaintenanceConfig configures the periodic pool consistency pass
+type PoolMaintenanceConfig struct {
+	Interval time.Duration       // Time between passes (default 30s)
+	MaxAge   time.Duration       // Drop transactions pending longer than this (0 keeps them)
+	OnDrop   func(PoolDropEvent) // Optional callback for every dropped transaction
+}
+
+// PoolMaintainer periodically checks the pool invariants so invalid transactions
+// are evicted as soon as the chain changes instead of being discovered at mining time
+type PoolMaintainer struct {
+	pool   *TransactionPool
+	lock   sync.Locker // Guards the chain the pool reads balances and nonces from
+	config PoolMaintenanceConfig
+	quit   chan struct{}
+	wg     sync.WaitGroup
+	once   sync.Once
+}
+
+// NewPoolMaintainer creates a maintainer for a pool.
+// The lock must be the one guarding the chain behind the pool's views (nil uses an internal mutex).
+func NewPoolMaintainer(pool *TransactionPool, lock sync.Locker, config PoolMaintenanceConfig) *PoolMaintainer {
+	if lock == nil {
+		lock = &sync.Mutex{}
+	}
+	if config.Interval <= 0 {
+		config.Interval = 30 * time.Second
+	}
+
+	return &PoolMaintainer{
+		pool:   pool,
+		lock:   lock,
+		config: config,
+		quit:   make(chan struct{}),
+	}
+}
+
+// Start begins the periodic consistency passes
+func (pm *PoolMaintainer) Start() {
+	pm.wg.Add(1)
+	go pm.loop()
+}
+
+// Stop ends the periodic passes and waits for a running pass to finish
+func (pm *PoolMaintainer) Stop() {
+	pm.once.Do(func() { close(pm.quit) })
+	pm.wg.Wait()
+}
+
+// RunOnce performs a single consistency pass and emits its drop events
+func (pm *PoolMaintainer) RunOnce() []PoolDropEvent {
+	pm.lock.Lock()
+	events := pm.pool.CheckInvariants(pm.config.MaxAge)
+	pm.lock.Unlock()
+
+	for _, event := range events {
+		log.Printf("Dropped pending transaction %s (%s): %s", event.Transaction.Hash, event.Reason, event.Detail)
+		if pm.config.OnDrop != nil {
+			pm.config.OnDrop(event)
+		}
+	}
+	return events
+}
+
+// loop runs consistency passes until the maintainer is stopped
+func (pm *PoolMaintainer) loop() {
+	defer pm.wg.Done()
+
+	ticker := time.NewTicker(pm.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-pm.quit:
+			return
+		case <-ticker.C:
+			pm.RunOnce()
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateProvider fetches the price of one native coin in fiat currencies
+type RateProvider interface {
+	Name() string
+	// FetchRates returns rates keyed by uppercase currency code. Currencies the
+	// provider doesn't quote are left out.
+	FetchRates(ctx context.Context, currencies []string) (map[string]float64, error)
+}
+
+// HTTPRateProvider fetches rates from a JSON endpoint such as a public price API.
+// The response must contain an object mapping currency codes (any case) to prices,
+// either at the top level or nested under RatesPath, e.g. {"mycoin": {"usd": 1.2}}
+// with RatesPath ["mycoin"].
+type HTTPRateProvider struct {
+	URL       string      // "{currencies}" is replaced with the comma-separated lowercase codes
+	RatesPath []string    // Object keys leading to the rates object
+	Header    http.Header // Extra request headers, e.g. an API key
+	Client    *http.Client
+}
+
+// maxRateResponse bounds the size of rate responses
+const maxRateResponse = 1 << 20
+
+// Name identifies the provider by host
+func (p *HTTPRateProvider) Name() string {
+	if i := strings.Index(p.URL, "://"); i >= 0 {
+		host := p.URL[i+3:]
+		if j := strings.IndexAny(host, "/?"); j >= 0 {
+			host = host[:j]
+		}
+		return host
+	}
+	return p.URL
+}
+
+// FetchRates requests the rates of the currencies
+func (p *HTTPRateProvider) FetchRates(ctx context.Context, currencies []string) (map[string]float64, error) {
+	codes := make([]string, len(currencies))
+	for i, currency := range currencies {
+		codes[i] = strings.ToLower(currency)
+	}
+	url := strings.ReplaceAll(p.URL, "{currencies}", strings.Join(codes, ","))
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range p.Header {
+		for _, value := range values {
+			request.Header.Add(key, value)
+		}
+	}
+	request.Header.Set("Accept", "application/json")
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rate request failed: %s", response.Status)
+	}
+
+	var document any
+	if err := json.NewDecoder(io.LimitReader(response.Body, maxRateResponse)).Decode(&document); err != nil {
+		return nil, fmt.Errorf("malformed rate response: %w", err)
+	}
+	for _, key := range p.RatesPath {
+		object, ok := document.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("rate response has no %q object", key)
+		}
+		document = object[key]
+	}
+	object, ok := document.(map[string]any)
+	if !ok {
+		return nil, errors.New("rate response has no rates object")
+	}
+
+	rates := make(map[string]float64)
+	for key, value := range object {
+		code := strings.ToUpper(key)
+		switch price := value.(type) {
+		case float64:
+			rates[code] = price
+		case string:
+			// Some APIs quote prices as strings to keep their precision
+			if parsed, err := strconv.ParseFloat(price, 64); err == nil {
+				rates[code] = parsed
+			}
+		}
+	}
+	return rates, nil
+}
+
+// ExchangeRate is the price of one native coin in a fiat currency
+type ExchangeRate struct {
+	Currency  string    `json:"currency"`
+	Rate      float64   `json:"rate"`
+	Source    string    `json:"source"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// FiatValue is a native amount with its fiat equivalent, for display only
+type FiatValue struct {
+	Amount    float64   `json:"amount"` // Native amount
+	Currency  string    `json:"currency"`
+	Value     float64   `json:"value"` // Fiat equivalent, 0 if no rate is known
+	Rate      float64   `json:"rate"`
+	AsOf      time.Time `json:"asOf"`
+	Available bool      `json:"available"` // A rate is known, even if stale
+	Stale     bool      `json:"stale"`     // The rate is older than the oracle's MaxAge
+}
+
+// String formats the amount with its fiat equivalent, e.g. "12.5 (≈ 31.25 USD)",
+// marking stale rates and leaving the equivalent out when no rate is known
+func (fv FiatValue) String() string {
+	amount := strconv.FormatFloat(fv.Amount, 'f', -1, 64)
+	if !fv.Available {
+		return amount
+	}
+	value := strconv.FormatFloat(fv.Value, 'f', 2, 64)
+	if fv.Stale {
+		return fmt.Sprintf("%s (≈ %s %s, stale since %s)", amount, value, fv.Currency, fv.AsOf.UTC().Format(time.RFC3339))
+	}
+	return fmt.Sprintf("%s (≈ %s %s)", amount, value, fv.Currency)
+}
+
+// PriceOracleConfig configures a price oracle
+type PriceOracleConfig struct {
+	Currencies      []string      // Fiat currencies to keep rates for (default USD)
+	RefreshInterval time.Duration // How often rates are refreshed in the background (default 5m)
+	MaxAge          time.Duration // Age after which rates are shown as stale (default 3 refresh intervals)
+	Timeout         time.Duration // Time limit of a refresh (default 10s)
+}
+
+// PriceOracle caches exchange rates from a provider so wallets and explorers can show fiat
+// equivalents next to native amounts. A failed refresh keeps the previous rates, which are
+// reported as stale once they exceed MaxAge. Rates are presentational and never affect consensus.
+type PriceOracle struct {
+	provider  RateProvider
+	config    PriceOracleConfig
+	rates     map[string]ExchangeRate
+	lastError error
+	mu        sync.RWMutex
+	quit      chan struct{}
+	wg        sync.WaitGroup
+	once      sync.Once
+}
+
+// NewPriceOracle creates a price oracle over a rate provider
+func NewPriceOracle(provider RateProvider, config PriceOracleConfig) *PriceOracle {
+	if len(config.Currencies) == 0 {
+		config.Currencies = []string{"USD"}
+	}
+	currencies := make([]string, len(config.Currencies))
+	for i, currency := range config.Currencies {
+		currencies[i] = strings.ToUpper(currency)
+	}
+	config.Currencies = currencies
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = 5 * time.Minute
+	}
+	if config.MaxAge <= 0 {
+		config.MaxAge = 3 * config.RefreshInterval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+
+	return &PriceOracle{
+		provider: provider,
+		config:   config,
+		rates:    make(map[string]ExchangeRate),
+		quit:     make(chan struct{}),
+	}
+}
+
+// Start refreshes the rates now and then periodically in the background
+func (po *PriceOracle) Start() {
+	po.wg.Add(1)
+	go po.loop()
+}
+
+// Stop ends background refreshing, waiting for a running refresh to finish
+func (po *PriceOracle) Stop() {
+	po.once.Do(func() { close(po.quit) })
+	po.wg.Wait()
+}
+
+// loop refreshes the rates until stopped
+func (po *PriceOracle) loop() {
+	defer po.wg.Done()
+
+	ticker := time.NewTicker(po.config.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := po.Refresh(context.Background()); err != nil {
+			log.Printf("Failed to refresh exchange rates from %s: %v", po.provider.Name(), err)
+		}
+
+		select {
+		case <-po.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh fetches the configured currencies from the provider
+func (po *PriceOracle) Refresh(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, po.config.Timeout)
+	defer cancel()
+
+	rates, err := po.provider.FetchRates(ctx, po.config.Currencies)
+	fetchedAt := time.Now()
+
+	po.mu.Lock()
+	defer po.mu.Unlock()
+
+	po.lastError = err
+	if err != nil {
+		return err
+	}
+	for _, currency := range po.config.Currencies {
+		rate, exists := rates[currency]
+		if !exists || rate <= 0 {
+			continue
+		}
+		po.rates[currency] = ExchangeRate{Currency: currency, Rate: rate, Source: po.provider.Name(), FetchedAt: fetchedAt}
+	}
+	return nil
+}
+
+// SetRate records a rate directly, e.g. one entered by the user or restored from a cache
+func (po *PriceOracle) SetRate(rate ExchangeRate) {
+	rate.Currency = strings.ToUpper(rate.Currency)
+
+	po.mu.Lock()
+	defer po.mu.Unlock()
+	po.rates[rate.Currency] = rate
+}
+
+// Rate returns the cached rate of a currency and whether it is stale
+func (po *PriceOracle) Rate(currency string) (ExchangeRate, bool, bool) {
+	po.mu.RLock()
+	defer po.mu.RUnlock()
+
+	rate, exists := po.rates[strings.ToUpper(currency)]
+	if !exists {
+		return ExchangeRate{}, false, false
+	}
+	return rate, true, time.Since(rate.FetchedAt) > po.config.MaxAge
+}
+
+// Rates returns every cached rate
+func (po *PriceOracle) Rates() []ExchangeRate {
+	po.mu.RLock()
+	defer po.mu.RUnlock()
+
+	rates := make([]ExchangeRate, 0, len(po.rates))
+	for _, currency := range po.config.Currencies {
+		if rate, exists := po.rates[currency]; exists {
+			rates = append(rates, rate)
+		}
+	}
+	return rates
+}
+
+// LastError returns the error of the last refresh, nil if it succeeded
+func (po *PriceOracle) LastError() error {
+	po.mu.RLock()
+	defer po.mu.RUnlock()
+	return po.lastError
+}
+
+// Convert returns the fiat equivalent of a native amount in a currency
+func (po *PriceOracle) Convert(amount float64, currency string) FiatValue {
+	value := FiatValue{Amount: amount, Currency: strings.ToUpper(currency)}
+	rate, exists, stale := po.Rate(currency)
+	if !exists {
+		return value
+	}
+	value.Value = amount * rate.Rate
+	value.Rate = rate.Rate
+	value.AsOf = rate.FetchedAt
+	value.Available = true
+	value.Stale = stale
+	return value
+}
+
+// FiatBalance returns the wallet's confirmed balance with its fiat equivalent
+func (w *Wallet) FiatBalance(node BalanceView, oracle *PriceOracle, currency string) FiatValue {
+	return oracle.Convert(node.GetBalance(w.Address), currency)
+}
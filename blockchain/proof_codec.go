@@ -0,0 +1,144 @@
+package blockchain
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Binary Merkle proofs
+const (
+	proofCodecLegacy byte = 1 // First byte of a binary proof of a LegacyMerkleScheme tree
+	proofCodecTagged byte = 2 // First byte of a binary proof of a TaggedMerkleScheme tree
+
+	// maxBinaryProofSize is the size of the deepest binary proof
+	maxBinaryProofSize = 2 + binary.MaxVarintLen64 + MaxProofDepth/8 + (MaxProofDepth+1)*32
+)
+
+// proofEncoding is the base64 alphabet of string proofs: URL-safe and unpadded, so a proof
+// can sit in a query parameter or a QR code without escaping
+var proofEncoding = base64.RawURLEncoding
+
+// MarshalBinary encodes the proof compactly: version (1, or 2 for a tagged tree), leaf
+// index (uvarint), sibling count (1), the sibling directions as a bitmap with bit i set when
+// sibling i is on the left (one byte per 8 siblings), then the leaf and sibling hashes as 32
+// raw bytes each.
+func (p *MerkleProof) MarshalBinary() ([]byte, error) {
+	if err := CheckProofLimits(p); err != nil {
+		return nil, err
+	}
+
+	depth := len(p.Hashes)
+	buf := make([]byte, 0, maxBinaryProofSize)
+	version := proofCodecLegacy
+	if p.Scheme == TaggedMerkleScheme {
+		version = proofCodecTagged
+	}
+	buf = append(buf, version)
+	buf = binary.AppendUvarint(buf, uint64(p.Index))
+	buf = append(buf, byte(depth))
+
+	bitmap := make([]byte, (depth+7)/8)
+	for i, left := range p.IsLeft {
+		if left {
+			bitmap[i/8] |= 1 << (i % 8)
+		}
+	}
+	buf = append(buf, bitmap...)
+
+	for i, hash := range append([]string{p.Hash}, p.Hashes...) {
+		decoded, err := hex.DecodeString(hash)
+		if err != nil || len(decoded) != 32 || hex.EncodeToString(decoded) != hash {
+			if i == 0 {
+				return nil, errors.New("proof hash is not a 32-byte lowercase hex hash")
+			}
+			return nil, fmt.Errorf("proof hashes[%d] is not a 32-byte lowercase hex hash", i-1)
+		}
+		buf = append(buf, decoded...)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a proof encoded by MarshalBinary
+func (p *MerkleProof) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 || (data[0] != proofCodecLegacy && data[0] != proofCodecTagged) {
+		return errors.New("unknown binary proof version")
+	}
+	scheme := LegacyMerkleScheme
+	if data[0] == proofCodecTagged {
+		scheme = TaggedMerkleScheme
+	}
+	data = data[1:]
+
+	index, n := binary.Uvarint(data)
+	if n <= 0 {
+		return errors.New("binary proof has an invalid leaf index")
+	}
+	data = data[n:]
+
+	if len(data) == 0 {
+		return errors.New("binary proof is truncated")
+	}
+	depth := int(data[0])
+	if depth > MaxProofDepth {
+		return &LimitError{Field: "hashes", Size: depth, Limit: MaxProofDepth}
+	}
+	data = data[1:]
+	// In a legacy tree the directions spell out the index, which cannot have more bits than
+	// the proof has levels. A tagged path skips the levels it is promoted through.
+	if index > math.MaxInt64 || scheme == LegacyMerkleScheme && depth < 63 && index>>depth != 0 {
+		return errors.New("binary proof leaf index is beyond its depth")
+	}
+
+	bitmapSize := (depth + 7) / 8
+	if len(data) != bitmapSize+(depth+1)*32 {
+		return fmt.Errorf("binary proof has the wrong length for %d siblings", depth)
+	}
+	bitmap, hashes := data[:bitmapSize], data[bitmapSize:]
+	if depth%8 != 0 && bitmap[bitmapSize-1]>>(depth%8) != 0 {
+		return errors.New("binary proof sets directions beyond its siblings")
+	}
+
+	decoded := MerkleProof{
+		Hash:   hex.EncodeToString(hashes[:32]),
+		Index:  int(index),
+		Hashes: make([]string, depth),
+		IsLeft: make([]bool, depth),
+		Scheme: scheme,
+	}
+	for i := range depth {
+		decoded.Hashes[i] = hex.EncodeToString(hashes[(i+1)*32 : (i+2)*32])
+		decoded.IsLeft[i] = bitmap[i/8]&(1<<(i%8)) != 0
+	}
+	*p = decoded
+	return nil
+}
+
+// EncodeString returns the binary encoding of the proof as unpadded URL-safe base64,
+// e.g. for a link, a QR code or an API field
+func (p *MerkleProof) EncodeString() (string, error) {
+	data, err := p.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	return proofEncoding.EncodeToString(data), nil
+}
+
+// DecodeProofString decodes a proof encoded by EncodeString
+func DecodeProofString(s string) (*MerkleProof, error) {
+	if limit := proofEncoding.EncodedLen(maxBinaryProofSize); len(s) > limit {
+		return nil, &DecodeError{Kind: "proof", Err: &LimitError{Field: "proof", Size: len(s), Limit: limit}}
+	}
+	data, err := proofEncoding.DecodeString(s)
+	if err != nil {
+		return nil, &DecodeError{Kind: "proof", Err: err}
+	}
+	var proof MerkleProof
+	if err := proof.UnmarshalBinary(data); err != nil {
+		return nil, &DecodeError{Kind: "proof", Err: err}
+	}
+	return &proof, nil
+}
@@ -0,0 +1,140 @@
+package blockchain
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func FuzzMerkleProofUnmarshalBinary(f *testing.F) {
+	for _, scheme := range []MerkleScheme{LegacyMerkleScheme, TaggedMerkleScheme} {
+		tree := newMerkleTreeFromHashes(sortedLeaves(int(scheme), 5), scheme)
+		for i := range 5 {
+			proof, err := tree.GenerateProofByIndex(i)
+			if err != nil {
+				f.Fatal(err)
+			}
+			data, err := proof.MarshalBinary()
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(data)
+		}
+	}
+	f.Add([]byte{proofCodecLegacy})
+	f.Add([]byte{proofCodecTagged, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, 0})
+	f.Add([]byte{proofCodecLegacy, 0, MaxProofDepth + 1})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var proof MerkleProof
+		if err := proof.UnmarshalBinary(data); err != nil {
+			return
+		}
+		if err := CheckProofLimits(&proof); err != nil {
+			t.Fatalf("decoded proof fails its limits: %v", err)
+		}
+		VerifyProof(&proof, proof.Hash, proof.Scheme)
+
+		encoded, err := proof.MarshalBinary()
+		if err != nil {
+			t.Fatalf("decoded proof does not encode: %v", err)
+		}
+		var decoded MerkleProof
+		if err := decoded.UnmarshalBinary(encoded); err != nil {
+			t.Fatalf("re-encoded proof does not decode: %v", err)
+		}
+		if !reflect.DeepEqual(decoded, proof) {
+			t.Fatalf("proof changed through a round trip: %+v != %+v", decoded, proof)
+		}
+	})
+}
+
+func TestProofCodecRoundTrip(t *testing.T) {
+	for _, scheme := range []MerkleScheme{LegacyMerkleScheme, TaggedMerkleScheme} {
+		// One leaf has no siblings, 2 one level, 13 and 300 several with odd levels
+		for _, leaves := range []int{1, 2, 13, 300} {
+			tree := newMerkleTreeFromHashes(sortedLeaves(leaves, leaves), scheme)
+			for _, index := range []int{0, leaves / 2, leaves - 1} {
+				proof, err := tree.GenerateProofByIndex(index)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				data, err := proof.MarshalBinary()
+				if err != nil {
+					t.Fatalf("scheme %d, %d leaves, leaf %d: %v", scheme, leaves, index, err)
+				}
+				var decoded MerkleProof
+				if err := decoded.UnmarshalBinary(data); err != nil {
+					t.Fatalf("scheme %d, %d leaves, leaf %d: %v", scheme, leaves, index, err)
+				}
+				if !reflect.DeepEqual(&decoded, proof) {
+					t.Fatalf("scheme %d, %d leaves, leaf %d: binary round trip gave %+v, expected %+v", scheme, leaves, index, decoded, *proof)
+				}
+
+				s, err := proof.EncodeString()
+				if err != nil {
+					t.Fatal(err)
+				}
+				fromString, err := DecodeProofString(s)
+				if err != nil {
+					t.Fatalf("scheme %d, %d leaves, leaf %d: %v", scheme, leaves, index, err)
+				}
+				if !reflect.DeepEqual(fromString, proof) {
+					t.Fatalf("scheme %d, %d leaves, leaf %d: string round trip gave %+v, expected %+v", scheme, leaves, index, *fromString, *proof)
+				}
+				if !VerifyProof(fromString, tree.GetMerkleRoot(), scheme) {
+					t.Fatalf("scheme %d, %d leaves, leaf %d: decoded proof does not verify", scheme, leaves, index)
+				}
+			}
+		}
+	}
+}
+
+func TestProofCodecRejectsMalformedInput(t *testing.T) {
+	tree := newMerkleTreeFromHashes(sortedLeaves(0, 13), TaggedMerkleScheme)
+	proof, err := tree.GenerateProofByIndex(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := proof.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for n := 0; n < len(data); n++ {
+		var decoded MerkleProof
+		if err := decoded.UnmarshalBinary(data[:n]); err == nil {
+			t.Fatalf("proof truncated to %d of %d bytes decoded", n, len(data))
+		}
+	}
+	var decoded MerkleProof
+	if err := decoded.UnmarshalBinary(append(data, 0)); err == nil {
+		t.Fatal("proof with a trailing byte decoded")
+	}
+
+	// Deeper than MaxProofDepth, both claimed in the header and as given to MarshalBinary
+	deep := []byte{proofCodecTagged, 0, MaxProofDepth + 1}
+	deep = append(deep, make([]byte, (MaxProofDepth+8)/8+(MaxProofDepth+2)*32)...)
+	if err := decoded.UnmarshalBinary(deep); err == nil {
+		t.Fatal("proof deeper than the limit decoded")
+	}
+	tooDeep := *proof
+	for len(tooDeep.Hashes) <= MaxProofDepth {
+		tooDeep.Hashes = append(tooDeep.Hashes, proof.Hash)
+		tooDeep.IsLeft = append(tooDeep.IsLeft, false)
+	}
+	if _, err := tooDeep.MarshalBinary(); err == nil {
+		t.Fatal("proof deeper than the limit encoded")
+	}
+
+	s, err := proof.EncodeString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{"", s[:len(s)-1], s + "A", s + "=", strings.Repeat("A", proofEncoding.EncodedLen(maxBinaryProofSize)+1)} {
+		if _, err := DecodeProofString(bad); err == nil {
+			t.Fatalf("proof string %q decoded", bad)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Block versions
+const (
+	LegacyBlockVersion int32 = 0 // Blocks created before versioning was introduced
+
+	// TaggedMerkleBlockVersion and later blocks commit their transactions with TaggedMerkleScheme.
+	// A network switches by scheduling an upgrade to this version; earlier blocks keep validating
+	// against their legacy roots.
+	TaggedMerkleBlockVersion int32 = 1
+
+	// ReceiptRootBlockVersion and later blocks commit the root of their receipts in the header
+	// (ReceiptRoot). Earlier blocks carry no receipt root, so their receipts can't be proven.
+	ReceiptRootBlockVersion int32 = 2
+)
+
+// ConsensusRule is a validation rule that becomes mandatory once its upgrade activates.
+// Check is nil for rules enforced by a consensus check that consults the schedule itself.
+type ConsensusRule struct {
+	Name  string
+	Check func(block *Block) error
+}
+
+// RequireSignaturesRule names the rule returned by RequireSignatures
+const RequireSignaturesRule = "require-signatures"
+
+// Upgrade describes a protocol upgrade activating at a given block height
+type Upgrade struct {
+	Name    string
+	Height  int64 // First block height at which the upgrade is enforced
+	Version int32 // Minimum block version required from Height onwards
+	Rules   []ConsensusRule
+}
+
+// UpgradeSchedule holds the protocol upgrades of a network ordered by activation height
+type UpgradeSchedule struct {
+	upgrades []Upgrade
+}
+
+// NewUpgradeSchedule creates a new upgrade schedule
+func NewUpgradeSchedule(upgrades ...Upgrade) (*UpgradeSchedule, error) {
+	schedule := &UpgradeSchedule{}
+	for _, upgrade := range upgrades {
+		if err := schedule.AddUpgrade(upgrade); err != nil {
+			return nil, err
+		}
+	}
+	return schedule, nil
+}
+
+// AddUpgrade registers an upgrade, keeping the schedule ordered by height
+func (us *UpgradeSchedule) AddUpgrade(upgrade Upgrade) error {
+	if upgrade.Name == "" {
+		return errors.New("upgrade name cannot be empty")
+	}
+	if upgrade.Height <= 0 {
+		return errors.New("upgrade height must be positive")
+	}
+
+	for _, existing := range us.upgrades {
+		if existing.Name == upgrade.Name {
+			return fmt.Errorf("upgrade %s already scheduled", upgrade.Name)
+		}
+		// Versions must never decrease as height increases
+		if existing.Height <= upgrade.Height && existing.Version > upgrade.Version {
+			return fmt.Errorf("upgrade %s lowers the block version below %s", upgrade.Name, existing.Name)
+		}
+		if existing.Height >= upgrade.Height && existing.Version < upgrade.Version {
+			return fmt.Errorf("upgrade %s raises the block version above later upgrade %s", upgrade.Name, existing.Name)
+		}
+	}
+
+	us.upgrades = append(us.upgrades, upgrade)
+	sort.SliceStable(us.upgrades, func(i, j int) bool {
+		return us.upgrades[i].Height < us.upgrades[j].Height
+	})
+	return nil
+}
+
+// GetUpgrades returns all scheduled upgrades ordered by height
+func (us *UpgradeSchedule) GetUpgrades() []Upgrade {
+	upgrades := make([]Upgrade, len(us.upgrades))
+	copy(upgrades, us.upgrades)
+	return upgrades
+}
+
+// ActiveUpgrades returns the upgrades in force at the given height
+func (us *UpgradeSchedule) ActiveUpgrades(height int64) []Upgrade {
+	var active []Upgrade
+	for _, upgrade := range us.upgrades {
+		if upgrade.Height > height {
+			break
+		}
+		active = append(active, upgrade)
+	}
+	return active
+}
+
+// IsActive checks whether the named upgrade is in force at the given height
+func (us *UpgradeSchedule) IsActive(name string, height int64) bool {
+	for _, upgrade := range us.ActiveUpgrades(height) {
+		if upgrade.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// VersionAt returns the block version miners must use at the given height
+func (us *UpgradeSchedule) VersionAt(height int64) int32 {
+	version := LegacyBlockVersion
+	for _, upgrade := range us.ActiveUpgrades(height) {
+		if upgrade.Version > version {
+			version = upgrade.Version
+		}
+	}
+	return version
+}
+
+// NextUpgrade returns the first upgrade scheduled above the given height, if any
+func (us *UpgradeSchedule) NextUpgrade(height int64) (*Upgrade, bool) {
+	for i := range us.upgrades {
+		if us.upgrades[i].Height > height {
+			upgrade := us.upgrades[i]
+			return &upgrade, true
+		}
+	}
+	return nil, false
+}
+
+// ValidateBlock enforces the version and rules of every upgrade active at the block's height.
+// Blocks below all activation heights are validated exactly as before, so existing chains stay valid.
+func (us *UpgradeSchedule) ValidateBlock(block *Block) error {
+	for _, upgrade := range us.ActiveUpgrades(block.Index) {
+		if block.Version < upgrade.Version {
+			return fmt.Errorf("block %d has version %d, upgrade %s requires at least %d",
+				block.Index, block.Version, upgrade.Name, upgrade.Version)
+		}
+
+		for _, rule := range upgrade.Rules {
+			if rule.Check == nil {
+				continue
+			}
+			if err := rule.Check(block); err != nil {
+				return fmt.Errorf("block %d violates rule %s (%s): %w", block.Index, rule.Name, upgrade.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// RequiresSignatures checks whether a RequireSignatures rule is in force at the given height
+func (us *UpgradeSchedule) RequiresSignatures(height int64) bool {
+	for _, upgrade := range us.ActiveUpgrades(height) {
+		for _, rule := range upgrade.Rules {
+			if rule.Name == RequireSignaturesRule {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RequireSignatures returns a rule making a signature mandatory for every non-coinbase
+// transaction, as SetRequireSignatures does for a single node. It has no Check of its own:
+// checkBlockSignatures consults it at the block's height, so every node enforces it from the
+// activation height on and blocks below it keep validating as before.
+func RequireSignatures() ConsensusRule {
+	return ConsensusRule{Name: RequireSignaturesRule}
+}
+
+// RequireMinimumFee returns a rule rejecting non-coinbase transactions that pay less than minFee
+func RequireMinimumFee(minFee float64) ConsensusRule {
+	return ConsensusRule{
+		Name: "minimum-fee",
+		Check: func(block *Block) error {
+			for _, tx := range block.Transactions {
+				if !tx.IsCoinbase() && tx.Fee < minFee {
+					return fmt.Errorf("transaction %s pays fee %.8f below minimum %.8f", tx.Hash, tx.Fee, minFee)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// RequireMaxTransactions returns a rule limiting the number of transactions per block
+func RequireMaxTransactions(maxTxs int) ConsensusRule {
+	return ConsensusRule{
+		Name: "max-transactions",
+		Check: func(block *Block) error {
+			if len(block.Transactions) > maxTxs {
+				return fmt.Errorf("block contains %d transactions, maximum is %d", len(block.Transactions), maxTxs)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,277 @@
+package blockchain
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"time"
+)
+
+// ErrPruned is returned for block bodies and transactions dropped by pruning
+var ErrPruned = errors.New("data has been pruned")
+
+const (
+	// MinPruneKeep is the fewest blocks pruning keeps bodies for, covering the fee
+	// estimation window and the confirmation depths wallets usually wait for
+	MinPruneKeep = 64
+
+	// pruneBatch is how far the chain grows past the kept blocks before pruning again,
+	// so the prune state isn't rewritten on every block
+	pruneBatch = 16
+)
+
+// PruneState summarizes the pruned part of the chain: what validation and queries
+// still need from the block bodies that are gone
+type PruneState struct {
+	Height       int64                 `json:"height"`       // Bodies below this height are pruned, except blocks keepsBody keeps
+	Transactions map[string]TxLocation `json:"transactions"` // Non-coinbase transactions of pruned blocks, for replay protection
+	Nonces       map[string]int64      `json:"nonces"`       // Highest nonce per sender in pruned blocks
+	Balances     map[string]float64    `json:"balances"`     // Net balance change per address in pruned blocks
+	Counts       map[string]int64      `json:"counts"`       // Balance updates per address in pruned blocks
+}
+
+// newPruneState creates the state of a chain that was never pruned
+func newPruneState() *PruneState {
+	return &PruneState{
+		Transactions: make(map[string]TxLocation),
+		Nonces:       make(map[string]int64),
+		Balances:     make(map[string]float64),
+		Counts:       make(map[string]int64),
+	}
+}
+
+// clone returns a copy of the state that can be updated independently
+func (ps *PruneState) clone() *PruneState {
+	return &PruneState{
+		Height:       ps.Height,
+		Transactions: maps.Clone(ps.Transactions),
+		Nonces:       maps.Clone(ps.Nonces),
+		Balances:     maps.Clone(ps.Balances),
+		Counts:       maps.Clone(ps.Counts),
+	}
+}
+
+// addBlock records what the transactions of a block being pruned leave behind
+func (ps *PruneState) addBlock(block *Block) {
+	for i, tx := range block.Transactions {
+		ps.Balances[tx.From] -= tx.debit(tx.From)
+		ps.Balances[tx.To] += tx.Amount
+		ps.Counts[tx.From]++
+		ps.Counts[tx.To]++
+		if tx.Sponsor != "" {
+			ps.Balances[tx.Sponsor] -= tx.Fee
+			ps.Counts[tx.Sponsor]++
+		}
+		if tx.IsCoinbase() {
+			continue
+		}
+		ps.Transactions[tx.Hash] = TxLocation{BlockIndex: block.Index, TxIndex: i}
+		if tx.Nonce > ps.Nonces[tx.From] {
+			ps.Nonces[tx.From] = tx.Nonce
+		}
+	}
+}
+
+// tracker returns a spend tracker holding the pruned transactions and nonces
+func (ps *PruneState) tracker() *spendTracker {
+	spends := newSpendTracker()
+	for hash := range ps.Transactions {
+		spends.confirmed[hash] = true
+	}
+	maps.Copy(spends.nonces, ps.Nonces)
+	return spends
+}
+
+// keepsBody reports whether pruning must keep a block's body: the genesis block, and blocks
+// whose system transactions (key-value entries, spend policies, chain halts) set state the
+// chain indexes are rebuilt from. Blocks under a legal hold are kept too, see LegalHold.
+func keepsBody(block *Block) bool {
+	if block.Index == 0 || block.KVRoot != "" {
+		return true
+	}
+	for i := range block.Transactions {
+		if TransactionSource(&block.Transactions[i]) == SourceSystem {
+			return true
+		}
+	}
+	return false
+}
+
+// prune drops the body of a block, keeping its header
+func (b *Block) prune() {
+	b.Transactions = nil
+	b.MerkleTree = nil
+	b.Pruned = true
+}
+
+// unpruned passes on a stored block, or ErrPruned if its body was pruned
+func unpruned(block *Block, err error) (*Block, error) {
+	if err == nil && block.Pruned {
+		return nil, fmt.Errorf("block %d: %w", block.Index, ErrPruned)
+	}
+	return block, err
+}
+
+// PruneBlocks replaces the stored blocks at the given heights with their headers, drops their
+// rows from the transactions table and saves the prune state, in one transaction. Address
+// balances and ledger entries are kept. Blocks under a legal hold fail with ErrLegalHold.
+func (d *Database) PruneBlocks(heights []int64, state *PruneState) error {
+	if err := d.Flush(); err != nil {
+		return err
+	}
+	stateData, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to serialize prune state: %w", err)
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, height := range heights {
+		var held int
+		if err := tx.QueryRow(d.rebind("SELECT COUNT(*) FROM legal_holds WHERE block_index = ?"), height).Scan(&held); err != nil {
+			return fmt.Errorf("failed to check legal holds: %w", err)
+		}
+		if held > 0 {
+			return fmt.Errorf("cannot prune block %d: %w", height, ErrLegalHold)
+		}
+
+		var blockData string
+		if err := tx.QueryRow(d.rebind("SELECT block_data FROM blocks WHERE block_index = ?"), height).Scan(&blockData); err != nil {
+			return fmt.Errorf("failed to load block %d: %w", height, err)
+		}
+		block, err := decodeStoredBlock(blockData)
+		if err != nil {
+			return err
+		}
+		block.prune()
+		data, err := d.encodeBlockData(block)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(d.rebind("UPDATE blocks SET block_data = ? WHERE block_index = ?"), data, height); err != nil {
+			return fmt.Errorf("failed to prune block %d: %w", height, err)
+		}
+		if _, err := tx.Exec(d.rebind("DELETE FROM transactions WHERE block_index = ?"), height); err != nil {
+			return fmt.Errorf("failed to prune transactions of block %d: %w", height, err)
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM prune_state WHERE id = 1"); err != nil {
+		return err
+	}
+	_, err = tx.Exec(d.rebind("INSERT INTO prune_state (id, height, state, last_updated) VALUES (1, ?, ?, ?)"),
+		state.Height, string(stateData), time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to save prune state: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// LoadPruneState returns the prune state, or nil if the database was never pruned
+func (d *Database) LoadPruneState() (*PruneState, error) {
+	var data string
+	err := d.db.QueryRow("SELECT state FROM prune_state WHERE id = 1").Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := newPruneState()
+	if err := json.Unmarshal([]byte(data), state); err != nil {
+		return nil, fmt.Errorf("failed to deserialize prune state: %w", err)
+	}
+	return state, nil
+}
+
+// SetPruning keeps the bodies of only the last keep blocks in memory and in the database, with
+// the headers and the address balances of every block. Pruned transactions can no longer be
+// looked up, proven or replayed; requests for them fail with ErrPruned. Blocks under a legal
+// hold are never pruned. 0 disables pruning, which doesn't bring pruned bodies back. Pruned
+// blocks can't be served to syncing peers.
+func (pbc *PersistentBlockchain) SetPruning(keep int64) error {
+	if keep < 0 {
+		return errors.New("pruning depth cannot be negative")
+	}
+	if keep != 0 && keep < MinPruneKeep {
+		return fmt.Errorf("pruning must keep at least %d blocks", MinPruneKeep)
+	}
+	pbc.pruneKeep = keep
+	return pbc.prune(true)
+}
+
+// PrunedHeight returns the height below which block bodies may have been pruned (0 if none were)
+func (pbc *PersistentBlockchain) PrunedHeight() int64 {
+	return pbc.prunedState().Height
+}
+
+// prunedState returns the prune state, empty if the chain was never pruned
+func (pbc *PersistentBlockchain) prunedState() *PruneState {
+	if pbc.pruneState == nil {
+		pbc.pruneState = newPruneState()
+	}
+	return pbc.pruneState
+}
+
+// prune drops the bodies of blocks older than the kept ones, once a batch has accumulated
+// (or right away if forced), persisting the pruned bodies and the new prune state atomically
+func (pbc *PersistentBlockchain) prune(force bool) error {
+	if pbc.pruneKeep == 0 {
+		return nil
+	}
+	state := pbc.prunedState()
+	target := int64(len(pbc.Chain)) - pbc.pruneKeep
+	if target <= state.Height || (!force && target-state.Height < pruneBatch) {
+		return nil
+	}
+
+	holds, err := pbc.Database.LoadLegalHolds()
+	if err != nil {
+		return fmt.Errorf("failed to load legal holds: %w", err)
+	}
+	held := heldHeights(holds)
+
+	next := state.clone()
+	var heights []int64
+	for height := state.Height; height < target; height++ {
+		block := pbc.Chain[height]
+		if block.Pruned || keepsBody(block) || held[height] {
+			continue
+		}
+		next.addBlock(block)
+		heights = append(heights, height)
+	}
+	next.Height = target
+
+	if err := pbc.pruneHeights(heights, next); err != nil {
+		return err
+	}
+	log.Printf("Pruned %d block bodies below height %d", len(heights), target)
+	return nil
+}
+
+// pruneHeights prunes the blocks at some heights, persisting them with the prune state that
+// accounts for them before dropping their bodies from memory
+func (pbc *PersistentBlockchain) pruneHeights(heights []int64, next *PruneState) error {
+	if err := pbc.Database.PruneBlocks(heights, next); err != nil {
+		return fmt.Errorf("failed to prune blocks: %w", err)
+	}
+	for _, height := range heights {
+		pbc.Chain[height].prune()
+	}
+	pbc.pruneState = next
+	if pbc.txIndex != nil {
+		pbc.txIndex.pruned = next.Transactions
+	}
+	return nil
+}
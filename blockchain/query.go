@@ -0,0 +1,594 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// QueryMaxRows caps the number of rows a single query may return
+const QueryMaxRows = 1000
+
+// QuerySource is the chain state a query reads from
+type QuerySource interface {
+	GetLatestBlock() *Block
+	GetBlockByIndex(index int64) (*Block, error)
+}
+
+// QueryResult holds the rows returned by a query
+type QueryResult struct {
+	Columns []string        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+// Tables that can be queried and their columns
+var queryTables = map[string][]string{
+	"mempool":      {"hash", "from", "to", "amount", "fee", "nonce", "added", "age", "source"},
+	"transactions": {"hash", "from", "to", "amount", "fee", "nonce", "block", "timestamp", "age", "source"},
+	"blocks":       {"index", "hash", "prev_hash", "version", "timestamp", "age", "nonce", "tx_count", "merkle_root"},
+	"admissions":   {"hash", "from", "accepted", "reason", "detail", "source", "timestamp", "age"},
+}
+
+// ExecuteQuery runs a read-only query against a chain and its transaction pool.
+//
+// Queries use a small SQL subset:
+//
+//	SELECT * | col, ... FROM mempool | transactions | blocks | admissions
+//	  [WHERE col op value [AND ...]] [ORDER BY col [ASC|DESC]] [LIMIT n]
+//
+// Operators are =, !=, <>, <, <=, > and >=. Values are numbers, 'quoted strings',
+// durations such as 90s, 30m, 1h or 2d (compared as seconds), or ? placeholders
+// bound to args in order. Example: SELECT hash, fee FROM mempool WHERE from = ? AND age > 1h
+//
+// The admissions table lists the pool's recent accept and reject decisions, newest first,
+// when the pool has an admission log. Accepted is 1 or 0.
+func ExecuteQuery(source QuerySource, pool *TransactionPool, query string, args ...interface{}) (*QueryResult, error) {
+	stmt, err := parseQuery(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := queryTables[stmt.table]
+	now := time.Now().Unix()
+
+	var rows [][]interface{}
+	limit := stmt.limit
+	if limit <= 0 || limit > QueryMaxRows {
+		limit = QueryMaxRows
+	}
+	// Without ordering the scan can stop as soon as enough rows match
+	full := func() bool { return stmt.orderBy == "" && len(rows) >= limit }
+
+	collect := func(row []interface{}) error {
+		match, err := stmt.matches(columns, row)
+		if err != nil {
+			return err
+		}
+		if match {
+			rows = append(rows, row)
+		}
+		return nil
+	}
+
+	switch stmt.table {
+	case "mempool":
+		if pool != nil {
+			txs := pool.GetTransactions()
+			sort.Slice(txs, func(i, j int) bool { return txs[i].Hash < txs[j].Hash })
+			for _, tx := range txs {
+				added, _ := pool.GetAddedTime(tx.Hash)
+				row := []interface{}{tx.Hash, tx.From, tx.To, tx.Amount, tx.Fee, tx.Nonce, added, now - added,
+					string(TransactionSource(tx))}
+				if err := collect(row); err != nil {
+					return nil, err
+				}
+				if full() {
+					break
+				}
+			}
+		}
+
+	case "admissions":
+		if pool != nil {
+			for _, record := range pool.AdmissionLog().Records() {
+				accepted := int64(0)
+				if record.Accepted {
+					accepted = 1
+				}
+				row := []interface{}{record.TxHash, record.From, accepted, string(record.Reason), record.Detail,
+					record.Source, record.Timestamp, now - record.Timestamp}
+				if err := collect(row); err != nil {
+					return nil, err
+				}
+				if full() {
+					break
+				}
+			}
+		}
+
+	case "transactions", "blocks":
+		latest := source.GetLatestBlock().Index
+	scan:
+		for index := int64(0); index <= latest; index++ {
+			block, err := source.GetBlockByIndex(index)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read block %d: %w", index, err)
+			}
+
+			if stmt.table == "blocks" {
+				row := []interface{}{block.Index, block.Hash, block.PrevHash, int64(block.Version), block.Timestamp,
+					now - block.Timestamp, block.Nonce, int64(len(block.Transactions)), block.MerkleRoot}
+				if err := collect(row); err != nil {
+					return nil, err
+				}
+			} else {
+				for _, tx := range block.Transactions {
+					row := []interface{}{tx.Hash, tx.From, tx.To, tx.Amount, tx.Fee, tx.Nonce, block.Index,
+						block.Timestamp, now - block.Timestamp, string(TransactionSource(&tx))}
+					if err := collect(row); err != nil {
+						return nil, err
+					}
+					if full() {
+						break scan
+					}
+				}
+			}
+			if full() {
+				break
+			}
+		}
+	}
+
+	if stmt.orderBy != "" {
+		col := columnIndex(columns, stmt.orderBy)
+		sort.SliceStable(rows, func(i, j int) bool {
+			cmp := compareValues(rows[i][col], rows[j][col])
+			if stmt.descending {
+				return cmp > 0
+			}
+			return cmp < 0
+		})
+	}
+	if len(rows) > limit {
+		rows = rows[:limit]
+	}
+
+	// Project the selected columns
+	result := &QueryResult{Columns: stmt.columns, Rows: make([][]interface{}, len(rows))}
+	for i, row := range rows {
+		projected := make([]interface{}, len(stmt.columns))
+		for j, name := range stmt.columns {
+			projected[j] = row[columnIndex(columns, name)]
+		}
+		result.Rows[i] = projected
+	}
+	return result, nil
+}
+
+// Query runs a read-only query over the chain and transaction pool (see ExecuteQuery)
+func (bc *Blockchain) Query(query string, args ...interface{}) (*QueryResult, error) {
+	return ExecuteQuery(bc, bc.TransactionPool, query, args...)
+}
+
+// Query runs a read-only query over the chain and transaction pool (see ExecuteQuery)
+func (pbc *PersistentBlockchain) Query(query string, args ...interface{}) (*QueryResult, error) {
+	return ExecuteQuery(pbc, pbc.TransactionPool, query, args...)
+}
+
+// queryCondition is a single comparison in a WHERE clause
+type queryCondition struct {
+	column string
+	op     string
+	value  interface{}
+}
+
+// queryStatement is a parsed query
+type queryStatement struct {
+	columns    []string
+	table      string
+	conditions []queryCondition
+	orderBy    string
+	descending bool
+	limit      int
+}
+
+// matches checks a row against all conditions
+func (stmt *queryStatement) matches(columns []string, row []interface{}) (bool, error) {
+	for _, cond := range stmt.conditions {
+		actual := row[columnIndex(columns, cond.column)]
+
+		expected := cond.value
+		if _, isString := actual.(string); !isString {
+			number, err := toNumber(expected)
+			if err != nil {
+				return false, fmt.Errorf("column %s is numeric: %w", cond.column, err)
+			}
+			expected = number
+		} else {
+			expected = fmt.Sprint(expected)
+		}
+
+		cmp := compareValues(actual, expected)
+		var ok bool
+		switch cond.op {
+		case "=":
+			ok = cmp == 0
+		case "!=", "<>":
+			ok = cmp != 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// queryToken is a lexical token of a query
+type queryToken struct {
+	kind  string // ident, number, string, op, param
+	text  string
+	value interface{}
+}
+
+// tokenizeQuery splits a query into tokens
+func tokenizeQuery(query string) ([]queryToken, error) {
+	var tokens []queryToken
+	runes := []rune(query)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '\'':
+			var sb strings.Builder
+			i++
+			for {
+				if i >= len(runes) {
+					return nil, errors.New("unterminated string literal")
+				}
+				if runes[i] == '\'' {
+					// A doubled quote is an escaped quote
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						sb.WriteRune('\'')
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			tokens = append(tokens, queryToken{kind: "string", value: sb.String()})
+
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			for i < len(runes) && unicode.IsLetter(runes[i]) {
+				i++
+			}
+			value, err := parseQueryNumber(string(runes[start:i]))
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, queryToken{kind: "number", text: string(runes[start:i]), value: value})
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, queryToken{kind: "ident", text: strings.ToLower(string(runes[start:i]))})
+
+		case r == '?':
+			tokens = append(tokens, queryToken{kind: "param", text: "?"})
+			i++
+
+		default:
+			if i+1 < len(runes) {
+				if two := string(runes[i : i+2]); two == "!=" || two == "<>" || two == "<=" || two == ">=" {
+					tokens = append(tokens, queryToken{kind: "op", text: two})
+					i += 2
+					continue
+				}
+			}
+			if strings.ContainsRune("=<>,*", r) {
+				tokens = append(tokens, queryToken{kind: "op", text: string(r)})
+				i++
+				continue
+			}
+			return nil, fmt.Errorf("unexpected character %q in query", r)
+		}
+	}
+	return tokens, nil
+}
+
+// parseQueryNumber parses a number or a duration literal (in seconds)
+func parseQueryNumber(text string) (float64, error) {
+	if number, err := strconv.ParseFloat(text, 64); err == nil {
+		return number, nil
+	}
+	if strings.HasSuffix(text, "d") {
+		days, err := strconv.ParseFloat(strings.TrimSuffix(text, "d"), 64)
+		if err == nil {
+			return days * 24 * 60 * 60, nil
+		}
+	}
+	duration, err := time.ParseDuration(text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number or duration %q", text)
+	}
+	return duration.Seconds(), nil
+}
+
+// queryParser parses a token stream into a statement
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+	args   []interface{}
+	argPos int
+}
+
+// parseQuery parses a query and binds its placeholders
+func parseQuery(query string, args []interface{}) (*queryStatement, error) {
+	tokens, err := tokenizeQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens, args: args}
+	stmt, err := p.parseStatement()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q at end of query", p.tokens[p.pos].text)
+	}
+	if p.argPos != len(args) {
+		return nil, fmt.Errorf("query has %d placeholders but %d arguments were given", p.argPos, len(args))
+	}
+	return stmt, nil
+}
+
+// parseStatement parses SELECT ... FROM ... [WHERE ...] [ORDER BY ...] [LIMIT ...]
+func (p *queryParser) parseStatement() (*queryStatement, error) {
+	stmt := &queryStatement{}
+
+	if err := p.expectKeyword("select"); err != nil {
+		return nil, err
+	}
+
+	var selected []string
+	star := false
+	for {
+		tok, ok := p.next()
+		if !ok {
+			return nil, errors.New("expected column list")
+		}
+		if tok.kind == "op" && tok.text == "*" {
+			star = true
+		} else if tok.kind == "ident" {
+			selected = append(selected, tok.text)
+		} else {
+			return nil, fmt.Errorf("unexpected %q in column list", tok.text)
+		}
+		if !p.acceptOp(",") {
+			break
+		}
+	}
+
+	if err := p.expectKeyword("from"); err != nil {
+		return nil, err
+	}
+	tok, ok := p.next()
+	if !ok || tok.kind != "ident" {
+		return nil, errors.New("expected table name")
+	}
+	columns, exists := queryTables[tok.text]
+	if !exists {
+		return nil, fmt.Errorf("unknown table %q (expected mempool, transactions, blocks or admissions)", tok.text)
+	}
+	stmt.table = tok.text
+
+	if star {
+		if len(selected) > 0 {
+			return nil, errors.New("* cannot be combined with other columns")
+		}
+		selected = columns
+	}
+	for _, name := range selected {
+		if columnIndex(columns, name) < 0 {
+			return nil, fmt.Errorf("unknown column %q in table %s", name, stmt.table)
+		}
+	}
+	stmt.columns = selected
+
+	if p.acceptKeyword("where") {
+		for {
+			cond, err := p.parseCondition(columns)
+			if err != nil {
+				return nil, err
+			}
+			stmt.conditions = append(stmt.conditions, cond)
+			if !p.acceptKeyword("and") {
+				break
+			}
+		}
+	}
+
+	if p.acceptKeyword("order") {
+		if err := p.expectKeyword("by"); err != nil {
+			return nil, err
+		}
+		tok, ok := p.next()
+		if !ok || tok.kind != "ident" || columnIndex(columns, tok.text) < 0 {
+			return nil, errors.New("expected column after ORDER BY")
+		}
+		stmt.orderBy = tok.text
+		if p.acceptKeyword("desc") {
+			stmt.descending = true
+		} else {
+			p.acceptKeyword("asc")
+		}
+	}
+
+	if p.acceptKeyword("limit") {
+		tok, ok := p.next()
+		if !ok {
+			return nil, errors.New("expected number after LIMIT")
+		}
+		value := tok.value
+		if tok.kind == "param" {
+			value = p.bindArg()
+		}
+		limit, err := toNumber(value)
+		if err != nil || limit < 1 {
+			return nil, errors.New("LIMIT must be a positive number")
+		}
+		stmt.limit = int(limit)
+	}
+
+	return stmt, nil
+}
+
+// parseCondition parses col op value
+func (p *queryParser) parseCondition(columns []string) (queryCondition, error) {
+	col, ok := p.next()
+	if !ok || col.kind != "ident" {
+		return queryCondition{}, errors.New("expected column in WHERE clause")
+	}
+	if columnIndex(columns, col.text) < 0 {
+		return queryCondition{}, fmt.Errorf("unknown column %q", col.text)
+	}
+
+	op, ok := p.next()
+	if !ok || op.kind != "op" || op.text == "," || op.text == "*" {
+		return queryCondition{}, fmt.Errorf("expected comparison operator after %s", col.text)
+	}
+
+	value, ok := p.next()
+	if !ok {
+		return queryCondition{}, fmt.Errorf("expected value after %s %s", col.text, op.text)
+	}
+
+	cond := queryCondition{column: col.text, op: op.text}
+	switch value.kind {
+	case "number", "string":
+		cond.value = value.value
+	case "param":
+		cond.value = p.bindArg()
+	default:
+		return queryCondition{}, fmt.Errorf("expected value after %s %s", col.text, op.text)
+	}
+	return cond, nil
+}
+
+// bindArg returns the next placeholder argument
+func (p *queryParser) bindArg() interface{} {
+	p.argPos++
+	if p.argPos > len(p.args) {
+		return nil
+	}
+	return p.args[p.argPos-1]
+}
+
+// next consumes the next token
+func (p *queryParser) next() (queryToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return queryToken{}, false
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	return tok, true
+}
+
+// acceptKeyword consumes the next token if it is the keyword
+func (p *queryParser) acceptKeyword(keyword string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == "ident" && p.tokens[p.pos].text == keyword {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// acceptOp consumes the next token if it is the operator
+func (p *queryParser) acceptOp(op string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == "op" && p.tokens[p.pos].text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// expectKeyword consumes a required keyword
+func (p *queryParser) expectKeyword(keyword string) error {
+	if !p.acceptKeyword(keyword) {
+		return fmt.Errorf("expected %s", strings.ToUpper(keyword))
+	}
+	return nil
+}
+
+// columnIndex returns the position of a column, or -1
+func columnIndex(columns []string, name string) int {
+	for i, column := range columns {
+		if column == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// toNumber converts a query value to float64
+func toNumber(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case time.Duration:
+		return v.Seconds(), nil
+	case string:
+		return parseQueryNumber(v)
+	}
+	return 0, fmt.Errorf("%v is not a number", value)
+}
+
+// compareValues orders two column values (numbers numerically, strings lexically)
+func compareValues(a, b interface{}) int {
+	if as, ok := a.(string); ok {
+		return strings.Compare(as, fmt.Sprint(b))
+	}
+
+	an, _ := toNumber(a)
+	bn, _ := toNumber(b)
+	switch {
+	case an < bn:
+		return -1
+	case an > bn:
+		return 1
+	}
+	return 0
+}
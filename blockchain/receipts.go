@@ -0,0 +1,222 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+
+	"blockchain/blockchain/verify"
+)
+
+// Receipt statuses. Blocks only confirm transactions that execute, so every receipt is
+// successful for now; the status leaves room for execution that can fail but still pays its fee.
+const (
+	ReceiptFailed  = 0
+	ReceiptSuccess = 1
+)
+
+// Event types emitted by transactions
+const (
+	EventMint   = "mint"   // Coinbase amount created for the recipient
+	EventDebit  = "debit"  // Amount taken from the sender
+	EventCredit = "credit" // Amount paid to the recipient
+	EventFee    = "fee"    // Fee paid by the sender or its sponsor
+	EventKVSet  = "kv.set" // Key set in the sender's namespace, the key as data
+)
+
+// ErrInvalidReceiptProof is returned for receipt proofs that don't match a block header
+var ErrInvalidReceiptProof = errors.New("invalid receipt proof")
+
+// Receipt is the result of executing a confirmed transaction: its status, the fee it consumed
+// and the events it emitted. A block commits the receipts of its transactions in order, so a
+// light client can check what a transaction did from a proof against the header.
+type Receipt struct {
+	TxHash   string         `json:"txHash"`
+	Status   int            `json:"status"`
+	FeePayer string         `json:"feePayer,omitempty"` // Sender or sponsor, "" for coinbase transactions
+	Fee      float64        `json:"fee"`
+	Events   []ReceiptEvent `json:"events,omitempty"`
+}
+
+// ReceiptEvent is an effect of a transaction on one account
+type ReceiptEvent struct {
+	Type    string  `json:"type"`
+	Address string  `json:"address"`
+	Amount  float64 `json:"amount,omitempty"`
+	Data    string  `json:"data,omitempty"`
+}
+
+// ReceiptProof proves the receipt of a transaction against the header of its block: Proof puts
+// the receipt under Root, which the header commits as its ReceiptRoot
+type ReceiptProof struct {
+	BlockIndex int64        `json:"blockIndex"`
+	BlockHash  string       `json:"blockHash"`
+	Receipt    Receipt      `json:"receipt"`
+	Root       string       `json:"root"`
+	Proof      *MerkleProof `json:"proof"`
+}
+
+// newReceipt executes a transaction into its receipt
+func newReceipt(tx *Transaction) Receipt {
+	receipt := Receipt{TxHash: tx.Hash, Status: ReceiptSuccess}
+	if tx.IsCoinbase() {
+		receipt.Events = []ReceiptEvent{{Type: EventMint, Address: tx.To, Amount: tx.Amount}}
+		return receipt
+	}
+
+	receipt.FeePayer = tx.FeePayer()
+	receipt.Fee = tx.Fee
+	if tx.Amount != 0 {
+		receipt.Events = append(receipt.Events,
+			ReceiptEvent{Type: EventDebit, Address: tx.From, Amount: tx.Amount},
+			ReceiptEvent{Type: EventCredit, Address: tx.To, Amount: tx.Amount})
+	}
+	if tx.Fee != 0 {
+		receipt.Events = append(receipt.Events, ReceiptEvent{Type: EventFee, Address: receipt.FeePayer, Amount: tx.Fee})
+	}
+	if tx.To == KVNamespaceAddress {
+		if entry, err := parseKVEntry(tx); err == nil {
+			receipt.Events = append(receipt.Events, ReceiptEvent{Type: EventKVSet, Address: entry.Namespace, Data: entry.Key})
+		}
+	}
+	return receipt
+}
+
+// leafHash returns the Merkle leaf committing the receipt
+func (r *Receipt) leafHash() string {
+	events := make([]verify.Event, len(r.Events))
+	for i, e := range r.Events {
+		events[i] = verify.Event{Type: e.Type, Address: e.Address, Amount: e.Amount, Data: e.Data}
+	}
+	return verify.ReceiptLeafHash(&verify.Receipt{
+		TxHash:   r.TxHash,
+		Status:   int64(r.Status),
+		FeePayer: r.FeePayer,
+		Fee:      r.Fee,
+		Events:   events,
+	})
+}
+
+// Receipts returns the receipts of the block's transactions in order
+func (b *Block) Receipts() ([]Receipt, error) {
+	if b.Pruned {
+		return nil, fmt.Errorf("block %d: %w", b.Index, ErrPruned)
+	}
+	receipts := make([]Receipt, len(b.Transactions))
+	for i := range b.Transactions {
+		receipts[i] = newReceipt(&b.Transactions[i])
+	}
+	return receipts, nil
+}
+
+// receiptTree builds the domain-separated Merkle tree over the block's receipts
+func (b *Block) receiptTree() (*MerkleTree, []Receipt, error) {
+	receipts, err := b.Receipts()
+	if err != nil {
+		return nil, nil, err
+	}
+	leaves := make([]string, len(receipts))
+	for i := range receipts {
+		leaves[i] = receipts[i].leafHash()
+	}
+	return newMerkleTreeFromHashes(leaves, TaggedMerkleScheme), receipts, nil
+}
+
+// calculateReceiptRoot returns the root of the block's receipts ("" if it has no transactions)
+func (b *Block) calculateReceiptRoot() (string, error) {
+	tree, _, err := b.receiptTree()
+	if err != nil {
+		return "", err
+	}
+	return tree.GetMerkleRoot(), nil
+}
+
+// receiptCommitment returns the receipt root the block's version commits in its header
+func (b *Block) receiptCommitment() string {
+	if b.Version < ReceiptRootBlockVersion {
+		return ""
+	}
+	root, _ := b.calculateReceiptRoot()
+	return root
+}
+
+// validateReceiptRoot checks the header commits the receipts of the block's transactions,
+// and that blocks older than ReceiptRootBlockVersion commit none
+func (b *Block) validateReceiptRoot() error {
+	if b.Version < ReceiptRootBlockVersion {
+		if b.ReceiptRoot != "" {
+			return fmt.Errorf("block version %d does not commit receipts", b.Version)
+		}
+		return nil
+	}
+	root, err := b.calculateReceiptRoot()
+	if err != nil {
+		return err
+	}
+	if b.ReceiptRoot != root {
+		return errors.New("receipt root does not match the transactions")
+	}
+	return nil
+}
+
+// GenerateReceiptProof proves the receipt of one of the block's transactions against its header
+func (b *Block) GenerateReceiptProof(txIndex int) (*ReceiptProof, error) {
+	tree, receipts, err := b.receiptTree()
+	if err != nil {
+		return nil, err
+	}
+	if txIndex < 0 || txIndex >= len(receipts) {
+		return nil, fmt.Errorf("transaction index %d out of range (%d transactions)", txIndex, len(receipts))
+	}
+	if b.ReceiptRoot == "" {
+		return nil, fmt.Errorf("block %d (version %d) does not commit its receipts", b.Index, b.Version)
+	}
+	proof, err := tree.GenerateProofByIndex(txIndex)
+	if err != nil {
+		return nil, err
+	}
+	return &ReceiptProof{
+		BlockIndex: b.Index,
+		BlockHash:  b.Hash,
+		Receipt:    receipts[txIndex],
+		Root:       tree.GetMerkleRoot(),
+		Proof:      proof,
+	}, nil
+}
+
+// VerifyReceiptProof checks a receipt proof against the header of its block, which the caller
+// trusts, e.g. one synced by a LightClient
+func VerifyReceiptProof(proof *ReceiptProof, header BlockHeader) error {
+	if proof == nil || proof.Proof == nil {
+		return errors.New("missing proof")
+	}
+	if proof.BlockIndex != header.Index || proof.BlockHash != header.Hash {
+		return fmt.Errorf("proof is for block %d, not %d: %w", proof.BlockIndex, header.Index, ErrInvalidReceiptProof)
+	}
+	if proof.Proof.Hash != proof.Receipt.leafHash() || !VerifyProof(proof.Proof, proof.Root, TaggedMerkleScheme) {
+		return fmt.Errorf("receipt of %s: %w", proof.Receipt.TxHash, ErrInvalidReceiptProof)
+	}
+	if header.ReceiptRoot == "" || header.ReceiptRoot != proof.Root {
+		return fmt.Errorf("block %d does not commit the receipt root: %w", header.Index, ErrInvalidReceiptProof)
+	}
+	return nil
+}
+
+// GetReceiptProof returns the receipt of a confirmed transaction with its proof against the
+// header of its block
+func (bc *Blockchain) GetReceiptProof(txHash string) (*ReceiptProof, error) {
+	_, location, err := bc.GetTransaction(txHash)
+	if err != nil {
+		return nil, err
+	}
+	return bc.Chain[location.BlockIndex].GenerateReceiptProof(location.TxIndex)
+}
+
+// GetReceiptProof returns the receipt of a confirmed transaction with its proof against the
+// header of its block. Receipts of pruned blocks are gone with their bodies.
+func (pbc *PersistentBlockchain) GetReceiptProof(txHash string) (*ReceiptProof, error) {
+	_, location, err := pbc.GetTransaction(txHash)
+	if err != nil {
+		return nil, err
+	}
+	return pbc.Chain[location.BlockIndex].GenerateReceiptProof(location.TxIndex)
+}
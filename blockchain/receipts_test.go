@@ -0,0 +1,68 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReceiptRootActivatesWithBlockVersion(t *testing.T) {
+	bc := NewBlockchain(1, "miner")
+	if err := bc.Upgrades.AddUpgrade(Upgrade{Name: "receipts", Height: 2, Version: ReceiptRootBlockVersion}); err != nil {
+		t.Fatal(err)
+	}
+	bc.MinePendingTransactions()
+	tx := NewTransaction("miner", "bob", 2, 0.5)
+	if err := bc.AddTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+	bc.MinePendingTransactions()
+
+	before, after := bc.Chain[1], bc.Chain[2]
+	if before.ReceiptRoot != "" {
+		t.Fatal("block below the upgrade commits a receipt root")
+	}
+	if _, err := before.GenerateReceiptProof(0); err == nil {
+		t.Fatal("proved a receipt of a block that commits none")
+	}
+	if after.ReceiptRoot == "" || !bc.IsChainValid() {
+		t.Fatal("block from the upgrade on does not commit a valid receipt root")
+	}
+
+	proof, err := bc.GetReceiptProof(tx.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := after.Header()
+	if err := VerifyReceiptProof(proof, header); err != nil {
+		t.Fatal(err)
+	}
+	if proof.Receipt.Fee != 0.5 || len(proof.Receipt.Events) != 3 {
+		t.Fatalf("unexpected receipt %+v", proof.Receipt)
+	}
+
+	// The root is part of the hashed header, so a light client can't be shown another one
+	forged := header
+	forged.ReceiptRoot = before.MerkleRoot
+	if forged.calculateHash() == header.Hash {
+		t.Fatal("receipt root is not covered by the header hash")
+	}
+	if err := VerifyReceiptProof(proof, forged); !errors.Is(err, ErrInvalidReceiptProof) {
+		t.Fatalf("proof verified against another receipt root: %v", err)
+	}
+
+	// The compact encoding carries the root from the upgrade's version on
+	for _, block := range []*Block{before, after} {
+		header := block.Header()
+		data, err := header.MarshalCompact()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := UnmarshalCompactHeader(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.ReceiptRoot != block.ReceiptRoot || decoded.calculateHash() != block.Hash {
+			t.Fatalf("block %d: compact header lost the receipt root", block.Index)
+		}
+	}
+}
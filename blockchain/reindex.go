@@ -0,0 +1,196 @@
+package blockchain
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DerivedIndexVersion is the version of the tables derived from block data. Bump it when
+// their schema or contents change, so existing databases are reindexed when opened.
+const DerivedIndexVersion = 2
+
+// derivedTables are rebuilt by Reindex from the raw blocks, in deletion order
+var derivedTables = []string{"transactions", "ledger_entries", "addresses", "blockchain_state"}
+
+// ReindexProgress reports how far a reindex got
+type ReindexProgress struct {
+	Height int64 `json:"height"` // Last reindexed block
+	Target int64 `json:"target"` // Latest stored block when the batch ran
+}
+
+// ReindexConfig configures a reindex
+type ReindexConfig struct {
+	BatchSize int                   // Blocks per database transaction (default 500)
+	Progress  func(ReindexProgress) // Called after every committed batch
+	Restart   bool                  // Start over instead of resuming an interrupted reindex
+}
+
+// ReindexReport summarizes a reindex
+type ReindexReport struct {
+	Blocks       int   `json:"blocks"` // Blocks reindexed by this run
+	Transactions int   `json:"transactions"`
+	ResumedAt    int64 `json:"resumedAt"` // Height this run started from (0 unless resumed)
+	Resumed      bool  `json:"resumed"`
+}
+
+// NeedsReindex reports whether the derived tables predate DerivedIndexVersion
+// or a reindex was interrupted
+func (d *Database) NeedsReindex() (bool, error) {
+	version, next, err := d.derivedIndexState()
+	if err != nil {
+		return false, err
+	}
+	return version < DerivedIndexVersion || next.Valid, nil
+}
+
+// Reindex rebuilds the derived tables (transactions, ledger entries, addresses, blockchain state) from the raw
+// block data. Progress is committed with every batch, so an interrupted reindex resumes where it
+// stopped the next time Reindex runs. Blocks must not be saved while reindexing.
+func (d *Database) Reindex(config ReindexConfig) (*ReindexReport, error) {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 500
+	}
+	if err := d.Flush(); err != nil {
+		return nil, err
+	}
+
+	// Pruned blocks no longer hold the transactions the derived tables would be rebuilt from
+	if state, err := d.LoadPruneState(); err != nil {
+		return nil, err
+	} else if state != nil {
+		return nil, fmt.Errorf("cannot reindex a pruned database: %w", ErrPruned)
+	}
+
+	_, next, err := d.derivedIndexState()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReindexReport{}
+	height := int64(0)
+	if next.Valid && !config.Restart {
+		height = next.Int64
+		report.ResumedAt = height
+		report.Resumed = true
+	} else if err := d.startReindex(); err != nil {
+		return nil, err
+	}
+
+	for {
+		blocks, err := d.blocksFrom(height, config.BatchSize)
+		if err != nil {
+			return report, fmt.Errorf("failed to load blocks from height %d: %w", height, err)
+		}
+		if len(blocks) == 0 {
+			break
+		}
+
+		transactions, err := d.reindexBatch(blocks)
+		if err != nil {
+			return report, fmt.Errorf("failed to reindex blocks from height %d: %w", height, err)
+		}
+		height = blocks[len(blocks)-1].Index + 1
+		report.Blocks += len(blocks)
+		report.Transactions += transactions
+
+		if config.Progress != nil {
+			var target int64
+			d.db.QueryRow(d.rebind("SELECT COALESCE(MAX(block_index), 0) FROM blocks")).Scan(&target)
+			config.Progress(ReindexProgress{Height: height - 1, Target: target})
+		}
+	}
+
+	_, err = d.db.Exec(d.rebind(`
+		UPDATE derived_index SET version = ?, reindex_next_height = NULL, reindex_started_at = NULL
+		WHERE id = 1`), DerivedIndexVersion)
+	if err != nil {
+		return report, fmt.Errorf("failed to finish reindex: %w", err)
+	}
+	return report, nil
+}
+
+// derivedIndexState returns the derived table version and the next height of an interrupted reindex
+func (d *Database) derivedIndexState() (int64, sql.NullInt64, error) {
+	var version int64
+	var next sql.NullInt64
+	err := d.db.QueryRow(d.rebind("SELECT version, reindex_next_height FROM derived_index WHERE id = 1")).Scan(&version, &next)
+	if err == sql.ErrNoRows {
+		return 0, next, nil
+	}
+	return version, next, err
+}
+
+// startReindex clears the derived tables and records a reindex from genesis
+func (d *Database) startReindex() error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range derivedTables {
+		if _, err := tx.Exec("DELETE FROM " + table); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+	_, err = tx.Exec(d.rebind(`
+		INSERT INTO derived_index (id, version, reindex_next_height, reindex_started_at) VALUES (1, 0, 0, ?)
+		ON CONFLICT(id) DO UPDATE SET reindex_next_height = 0, reindex_started_at = excluded.reindex_started_at`),
+		time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to record reindex start: %w", err)
+	}
+	return tx.Commit()
+}
+
+// blocksFrom loads up to limit stored blocks from a height on
+func (d *Database) blocksFrom(height int64, limit int) ([]*Block, error) {
+	rows, err := d.db.Query(d.rebind("SELECT block_data FROM blocks WHERE block_index >= ? ORDER BY block_index ASC LIMIT ?"), height, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blocks []*Block
+	for rows.Next() {
+		var blockData string
+		if err := rows.Scan(&blockData); err != nil {
+			return nil, err
+		}
+
+		block, err := decodeStoredBlock(blockData)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, rows.Err()
+}
+
+// reindexBatch rebuilds the derived rows of a batch of blocks and records the progress atomically
+func (d *Database) reindexBatch(blocks []*Block) (int, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	transactions := 0
+	for _, block := range blocks {
+		rows, err := d.newBlockRows(block, false)
+		if err != nil {
+			return 0, err
+		}
+		if err := d.writeBlockRows(tx, rows); err != nil {
+			return 0, err
+		}
+		transactions += len(block.Transactions)
+	}
+
+	next := blocks[len(blocks)-1].Index + 1
+	if _, err := tx.Exec(d.rebind("UPDATE derived_index SET reindex_next_height = ? WHERE id = 1"), next); err != nil {
+		return 0, fmt.Errorf("failed to record reindex progress: %w", err)
+	}
+	return transactions, tx.Commit()
+}
@@ -0,0 +1,221 @@
+package blockchain
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+)
+
+// secp256k1 is the Koblitz curve y² = x³ + 7 used by Bitcoin and Ethereum (SEC 2, section 2.4.1).
+// The standard library has no implementation, and elliptic.CurveParams assumes a = -3, so
+// points are handled here in affine coordinates. The arithmetic is not constant-time.
+var secp256k1 = struct {
+	P, N, B, Gx, Gy *big.Int
+}{
+	P:  hexInt("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f"),
+	N:  hexInt("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141"),
+	B:  big.NewInt(7),
+	Gx: hexInt("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"),
+	Gy: hexInt("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"),
+}
+
+// hexInt parses a hex constant
+func hexInt(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("invalid hex constant " + s)
+	}
+	return n
+}
+
+// secp256k1Point is an affine point, the point at infinity having a nil X
+type secp256k1Point struct {
+	X, Y *big.Int
+}
+
+// infinity reports whether the point is the point at infinity
+func (pt secp256k1Point) infinity() bool {
+	return pt.X == nil
+}
+
+// secp256k1Add adds two points
+func secp256k1Add(a, b secp256k1Point) secp256k1Point {
+	if a.infinity() {
+		return b
+	}
+	if b.infinity() {
+		return a
+	}
+
+	p := secp256k1.P
+	var lambda *big.Int
+	if a.X.Cmp(b.X) == 0 {
+		if sum := new(big.Int).Add(a.Y, b.Y); sum.Mod(sum, p).Sign() == 0 {
+			return secp256k1Point{}
+		}
+		// Doubling: λ = 3x² / 2y
+		numerator := new(big.Int).Mul(a.X, a.X)
+		numerator.Mul(numerator, big.NewInt(3))
+		denominator := new(big.Int).Lsh(a.Y, 1)
+		lambda = numerator.Mul(numerator, denominator.ModInverse(denominator.Mod(denominator, p), p))
+	} else {
+		// λ = (y2 - y1) / (x2 - x1)
+		numerator := new(big.Int).Sub(b.Y, a.Y)
+		denominator := new(big.Int).Sub(b.X, a.X)
+		lambda = numerator.Mul(numerator, denominator.ModInverse(denominator.Mod(denominator, p), p))
+	}
+	lambda.Mod(lambda, p)
+
+	x := new(big.Int).Mul(lambda, lambda)
+	x.Sub(x, a.X).Sub(x, b.X).Mod(x, p)
+	y := new(big.Int).Sub(a.X, x)
+	y.Mul(y, lambda).Sub(y, a.Y).Mod(y, p)
+	return secp256k1Point{X: x, Y: y}
+}
+
+// secp256k1ScalarMult multiplies a point by a big-endian scalar
+func secp256k1ScalarMult(pt secp256k1Point, k []byte) secp256k1Point {
+	result := secp256k1Point{}
+	for _, b := range k {
+		for bit := 7; bit >= 0; bit-- {
+			result = secp256k1Add(result, result)
+			if b>>uint(bit)&1 == 1 {
+				result = secp256k1Add(result, pt)
+			}
+		}
+	}
+	return result
+}
+
+// secp256k1BaseMult multiplies the generator by a big-endian scalar
+func secp256k1BaseMult(k []byte) secp256k1Point {
+	return secp256k1ScalarMult(secp256k1Point{X: secp256k1.Gx, Y: secp256k1.Gy}, k)
+}
+
+// secp256k1Right computes x³ + 7 mod p
+func secp256k1Right(x *big.Int) *big.Int {
+	right := new(big.Int).Mul(x, x)
+	right.Mul(right, x).Add(right, secp256k1.B)
+	return right.Mod(right, secp256k1.P)
+}
+
+// secp256k1Compress encodes a point as its SEC 1 compressed form (33 bytes)
+func secp256k1Compress(pt secp256k1Point) []byte {
+	encoded := make([]byte, 33)
+	encoded[0] = 0x02 | byte(pt.Y.Bit(0))
+	pt.X.FillBytes(encoded[1:])
+	return encoded
+}
+
+// secp256k1Decompress decodes a SEC 1 compressed point
+func secp256k1Decompress(encoded []byte) (secp256k1Point, error) {
+	if len(encoded) != 33 || (encoded[0] != 0x02 && encoded[0] != 0x03) {
+		return secp256k1Point{}, errors.New("invalid compressed secp256k1 point")
+	}
+	x := new(big.Int).SetBytes(encoded[1:])
+	if x.Cmp(secp256k1.P) >= 0 {
+		return secp256k1Point{}, errors.New("invalid compressed secp256k1 point")
+	}
+	y := new(big.Int).ModSqrt(secp256k1Right(x), secp256k1.P)
+	if y == nil {
+		return secp256k1Point{}, errors.New("secp256k1 point is not on the curve")
+	}
+	if y.Bit(0) != uint(encoded[0]&1) {
+		y.Sub(secp256k1.P, y)
+	}
+	return secp256k1Point{X: x, Y: y}, nil
+}
+
+// secp256k1Signer signs with a secp256k1 private scalar (ECDSA over SHA-256, RFC 6979, low s)
+type secp256k1Signer struct {
+	d      *big.Int
+	public secp256k1Point
+}
+
+// newSecp256k1Signer builds a signer from a 32-byte private scalar
+func newSecp256k1Signer(privateKey []byte) (*secp256k1Signer, error) {
+	d := new(big.Int).SetBytes(privateKey)
+	if len(privateKey) != 32 || d.Sign() <= 0 || d.Cmp(secp256k1.N) >= 0 {
+		return nil, errors.New("invalid secp256k1 private key")
+	}
+	return &secp256k1Signer{d: d, public: secp256k1BaseMult(privateKey)}, nil
+}
+
+// generateSecp256k1Signer creates a signer with a random key
+func generateSecp256k1Signer() (*secp256k1Signer, error) {
+	privateKey := make([]byte, 32)
+	for {
+		if _, err := rand.Read(privateKey); err != nil {
+			return nil, err
+		}
+		if signer, err := newSecp256k1Signer(privateKey); err == nil {
+			return signer, nil
+		}
+	}
+}
+
+// Scheme returns SchemeSecp256k1
+func (s *secp256k1Signer) Scheme() SignatureScheme {
+	return SchemeSecp256k1
+}
+
+// PublicKey returns the compressed public point
+func (s *secp256k1Signer) PublicKey() []byte {
+	return secp256k1Compress(s.public)
+}
+
+// Sign signs the SHA-256 of a message
+func (s *secp256k1Signer) Sign(message []byte) ([]byte, error) {
+	r, sig, err := signDeterministicCurve(secp256k1Curve, s.d, sha256Digest(message))
+	if err != nil {
+		return nil, err
+	}
+	return encodeSignature(r, sig), nil
+}
+
+// privateKeyBytes returns the private scalar
+func (s *secp256k1Signer) privateKeyBytes() []byte {
+	return s.d.FillBytes(make([]byte, 32))
+}
+
+// wipe zeroes the private scalar
+func (s *secp256k1Signer) wipe() {
+	s.d.SetInt64(0)
+}
+
+// secp256k1Verifier checks secp256k1 ECDSA signatures
+type secp256k1Verifier struct{}
+
+// Verify checks a 64-byte or DER low-s signature over the SHA-256 of a message
+func (secp256k1Verifier) Verify(publicKey, message, signature []byte) bool {
+	q, err := secp256k1Decompress(publicKey)
+	if err != nil {
+		return false
+	}
+	r, s, err := parseSignatureForOrder(signature, secp256k1.N)
+	if err != nil {
+		return false
+	}
+
+	n := secp256k1.N
+	e := new(big.Int).SetBytes(sha256Digest(message))
+	w := new(big.Int).ModInverse(s, n)
+	u1 := new(big.Int).Mul(e, w)
+	u1.Mod(u1, n)
+	u2 := new(big.Int).Mul(r, w)
+	u2.Mod(u2, n)
+
+	point := secp256k1Add(secp256k1BaseMult(u1.Bytes()), secp256k1ScalarMult(q, u2.Bytes()))
+	if point.infinity() {
+		return false
+	}
+	return new(big.Int).Mod(point.X, n).Cmp(r) == 0
+}
+
+// secp256k1Curve is the curve description used for deterministic signing
+var secp256k1Curve = ecdsaCurve{
+	N: secp256k1.N,
+	BaseX: func(k []byte) *big.Int {
+		return secp256k1BaseMult(k).X
+	},
+}
@@ -0,0 +1,173 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"math/big"
+)
+
+// SignatureSize is the size of an encoded transaction signature: r and s as 32 bytes each
+const SignatureSize = 64
+
+// derSignature is the ASN.1 structure of a DER-encoded ECDSA signature
+type derSignature struct {
+	R, S *big.Int
+}
+
+// ecdsaCurve describes a 256-bit curve for deterministic ECDSA signing
+type ecdsaCurve struct {
+	N     *big.Int                // Group order
+	BaseX func(k []byte) *big.Int // X coordinate of k·G
+}
+
+// p256Curve is the curve description of P-256
+var p256Curve = ecdsaCurve{
+	N: elliptic.P256().Params().N,
+	BaseX: func(k []byte) *big.Int {
+		x, _ := elliptic.P256().ScalarBaseMult(k)
+		return x
+	},
+}
+
+// sha256Digest returns the SHA-256 of a message
+func sha256Digest(message []byte) []byte {
+	digest := sha256.Sum256(message)
+	return digest[:]
+}
+
+// signDeterministic signs a SHA-256 digest with a P-256 key (see signDeterministicCurve)
+func signDeterministic(key *ecdsa.PrivateKey, digest []byte) (*big.Int, *big.Int, error) {
+	if key.D == nil {
+		return nil, nil, errors.New("invalid private key")
+	}
+	return signDeterministicCurve(p256Curve, key.D, digest)
+}
+
+// signDeterministicCurve signs a SHA-256 digest with a nonce derived per RFC 6979 (HMAC-SHA256),
+// so the same key and message always give the same signature, and normalizes s to the lower half
+// of the curve order so the signature can't be altered into a second valid one.
+func signDeterministicCurve(curve ecdsaCurve, d *big.Int, digest []byte) (*big.Int, *big.Int, error) {
+	n := curve.N
+	if d.Sign() <= 0 || d.Cmp(n) >= 0 {
+		return nil, nil, errors.New("invalid private key")
+	}
+
+	e := new(big.Int).SetBytes(digest)
+	x := d.FillBytes(make([]byte, 32))
+	h := new(big.Int).Mod(e, n).FillBytes(make([]byte, 32))
+
+	hmacSum := func(key []byte, parts ...[]byte) []byte {
+		mac := hmac.New(sha256.New, key)
+		for _, part := range parts {
+			mac.Write(part)
+		}
+		return mac.Sum(nil)
+	}
+	v := bytes.Repeat([]byte{0x01}, 32)
+	k := make([]byte, 32)
+	k = hmacSum(k, v, []byte{0x00}, x, h)
+	v = hmacSum(k, v)
+	k = hmacSum(k, v, []byte{0x01}, x, h)
+	v = hmacSum(k, v)
+
+	for {
+		v = hmacSum(k, v)
+		nonce := new(big.Int).SetBytes(v)
+		if nonce.Sign() > 0 && nonce.Cmp(n) < 0 {
+			r := new(big.Int).Mod(curve.BaseX(v), n)
+			if r.Sign() > 0 {
+				s := new(big.Int).Mul(r, d)
+				s.Add(s, e)
+				s.Mul(s, new(big.Int).ModInverse(nonce, n))
+				s.Mod(s, n)
+				if s.Sign() > 0 {
+					if s.Cmp(halfOrder(n)) > 0 {
+						s.Sub(n, s)
+					}
+					return r, s, nil
+				}
+			}
+		}
+		k = hmacSum(k, v, []byte{0x00})
+		v = hmacSum(k, v)
+	}
+}
+
+// halfOrder returns n/2, the largest s a canonical signature may have
+func halfOrder(n *big.Int) *big.Int {
+	return new(big.Int).Rsh(n, 1)
+}
+
+// encodeSignature encodes r and s as fixed-size big-endian halves
+func encodeSignature(r, s *big.Int) []byte {
+	signature := make([]byte, SignatureSize)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return signature
+}
+
+// parseSignature decodes a fixed-size r||s or a strict DER P-256 signature and
+// rejects values out of range or with a high s
+func parseSignature(signature []byte) (*big.Int, *big.Int, error) {
+	return parseSignatureForOrder(signature, p256Curve.N)
+}
+
+// parseSignatureForOrder parses a signature of a curve with group order n (see parseSignature)
+func parseSignatureForOrder(signature []byte, n *big.Int) (*big.Int, *big.Int, error) {
+	var r, s *big.Int
+	switch {
+	case len(signature) == SignatureSize:
+		r = new(big.Int).SetBytes(signature[:32])
+		s = new(big.Int).SetBytes(signature[32:])
+	case len(signature) > 0 && signature[0] == 0x30:
+		var parsed derSignature
+		rest, err := asn1.Unmarshal(signature, &parsed)
+		if err != nil || len(rest) > 0 {
+			return nil, nil, errors.New("malformed DER signature")
+		}
+		// Only the canonical encoding is accepted, so a signature has a single byte form
+		canonical, err := asn1.Marshal(parsed)
+		if err != nil || !bytes.Equal(canonical, signature) {
+			return nil, nil, errors.New("non-canonical DER signature")
+		}
+		r, s = parsed.R, parsed.S
+	default:
+		return nil, nil, errors.New("signature must be 64 bytes or DER encoded")
+	}
+
+	if r.Sign() <= 0 || r.Cmp(n) >= 0 || s.Sign() <= 0 || s.Cmp(n) >= 0 {
+		return nil, nil, errors.New("signature value out of range")
+	}
+	if s.Cmp(halfOrder(n)) > 0 {
+		return nil, nil, errors.New("signature has a high s value")
+	}
+	return r, s, nil
+}
+
+// SignatureToDER converts a hex transaction signature to hex DER, for tools expecting ASN.1
+func SignatureToDER(signature string) (string, error) {
+	r, s, err := parseSignatureHex(signature)
+	if err != nil {
+		return "", err
+	}
+	der, err := asn1.Marshal(derSignature{R: r, S: s})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(der), nil
+}
+
+// parseSignatureHex decodes and parses a hex-encoded signature
+func parseSignatureHex(signature string) (*big.Int, *big.Int, error) {
+	sigBytes, err := hex.DecodeString(signature)
+	if err != nil {
+		return nil, nil, errors.New("signature is not hex encoded")
+	}
+	return parseSignature(sigBytes)
+}
@@ -0,0 +1,298 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// SignatureScheme identifies the key type and signature algorithm of a wallet.
+// Its value is the tag byte embedded in encoded public keys and in non-P-256 addresses.
+type SignatureScheme byte
+
+const (
+	SchemeP256      SignatureScheme = 0 // ECDSA over P-256, the original scheme with untagged addresses
+	SchemeEd25519   SignatureScheme = 1 // Ed25519, fast and deterministic
+	SchemeSecp256k1 SignatureScheme = 2 // ECDSA over secp256k1, as used by Bitcoin and Ethereum
+)
+
+// schemeNames are the names of the signature schemes
+var schemeNames = map[SignatureScheme]string{
+	SchemeP256:      "p256",
+	SchemeEd25519:   "ed25519",
+	SchemeSecp256k1: "secp256k1",
+}
+
+// String returns the scheme name
+func (s SignatureScheme) String() string {
+	if name, exists := schemeNames[s]; exists {
+		return name
+	}
+	return fmt.Sprintf("scheme(%d)", byte(s))
+}
+
+// ParseSignatureScheme returns the scheme with a name
+func ParseSignatureScheme(name string) (SignatureScheme, error) {
+	for scheme, schemeName := range schemeNames {
+		if schemeName == name {
+			return scheme, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown signature scheme %q", name)
+}
+
+// Signer signs messages with a private key. ECDSA schemes sign the SHA-256 of the message,
+// Ed25519 signs the message itself.
+type Signer interface {
+	Scheme() SignatureScheme
+	PublicKey() []byte // Raw public key: a compressed point for ECDSA, 32 bytes for Ed25519
+	Sign(message []byte) ([]byte, error)
+}
+
+// Verifier checks signatures of one scheme
+type Verifier interface {
+	Verify(publicKey, message, signature []byte) bool
+}
+
+// keyHolder is implemented by signers holding their private key in memory
+type keyHolder interface {
+	privateKeyBytes() []byte
+	wipe()
+}
+
+// verifiers are the verifiers of the supported schemes
+var verifiers = map[SignatureScheme]Verifier{
+	SchemeP256:      p256Verifier{},
+	SchemeEd25519:   ed25519Verifier{},
+	SchemeSecp256k1: secp256k1Verifier{},
+}
+
+// VerifierFor returns the verifier of a scheme
+func VerifierFor(scheme SignatureScheme) (Verifier, error) {
+	verifier, exists := verifiers[scheme]
+	if !exists {
+		return nil, fmt.Errorf("unsupported signature scheme %s", scheme)
+	}
+	return verifier, nil
+}
+
+// EncodePublicKey encodes a public key with its scheme tag as hex
+func EncodePublicKey(scheme SignatureScheme, publicKey []byte) string {
+	return hex.EncodeToString(append([]byte{byte(scheme)}, publicKey...))
+}
+
+// DecodePublicKey splits a hex public key encoded by EncodePublicKey into its scheme and raw key
+func DecodePublicKey(encoded string) (SignatureScheme, []byte, error) {
+	data, err := hex.DecodeString(encoded)
+	if err != nil || len(data) < 2 {
+		return 0, nil, errors.New("malformed public key encoding")
+	}
+	scheme := SignatureScheme(data[0])
+	if _, exists := verifiers[scheme]; !exists {
+		return 0, nil, fmt.Errorf("unsupported signature scheme %s", scheme)
+	}
+	return scheme, data[1:], nil
+}
+
+// AddressFromPublicKey derives the address of a public key encoded by EncodePublicKey.
+// P-256 keys keep the original untagged address; other schemes prefix the hash with their tag.
+func AddressFromPublicKey(encoded string) (string, error) {
+	scheme, publicKey, err := DecodePublicKey(encoded)
+	if err != nil {
+		return "", err
+	}
+	return HexAddressFormat{}.Address(scheme, publicKey)
+}
+
+// schemeAddress derives the tagged address of a non-P-256 public key
+func schemeAddress(scheme SignatureScheme, publicKey []byte) string {
+	hash := sha256.Sum256(publicKey)
+	return hex.EncodeToString(append([]byte{byte(scheme)}, hash[:]...))
+}
+
+// AddressScheme returns the signature scheme an address belongs to
+func AddressScheme(address string) (SignatureScheme, error) {
+	switch len(address) {
+	case 2 * sha256.Size:
+		return SchemeP256, nil
+	case 2 * (sha256.Size + 1):
+		tag, err := hex.DecodeString(address[:2])
+		if err != nil {
+			return 0, fmt.Errorf("%w: malformed", ErrInvalidAddress)
+		}
+		scheme := SignatureScheme(tag[0])
+		if _, exists := verifiers[scheme]; !exists || scheme == SchemeP256 {
+			return 0, fmt.Errorf("unsupported signature scheme %s", scheme)
+		}
+		return scheme, nil
+	default:
+		return 0, fmt.Errorf("%w: malformed", ErrInvalidAddress)
+	}
+}
+
+// VerifySignature checks a hex signature of a message against a public key encoded by EncodePublicKey
+func VerifySignature(publicKey string, message []byte, signature string) bool {
+	scheme, key, err := DecodePublicKey(publicKey)
+	if err != nil {
+		return false
+	}
+	sigBytes, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return verifiers[scheme].Verify(key, message, sigBytes)
+}
+
+// NewWalletWithScheme creates a wallet with a fresh key of a signature scheme
+func NewWalletWithScheme(scheme SignatureScheme) (*Wallet, error) {
+	switch scheme {
+	case SchemeP256:
+		return NewWallet()
+	case SchemeEd25519:
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return NewWalletFromSigner(ed25519Signer{key: privateKey}), nil
+	case SchemeSecp256k1:
+		signer, err := generateSecp256k1Signer()
+		if err != nil {
+			return nil, err
+		}
+		return NewWalletFromSigner(signer), nil
+	default:
+		return nil, fmt.Errorf("unsupported signature scheme %s", scheme)
+	}
+}
+
+// NewWalletFromSigner creates a wallet around a signer, e.g. one backed by a hardware device.
+// The wallet has no ECDSA key fields unless the signer is a P-256 key held in memory.
+func NewWalletFromSigner(signer Signer) *Wallet {
+	if p256, ok := signer.(p256Signer); ok {
+		return &Wallet{PrivateKey: p256.key, PublicKey: &p256.key.PublicKey, Address: generateAddress(&p256.key.PublicKey)}
+	}
+	address, _ := AddressFromPublicKey(EncodePublicKey(signer.Scheme(), signer.PublicKey()))
+	return &Wallet{Address: address, signer: signer}
+}
+
+// walletFromPrivateKey rebuilds a wallet from the raw private key of a scheme
+func walletFromPrivateKey(scheme SignatureScheme, privateKey []byte) (*Wallet, error) {
+	switch scheme {
+	case SchemeP256:
+		d := new(big.Int).SetBytes(privateKey)
+		if len(privateKey) != 32 || d.Sign() <= 0 || d.Cmp(p256Curve.N) >= 0 {
+			return nil, errors.New("invalid P-256 private key")
+		}
+		return NewWalletFromSigner(p256Signer{key: privateKeyFromScalar(d)}), nil
+	case SchemeEd25519:
+		if len(privateKey) != ed25519.SeedSize {
+			return nil, errors.New("invalid Ed25519 private key")
+		}
+		return NewWalletFromSigner(ed25519Signer{key: ed25519.NewKeyFromSeed(privateKey)}), nil
+	case SchemeSecp256k1:
+		signer, err := newSecp256k1Signer(privateKey)
+		if err != nil {
+			return nil, err
+		}
+		return NewWalletFromSigner(signer), nil
+	default:
+		return nil, fmt.Errorf("unsupported signature scheme %s", scheme)
+	}
+}
+
+// p256Signer signs with a P-256 key (ECDSA over SHA-256, RFC 6979, low s)
+type p256Signer struct {
+	key *ecdsa.PrivateKey
+}
+
+// Scheme returns SchemeP256
+func (s p256Signer) Scheme() SignatureScheme {
+	return SchemeP256
+}
+
+// PublicKey returns the compressed public point
+func (s p256Signer) PublicKey() []byte {
+	return elliptic.MarshalCompressed(elliptic.P256(), s.key.X, s.key.Y)
+}
+
+// Sign signs the SHA-256 of a message
+func (s p256Signer) Sign(message []byte) ([]byte, error) {
+	r, sig, err := signDeterministic(s.key, sha256Digest(message))
+	if err != nil {
+		return nil, err
+	}
+	return encodeSignature(r, sig), nil
+}
+
+// privateKeyBytes returns the private scalar
+func (s p256Signer) privateKeyBytes() []byte {
+	return s.key.D.FillBytes(make([]byte, 32))
+}
+
+// wipe zeroes the private scalar
+func (s p256Signer) wipe() {
+	s.key.D.SetInt64(0)
+}
+
+// p256Verifier checks P-256 ECDSA signatures
+type p256Verifier struct{}
+
+// Verify checks a 64-byte or DER low-s signature over the SHA-256 of a message
+func (p256Verifier) Verify(publicKey, message, signature []byte) bool {
+	x, y := elliptic.UnmarshalCompressed(elliptic.P256(), publicKey)
+	if x == nil {
+		return false
+	}
+	r, s, err := parseSignature(signature)
+	if err != nil {
+		return false
+	}
+	return ecdsa.Verify(&ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, sha256Digest(message), r, s)
+}
+
+// ed25519Signer signs with an Ed25519 key
+type ed25519Signer struct {
+	key ed25519.PrivateKey
+}
+
+// Scheme returns SchemeEd25519
+func (s ed25519Signer) Scheme() SignatureScheme {
+	return SchemeEd25519
+}
+
+// PublicKey returns the 32-byte public key
+func (s ed25519Signer) PublicKey() []byte {
+	return []byte(s.key.Public().(ed25519.PublicKey))
+}
+
+// Sign signs a message
+func (s ed25519Signer) Sign(message []byte) ([]byte, error) {
+	return ed25519.Sign(s.key, message), nil
+}
+
+// privateKeyBytes returns the 32-byte seed
+func (s ed25519Signer) privateKeyBytes() []byte {
+	return append([]byte(nil), s.key.Seed()...)
+}
+
+// wipe zeroes the key
+func (s ed25519Signer) wipe() {
+	clear(s.key)
+}
+
+// ed25519Verifier checks Ed25519 signatures
+type ed25519Verifier struct{}
+
+// Verify checks a 64-byte signature of a message
+func (ed25519Verifier) Verify(publicKey, message, signature []byte) bool {
+	if len(publicKey) != ed25519.PublicKeySize || len(signature) != ed25519.SignatureSize {
+		return false
+	}
+	return ed25519.Verify(publicKey, message, signature)
+}
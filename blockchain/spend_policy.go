@@ -0,0 +1,433 @@
+package blockchain
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// SpendPolicyAddress is the recipient of transactions registering or revoking spend policies
+const SpendPolicyAddress = "policy"
+
+// Spend policy actions
+const (
+	SpendPolicyRegister = "register"
+	SpendPolicyRevoke   = "revoke"
+)
+
+// minPolicyRevokers is the minimum number of revoker signatures a policy may require,
+// so a single stolen key can't lift the protection
+const minPolicyRevokers = 2
+
+// SpendPolicy restricts spends from an address until revoked by a quorum of revokers
+type SpendPolicy struct {
+	MaxAmount         float64  `json:"maxAmount,omitempty"`         // Maximum amount plus fee of a spend (0 means unlimited)
+	AllowedRecipients []string `json:"allowedRecipients,omitempty"` // Recipients spends may go to (empty allows any)
+	Revokers          []string `json:"revokers"`                    // Hex PKIX public keys allowed to sign a revocation
+	RequiredRevokers  int      `json:"requiredRevokers"`            // Signatures needed to revoke the policy
+}
+
+// spendPolicyPayload is the transaction payload of a policy registration or revocation
+type spendPolicyPayload struct {
+	Action     string       `json:"action"`
+	Policy     *SpendPolicy `json:"policy,omitempty"`    // Registered policy
+	PublicKey  string       `json:"publicKey,omitempty"` // Owner key of a registration, hex PKIX
+	Signatures []string     `json:"signatures"`          // Owner signature, or revoker signatures (hex ASN.1)
+}
+
+// Validate checks the policy configuration
+func (sp *SpendPolicy) Validate() error {
+	if sp.MaxAmount < 0 {
+		return errors.New("spend policy maximum amount cannot be negative")
+	}
+	if sp.MaxAmount == 0 && len(sp.AllowedRecipients) == 0 {
+		return errors.New("spend policy must limit the amount or the recipients")
+	}
+	if sp.RequiredRevokers < minPolicyRevokers {
+		return fmt.Errorf("spend policy must require at least %d revoker signatures", minPolicyRevokers)
+	}
+	if sp.RequiredRevokers > len(sp.Revokers) {
+		return errors.New("spend policy requires more revoker signatures than it has revokers")
+	}
+
+	seen := make(map[string]bool)
+	for _, revoker := range sp.Revokers {
+		if _, err := parsePublicKeyHex(revoker); err != nil {
+			return fmt.Errorf("invalid revoker key: %w", err)
+		}
+		if seen[revoker] {
+			return errors.New("duplicate revoker key")
+		}
+		seen[revoker] = true
+	}
+	return nil
+}
+
+// allows checks a spend against the policy; a sponsored fee isn't part of the sender's spend
+func (sp *SpendPolicy) allows(tx *Transaction) error {
+	if spend := tx.debit(tx.From); sp.MaxAmount > 0 && spend > sp.MaxAmount {
+		return fmt.Errorf("spend of %.8f exceeds the policy maximum of %.8f", spend, sp.MaxAmount)
+	}
+	if len(sp.AllowedRecipients) == 0 {
+		return nil
+	}
+	for _, recipient := range sp.AllowedRecipients {
+		if recipient == tx.To {
+			return nil
+		}
+	}
+	return fmt.Errorf("recipient %s is not allowed by the spend policy", tx.To)
+}
+
+// NewSpendPolicyTransaction creates a transaction registering a spend policy on the owner's address
+func NewSpendPolicyTransaction(owner *Wallet, policy SpendPolicy, fee float64, nonce int64) (*Transaction, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	publicKey, err := owner.PublicKeyHex()
+	if err != nil {
+		return nil, err
+	}
+
+	policyBytes, err := json.Marshal(policy)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := signPolicyMessage(owner, spendPolicyMessage(SpendPolicyRegister, owner.Address, nonce, policyBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	payload := spendPolicyPayload{
+		Action:     SpendPolicyRegister,
+		Policy:     &policy,
+		PublicKey:  publicKey,
+		Signatures: []string{signature},
+	}
+	return newSpendPolicyTransaction(owner.Address, payload, fee, nonce)
+}
+
+// NewSpendPolicyRevocation creates a transaction revoking the spend policy of an address,
+// signed by revokers of the policy
+func NewSpendPolicyRevocation(address string, revokers []*Wallet, fee float64, nonce int64) (*Transaction, error) {
+	message := spendPolicyMessage(SpendPolicyRevoke, address, nonce, nil)
+
+	payload := spendPolicyPayload{Action: SpendPolicyRevoke}
+	for _, revoker := range revokers {
+		signature, err := signPolicyMessage(revoker, message)
+		if err != nil {
+			return nil, err
+		}
+		payload.Signatures = append(payload.Signatures, signature)
+	}
+	return newSpendPolicyTransaction(address, payload, fee, nonce)
+}
+
+// newSpendPolicyTransaction wraps a policy payload in a transaction
+func newSpendPolicyTransaction(from string, payload spendPolicyPayload, fee float64, nonce int64) (*Transaction, error) {
+	if nonce <= 0 {
+		return nil, errors.New("spend policy transactions require a nonce")
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := &Transaction{
+		From:  from,
+		To:    SpendPolicyAddress,
+		Fee:   fee,
+		Nonce: nonce,
+		Data:  data,
+	}
+	tx.Hash = tx.calculateHash()
+	return tx, nil
+}
+
+// parseSpendPolicyPayload decodes and structurally checks the payload of a policy transaction
+func parseSpendPolicyPayload(tx *Transaction) (*spendPolicyPayload, error) {
+	if tx.Amount != 0 {
+		return nil, fmt.Errorf("%w: spend policy transactions cannot transfer an amount", ErrInvalidTransaction)
+	}
+	if tx.Nonce <= 0 {
+		return nil, fmt.Errorf("%w: spend policy transactions require a nonce", ErrInvalidTransaction)
+	}
+
+	var payload spendPolicyPayload
+	if err := json.Unmarshal(tx.Data, &payload); err != nil {
+		return nil, fmt.Errorf("invalid spend policy payload: %w", err)
+	}
+
+	switch payload.Action {
+	case SpendPolicyRegister:
+		if payload.Policy == nil {
+			return nil, errors.New("invalid spend policy payload: missing policy")
+		}
+		if err := payload.Policy.Validate(); err != nil {
+			return nil, err
+		}
+		if len(payload.Signatures) != 1 {
+			return nil, errors.New("invalid spend policy payload: registration needs exactly one owner signature")
+		}
+	case SpendPolicyRevoke:
+		if payload.Policy != nil || payload.PublicKey != "" {
+			return nil, errors.New("invalid spend policy payload: revocation carries no policy")
+		}
+	default:
+		return nil, fmt.Errorf("invalid spend policy payload: unknown action %q", payload.Action)
+	}
+	return &payload, nil
+}
+
+// validateSpendPolicyTransaction checks the structure of a policy transaction
+func validateSpendPolicyTransaction(tx *Transaction) error {
+	_, err := parseSpendPolicyPayload(tx)
+	return err
+}
+
+// spendPolicyMessage returns the bytes signed by policy owners and revokers
+func spendPolicyMessage(action, address string, nonce int64, policy []byte) []byte {
+	message := "spend-policy:" + action + ":" + address + ":" + strconv.FormatInt(nonce, 10)
+	if policy != nil {
+		message += ":" + string(policy)
+	}
+	return []byte(message)
+}
+
+// signPolicyMessage signs a policy message with a wallet key
+func signPolicyMessage(w *Wallet, message []byte) (string, error) {
+	if w.PrivateKey == nil {
+		return "", errors.New("spend policies require a P-256 wallet")
+	}
+	hash := sha256.Sum256(message)
+	signature, err := ecdsa.SignASN1(rand.Reader, w.PrivateKey, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(signature), nil
+}
+
+// verifyPolicySignature checks a policy message signature against a hex PKIX public key
+func verifyPolicySignature(publicKeyHex string, message []byte, signatureHex string) bool {
+	publicKey, err := parsePublicKeyHex(publicKeyHex)
+	if err != nil {
+		return false
+	}
+	signature, err := hex.DecodeString(signatureHex)
+	if err != nil {
+		return false
+	}
+	hash := sha256.Sum256(message)
+	return ecdsa.VerifyASN1(publicKey, hash[:], signature)
+}
+
+// PublicKeyHex returns the wallet public key as hex-encoded PKIX, the form used in spend policies
+func (w *Wallet) PublicKeyHex() (string, error) {
+	if w.PublicKey == nil {
+		return "", errors.New("spend policies require a P-256 wallet")
+	}
+	keyBytes, err := x509.MarshalPKIXPublicKey(w.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(keyBytes), nil
+}
+
+// parsePublicKeyHex decodes a hex-encoded PKIX ECDSA public key
+func parsePublicKeyHex(publicKeyHex string) (*ecdsa.PublicKey, error) {
+	keyBytes, err := hex.DecodeString(publicKeyHex)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := x509.ParsePKIXPublicKey(keyBytes)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not an ECDSA key")
+	}
+	return publicKey, nil
+}
+
+// spendPolicyIndex tracks the active spend policy of each address.
+// Like the transaction index it follows the chain incrementally and is rebuilt after a reorg.
+type spendPolicyIndex struct {
+	policies map[string]*SpendPolicy
+	height   int64 // Height of the last indexed block (-1 if empty)
+	tipHash  string
+}
+
+// newSpendPolicyIndex creates an empty spend policy index
+func newSpendPolicyIndex() *spendPolicyIndex {
+	return &spendPolicyIndex{
+		policies: make(map[string]*SpendPolicy),
+		height:   -1,
+	}
+}
+
+// sync brings the index up to date with the chain
+func (si *spendPolicyIndex) sync(chain []*Block) {
+	length := int64(len(chain))
+	if si.height >= 0 && (si.height >= length || chain[si.height].Hash != si.tipHash) {
+		si.policies = make(map[string]*SpendPolicy)
+		si.height = -1
+		si.tipHash = ""
+	}
+
+	for height := si.height + 1; height < length; height++ {
+		si.addBlock(chain[height])
+	}
+}
+
+// addBlock applies the policy transactions of the next block. Blocks are validated
+// before they are indexed, so transactions that don't apply are skipped.
+func (si *spendPolicyIndex) addBlock(block *Block) {
+	for i := range block.Transactions {
+		si.apply(&block.Transactions[i])
+	}
+	si.height = block.Index
+	si.tipHash = block.Hash
+}
+
+// check validates a transaction against the active policies without applying it
+func (si *spendPolicyIndex) check(tx *Transaction) error {
+	if tx.IsCoinbase() {
+		return nil
+	}
+
+	active, protected := si.policies[tx.From]
+	if tx.To != SpendPolicyAddress {
+		if protected {
+			return active.allows(tx)
+		}
+		return nil
+	}
+
+	payload, err := parseSpendPolicyPayload(tx)
+	if err != nil {
+		return err
+	}
+
+	switch payload.Action {
+	case SpendPolicyRegister:
+		if protected {
+			return errors.New("address already has a spend policy, it must be revoked first")
+		}
+		publicKey, err := parsePublicKeyHex(payload.PublicKey)
+		if err != nil {
+			return fmt.Errorf("invalid spend policy owner key: %w", err)
+		}
+		if generateAddress(publicKey) != tx.From {
+			return errors.New("spend policy owner key does not match the sender")
+		}
+		policyBytes, err := json.Marshal(payload.Policy)
+		if err != nil {
+			return err
+		}
+		message := spendPolicyMessage(SpendPolicyRegister, tx.From, tx.Nonce, policyBytes)
+		if !verifyPolicySignature(payload.PublicKey, message, payload.Signatures[0]) {
+			return errors.New("invalid spend policy owner signature")
+		}
+
+	case SpendPolicyRevoke:
+		if !protected {
+			return errors.New("address has no spend policy to revoke")
+		}
+		message := spendPolicyMessage(SpendPolicyRevoke, tx.From, tx.Nonce, nil)
+		signed := make(map[string]bool)
+		for _, signature := range payload.Signatures {
+			for _, revoker := range active.Revokers {
+				if !signed[revoker] && verifyPolicySignature(revoker, message, signature) {
+					signed[revoker] = true
+					break
+				}
+			}
+		}
+		if len(signed) < active.RequiredRevokers {
+			return fmt.Errorf("revocation has %d valid revoker signatures, %d required", len(signed), active.RequiredRevokers)
+		}
+	}
+	return nil
+}
+
+// apply validates a transaction and updates the active policies
+func (si *spendPolicyIndex) apply(tx *Transaction) error {
+	if err := si.check(tx); err != nil {
+		return err
+	}
+	if tx.To != SpendPolicyAddress {
+		return nil
+	}
+
+	payload, _ := parseSpendPolicyPayload(tx)
+	if payload.Action == SpendPolicyRegister {
+		si.policies[tx.From] = payload.Policy
+	} else {
+		delete(si.policies, tx.From)
+	}
+	return nil
+}
+
+// checkBlock validates the transactions of a block in order against the active policies,
+// without modifying the index
+func (si *spendPolicyIndex) checkBlock(block *Block) error {
+	scratch := &spendPolicyIndex{policies: make(map[string]*SpendPolicy, len(si.policies))}
+	for address, policy := range si.policies {
+		scratch.policies[address] = policy
+	}
+
+	for i := range block.Transactions {
+		tx := &block.Transactions[i]
+		if err := scratch.apply(tx); err != nil {
+			return fmt.Errorf("transaction %s violates spend policy: %w", tx.Hash, err)
+		}
+	}
+	return nil
+}
+
+// spendPolicies returns the spend policy index, caught up with the chain
+func (bc *Blockchain) spendPolicies() *spendPolicyIndex {
+	if bc.policyIndex == nil {
+		bc.policyIndex = newSpendPolicyIndex()
+	}
+	bc.policyIndex.sync(bc.Chain)
+	return bc.policyIndex
+}
+
+// GetSpendPolicy returns the active spend policy of an address
+func (bc *Blockchain) GetSpendPolicy(address string) (*SpendPolicy, bool) {
+	policy, exists := bc.spendPolicies().policies[address]
+	return policy, exists
+}
+
+// CheckSpendPolicy checks a transaction against the confirmed spend policies
+func (bc *Blockchain) CheckSpendPolicy(tx *Transaction) error {
+	return bc.spendPolicies().check(tx)
+}
+
+// spendPolicies returns the spend policy index, caught up with the chain
+func (pbc *PersistentBlockchain) spendPolicies() *spendPolicyIndex {
+	if pbc.policyIndex == nil {
+		pbc.policyIndex = newSpendPolicyIndex()
+	}
+	pbc.policyIndex.sync(pbc.Chain)
+	return pbc.policyIndex
+}
+
+// GetSpendPolicy returns the active spend policy of an address
+func (pbc *PersistentBlockchain) GetSpendPolicy(address string) (*SpendPolicy, bool) {
+	policy, exists := pbc.spendPolicies().policies[address]
+	return policy, exists
+}
+
+// CheckSpendPolicy checks a transaction against the confirmed spend policies
+func (pbc *PersistentBlockchain) CheckSpendPolicy(tx *Transaction) error {
+	return pbc.spendPolicies().check(tx)
+}
@@ -0,0 +1,57 @@
+package blockchain
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// postgresTypes maps the SQLite column types of the schema to their PostgreSQL equivalents.
+// SQLite integers and reals are 64-bit, so they become BIGINT and DOUBLE PRECISION.
+var postgresTypes = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`\bINTEGER PRIMARY KEY AUTOINCREMENT\b`), "BIGSERIAL PRIMARY KEY"},
+	{regexp.MustCompile(`\bINTEGER\b`), "BIGINT"},
+	{regexp.MustCompile(`\bREAL\b`), "DOUBLE PRECISION"},
+	{regexp.MustCompile(`\bDATETIME\b`), "TIMESTAMP"},
+}
+
+// schema adapts a CREATE TABLE statement to the database's dialect
+func (d *Database) schema(ddl string) string {
+	if !d.pg {
+		return ddl
+	}
+	for _, t := range postgresTypes {
+		ddl = t.pattern.ReplaceAllString(ddl, t.replacement)
+	}
+	return ddl
+}
+
+// rebind rewrites the ? placeholders of a query to the database's dialect,
+// $1, $2, ... on PostgreSQL. Question marks in string literals are kept.
+func (d *Database) rebind(query string) string {
+	if !d.pg || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var rebound strings.Builder
+	rebound.Grow(len(query) + 8)
+	n := 0
+	quoted := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'':
+			quoted = !quoted
+		case c == '?' && !quoted:
+			n++
+			rebound.WriteByte('$')
+			rebound.WriteString(strconv.Itoa(n))
+			continue
+		}
+		rebound.WriteByte(c)
+	}
+	return rebound.String()
+}
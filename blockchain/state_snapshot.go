@@ -0,0 +1,288 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+)
+
+// StateSnapshot is the state of the chain at a block: every address balance and sender nonce
+// with the header of the block, signed by the node that took it. A new node trusting the signer
+// can start from it instead of replaying the chain from genesis (see LoadSnapshot).
+type StateSnapshot struct {
+	Header    BlockHeader        `json:"header"`   // Latest block covered
+	Balances  map[string]float64 `json:"balances"` // Balance per address
+	Counts    map[string]int64   `json:"counts"`   // Balance updates per address
+	Nonces    map[string]int64   `json:"nonces"`   // Highest confirmed nonce per sender
+	CreatedAt int64              `json:"createdAt"`
+	PublicKey string             `json:"publicKey"` // Signer's encoded public key
+	Signature string             `json:"signature"`
+}
+
+// SnapshotPolicy configures the snapshots a node takes as its chain grows
+type SnapshotPolicy struct {
+	Interval int64   // Blocks between snapshots, taken at heights that are multiples of it
+	Signer   *Wallet // Signs the snapshots
+	Keep     int     // Snapshots kept in storage, older ones are deleted (default 2)
+}
+
+// Validate checks that the policy can take snapshots
+func (p *SnapshotPolicy) Validate() error {
+	if p.Interval <= 0 {
+		return errors.New("snapshot interval must be positive")
+	}
+	if p.Signer == nil || (p.Signer.signer == nil && p.Signer.PrivateKey == nil) {
+		return errors.New("snapshot policy needs a signing wallet")
+	}
+	if p.Keep < 0 {
+		return errors.New("snapshots kept cannot be negative")
+	}
+	return nil
+}
+
+// signingBytes returns the bytes the signer signs: the snapshot without its signature, with a
+// prefix so the signature can't be passed off as one of a transaction or a message
+func (s *StateSnapshot) signingBytes() ([]byte, error) {
+	unsigned := *s
+	unsigned.PublicKey = ""
+	unsigned.Signature = ""
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize snapshot: %w", err)
+	}
+	digest := sha256.Sum256(data)
+	return append([]byte("snapshot:"), digest[:]...), nil
+}
+
+// sign signs the snapshot with a wallet
+func (s *StateSnapshot) sign(w *Wallet) error {
+	s.PublicKey = w.EncodedPublicKey()
+	message, err := s.signingBytes()
+	if err != nil {
+		return err
+	}
+	signature, err := w.keySigner().Sign(message)
+	if err != nil {
+		return err
+	}
+	s.Signature = hex.EncodeToString(signature)
+	return nil
+}
+
+// Verify checks that the snapshot is signed by one of the trusted public keys
+func (s *StateSnapshot) Verify(trustedKeys []string) error {
+	if s.PublicKey == "" || s.Signature == "" {
+		return errors.New("snapshot is not signed")
+	}
+	if !slices.Contains(trustedKeys, s.PublicKey) {
+		return errors.New("snapshot is not signed by a trusted key")
+	}
+	message, err := s.signingBytes()
+	if err != nil {
+		return err
+	}
+	if !VerifySignature(s.PublicKey, message, s.Signature) {
+		return errors.New("invalid snapshot signature")
+	}
+	return nil
+}
+
+// SaveSnapshot stores a snapshot, keeping only the keep latest ones
+func (d *Database) SaveSnapshot(snapshot *StateSnapshot, keep int) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to serialize snapshot: %w", err)
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(d.rebind(`
+		INSERT INTO state_snapshots (height, hash, snapshot_data, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(height) DO UPDATE SET
+			hash = excluded.hash,
+			snapshot_data = excluded.snapshot_data,
+			created_at = excluded.created_at`),
+		snapshot.Header.Index, snapshot.Header.Hash, string(data), snapshot.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	_, err = tx.Exec(d.rebind(`
+		DELETE FROM state_snapshots WHERE height NOT IN (
+			SELECT height FROM state_snapshots ORDER BY height DESC LIMIT ?)`), keep)
+	if err != nil {
+		return fmt.Errorf("failed to delete old snapshots: %w", err)
+	}
+	return tx.Commit()
+}
+
+// LatestSnapshot returns the most recent snapshot, or nil if none was taken
+func (d *Database) LatestSnapshot() (*StateSnapshot, error) {
+	var data string
+	err := d.db.QueryRow("SELECT snapshot_data FROM state_snapshots ORDER BY height DESC LIMIT 1").Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &StateSnapshot{}
+	if err := json.Unmarshal([]byte(data), snapshot); err != nil {
+		return nil, fmt.Errorf("failed to deserialize snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+// SetSnapshotPolicy takes a signed snapshot every policy.Interval blocks as blocks are mined or
+// connected (nil disables)
+func (pbc *PersistentBlockchain) SetSnapshotPolicy(policy *SnapshotPolicy) error {
+	if policy != nil {
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+	}
+	pbc.snapshotPolicy = policy
+	return nil
+}
+
+// CreateSnapshot takes a snapshot of the chain tip, signs it with the snapshot policy's signer
+// and stores it
+func (pbc *PersistentBlockchain) CreateSnapshot() (*StateSnapshot, error) {
+	policy := pbc.snapshotPolicy
+	if policy == nil {
+		return nil, errors.New("no snapshot policy is set")
+	}
+
+	// The tip's state is what pruning every block but genesis would leave behind
+	state := pbc.prunedState().clone()
+	for _, block := range pbc.Chain[1:] {
+		if !block.Pruned {
+			state.addBlock(block)
+		}
+	}
+	snapshot := &StateSnapshot{
+		Header:    pbc.GetLatestBlock().Header(),
+		Balances:  state.Balances,
+		Counts:    state.Counts,
+		Nonces:    state.Nonces,
+		CreatedAt: time.Now().Unix(),
+	}
+	if err := snapshot.sign(policy.Signer); err != nil {
+		return nil, fmt.Errorf("failed to sign snapshot: %w", err)
+	}
+
+	keep := policy.Keep
+	if keep == 0 {
+		keep = 2
+	}
+	if err := pbc.Database.SaveSnapshot(snapshot, keep); err != nil {
+		return nil, err
+	}
+	log.Printf("Took state snapshot at height %d (%d addresses)", snapshot.Header.Index, len(snapshot.Balances))
+	return snapshot, nil
+}
+
+// LatestSnapshot returns the most recent stored snapshot, or nil if none was taken
+func (pbc *PersistentBlockchain) LatestSnapshot() (*StateSnapshot, error) {
+	return pbc.Database.LatestSnapshot()
+}
+
+// snapshot takes the snapshot due at the tip's height, if any
+func (pbc *PersistentBlockchain) snapshot() {
+	policy := pbc.snapshotPolicy
+	if policy == nil || pbc.GetLatestBlock().Index%policy.Interval != 0 {
+		return
+	}
+	if _, err := pbc.CreateSnapshot(); err != nil {
+		log.Printf("Warning: failed to take state snapshot: %v", err)
+	}
+}
+
+// LoadSnapshot bootstraps a fresh chain from a snapshot signed by a trusted key. headers are the
+// headers after genesis up to the snapshot's block; their linkage and proof of work are checked,
+// but the blocks are stored as pruned headers and only blocks after the snapshot are validated
+// in full. Key-value entries, spend policies and chain halts set before the snapshot aren't part
+// of it, and transactions before it are protected from replay by their nonces only. A load that
+// fails partway leaves a database that must be deleted before retrying.
+func (pbc *PersistentBlockchain) LoadSnapshot(snapshot *StateSnapshot, headers []BlockHeader, trustedKeys []string) error {
+	if len(pbc.Chain) != 1 {
+		return errors.New("snapshots can only be loaded into a chain holding only the genesis block")
+	}
+	if err := snapshot.Verify(trustedKeys); err != nil {
+		return err
+	}
+	if int64(len(headers)) != snapshot.Header.Index || len(headers) == 0 || headers[len(headers)-1] != snapshot.Header {
+		return errors.New("headers do not lead to the snapshot's block")
+	}
+
+	parent := pbc.Chain[0].Header()
+	for i := range headers {
+		if err := headers[i].validateAgainstParent(&parent, pbc.Difficulty); err != nil {
+			return fmt.Errorf("invalid header %d: %w", headers[i].Index, err)
+		}
+		parent = headers[i]
+	}
+
+	state := newPruneState()
+	state.Height = snapshot.Header.Index + 1
+	state.Balances = snapshot.Balances
+	state.Counts = snapshot.Counts
+	state.Nonces = snapshot.Nonces
+
+	chain := []*Block{pbc.Chain[0]}
+	for _, header := range headers {
+		block := blockFromHeader(header)
+		if err := pbc.Database.SaveBlock(block); err != nil {
+			return fmt.Errorf("failed to persist block %d: %w", block.Index, err)
+		}
+		chain = append(chain, block)
+	}
+	if err := pbc.Database.PruneBlocks(nil, state); err != nil {
+		return fmt.Errorf("failed to save snapshot state: %w", err)
+	}
+	if _, err := pbc.Database.RebuildAddressIndex(); err != nil {
+		return fmt.Errorf("failed to load snapshot balances: %w", err)
+	}
+
+	pbc.Chain = chain
+	pbc.pruneState = state
+	pbc.txIndex = nil
+	pbc.policyIndex = nil
+	pbc.haltIndex = nil
+	pbc.history = nil
+	pbc.state = nil
+
+	log.Printf("Loaded state snapshot at height %d (%d addresses)", snapshot.Header.Index, len(snapshot.Balances))
+	return nil
+}
+
+// blockFromHeader returns a pruned block holding only a header
+func blockFromHeader(header BlockHeader) *Block {
+	return &Block{
+		Version:     header.Version,
+		Index:       header.Index,
+		Timestamp:   header.Timestamp,
+		PrevHash:    header.PrevHash,
+		Hash:        header.Hash,
+		Nonce:       header.Nonce,
+		MerkleRoot:  header.MerkleRoot,
+		KVRoot:      header.KVRoot,
+		MMRRoot:     header.MMRRoot,
+		ExtRoot:     header.ExtRoot,
+		StateRoot:   header.StateRoot,
+		ReceiptRoot: header.ReceiptRoot,
+		Bloom:       header.Bloom,
+		Pruned:      true,
+	}
+}
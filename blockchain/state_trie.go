@@ -0,0 +1,387 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"blockchain/blockchain/verify"
+)
+
+// ErrInvalidStateProof is returned for a state proof that does not match the state root it names
+var ErrInvalidStateProof = errors.New("invalid state proof")
+
+// AccountState is the state of an address committed by the state trie
+type AccountState struct {
+	Balance float64 `json:"balance"`
+	Nonce   int64   `json:"nonce"` // Highest nonce the address has sent
+}
+
+// encode returns the encoding hashed into the account's trie leaf
+func (a AccountState) encode() []byte {
+	return verify.EncodeAccount(&verify.Account{Balance: a.Balance, Nonce: a.Nonce})
+}
+
+// stateNode is a node of the state trie: a leaf holding an account, or a branch splitting
+// the keys below it on a bit
+type stateNode struct {
+	bit      int           // Key bit a branch splits on, clear on the left
+	children [2]*stateNode // Children of a branch, nil for a leaf
+	address  string        // Address of a leaf
+	key      string        // Key of a leaf, verify.StateKey of the address
+	account  []byte        // Account encoding of a leaf
+	hash     string        // Cached hash, "" until computed and after a change below the node
+}
+
+// isLeaf reports whether the node is a leaf
+func (n *stateNode) isLeaf() bool {
+	return n.children[0] == nil
+}
+
+// stateTrie is a binary Merkle-Patricia trie mapping the key of every address the chain has
+// touched (see verify.StateKey) to its balance and nonce. A branch only sits where the keys
+// below it first differ, so the trie's shape and root depend on the accounts alone, and
+// updating an account rehashes the one path to it. Like the history range it follows the
+// chain incrementally and is rebuilt after a reorg.
+type stateTrie struct {
+	root     *stateNode
+	accounts map[string]AccountState // by address
+	size     int64                   // Number of blocks applied
+	tipHash  string
+}
+
+// newStateTrie creates a trie holding a chain's pruned state (nil for none)
+func newStateTrie(base *PruneState) *stateTrie {
+	t := &stateTrie{accounts: make(map[string]AccountState)}
+	if base == nil {
+		return t
+	}
+	for address, balance := range base.Balances {
+		t.accounts[address] = AccountState{Balance: balance, Nonce: base.Nonces[address]}
+	}
+	for address, nonce := range base.Nonces {
+		if _, ok := base.Balances[address]; !ok {
+			t.accounts[address] = AccountState{Nonce: nonce}
+		}
+	}
+	for address, account := range t.accounts {
+		t.set(address, account.encode())
+	}
+	return t
+}
+
+// sync brings the trie up to date with the chain, rebuilding it from the pruned state if the
+// chain no longer extends the blocks applied
+func (t *stateTrie) sync(chain []*Block, base *PruneState) {
+	if t.size > 0 && (t.size > int64(len(chain)) || chain[t.size-1].Hash != t.tipHash) {
+		*t = *newStateTrie(base)
+	}
+	for _, block := range chain[t.size:] {
+		t.addBlock(block)
+	}
+}
+
+// addBlock applies the transactions of the next block
+func (t *stateTrie) addBlock(block *Block) {
+	t.apply(block)
+	t.size++
+	t.tipHash = block.Hash
+}
+
+// apply applies the transactions of a block to the accounts, returning the accounts they
+// replace so the block can be undone. Fees are debited from the sender or the sponsor.
+func (t *stateTrie) apply(block *Block) map[string]*AccountState {
+	previous := make(map[string]*AccountState)
+	update := func(address string, change func(*AccountState)) {
+		account, ok := t.accounts[address]
+		if _, seen := previous[address]; !seen {
+			previous[address] = nil
+			if ok {
+				saved := account
+				previous[address] = &saved
+			}
+		}
+		change(&account)
+		t.accounts[address] = account
+		t.set(address, account.encode())
+	}
+
+	for _, tx := range block.Transactions {
+		update(tx.From, func(a *AccountState) {
+			a.Balance -= tx.debit(tx.From)
+			if !tx.IsCoinbase() && tx.Nonce > a.Nonce {
+				a.Nonce = tx.Nonce
+			}
+		})
+		if tx.Sponsor != "" && tx.Sponsor != tx.From {
+			update(tx.Sponsor, func(a *AccountState) { a.Balance -= tx.debit(tx.Sponsor) })
+		}
+		update(tx.To, func(a *AccountState) { a.Balance += tx.Amount })
+	}
+	return previous
+}
+
+// undo restores the accounts a block's application replaced
+func (t *stateTrie) undo(previous map[string]*AccountState) {
+	for address, account := range previous {
+		if account == nil {
+			delete(t.accounts, address)
+			t.remove(address)
+			continue
+		}
+		t.accounts[address] = *account
+		t.set(address, account.encode())
+	}
+}
+
+// rootAfter returns the root the trie would have with a block applied, leaving it unchanged
+func (t *stateTrie) rootAfter(block *Block) string {
+	previous := t.apply(block)
+	defer t.undo(previous)
+	return t.rootHash()
+}
+
+// set maps the key of an address to an account encoding, inserting a leaf (and the branch
+// above it) if the address is new
+func (t *stateTrie) set(address string, account []byte) {
+	key := verify.StateKey(address)
+	leaf := &stateNode{address: address, key: key, account: account}
+	if t.root == nil {
+		t.root = leaf
+		return
+	}
+
+	// The leaf the key's bits lead to shares the longest prefix with it of all the keys
+	closest := t.root
+	for !closest.isLeaf() {
+		closest = closest.children[verify.StateKeyBit(key, closest.bit)]
+	}
+	split := firstDifferingBit(key, closest.key)
+
+	// Walk down again to where the keys split, clearing the hashes on the way
+	link := &t.root
+	for node := *link; !node.isLeaf() && (split < 0 || node.bit < split); node = *link {
+		node.hash = ""
+		link = &node.children[verify.StateKeyBit(key, node.bit)]
+	}
+	if split < 0 {
+		*link = leaf
+		return
+	}
+	branch := &stateNode{bit: split}
+	side := verify.StateKeyBit(key, split)
+	branch.children[side] = leaf
+	branch.children[1-side] = *link
+	*link = branch
+}
+
+// remove drops the leaf of an address, replacing the branch above it by its other child
+func (t *stateTrie) remove(address string) {
+	if t.root == nil {
+		return
+	}
+	key := verify.StateKey(address)
+	var parent **stateNode
+	link := &t.root
+	for node := *link; !node.isLeaf(); node = *link {
+		node.hash = ""
+		parent = link
+		link = &node.children[verify.StateKeyBit(key, node.bit)]
+	}
+	if (*link).key != key {
+		return
+	}
+	if parent == nil {
+		t.root = nil
+		return
+	}
+	branch := *parent
+	*parent = branch.children[1-verify.StateKeyBit(key, branch.bit)]
+}
+
+// firstDifferingBit returns the first bit at which two keys differ, -1 if they are equal
+func firstDifferingBit(a, b string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		x, y := hexNibble(a[i]), hexNibble(b[i])
+		return i*4 + bits.LeadingZeros8(x^y) - 4
+	}
+	return -1
+}
+
+// hexNibble returns the value of a lowercase hex digit
+func hexNibble(c byte) byte {
+	if c >= 'a' {
+		return c - 'a' + 10
+	}
+	return c - '0'
+}
+
+// rootHash returns the root of the trie ("" if empty)
+func (t *stateTrie) rootHash() string {
+	if t.root == nil {
+		return ""
+	}
+	return t.root.nodeHash()
+}
+
+// nodeHash returns the hash of a node, computing the hashes changed below it
+func (n *stateNode) nodeHash() string {
+	if n.hash == "" {
+		if n.isLeaf() {
+			n.hash = verify.StateLeafHash(n.key, n.account)
+		} else {
+			n.hash = verify.StateBranchHash(n.bit, n.children[0].nodeHash(), n.children[1].nodeHash())
+		}
+	}
+	return n.hash
+}
+
+// StateProof proves the state of an address against the state root of a block: the path of
+// the address's key from the root to its leaf, or, for an address with no state, to the leaf
+// its key's bits lead to, which shows no leaf of its own exists
+type StateProof struct {
+	Address  string        `json:"address"`
+	Height   int64         `json:"height"`            // Block whose state root the proof is against
+	Account  *AccountState `json:"account,omitempty"` // State of the address, nil if it has none
+	Leaf     *StateLeaf    `json:"leaf,omitempty"`    // Leaf the path of an address with no state ends at, nil in an empty trie
+	Bits     []int         `json:"bits,omitempty"`    // Bits the branches on the path split on, from the root down
+	Siblings []string      `json:"siblings,omitempty"`
+}
+
+// StateLeaf is a leaf of the state trie
+type StateLeaf struct {
+	Key     string       `json:"key"`
+	Account AccountState `json:"account"`
+}
+
+// proof builds the proof of an address against the trie's root
+func (t *stateTrie) proof(address string, height int64) *StateProof {
+	proof := &StateProof{Address: address, Height: height}
+	if t.root == nil {
+		return proof
+	}
+
+	key := verify.StateKey(address)
+	node := t.root
+	for !node.isLeaf() {
+		side := verify.StateKeyBit(key, node.bit)
+		proof.Bits = append(proof.Bits, node.bit)
+		proof.Siblings = append(proof.Siblings, node.children[1-side].nodeHash())
+		node = node.children[side]
+	}
+	if node.key == key {
+		account := t.accounts[address]
+		proof.Account = &account
+		return proof
+	}
+	proof.Leaf = &StateLeaf{Key: node.key, Account: t.accounts[node.address]}
+	return proof
+}
+
+// VerifyStateProof checks a proof against the header of the block it names, which the caller
+// trusts, e.g. one a LightClient synced. It returns the state of the address, nil if it has none.
+func VerifyStateProof(proof *StateProof, header BlockHeader) (*AccountState, error) {
+	if proof == nil {
+		return nil, errors.New("missing proof")
+	}
+	if len(proof.Bits) > verify.StateKeyBits || len(proof.Siblings) > verify.StateKeyBits {
+		return nil, &LimitError{Field: "bits", Size: max(len(proof.Bits), len(proof.Siblings)), Limit: verify.StateKeyBits}
+	}
+	if proof.Height != header.Index {
+		return nil, fmt.Errorf("proof is against block %d, not %d", proof.Height, header.Index)
+	}
+	if header.StateRoot == "" {
+		return nil, fmt.Errorf("block %d does not commit a state root", header.Index)
+	}
+
+	key := verify.StateKey(proof.Address)
+	leafKey, leafAccount := key, []byte(nil)
+	switch {
+	case proof.Account != nil && proof.Leaf == nil:
+		leafAccount = proof.Account.encode()
+	case proof.Account == nil && proof.Leaf != nil && proof.Leaf.Key != key:
+		leafKey, leafAccount = proof.Leaf.Key, proof.Leaf.Account.encode()
+	case proof.Account == nil && proof.Leaf == nil:
+		leafKey = ""
+	default:
+		return nil, ErrInvalidStateProof
+	}
+	if !verify.VerifyStatePath(key, leafKey, leafAccount, proof.Bits, proof.Siblings, header.StateRoot) {
+		return nil, ErrInvalidStateProof
+	}
+	return proof.Account, nil
+}
+
+// stateCommitment returns the state root a block must commit: the root of the state after its
+// transactions from the activation height on, "" before. The trie must hold exactly the blocks
+// below the block.
+func stateCommitment(params *ChainParams, state *stateTrie, block *Block) string {
+	activation := params.stateActivation()
+	if activation == 0 || block.Index < activation {
+		return ""
+	}
+	return state.rootAfter(block)
+}
+
+// checkStateRoot checks a block commits the state root expected after it
+func checkStateRoot(block *Block, params *ChainParams, state *stateTrie) error {
+	expected := stateCommitment(params, state, block)
+	if block.StateRoot == expected {
+		return nil
+	}
+	if expected == "" {
+		return errors.New("block commits a state root before activation")
+	}
+	return errors.New("block commits the wrong state root")
+}
+
+// currentState returns the state trie, caught up with the chain
+func (bc *Blockchain) currentState() *stateTrie {
+	if bc.state == nil {
+		bc.state = newStateTrie(nil)
+	}
+	bc.state.sync(bc.Chain, nil)
+	return bc.state
+}
+
+// StateRoot returns the root of the state trie after the latest block
+func (bc *Blockchain) StateRoot() string {
+	return bc.currentState().rootHash()
+}
+
+// GetStateProof proves the state of an address after the latest block, against the state
+// root the block commits
+func (bc *Blockchain) GetStateProof(address string) (*StateProof, error) {
+	latest := bc.GetLatestBlock()
+	if latest.StateRoot == "" {
+		return nil, fmt.Errorf("block %d does not commit a state root", latest.Index)
+	}
+	return bc.currentState().proof(address, latest.Index), nil
+}
+
+// currentState returns the state trie, caught up with the chain
+func (pbc *PersistentBlockchain) currentState() *stateTrie {
+	if pbc.state == nil {
+		pbc.state = newStateTrie(pbc.prunedState())
+	}
+	pbc.state.sync(pbc.Chain, pbc.prunedState())
+	return pbc.state
+}
+
+// StateRoot returns the root of the state trie after the latest block
+func (pbc *PersistentBlockchain) StateRoot() string {
+	return pbc.currentState().rootHash()
+}
+
+// GetStateProof proves the state of an address after the latest block, against the state
+// root the block commits
+func (pbc *PersistentBlockchain) GetStateProof(address string) (*StateProof, error) {
+	latest := pbc.GetLatestBlock()
+	if latest.StateRoot == "" {
+		return nil, fmt.Errorf("block %d does not commit a state root", latest.Index)
+	}
+	return pbc.currentState().proof(address, latest.Index), nil
+}
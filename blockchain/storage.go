@@ -0,0 +1,48 @@
+package blockchain
+
+import (
+	"errors"
+)
+
+// ErrBlockNotFound is returned for blocks a chain or its storage doesn't hold
+var ErrBlockNotFound = errors.New("block not found")
+
+// Storage persists the blocks of a PersistentBlockchain and the state derived from them.
+// *Database implements it on SQL; LevelDBStorage on an embedded key-value store.
+type Storage interface {
+	SaveBlock(block *Block) error
+	GetBlock(hash string) (*Block, error)
+	GetBlockByIndex(index int64) (*Block, error)
+	GetLatestBlock() (*Block, error)
+	LoadBlockchain() ([]*Block, error)
+	IterateBlocks(fn func(*Block) error) error // Streams the blocks in height order
+	GetAddressBalance(address string) (float64, error)
+	GetBlockchainStats() (map[string]interface{}, error)
+	NeedsReindex() (bool, error)
+	Reindex(config ReindexConfig) (*ReindexReport, error)
+	RebuildAddressIndex() (*AddressIndexReport, error)
+	PruneBlocks(heights []int64, state *PruneState) error
+	LoadPruneState() (*PruneState, error) // nil if the database was never pruned
+	SaveLegalHold(hold LegalHold) error
+	DeleteLegalHold(kind, id string) error
+	LoadLegalHolds() ([]LegalHold, error)
+	SaveSnapshot(snapshot *StateSnapshot, keep int) error
+	LatestSnapshot() (*StateSnapshot, error) // nil if no snapshot was taken
+	SaveMempool(entries []MempoolEntry) error
+	LoadMempool() ([]MempoolEntry, error)
+	Backup(path string) error
+	Close() error
+}
+
+// OpenStorage opens the storage backend named by the config's driver: "leveldb" for
+// the key-value backend, "memory" for it held in memory (Path is ignored), or a SQL driver
+// ("sqlite3", "postgres")
+func OpenStorage(config DatabaseConfig) (Storage, error) {
+	switch config.Driver {
+	case "leveldb":
+		return NewLevelDBStorage(config.Path)
+	case "memory":
+		return NewMemoryStorage()
+	}
+	return NewDatabase(config)
+}
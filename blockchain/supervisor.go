@@ -0,0 +1,492 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrUnknownService is returned for a service name the supervisor doesn't manage
+var ErrUnknownService = errors.New("unknown service")
+
+// Service is a node subsystem run by a Supervisor, such as the p2p node, the miner or an RPC server.
+// Start must be callable again after Stop, so services wrapping single-use components such as
+// Node or Maintenance build a fresh instance on every Start.
+type Service interface {
+	Name() string
+	Start() error
+	Stop() error
+	Health() error // nil while the service works
+}
+
+// ServiceFuncs adapts plain functions to a Service
+type ServiceFuncs struct {
+	ServiceName string
+	OnStart     func() error
+	OnStop      func() error
+	OnHealth    func() error // Optional, nil reports healthy
+}
+
+// Name implements Service
+func (sf *ServiceFuncs) Name() string { return sf.ServiceName }
+
+// Start implements Service
+func (sf *ServiceFuncs) Start() error { return sf.OnStart() }
+
+// Stop implements Service
+func (sf *ServiceFuncs) Stop() error {
+	if sf.OnStop == nil {
+		return nil
+	}
+	return sf.OnStop()
+}
+
+// Health implements Service
+func (sf *ServiceFuncs) Health() error {
+	if sf.OnHealth == nil {
+		return nil
+	}
+	return sf.OnHealth()
+}
+
+// ServiceState is the lifecycle state of a supervised service
+type ServiceState string
+
+const (
+	ServiceStopped ServiceState = "stopped" // Not running, by request or before the supervisor started
+	ServiceRunning ServiceState = "running" // Started and passing health checks
+	ServiceFailed  ServiceState = "failed"  // Crashed or unhealthy, waiting for a restart
+)
+
+// ServiceStatus reports the state of a supervised service
+type ServiceStatus struct {
+	Name      string       `json:"name"`
+	State     ServiceState `json:"state"`
+	Since     time.Time    `json:"since"`    // Last state change
+	Restarts  int          `json:"restarts"` // Automatic restarts since the service was added
+	LastCheck time.Time    `json:"lastCheck,omitempty"`
+	LastError string       `json:"lastError,omitempty"`
+	GaveUp    bool         `json:"gaveUp,omitempty"` // Restart limit reached, only an operator restart brings it back
+}
+
+// SupervisorConfig configures a supervisor
+type SupervisorConfig struct {
+	HealthInterval  time.Duration // How often running services are health-checked (default 5s)
+	RestartDelay    time.Duration // Delay before the first restart of a failed service (default 1s)
+	MaxRestartDelay time.Duration // Cap of the delay, which doubles on consecutive failures (default 1m)
+	MaxRestarts     int           // Consecutive failed restarts before giving up (default 10, negative for no limit)
+}
+
+// supervisedService is a service with its supervision state
+type supervisedService struct {
+	service  Service
+	status   ServiceStatus
+	failures int           // Consecutive failures since the service was last healthy
+	retryAt  time.Time     // When a failed service is restarted
+	delay    time.Duration // Current restart delay
+}
+
+// Supervisor runs node subsystems as independent services: each can be stopped, started and
+// restarted on its own, and a service that crashes or fails its health check is restarted with
+// exponential backoff, so one failing subsystem doesn't take the whole node down.
+// Panics raised by Start, Stop and Health are recovered and treated as failures; components
+// that fail in their own goroutines report it with Fail.
+type Supervisor struct {
+	config   SupervisorConfig
+	services []*supervisedService // In start order
+	byName   map[string]*supervisedService
+	ops      sync.Mutex // Serializes Start, Stop and Health calls on services
+	mu       sync.Mutex // Guards the supervision state
+	started  bool
+	quit     chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+// NewSupervisor creates a supervisor with no services
+func NewSupervisor(config SupervisorConfig) *Supervisor {
+	if config.HealthInterval <= 0 {
+		config.HealthInterval = 5 * time.Second
+	}
+	if config.RestartDelay <= 0 {
+		config.RestartDelay = time.Second
+	}
+	if config.MaxRestartDelay < config.RestartDelay {
+		config.MaxRestartDelay = time.Minute
+		if config.MaxRestartDelay < config.RestartDelay {
+			config.MaxRestartDelay = config.RestartDelay
+		}
+	}
+	if config.MaxRestarts == 0 {
+		config.MaxRestarts = 10
+	}
+
+	return &Supervisor{
+		config: config,
+		byName: make(map[string]*supervisedService),
+		quit:   make(chan struct{}),
+	}
+}
+
+// Add registers a service. Services start in the order they are added and stop in reverse.
+// A service added after Start is started immediately.
+func (s *Supervisor) Add(service Service) error {
+	name := service.Name()
+	if name == "" {
+		return errors.New("service name is required")
+	}
+
+	s.mu.Lock()
+	if _, exists := s.byName[name]; exists {
+		s.mu.Unlock()
+		return fmt.Errorf("service %s already added", name)
+	}
+	ss := &supervisedService{
+		service: service,
+		status:  ServiceStatus{Name: name, State: ServiceStopped, Since: time.Now()},
+		delay:   s.config.RestartDelay,
+	}
+	s.services = append(s.services, ss)
+	s.byName[name] = ss
+	started := s.started
+	s.mu.Unlock()
+
+	if started {
+		s.startService(ss)
+	}
+	return nil
+}
+
+// Start starts every service and begins supervising them. A service that fails to start is
+// marked failed and retried like one that crashed.
+func (s *Supervisor) Start() {
+	s.mu.Lock()
+	s.started = true
+	services := append([]*supervisedService(nil), s.services...)
+	s.mu.Unlock()
+
+	for _, ss := range services {
+		s.startService(ss)
+	}
+
+	s.wg.Add(1)
+	go s.loop()
+}
+
+// Stop ends supervision and stops every service in reverse start order
+func (s *Supervisor) Stop() {
+	s.once.Do(func() { close(s.quit) })
+	s.wg.Wait()
+
+	s.mu.Lock()
+	s.started = false
+	services := append([]*supervisedService(nil), s.services...)
+	s.mu.Unlock()
+
+	for i := len(services) - 1; i >= 0; i-- {
+		if err := s.stopService(services[i]); err != nil {
+			log.Printf("Failed to stop service %s: %v", services[i].status.Name, err)
+		}
+	}
+}
+
+// StartService starts a stopped or failed service and clears its restart backoff
+func (s *Supervisor) StartService(name string) error {
+	ss, err := s.lookup(name)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	running := ss.status.State == ServiceRunning
+	s.resetBackoff(ss)
+	s.mu.Unlock()
+
+	if running {
+		return fmt.Errorf("service %s is already running", name)
+	}
+	return s.startService(ss)
+}
+
+// StopService stops a service. It stays stopped, without automatic restarts, until started again.
+func (s *Supervisor) StopService(name string) error {
+	ss, err := s.lookup(name)
+	if err != nil {
+		return err
+	}
+	return s.stopService(ss)
+}
+
+// RestartService stops a service if it is running and starts it again
+func (s *Supervisor) RestartService(name string) error {
+	ss, err := s.lookup(name)
+	if err != nil {
+		return err
+	}
+
+	if err := s.stopService(ss); err != nil {
+		log.Printf("Failed to stop service %s for restart: %v", name, err)
+	}
+	s.mu.Lock()
+	s.resetBackoff(ss)
+	s.mu.Unlock()
+	return s.startService(ss)
+}
+
+// Fail reports that a running service has crashed, e.g. when a server's serve loop returns.
+// The service is stopped and restarted after its backoff delay.
+func (s *Supervisor) Fail(name string, err error) {
+	ss, lookupErr := s.lookup(name)
+	if lookupErr != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ss.status.State == ServiceRunning {
+		s.markFailed(ss, err)
+	}
+}
+
+// CheckHealth health-checks every running service now instead of waiting for the next interval
+func (s *Supervisor) CheckHealth() {
+	s.mu.Lock()
+	services := append([]*supervisedService(nil), s.services...)
+	s.mu.Unlock()
+
+	for _, ss := range services {
+		s.checkService(ss)
+	}
+}
+
+// Status reports every service in start order
+func (s *Supervisor) Status() []ServiceStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]ServiceStatus, len(s.services))
+	for i, ss := range s.services {
+		statuses[i] = ss.status
+	}
+	return statuses
+}
+
+// ServiceStatus reports one service
+func (s *Supervisor) ServiceStatus(name string) (ServiceStatus, error) {
+	ss, err := s.lookup(name)
+	if err != nil {
+		return ServiceStatus{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return ss.status, nil
+}
+
+// lookup finds a service by name
+func (s *Supervisor) lookup(name string) (*supervisedService, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ss, ok := s.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownService, name)
+	}
+	return ss, nil
+}
+
+// loop health-checks running services and restarts failed ones until stopped
+func (s *Supervisor) loop() {
+	defer s.wg.Done()
+
+	// Restarts are due at RestartDelay granularity, checks at HealthInterval
+	tick := s.config.RestartDelay
+	if s.config.HealthInterval < tick {
+		tick = s.config.HealthInterval
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	lastCheck := time.Now()
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-ticker.C:
+		}
+
+		if time.Since(lastCheck) >= s.config.HealthInterval {
+			s.CheckHealth()
+			lastCheck = time.Now()
+		}
+		s.restartFailed()
+	}
+}
+
+// restartFailed restarts every failed service whose backoff delay has passed
+func (s *Supervisor) restartFailed() {
+	now := time.Now()
+
+	s.mu.Lock()
+	var due []*supervisedService
+	for _, ss := range s.services {
+		if ss.status.State == ServiceFailed && !ss.status.GaveUp && !now.Before(ss.retryAt) {
+			due = append(due, ss)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, ss := range due {
+		select {
+		case <-s.quit:
+			return
+		default:
+		}
+
+		s.ops.Lock()
+		s.mu.Lock()
+		stillFailed := ss.status.State == ServiceFailed && !ss.status.GaveUp
+		s.mu.Unlock()
+		if !stillFailed { // Stopped or restarted by an operator meanwhile
+			s.ops.Unlock()
+			continue
+		}
+
+		// Clean up what's left of the crashed instance before starting a new one
+		if err := safeCall(ss.service.Stop); err != nil {
+			log.Printf("Failed to clean up service %s: %v", ss.status.Name, err)
+		}
+		s.mu.Lock()
+		ss.status.Restarts++
+		s.mu.Unlock()
+
+		log.Printf("Restarting service %s", ss.status.Name)
+		s.start(ss)
+		s.ops.Unlock()
+	}
+}
+
+// startService starts a service, marking it running or failed
+func (s *Supervisor) startService(ss *supervisedService) error {
+	s.ops.Lock()
+	defer s.ops.Unlock()
+	return s.start(ss)
+}
+
+// start starts a service. The caller must hold s.ops.
+func (s *Supervisor) start(ss *supervisedService) error {
+	s.mu.Lock()
+	if ss.status.State == ServiceRunning {
+		s.mu.Unlock()
+		return nil
+	}
+	s.mu.Unlock()
+
+	err := safeCall(ss.service.Start)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.markFailed(ss, err)
+		return fmt.Errorf("failed to start service %s: %w", ss.status.Name, err)
+	}
+	ss.status.State = ServiceRunning
+	ss.status.Since = time.Now()
+	ss.status.LastError = ""
+	log.Printf("Service %s started", ss.status.Name)
+	return nil
+}
+
+// stopService stops a service and keeps it stopped
+func (s *Supervisor) stopService(ss *supervisedService) error {
+	s.ops.Lock()
+	defer s.ops.Unlock()
+
+	s.mu.Lock()
+	state := ss.status.State
+	s.mu.Unlock()
+
+	var err error
+	if state != ServiceStopped {
+		err = safeCall(ss.service.Stop)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state != ServiceStopped {
+		ss.status.State = ServiceStopped
+		ss.status.Since = time.Now()
+		log.Printf("Service %s stopped", ss.status.Name)
+	}
+	s.resetBackoff(ss)
+	return err
+}
+
+// checkService health-checks a running service, marking it failed if the check fails
+func (s *Supervisor) checkService(ss *supervisedService) {
+	s.ops.Lock()
+	defer s.ops.Unlock()
+
+	s.mu.Lock()
+	running := ss.status.State == ServiceRunning
+	s.mu.Unlock()
+	if !running {
+		return
+	}
+
+	err := safeCall(ss.service.Health)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ss.status.LastCheck = time.Now()
+	if ss.status.State != ServiceRunning {
+		return
+	}
+	if err != nil {
+		s.markFailed(ss, fmt.Errorf("health check failed: %w", err))
+		return
+	}
+	ss.failures = 0
+	ss.delay = s.config.RestartDelay
+}
+
+// markFailed records a failure and schedules the restart, or gives up past MaxRestarts.
+// The caller must hold s.mu.
+func (s *Supervisor) markFailed(ss *supervisedService, err error) {
+	ss.status.State = ServiceFailed
+	ss.status.Since = time.Now()
+	ss.status.LastError = err.Error()
+	ss.failures++
+
+	if s.config.MaxRestarts > 0 && ss.failures > s.config.MaxRestarts {
+		ss.status.GaveUp = true
+		log.Printf("Service %s failed %d times in a row, giving up: %v", ss.status.Name, ss.failures, err)
+		return
+	}
+
+	ss.retryAt = time.Now().Add(ss.delay)
+	log.Printf("Service %s failed, restarting in %v: %v", ss.status.Name, ss.delay, err)
+	ss.delay *= 2
+	if ss.delay > s.config.MaxRestartDelay {
+		ss.delay = s.config.MaxRestartDelay
+	}
+}
+
+// resetBackoff clears the failure count after an operator action. The caller must hold s.mu.
+func (s *Supervisor) resetBackoff(ss *supervisedService) {
+	ss.failures = 0
+	ss.delay = s.config.RestartDelay
+	ss.status.GaveUp = false
+}
+
+// safeCall runs a service callback, turning a panic into an error
+func safeCall(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn()
+}
@@ -2,46 +2,285 @@ package blockchain
 
 import (
 	"errors"
+	"fmt"
+	"slices"
 	"sync"
+	"time"
 )
 
-// TransactionPool represents the mempool of pending transactions
-type TransactionPool struct {
+// replacementFeeMultiplier is the minimum fee bump required to replace a conflicting transaction
+const replacementFeeMultiplier = 1.1
+
+// reservationEpsilon absorbs floating point residue when releasing reservations
+const reservationEpsilon = 1e-9
+
+// Mempool holds the pending transactions of a node: standard transactions, admitted and taken
+// into blocks under the checks below, and enhanced transactions waiting for their signatures or
+// lock time before they join blocks in standard form
+type Mempool struct {
 	transactions map[string]*Transaction
+	bySenderSeq  map[string]string  // "sender:nonce" -> transaction hash
+	addedAt      map[string]int64   // Unix time each transaction entered the pool
+	reserved     map[string]float64 // sender -> amount plus fee spent by its pending transactions
+	parents      map[string][]string
+	children     map[string][]string
+	enhanced     map[string]*EnhancedTransaction
+	enhancedDB   EnhancedTransactionStore
+	chain        ChainView
+	balances     BalanceView
+	policies     SpendPolicyView
+	halts        HaltView
+	tip          TipView
+	feePolicy    *FeePolicy
+	admissions   *AdmissionLog
+	eviction     *EvictionPolicy
+	limits       *PoolLimits
+	evictions    EvictionStats
 	mu           sync.RWMutex
+	signedOnly   bool
+	params       *ChainParams
+	closed       bool // Refusing transactions from sources other than local
 	maxSize      int
+	subscribers  map[int]poolSubscriber
+	nextSub      int
 }
 
-// NewTransactionPool creates a new transaction pool
-func NewTransactionPool(maxSize int) *TransactionPool {
-	return &TransactionPool{
+// TransactionPool is the mempool under the name of its standard transaction side
+type TransactionPool = Mempool
+
+// NewMempool creates a mempool holding up to maxSize standard and enhanced transactions
+func NewMempool(maxSize int) *Mempool {
+	return &Mempool{
 		transactions: make(map[string]*Transaction),
+		bySenderSeq:  make(map[string]string),
+		addedAt:      make(map[string]int64),
+		parents:      make(map[string][]string),
+		children:     make(map[string][]string),
+		reserved:     make(map[string]float64),
+		enhanced:     make(map[string]*EnhancedTransaction),
+		subscribers:  make(map[int]poolSubscriber),
 		maxSize:      maxSize,
 	}
 }
 
-// AddTransaction adds a transaction to the pool if it's valid
-func (tp *TransactionPool) AddTransaction(tx *Transaction) error {
+// NewTransactionPool creates a new transaction pool
+func NewTransactionPool(maxSize int) *TransactionPool {
+	return NewMempool(maxSize)
+}
+
+// SetChainView connects the pool to confirmed chain state for double-spend detection
+func (tp *Mempool) SetChainView(chain ChainView) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	tp.chain = chain
+}
+
+// SetBalanceView makes the pool reject transactions spending more than the sender's
+// available balance, i.e. its confirmed balance minus what its pending transactions reserve,
+// and sponsored transactions whose fee exceeds the sponsor's available balance
+func (tp *Mempool) SetBalanceView(balances BalanceView) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	tp.balances = balances
+}
+
+// SetSpendPolicyView makes the pool reject spends violating a confirmed spend policy
+func (tp *Mempool) SetSpendPolicyView(policies SpendPolicyView) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	tp.policies = policies
+}
+
+// SetFeePolicy makes the pool reject transactions paying less than their type requires (nil disables)
+func (tp *Mempool) SetFeePolicy(policy *FeePolicy) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	tp.feePolicy = policy
+}
+
+// SetAdmissionLog records every accept or reject decision of the pool in the log (nil disables)
+func (tp *Mempool) SetAdmissionLog(admissions *AdmissionLog) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	tp.admissions = admissions
+}
+
+// AdmissionLog returns the admission log of the pool, or nil if decisions aren't recorded
+func (tp *Mempool) AdmissionLog() *AdmissionLog {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+	return tp.admissions
+}
+
+// AddTransaction adds a transaction submitted in-process to the pool if it's valid
+func (tp *Mempool) AddTransaction(tx *Transaction) error {
+	return tp.AddTransactionFrom(tx, AdmissionSourceLocal)
+}
+
+// AddTransactionFrom adds a transaction to the pool if it's valid, recording the decision
+// against the source it came from. A transaction reusing the nonce of a pending transaction
+// from the same sender replaces it only if it pays a sufficiently higher fee.
+func (tp *Mempool) AddTransactionFrom(tx *Transaction, source string) error {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	return tp.addFromLocked(tx, source)
+}
+
+// AddTransactions adds a batch of transactions submitted in-process, see AddTransactionsFrom
+func (tp *Mempool) AddTransactions(txs []*Transaction) []error {
+	return tp.AddTransactionsFrom(txs, AdmissionSourceLocal)
+}
+
+// AddTransactionsFrom adds a batch of transactions in one critical section, in order, so a
+// transaction may depend on one before it in the batch. It returns the outcome of every
+// transaction at its index, nil for those accepted; a rejection doesn't stop the batch.
+func (tp *Mempool) AddTransactionsFrom(txs []*Transaction, source string) []error {
 	tp.mu.Lock()
 	defer tp.mu.Unlock()
 
-	// Check pool size
-	if len(tp.transactions) >= tp.maxSize {
-		return errors.New("transaction pool is full")
+	errs := make([]error, len(txs))
+	for i, tx := range txs {
+		if tx == nil {
+			errs[i] = fmt.Errorf("%w: missing transaction", ErrInvalidTransaction)
+			continue
+		}
+		errs[i] = tp.addFromLocked(tx, source)
+	}
+	return errs
+}
+
+// addFromLocked admits a transaction from a source and records the decision (caller must hold the lock)
+func (tp *Mempool) addFromLocked(tx *Transaction, source string) error {
+	if tp.closed && source != AdmissionSourceLocal {
+		err := reject(AdmissionClosed, errors.New("node is not accepting transactions during maintenance"))
+		tp.admissions.record(tx, source, nil, err)
+		return err
 	}
 
+	replaced, err := tp.addLocked(tx)
+	tp.admissions.record(tx, source, replaced, err)
+	return err
+}
+
+// SetAcceptExternal opens or closes the pool to transactions from sources other than local
+func (tp *Mempool) SetAcceptExternal(accept bool) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	tp.closed = !accept
+}
+
+// addLocked admits a transaction, returning the pending transaction it replaced if any
+// (caller must hold the lock)
+func (tp *Mempool) addLocked(tx *Transaction) (*Transaction, error) {
 	// Validate transaction
 	if err := tp.validateTransaction(tx); err != nil {
-		return err
+		return nil, err
+	}
+
+	// Check for a conflicting pending transaction
+	conflict, err := tp.findConflict(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the transaction is within the per-sender, dust and fee rate limits
+	if err := tp.checkLimits(tx, conflict); err != nil {
+		return nil, err
+	}
+
+	// Check the sender can cover the transaction on top of its other pending spends, possibly
+	// with funds pending transactions credit it
+	creditors, err := tp.checkAvailableBalance(tx, conflict)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check pool size (a replacement doesn't grow the pool)
+	if conflict == nil && tp.sizeLocked() >= tp.maxSize {
+		if err := tp.makeRoomLocked(tx); err != nil {
+			return nil, err
+		}
+	}
+
+	var orphans []string
+	if conflict != nil {
+		orphans = slices.Clone(tp.children[conflict.Hash])
+		tp.removeLocked(conflict)
 	}
 
 	// Add transaction to pool
 	tp.transactions[tx.Hash] = tx
-	return nil
+	tp.addedAt[tx.Hash] = time.Now().Unix()
+	if !tx.IsCoinbase() {
+		tp.reserve(tx)
+	}
+	if tx.Nonce != 0 {
+		tp.bySenderSeq[senderSeqKey(tx)] = tx.Hash
+	}
+	// A replacement takes over the dependents of the transaction it replaces
+	tp.linkLocked(tx, creditors, orphans)
+
+	if conflict != nil {
+		tp.publishLocked(PoolEvent{Type: PoolEventReplace, Transaction: tx, Replaced: conflict})
+	} else {
+		tp.publishLocked(PoolEvent{Type: PoolEventAdd, Transaction: tx})
+	}
+	return conflict, nil
+}
+
+// findConflict returns the pending transaction the new one would replace,
+// or an error if it conflicts without paying enough to replace it
+func (tp *Mempool) findConflict(tx *Transaction) (*Transaction, error) {
+	if tx.Nonce == 0 {
+		return nil, nil
+	}
+
+	existingHash, exists := tp.bySenderSeq[senderSeqKey(tx)]
+	if !exists {
+		return nil, nil
+	}
+
+	existing := tp.transactions[existingHash]
+	if tx.Fee <= existing.Fee || tx.Fee < existing.Fee*replacementFeeMultiplier {
+		return nil, reject(AdmissionConflict, fmt.Errorf("transaction conflicts with pending transaction %s (replacement requires fee >= %.8f)",
+			existing.Hash, existing.Fee*replacementFeeMultiplier))
+	}
+	return existing, nil
+}
+
+// removeLocked removes a transaction, its nonce entry and its dependency links. Its dependents
+// stay in the pool (caller must hold the lock).
+func (tp *Mempool) removeLocked(tx *Transaction) {
+	delete(tp.transactions, tx.Hash)
+	delete(tp.addedAt, tx.Hash)
+	tp.unlinkLocked(tx.Hash)
+	if !tx.IsCoinbase() {
+		for _, payer := range tx.payers() {
+			tp.reserved[payer] -= tx.debit(payer)
+			if tp.reserved[payer] <= reservationEpsilon {
+				delete(tp.reserved, payer)
+			}
+		}
+	}
+	if tx.Nonce != 0 && tp.bySenderSeq[senderSeqKey(tx)] == tx.Hash {
+		delete(tp.bySenderSeq, senderSeqKey(tx))
+	}
+}
+
+// reserve commits the balances a pending transaction spends: the amount of the sender and
+// the fee of its payer (caller must hold the lock)
+func (tp *Mempool) reserve(tx *Transaction) {
+	tp.reserved[tx.From] += tx.Amount
+	tp.reserved[tx.FeePayer()] += tx.Fee
+}
+
+// senderSeqKey identifies a sender nonce slot
+func senderSeqKey(tx *Transaction) string {
+	return fmt.Sprintf("%s:%d", tx.From, tx.Nonce)
 }
 
 // GetTransactions returns all transactions in the pool
-func (tp *TransactionPool) GetTransactions() []*Transaction {
+func (tp *Mempool) GetTransactions() []*Transaction {
 	tp.mu.RLock()
 	defer tp.mu.RUnlock()
 
@@ -52,34 +291,184 @@ func (tp *TransactionPool) GetTransactions() []*Transaction {
 	return txs
 }
 
-// RemoveTransactions removes transactions from the pool
-func (tp *TransactionPool) RemoveTransactions(txs []*Transaction) {
+// GetTransaction returns a pending transaction by hash
+func (tp *Mempool) GetTransaction(hash string) (*Transaction, bool) {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+
+	tx, exists := tp.transactions[hash]
+	return tx, exists
+}
+
+// GetAddedTime returns the Unix time a pending transaction entered the pool
+func (tp *Mempool) GetAddedTime(hash string) (int64, bool) {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+
+	added, exists := tp.addedAt[hash]
+	return added, exists
+}
+
+// GetReservedBalance returns the amount an address has committed to pending transactions
+func (tp *Mempool) GetReservedBalance(address string) float64 {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+	return tp.reserved[address]
+}
+
+// checkAvailableBalance rejects a transaction the sender cannot cover with its available balance,
+// or whose sponsor cannot cover the fee. The amount reserved by a transaction being replaced is
+// released first. When the confirmed balance falls short, the amounts pending transactions credit
+// the payer count too, and those transactions are returned as the ones the new one depends on.
+func (tp *Mempool) checkAvailableBalance(tx *Transaction, replaced *Transaction) ([]string, error) {
+	if tp.balances == nil || tx.IsCoinbase() {
+		return nil, nil
+	}
+
+	var creditors []string
+	for _, payer := range tx.payers() {
+		reserved := tp.reserved[payer]
+		if replaced != nil {
+			reserved -= replaced.debit(payer)
+		}
+
+		available := tp.balances.GetBalance(payer) - reserved
+		required := tx.debit(payer)
+		if required > available {
+			credited, amount := tp.pendingCredits(payer, required-available, tx, replaced)
+			creditors = append(creditors, credited...)
+			available += amount
+		}
+		if required > available {
+			who := "insufficient"
+			if payer != tx.From {
+				who = "sponsor has insufficient"
+			}
+			return nil, reject(AdmissionInsufficientBalance,
+				fmt.Errorf("%s available balance: %.8f available, %.8f required", who, available, required))
+		}
+	}
+	return creditors, nil
+}
+
+// GetPendingNonce returns the highest nonce used by pending transactions of an address
+func (tp *Mempool) GetPendingNonce(address string) int64 {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+
+	var nonce int64
+	for _, tx := range tp.transactions {
+		if tx.From == address && tx.Nonce > nonce {
+			nonce = tx.Nonce
+		}
+	}
+	return nonce
+}
+
+// RemoveTransactions removes transactions from the pool, enhanced ones matched by the hash of
+// their standard form
+func (tp *Mempool) RemoveTransactions(txs []*Transaction) {
 	tp.mu.Lock()
 	defer tp.mu.Unlock()
 
 	for _, tx := range txs {
-		delete(tp.transactions, tx.Hash)
+		if pending, exists := tp.transactions[tx.Hash]; exists {
+			tp.removeLocked(pending)
+			tp.publishLocked(PoolEvent{Type: PoolEventRemove, Transaction: pending, Reason: DropConfirmed})
+		}
+		if enhanced, exists := tp.enhanced[tx.Hash]; exists {
+			delete(tp.enhanced, tx.Hash)
+			tp.publishEnhancedLocked(PoolEventRemove, enhanced, DropConfirmed, "")
+		}
 	}
 }
 
 // validateTransaction validates a transaction
-func (tp *TransactionPool) validateTransaction(tx *Transaction) error {
+func (tp *Mempool) validateTransaction(tx *Transaction) error {
+	if err := CheckTransactionLimits(tx); err != nil {
+		return err
+	}
+
 	// Basic validation
 	if tx.From == "" || tx.To == "" {
-		return errors.New("invalid transaction: missing from/to address")
+		return ErrMissingAddress
 	}
 
-	if tx.Amount <= 0 {
-		return errors.New("invalid transaction: amount must be positive")
+	// Key-value entries and spend policies carry no value transfer, only a payload
+	switch tx.To {
+	case KVNamespaceAddress:
+		if err := validateKVTransaction(tx); err != nil {
+			return err
+		}
+	case SpendPolicyAddress:
+		if err := validateSpendPolicyTransaction(tx); err != nil {
+			return err
+		}
+	case HaltAddress:
+		if err := validateHaltTransaction(tx); err != nil {
+			return err
+		}
+	default:
+		if tx.Amount <= 0 {
+			return ErrInvalidAmount
+		}
 	}
-
 	if tx.Fee < 0 {
-		return errors.New("invalid transaction: fee cannot be negative")
+		return ErrNegativeFee
+	}
+
+	if tp.params != nil {
+		if err := checkTransactionAddresses(tx, tp.params); err != nil {
+			return reject(AdmissionBadAddress, err)
+		}
+	}
+
+	if err := checkTransactionSignature(tx, tp.signedOnly, tp.params); err != nil {
+		return reject(AdmissionBadSignature, err)
+	}
+
+	if err := tp.feePolicy.CheckData(tx); err != nil {
+		return reject(AdmissionInvalid, err)
+	}
+	if err := tp.feePolicy.CheckTransaction(tx); err != nil {
+		return reject(AdmissionFeeTooLow, err)
 	}
 
 	// Check if transaction already exists
 	if _, exists := tp.transactions[tx.Hash]; exists {
-		return errors.New("transaction already exists in pool")
+		return reject(AdmissionDuplicate, ErrDuplicateTx)
+	}
+
+	// Check against confirmed transactions (coinbase rewards legitimately repeat)
+	if tp.chain != nil && !tx.IsCoinbase() {
+		if tp.chain.IsTransactionConfirmed(tx.Hash) {
+			return reject(AdmissionAlreadyConfirmed, errors.New("transaction already confirmed in chain"))
+		}
+		if tx.Nonce != 0 && tx.Nonce <= tp.chain.GetConfirmedNonce(tx.From) {
+			return reject(AdmissionNonceUsed, fmt.Errorf("transaction nonce %d already used by a confirmed transaction", tx.Nonce))
+		}
+	}
+
+	if tp.policies != nil {
+		if err := tp.policies.CheckSpendPolicy(tx); err != nil {
+			return reject(AdmissionPolicyViolation, err)
+		}
+	}
+
+	if tp.halts != nil {
+		if err := tp.halts.CheckHalt(tx); err != nil {
+			if errors.Is(err, ErrChainHalted) {
+				return reject(AdmissionHalted, err)
+			}
+			return err
+		}
+	}
+
+	if tp.tip != nil {
+		height, timestamp := tp.tip.NextBlock()
+		if err := checkExpiry(tx, height, timestamp); err != nil {
+			return reject(AdmissionExpired, err)
+		}
 	}
 
 	return nil
@@ -0,0 +1,13 @@
+package blockchain
+
+import "fmt"
+
+// ErrDataTooLarge is returned for a transaction carrying more data than the fee policy allows
+var ErrDataTooLarge = fmt.Errorf("%w: data too large", ErrInvalidTransaction)
+
+// SetData attaches a memo, reference ID or other payload to a transaction and recomputes its
+// hash, so signatures must be attached after. Data is arbitrary bytes, covered by the hash.
+func (tx *Transaction) SetData(data []byte) {
+	tx.Data = data
+	tx.Hash = tx.calculateHash()
+}
@@ -0,0 +1,39 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTransactionBinaryData(t *testing.T) {
+	data := []byte{0xff, 0x00, 0xfe, 'a'}
+	tx := NewTransaction("alice", "bob", 1, 0.1)
+	unchanged := tx.Hash
+	tx.SetData(data)
+	if tx.Hash == unchanged {
+		t.Fatal("data is not covered by the hash")
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+	if encoding := string(tx.encode()); !strings.Contains(encoding, `"Data":"`+encoded+`"`) {
+		t.Fatalf("canonical encoding %s does not hold the data as base64", encoding)
+	}
+
+	raw, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), `"data":"`+encoded+`"`) {
+		t.Fatalf("JSON %s does not hold the data as base64", raw)
+	}
+	var decoded Transaction
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded.Data, data) || decoded.calculateHash() != tx.Hash {
+		t.Fatal("data does not survive a JSON round trip")
+	}
+}
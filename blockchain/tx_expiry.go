@@ -0,0 +1,109 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ValidUntilTimestampThreshold splits the meanings of a transaction's ValidUntil: below it the
+// last block height the transaction can confirm at, from it on the last block Unix time
+const ValidUntilTimestampThreshold = 500000000
+
+// DropValidityExpired is the reason of a transaction removed once its validity window passed
+const DropValidityExpired DropReason = "validity_expired"
+
+// ErrTxExpired is returned for a transaction whose validity window has passed
+var ErrTxExpired = errors.New("transaction validity window has passed")
+
+// ExpiredAt reports whether a transaction can no longer confirm in a block at a height with a
+// timestamp. A transaction without ValidUntil never expires.
+func (tx *Transaction) ExpiredAt(height, timestamp int64) bool {
+	switch {
+	case tx.ValidUntil <= 0:
+		return false
+	case tx.ValidUntil < ValidUntilTimestampThreshold:
+		return height > tx.ValidUntil
+	default:
+		return timestamp > tx.ValidUntil
+	}
+}
+
+// SetValidUntil gives a transaction a validity window and recomputes its hash, so signatures
+// must be attached after
+func (tx *Transaction) SetValidUntil(validUntil int64) {
+	tx.ValidUntil = validUntil
+	tx.Hash = tx.calculateHash()
+}
+
+// checkExpiry returns ErrTxExpired if a transaction can't confirm in a block at a height with a timestamp
+func checkExpiry(tx *Transaction, height, timestamp int64) error {
+	if !tx.ExpiredAt(height, timestamp) {
+		return nil
+	}
+	if tx.ValidUntil < ValidUntilTimestampThreshold {
+		return fmt.Errorf("%w: valid until height %d, block height %d", ErrTxExpired, tx.ValidUntil, height)
+	}
+	return fmt.Errorf("%w: valid until %s, block time %s", ErrTxExpired,
+		time.Unix(tx.ValidUntil, 0).UTC().Format(time.RFC3339), time.Unix(timestamp, 0).UTC().Format(time.RFC3339))
+}
+
+// checkBlockExpiry rejects a block including a transaction past its validity window
+func checkBlockExpiry(block *Block) error {
+	for i := range block.Transactions {
+		tx := &block.Transactions[i]
+		if err := checkExpiry(tx, block.Index, block.Timestamp); err != nil {
+			return fmt.Errorf("transaction %s: %w", tx.Hash, err)
+		}
+	}
+	return nil
+}
+
+// TipView gives the transaction pool the height and time of the next block
+type TipView interface {
+	NextBlock() (height, timestamp int64)
+}
+
+// SetTipView makes the pool refuse, and drop on maintenance, transactions past their validity window
+func (tp *Mempool) SetTipView(tip TipView) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	tp.tip = tip
+}
+
+// DropExpiredAt removes the pending transactions that can't confirm in a block at a height with
+// a timestamp, with the transactions depending on them
+func (tp *Mempool) DropExpiredAt(height, timestamp int64) []PoolDropEvent {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	now := time.Now().Unix()
+	var events []PoolDropEvent
+	for _, tx := range tp.transactions {
+		err := checkExpiry(tx, height, timestamp)
+		if err == nil {
+			continue
+		}
+
+		dependents := tp.dependentsLocked(tx.Hash)
+		tp.removeLocked(tx)
+		events = append(events, PoolDropEvent{Transaction: tx, Reason: DropValidityExpired, Detail: err.Error(), Time: now})
+		for _, dependent := range dependents {
+			tp.removeLocked(dependent)
+			events = append(events, PoolDropEvent{Transaction: dependent, Reason: DropParentDropped,
+				Detail: fmt.Sprintf("depends on dropped transaction %s", tx.Hash), Time: now})
+		}
+	}
+	tp.publishDropsLocked(events)
+	return events
+}
+
+// NextBlock returns the height and adjusted time of the next block
+func (bc *Blockchain) NextBlock() (int64, int64) {
+	return bc.GetLatestBlock().Index + 1, bc.adjustedTime()
+}
+
+// NextBlock returns the height and adjusted time of the next block
+func (pbc *PersistentBlockchain) NextBlock() (int64, int64) {
+	return pbc.GetLatestBlock().Index + 1, pbc.adjustedTime()
+}
@@ -0,0 +1,35 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSignatureCoversValidUntil(t *testing.T) {
+	wallet, err := NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc := NewBlockchain(1, wallet.Address)
+	bc.SetRequireSignatures(true)
+	bc.MinePendingTransactions()
+
+	tx := NewSponsoredTransaction(wallet.Address, "bob", 3, 0.1, 1, "")
+	tx.SetValidUntil(1000)
+	if err := wallet.AttachSignature(tx); err != nil {
+		t.Fatal(err)
+	}
+	for _, validUntil := range []int64{0, 2000} {
+		assertTamperingBreaksSignature(t, tx, "validity window", func(tx *Transaction) { tx.ValidUntil = validUntil })
+
+		// A relayer can't lift or move the deadline the sender signed
+		forged := *tx
+		forged.SetValidUntil(validUntil)
+		if err := bc.AddTransaction(&forged); !errors.Is(err, ErrInvalidSignature) {
+			t.Fatalf("transaction valid until %d was admitted with the original signature: %v", validUntil, err)
+		}
+	}
+	if err := bc.AddTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+}
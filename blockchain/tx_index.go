@@ -0,0 +1,103 @@
+package blockchain
+
+import "fmt"
+
+// TxLocation is the position of a confirmed transaction in the chain
+type TxLocation struct {
+	BlockIndex int64 `json:"blockIndex"`
+	TxIndex    int   `json:"txIndex"`
+}
+
+// txIndex maps confirmed transaction hashes to their location in the chain.
+// It follows the chain incrementally as blocks are appended and is rebuilt
+// whenever the chain no longer extends the indexed tip (e.g. after a reorg or reload).
+type txIndex struct {
+	locations map[string]TxLocation
+	height    int64 // Height of the last indexed block (-1 if empty)
+	tipHash   string
+	pruned    map[string]TxLocation // Transactions of pruned blocks, kept across rebuilds
+}
+
+// newTxIndex creates an empty transaction index
+func newTxIndex() *txIndex {
+	return &txIndex{
+		locations: make(map[string]TxLocation),
+		height:    -1,
+	}
+}
+
+// sync brings the index up to date with the chain
+func (ti *txIndex) sync(chain []*Block) {
+	length := int64(len(chain))
+	if ti.height >= 0 && (ti.height >= length || chain[ti.height].Hash != ti.tipHash) {
+		ti.locations = make(map[string]TxLocation)
+		ti.height = -1
+		ti.tipHash = ""
+	}
+
+	for height := ti.height + 1; height < length; height++ {
+		ti.addBlock(chain[height])
+	}
+}
+
+// addBlock indexes the transactions of the next block.
+// Identical coinbase transactions keep their first location.
+func (ti *txIndex) addBlock(block *Block) {
+	for i, tx := range block.Transactions {
+		if _, exists := ti.locations[tx.Hash]; exists {
+			continue
+		}
+		ti.locations[tx.Hash] = TxLocation{BlockIndex: block.Index, TxIndex: i}
+	}
+	ti.height = block.Index
+	ti.tipHash = block.Hash
+}
+
+// lookup returns the location of a transaction
+func (ti *txIndex) lookup(hash string) (TxLocation, bool) {
+	location, exists := ti.locations[hash]
+	if !exists {
+		location, exists = ti.pruned[hash]
+	}
+	return location, exists
+}
+
+// transactionIndex returns the transaction index, caught up with the chain
+func (bc *Blockchain) transactionIndex() *txIndex {
+	if bc.txIndex == nil {
+		bc.txIndex = newTxIndex()
+	}
+	bc.txIndex.sync(bc.Chain)
+	return bc.txIndex
+}
+
+// GetTransaction finds a confirmed transaction by hash along with its location
+func (bc *Blockchain) GetTransaction(hash string) (*Transaction, TxLocation, error) {
+	location, exists := bc.transactionIndex().lookup(hash)
+	if !exists {
+		return nil, TxLocation{}, ErrTransactionNotFound
+	}
+	return &bc.Chain[location.BlockIndex].Transactions[location.TxIndex], location, nil
+}
+
+// transactionIndex returns the transaction index, caught up with the chain
+func (pbc *PersistentBlockchain) transactionIndex() *txIndex {
+	if pbc.txIndex == nil {
+		pbc.txIndex = newTxIndex()
+		pbc.txIndex.pruned = pbc.prunedState().Transactions
+	}
+	pbc.txIndex.sync(pbc.Chain)
+	return pbc.txIndex
+}
+
+// GetTransaction finds a confirmed transaction by hash along with its location
+func (pbc *PersistentBlockchain) GetTransaction(hash string) (*Transaction, TxLocation, error) {
+	location, exists := pbc.transactionIndex().lookup(hash)
+	if !exists {
+		return nil, TxLocation{}, ErrTransactionNotFound
+	}
+	if pbc.Chain[location.BlockIndex].Pruned {
+		return nil, location, fmt.Errorf("transaction %s: %w", hash, ErrPruned)
+	}
+	return &pbc.Chain[location.BlockIndex].Transactions[location.TxIndex], location, nil
+}
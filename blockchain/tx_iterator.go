@@ -0,0 +1,99 @@
+package blockchain
+
+import (
+	"iter"
+)
+
+// TransactionMatcher selects transactions for FindTransactions (nil matches everything)
+type TransactionMatcher func(tx *Transaction, block *Block) bool
+
+// FindTransactions iterates over transactions matching a predicate, in chain order,
+// within the block heights [fromHeight, toHeight] (a negative toHeight means the chain tip).
+// Iteration stops as soon as the loop body breaks. Yielded values point into the chain and must not be modified.
+func (bc *Blockchain) FindTransactions(match TransactionMatcher, fromHeight, toHeight int64) iter.Seq2[*Transaction, *Block] {
+	return findTransactionsInChain(bc.Chain, match, fromHeight, toHeight)
+}
+
+// FindTransactions iterates over transactions matching a predicate (see Blockchain.FindTransactions).
+// Pruned blocks hold no transactions: heights below PrunedHeight may be incomplete.
+func (pbc *PersistentBlockchain) FindTransactions(match TransactionMatcher, fromHeight, toHeight int64) iter.Seq2[*Transaction, *Block] {
+	return findTransactionsInChain(pbc.Chain, match, fromHeight, toHeight)
+}
+
+// findTransactionsInChain iterates over matching transactions of an in-memory chain
+func findTransactionsInChain(chain []*Block, match TransactionMatcher, fromHeight, toHeight int64) iter.Seq2[*Transaction, *Block] {
+	return func(yield func(*Transaction, *Block) bool) {
+		if fromHeight < 0 {
+			fromHeight = 0
+		}
+		if toHeight < 0 || toHeight >= int64(len(chain)) {
+			toHeight = int64(len(chain)) - 1
+		}
+
+		for height := fromHeight; height <= toHeight; height++ {
+			if !yieldMatches(chain[height], match, yield) {
+				return
+			}
+		}
+	}
+}
+
+// FindTransactions iterates over stored transactions matching a predicate within the block heights
+// [fromHeight, toHeight] (a negative toHeight means the latest block), loading one block at a time.
+// Iteration stops at the first database error, which is then returned by the error function.
+func (d *Database) FindTransactions(match TransactionMatcher, fromHeight, toHeight int64) (iter.Seq2[*Transaction, *Block], func() error) {
+	var iterErr error
+
+	seq := func(yield func(*Transaction, *Block) bool) {
+		iterErr = nil
+
+		query := "SELECT block_data FROM blocks WHERE block_index >= ? ORDER BY block_index ASC"
+		args := []interface{}{fromHeight}
+		if toHeight >= 0 {
+			query = "SELECT block_data FROM blocks WHERE block_index >= ? AND block_index <= ? ORDER BY block_index ASC"
+			args = append(args, toHeight)
+		}
+
+		rows, err := d.db.Query(d.rebind(query), args...)
+		if err != nil {
+			iterErr = err
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var blockData string
+			if err := rows.Scan(&blockData); err != nil {
+				iterErr = err
+				return
+			}
+
+			block, err := unpruned(decodeStoredBlock(blockData))
+			if err != nil {
+				iterErr = err
+				return
+			}
+
+			if !yieldMatches(block, match, yield) {
+				return
+			}
+		}
+		iterErr = rows.Err()
+	}
+
+	return seq, func() error { return iterErr }
+}
+
+// yieldMatches yields the matching transactions of a block, reporting whether iteration should continue
+func yieldMatches(block *Block, match TransactionMatcher, yield func(*Transaction, *Block) bool) bool {
+	for i := range block.Transactions {
+		tx := &block.Transactions[i]
+		if match != nil && !match(tx, block) {
+			continue
+		}
+		if !yield(tx, block) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+package blockchain
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrTransactionNotFound is returned for transactions the database doesn't hold
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+// TransactionRecord is a confirmed transaction with its place in the chain
+type TransactionRecord struct {
+	Transaction
+	BlockIndex     int64  `json:"blockIndex"`
+	BlockHash      string `json:"blockHash"`
+	TxIndex        int    `json:"txIndex"`        // Position in the block
+	BlockTimestamp int64  `json:"blockTimestamp"` // Block time, not the time the row was written
+}
+
+// TransactionPage is one page of a transaction query
+type TransactionPage struct {
+	Transactions []TransactionRecord `json:"transactions"`
+	Offset       int                 `json:"offset"`
+	Total        int                 `json:"total"` // Matching transactions across all pages
+}
+
+// transactionColumns are the columns scanned by scanTransactionRecords, read from the
+// transactions table joined with the blocks table
+const transactionColumns = "t.block_index, t.block_hash, t.tx_index, b.timestamp, t.transaction_data"
+
+// GetTransactionByHash returns a confirmed transaction, or ErrTransactionNotFound.
+// Transactions of pruned blocks are gone from the table and not found.
+func (d *Database) GetTransactionByHash(hash string) (*TransactionRecord, error) {
+	rows, err := d.db.Query(d.rebind(`
+		SELECT `+transactionColumns+`
+		FROM transactions t JOIN blocks b ON b.hash = t.block_hash
+		WHERE t.hash = ?`), hash)
+	if err != nil {
+		return nil, err
+	}
+	records, err := scanTransactionRecords(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, ErrTransactionNotFound
+	}
+	return &records[0], nil
+}
+
+// GetTransactionsByAddress returns a page of the transactions sent or received by an
+// address, newest first. limit is capped at QueryMaxRows (0 or less returns the cap).
+func (d *Database) GetTransactionsByAddress(address string, limit, offset int) (*TransactionPage, error) {
+	return d.transactionPage(
+		"t.from_address = ? OR t.to_address = ?", []interface{}{address, address},
+		"t.block_index DESC, t.tx_index DESC", limit, offset)
+}
+
+// GetTransactionsByTimeRange returns a page of the transactions in blocks timestamped between
+// from and to (inclusive, Unix seconds), oldest first. limit is capped at QueryMaxRows.
+func (d *Database) GetTransactionsByTimeRange(from, to int64, limit, offset int) (*TransactionPage, error) {
+	if from > to {
+		return nil, errors.New("time range starts after it ends")
+	}
+	return d.transactionPage(
+		"b.timestamp >= ? AND b.timestamp <= ?", []interface{}{from, to},
+		"t.block_index, t.tx_index", limit, offset)
+}
+
+// transactionPage runs a paginated query over the transactions matching a condition
+func (d *Database) transactionPage(where string, args []interface{}, orderBy string, limit, offset int) (*TransactionPage, error) {
+	if limit <= 0 || limit > QueryMaxRows {
+		limit = QueryMaxRows
+	}
+	offset = max(offset, 0)
+
+	page := &TransactionPage{Offset: offset, Transactions: []TransactionRecord{}}
+	from := " FROM transactions t JOIN blocks b ON b.hash = t.block_hash WHERE (" + where + ")"
+	if err := d.db.QueryRow(d.rebind("SELECT COUNT(*)"+from), args...).Scan(&page.Total); err != nil {
+		return nil, fmt.Errorf("failed to count transactions: %w", err)
+	}
+	if offset >= page.Total {
+		return page, nil
+	}
+
+	rows, err := d.db.Query(d.rebind("SELECT "+transactionColumns+from+" ORDER BY "+orderBy+" LIMIT ? OFFSET ?"),
+		append(args, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transactions: %w", err)
+	}
+	records, err := scanTransactionRecords(rows)
+	if err != nil {
+		return nil, err
+	}
+	page.Transactions = records
+	return page, nil
+}
+
+// scanTransactionRecords reads the rows of a query selecting transactionColumns and closes them
+func scanTransactionRecords(rows *sql.Rows) ([]TransactionRecord, error) {
+	defer rows.Close()
+
+	var records []TransactionRecord
+	for rows.Next() {
+		var record TransactionRecord
+		var data string
+		if err := rows.Scan(&record.BlockIndex, &record.BlockHash, &record.TxIndex, &record.BlockTimestamp, &data); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(data), &record.Transaction); err != nil {
+			return nil, fmt.Errorf("failed to deserialize transaction: %w", err)
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
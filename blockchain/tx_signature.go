@@ -0,0 +1,110 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnsignedTransaction is returned for transactions without an attached signature
+// when signatures are required
+var ErrUnsignedTransaction = errors.New("transaction is not signed")
+
+// VerifyTransactionSignature checks a signature over a transaction against its sender:
+// the public key (encoded by EncodePublicKey) must derive the From address, and the
+// signature must verify under it. Unlike Wallet.VerifyTransaction it needs no wallet,
+// so any node can validate a transaction from a stranger.
+func VerifyTransactionSignature(tx Transaction, publicKey, signature string) error {
+	return DefaultChainParams.VerifyTransactionSignature(tx, publicKey, signature)
+}
+
+// VerifyTransactionSignature checks a signature over a transaction against its sender,
+// deriving the sender address in the network's format
+func (p *ChainParams) VerifyTransactionSignature(tx Transaction, publicKey, signature string) error {
+	address, err := p.AddressFromPublicKey(publicKey)
+	if err != nil {
+		return fmt.Errorf("invalid public key: %w", err)
+	}
+	if address != tx.From {
+		return fmt.Errorf("public key belongs to %s, not to sender %s", address, tx.From)
+	}
+	if !VerifySignature(publicKey, signingMessage(tx), signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// VerifySignature checks the public key and signature attached to the transaction
+func (tx *Transaction) VerifySignature() error {
+	if tx.PublicKey == "" || tx.Signature == "" {
+		return ErrUnsignedTransaction
+	}
+	return VerifyTransactionSignature(*tx, tx.PublicKey, tx.Signature)
+}
+
+// AttachSignature signs a transaction sent from the wallet and attaches the signature
+// and public key, so nodes can verify it without the wallet
+func (w *Wallet) AttachSignature(tx *Transaction) error {
+	if tx.From != w.Address {
+		return fmt.Errorf("transaction is sent from %s, not from this wallet", tx.From)
+	}
+	signature, err := w.SignTransaction(*tx)
+	if err != nil {
+		return err
+	}
+	tx.PublicKey = w.EncodedPublicKey()
+	tx.Signature = signature
+	return nil
+}
+
+// checkTransactionSignature verifies an attached signature against the sender address in the
+// network's format, and the sponsor's signature of a sponsored transaction. Coinbase transactions
+// have no signer; other unsigned transactions are only accepted when signatures aren't required.
+func checkTransactionSignature(tx *Transaction, required bool, params *ChainParams) error {
+	if err := checkSponsorship(tx, params); err != nil {
+		return err
+	}
+	if tx.IsCoinbase() {
+		return nil
+	}
+	if tx.PublicKey == "" && tx.Signature == "" {
+		if required {
+			return ErrUnsignedTransaction
+		}
+		return nil
+	}
+	return params.VerifyTransactionSignature(*tx, tx.PublicKey, tx.Signature)
+}
+
+// checkBlockSignatures verifies the signatures of every transaction in a block. Signatures are
+// required if the caller asks for them or a RequireSignatures rule is active at the block's height.
+func checkBlockSignatures(block *Block, required bool, upgrades *UpgradeSchedule, params *ChainParams) error {
+	required = required || upgrades.RequiresSignatures(block.Index)
+	for i := range block.Transactions {
+		tx := &block.Transactions[i]
+		if err := checkTransactionSignature(tx, required, params); err != nil {
+			return fmt.Errorf("transaction %s: %w", tx.Hash, err)
+		}
+	}
+	return nil
+}
+
+// SetRequireSignatures makes pool admission and newly connected blocks reject unsigned
+// transactions. Attached signatures are verified either way.
+func (bc *Blockchain) SetRequireSignatures(required bool) {
+	bc.signedOnly = required
+	bc.TransactionPool.SetRequireSignatures(required)
+}
+
+// SetRequireSignatures makes pool admission and newly connected blocks reject unsigned
+// transactions. Attached signatures are verified either way.
+func (pbc *PersistentBlockchain) SetRequireSignatures(required bool) {
+	pbc.signedOnly = required
+	pbc.TransactionPool.SetRequireSignatures(required)
+}
+
+// SetRequireSignatures makes the pool reject unsigned transactions
+func (tp *Mempool) SetRequireSignatures(required bool) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	tp.signedOnly = required
+}
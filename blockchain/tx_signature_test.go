@@ -0,0 +1,97 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+)
+
+// signedTransaction returns a transaction from a new wallet with its signature attached
+func signedTransaction(t *testing.T, nonce int64) (*Wallet, *Transaction) {
+	t.Helper()
+	wallet, err := NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := NewSponsoredTransaction(wallet.Address, "bob", 3, 0.1, nonce, "")
+	if err := wallet.AttachSignature(tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.VerifySignature(); err != nil {
+		t.Fatal(err)
+	}
+	return wallet, tx
+}
+
+// assertTamperingBreaksSignature changes a signed transaction, recomputes its hash as anyone
+// relaying it could, and checks the original signature no longer verifies
+func assertTamperingBreaksSignature(t *testing.T, tx *Transaction, field string, tamper func(*Transaction)) {
+	t.Helper()
+	forged := *tx
+	tamper(&forged)
+	forged.Hash = forged.calculateHash()
+	if forged.Hash == tx.Hash {
+		t.Fatalf("%s: tampering left the hash unchanged", field)
+	}
+	if err := forged.VerifySignature(); err == nil {
+		t.Fatalf("signature still verifies with the %s changed", field)
+	}
+}
+
+func TestSignatureCoversHashedFields(t *testing.T) {
+	_, tx := signedTransaction(t, 1)
+	for field, tamper := range map[string]func(*Transaction){
+		"amount":    func(tx *Transaction) { tx.Amount = 30 },
+		"recipient": func(tx *Transaction) { tx.To = "mallory" },
+		"fee":       func(tx *Transaction) { tx.Fee = 0.2 },
+		"nonce":     func(tx *Transaction) { tx.Nonce = 2 },
+		"data":      func(tx *Transaction) { tx.Data = []byte("memo") },
+		"sponsor":   func(tx *Transaction) { tx.Sponsor = "carol" },
+	} {
+		assertTamperingBreaksSignature(t, tx, field, tamper)
+	}
+}
+
+func TestSponsorSignatureIsNotASenderSignature(t *testing.T) {
+	wallet, err := NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := NewSponsoredTransaction(wallet.Address, "bob", 3, 0.1, 1, wallet.Address)
+	if err := wallet.AttachSponsorSignature(tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyTransactionSignature(*tx, tx.SponsorKey, tx.SponsorSignature); err == nil {
+		t.Fatal("a sponsor signature verifies as the sender's")
+	}
+}
+
+func TestRequireSignaturesUpgrade(t *testing.T) {
+	schedule, err := NewUpgradeSchedule(Upgrade{Name: "signatures", Height: 3, Rules: []ConsensusRule{RequireSignatures()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := NewBlockchain(1, "miner")
+	source.Upgrades = schedule
+	source.MinePendingTransactions()
+	for nonce := int64(1); nonce <= 2; nonce++ {
+		if err := source.AddTransaction(NewSponsoredTransaction("miner", "bob", 1, 0.1, nonce, "")); err != nil {
+			t.Fatal(err)
+		}
+		source.MinePendingTransactions()
+	}
+
+	// The miner ignores the rule, but every node enforces it from the activation height on
+	target := NewBlockchain(1, "miner")
+	target.Upgrades = schedule
+	for _, block := range source.Chain[1:3] {
+		if err := target.AddBlock(block); err != nil {
+			t.Fatalf("unsigned transaction below the activation height: %v", err)
+		}
+	}
+	if err := target.AddBlock(source.Chain[3]); !errors.Is(err, ErrUnsignedTransaction) {
+		t.Fatalf("unsigned transaction at the activation height returned %v", err)
+	}
+	if source.IsChainValid() {
+		t.Fatal("chain with an unsigned transaction past the activation height is valid")
+	}
+}
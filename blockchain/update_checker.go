@@ -0,0 +1,177 @@
+package blockchain
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// ProtocolVersion is the P2P protocol version implemented by this node
+const ProtocolVersion = 2
+
+// MinProtocolVersion is the oldest P2P protocol version this node still talks to
+const MinProtocolVersion = 1
+
+// UpgradeAnnouncement advertises a scheduled protocol upgrade to peers
+type UpgradeAnnouncement struct {
+	Name    string `json:"name"`
+	Height  int64  `json:"height"`
+	Version int32  `json:"version"`
+}
+
+// PeerVersionInfo is the version information a peer advertised in its handshake
+type PeerVersionInfo struct {
+	ProtocolVersion int
+	Features        FeatureFlags
+	Upgrades        []UpgradeAnnouncement
+
+	// Chain the peer follows (empty for peers older than protocol version 2)
+	ChainID     string
+	GenesisHash string
+	BestHeight  int64
+}
+
+// UpdateWarning describes a detected mismatch between this node and the network
+type UpdateWarning struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// Kinds of update warnings
+const (
+	WarningNewerProtocol    = "newer-protocol"
+	WarningUnknownUpgrade   = "unknown-upgrade"
+	WarningPeersNotUpgraded = "peers-not-upgraded"
+)
+
+// UpdateChecker compares this node's protocol version and upgrade schedule with the network majority
+type UpdateChecker struct {
+	schedule   *UpgradeSchedule
+	peers      map[string]PeerVersionInfo // Keyed by node ID
+	warnWindow int64                      // Blocks before an activation height to start warning
+	mu         sync.RWMutex
+}
+
+// NewUpdateChecker creates a new update checker for the given local upgrade schedule
+func NewUpdateChecker(schedule *UpgradeSchedule, warnWindow int64) *UpdateChecker {
+	if schedule == nil {
+		schedule = &UpgradeSchedule{}
+	}
+	return &UpdateChecker{
+		schedule:   schedule,
+		peers:      make(map[string]PeerVersionInfo),
+		warnWindow: warnWindow,
+	}
+}
+
+// RecordPeer stores the version information advertised by a peer
+func (uc *UpdateChecker) RecordPeer(nodeID string, info PeerVersionInfo) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	uc.peers[nodeID] = info
+}
+
+// RemovePeer forgets a disconnected peer
+func (uc *UpdateChecker) RemovePeer(nodeID string) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	delete(uc.peers, nodeID)
+}
+
+// Check compares local versions with the network at the given chain height,
+// logs and returns any warnings
+func (uc *UpdateChecker) Check(currentHeight int64) []UpdateWarning {
+	uc.mu.RLock()
+	defer uc.mu.RUnlock()
+
+	total := len(uc.peers)
+	if total == 0 {
+		return nil
+	}
+
+	var warnings []UpdateWarning
+
+	// Majority running a newer protocol version
+	newer := 0
+	highest := ProtocolVersion
+	for _, info := range uc.peers {
+		if info.ProtocolVersion > ProtocolVersion {
+			newer++
+			if info.ProtocolVersion > highest {
+				highest = info.ProtocolVersion
+			}
+		}
+	}
+	if newer*2 > total {
+		warnings = append(warnings, UpdateWarning{
+			Kind: WarningNewerProtocol,
+			Message: fmt.Sprintf("%d of %d peers run protocol version %d or newer (local version %d), please update your node",
+				newer, total, highest, ProtocolVersion),
+		})
+	}
+
+	// Upgrades announced by the majority that this node doesn't know about
+	announced := make(map[string]UpgradeAnnouncement)
+	counts := make(map[string]int)
+	for _, info := range uc.peers {
+		for _, upgrade := range info.Upgrades {
+			announced[upgrade.Name] = upgrade
+			counts[upgrade.Name]++
+		}
+	}
+
+	known := make(map[string]bool)
+	for _, upgrade := range uc.schedule.GetUpgrades() {
+		known[upgrade.Name] = true
+	}
+
+	for name, upgrade := range announced {
+		if known[name] || counts[name]*2 <= total || upgrade.Height <= currentHeight-uc.warnWindow {
+			continue
+		}
+		if currentHeight >= upgrade.Height-uc.warnWindow {
+			warnings = append(warnings, UpdateWarning{
+				Kind: WarningUnknownUpgrade,
+				Message: fmt.Sprintf("upgrade %s activates at height %d (%d blocks away) and is not supported by this node; blocks will be rejected by upgraded peers",
+					name, upgrade.Height, upgrade.Height-currentHeight),
+			})
+		}
+	}
+
+	// Local upgrades approaching that most peers don't announce
+	for _, upgrade := range uc.schedule.GetUpgrades() {
+		if upgrade.Height <= currentHeight || currentHeight < upgrade.Height-uc.warnWindow {
+			continue
+		}
+		if counts[upgrade.Name]*2 <= total {
+			warnings = append(warnings, UpdateWarning{
+				Kind: WarningPeersNotUpgraded,
+				Message: fmt.Sprintf("upgrade %s activates at height %d but only %d of %d peers announce it",
+					upgrade.Name, upgrade.Height, counts[upgrade.Name], total),
+			})
+		}
+	}
+
+	for _, warning := range warnings {
+		log.Printf("Warning: %s", warning.Message)
+	}
+	return warnings
+}
+
+// upgradeAnnouncements lists the scheduled upgrades of a schedule for the handshake
+func upgradeAnnouncements(schedule *UpgradeSchedule) []UpgradeAnnouncement {
+	if schedule == nil {
+		return nil
+	}
+
+	upgrades := schedule.GetUpgrades()
+	announcements := make([]UpgradeAnnouncement, len(upgrades))
+	for i, upgrade := range upgrades {
+		announcements[i] = UpgradeAnnouncement{
+			Name:    upgrade.Name,
+			Height:  upgrade.Height,
+			Version: upgrade.Version,
+		}
+	}
+	return announcements
+}
@@ -0,0 +1,139 @@
+package verify
+
+import (
+	"math"
+	"strconv"
+	"unicode/utf8"
+)
+
+// hexDigits are the digits of \u escapes, lowercase like encoding/json
+const hexDigits = "0123456789abcdef"
+
+// object builds a compact JSON object byte for byte like encoding/json encodes a struct,
+// without reflection so it compiles under TinyGo
+type object struct {
+	buf []byte
+	err bool // A float had no JSON representation
+}
+
+// newObject starts an object
+func newObject() *object {
+	return &object{buf: []byte{'{'}}
+}
+
+// appendObject starts an object at the end of buf
+func appendObject(buf []byte) *object {
+	return &object{buf: append(buf, '{')}
+}
+
+// key appends a field name
+func (o *object) key(name string) {
+	if o.buf[len(o.buf)-1] != '{' {
+		o.buf = append(o.buf, ',')
+	}
+	o.buf = appendString(o.buf, name)
+	o.buf = append(o.buf, ':')
+}
+
+// str appends a string field
+func (o *object) str(name, value string) {
+	o.key(name)
+	o.buf = appendString(o.buf, value)
+}
+
+// int appends an integer field
+func (o *object) int(name string, value int64) {
+	o.key(name)
+	o.buf = strconv.AppendInt(o.buf, value, 10)
+}
+
+// float appends a float field
+func (o *object) float(name string, value float64) {
+	o.key(name)
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		o.err = true
+		return
+	}
+	o.buf = appendFloat(o.buf, value)
+}
+
+// bytes closes the object, returning nil if a value couldn't be encoded
+func (o *object) bytes() []byte {
+	if o.err {
+		return nil
+	}
+	return append(o.buf, '}')
+}
+
+// appendFloat formats a float the way encoding/json does: the shortest representation,
+// with exponent notation below 1e-6 and from 1e21, and no leading zero in the exponent
+func appendFloat(buf []byte, f float64) []byte {
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	buf = strconv.AppendFloat(buf, f, format, -1, 64)
+	if format == 'e' {
+		// Clean up e-09 to e-9
+		n := len(buf)
+		if n >= 4 && buf[n-4] == 'e' && buf[n-3] == '-' && buf[n-2] == '0' {
+			buf[n-2] = buf[n-1]
+			buf = buf[:n-1]
+		}
+	}
+	return buf
+}
+
+// appendString quotes a string the way encoding/json does: <, > and & are escaped for HTML,
+// U+2028 and U+2029 for JavaScript, and invalid UTF-8 becomes U+FFFD
+func appendString(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' && b != '<' && b != '>' && b != '&' {
+				i++
+				continue
+			}
+			buf = append(buf, s[start:i]...)
+			switch b {
+			case '"', '\\':
+				buf = append(buf, '\\', b)
+			case '\b':
+				buf = append(buf, '\\', 'b')
+			case '\f':
+				buf = append(buf, '\\', 'f')
+			case '\n':
+				buf = append(buf, '\\', 'n')
+			case '\r':
+				buf = append(buf, '\\', 'r')
+			case '\t':
+				buf = append(buf, '\\', 't')
+			default:
+				buf = append(buf, '\\', 'u', '0', '0', hexDigits[b>>4], hexDigits[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if c == utf8.RuneError && size == 1 {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if c == '\u2028' || c == '\u2029' {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, '\\', 'u', '2', '0', '2', hexDigits[c&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf = append(buf, s[start:]...)
+	return append(buf, '"')
+}
@@ -0,0 +1,226 @@
+package verify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Merkle tree schemes
+const (
+	// MerkleLegacy hashes leaves as they are, like inner nodes, and duplicates the last node
+	// of an odd level. An inner node can pass for a leaf, and a list ending in a repeated
+	// leaf has the root of the list without it.
+	MerkleLegacy = 0
+
+	// MerkleTagged hashes leaves and inner nodes under distinct prefix bytes and promotes the
+	// last node of an odd level unchanged to the level above
+	MerkleTagged = 1
+)
+
+// Prefix bytes of MerkleTagged hashes
+const (
+	leafPrefix  = "\x00"
+	innerPrefix = "\x01"
+)
+
+// NodeHash returns the hash of a Merkle tree node: the SHA-256 of its children's hex hashes concatenated
+func NodeHash(leftHash, rightHash string) string {
+	hash := sha256.Sum256([]byte(leftHash + rightHash))
+	return hex.EncodeToString(hash[:])
+}
+
+// TaggedLeafHash returns the hash of a MerkleTagged leaf: the SHA-256 of a zero byte and the leaf
+func TaggedLeafHash(leaf string) string {
+	hash := sha256.Sum256([]byte(leafPrefix + leaf))
+	return hex.EncodeToString(hash[:])
+}
+
+// TaggedNodeHash returns the hash of a MerkleTagged inner node: the SHA-256 of a one byte and
+// its children's hex hashes concatenated
+func TaggedNodeHash(leftHash, rightHash string) string {
+	hash := sha256.Sum256([]byte(innerPrefix + leftHash + rightHash))
+	return hex.EncodeToString(hash[:])
+}
+
+// MerkleRoot computes the root over leaf hashes ("" for no leaves).
+// A level with an odd number of nodes duplicates its last node.
+func MerkleRoot(leaves []string) string {
+	return SchemeMerkleRoot(MerkleLegacy, leaves)
+}
+
+// SchemeMerkleRoot computes the root over leaf hashes under a scheme ("" for no leaves)
+func SchemeMerkleRoot(scheme int, leaves []string) string {
+	if len(leaves) == 0 {
+		return ""
+	}
+
+	level := append([]string(nil), leaves...)
+	if scheme == MerkleTagged {
+		for i, leaf := range level {
+			level[i] = TaggedLeafHash(leaf)
+		}
+	}
+	for {
+		if scheme == MerkleTagged && len(level) == 1 {
+			return level[0]
+		}
+		if scheme != MerkleTagged && len(level)%2 != 0 {
+			level = append(level, level[len(level)-1])
+		}
+		next := make([]string, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, schemeNodeHash(scheme, level[i], level[i+1]))
+		}
+		if scheme != MerkleTagged && len(next) == 1 {
+			return next[0]
+		}
+		level = next
+	}
+}
+
+// schemeNodeHash returns the hash of an inner node under a scheme
+func schemeNodeHash(scheme int, leftHash, rightHash string) string {
+	if scheme == MerkleTagged {
+		return TaggedNodeHash(leftHash, rightHash)
+	}
+	return NodeHash(leftHash, rightHash)
+}
+
+// schemeLeafHash returns the hash of a leaf node under a scheme
+func schemeLeafHash(scheme int, leaf string) string {
+	if scheme == MerkleTagged {
+		return TaggedLeafHash(leaf)
+	}
+	return leaf
+}
+
+// VerifyProof checks that a leaf hashes up to the root through its siblings, listed from the
+// leaf upwards. isLeft tells whether each sibling sits on the left.
+func VerifyProof(leaf string, siblings []string, isLeft []bool, root string) bool {
+	return VerifySchemeProof(MerkleLegacy, leaf, siblings, isLeft, root)
+}
+
+// VerifySchemeProof checks a proof like VerifyProof under a scheme. Levels at which a
+// MerkleTagged path is promoted have no sibling in the proof.
+func VerifySchemeProof(scheme int, leaf string, siblings []string, isLeft []bool, root string) bool {
+	if len(siblings) != len(isLeft) {
+		return false
+	}
+
+	current := schemeLeafHash(scheme, leaf)
+	for i, sibling := range siblings {
+		if isLeft[i] {
+			current = schemeNodeHash(scheme, sibling, current)
+		} else {
+			current = schemeNodeHash(scheme, current, sibling)
+		}
+	}
+	return current == root
+}
+
+// VerifyMultiProof checks that several leaves of a tree over leafCount leaves hash up to the
+// root under a scheme. indices are the strictly increasing positions of the leaves, and
+// siblings the hashes of the other nodes needed, in the order a level-by-level walk from the
+// leaves consumes them.
+func VerifyMultiProof(scheme int, leaves []string, indices []int, leafCount int, siblings []string, root string) bool {
+	if len(leaves) == 0 || len(leaves) != len(indices) {
+		return false
+	}
+	for i, index := range indices {
+		if index < 0 || index >= leafCount || (i > 0 && index <= indices[i-1]) {
+			return false
+		}
+	}
+
+	positions := append([]int(nil), indices...)
+	hashes := make([]string, len(leaves))
+	for i, leaf := range leaves {
+		hashes[i] = schemeLeafHash(scheme, leaf)
+	}
+	for width := leafCount; scheme != MerkleTagged || width > 1; {
+		nextPositions := positions[:0:0]
+		nextHashes := hashes[:0:0]
+		for i := 0; i < len(positions); i++ {
+			position, hash := positions[i], hashes[i]
+			var left, right string
+			switch {
+			case position%2 == 1:
+				// Our left sibling would have been paired already had it been known
+				if len(siblings) == 0 {
+					return false
+				}
+				left, right = siblings[0], hash
+				siblings = siblings[1:]
+			case position+1 == width && scheme == MerkleTagged:
+				// The last node of an odd level is promoted
+				nextPositions = append(nextPositions, position/2)
+				nextHashes = append(nextHashes, hash)
+				continue
+			case position+1 == width:
+				// The last node of an odd level is paired with itself
+				left, right = hash, hash
+			case i+1 < len(positions) && positions[i+1] == position+1:
+				left, right = hash, hashes[i+1]
+				i++
+			default:
+				if len(siblings) == 0 {
+					return false
+				}
+				left, right = hash, siblings[0]
+				siblings = siblings[1:]
+			}
+			nextPositions = append(nextPositions, position/2)
+			nextHashes = append(nextHashes, schemeNodeHash(scheme, left, right))
+		}
+		positions, hashes = nextPositions, nextHashes
+
+		width = (width + 1) / 2
+		if width == 1 {
+			break
+		}
+	}
+	return len(siblings) == 0 && hashes[0] == root
+}
+
+// VerifyPositionedProof checks a proof like VerifySchemeProof and also that it is the path of
+// the leaf at index in a tree over leafCount leaves: every direction matches the position,
+// a legacy level pairing the node with itself lists the node as its sibling, and a tagged
+// level promoting the node lists nothing. Proofs of adjacent leaves checked this way show
+// that no leaf sits between them.
+func VerifyPositionedProof(scheme int, leaf string, index, leafCount int, siblings []string, isLeft []bool, root string) bool {
+	if index < 0 || index >= leafCount || len(siblings) != len(isLeft) {
+		return false
+	}
+
+	current := schemeLeafHash(scheme, leaf)
+	position, used := index, 0
+	for width := leafCount; scheme != MerkleTagged || width > 1; {
+		last := position%2 == 0 && position+1 == width
+		if !last || scheme != MerkleTagged {
+			if used == len(siblings) || isLeft[used] != (position%2 == 1) {
+				return false
+			}
+			sibling := siblings[used]
+			used++
+			if last && sibling != current {
+				return false
+			}
+			if position%2 == 1 {
+				current = schemeNodeHash(scheme, sibling, current)
+			} else {
+				current = schemeNodeHash(scheme, current, sibling)
+			}
+		}
+
+		position /= 2
+		width = (width + 1) / 2
+		if width == 1 {
+			break
+		}
+	}
+	return used == len(siblings) && current == root
+}
@@ -0,0 +1,55 @@
+package verify
+
+import "math/bits"
+
+// MMRRoot bags the peaks of a Merkle mountain range, listed left to right, into its root:
+// each peak is hashed with the bag of the peaks to its right ("" for no peaks)
+func MMRRoot(peaks []string) string {
+	if len(peaks) == 0 {
+		return ""
+	}
+	root := peaks[len(peaks)-1]
+	for i := len(peaks) - 2; i >= 0; i-- {
+		root = NodeHash(peaks[i], root)
+	}
+	return root
+}
+
+// VerifyMMRProof checks that a leaf sits at leafIndex of the Merkle mountain range of leafCount
+// leaves with the given root. The range has one perfect tree per set bit of leafCount, largest
+// first; siblings lead from the leaf up to the peak of its tree and peaks lists every peak.
+func VerifyMMRProof(leaf string, leafIndex, leafCount int64, siblings, peaks []string, root string) bool {
+	if leafIndex < 0 || leafIndex >= leafCount || len(peaks) != bits.OnesCount64(uint64(leafCount)) {
+		return false
+	}
+
+	var offset int64
+	peak := 0
+	for height := 62; height >= 0; height-- {
+		size := int64(1) << height
+		if leafCount&size == 0 {
+			continue
+		}
+		if leafIndex >= offset+size {
+			offset += size
+			peak++
+			continue
+		}
+
+		if len(siblings) != height {
+			return false
+		}
+		current := leaf
+		position := leafIndex - offset
+		for _, sibling := range siblings {
+			if position&1 == 1 {
+				current = NodeHash(sibling, current)
+			} else {
+				current = NodeHash(current, sibling)
+			}
+			position >>= 1
+		}
+		return current == peaks[peak] && MMRRoot(peaks) == root
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package verify
+
+// Receipt holds the fields of a transaction receipt committed by a block's receipt root
+type Receipt struct {
+	TxHash   string
+	Status   int64
+	FeePayer string
+	Fee      float64
+	Events   []Event
+}
+
+// Event holds the fields of an event a transaction emitted
+type Event struct {
+	Type    string
+	Address string
+	Amount  float64
+	Data    string
+}
+
+// EncodeReceipt returns the canonical encoding of a receipt, its events as an array of objects
+// in order. It returns nil if an amount is not a finite number.
+func EncodeReceipt(r *Receipt) []byte {
+	o := newObject()
+	o.str("txHash", r.TxHash)
+	o.int("status", r.Status)
+	o.str("feePayer", r.FeePayer)
+	o.float("fee", r.Fee)
+	o.key("events")
+	o.buf = append(o.buf, '[')
+	for i := range r.Events {
+		e := &r.Events[i]
+		if i > 0 {
+			o.buf = append(o.buf, ',')
+		}
+		event := appendObject(o.buf)
+		event.str("type", e.Type)
+		event.str("address", e.Address)
+		event.float("amount", e.Amount)
+		event.str("data", e.Data)
+		if o.buf = event.bytes(); o.buf == nil {
+			return nil
+		}
+	}
+	o.buf = append(o.buf, ']')
+	return o.bytes()
+}
+
+// ReceiptLeafHash returns the Merkle leaf committing a receipt ("" if it can't be encoded)
+func ReceiptLeafHash(r *Receipt) string {
+	return HashEncoding(EncodeReceipt(r))
+}
@@ -0,0 +1,117 @@
+package verify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// StateKeyBits is the length in bits of a state trie key
+const StateKeyBits = 256
+
+// Account holds the state of an address committed by the state trie
+type Account struct {
+	Balance float64
+	Nonce   int64 // Highest nonce the address has sent
+}
+
+// EncodeAccount returns the canonical encoding of an account hashed into its trie leaf.
+// It returns nil if the balance is not a finite number.
+func EncodeAccount(a *Account) []byte {
+	o := newObject()
+	o.float("Balance", a.Balance)
+	o.int("Nonce", a.Nonce)
+	return o.bytes()
+}
+
+// StateKey returns the key of an address in the state trie: the hex SHA-256 of the address,
+// so keys are spread evenly and paths stay short
+func StateKey(address string) string {
+	hash := sha256.Sum256([]byte(address))
+	return hex.EncodeToString(hash[:])
+}
+
+// StateLeafHash returns the hash of a state trie leaf: the SHA-256 of a zero byte, the key
+// and the account encoding
+func StateLeafHash(key string, account []byte) string {
+	hash := sha256.Sum256(append([]byte(leafPrefix+key), account...))
+	return hex.EncodeToString(hash[:])
+}
+
+// StateBranchHash returns the hash of a state trie branch splitting its keys on a bit: the
+// SHA-256 of a one byte, the bit and its children's hex hashes concatenated. Keys with the
+// bit clear are on the left.
+func StateBranchHash(bit int, leftHash, rightHash string) string {
+	hash := sha256.Sum256([]byte(innerPrefix + string([]byte{byte(bit)}) + leftHash + rightHash))
+	return hex.EncodeToString(hash[:])
+}
+
+// StateKeyBit returns a bit of a state key, counted from the most significant (0 if the key
+// is not hex)
+func StateKeyBit(key string, bit int) int {
+	digit, ok := hexValue(key[bit/4])
+	if !ok {
+		return 0
+	}
+	return int(digit>>(3-bit%4)) & 1
+}
+
+// VerifyStatePath checks a path of the state trie with the given root. bits and siblings list
+// the branches from the root down: the bit each splits its keys on, strictly increasing, and
+// the hash of its child off the path. The path follows the bits of key and ends at the leaf of
+// leafKey and the account encoding leafAccount. If leafKey is key the path proves the key maps
+// to the account; otherwise it proves the key is absent, since the only key the trie holds with
+// the bits the path takes is leafKey. An empty leafKey and no branches prove an empty trie.
+func VerifyStatePath(key, leafKey string, leafAccount []byte, bits []int, siblings []string, root string) bool {
+	if len(bits) != len(siblings) || !isStateKey(key) {
+		return false
+	}
+	if leafKey == "" {
+		return len(bits) == 0 && root == ""
+	}
+	if !isStateKey(leafKey) {
+		return false
+	}
+
+	for i, bit := range bits {
+		if bit < 0 || bit >= StateKeyBits || (i > 0 && bit <= bits[i-1]) {
+			return false
+		}
+		if StateKeyBit(leafKey, bit) != StateKeyBit(key, bit) {
+			return false
+		}
+	}
+
+	current := StateLeafHash(leafKey, leafAccount)
+	for i := len(bits) - 1; i >= 0; i-- {
+		if StateKeyBit(key, bits[i]) == 1 {
+			current = StateBranchHash(bits[i], siblings[i], current)
+		} else {
+			current = StateBranchHash(bits[i], current, siblings[i])
+		}
+	}
+	return current == root
+}
+
+// isStateKey checks a key is a lowercase hex SHA-256
+func isStateKey(key string) bool {
+	if len(key) != StateKeyBits/4 {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if _, ok := hexValue(key[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// hexValue returns the value of a lowercase hex digit
+func hexValue(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	}
+	return 0, false
+}
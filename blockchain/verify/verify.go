@@ -0,0 +1,169 @@
+// Package verify holds the consensus hashing and verification rules of the node with no
+// dependencies beyond a small part of the standard library, so it compiles under TinyGo and
+// GOOS=js/wasip1 and browsers or embedded light clients verify exactly like the node does.
+//
+// Values are plain strings, numbers and byte slices; the blockchain package converts its types to these.
+package verify
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// Transaction holds the fields of a transaction covered by its hash
+type Transaction struct {
+	From   string
+	To     string
+	Amount float64
+	Fee    float64
+	Nonce  int64
+	Data   []byte
+
+	Sponsor string // Fee payer of a sponsored transaction, encoded only when set
+
+	ValidUntil int64 // Deadline of a transaction with a validity window, encoded only when set
+}
+
+// Header holds the fields of a block header
+type Header struct {
+	Version     int32
+	Index       int64
+	Timestamp   int64
+	PrevHash    string
+	Hash        string
+	Nonce       int64
+	MerkleRoot  string
+	KVRoot      string
+	MMRRoot     string // Root of the Merkle mountain range over the hashes of every earlier block
+	ExtRoot     string // Root of the block's extensions
+	StateRoot   string // Root of the state trie after every earlier block
+	ReceiptRoot string // Root of the receipts of the block's transactions
+}
+
+// HashEncoding returns the hex-encoded SHA-256 of a canonical encoding ("" for a nil encoding)
+func HashEncoding(data []byte) string {
+	if data == nil {
+		return ""
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// EncodeTransaction returns the canonical encoding hashed into the transaction hash.
+// Unsequenced transactions encode without the nonce and a payload always encodes the nonce.
+// The payload encodes as standard base64, like encoding/json encodes a byte slice.
+// It returns nil if an amount is not a finite number.
+func EncodeTransaction(tx *Transaction) []byte {
+	o := newObject()
+	o.str("From", tx.From)
+	o.str("To", tx.To)
+	o.float("Amount", tx.Amount)
+	o.float("Fee", tx.Fee)
+	if tx.Nonce != 0 || len(tx.Data) != 0 {
+		o.int("Nonce", tx.Nonce)
+	}
+	if len(tx.Data) != 0 {
+		o.str("Data", base64.StdEncoding.EncodeToString(tx.Data))
+	}
+	if tx.Sponsor != "" {
+		o.str("Sponsor", tx.Sponsor)
+	}
+	if tx.ValidUntil != 0 {
+		o.int("ValidUntil", tx.ValidUntil)
+	}
+	return o.bytes()
+}
+
+// TransactionHash returns the hash of a transaction
+func TransactionHash(tx *Transaction) string {
+	return HashEncoding(EncodeTransaction(tx))
+}
+
+// EncodeHeader returns the canonical encoding hashed into the block hash.
+// Legacy (version 0) headers encode without the version, and a header committing key-value
+// entries, the chain history, extensions, the state or receipts always encodes the version and appends the roots.
+func EncodeHeader(h *Header) []byte {
+	return AppendHeader(nil, h)
+}
+
+// AppendHeader appends the encoding of EncodeHeader to buf, so a caller hashing one header
+// over and over, like a miner trying nonces, can reuse a buffer
+func AppendHeader(buf []byte, h *Header) []byte {
+	o := appendObject(buf)
+	if h.Version != 0 || h.KVRoot != "" || h.MMRRoot != "" || h.ExtRoot != "" || h.StateRoot != "" || h.ReceiptRoot != "" {
+		o.int("Version", int64(h.Version))
+	}
+	o.int("Index", h.Index)
+	o.int("Timestamp", h.Timestamp)
+	o.str("MerkleRoot", h.MerkleRoot)
+	o.str("PrevHash", h.PrevHash)
+	o.int("Nonce", h.Nonce)
+	if h.KVRoot != "" {
+		o.str("KVRoot", h.KVRoot)
+	}
+	if h.MMRRoot != "" {
+		o.str("MMRRoot", h.MMRRoot)
+	}
+	if h.ExtRoot != "" {
+		o.str("ExtRoot", h.ExtRoot)
+	}
+	if h.StateRoot != "" {
+		o.str("StateRoot", h.StateRoot)
+	}
+	if h.ReceiptRoot != "" {
+		o.str("ReceiptRoot", h.ReceiptRoot)
+	}
+	return o.bytes()
+}
+
+// HeaderHash computes the hash of a header from its fields
+func HeaderHash(h *Header) string {
+	return HashEncoding(EncodeHeader(h))
+}
+
+// MeetsDifficulty checks a block hash starts with difficulty zeros
+func MeetsDifficulty(hash string, difficulty int) bool {
+	return strings.HasPrefix(hash, strings.Repeat("0", difficulty))
+}
+
+// ValidateHeader checks that a header correctly extends its parent: index, linkage,
+// hash and proof of work
+func ValidateHeader(h, parent *Header, difficulty int) error {
+	if h.Index != parent.Index+1 {
+		return errors.New("invalid header index")
+	}
+
+	if h.PrevHash != parent.Hash {
+		return errors.New("header does not link to parent hash")
+	}
+
+	if h.Hash != HeaderHash(h) {
+		return errors.New("invalid header hash")
+	}
+
+	if !MeetsDifficulty(h.Hash, difficulty) {
+		return errors.New("header hash does not meet difficulty target")
+	}
+
+	return nil
+}
+
+// KVLeafHash returns the Merkle leaf committing a key-value entry
+func KVLeafHash(namespace, key, value string) string {
+	o := newObject()
+	o.str("namespace", namespace)
+	o.str("key", key)
+	o.str("value", value)
+	return HashEncoding(o.bytes())
+}
+
+// ExtensionLeafHash returns the Merkle leaf committing a block extension
+func ExtensionLeafHash(extType, data string) string {
+	o := newObject()
+	o.str("type", extType)
+	o.str("data", data)
+	return HashEncoding(o.bytes())
+}
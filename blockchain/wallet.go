@@ -6,15 +6,19 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
-	"math/big"
-	"strconv"
+	"errors"
 )
 
-// Wallet represents a wallet in the blockchain
+// ErrNoPrivateKey is returned when signing with a wallet holding only a public key
+var ErrNoPrivateKey = errors.New("wallet has no private key")
+
+// Wallet represents a wallet in the blockchain.
+// P-256 wallets hold their key in PrivateKey; wallets of other schemes sign through their signer.
 type Wallet struct {
 	PrivateKey *ecdsa.PrivateKey
 	PublicKey  *ecdsa.PublicKey
 	Address    string
+	signer     Signer
 }
 
 // NewWallet creates a new wallet
@@ -46,44 +50,80 @@ func generateAddress(publicKey *ecdsa.PublicKey) string {
 	return hex.EncodeToString(hash[:])
 }
 
-// SignTransaction signs a transaction with the private key
-func (w *Wallet) SignTransaction(tx Transaction) (string, error) {
-	// Convert transaction to bytes
-	txBytes := []byte(tx.From + tx.To + strconv.FormatFloat(tx.Amount, 'f', -1, 64))
+// keySigner returns the signer of the wallet, its P-256 key unless it was created with another scheme
+func (w *Wallet) keySigner() Signer {
+	if w.signer != nil {
+		return w.signer
+	}
+	return p256Signer{key: w.PrivateKey}
+}
 
-	// Hash the transaction
-	hash := sha256.Sum256(txBytes)
+// Scheme returns the signature scheme of the wallet
+func (w *Wallet) Scheme() SignatureScheme {
+	if w.signer != nil {
+		return w.signer.Scheme()
+	}
+	return SchemeP256
+}
 
-	// Sign the hash
-	r, s, err := ecdsa.Sign(rand.Reader, w.PrivateKey, hash[:])
-	if err != nil {
-		return "", err
+// EncodedPublicKey returns the public key tagged with its scheme, as hex (see EncodePublicKey)
+func (w *Wallet) EncodedPublicKey() string {
+	if w.signer != nil {
+		return EncodePublicKey(w.signer.Scheme(), w.signer.PublicKey())
 	}
+	return EncodePublicKey(SchemeP256, elliptic.MarshalCompressed(elliptic.P256(), w.PublicKey.X, w.PublicKey.Y))
+}
 
-	// Combine r and s into a single signature
-	signature := append(r.Bytes(), s.Bytes()...)
+// wipe zeroes the private key held in memory
+func (w *Wallet) wipe() {
+	if holder, ok := w.signer.(keyHolder); ok {
+		holder.wipe()
+	}
+	if w.PrivateKey != nil {
+		w.PrivateKey.D.SetInt64(0)
+	}
+}
 
+// SignTransaction signs a transaction with the private key. P-256 signatures use RFC 6979
+// nonces and a low s, encoded as 64-byte r||s.
+func (w *Wallet) SignTransaction(tx Transaction) (string, error) {
+	if w.signer == nil && w.PrivateKey == nil {
+		return "", ErrNoPrivateKey
+	}
+	signature, err := w.keySigner().Sign(signingMessage(tx))
+	if err != nil {
+		return "", err
+	}
 	return hex.EncodeToString(signature), nil
 }
 
 // VerifyTransaction verifies a transaction signature
 func (w *Wallet) VerifyTransaction(tx Transaction, signature string) bool {
-	// Convert transaction to bytes
-	txBytes := []byte(tx.From + tx.To + strconv.FormatFloat(tx.Amount, 'f', -1, 64))
+	if w.signer != nil {
+		return VerifySignature(w.EncodedPublicKey(), signingMessage(tx), signature)
+	}
+	return verifyTransactionSignature(w.PublicKey, tx, signature)
+}
 
+// signingMessage returns the bytes the sender signs: the whole hashed encoding under a "tx:"
+// prefix, so the signature commits to the fee, nonce, payload, sponsor and validity window
+// as well as the payment, and can't pass for a sponsor's signature (see sponsorMessage)
+func signingMessage(tx Transaction) []byte {
+	return append([]byte("tx:"), tx.encode()...)
+}
+
+// verifyTransactionSignature checks a hex-encoded signature of a transaction,
+// either 64-byte r||s or DER, rejecting malleable high-s signatures
+func verifyTransactionSignature(publicKey *ecdsa.PublicKey, tx Transaction, signature string) bool {
 	// Hash the transaction
-	hash := sha256.Sum256(txBytes)
+	hash := sha256.Sum256(signingMessage(tx))
 
 	// Decode the signature
-	sigBytes, err := hex.DecodeString(signature)
+	r, s, err := parseSignatureHex(signature)
 	if err != nil {
 		return false
 	}
 
-	// Split signature into r and s
-	r := new(big.Int).SetBytes(sigBytes[:len(sigBytes)/2])
-	s := new(big.Int).SetBytes(sigBytes[len(sigBytes)/2:])
-
 	// Verify the signature
-	return ecdsa.Verify(w.PublicKey, hash[:], r, s)
+	return ecdsa.Verify(publicKey, hash[:], r, s)
 }
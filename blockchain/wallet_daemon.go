@@ -0,0 +1,202 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MaxUnlockTimeout caps how long a wallet daemon stays unlocked after a single Unlock
+const MaxUnlockTimeout = 24 * time.Hour
+
+// ErrWalletLocked is returned when signing is requested while the wallet daemon is locked
+var ErrWalletLocked = errors.New("wallet is locked")
+
+// WalletDaemon holds keystores for a long-running signing service. Private keys stay
+// encrypted in memory until Unlock is called and are dropped again when the unlock times out,
+// so local applications can request signatures without ever handling raw keys.
+type WalletDaemon struct {
+	dir       string
+	keystores map[string]*keystoreFile // Encrypted keys by address
+	unlocked  map[string]*Wallet       // Decrypted keys, only while unlocked
+	until     time.Time
+	relock    *time.Timer
+	mu        sync.Mutex
+}
+
+// OpenWalletDaemon loads every keystore file (*.json) of a directory, creating it if needed
+func OpenWalletDaemon(dir string) (*WalletDaemon, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	wd := &WalletDaemon{
+		dir:       dir,
+		keystores: make(map[string]*keystoreFile),
+	}
+	for _, path := range paths {
+		keystore, err := readKeystore(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load keystore %s: %w", path, err)
+		}
+		wd.keystores[keystore.Address] = keystore
+	}
+	return wd, nil
+}
+
+// Addresses returns the addresses of the loaded keystores, sorted
+func (wd *WalletDaemon) Addresses() []string {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+
+	addresses := make([]string, 0, len(wd.keystores))
+	for address := range wd.keystores {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+// NewAddress creates a wallet, stores it encrypted under the passphrase and returns its address.
+// The daemon must be unlocked so the new key uses the passphrase of the others.
+func (wd *WalletDaemon) NewAddress(passphrase string) (string, error) {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+
+	if wd.unlocked == nil {
+		return "", ErrWalletLocked
+	}
+	if err := wd.checkPassphrase(passphrase); err != nil {
+		return "", err
+	}
+
+	wallet, err := NewWallet()
+	if err != nil {
+		return "", err
+	}
+	keystore, err := encryptWallet(wallet, passphrase)
+	if err != nil {
+		return "", err
+	}
+	if err := writeKeystore(filepath.Join(wd.dir, wallet.Address+".json"), keystore); err != nil {
+		return "", err
+	}
+
+	wd.keystores[wallet.Address] = keystore
+	wd.unlocked[wallet.Address] = wallet
+	return wallet.Address, nil
+}
+
+// Unlock decrypts every keystore with the passphrase and keeps the keys available for
+// signing until the timeout elapses. Unlocking again extends or shortens the window.
+func (wd *WalletDaemon) Unlock(passphrase string, timeout time.Duration) error {
+	if timeout <= 0 || timeout > MaxUnlockTimeout {
+		return fmt.Errorf("unlock timeout must be between 0 and %s", MaxUnlockTimeout)
+	}
+
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+
+	unlocked := make(map[string]*Wallet, len(wd.keystores))
+	for address, keystore := range wd.keystores {
+		wallet, err := decryptWallet(keystore, passphrase)
+		if err != nil {
+			return err
+		}
+		unlocked[address] = wallet
+	}
+
+	if wd.relock != nil {
+		wd.relock.Stop()
+	}
+	wd.unlocked = unlocked
+	wd.until = time.Now().Add(timeout)
+	wd.relock = time.AfterFunc(timeout, wd.expire)
+	return nil
+}
+
+// Lock drops the decrypted keys immediately
+func (wd *WalletDaemon) Lock() {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	wd.lockLocked()
+}
+
+// expire relocks the daemon once its unlock window has passed. A timer that fired
+// while a new Unlock was extending the window leaves the keys available.
+func (wd *WalletDaemon) expire() {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	if wd.unlocked != nil && !time.Now().Before(wd.until) {
+		wd.lockLocked()
+	}
+}
+
+// lockLocked drops the decrypted keys (caller must hold the lock)
+func (wd *WalletDaemon) lockLocked() {
+	if wd.relock != nil {
+		wd.relock.Stop()
+		wd.relock = nil
+	}
+	for _, wallet := range wd.unlocked {
+		wallet.wipe()
+	}
+	wd.unlocked = nil
+	wd.until = time.Time{}
+}
+
+// Status reports whether the daemon is unlocked and until when
+func (wd *WalletDaemon) Status() (bool, time.Time) {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	return wd.unlocked != nil, wd.until
+}
+
+// SignTransaction signs a transaction with the key of its sender
+func (wd *WalletDaemon) SignTransaction(tx Transaction) (string, error) {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+
+	if wd.unlocked == nil {
+		return "", ErrWalletLocked
+	}
+	wallet, exists := wd.unlocked[tx.From]
+	if !exists {
+		return "", fmt.Errorf("no key for address %s", tx.From)
+	}
+	return wallet.SignTransaction(tx)
+}
+
+// AttachSignature signs a transaction with the key of its sender and attaches the
+// signature and public key to it
+func (wd *WalletDaemon) AttachSignature(tx *Transaction) error {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+
+	if wd.unlocked == nil {
+		return ErrWalletLocked
+	}
+	wallet, exists := wd.unlocked[tx.From]
+	if !exists {
+		return fmt.Errorf("no key for address %s", tx.From)
+	}
+	return wallet.AttachSignature(tx)
+}
+
+// checkPassphrase verifies the passphrase opens one of the existing keystores (caller must hold the lock)
+func (wd *WalletDaemon) checkPassphrase(passphrase string) error {
+	for _, keystore := range wd.keystores {
+		_, err := decryptWallet(keystore, passphrase)
+		return err
+	}
+	return nil
+}
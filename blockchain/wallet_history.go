@@ -0,0 +1,202 @@
+package blockchain
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// HistoryEntry is a transaction in the history of an address
+type HistoryEntry struct {
+	Hash          string         `json:"hash"`
+	Direction     WatchDirection `json:"direction"`
+	Source        EntrySource    `json:"source"` // Origin of the amount; the fee is always SourceFee
+	From          string         `json:"from"`
+	To            string         `json:"to"`
+	Amount        float64        `json:"amount"`
+	Fee           float64        `json:"fee"`
+	Sponsor       string         `json:"sponsor,omitempty"`
+	Net           float64        `json:"net"` // Change to the address: amount received minus amount and fee sent
+	Nonce         int64          `json:"nonce,omitempty"`
+	Timestamp     int64          `json:"timestamp"`  // Block time, or pool admission time while pending
+	BlockIndex    int64          `json:"blockIndex"` // -1 while pending
+	BlockHash     string         `json:"blockHash,omitempty"`
+	Confirmations int64          `json:"confirmations"` // 0 while pending
+	Pending       bool           `json:"pending"`
+}
+
+// HistoryPage is a page of an address history, newest first: pending transactions, then
+// confirmed ones from the chain tip down
+type HistoryPage struct {
+	Address string         `json:"address"`
+	Entries []HistoryEntry `json:"entries"`
+	Offset  int            `json:"offset"`
+	Total   int            `json:"total"` // Entries across all pages
+	Pruned  int64          `json:"pruned,omitempty"`
+}
+
+// HistorySource is the node surface a wallet reads its history from.
+// Both *Blockchain and *PersistentBlockchain implement it.
+type HistorySource interface {
+	GetTransactionHistory(address string, offset, limit int) *HistoryPage
+}
+
+// History returns a page of the wallet's transaction history
+func (w *Wallet) History(node HistorySource, offset, limit int) *HistoryPage {
+	return node.GetTransactionHistory(w.Address, offset, limit)
+}
+
+// GetTransactionHistory returns a page of the confirmed and pending transactions of an address.
+// A limit of 0 or less returns every entry from the offset on.
+func (bc *Blockchain) GetTransactionHistory(address string, offset, limit int) *HistoryPage {
+	return paginateHistory(address, addressHistory(bc.Chain, bc.TransactionPool, address), offset, limit)
+}
+
+// GetTransactionHistory returns a page of the confirmed and pending transactions of an address.
+// A limit of 0 or less returns every entry from the offset on. On a pruned chain the page's
+// Pruned height is set: transactions of the pruned blocks below it are missing.
+func (pbc *PersistentBlockchain) GetTransactionHistory(address string, offset, limit int) *HistoryPage {
+	page := paginateHistory(address, addressHistory(pbc.Chain, pbc.TransactionPool, address), offset, limit)
+	page.Pruned = pbc.PrunedHeight()
+	return page
+}
+
+// addressHistory collects the history of an address, newest first
+func addressHistory(chain []*Block, pool *TransactionPool, address string) []HistoryEntry {
+	var pending []HistoryEntry
+	for _, tx := range pool.GetTransactions() {
+		if !tx.involves(address) {
+			continue
+		}
+		entry := newHistoryEntry(tx, address)
+		entry.Timestamp, _ = pool.GetAddedTime(tx.Hash)
+		entry.BlockIndex = -1
+		entry.Pending = true
+		pending = append(pending, entry)
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].Timestamp > pending[j].Timestamp
+	})
+
+	entries := pending
+	tip := int64(len(chain)) - 1
+	for height := tip; height >= 0; height-- {
+		block := chain[height]
+		for i := len(block.Transactions) - 1; i >= 0; i-- {
+			tx := &block.Transactions[i]
+			if !tx.involves(address) {
+				continue
+			}
+			entry := newHistoryEntry(tx, address)
+			entry.Timestamp = block.Timestamp
+			entry.BlockIndex = block.Index
+			entry.BlockHash = block.Hash
+			entry.Confirmations = tip - block.Index + 1
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// newHistoryEntry describes a transaction from the point of view of an address
+func newHistoryEntry(tx *Transaction, address string) HistoryEntry {
+	entry := HistoryEntry{
+		Hash:    tx.Hash,
+		From:    tx.From,
+		To:      tx.To,
+		Amount:  tx.Amount,
+		Fee:     tx.Fee,
+		Sponsor: tx.Sponsor,
+		Nonce:   tx.Nonce,
+		Source:  TransactionSource(tx),
+	}
+	switch {
+	case tx.From == address && tx.To == address:
+		entry.Direction = WatchSelf
+	case tx.From == address || tx.Sponsor == address:
+		entry.Direction = WatchOutgoing
+	default:
+		entry.Direction = WatchIncoming
+	}
+	entry.Net = -tx.debit(address)
+	if tx.To == address {
+		entry.Net += tx.Amount
+	}
+	return entry
+}
+
+// involves reports whether a transaction sends from, pays to or sponsors an address
+func (tx *Transaction) involves(address string) bool {
+	return tx.From == address || tx.To == address || tx.Sponsor == address
+}
+
+// paginateHistory cuts a page out of an address history
+func paginateHistory(address string, entries []HistoryEntry, offset, limit int) *HistoryPage {
+	offset = max(offset, 0)
+	page := &HistoryPage{Address: address, Offset: offset, Total: len(entries), Entries: []HistoryEntry{}}
+	if offset >= len(entries) {
+		return page
+	}
+	end := len(entries)
+	if limit > 0 {
+		end = min(offset+limit, end)
+	}
+	page.Entries = entries[offset:end]
+	return page
+}
+
+// historyCSVHeader is the column row of CSV history exports
+var historyCSVHeader = []string{
+	"date", "hash", "direction", "source", "from", "to", "amount", "fee", "net",
+	"nonce", "block", "block_hash", "confirmations", "status",
+}
+
+// WriteHistoryCSV writes history entries as CSV for accounting tools, dates in UTC RFC 3339
+func WriteHistoryCSV(w io.Writer, entries []HistoryEntry) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(historyCSVHeader); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		status, block := "confirmed", strconv.FormatInt(entry.BlockIndex, 10)
+		if entry.Pending {
+			status, block = "pending", ""
+		}
+		record := []string{
+			time.Unix(entry.Timestamp, 0).UTC().Format(time.RFC3339),
+			entry.Hash,
+			string(entry.Direction),
+			string(entry.Source),
+			entry.From,
+			entry.To,
+			strconv.FormatFloat(entry.Amount, 'f', -1, 64),
+			strconv.FormatFloat(entry.Fee, 'f', -1, 64),
+			strconv.FormatFloat(entry.Net, 'f', -1, 64),
+			strconv.FormatInt(entry.Nonce, 10),
+			block,
+			entry.BlockHash,
+			strconv.FormatInt(entry.Confirmations, 10),
+			status,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteHistoryJSON writes history entries as an indented JSON array
+func WriteHistoryJSON(w io.Writer, entries []HistoryEntry) error {
+	if entries == nil {
+		entries = []HistoryEntry{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
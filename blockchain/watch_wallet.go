@@ -0,0 +1,428 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WatchSource is the chain surface a watch-only wallet follows
+type WatchSource interface {
+	GetLatestBlock() *Block
+	GetBlockByIndex(index int64) (*Block, error)
+}
+
+// WatchDirection tells how a transaction affects a watched address
+type WatchDirection string
+
+const (
+	WatchIncoming WatchDirection = "incoming"
+	WatchOutgoing WatchDirection = "outgoing"
+	WatchSelf     WatchDirection = "self" // Sent from the address to itself
+)
+
+// WatchedTransaction is a confirmed transaction involving a watched address
+type WatchedTransaction struct {
+	Transaction
+	Direction  WatchDirection `json:"direction"`
+	BlockIndex int64          `json:"blockIndex"`
+	BlockHash  string         `json:"blockHash"`
+	Timestamp  int64          `json:"timestamp"`
+}
+
+// WatchedAddress is the tracked state of an address. Balances follow GetBalance, counting amounts only.
+type WatchedAddress struct {
+	Address  string               `json:"address"`
+	Label    string               `json:"label,omitempty"`
+	Balance  float64              `json:"balance"`
+	Received float64              `json:"received"`
+	Sent     float64              `json:"sent"`
+	History  []WatchedTransaction `json:"history"`
+}
+
+// WatchEvent notifies subscribers of a new confirmed transaction involving a watched address
+type WatchEvent struct {
+	Address     string
+	Transaction WatchedTransaction
+}
+
+// WatchOnlyWalletConfig configures a watch-only wallet
+type WatchOnlyWalletConfig struct {
+	PollInterval time.Duration // How often the chain tip is checked for new blocks
+}
+
+// WatchOnlyWallet tracks balances and history of addresses it holds no keys for.
+// Newly added addresses are rescanned from genesis; after that only new blocks are applied,
+// and a reorg below the synced tip triggers a full rescan.
+type WatchOnlyWallet struct {
+	source      WatchSource
+	lock        sync.Locker // Serializes chain access with other users such as the miner
+	config      WatchOnlyWalletConfig
+	addresses   map[string]*WatchedAddress
+	unscanned   map[string]bool // Addresses added since the last sync
+	height      int64           // Last applied block (-1 before the first sync)
+	tipHash     string
+	subscribers map[int]chan WatchEvent
+	nextSub     int
+	mu          sync.Mutex
+	quit        chan struct{}
+	wg          sync.WaitGroup
+	once        sync.Once
+}
+
+// NewWatchOnlyWallet creates a watch-only wallet following a chain.
+// The lock must be the one guarding the chain (nil uses an internal mutex).
+func NewWatchOnlyWallet(source WatchSource, lock sync.Locker, config WatchOnlyWalletConfig) *WatchOnlyWallet {
+	if lock == nil {
+		lock = &sync.Mutex{}
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+
+	return &WatchOnlyWallet{
+		source:      source,
+		lock:        lock,
+		config:      config,
+		addresses:   make(map[string]*WatchedAddress),
+		unscanned:   make(map[string]bool),
+		height:      -1,
+		subscribers: make(map[int]chan WatchEvent),
+		quit:        make(chan struct{}),
+	}
+}
+
+// Start begins applying new blocks as they arrive
+func (ww *WatchOnlyWallet) Start() {
+	ww.wg.Add(1)
+	go ww.loop()
+}
+
+// Stop ends block following and closes subscriptions
+func (ww *WatchOnlyWallet) Stop() {
+	ww.once.Do(func() { close(ww.quit) })
+	ww.wg.Wait()
+
+	ww.mu.Lock()
+	defer ww.mu.Unlock()
+	for id, ch := range ww.subscribers {
+		close(ch)
+		delete(ww.subscribers, id)
+	}
+}
+
+// loop syncs with the chain tip until stopped
+func (ww *WatchOnlyWallet) loop() {
+	defer ww.wg.Done()
+
+	ticker := time.NewTicker(ww.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := ww.Sync(); err != nil {
+			log.Printf("Watch-only wallet: sync failed: %v", err)
+		}
+
+		select {
+		case <-ww.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// AddAddress starts watching an address; its history is built on the next sync.
+// Returns false if the address was already watched.
+func (ww *WatchOnlyWallet) AddAddress(address, label string) bool {
+	ww.mu.Lock()
+	defer ww.mu.Unlock()
+
+	if _, exists := ww.addresses[address]; exists {
+		return false
+	}
+	ww.addresses[address] = &WatchedAddress{Address: address, Label: label}
+	ww.unscanned[address] = true
+	return true
+}
+
+// RemoveAddress stops watching an address
+func (ww *WatchOnlyWallet) RemoveAddress(address string) {
+	ww.mu.Lock()
+	defer ww.mu.Unlock()
+	delete(ww.addresses, address)
+	delete(ww.unscanned, address)
+}
+
+// Address returns a copy of the tracked state of a watched address
+func (ww *WatchOnlyWallet) Address(address string) (*WatchedAddress, bool) {
+	ww.mu.Lock()
+	defer ww.mu.Unlock()
+
+	watched, exists := ww.addresses[address]
+	if !exists {
+		return nil, false
+	}
+	copied := *watched
+	copied.History = append([]WatchedTransaction(nil), watched.History...)
+	return &copied, true
+}
+
+// Addresses returns the watched addresses
+func (ww *WatchOnlyWallet) Addresses() []string {
+	ww.mu.Lock()
+	defer ww.mu.Unlock()
+
+	addresses := make([]string, 0, len(ww.addresses))
+	for address := range ww.addresses {
+		addresses = append(addresses, address)
+	}
+	return addresses
+}
+
+// Height returns the last block applied to the wallet (-1 before the first sync)
+func (ww *WatchOnlyWallet) Height() int64 {
+	ww.mu.Lock()
+	defer ww.mu.Unlock()
+	return ww.height
+}
+
+// Subscribe returns a channel receiving an event for every new transaction involving a
+// watched address, and a function ending the subscription. Events found by rescans are not
+// delivered, and events are dropped while the channel buffer is full.
+func (ww *WatchOnlyWallet) Subscribe(buffer int) (<-chan WatchEvent, func()) {
+	ww.mu.Lock()
+	defer ww.mu.Unlock()
+
+	id := ww.nextSub
+	ww.nextSub++
+	ch := make(chan WatchEvent, buffer)
+	ww.subscribers[id] = ch
+
+	return ch, func() {
+		ww.mu.Lock()
+		defer ww.mu.Unlock()
+		if _, exists := ww.subscribers[id]; exists {
+			close(ch)
+			delete(ww.subscribers, id)
+		}
+	}
+}
+
+// Sync rescans newly added addresses and applies blocks added since the last sync
+func (ww *WatchOnlyWallet) Sync() error {
+	ww.lock.Lock()
+	defer ww.lock.Unlock()
+	ww.mu.Lock()
+	defer ww.mu.Unlock()
+
+	tip := ww.source.GetLatestBlock()
+
+	// A reorg below the synced tip invalidates every history
+	if ww.height >= 0 {
+		block, err := ww.source.GetBlockByIndex(ww.height)
+		if err != nil || block.Hash != ww.tipHash {
+			log.Printf("Watch-only wallet: block %d (%s) left the chain, rescanning", ww.height, ww.tipHash)
+			for address := range ww.addresses {
+				ww.unscanned[address] = true
+			}
+		}
+	}
+
+	// New addresses catch up with the others; when every address restarts they all go to the tip
+	if len(ww.unscanned) > 0 {
+		height := ww.height
+		if len(ww.unscanned) == len(ww.addresses) {
+			height = tip.Index
+		}
+		if err := ww.rescan(ww.unscanned, height); err != nil {
+			return err
+		}
+		if height == tip.Index {
+			ww.tipHash = tip.Hash
+		}
+		ww.height = height
+		clear(ww.unscanned)
+	}
+
+	for height := ww.height + 1; height <= tip.Index; height++ {
+		block, err := ww.source.GetBlockByIndex(height)
+		if err != nil {
+			return fmt.Errorf("failed to load block %d: %w", height, err)
+		}
+		for _, event := range ww.applyBlock(block, ww.addresses) {
+			ww.publish(event)
+		}
+		ww.height = block.Index
+		ww.tipHash = block.Hash
+	}
+	return nil
+}
+
+// rescan rebuilds the history of addresses from the chain up to a height (caller must hold both locks)
+func (ww *WatchOnlyWallet) rescan(addresses map[string]bool, height int64) error {
+	watched := ww.resetAddresses(addresses)
+	for index := int64(0); index <= height; index++ {
+		block, err := ww.source.GetBlockByIndex(index)
+		if err != nil {
+			return fmt.Errorf("failed to load block %d: %w", index, err)
+		}
+		ww.applyBlock(block, watched)
+	}
+	return nil
+}
+
+// RescanFromDatabase rebuilds the history of newly added addresses from the indexed
+// transactions table instead of walking every block, up to the synced height.
+// The table stores repeated coinbase rewards once, so miner addresses should be rescanned from the chain.
+func (ww *WatchOnlyWallet) RescanFromDatabase(db *Database) error {
+	ww.mu.Lock()
+	defer ww.mu.Unlock()
+
+	if len(ww.unscanned) == 0 {
+		return nil
+	}
+	if ww.height < 0 {
+		return fmt.Errorf("watch-only wallet has not synced yet")
+	}
+	if state, err := db.LoadPruneState(); err != nil {
+		return err
+	} else if state != nil {
+		return fmt.Errorf("cannot rescan from a pruned database: %w", ErrPruned)
+	}
+
+	watched := ww.resetAddresses(ww.unscanned)
+	var list []string
+	for address := range watched {
+		list = append(list, address)
+	}
+
+	entries, err := db.addressTransactions(list, ww.height)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		ww.applyTransaction(&entry.tx, entry.blockIndex, entry.blockHash, entry.timestamp, watched)
+	}
+
+	clear(ww.unscanned)
+	return nil
+}
+
+// resetAddresses clears the history of addresses and returns them (caller must hold the lock)
+func (ww *WatchOnlyWallet) resetAddresses(addresses map[string]bool) map[string]*WatchedAddress {
+	watched := make(map[string]*WatchedAddress, len(addresses))
+	for address := range addresses {
+		if current, exists := ww.addresses[address]; exists {
+			reset := &WatchedAddress{Address: address, Label: current.Label}
+			ww.addresses[address] = reset
+			watched[address] = reset
+		}
+	}
+	return watched
+}
+
+// applyBlock records the transactions of a block involving the given addresses (caller must hold the lock)
+func (ww *WatchOnlyWallet) applyBlock(block *Block, watched map[string]*WatchedAddress) []WatchEvent {
+	var events []WatchEvent
+	for i := range block.Transactions {
+		events = append(events, ww.applyTransaction(&block.Transactions[i], block.Index, block.Hash, block.Timestamp, watched)...)
+	}
+	return events
+}
+
+// applyTransaction updates the addresses a transaction involves (caller must hold the lock)
+func (ww *WatchOnlyWallet) applyTransaction(tx *Transaction, blockIndex int64, blockHash string, timestamp int64, watched map[string]*WatchedAddress) []WatchEvent {
+	entry := WatchedTransaction{Transaction: *tx, BlockIndex: blockIndex, BlockHash: blockHash, Timestamp: timestamp}
+
+	var events []WatchEvent
+	record := func(address *WatchedAddress, direction WatchDirection) {
+		entry.Direction = direction
+		address.History = append(address.History, entry)
+		events = append(events, WatchEvent{Address: address.Address, Transaction: entry})
+	}
+
+	sender, fromWatched := watched[tx.From]
+	receiver, toWatched := watched[tx.To]
+	switch {
+	case fromWatched && tx.From == tx.To:
+		sender.Sent += tx.Amount
+		sender.Received += tx.Amount
+		record(sender, WatchSelf)
+	default:
+		if fromWatched {
+			sender.Sent += tx.Amount
+			sender.Balance -= tx.Amount
+			record(sender, WatchOutgoing)
+		}
+		if toWatched {
+			receiver.Received += tx.Amount
+			receiver.Balance += tx.Amount
+			record(receiver, WatchIncoming)
+		}
+	}
+	return events
+}
+
+// publish delivers an event to every subscriber without blocking (caller must hold the lock)
+func (ww *WatchOnlyWallet) publish(event WatchEvent) {
+	for _, ch := range ww.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// addressTransactionEntry is a stored transaction with its block position
+type addressTransactionEntry struct {
+	tx         Transaction
+	blockIndex int64
+	blockHash  string
+	timestamp  int64
+}
+
+// addressTransactions returns the stored transactions involving any of the addresses
+// up to a block height, in chain order
+func (d *Database) addressTransactions(addresses []string, toHeight int64) ([]addressTransactionEntry, error) {
+	if len(addresses) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(addresses)), ", ")
+	query := fmt.Sprintf(`
+		SELECT t.transaction_data, t.block_index, t.block_hash, b.timestamp
+		FROM transactions t JOIN blocks b ON b.hash = t.block_hash
+		WHERE t.block_index <= ? AND (t.from_address IN (%s) OR t.to_address IN (%s))
+		ORDER BY t.block_index, t.tx_index`, placeholders, placeholders)
+
+	args := []interface{}{toHeight}
+	for i := 0; i < 2; i++ {
+		for _, address := range addresses {
+			args = append(args, address)
+		}
+	}
+
+	rows, err := d.db.Query(d.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query address transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []addressTransactionEntry
+	for rows.Next() {
+		var entry addressTransactionEntry
+		var data string
+		if err := rows.Scan(&data, &entry.blockIndex, &entry.blockHash, &entry.timestamp); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(data), &entry.tx); err != nil {
+			return nil, fmt.Errorf("failed to deserialize transaction: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
@@ -0,0 +1,250 @@
+package blockchain
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Workload event types
+const (
+	EventTransaction         = "tx"
+	EventEnhancedTransaction = "enhanced_tx"
+	EventBlock               = "block"
+)
+
+// WorkloadEvent is a single entry of a recorded workload
+type WorkloadEvent struct {
+	Seq         int64                `json:"seq"`
+	Type        string               `json:"type"`
+	Offset      time.Duration        `json:"offset"` // Time since the recording started
+	Transaction *Transaction         `json:"tx,omitempty"`
+	Enhanced    *EnhancedTransaction `json:"enhancedTx,omitempty"`
+	Block       *Block               `json:"block,omitempty"`
+}
+
+// WorkloadRecorder writes an ordered log of accepted transactions and blocks as newline-delimited JSON
+type WorkloadRecorder struct {
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	started time.Time
+	seq     int64
+	err     error
+	mu      sync.Mutex
+}
+
+// NewWorkloadRecorder creates a recorder writing to w
+func NewWorkloadRecorder(w io.Writer) *WorkloadRecorder {
+	writer := bufio.NewWriter(w)
+	return &WorkloadRecorder{
+		writer:  writer,
+		encoder: json.NewEncoder(writer),
+		started: time.Now(),
+	}
+}
+
+// RecordTransaction logs a transaction accepted into the pool
+func (wr *WorkloadRecorder) RecordTransaction(tx *Transaction) {
+	wr.record(&WorkloadEvent{Type: EventTransaction, Transaction: tx})
+}
+
+// RecordEnhancedTransaction logs an enhanced transaction accepted into the pool
+func (wr *WorkloadRecorder) RecordEnhancedTransaction(tx *EnhancedTransaction) {
+	wr.record(&WorkloadEvent{Type: EventEnhancedTransaction, Enhanced: tx})
+}
+
+// RecordBlock logs a block appended to the chain
+func (wr *WorkloadRecorder) RecordBlock(block *Block) {
+	wr.record(&WorkloadEvent{Type: EventBlock, Block: block})
+}
+
+// record assigns the next sequence number and writes the event.
+// The first write error is kept and returned by Flush.
+func (wr *WorkloadRecorder) record(event *WorkloadEvent) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	if wr.err != nil {
+		return
+	}
+
+	wr.seq++
+	event.Seq = wr.seq
+	event.Offset = time.Since(wr.started)
+	wr.err = wr.encoder.Encode(event)
+}
+
+// Flush writes buffered events to the underlying writer
+func (wr *WorkloadRecorder) Flush() error {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	if wr.err != nil {
+		return wr.err
+	}
+	wr.err = wr.writer.Flush()
+	return wr.err
+}
+
+// ReplayTarget is the node surface a recorded workload is replayed against
+type ReplayTarget interface {
+	AddTransaction(tx *Transaction) error
+	AddBlock(block *Block) error
+	GetLatestBlock() *Block
+}
+
+// ReplayConfig configures a workload replay
+type ReplayConfig struct {
+	PreserveTiming   bool // Wait between events as in the recording instead of replaying at full speed
+	StopOnDivergence bool // Abort at the first event whose outcome differs from the recording
+}
+
+// ReplayDivergence describes an event whose replay outcome differs from the recording
+type ReplayDivergence struct {
+	Seq    int64  `json:"seq"`
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// ReplayReport summarizes a workload replay
+type ReplayReport struct {
+	Events       int                `json:"events"`
+	Transactions int                `json:"transactions"`
+	Blocks       int                `json:"blocks"`
+	Skipped      int                `json:"skipped"` // Enhanced transactions the target cannot accept
+	Elapsed      time.Duration      `json:"elapsed"`
+	TxTime       time.Duration      `json:"txTime"`    // Total time spent accepting transactions
+	BlockTime    time.Duration      `json:"blockTime"` // Total time spent validating and connecting blocks
+	Divergences  []ReplayDivergence `json:"divergences,omitempty"`
+}
+
+// String formats the report for console output
+func (r *ReplayReport) String() string {
+	return fmt.Sprintf("events=%d txs=%d blocks=%d skipped=%d elapsed=%v tx_time=%v block_time=%v divergences=%d",
+		r.Events, r.Transactions, r.Blocks, r.Skipped, r.Elapsed, r.TxTime, r.BlockTime, len(r.Divergences))
+}
+
+// ReplayWorkload applies a recorded workload to a fresh node in order.
+// Transactions must be accepted again and every recorded block must become the
+// chain tip with a byte-identical encoding; any difference is reported as a divergence.
+func ReplayWorkload(r io.Reader, target ReplayTarget, config ReplayConfig) (*ReplayReport, error) {
+	report := &ReplayReport{}
+	decoder := json.NewDecoder(r)
+	start := time.Now()
+
+	var lastSeq int64
+	for {
+		var event WorkloadEvent
+		if err := decoder.Decode(&event); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return report, fmt.Errorf("failed to read workload event %d: %w", lastSeq+1, err)
+		}
+
+		if event.Seq != lastSeq+1 {
+			return report, fmt.Errorf("workload log out of order: expected event %d, got %d", lastSeq+1, event.Seq)
+		}
+		lastSeq = event.Seq
+
+		if config.PreserveTiming {
+			if wait := event.Offset - time.Since(start); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+
+		report.Events++
+		reason, err := replayEvent(&event, target, report)
+		if err != nil {
+			return report, err
+		}
+		if reason == "" {
+			continue
+		}
+
+		report.Divergences = append(report.Divergences, ReplayDivergence{Seq: event.Seq, Type: event.Type, Reason: reason})
+		if config.StopOnDivergence {
+			break
+		}
+	}
+
+	report.Elapsed = time.Since(start)
+	return report, nil
+}
+
+// replayEvent applies a single event, returning a divergence reason if the outcome differs from the recording
+func replayEvent(event *WorkloadEvent, target ReplayTarget, report *ReplayReport) (string, error) {
+	switch event.Type {
+	case EventTransaction:
+		if event.Transaction == nil {
+			return "", fmt.Errorf("workload event %d has no transaction", event.Seq)
+		}
+		report.Transactions++
+
+		start := time.Now()
+		err := target.AddTransaction(event.Transaction)
+		report.TxTime += time.Since(start)
+
+		if err != nil {
+			return fmt.Sprintf("transaction %s rejected: %v", event.Transaction.Hash, err), nil
+		}
+
+	case EventEnhancedTransaction:
+		if event.Enhanced == nil {
+			return "", fmt.Errorf("workload event %d has no enhanced transaction", event.Seq)
+		}
+		enhancedTarget, ok := target.(EnhancedLoadTarget)
+		if !ok {
+			report.Skipped++
+			return "", nil
+		}
+		report.Transactions++
+
+		start := time.Now()
+		err := enhancedTarget.AddEnhancedTransaction(event.Enhanced)
+		report.TxTime += time.Since(start)
+
+		if err != nil {
+			return fmt.Sprintf("enhanced transaction %s rejected: %v", event.Enhanced.Hash, err), nil
+		}
+
+	case EventBlock:
+		if event.Block == nil {
+			return "", fmt.Errorf("workload event %d has no block", event.Seq)
+		}
+		report.Blocks++
+
+		recorded, err := json.Marshal(event.Block)
+		if err != nil {
+			return "", err
+		}
+
+		start := time.Now()
+		err = target.AddBlock(event.Block)
+		report.BlockTime += time.Since(start)
+
+		if err != nil {
+			return fmt.Sprintf("block %d rejected: %v", event.Block.Index, err), nil
+		}
+
+		latest := target.GetLatestBlock()
+		replayed, err := json.Marshal(latest)
+		if err != nil {
+			return "", err
+		}
+		if !bytes.Equal(recorded, replayed) {
+			return fmt.Sprintf("chain tip %d (%s) differs from recorded block %d (%s)",
+				latest.Index, latest.Hash, event.Block.Index, event.Block.Hash), nil
+		}
+
+	default:
+		return "", fmt.Errorf("unknown workload event type %q", event.Type)
+	}
+
+	return "", nil
+}
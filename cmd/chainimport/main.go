@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"blockchain/blockchain"
+)
+
+func main() {
+	dbPath := flag.String("db", "analysis.db", "SQLite database to import into")
+	input := flag.String("input", "", "block JSON file (array or one object per block); stdin when empty")
+	format := flag.String("format", "bitcoin", "built-in field mapping: bitcoin or native")
+	mappingFile := flag.String("mapping", "", "JSON field mapping file, overriding the built-in mapping fields it sets")
+	keepGoing := flag.Bool("continue", false, "skip blocks that fail instead of aborting")
+	flag.Parse()
+
+	var mapping blockchain.ImportMapping
+	switch *format {
+	case "bitcoin":
+		mapping = blockchain.BitcoinImportMapping()
+	case "native":
+		mapping = blockchain.NativeImportMapping()
+	default:
+		log.Fatalf("unknown format %q", *format)
+	}
+
+	if *mappingFile != "" {
+		data, err := os.ReadFile(*mappingFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := json.Unmarshal(data, &mapping); err != nil {
+			log.Fatalf("invalid mapping file: %v", err)
+		}
+	}
+
+	source := os.Stdin
+	if *input != "" {
+		file, err := os.Open(*input)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		source = file
+	}
+
+	db, err := blockchain.NewDatabase(blockchain.DatabaseConfig{Driver: "sqlite3", Path: *dbPath})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	report, err := db.ImportBlocks(source, blockchain.ImportConfig{Mapping: mapping, ContinueOnError: *keepGoing})
+	for _, message := range report.Errors {
+		fmt.Fprintln(os.Stderr, message)
+	}
+	fmt.Printf("Imported %d blocks (%d transactions), skipped %d\n", report.Blocks, report.Transactions, report.Skipped)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"blockchain/blockchain"
+)
+
+func main() {
+	vectors := flag.String("vectors", "conformance/vectors.json", "conformance vector file")
+	generate := flag.Bool("generate", false, "regenerate the vector file from this implementation instead of checking it")
+	verbose := flag.Bool("v", false, "list every vector, not only failures")
+	flag.Parse()
+
+	if *generate {
+		suite, err := blockchain.GenerateConformanceSuite()
+		if err != nil {
+			log.Fatal(err)
+		}
+		data, err := json.MarshalIndent(suite, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(*vectors, append(data, '\n'), 0644); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Wrote conformance vectors to %s\n", *vectors)
+		return
+	}
+
+	file, err := os.Open(*vectors)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	suite, err := blockchain.LoadConformanceSuite(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	report := blockchain.RunConformance(suite)
+	if *verbose {
+		for _, result := range report.Results {
+			status := "PASS"
+			if !result.Passed {
+				status = "FAIL"
+			}
+			fmt.Printf("%s %s/%s\n", status, result.Category, result.Name)
+		}
+	}
+	fmt.Print(report)
+
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
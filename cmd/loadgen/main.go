@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"blockchain/blockchain"
+)
+
+func main() {
+	wallets := flag.Int("wallets", 1000, "number of wallets to generate")
+	tps := flag.Float64("tps", 200, "target transactions per second")
+	duration := flag.Duration("duration", 30*time.Second, "length of the run")
+	total := flag.Int("total", 0, "stop after this many transactions (overrides duration)")
+	distribution := flag.String("distribution", blockchain.DistributionUniform, "amount distribution: fixed, uniform or exponential")
+	minAmount := flag.Float64("min-amount", 0.01, "minimum (or fixed) amount")
+	maxAmount := flag.Float64("max-amount", 100, "maximum amount")
+	fee := flag.Float64("fee", 0.1, "fee per transaction")
+	fanOut := flag.Int("fanout", 1, "recipients per payment burst")
+	contractRatio := flag.Float64("contract-ratio", 0, "share of contract call transactions (0..1)")
+	difficulty := flag.Int("difficulty", 2, "mining difficulty")
+	mineInterval := flag.Duration("mine-interval", 2*time.Second, "how often to mine a block")
+	seed := flag.Int64("seed", 1, "random seed for a reproducible workload")
+	record := flag.String("record", "", "write accepted transactions and blocks to this workload log")
+	replay := flag.String("replay", "", "replay a recorded workload log against a fresh node instead of generating load")
+	preserveTiming := flag.Bool("preserve-timing", false, "replay events with their recorded timing")
+	flag.Parse()
+
+	bc := blockchain.NewBlockchain(*difficulty, "loadgen-miner")
+	bc.TransactionPool = blockchain.NewTransactionPool(1000000)
+	bc.TransactionPool.SetChainView(bc)
+
+	if *replay != "" {
+		runReplay(bc, *replay, *preserveTiming)
+		return
+	}
+
+	if *record != "" {
+		file, err := os.Create(*record)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+
+		recorder := blockchain.NewWorkloadRecorder(file)
+		bc.Recorder = recorder
+		defer func() {
+			if err := recorder.Flush(); err != nil {
+				log.Printf("Failed to write workload log: %v", err)
+			}
+		}()
+	}
+
+	fmt.Printf("Generating %d wallets...\n", *wallets)
+	generator, err := blockchain.NewLoadGenerator(bc, blockchain.LoadConfig{
+		Wallets:            *wallets,
+		TargetTPS:          *tps,
+		Duration:           *duration,
+		TotalTransactions:  *total,
+		AmountDistribution: *distribution,
+		MinAmount:          *minAmount,
+		MaxAmount:          *maxAmount,
+		Fee:                *fee,
+		FanOut:             *fanOut,
+		ContractCallRatio:  *contractRatio,
+		MineInterval:       *mineInterval,
+		Mine: func() error {
+			bc.MinePendingTransactions()
+			return nil
+		},
+		Seed: *seed,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Running load at %.0f TPS...\n", *tps)
+	report, err := generator.Run(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(report)
+	for reason, count := range report.RejectReasons {
+		fmt.Printf("  rejected %d: %s\n", count, reason)
+	}
+}
+
+// runReplay replays a workload log against the node and reports timings and divergences
+func runReplay(bc *blockchain.Blockchain, path string, preserveTiming bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	fmt.Printf("Replaying %s...\n", path)
+	report, err := blockchain.ReplayWorkload(file, bc, blockchain.ReplayConfig{PreserveTiming: preserveTiming})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(report)
+	for _, divergence := range report.Divergences {
+		fmt.Printf("  diverged at event %d (%s): %s\n", divergence.Seq, divergence.Type, divergence.Reason)
+	}
+}
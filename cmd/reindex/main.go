@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"blockchain/blockchain"
+)
+
+func main() {
+	dbPath := flag.String("db", "blockchain.db", "database to reindex (the node must be stopped)")
+	driver := flag.String("driver", "sqlite3", "storage backend: sqlite3 or leveldb")
+	restart := flag.Bool("restart", false, "start over instead of resuming an interrupted reindex")
+	ifNeeded := flag.Bool("if-needed", false, "only reindex when the derived tables are outdated or a reindex was interrupted")
+	batchSize := flag.Int("batch", 500, "blocks per database transaction")
+	addresses := flag.Bool("addresses", false, "only rebuild the address balances and transaction counts, atomically")
+	verify := flag.Bool("verify", false, "check the stored blocks, transactions and chain state for corruption instead of reindexing (SQL only)")
+	compress := flag.String("compress", "", "rewrite the stored blocks with gzip, zstd or none instead of reindexing (SQL only)")
+	compressionStats := flag.Bool("compression-stats", false, "report the space block compression saves instead of reindexing (SQL only)")
+	flag.Parse()
+
+	db, err := blockchain.OpenStorage(blockchain.DatabaseConfig{Driver: *driver, Path: *dbPath, Compression: *compress})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	if *verify {
+		database, ok := db.(*blockchain.Database)
+		if !ok {
+			log.Fatalf("integrity checks need a SQL database, not %s", *driver)
+		}
+		report, err := database.VerifyIntegrity()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, issue := range report.Issues {
+			fmt.Printf("block %d: %s: %s\n", issue.BlockIndex, issue.Kind, issue.Detail)
+		}
+		fmt.Printf("Checked %d blocks (%d transactions), %d issues\n", report.Blocks, report.Transactions, len(report.Issues))
+		if !report.OK() {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *compress != "" || *compressionStats {
+		database, ok := db.(*blockchain.Database)
+		if !ok {
+			log.Fatalf("block compression needs a SQL database, not %s", *driver)
+		}
+		if *compress != "" {
+			rewritten, err := database.CompressBlocks(*batchSize)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("Rewrote %d blocks with %s\n", rewritten, *compress)
+		}
+		stats, err := database.CompressionStats()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%d blocks %v: %d bytes stored, %d uncompressed, %d saved (%.1f%%)\n",
+			stats.Blocks, stats.Codecs, stats.StoredBytes, stats.RawBytes, stats.Saved(), 100*(1-stats.Ratio()))
+		return
+	}
+
+	if *addresses {
+		report, err := db.RebuildAddressIndex()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Rebuilt %d addresses (%d repaired)\n", report.Addresses, report.Repaired)
+		return
+	}
+
+	if *ifNeeded {
+		needed, err := db.NeedsReindex()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !needed {
+			fmt.Println("Derived tables are up to date")
+			return
+		}
+	}
+
+	report, err := db.Reindex(blockchain.ReindexConfig{
+		BatchSize: *batchSize,
+		Restart:   *restart,
+		Progress: func(progress blockchain.ReindexProgress) {
+			fmt.Printf("Reindexed up to block %d of %d\n", progress.Height, progress.Target)
+		},
+	})
+	if report != nil && report.Resumed {
+		fmt.Printf("Resumed at block %d\n", report.ResumedAt)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Reindexed %d blocks (%d transactions)\n", report.Blocks, report.Transactions)
+}
@@ -0,0 +1,483 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"blockchain/blockchain"
+	"blockchain/nodepb"
+)
+
+// QueryBackend is the node surface served by the admin API.
+// Both *blockchain.Blockchain and *blockchain.PersistentBlockchain implement it.
+type QueryBackend interface {
+	Query(query string, args ...interface{}) (*blockchain.QueryResult, error)
+}
+
+// LegalHoldBackend is the node surface served by the legal hold RPCs.
+// *blockchain.PersistentBlockchain implements it.
+type LegalHoldBackend interface {
+	PlaceLegalHold(kind, id, reason string) (*blockchain.LegalHold, error)
+	ReleaseLegalHold(kind, id string) error
+	LegalHolds() ([]blockchain.LegalHold, error)
+}
+
+// MultiSigBackend is the node surface served by the multi-sig dashboard RPC.
+// *blockchain.PersistentBlockchain implements it.
+type MultiSigBackend interface {
+	MultiSigDashboard(address string) (*blockchain.MultiSigDashboard, error)
+}
+
+// MempoolBackend is the node surface served by the mempool inspection RPCs.
+// Both *blockchain.Blockchain and *blockchain.PersistentBlockchain implement it.
+type MempoolBackend interface {
+	ListPendingTransactions(filter blockchain.PendingFilter) []blockchain.PendingTransaction
+	InspectPendingTransaction(hash string) (*blockchain.PendingTransaction, error)
+	RemovePendingTransaction(hash string) ([]blockchain.PoolDropEvent, error)
+}
+
+// AdminServer implements the nodepb.AdminServer gRPC service.
+// It should only be exposed to operators, e.g. on a loopback listener.
+type AdminServer struct {
+	nodepb.UnimplementedAdminServer
+
+	backend     QueryBackend
+	mu          sync.Locker
+	maintenance *blockchain.Maintenance
+	supervisor  *blockchain.Supervisor
+	holds       LegalHoldBackend
+	multiSig    MultiSigBackend
+	mempool     MempoolBackend
+}
+
+// NewAdminServer creates a gRPC admin service sharing the given chain lock (nil for an internal one)
+func NewAdminServer(backend QueryBackend, lock sync.Locker) *AdminServer {
+	if lock == nil {
+		lock = &sync.Mutex{}
+	}
+	return &AdminServer{backend: backend, mu: lock}
+}
+
+// SetMaintenance enables the maintenance RPCs. Call it before serving.
+func (s *AdminServer) SetMaintenance(maintenance *blockchain.Maintenance) {
+	s.maintenance = maintenance
+}
+
+// SetSupervisor enables the service RPCs. Call it before serving.
+func (s *AdminServer) SetSupervisor(supervisor *blockchain.Supervisor) {
+	s.supervisor = supervisor
+}
+
+// SetLegalHolds enables the legal hold RPCs. Call it before serving.
+func (s *AdminServer) SetLegalHolds(holds LegalHoldBackend) {
+	s.holds = holds
+}
+
+// SetMultiSig enables the multi-sig dashboard RPC. Call it before serving.
+func (s *AdminServer) SetMultiSig(multiSig MultiSigBackend) {
+	s.multiSig = multiSig
+}
+
+// SetMempool enables the mempool inspection RPCs. Call it before serving.
+func (s *AdminServer) SetMempool(mempool MempoolBackend) {
+	s.mempool = mempool
+}
+
+// Serve registers the service on a new gRPC server and serves it on the listener
+func (s *AdminServer) Serve(listener net.Listener, opts ...grpc.ServerOption) (*grpc.Server, error) {
+	grpcServer := grpc.NewServer(opts...)
+	nodepb.RegisterAdminServer(grpcServer, s)
+	return serve(grpcServer, listener)
+}
+
+// Query runs a read-only query over the mempool and chain
+func (s *AdminServer) Query(ctx context.Context, req *nodepb.QueryRequest) (*nodepb.QueryResponse, error) {
+	if req.GetQuery() == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+
+	args := make([]interface{}, len(req.GetArgs()))
+	for i, arg := range req.GetArgs() {
+		args[i] = arg
+	}
+
+	s.mu.Lock()
+	result, err := s.backend.Query(req.GetQuery(), args...)
+	s.mu.Unlock()
+
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	resp := &nodepb.QueryResponse{Columns: result.Columns}
+	for _, row := range result.Rows {
+		values := make([]string, len(row))
+		for i, value := range row {
+			values[i] = fmt.Sprint(value)
+		}
+		resp.Rows = append(resp.Rows, &nodepb.QueryRow{Values: values})
+	}
+	return resp, nil
+}
+
+// ScheduleMaintenance plans a maintenance window, or runs one now if no start is given
+func (s *AdminServer) ScheduleMaintenance(ctx context.Context, req *nodepb.ScheduleMaintenanceRequest) (*nodepb.MaintenanceStatus, error) {
+	if s.maintenance == nil {
+		return nil, status.Error(codes.Unimplemented, "maintenance is not configured")
+	}
+	if req.GetDurationSeconds() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "duration cannot be negative")
+	}
+
+	duration := time.Duration(req.GetDurationSeconds()) * time.Second
+	var err error
+	if req.GetStart() == 0 {
+		err = s.maintenance.Begin(req.GetReason(), duration)
+	} else {
+		err = s.maintenance.Schedule(blockchain.MaintenanceWindow{
+			Start:    time.Unix(req.GetStart(), 0),
+			Duration: duration,
+			Reason:   req.GetReason(),
+		})
+	}
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return maintenanceToProto(s.maintenance.Status()), nil
+}
+
+// CancelMaintenance drops the scheduled window
+func (s *AdminServer) CancelMaintenance(ctx context.Context, req *nodepb.CancelMaintenanceRequest) (*nodepb.MaintenanceStatus, error) {
+	if s.maintenance == nil {
+		return nil, status.Error(codes.Unimplemented, "maintenance is not configured")
+	}
+	if err := s.maintenance.Cancel(); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return maintenanceToProto(s.maintenance.Status()), nil
+}
+
+// ResumeMaintenance ends maintenance
+func (s *AdminServer) ResumeMaintenance(ctx context.Context, req *nodepb.ResumeMaintenanceRequest) (*nodepb.MaintenanceStatus, error) {
+	if s.maintenance == nil {
+		return nil, status.Error(codes.Unimplemented, "maintenance is not configured")
+	}
+	if err := s.maintenance.Resume(); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return maintenanceToProto(s.maintenance.Status()), nil
+}
+
+// GetMaintenanceStatus reports the maintenance state
+func (s *AdminServer) GetMaintenanceStatus(ctx context.Context, req *nodepb.GetMaintenanceStatusRequest) (*nodepb.MaintenanceStatus, error) {
+	if s.maintenance == nil {
+		return nil, status.Error(codes.Unimplemented, "maintenance is not configured")
+	}
+	return maintenanceToProto(s.maintenance.Status()), nil
+}
+
+// maintenanceToProto converts a maintenance status to its protobuf message
+func maintenanceToProto(maintenance blockchain.MaintenanceStatus) *nodepb.MaintenanceStatus {
+	resp := &nodepb.MaintenanceStatus{
+		State:     string(maintenance.State),
+		Since:     maintenance.Since.Unix(),
+		Backup:    maintenance.Backup,
+		LastError: maintenance.LastError,
+	}
+	if window := maintenance.Window; window != nil {
+		resp.WindowStart = window.Start.Unix()
+		resp.WindowDurationSeconds = int64(window.Duration / time.Second)
+		resp.Reason = window.Reason
+	}
+	return resp
+}
+
+// ListServices reports the supervised services
+func (s *AdminServer) ListServices(ctx context.Context, req *nodepb.ListServicesRequest) (*nodepb.ListServicesResponse, error) {
+	if s.supervisor == nil {
+		return nil, status.Error(codes.Unimplemented, "supervisor is not configured")
+	}
+	if req.GetCheck() {
+		s.supervisor.CheckHealth()
+	}
+
+	resp := &nodepb.ListServicesResponse{}
+	for _, service := range s.supervisor.Status() {
+		resp.Services = append(resp.Services, serviceToProto(service))
+	}
+	return resp, nil
+}
+
+// StartService starts a stopped or failed service
+func (s *AdminServer) StartService(ctx context.Context, req *nodepb.StartServiceRequest) (*nodepb.ServiceStatus, error) {
+	if s.supervisor == nil {
+		return nil, status.Error(codes.Unimplemented, "supervisor is not configured")
+	}
+	return s.serviceResult(req.GetName(), s.supervisor.StartService(req.GetName()))
+}
+
+// StopService stops a service
+func (s *AdminServer) StopService(ctx context.Context, req *nodepb.StopServiceRequest) (*nodepb.ServiceStatus, error) {
+	if s.supervisor == nil {
+		return nil, status.Error(codes.Unimplemented, "supervisor is not configured")
+	}
+	return s.serviceResult(req.GetName(), s.supervisor.StopService(req.GetName()))
+}
+
+// RestartService stops a service and starts it again
+func (s *AdminServer) RestartService(ctx context.Context, req *nodepb.RestartServiceRequest) (*nodepb.ServiceStatus, error) {
+	if s.supervisor == nil {
+		return nil, status.Error(codes.Unimplemented, "supervisor is not configured")
+	}
+	return s.serviceResult(req.GetName(), s.supervisor.RestartService(req.GetName()))
+}
+
+// serviceResult answers a service action with the service's status, or the action's error
+func (s *AdminServer) serviceResult(name string, err error) (*nodepb.ServiceStatus, error) {
+	if errors.Is(err, blockchain.ErrUnknownService) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	service, err := s.supervisor.ServiceStatus(name)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return serviceToProto(service), nil
+}
+
+// serviceToProto converts a service status to its protobuf message
+func serviceToProto(service blockchain.ServiceStatus) *nodepb.ServiceStatus {
+	resp := &nodepb.ServiceStatus{
+		Name:      service.Name,
+		State:     string(service.State),
+		Since:     service.Since.Unix(),
+		Restarts:  int64(service.Restarts),
+		LastError: service.LastError,
+		GaveUp:    service.GaveUp,
+	}
+	if !service.LastCheck.IsZero() {
+		resp.LastCheck = service.LastCheck.Unix()
+	}
+	return resp
+}
+
+// PlaceLegalHold tags a block or transaction that pruning must keep
+func (s *AdminServer) PlaceLegalHold(ctx context.Context, req *nodepb.PlaceLegalHoldRequest) (*nodepb.LegalHold, error) {
+	if s.holds == nil {
+		return nil, status.Error(codes.Unimplemented, "legal holds are not configured")
+	}
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	s.mu.Lock()
+	hold, err := s.holds.PlaceLegalHold(req.GetKind(), req.GetId(), req.GetReason())
+	s.mu.Unlock()
+
+	if errors.Is(err, blockchain.ErrPruned) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return legalHoldToProto(*hold), nil
+}
+
+// ListLegalHolds reports the placed holds
+func (s *AdminServer) ListLegalHolds(ctx context.Context, req *nodepb.ListLegalHoldsRequest) (*nodepb.ListLegalHoldsResponse, error) {
+	if s.holds == nil {
+		return nil, status.Error(codes.Unimplemented, "legal holds are not configured")
+	}
+
+	s.mu.Lock()
+	holds, err := s.holds.LegalHolds()
+	s.mu.Unlock()
+
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &nodepb.ListLegalHoldsResponse{}
+	for _, hold := range holds {
+		resp.Holds = append(resp.Holds, legalHoldToProto(hold))
+	}
+	return resp, nil
+}
+
+// ReleaseLegalHold releases a hold
+func (s *AdminServer) ReleaseLegalHold(ctx context.Context, req *nodepb.ReleaseLegalHoldRequest) (*nodepb.ReleaseLegalHoldResponse, error) {
+	if s.holds == nil {
+		return nil, status.Error(codes.Unimplemented, "legal holds are not configured")
+	}
+
+	s.mu.Lock()
+	err := s.holds.ReleaseLegalHold(req.GetKind(), req.GetId())
+	s.mu.Unlock()
+
+	if errors.Is(err, blockchain.ErrHoldNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &nodepb.ReleaseLegalHoldResponse{}, nil
+}
+
+// legalHoldToProto converts a legal hold to its protobuf message
+func legalHoldToProto(hold blockchain.LegalHold) *nodepb.LegalHold {
+	return &nodepb.LegalHold{
+		Kind:       hold.Kind,
+		Id:         hold.ID,
+		BlockIndex: hold.BlockIndex,
+		Reason:     hold.Reason,
+		PlacedAt:   hold.PlacedAt,
+	}
+}
+
+// GetMultiSigDashboard reports the treasury view of a multi-signature address
+func (s *AdminServer) GetMultiSigDashboard(ctx context.Context, req *nodepb.GetMultiSigDashboardRequest) (*nodepb.MultiSigDashboard, error) {
+	if s.multiSig == nil {
+		return nil, status.Error(codes.Unimplemented, "multi-sig dashboard is not configured")
+	}
+	if req.GetAddress() == "" {
+		return nil, status.Error(codes.InvalidArgument, "address is required")
+	}
+
+	s.mu.Lock()
+	dashboard, err := s.multiSig.MultiSigDashboard(req.GetAddress())
+	s.mu.Unlock()
+
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &nodepb.MultiSigDashboard{
+		Address:     dashboard.Address,
+		Balance:     dashboard.Balance,
+		Reserved:    dashboard.Reserved,
+		SpendPolicy: dashboard.SpendPolicy != nil,
+		Height:      dashboard.Height,
+		GeneratedAt: dashboard.GeneratedAt,
+	}
+	for _, pending := range dashboard.Pending {
+		resp.Pending = append(resp.Pending, &nodepb.PendingMultiSig{
+			Hash:         pending.Hash,
+			To:           pending.To,
+			Amount:       pending.Amount,
+			Fee:          pending.Fee,
+			CreatedAt:    pending.CreatedAt,
+			RequiredSigs: int32(pending.RequiredSigs),
+			Signed:       pending.Signed,
+			Missing:      pending.Missing,
+			Ready:        pending.Ready,
+		})
+	}
+	for _, lock := range dashboard.Locks {
+		resp.Locks = append(resp.Locks, &nodepb.PendingTimeLock{
+			Hash:      lock.Hash,
+			To:        lock.To,
+			Amount:    lock.Amount,
+			Fee:       lock.Fee,
+			LockTime:  lock.LockTime,
+			Remaining: lock.Remaining,
+			Signed:    lock.Signed,
+		})
+	}
+	return resp, nil
+}
+
+// ListPendingTransactions lists the transactions in the pool matching the request's filters
+func (s *AdminServer) ListPendingTransactions(ctx context.Context, req *nodepb.ListPendingTransactionsRequest) (*nodepb.ListPendingTransactionsResponse, error) {
+	if s.mempool == nil {
+		return nil, status.Error(codes.Unimplemented, "mempool inspection is not configured")
+	}
+	if req.GetMinFee() < 0 || req.GetMaxFee() < 0 || req.GetLimit() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "fees and limit cannot be negative")
+	}
+
+	s.mu.Lock()
+	pending := s.mempool.ListPendingTransactions(blockchain.PendingFilter{
+		Sender: req.GetSender(),
+		Type:   blockchain.TransactionType(req.GetType()),
+		MinFee: req.GetMinFee(),
+		MaxFee: req.GetMaxFee(),
+		Limit:  int(req.GetLimit()),
+	})
+	s.mu.Unlock()
+
+	resp := &nodepb.ListPendingTransactionsResponse{}
+	for i := range pending {
+		resp.Transactions = append(resp.Transactions, pendingToProto(&pending[i]))
+	}
+	return resp, nil
+}
+
+// GetPendingTransaction describes a transaction in the pool
+func (s *AdminServer) GetPendingTransaction(ctx context.Context, req *nodepb.GetPendingTransactionRequest) (*nodepb.PendingTransaction, error) {
+	if s.mempool == nil {
+		return nil, status.Error(codes.Unimplemented, "mempool inspection is not configured")
+	}
+	if req.GetHash() == "" {
+		return nil, status.Error(codes.InvalidArgument, "hash is required")
+	}
+
+	s.mu.Lock()
+	pending, err := s.mempool.InspectPendingTransaction(req.GetHash())
+	s.mu.Unlock()
+
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return pendingToProto(pending), nil
+}
+
+// RemovePendingTransaction removes a transaction from the pool with its dependents
+func (s *AdminServer) RemovePendingTransaction(ctx context.Context, req *nodepb.RemovePendingTransactionRequest) (*nodepb.RemovePendingTransactionResponse, error) {
+	if s.mempool == nil {
+		return nil, status.Error(codes.Unimplemented, "mempool inspection is not configured")
+	}
+	if req.GetHash() == "" {
+		return nil, status.Error(codes.InvalidArgument, "hash is required")
+	}
+
+	s.mu.Lock()
+	events, err := s.mempool.RemovePendingTransaction(req.GetHash())
+	s.mu.Unlock()
+
+	if errors.Is(err, blockchain.ErrTransactionNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &nodepb.RemovePendingTransactionResponse{}
+	for _, event := range events {
+		resp.Removed = append(resp.Removed, event.Transaction.Hash)
+	}
+	return resp, nil
+}
+
+// pendingToProto converts a pending transaction to its protobuf message
+func pendingToProto(pending *blockchain.PendingTransaction) *nodepb.PendingTransaction {
+	return &nodepb.PendingTransaction{
+		Hash:       pending.Hash,
+		Type:       string(pending.Type),
+		Enhanced:   pending.Enhanced,
+		From:       pending.From,
+		To:         pending.To,
+		Amount:     pending.Amount,
+		Fee:        pending.Fee,
+		Nonce:      pending.Nonce,
+		AddedAt:    pending.AddedAt,
+		Executable: pending.Executable,
+	}
+}
@@ -0,0 +1,138 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"blockchain/blockchain"
+	"blockchain/nodepb"
+)
+
+// Client is a thin SDK over the Node service for consumers that would otherwise
+// poll blocks and do their own height arithmetic
+type Client struct {
+	node         nodepb.NodeClient
+	PollInterval time.Duration // How often WaitForConfirmation asks the node (default 1s)
+}
+
+// NewClient creates a client on a gRPC connection to a node
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{node: nodepb.NewNodeClient(conn), PollInterval: pollInterval}
+}
+
+// Node returns the underlying generated client for calls the SDK doesn't wrap
+func (c *Client) Node() nodepb.NodeClient {
+	return c.node
+}
+
+// GetConfirmations returns the confirmation status of a transaction on the node's current chain
+func (c *Client) GetConfirmations(ctx context.Context, txHash string) (*blockchain.ConfirmationStatus, error) {
+	resp, err := c.node.GetConfirmations(ctx, &nodepb.GetConfirmationsRequest{TxHash: txHash})
+	if err != nil {
+		return nil, err
+	}
+	return &blockchain.ConfirmationStatus{
+		Hash:          resp.GetHash(),
+		Found:         resp.GetFound(),
+		Pending:       resp.GetPending(),
+		BlockIndex:    resp.GetBlockIndex(),
+		BlockHash:     resp.GetBlockHash(),
+		Confirmations: resp.GetConfirmations(),
+		Final:         resp.GetFinal(),
+	}, nil
+}
+
+// EstimateFee asks the node for a fee suggestion for a confirmation target (0 for the node's default).
+// Price a transaction with the FeeFor methods of the result.
+func (c *Client) EstimateFee(ctx context.Context, targetBlocks int) (blockchain.FeeEstimate, error) {
+	resp, err := c.node.EstimateFee(ctx, &nodepb.EstimateFeeRequest{TargetBlocks: int32(targetBlocks)})
+	if err != nil {
+		return blockchain.FeeEstimate{}, err
+	}
+	return feeEstimateFromProto(resp), nil
+}
+
+// GetFeeMarket asks the node for the pending fee rates, the fees of the last blocks blocks and the
+// fee projected to confirm within targetBlocks blocks (0 for the node's defaults)
+func (c *Client) GetFeeMarket(ctx context.Context, blocks, targetBlocks int) (blockchain.FeeMarket, error) {
+	resp, err := c.node.GetFeeMarket(ctx, &nodepb.GetFeeMarketRequest{Blocks: int32(blocks), TargetBlocks: int32(targetBlocks)})
+	if err != nil {
+		return blockchain.FeeMarket{}, err
+	}
+
+	market := blockchain.FeeMarket{
+		Recent: blockchain.BlockFeeStats{
+			Blocks:        int(resp.GetRecent().GetBlocks()),
+			Transactions:  int(resp.GetRecent().GetTransactions()),
+			MedianFee:     resp.GetRecent().GetMedianFee(),
+			MedianFeeRate: resp.GetRecent().GetMedianFeeRate(),
+			MinFee:        resp.GetRecent().GetMinFee(),
+			MaxFee:        resp.GetRecent().GetMaxFee(),
+			TotalFees:     resp.GetRecent().GetTotalFees(),
+		},
+		Projection: feeEstimateFromProto(resp.GetProjection()),
+	}
+	for _, bucket := range resp.GetHistogram() {
+		market.Histogram = append(market.Histogram, blockchain.FeeHistogramBucket{
+			MinFeeRate: bucket.GetMinFeeRate(),
+			MaxFeeRate: bucket.GetMaxFeeRate(),
+			Count:      int(bucket.GetCount()),
+			Size:       int(bucket.GetSize()),
+			TotalFee:   bucket.GetTotalFee(),
+		})
+	}
+	return market, nil
+}
+
+// feeEstimateFromProto converts a fee estimate message
+func feeEstimateFromProto(resp *nodepb.FeeEstimate) blockchain.FeeEstimate {
+	return blockchain.FeeEstimate{
+		TargetBlocks: int(resp.GetTargetBlocks()),
+		FeeRate:      resp.GetFeeRate(),
+		MinFee:       resp.GetMinFee(),
+		MinFeeRate:   resp.GetMinFeeRate(),
+		Samples:      int(resp.GetSamples()),
+		Fallback:     resp.GetFallback(),
+		DataByteFee:  resp.GetDataByteFee(),
+	}
+}
+
+// IsConfirmed reports whether a transaction is final or has at least depth confirmations
+func (c *Client) IsConfirmed(ctx context.Context, txHash string, depth int64) (bool, error) {
+	status, err := c.GetConfirmations(ctx, txHash)
+	if err != nil {
+		return false, err
+	}
+	return status.Confirmed(depth), nil
+}
+
+// WaitForConfirmation blocks until a transaction is final or has depth confirmations.
+// The node recomputes the status on every poll, so confirmations lost to a reorg are
+// waited for again. It fails once the context ends or a request fails.
+func (c *Client) WaitForConfirmation(ctx context.Context, txHash string, depth int64) (*blockchain.ConfirmationStatus, error) {
+	interval := c.PollInterval
+	if interval <= 0 {
+		interval = pollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		status, err := c.GetConfirmations(ctx, txHash)
+		if err != nil {
+			return nil, err
+		}
+		if status.Confirmed(depth) {
+			return status, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, fmt.Errorf("transaction %s not confirmed: %v", txHash, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
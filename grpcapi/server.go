@@ -0,0 +1,440 @@
+// Package grpcapi serves the blockchain node over gRPC using the nodepb service definitions.
+package grpcapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"blockchain/blockchain"
+	"blockchain/nodepb"
+)
+
+// Backend is the node surface served over gRPC.
+// Both *blockchain.Blockchain and *blockchain.PersistentBlockchain implement it.
+type Backend interface {
+	AddTransactionFrom(tx *blockchain.Transaction, source string) error
+	AddTransactionsFrom(txs []*blockchain.Transaction, source string) []error
+	GetBalance(address string) float64
+	GetReservedBalance(address string) float64
+	GetLatestBlock() *blockchain.Block
+	GetBlockByIndex(index int64) (*blockchain.Block, error)
+	GetBlockByHash(hash string) (*blockchain.Block, error)
+	GetTransactionProof(blockIndex int, txHash string) (*blockchain.MerkleProof, error)
+	GetConfirmations(txHash string) *blockchain.ConfirmationStatus
+	EstimateFee(targetBlocks int) blockchain.FeeEstimate
+	FeeMarket(blocks, targetBlocks int) blockchain.FeeMarket
+}
+
+// pollInterval is how often block streams check for new blocks
+const pollInterval = time.Second
+
+// apiKeyHeader is the metadata key clients may identify themselves with
+const apiKeyHeader = "x-api-key"
+
+// maxSubmitBatch is the most transactions a SubmitTransactions request may carry
+const maxSubmitBatch = 1000
+
+// Server implements the nodepb.NodeServer gRPC service
+type Server struct {
+	nodepb.UnimplementedNodeServer
+
+	backend Backend
+	mu      sync.Locker // Serializes backend access with other users such as the miner
+	prices  *blockchain.PriceOracle
+}
+
+// NewServer creates a gRPC node service.
+// Pass the lock the miner holds while mutating the chain, or nil if the backend is only used here.
+func NewServer(backend Backend, lock sync.Locker) *Server {
+	if lock == nil {
+		lock = &sync.Mutex{}
+	}
+	return &Server{backend: backend, mu: lock}
+}
+
+// SetPriceOracle lets balance requests ask for fiat equivalents. Call it before serving.
+func (s *Server) SetPriceOracle(oracle *blockchain.PriceOracle) {
+	s.prices = oracle
+}
+
+// Serve registers the service on a new gRPC server and serves it on the listener
+func (s *Server) Serve(listener net.Listener, opts ...grpc.ServerOption) (*grpc.Server, error) {
+	grpcServer := grpc.NewServer(opts...)
+	nodepb.RegisterNodeServer(grpcServer, s)
+	return serve(grpcServer, listener)
+}
+
+// serve starts a gRPC server in the background, returning early if it fails to start
+func serve(grpcServer *grpc.Server, listener net.Listener) (*grpc.Server, error) {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- grpcServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-errs:
+		return nil, err
+	case <-time.After(50 * time.Millisecond):
+		return grpcServer, nil
+	}
+}
+
+// SubmitTransaction adds a transaction to the pool
+func (s *Server) SubmitTransaction(ctx context.Context, req *nodepb.SubmitTransactionRequest) (*nodepb.SubmitTransactionResponse, error) {
+	tx, err := transactionFromProto(req.GetTransaction())
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	err = s.backend.AddTransactionFrom(tx, submissionSource(ctx))
+	s.mu.Unlock()
+
+	if err != nil {
+		return nil, status.Error(submitErrorCode(err), err.Error())
+	}
+	return &nodepb.SubmitTransactionResponse{Hash: tx.Hash}, nil
+}
+
+// SubmitTransactions adds a batch of transactions to the pool in one critical section and
+// reports the outcome of each
+func (s *Server) SubmitTransactions(ctx context.Context, req *nodepb.SubmitTransactionsRequest) (*nodepb.SubmitTransactionsResponse, error) {
+	if len(req.GetTransactions()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "transactions are required")
+	}
+	if len(req.GetTransactions()) > maxSubmitBatch {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d transactions per request", maxSubmitBatch)
+	}
+
+	results := make([]*nodepb.SubmitResult, len(req.GetTransactions()))
+	var txs []*blockchain.Transaction
+	var indexes []int // Index in the request of every transaction in txs
+	for i, pbTx := range req.GetTransactions() {
+		tx, err := transactionFromProto(pbTx)
+		if err != nil {
+			st := status.Convert(err)
+			results[i] = &nodepb.SubmitResult{Hash: pbTx.GetHash(), Code: int32(st.Code()), Error: st.Message()}
+			continue
+		}
+		txs = append(txs, tx)
+		indexes = append(indexes, i)
+	}
+
+	s.mu.Lock()
+	errs := s.backend.AddTransactionsFrom(txs, submissionSource(ctx))
+	s.mu.Unlock()
+
+	resp := &nodepb.SubmitTransactionsResponse{Results: results}
+	for j, tx := range txs {
+		result := &nodepb.SubmitResult{Hash: tx.Hash, Accepted: errs[j] == nil}
+		if errs[j] != nil {
+			result.Code = int32(submitErrorCode(errs[j]))
+			result.Error = errs[j].Error()
+		} else {
+			resp.Accepted++
+		}
+		results[indexes[j]] = result
+	}
+	return resp, nil
+}
+
+// transactionFromProto converts a submitted transaction, checking its hash and size limits
+func transactionFromProto(pbTx *nodepb.Transaction) (*blockchain.Transaction, error) {
+	if pbTx == nil {
+		return nil, status.Error(codes.InvalidArgument, "transaction is required")
+	}
+
+	tx := blockchain.NewSponsoredTransaction(pbTx.GetFrom(), pbTx.GetTo(), pbTx.GetAmount(), pbTx.GetFee(), pbTx.GetNonce(), pbTx.GetSponsor())
+	tx.SetValidUntil(pbTx.GetValidUntil())
+	tx.SetData(pbTx.GetData())
+	if pbTx.GetHash() != "" && pbTx.GetHash() != tx.Hash {
+		return nil, status.Error(codes.InvalidArgument, "transaction hash does not match its contents")
+	}
+	tx.PublicKey = pbTx.GetPublicKey()
+	tx.Signature = pbTx.GetSignature()
+	tx.SponsorKey = pbTx.GetSponsorKey()
+	tx.SponsorSignature = pbTx.GetSponsorSignature()
+	if err := blockchain.CheckTransactionLimits(tx); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return tx, nil
+}
+
+// submitErrorCode returns the status code of a transaction the pool refused
+func submitErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, blockchain.ErrPoolFull), errors.Is(err, blockchain.ErrSenderLimit):
+		return codes.ResourceExhausted
+	case errors.Is(err, blockchain.ErrDuplicateTx), errors.Is(err, blockchain.ErrAlreadyConfirmed):
+		return codes.AlreadyExists
+	case errors.Is(err, blockchain.ErrInvalidTransaction), errors.Is(err, blockchain.ErrInvalidSignature),
+		errors.Is(err, blockchain.ErrInvalidAddress):
+		return codes.InvalidArgument
+	}
+	return codes.FailedPrecondition
+}
+
+// submissionSource identifies the client of a request for the admission log: its address,
+// and a fingerprint of its API key if it sent one (the key itself is never logged)
+func submissionSource(ctx context.Context) string {
+	source := "api"
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		source += ":" + p.Addr.String()
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if keys := md.Get(apiKeyHeader); len(keys) > 0 && keys[0] != "" {
+			fingerprint := sha256.Sum256([]byte(keys[0]))
+			source += " key:" + hex.EncodeToString(fingerprint[:4])
+		}
+	}
+	return source
+}
+
+// GetBalance returns the balance of an address
+func (s *Server) GetBalance(ctx context.Context, req *nodepb.GetBalanceRequest) (*nodepb.GetBalanceResponse, error) {
+	if req.GetAddress() == "" {
+		return nil, status.Error(codes.InvalidArgument, "address is required")
+	}
+
+	s.mu.Lock()
+	balance := s.backend.GetBalance(req.GetAddress())
+	reserved := s.backend.GetReservedBalance(req.GetAddress())
+	s.mu.Unlock()
+
+	resp := &nodepb.GetBalanceResponse{
+		Address:   req.GetAddress(),
+		Balance:   balance,
+		Reserved:  reserved,
+		Available: balance - reserved,
+	}
+	if currency := req.GetFiatCurrency(); currency != "" && s.prices != nil {
+		if fiat := s.prices.Convert(balance, currency); fiat.Available {
+			resp.Fiat = &nodepb.FiatValue{
+				Currency: fiat.Currency,
+				Value:    fiat.Value,
+				Rate:     fiat.Rate,
+				AsOf:     fiat.AsOf.Unix(),
+				Stale:    fiat.Stale,
+			}
+		}
+	}
+	return resp, nil
+}
+
+// GetBlock returns a block by index or hash
+func (s *Server) GetBlock(ctx context.Context, req *nodepb.GetBlockRequest) (*nodepb.Block, error) {
+	var block *blockchain.Block
+	var err error
+
+	s.mu.Lock()
+	switch selector := req.GetSelector().(type) {
+	case *nodepb.GetBlockRequest_Index:
+		block, err = s.backend.GetBlockByIndex(selector.Index)
+	case *nodepb.GetBlockRequest_Hash:
+		block, err = s.backend.GetBlockByHash(selector.Hash)
+	default:
+		s.mu.Unlock()
+		return nil, status.Error(codes.InvalidArgument, "index or hash is required")
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return blockToProto(block), nil
+}
+
+// StreamBlocks sends blocks from the requested height and follows the chain tip
+func (s *Server) StreamBlocks(req *nodepb.StreamBlocksRequest, stream nodepb.Node_StreamBlocksServer) error {
+	next := req.GetFromIndex()
+	if next < 0 {
+		return status.Error(codes.InvalidArgument, "from_index cannot be negative")
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.mu.Lock()
+		latest := s.backend.GetLatestBlock().Index
+		var blocks []*blockchain.Block
+		for ; next <= latest; next++ {
+			block, err := s.backend.GetBlockByIndex(next)
+			if err != nil {
+				s.mu.Unlock()
+				return status.Error(codes.Internal, err.Error())
+			}
+			blocks = append(blocks, block)
+		}
+		s.mu.Unlock()
+
+		for _, block := range blocks {
+			if err := stream.Send(blockToProto(block)); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetTransactionProof returns a Merkle inclusion proof for a transaction
+func (s *Server) GetTransactionProof(ctx context.Context, req *nodepb.GetTransactionProofRequest) (*nodepb.MerkleProof, error) {
+	if req.GetTxHash() == "" {
+		return nil, status.Error(codes.InvalidArgument, "tx_hash is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	proof, err := s.backend.GetTransactionProof(int(req.GetBlockIndex()), req.GetTxHash())
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	block, err := s.backend.GetBlockByIndex(req.GetBlockIndex())
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	return &nodepb.MerkleProof{
+		Hash:       proof.Hash,
+		Hashes:     proof.Hashes,
+		IsLeft:     proof.IsLeft,
+		MerkleRoot: block.MerkleRoot,
+		Index:      int64(proof.Index),
+		Scheme:     int32(proof.Scheme),
+	}, nil
+}
+
+// GetConfirmations returns the confirmation status of a transaction
+func (s *Server) GetConfirmations(ctx context.Context, req *nodepb.GetConfirmationsRequest) (*nodepb.ConfirmationStatus, error) {
+	if req.GetTxHash() == "" {
+		return nil, status.Error(codes.InvalidArgument, "tx_hash is required")
+	}
+
+	s.mu.Lock()
+	confirmation := s.backend.GetConfirmations(req.GetTxHash())
+	s.mu.Unlock()
+
+	return &nodepb.ConfirmationStatus{
+		Hash:          confirmation.Hash,
+		Found:         confirmation.Found,
+		Pending:       confirmation.Pending,
+		BlockIndex:    confirmation.BlockIndex,
+		BlockHash:     confirmation.BlockHash,
+		Confirmations: confirmation.Confirmations,
+		Final:         confirmation.Final,
+	}, nil
+}
+
+// EstimateFee suggests a fee for a confirmation target
+func (s *Server) EstimateFee(ctx context.Context, req *nodepb.EstimateFeeRequest) (*nodepb.FeeEstimate, error) {
+	if req.GetTargetBlocks() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "target_blocks cannot be negative")
+	}
+
+	s.mu.Lock()
+	estimate := s.backend.EstimateFee(int(req.GetTargetBlocks()))
+	s.mu.Unlock()
+
+	return feeEstimateToProto(estimate), nil
+}
+
+// GetFeeMarket describes the pending fee rates, the fees of recent blocks and the projected fee
+func (s *Server) GetFeeMarket(ctx context.Context, req *nodepb.GetFeeMarketRequest) (*nodepb.FeeMarket, error) {
+	if req.GetBlocks() < 0 || req.GetTargetBlocks() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "blocks and target_blocks cannot be negative")
+	}
+
+	s.mu.Lock()
+	market := s.backend.FeeMarket(int(req.GetBlocks()), int(req.GetTargetBlocks()))
+	s.mu.Unlock()
+
+	histogram := make([]*nodepb.FeeHistogramBucket, len(market.Histogram))
+	for i, bucket := range market.Histogram {
+		histogram[i] = &nodepb.FeeHistogramBucket{
+			MinFeeRate: bucket.MinFeeRate,
+			MaxFeeRate: bucket.MaxFeeRate,
+			Count:      int32(bucket.Count),
+			Size:       int64(bucket.Size),
+			TotalFee:   bucket.TotalFee,
+		}
+	}
+	return &nodepb.FeeMarket{
+		Histogram: histogram,
+		Recent: &nodepb.BlockFeeStats{
+			Blocks:        int32(market.Recent.Blocks),
+			Transactions:  int32(market.Recent.Transactions),
+			MedianFee:     market.Recent.MedianFee,
+			MedianFeeRate: market.Recent.MedianFeeRate,
+			MinFee:        market.Recent.MinFee,
+			MaxFee:        market.Recent.MaxFee,
+			TotalFees:     market.Recent.TotalFees,
+		},
+		Projection: feeEstimateToProto(market.Projection),
+	}, nil
+}
+
+// feeEstimateToProto converts a fee estimate to its protobuf message
+func feeEstimateToProto(estimate blockchain.FeeEstimate) *nodepb.FeeEstimate {
+	return &nodepb.FeeEstimate{
+		TargetBlocks: int32(estimate.TargetBlocks),
+		FeeRate:      estimate.FeeRate,
+		MinFee:       estimate.MinFee,
+		MinFeeRate:   estimate.MinFeeRate,
+		Samples:      int32(estimate.Samples),
+		Fallback:     estimate.Fallback,
+		DataByteFee:  estimate.DataByteFee,
+	}
+}
+
+// blockToProto converts a block to its protobuf message
+func blockToProto(block *blockchain.Block) *nodepb.Block {
+	txs := make([]*nodepb.Transaction, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		txs[i] = &nodepb.Transaction{
+			From:      tx.From,
+			To:        tx.To,
+			Amount:    tx.Amount,
+			Fee:       tx.Fee,
+			Nonce:     tx.Nonce,
+			Hash:      tx.Hash,
+			PublicKey: tx.PublicKey,
+			Signature: tx.Signature,
+
+			Sponsor:          tx.Sponsor,
+			SponsorKey:       tx.SponsorKey,
+			SponsorSignature: tx.SponsorSignature,
+
+			ValidUntil: tx.ValidUntil,
+			Data:       tx.Data,
+		}
+	}
+
+	return &nodepb.Block{
+		Version:      block.Version,
+		Index:        block.Index,
+		Timestamp:    block.Timestamp,
+		Transactions: txs,
+		PrevHash:     block.PrevHash,
+		Hash:         block.Hash,
+		Nonce:        block.Nonce,
+		MerkleRoot:   block.MerkleRoot,
+	}
+}
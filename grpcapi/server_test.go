@@ -0,0 +1,55 @@
+package grpcapi
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"blockchain/blockchain"
+	"blockchain/nodepb"
+)
+
+// FuzzSubmitTransaction decodes request bodies the way the gRPC server does and submits them
+// to an in-memory chain
+func FuzzSubmitTransaction(f *testing.F) {
+	seeds := []*nodepb.Transaction{
+		{From: "alice", To: "bob", Amount: 1, Fee: 0.1},
+		{From: "alice", To: "bob", Amount: 1, Fee: 0.1, Nonce: 3, Sponsor: "carol", ValidUntil: 100, Data: []byte("ref")},
+		{From: "miner", To: "kv", Fee: 0.1, Nonce: 1, Data: []byte(`{"key":"k","value":"v"}`)},
+		{From: "alice", To: "bob", Amount: 1, Fee: 0.1, Data: []byte{0xff, 0x00, 0xfe}},
+		{From: "alice", To: "bob", Amount: -1, Hash: "00"},
+	}
+	for _, tx := range seeds {
+		data, err := proto.Marshal(&nodepb.SubmitTransactionRequest{Transaction: tx})
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Add([]byte{})
+
+	log.SetOutput(io.Discard)
+	f.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var req nodepb.SubmitTransactionRequest
+		if err := proto.Unmarshal(data, &req); err != nil {
+			return
+		}
+		chain := blockchain.NewBlockchain(1, "miner")
+		chain.MinePendingTransactions()
+		server := NewServer(chain, nil)
+
+		resp, err := server.SubmitTransaction(context.Background(), &req)
+		if err != nil {
+			return
+		}
+		if _, exists := chain.GetPendingTransaction(resp.GetHash()); !exists {
+			t.Fatalf("accepted transaction %s is not pending", resp.GetHash())
+		}
+	})
+}
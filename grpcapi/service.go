@@ -0,0 +1,98 @@
+package grpcapi
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// RPCService runs a gRPC server as a blockchain.Service, so a Supervisor can restart it
+// without touching the rest of the node. Every start listens anew and builds a new server.
+type RPCService struct {
+	name     string
+	address  string
+	register func(*grpc.Server) // Registers the gRPC services, e.g. nodepb.RegisterNodeServer
+	opts     []grpc.ServerOption
+
+	mu       sync.Mutex
+	server   *grpc.Server
+	done     chan struct{} // Closed when Serve returns
+	serveErr error
+}
+
+// NewRPCService creates a supervised gRPC server listening on address
+func NewRPCService(name, address string, register func(*grpc.Server), opts ...grpc.ServerOption) *RPCService {
+	return &RPCService{name: name, address: address, register: register, opts: opts}
+}
+
+// Name implements blockchain.Service
+func (rs *RPCService) Name() string {
+	return rs.name
+}
+
+// Start listens on the address and serves a new gRPC server
+func (rs *RPCService) Start() error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.server != nil {
+		return errors.New("rpc server is already running")
+	}
+
+	listener, err := net.Listen("tcp", rs.address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", rs.address, err)
+	}
+
+	server := grpc.NewServer(rs.opts...)
+	rs.register(server)
+	done := make(chan struct{})
+	go func() {
+		err := server.Serve(listener)
+		rs.mu.Lock()
+		rs.serveErr = err
+		rs.mu.Unlock()
+		close(done)
+	}()
+
+	rs.server = server
+	rs.done = done
+	rs.serveErr = nil
+	return nil
+}
+
+// Stop stops the server, closing open connections
+func (rs *RPCService) Stop() error {
+	rs.mu.Lock()
+	server, done := rs.server, rs.done
+	rs.server = nil
+	rs.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+	server.Stop()
+	<-done
+	return nil
+}
+
+// Health reports an error once the server has stopped serving
+func (rs *RPCService) Health() error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.server == nil {
+		return errors.New("rpc server is not running")
+	}
+
+	select {
+	case <-rs.done:
+		if rs.serveErr != nil {
+			return fmt.Errorf("rpc server stopped serving: %v", rs.serveErr)
+		}
+		return errors.New("rpc server stopped serving")
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,136 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"blockchain/blockchain"
+	"blockchain/nodepb"
+)
+
+// WalletServer implements the nodepb.WalletServer gRPC service on top of a wallet daemon.
+// It should only be exposed to local applications, e.g. on a loopback listener.
+type WalletServer struct {
+	nodepb.UnimplementedWalletServer
+
+	daemon *blockchain.WalletDaemon
+}
+
+// NewWalletServer creates a gRPC wallet service
+func NewWalletServer(daemon *blockchain.WalletDaemon) *WalletServer {
+	return &WalletServer{daemon: daemon}
+}
+
+// Serve registers the service on a new gRPC server and serves it on the listener
+func (s *WalletServer) Serve(listener net.Listener, opts ...grpc.ServerOption) (*grpc.Server, error) {
+	grpcServer := grpc.NewServer(opts...)
+	nodepb.RegisterWalletServer(grpcServer, s)
+	return serve(grpcServer, listener)
+}
+
+// Unlock decrypts the wallet keys for the requested time
+func (s *WalletServer) Unlock(ctx context.Context, req *nodepb.UnlockRequest) (*nodepb.WalletStatus, error) {
+	timeout := time.Duration(req.GetTimeoutSeconds()) * time.Second
+	if timeout <= 0 || timeout > blockchain.MaxUnlockTimeout {
+		return nil, status.Errorf(codes.InvalidArgument, "timeout must be between 1 and %d seconds",
+			int64(blockchain.MaxUnlockTimeout/time.Second))
+	}
+
+	if err := s.daemon.Unlock(req.GetPassphrase(), timeout); err != nil {
+		if errors.Is(err, blockchain.ErrWrongPassphrase) {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return s.status(), nil
+}
+
+// Lock drops the decrypted wallet keys
+func (s *WalletServer) Lock(ctx context.Context, req *nodepb.LockRequest) (*nodepb.WalletStatus, error) {
+	s.daemon.Lock()
+	return s.status(), nil
+}
+
+// Status reports whether the wallet is unlocked
+func (s *WalletServer) Status(ctx context.Context, req *nodepb.WalletStatusRequest) (*nodepb.WalletStatus, error) {
+	return s.status(), nil
+}
+
+// ListAddresses returns the addresses the wallet holds keys for
+func (s *WalletServer) ListAddresses(ctx context.Context, req *nodepb.ListAddressesRequest) (*nodepb.ListAddressesResponse, error) {
+	return &nodepb.ListAddressesResponse{Addresses: s.daemon.Addresses()}, nil
+}
+
+// NewAddress creates a key encrypted under the wallet passphrase
+func (s *WalletServer) NewAddress(ctx context.Context, req *nodepb.NewAddressRequest) (*nodepb.NewAddressResponse, error) {
+	address, err := s.daemon.NewAddress(req.GetPassphrase())
+	if err != nil {
+		return nil, walletError(err)
+	}
+	return &nodepb.NewAddressResponse{Address: address}, nil
+}
+
+// SignTransaction signs a transaction with the key of its sender
+func (s *WalletServer) SignTransaction(ctx context.Context, req *nodepb.SignTransactionRequest) (*nodepb.SignTransactionResponse, error) {
+	if req.GetTransaction() == nil {
+		return nil, status.Error(codes.InvalidArgument, "transaction is required")
+	}
+
+	pbTx := req.GetTransaction()
+	// The signature covers every hashed field, so the transaction is signed as it will be submitted
+	tx := blockchain.NewSponsoredTransaction(pbTx.GetFrom(), pbTx.GetTo(), pbTx.GetAmount(), pbTx.GetFee(), pbTx.GetNonce(), pbTx.GetSponsor())
+	tx.SetValidUntil(pbTx.GetValidUntil())
+	tx.SetData(pbTx.GetData())
+	if pbTx.GetHash() != "" && pbTx.GetHash() != tx.Hash {
+		return nil, status.Error(codes.InvalidArgument, "transaction hash does not match its contents")
+	}
+	if err := blockchain.CheckTransactionLimits(tx); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := s.daemon.AttachSignature(tx); err != nil {
+		return nil, walletError(err)
+	}
+	return &nodepb.SignTransactionResponse{Hash: tx.Hash, Signature: tx.Signature, PublicKey: tx.PublicKey}, nil
+}
+
+// SignMessage signs a message with the key of an address
+func (s *WalletServer) SignMessage(ctx context.Context, req *nodepb.SignMessageRequest) (*nodepb.SignMessageResponse, error) {
+	if req.GetAddress() == "" {
+		return nil, status.Error(codes.InvalidArgument, "address is required")
+	}
+
+	signature, err := s.daemon.SignMessage(req.GetAddress(), req.GetMessage())
+	if err != nil {
+		return nil, walletError(err)
+	}
+	return &nodepb.SignMessageResponse{Signature: signature}, nil
+}
+
+// status returns the current lock state of the daemon
+func (s *WalletServer) status() *nodepb.WalletStatus {
+	unlocked, until := s.daemon.Status()
+	resp := &nodepb.WalletStatus{Unlocked: unlocked}
+	if unlocked {
+		resp.UnlockedUntil = until.Unix()
+	}
+	return resp
+}
+
+// walletError maps wallet daemon errors to gRPC status codes
+func walletError(err error) error {
+	switch {
+	case errors.Is(err, blockchain.ErrWalletLocked):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, blockchain.ErrWrongPassphrase):
+		return status.Error(codes.PermissionDenied, err.Error())
+	default:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+}
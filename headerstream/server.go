@@ -0,0 +1,315 @@
+// Package headerstream streams compact binary block headers over HTTP and WebSocket,
+// for SPV light clients on devices too small to speak gRPC.
+package headerstream
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"blockchain/blockchain"
+)
+
+// Frame types. Every frame is a type byte followed by its payload; over WebSocket
+// each frame is sent as one binary message.
+const (
+	FrameHeader    byte = 1 // Followed by a compact header (blockchain.CompactHeaderLength bytes)
+	FrameKeepAlive byte = 2 // Followed by the resumption cursor: the next height to be streamed (8 bytes)
+	FrameReset     byte = 3 // Followed by the height streaming restarts from after a reorg (8 bytes)
+)
+
+// maxHeadersPerPass bounds how many headers are read per chain lock hold
+const maxHeadersPerPass = 500
+
+// Source is the chain surface headers are streamed from.
+// Both *blockchain.Blockchain and *blockchain.PersistentBlockchain implement it.
+type Source interface {
+	GetLatestBlock() *blockchain.Block
+	GetHeader(index int64) (blockchain.BlockHeader, error)
+}
+
+// Config configures header streaming
+type Config struct {
+	PollInterval   time.Duration // How often streams check for new blocks
+	KeepAlive      time.Duration // Idle time after which a keep-alive frame is sent
+	ResumeLookback int64         // Headers re-sent when a resumption cursor or streamed header left the chain
+}
+
+// Frame is a decoded stream frame
+type Frame struct {
+	Type   byte
+	Header *blockchain.BlockHeader // Set for header frames
+	Height int64                   // Set for keep-alive and reset frames
+}
+
+// Server streams headers from a chain.
+//
+// GET /headers?from=H[&prev=HASH] streams frames over a chunked HTTP response and
+// GET /headers/ws with the same parameters streams them over WebSocket. A client resumes
+// by reconnecting with from set to the height after its last header and prev set to that
+// header's hash; if the header has left the chain, a reset frame rewinds the client.
+type Server struct {
+	source Source
+	lock   sync.Locker // Serializes chain access with other users such as the miner
+	config Config
+}
+
+// NewServer creates a header streaming service.
+// Pass the lock the miner holds while mutating the chain, or nil if the chain is only used here.
+func NewServer(source Source, lock sync.Locker, config Config) *Server {
+	if lock == nil {
+		lock = &sync.Mutex{}
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	if config.KeepAlive <= 0 {
+		config.KeepAlive = 15 * time.Second
+	}
+	if config.ResumeLookback <= 0 {
+		config.ResumeLookback = 6
+	}
+	return &Server{source: source, lock: lock, config: config}
+}
+
+// Handler returns the HTTP handler serving both endpoints
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/headers", s.serveHTTP)
+	// websocket.Server skips the origin check of websocket.Handler; devices send no Origin
+	mux.Handle("/headers/ws", websocket.Server{Handler: s.serveWebSocket})
+	return mux
+}
+
+// Serve serves the endpoints on the listener in the background, returning early if it fails to start
+func (s *Server) Serve(listener net.Listener) (*http.Server, error) {
+	httpServer := &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- httpServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-errs:
+		return nil, err
+	case <-time.After(50 * time.Millisecond):
+		return httpServer, nil
+	}
+}
+
+// serveHTTP streams frames over a chunked HTTP response
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	from, prev, err := parseCursor(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	s.stream(r.Context(), from, prev, func(frame []byte) error {
+		if _, err := w.Write(frame); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+}
+
+// serveWebSocket streams frames as binary WebSocket messages
+func (s *Server) serveWebSocket(ws *websocket.Conn) {
+	defer ws.Close()
+
+	from, prev, err := parseCursor(ws.Request())
+	if err != nil {
+		websocket.Message.Send(ws, err.Error())
+		return
+	}
+
+	s.stream(ws.Request().Context(), from, prev, func(frame []byte) error {
+		return websocket.Message.Send(ws, frame)
+	})
+}
+
+// parseCursor reads the resumption cursor of a request
+func parseCursor(r *http.Request) (int64, string, error) {
+	query := r.URL.Query()
+	var from int64
+	if value := query.Get("from"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 0 {
+			return 0, "", errors.New("from must be a non-negative height")
+		}
+		from = parsed
+	}
+	return from, query.Get("prev"), nil
+}
+
+// sentHeader is a header already streamed, remembered to detect reorgs
+type sentHeader struct {
+	height int64
+	hash   string
+}
+
+// stream writes frames from a height onward until the context ends or a write fails
+func (s *Server) stream(ctx context.Context, next int64, prev string, write func([]byte) error) error {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	// A cursor whose header left the chain restarts a few headers back
+	if prev != "" && next > 0 {
+		s.lock.Lock()
+		header, err := s.source.GetHeader(next - 1)
+		s.lock.Unlock()
+		if err != nil || header.Hash != prev {
+			next = max(next-s.config.ResumeLookback, 0)
+			if err := write(heightFrame(FrameReset, next)); err != nil {
+				return err
+			}
+		}
+	}
+
+	var sent []sentHeader
+	lastWrite := time.Now()
+	for {
+		headers, rewind := s.nextHeaders(next, sent)
+		if rewind >= 0 {
+			next = rewind
+			for len(sent) > 0 && sent[len(sent)-1].height >= next {
+				sent = sent[:len(sent)-1]
+			}
+			if err := write(heightFrame(FrameReset, next)); err != nil {
+				return err
+			}
+			lastWrite = time.Now()
+		}
+
+		for _, header := range headers {
+			encoded, err := header.MarshalCompact()
+			if err != nil {
+				return fmt.Errorf("failed to encode header %d: %v", header.Index, err)
+			}
+			if err := write(append([]byte{FrameHeader}, encoded...)); err != nil {
+				return err
+			}
+			sent = append(sent, sentHeader{height: header.Index, hash: header.Hash})
+			if int64(len(sent)) > s.config.ResumeLookback {
+				sent = sent[1:]
+			}
+			next = header.Index + 1
+			lastWrite = time.Now()
+		}
+		if len(headers) == maxHeadersPerPass {
+			continue // Catching up, don't wait for the next poll
+		}
+
+		if time.Since(lastWrite) >= s.config.KeepAlive {
+			if err := write(heightFrame(FrameKeepAlive, next)); err != nil {
+				return err
+			}
+			lastWrite = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// nextHeaders reads the headers from a height onward. If a streamed header left the chain
+// it returns the height streaming must restart from, just above the newest streamed header
+// still on the chain (or the oldest remembered one if none is); otherwise rewind is -1.
+func (s *Server) nextHeaders(next int64, sent []sentHeader) (headers []blockchain.BlockHeader, rewind int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	rewind = -1
+	if len(sent) > 0 && !s.onChain(sent[len(sent)-1]) {
+		rewind = sent[0].height
+		for i := len(sent) - 2; i >= 0; i-- {
+			if s.onChain(sent[i]) {
+				rewind = sent[i].height + 1
+				break
+			}
+		}
+		next = rewind
+	}
+
+	tip := s.source.GetLatestBlock().Index
+	for height := next; height <= tip && len(headers) < maxHeadersPerPass; height++ {
+		header, err := s.source.GetHeader(height)
+		if err != nil {
+			break
+		}
+		headers = append(headers, header)
+	}
+	return headers, rewind
+}
+
+// onChain checks a streamed header is still part of the chain (caller must hold the lock)
+func (s *Server) onChain(header sentHeader) bool {
+	stored, err := s.source.GetHeader(header.height)
+	return err == nil && stored.Hash == header.hash
+}
+
+// heightFrame encodes a keep-alive or reset frame
+func heightFrame(frameType byte, height int64) []byte {
+	frame := make([]byte, 9)
+	frame[0] = frameType
+	binary.BigEndian.PutUint64(frame[1:], uint64(height))
+	return frame
+}
+
+// ReadFrame reads the next frame of an HTTP header stream
+func ReadFrame(r io.Reader) (*Frame, error) {
+	var frameType [1]byte
+	if _, err := io.ReadFull(r, frameType[:]); err != nil {
+		return nil, err
+	}
+
+	switch frameType[0] {
+	case FrameHeader:
+		var prefix [blockchain.CompactHeaderPrefix]byte
+		if _, err := io.ReadFull(r, prefix[:]); err != nil {
+			return nil, err
+		}
+		data := make([]byte, blockchain.CompactHeaderLength(prefix[:]))
+		copy(data, prefix[:])
+		if _, err := io.ReadFull(r, data[len(prefix):]); err != nil {
+			return nil, err
+		}
+		header, err := blockchain.UnmarshalCompactHeader(data)
+		if err != nil {
+			return nil, err
+		}
+		return &Frame{Type: FrameHeader, Header: header}, nil
+	case FrameKeepAlive, FrameReset:
+		var height [8]byte
+		if _, err := io.ReadFull(r, height[:]); err != nil {
+			return nil, err
+		}
+		return &Frame{Type: frameType[0], Height: int64(binary.BigEndian.Uint64(height[:]))}, nil
+	default:
+		return nil, fmt.Errorf("unknown frame type %d", frameType[0])
+	}
+}
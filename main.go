@@ -8,7 +8,8 @@ import (
 )
 
 func main() {
-	fmt.Println("=== Enhanced Blockchain with Merkle Trees ===\n")
+	fmt.Println("=== Enhanced Blockchain with Merkle Trees ===")
+	fmt.Println()
 
 	// Create a new blockchain with difficulty 4
 	bc := blockchain.NewBlockchain(4, "miner1")
@@ -76,7 +77,7 @@ func main() {
 			fmt.Printf("Proof verification result: %v\n", isValid)
 
 			// Demonstrate light client verification (without full block data)
-			isValidDirect := blockchain.VerifyProof(proof, latestBlock.MerkleRoot)
+			isValidDirect := blockchain.VerifyProof(proof, latestBlock.MerkleRoot, latestBlock.MerkleScheme())
 			fmt.Printf("Direct proof verification: %v\n", isValidDirect)
 		}
 	}
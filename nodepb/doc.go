@@ -0,0 +1,5 @@
+// Package nodepb contains the protobuf messages and gRPC client/server stubs of the node API.
+// Other services can depend on this package alone to talk to a node.
+package nodepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative node.proto admin.proto wallet.proto
@@ -0,0 +1,1776 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: node.proto
+
+package nodepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Transaction struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	From             string                 `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To               string                 `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	Amount           float64                `protobuf:"fixed64,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	Fee              float64                `protobuf:"fixed64,4,opt,name=fee,proto3" json:"fee,omitempty"`
+	Nonce            int64                  `protobuf:"varint,5,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	Hash             string                 `protobuf:"bytes,6,opt,name=hash,proto3" json:"hash,omitempty"`
+	PublicKey        string                 `protobuf:"bytes,7,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	Signature        string                 `protobuf:"bytes,8,opt,name=signature,proto3" json:"signature,omitempty"`
+	Sponsor          string                 `protobuf:"bytes,9,opt,name=sponsor,proto3" json:"sponsor,omitempty"`
+	SponsorKey       string                 `protobuf:"bytes,10,opt,name=sponsor_key,json=sponsorKey,proto3" json:"sponsor_key,omitempty"`
+	SponsorSignature string                 `protobuf:"bytes,11,opt,name=sponsor_signature,json=sponsorSignature,proto3" json:"sponsor_signature,omitempty"`
+	ValidUntil       int64                  `protobuf:"varint,12,opt,name=valid_until,json=validUntil,proto3" json:"valid_until,omitempty"`
+	Data             []byte                 `protobuf:"bytes,13,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Transaction) Reset() {
+	*x = Transaction{}
+	mi := &file_node_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Transaction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Transaction) ProtoMessage() {}
+
+func (x *Transaction) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Transaction.ProtoReflect.Descriptor instead.
+func (*Transaction) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Transaction) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *Transaction) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *Transaction) GetAmount() float64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *Transaction) GetFee() float64 {
+	if x != nil {
+		return x.Fee
+	}
+	return 0
+}
+
+func (x *Transaction) GetNonce() int64 {
+	if x != nil {
+		return x.Nonce
+	}
+	return 0
+}
+
+func (x *Transaction) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *Transaction) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *Transaction) GetSignature() string {
+	if x != nil {
+		return x.Signature
+	}
+	return ""
+}
+
+func (x *Transaction) GetSponsor() string {
+	if x != nil {
+		return x.Sponsor
+	}
+	return ""
+}
+
+func (x *Transaction) GetSponsorKey() string {
+	if x != nil {
+		return x.SponsorKey
+	}
+	return ""
+}
+
+func (x *Transaction) GetSponsorSignature() string {
+	if x != nil {
+		return x.SponsorSignature
+	}
+	return ""
+}
+
+func (x *Transaction) GetValidUntil() int64 {
+	if x != nil {
+		return x.ValidUntil
+	}
+	return 0
+}
+
+func (x *Transaction) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type Block struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       int32                  `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Index         int64                  `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Transactions  []*Transaction         `protobuf:"bytes,4,rep,name=transactions,proto3" json:"transactions,omitempty"`
+	PrevHash      string                 `protobuf:"bytes,5,opt,name=prev_hash,json=prevHash,proto3" json:"prev_hash,omitempty"`
+	Hash          string                 `protobuf:"bytes,6,opt,name=hash,proto3" json:"hash,omitempty"`
+	Nonce         int64                  `protobuf:"varint,7,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	MerkleRoot    string                 `protobuf:"bytes,8,opt,name=merkle_root,json=merkleRoot,proto3" json:"merkle_root,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Block) Reset() {
+	*x = Block{}
+	mi := &file_node_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Block) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Block) ProtoMessage() {}
+
+func (x *Block) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Block.ProtoReflect.Descriptor instead.
+func (*Block) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Block) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Block) GetIndex() int64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *Block) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *Block) GetTransactions() []*Transaction {
+	if x != nil {
+		return x.Transactions
+	}
+	return nil
+}
+
+func (x *Block) GetPrevHash() string {
+	if x != nil {
+		return x.PrevHash
+	}
+	return ""
+}
+
+func (x *Block) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *Block) GetNonce() int64 {
+	if x != nil {
+		return x.Nonce
+	}
+	return 0
+}
+
+func (x *Block) GetMerkleRoot() string {
+	if x != nil {
+		return x.MerkleRoot
+	}
+	return ""
+}
+
+type MerkleProof struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hash          string                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	Hashes        []string               `protobuf:"bytes,2,rep,name=hashes,proto3" json:"hashes,omitempty"`
+	IsLeft        []bool                 `protobuf:"varint,3,rep,packed,name=is_left,json=isLeft,proto3" json:"is_left,omitempty"`
+	MerkleRoot    string                 `protobuf:"bytes,4,opt,name=merkle_root,json=merkleRoot,proto3" json:"merkle_root,omitempty"`
+	Index         int64                  `protobuf:"varint,5,opt,name=index,proto3" json:"index,omitempty"`
+	Scheme        int32                  `protobuf:"varint,6,opt,name=scheme,proto3" json:"scheme,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MerkleProof) Reset() {
+	*x = MerkleProof{}
+	mi := &file_node_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MerkleProof) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MerkleProof) ProtoMessage() {}
+
+func (x *MerkleProof) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MerkleProof.ProtoReflect.Descriptor instead.
+func (*MerkleProof) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *MerkleProof) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *MerkleProof) GetHashes() []string {
+	if x != nil {
+		return x.Hashes
+	}
+	return nil
+}
+
+func (x *MerkleProof) GetIsLeft() []bool {
+	if x != nil {
+		return x.IsLeft
+	}
+	return nil
+}
+
+func (x *MerkleProof) GetMerkleRoot() string {
+	if x != nil {
+		return x.MerkleRoot
+	}
+	return ""
+}
+
+func (x *MerkleProof) GetIndex() int64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *MerkleProof) GetScheme() int32 {
+	if x != nil {
+		return x.Scheme
+	}
+	return 0
+}
+
+type SubmitTransactionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transaction   *Transaction           `protobuf:"bytes,1,opt,name=transaction,proto3" json:"transaction,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitTransactionRequest) Reset() {
+	*x = SubmitTransactionRequest{}
+	mi := &file_node_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitTransactionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitTransactionRequest) ProtoMessage() {}
+
+func (x *SubmitTransactionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitTransactionRequest.ProtoReflect.Descriptor instead.
+func (*SubmitTransactionRequest) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SubmitTransactionRequest) GetTransaction() *Transaction {
+	if x != nil {
+		return x.Transaction
+	}
+	return nil
+}
+
+type SubmitTransactionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hash          string                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitTransactionResponse) Reset() {
+	*x = SubmitTransactionResponse{}
+	mi := &file_node_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitTransactionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitTransactionResponse) ProtoMessage() {}
+
+func (x *SubmitTransactionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitTransactionResponse.ProtoReflect.Descriptor instead.
+func (*SubmitTransactionResponse) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SubmitTransactionResponse) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+type SubmitTransactionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transactions  []*Transaction         `protobuf:"bytes,1,rep,name=transactions,proto3" json:"transactions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitTransactionsRequest) Reset() {
+	*x = SubmitTransactionsRequest{}
+	mi := &file_node_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitTransactionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitTransactionsRequest) ProtoMessage() {}
+
+func (x *SubmitTransactionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitTransactionsRequest.ProtoReflect.Descriptor instead.
+func (*SubmitTransactionsRequest) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SubmitTransactionsRequest) GetTransactions() []*Transaction {
+	if x != nil {
+		return x.Transactions
+	}
+	return nil
+}
+
+type SubmitTransactionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*SubmitResult        `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	Accepted      int32                  `protobuf:"varint,2,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitTransactionsResponse) Reset() {
+	*x = SubmitTransactionsResponse{}
+	mi := &file_node_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitTransactionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitTransactionsResponse) ProtoMessage() {}
+
+func (x *SubmitTransactionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitTransactionsResponse.ProtoReflect.Descriptor instead.
+func (*SubmitTransactionsResponse) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SubmitTransactionsResponse) GetResults() []*SubmitResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *SubmitTransactionsResponse) GetAccepted() int32 {
+	if x != nil {
+		return x.Accepted
+	}
+	return 0
+}
+
+type SubmitResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hash          string                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	Accepted      bool                   `protobuf:"varint,2,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	Code          int32                  `protobuf:"varint,3,opt,name=code,proto3" json:"code,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitResult) Reset() {
+	*x = SubmitResult{}
+	mi := &file_node_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitResult) ProtoMessage() {}
+
+func (x *SubmitResult) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitResult.ProtoReflect.Descriptor instead.
+func (*SubmitResult) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SubmitResult) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *SubmitResult) GetAccepted() bool {
+	if x != nil {
+		return x.Accepted
+	}
+	return false
+}
+
+func (x *SubmitResult) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *SubmitResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type GetBalanceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	FiatCurrency  string                 `protobuf:"bytes,2,opt,name=fiat_currency,json=fiatCurrency,proto3" json:"fiat_currency,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBalanceRequest) Reset() {
+	*x = GetBalanceRequest{}
+	mi := &file_node_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBalanceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBalanceRequest) ProtoMessage() {}
+
+func (x *GetBalanceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBalanceRequest.ProtoReflect.Descriptor instead.
+func (*GetBalanceRequest) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetBalanceRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *GetBalanceRequest) GetFiatCurrency() string {
+	if x != nil {
+		return x.FiatCurrency
+	}
+	return ""
+}
+
+type GetBalanceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Balance       float64                `protobuf:"fixed64,2,opt,name=balance,proto3" json:"balance,omitempty"`
+	Reserved      float64                `protobuf:"fixed64,3,opt,name=reserved,proto3" json:"reserved,omitempty"`
+	Available     float64                `protobuf:"fixed64,4,opt,name=available,proto3" json:"available,omitempty"`
+	Fiat          *FiatValue             `protobuf:"bytes,5,opt,name=fiat,proto3" json:"fiat,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBalanceResponse) Reset() {
+	*x = GetBalanceResponse{}
+	mi := &file_node_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBalanceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBalanceResponse) ProtoMessage() {}
+
+func (x *GetBalanceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBalanceResponse.ProtoReflect.Descriptor instead.
+func (*GetBalanceResponse) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetBalanceResponse) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *GetBalanceResponse) GetBalance() float64 {
+	if x != nil {
+		return x.Balance
+	}
+	return 0
+}
+
+func (x *GetBalanceResponse) GetReserved() float64 {
+	if x != nil {
+		return x.Reserved
+	}
+	return 0
+}
+
+func (x *GetBalanceResponse) GetAvailable() float64 {
+	if x != nil {
+		return x.Available
+	}
+	return 0
+}
+
+func (x *GetBalanceResponse) GetFiat() *FiatValue {
+	if x != nil {
+		return x.Fiat
+	}
+	return nil
+}
+
+type FiatValue struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Currency      string                 `protobuf:"bytes,1,opt,name=currency,proto3" json:"currency,omitempty"`
+	Value         float64                `protobuf:"fixed64,2,opt,name=value,proto3" json:"value,omitempty"`
+	Rate          float64                `protobuf:"fixed64,3,opt,name=rate,proto3" json:"rate,omitempty"`
+	AsOf          int64                  `protobuf:"varint,4,opt,name=as_of,json=asOf,proto3" json:"as_of,omitempty"`
+	Stale         bool                   `protobuf:"varint,5,opt,name=stale,proto3" json:"stale,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FiatValue) Reset() {
+	*x = FiatValue{}
+	mi := &file_node_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FiatValue) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FiatValue) ProtoMessage() {}
+
+func (x *FiatValue) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FiatValue.ProtoReflect.Descriptor instead.
+func (*FiatValue) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *FiatValue) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *FiatValue) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *FiatValue) GetRate() float64 {
+	if x != nil {
+		return x.Rate
+	}
+	return 0
+}
+
+func (x *FiatValue) GetAsOf() int64 {
+	if x != nil {
+		return x.AsOf
+	}
+	return 0
+}
+
+func (x *FiatValue) GetStale() bool {
+	if x != nil {
+		return x.Stale
+	}
+	return false
+}
+
+type GetBlockRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Selector:
+	//
+	//	*GetBlockRequest_Index
+	//	*GetBlockRequest_Hash
+	Selector      isGetBlockRequest_Selector `protobuf_oneof:"selector"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBlockRequest) Reset() {
+	*x = GetBlockRequest{}
+	mi := &file_node_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBlockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBlockRequest) ProtoMessage() {}
+
+func (x *GetBlockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBlockRequest.ProtoReflect.Descriptor instead.
+func (*GetBlockRequest) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetBlockRequest) GetSelector() isGetBlockRequest_Selector {
+	if x != nil {
+		return x.Selector
+	}
+	return nil
+}
+
+func (x *GetBlockRequest) GetIndex() int64 {
+	if x != nil {
+		if x, ok := x.Selector.(*GetBlockRequest_Index); ok {
+			return x.Index
+		}
+	}
+	return 0
+}
+
+func (x *GetBlockRequest) GetHash() string {
+	if x != nil {
+		if x, ok := x.Selector.(*GetBlockRequest_Hash); ok {
+			return x.Hash
+		}
+	}
+	return ""
+}
+
+type isGetBlockRequest_Selector interface {
+	isGetBlockRequest_Selector()
+}
+
+type GetBlockRequest_Index struct {
+	Index int64 `protobuf:"varint,1,opt,name=index,proto3,oneof"`
+}
+
+type GetBlockRequest_Hash struct {
+	Hash string `protobuf:"bytes,2,opt,name=hash,proto3,oneof"`
+}
+
+func (*GetBlockRequest_Index) isGetBlockRequest_Selector() {}
+
+func (*GetBlockRequest_Hash) isGetBlockRequest_Selector() {}
+
+type StreamBlocksRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FromIndex     int64                  `protobuf:"varint,1,opt,name=from_index,json=fromIndex,proto3" json:"from_index,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamBlocksRequest) Reset() {
+	*x = StreamBlocksRequest{}
+	mi := &file_node_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamBlocksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamBlocksRequest) ProtoMessage() {}
+
+func (x *StreamBlocksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamBlocksRequest.ProtoReflect.Descriptor instead.
+func (*StreamBlocksRequest) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *StreamBlocksRequest) GetFromIndex() int64 {
+	if x != nil {
+		return x.FromIndex
+	}
+	return 0
+}
+
+type GetTransactionProofRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BlockIndex    int64                  `protobuf:"varint,1,opt,name=block_index,json=blockIndex,proto3" json:"block_index,omitempty"`
+	TxHash        string                 `protobuf:"bytes,2,opt,name=tx_hash,json=txHash,proto3" json:"tx_hash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTransactionProofRequest) Reset() {
+	*x = GetTransactionProofRequest{}
+	mi := &file_node_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTransactionProofRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTransactionProofRequest) ProtoMessage() {}
+
+func (x *GetTransactionProofRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTransactionProofRequest.ProtoReflect.Descriptor instead.
+func (*GetTransactionProofRequest) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *GetTransactionProofRequest) GetBlockIndex() int64 {
+	if x != nil {
+		return x.BlockIndex
+	}
+	return 0
+}
+
+func (x *GetTransactionProofRequest) GetTxHash() string {
+	if x != nil {
+		return x.TxHash
+	}
+	return ""
+}
+
+type GetConfirmationsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TxHash        string                 `protobuf:"bytes,1,opt,name=tx_hash,json=txHash,proto3" json:"tx_hash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetConfirmationsRequest) Reset() {
+	*x = GetConfirmationsRequest{}
+	mi := &file_node_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetConfirmationsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetConfirmationsRequest) ProtoMessage() {}
+
+func (x *GetConfirmationsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetConfirmationsRequest.ProtoReflect.Descriptor instead.
+func (*GetConfirmationsRequest) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetConfirmationsRequest) GetTxHash() string {
+	if x != nil {
+		return x.TxHash
+	}
+	return ""
+}
+
+type ConfirmationStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hash          string                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	Found         bool                   `protobuf:"varint,2,opt,name=found,proto3" json:"found,omitempty"`
+	Pending       bool                   `protobuf:"varint,3,opt,name=pending,proto3" json:"pending,omitempty"`
+	BlockIndex    int64                  `protobuf:"varint,4,opt,name=block_index,json=blockIndex,proto3" json:"block_index,omitempty"`
+	BlockHash     string                 `protobuf:"bytes,5,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
+	Confirmations int64                  `protobuf:"varint,6,opt,name=confirmations,proto3" json:"confirmations,omitempty"`
+	Final         bool                   `protobuf:"varint,7,opt,name=final,proto3" json:"final,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfirmationStatus) Reset() {
+	*x = ConfirmationStatus{}
+	mi := &file_node_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfirmationStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfirmationStatus) ProtoMessage() {}
+
+func (x *ConfirmationStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfirmationStatus.ProtoReflect.Descriptor instead.
+func (*ConfirmationStatus) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ConfirmationStatus) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *ConfirmationStatus) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+func (x *ConfirmationStatus) GetPending() bool {
+	if x != nil {
+		return x.Pending
+	}
+	return false
+}
+
+func (x *ConfirmationStatus) GetBlockIndex() int64 {
+	if x != nil {
+		return x.BlockIndex
+	}
+	return 0
+}
+
+func (x *ConfirmationStatus) GetBlockHash() string {
+	if x != nil {
+		return x.BlockHash
+	}
+	return ""
+}
+
+func (x *ConfirmationStatus) GetConfirmations() int64 {
+	if x != nil {
+		return x.Confirmations
+	}
+	return 0
+}
+
+func (x *ConfirmationStatus) GetFinal() bool {
+	if x != nil {
+		return x.Final
+	}
+	return false
+}
+
+type EstimateFeeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TargetBlocks  int32                  `protobuf:"varint,1,opt,name=target_blocks,json=targetBlocks,proto3" json:"target_blocks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EstimateFeeRequest) Reset() {
+	*x = EstimateFeeRequest{}
+	mi := &file_node_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EstimateFeeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EstimateFeeRequest) ProtoMessage() {}
+
+func (x *EstimateFeeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EstimateFeeRequest.ProtoReflect.Descriptor instead.
+func (*EstimateFeeRequest) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *EstimateFeeRequest) GetTargetBlocks() int32 {
+	if x != nil {
+		return x.TargetBlocks
+	}
+	return 0
+}
+
+type FeeEstimate struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TargetBlocks  int32                  `protobuf:"varint,1,opt,name=target_blocks,json=targetBlocks,proto3" json:"target_blocks,omitempty"`
+	FeeRate       float64                `protobuf:"fixed64,2,opt,name=fee_rate,json=feeRate,proto3" json:"fee_rate,omitempty"`
+	MinFee        float64                `protobuf:"fixed64,3,opt,name=min_fee,json=minFee,proto3" json:"min_fee,omitempty"`
+	MinFeeRate    float64                `protobuf:"fixed64,4,opt,name=min_fee_rate,json=minFeeRate,proto3" json:"min_fee_rate,omitempty"`
+	Samples       int32                  `protobuf:"varint,5,opt,name=samples,proto3" json:"samples,omitempty"`
+	Fallback      bool                   `protobuf:"varint,6,opt,name=fallback,proto3" json:"fallback,omitempty"`
+	DataByteFee   float64                `protobuf:"fixed64,7,opt,name=data_byte_fee,json=dataByteFee,proto3" json:"data_byte_fee,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FeeEstimate) Reset() {
+	*x = FeeEstimate{}
+	mi := &file_node_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FeeEstimate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FeeEstimate) ProtoMessage() {}
+
+func (x *FeeEstimate) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FeeEstimate.ProtoReflect.Descriptor instead.
+func (*FeeEstimate) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *FeeEstimate) GetTargetBlocks() int32 {
+	if x != nil {
+		return x.TargetBlocks
+	}
+	return 0
+}
+
+func (x *FeeEstimate) GetFeeRate() float64 {
+	if x != nil {
+		return x.FeeRate
+	}
+	return 0
+}
+
+func (x *FeeEstimate) GetMinFee() float64 {
+	if x != nil {
+		return x.MinFee
+	}
+	return 0
+}
+
+func (x *FeeEstimate) GetMinFeeRate() float64 {
+	if x != nil {
+		return x.MinFeeRate
+	}
+	return 0
+}
+
+func (x *FeeEstimate) GetSamples() int32 {
+	if x != nil {
+		return x.Samples
+	}
+	return 0
+}
+
+func (x *FeeEstimate) GetFallback() bool {
+	if x != nil {
+		return x.Fallback
+	}
+	return false
+}
+
+func (x *FeeEstimate) GetDataByteFee() float64 {
+	if x != nil {
+		return x.DataByteFee
+	}
+	return 0
+}
+
+type GetFeeMarketRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Blocks        int32                  `protobuf:"varint,1,opt,name=blocks,proto3" json:"blocks,omitempty"`
+	TargetBlocks  int32                  `protobuf:"varint,2,opt,name=target_blocks,json=targetBlocks,proto3" json:"target_blocks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetFeeMarketRequest) Reset() {
+	*x = GetFeeMarketRequest{}
+	mi := &file_node_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetFeeMarketRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetFeeMarketRequest) ProtoMessage() {}
+
+func (x *GetFeeMarketRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetFeeMarketRequest.ProtoReflect.Descriptor instead.
+func (*GetFeeMarketRequest) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *GetFeeMarketRequest) GetBlocks() int32 {
+	if x != nil {
+		return x.Blocks
+	}
+	return 0
+}
+
+func (x *GetFeeMarketRequest) GetTargetBlocks() int32 {
+	if x != nil {
+		return x.TargetBlocks
+	}
+	return 0
+}
+
+type FeeHistogramBucket struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinFeeRate    float64                `protobuf:"fixed64,1,opt,name=min_fee_rate,json=minFeeRate,proto3" json:"min_fee_rate,omitempty"`
+	MaxFeeRate    float64                `protobuf:"fixed64,2,opt,name=max_fee_rate,json=maxFeeRate,proto3" json:"max_fee_rate,omitempty"`
+	Count         int32                  `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	Size          int64                  `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`
+	TotalFee      float64                `protobuf:"fixed64,5,opt,name=total_fee,json=totalFee,proto3" json:"total_fee,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FeeHistogramBucket) Reset() {
+	*x = FeeHistogramBucket{}
+	mi := &file_node_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FeeHistogramBucket) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FeeHistogramBucket) ProtoMessage() {}
+
+func (x *FeeHistogramBucket) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FeeHistogramBucket.ProtoReflect.Descriptor instead.
+func (*FeeHistogramBucket) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *FeeHistogramBucket) GetMinFeeRate() float64 {
+	if x != nil {
+		return x.MinFeeRate
+	}
+	return 0
+}
+
+func (x *FeeHistogramBucket) GetMaxFeeRate() float64 {
+	if x != nil {
+		return x.MaxFeeRate
+	}
+	return 0
+}
+
+func (x *FeeHistogramBucket) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *FeeHistogramBucket) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *FeeHistogramBucket) GetTotalFee() float64 {
+	if x != nil {
+		return x.TotalFee
+	}
+	return 0
+}
+
+type BlockFeeStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Blocks        int32                  `protobuf:"varint,1,opt,name=blocks,proto3" json:"blocks,omitempty"`
+	Transactions  int32                  `protobuf:"varint,2,opt,name=transactions,proto3" json:"transactions,omitempty"`
+	MedianFee     float64                `protobuf:"fixed64,3,opt,name=median_fee,json=medianFee,proto3" json:"median_fee,omitempty"`
+	MedianFeeRate float64                `protobuf:"fixed64,4,opt,name=median_fee_rate,json=medianFeeRate,proto3" json:"median_fee_rate,omitempty"`
+	MinFee        float64                `protobuf:"fixed64,5,opt,name=min_fee,json=minFee,proto3" json:"min_fee,omitempty"`
+	MaxFee        float64                `protobuf:"fixed64,6,opt,name=max_fee,json=maxFee,proto3" json:"max_fee,omitempty"`
+	TotalFees     float64                `protobuf:"fixed64,7,opt,name=total_fees,json=totalFees,proto3" json:"total_fees,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BlockFeeStats) Reset() {
+	*x = BlockFeeStats{}
+	mi := &file_node_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BlockFeeStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockFeeStats) ProtoMessage() {}
+
+func (x *BlockFeeStats) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockFeeStats.ProtoReflect.Descriptor instead.
+func (*BlockFeeStats) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *BlockFeeStats) GetBlocks() int32 {
+	if x != nil {
+		return x.Blocks
+	}
+	return 0
+}
+
+func (x *BlockFeeStats) GetTransactions() int32 {
+	if x != nil {
+		return x.Transactions
+	}
+	return 0
+}
+
+func (x *BlockFeeStats) GetMedianFee() float64 {
+	if x != nil {
+		return x.MedianFee
+	}
+	return 0
+}
+
+func (x *BlockFeeStats) GetMedianFeeRate() float64 {
+	if x != nil {
+		return x.MedianFeeRate
+	}
+	return 0
+}
+
+func (x *BlockFeeStats) GetMinFee() float64 {
+	if x != nil {
+		return x.MinFee
+	}
+	return 0
+}
+
+func (x *BlockFeeStats) GetMaxFee() float64 {
+	if x != nil {
+		return x.MaxFee
+	}
+	return 0
+}
+
+func (x *BlockFeeStats) GetTotalFees() float64 {
+	if x != nil {
+		return x.TotalFees
+	}
+	return 0
+}
+
+type FeeMarket struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Histogram     []*FeeHistogramBucket  `protobuf:"bytes,1,rep,name=histogram,proto3" json:"histogram,omitempty"`
+	Recent        *BlockFeeStats         `protobuf:"bytes,2,opt,name=recent,proto3" json:"recent,omitempty"`
+	Projection    *FeeEstimate           `protobuf:"bytes,3,opt,name=projection,proto3" json:"projection,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FeeMarket) Reset() {
+	*x = FeeMarket{}
+	mi := &file_node_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FeeMarket) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FeeMarket) ProtoMessage() {}
+
+func (x *FeeMarket) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FeeMarket.ProtoReflect.Descriptor instead.
+func (*FeeMarket) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *FeeMarket) GetHistogram() []*FeeHistogramBucket {
+	if x != nil {
+		return x.Histogram
+	}
+	return nil
+}
+
+func (x *FeeMarket) GetRecent() *BlockFeeStats {
+	if x != nil {
+		return x.Recent
+	}
+	return nil
+}
+
+func (x *FeeMarket) GetProjection() *FeeEstimate {
+	if x != nil {
+		return x.Projection
+	}
+	return nil
+}
+
+var File_node_proto protoreflect.FileDescriptor
+
+const file_node_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"node.proto\x12\x12blockchain.node.v1\"\xdf\x02\n" +
+	"\vTransaction\x12\x12\n" +
+	"\x04from\x18\x01 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x02 \x01(\tR\x02to\x12\x16\n" +
+	"\x06amount\x18\x03 \x01(\x01R\x06amount\x12\x10\n" +
+	"\x03fee\x18\x04 \x01(\x01R\x03fee\x12\x14\n" +
+	"\x05nonce\x18\x05 \x01(\x03R\x05nonce\x12\x12\n" +
+	"\x04hash\x18\x06 \x01(\tR\x04hash\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\a \x01(\tR\tpublicKey\x12\x1c\n" +
+	"\tsignature\x18\b \x01(\tR\tsignature\x12\x18\n" +
+	"\asponsor\x18\t \x01(\tR\asponsor\x12\x1f\n" +
+	"\vsponsor_key\x18\n" +
+	" \x01(\tR\n" +
+	"sponsorKey\x12+\n" +
+	"\x11sponsor_signature\x18\v \x01(\tR\x10sponsorSignature\x12\x1f\n" +
+	"\vvalid_until\x18\f \x01(\x03R\n" +
+	"validUntil\x12\x12\n" +
+	"\x04data\x18\r \x01(\fR\x04data\"\x82\x02\n" +
+	"\x05Block\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x05R\aversion\x12\x14\n" +
+	"\x05index\x18\x02 \x01(\x03R\x05index\x12\x1c\n" +
+	"\ttimestamp\x18\x03 \x01(\x03R\ttimestamp\x12C\n" +
+	"\ftransactions\x18\x04 \x03(\v2\x1f.blockchain.node.v1.TransactionR\ftransactions\x12\x1b\n" +
+	"\tprev_hash\x18\x05 \x01(\tR\bprevHash\x12\x12\n" +
+	"\x04hash\x18\x06 \x01(\tR\x04hash\x12\x14\n" +
+	"\x05nonce\x18\a \x01(\x03R\x05nonce\x12\x1f\n" +
+	"\vmerkle_root\x18\b \x01(\tR\n" +
+	"merkleRoot\"\xa1\x01\n" +
+	"\vMerkleProof\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\x12\x16\n" +
+	"\x06hashes\x18\x02 \x03(\tR\x06hashes\x12\x17\n" +
+	"\ais_left\x18\x03 \x03(\bR\x06isLeft\x12\x1f\n" +
+	"\vmerkle_root\x18\x04 \x01(\tR\n" +
+	"merkleRoot\x12\x14\n" +
+	"\x05index\x18\x05 \x01(\x03R\x05index\x12\x16\n" +
+	"\x06scheme\x18\x06 \x01(\x05R\x06scheme\"]\n" +
+	"\x18SubmitTransactionRequest\x12A\n" +
+	"\vtransaction\x18\x01 \x01(\v2\x1f.blockchain.node.v1.TransactionR\vtransaction\"/\n" +
+	"\x19SubmitTransactionResponse\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\"`\n" +
+	"\x19SubmitTransactionsRequest\x12C\n" +
+	"\ftransactions\x18\x01 \x03(\v2\x1f.blockchain.node.v1.TransactionR\ftransactions\"t\n" +
+	"\x1aSubmitTransactionsResponse\x12:\n" +
+	"\aresults\x18\x01 \x03(\v2 .blockchain.node.v1.SubmitResultR\aresults\x12\x1a\n" +
+	"\baccepted\x18\x02 \x01(\x05R\baccepted\"h\n" +
+	"\fSubmitResult\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\x12\x1a\n" +
+	"\baccepted\x18\x02 \x01(\bR\baccepted\x12\x12\n" +
+	"\x04code\x18\x03 \x01(\x05R\x04code\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"R\n" +
+	"\x11GetBalanceRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x12#\n" +
+	"\rfiat_currency\x18\x02 \x01(\tR\ffiatCurrency\"\xb5\x01\n" +
+	"\x12GetBalanceResponse\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x12\x18\n" +
+	"\abalance\x18\x02 \x01(\x01R\abalance\x12\x1a\n" +
+	"\breserved\x18\x03 \x01(\x01R\breserved\x12\x1c\n" +
+	"\tavailable\x18\x04 \x01(\x01R\tavailable\x121\n" +
+	"\x04fiat\x18\x05 \x01(\v2\x1d.blockchain.node.v1.FiatValueR\x04fiat\"|\n" +
+	"\tFiatValue\x12\x1a\n" +
+	"\bcurrency\x18\x01 \x01(\tR\bcurrency\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value\x12\x12\n" +
+	"\x04rate\x18\x03 \x01(\x01R\x04rate\x12\x13\n" +
+	"\x05as_of\x18\x04 \x01(\x03R\x04asOf\x12\x14\n" +
+	"\x05stale\x18\x05 \x01(\bR\x05stale\"K\n" +
+	"\x0fGetBlockRequest\x12\x16\n" +
+	"\x05index\x18\x01 \x01(\x03H\x00R\x05index\x12\x14\n" +
+	"\x04hash\x18\x02 \x01(\tH\x00R\x04hashB\n" +
+	"\n" +
+	"\bselector\"4\n" +
+	"\x13StreamBlocksRequest\x12\x1d\n" +
+	"\n" +
+	"from_index\x18\x01 \x01(\x03R\tfromIndex\"V\n" +
+	"\x1aGetTransactionProofRequest\x12\x1f\n" +
+	"\vblock_index\x18\x01 \x01(\x03R\n" +
+	"blockIndex\x12\x17\n" +
+	"\atx_hash\x18\x02 \x01(\tR\x06txHash\"2\n" +
+	"\x17GetConfirmationsRequest\x12\x17\n" +
+	"\atx_hash\x18\x01 \x01(\tR\x06txHash\"\xd4\x01\n" +
+	"\x12ConfirmationStatus\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\x12\x14\n" +
+	"\x05found\x18\x02 \x01(\bR\x05found\x12\x18\n" +
+	"\apending\x18\x03 \x01(\bR\apending\x12\x1f\n" +
+	"\vblock_index\x18\x04 \x01(\x03R\n" +
+	"blockIndex\x12\x1d\n" +
+	"\n" +
+	"block_hash\x18\x05 \x01(\tR\tblockHash\x12$\n" +
+	"\rconfirmations\x18\x06 \x01(\x03R\rconfirmations\x12\x14\n" +
+	"\x05final\x18\a \x01(\bR\x05final\"9\n" +
+	"\x12EstimateFeeRequest\x12#\n" +
+	"\rtarget_blocks\x18\x01 \x01(\x05R\ftargetBlocks\"\xe2\x01\n" +
+	"\vFeeEstimate\x12#\n" +
+	"\rtarget_blocks\x18\x01 \x01(\x05R\ftargetBlocks\x12\x19\n" +
+	"\bfee_rate\x18\x02 \x01(\x01R\afeeRate\x12\x17\n" +
+	"\amin_fee\x18\x03 \x01(\x01R\x06minFee\x12 \n" +
+	"\fmin_fee_rate\x18\x04 \x01(\x01R\n" +
+	"minFeeRate\x12\x18\n" +
+	"\asamples\x18\x05 \x01(\x05R\asamples\x12\x1a\n" +
+	"\bfallback\x18\x06 \x01(\bR\bfallback\x12\"\n" +
+	"\rdata_byte_fee\x18\a \x01(\x01R\vdataByteFee\"R\n" +
+	"\x13GetFeeMarketRequest\x12\x16\n" +
+	"\x06blocks\x18\x01 \x01(\x05R\x06blocks\x12#\n" +
+	"\rtarget_blocks\x18\x02 \x01(\x05R\ftargetBlocks\"\x9f\x01\n" +
+	"\x12FeeHistogramBucket\x12 \n" +
+	"\fmin_fee_rate\x18\x01 \x01(\x01R\n" +
+	"minFeeRate\x12 \n" +
+	"\fmax_fee_rate\x18\x02 \x01(\x01R\n" +
+	"maxFeeRate\x12\x14\n" +
+	"\x05count\x18\x03 \x01(\x05R\x05count\x12\x12\n" +
+	"\x04size\x18\x04 \x01(\x03R\x04size\x12\x1b\n" +
+	"\ttotal_fee\x18\x05 \x01(\x01R\btotalFee\"\xe3\x01\n" +
+	"\rBlockFeeStats\x12\x16\n" +
+	"\x06blocks\x18\x01 \x01(\x05R\x06blocks\x12\"\n" +
+	"\ftransactions\x18\x02 \x01(\x05R\ftransactions\x12\x1d\n" +
+	"\n" +
+	"median_fee\x18\x03 \x01(\x01R\tmedianFee\x12&\n" +
+	"\x0fmedian_fee_rate\x18\x04 \x01(\x01R\rmedianFeeRate\x12\x17\n" +
+	"\amin_fee\x18\x05 \x01(\x01R\x06minFee\x12\x17\n" +
+	"\amax_fee\x18\x06 \x01(\x01R\x06maxFee\x12\x1d\n" +
+	"\n" +
+	"total_fees\x18\a \x01(\x01R\ttotalFees\"\xcd\x01\n" +
+	"\tFeeMarket\x12D\n" +
+	"\thistogram\x18\x01 \x03(\v2&.blockchain.node.v1.FeeHistogramBucketR\thistogram\x129\n" +
+	"\x06recent\x18\x02 \x01(\v2!.blockchain.node.v1.BlockFeeStatsR\x06recent\x12?\n" +
+	"\n" +
+	"projection\x18\x03 \x01(\v2\x1f.blockchain.node.v1.FeeEstimateR\n" +
+	"projection2\xed\x06\n" +
+	"\x04Node\x12p\n" +
+	"\x11SubmitTransaction\x12,.blockchain.node.v1.SubmitTransactionRequest\x1a-.blockchain.node.v1.SubmitTransactionResponse\x12s\n" +
+	"\x12SubmitTransactions\x12-.blockchain.node.v1.SubmitTransactionsRequest\x1a..blockchain.node.v1.SubmitTransactionsResponse\x12[\n" +
+	"\n" +
+	"GetBalance\x12%.blockchain.node.v1.GetBalanceRequest\x1a&.blockchain.node.v1.GetBalanceResponse\x12J\n" +
+	"\bGetBlock\x12#.blockchain.node.v1.GetBlockRequest\x1a\x19.blockchain.node.v1.Block\x12T\n" +
+	"\fStreamBlocks\x12'.blockchain.node.v1.StreamBlocksRequest\x1a\x19.blockchain.node.v1.Block0\x01\x12f\n" +
+	"\x13GetTransactionProof\x12..blockchain.node.v1.GetTransactionProofRequest\x1a\x1f.blockchain.node.v1.MerkleProof\x12g\n" +
+	"\x10GetConfirmations\x12+.blockchain.node.v1.GetConfirmationsRequest\x1a&.blockchain.node.v1.ConfirmationStatus\x12V\n" +
+	"\vEstimateFee\x12&.blockchain.node.v1.EstimateFeeRequest\x1a\x1f.blockchain.node.v1.FeeEstimate\x12V\n" +
+	"\fGetFeeMarket\x12'.blockchain.node.v1.GetFeeMarketRequest\x1a\x1d.blockchain.node.v1.FeeMarketB\x13Z\x11blockchain/nodepbb\x06proto3"
+
+var (
+	file_node_proto_rawDescOnce sync.Once
+	file_node_proto_rawDescData []byte
+)
+
+func file_node_proto_rawDescGZIP() []byte {
+	file_node_proto_rawDescOnce.Do(func() {
+		file_node_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_node_proto_rawDesc), len(file_node_proto_rawDesc)))
+	})
+	return file_node_proto_rawDescData
+}
+
+var file_node_proto_msgTypes = make([]protoimpl.MessageInfo, 22)
+var file_node_proto_goTypes = []any{
+	(*Transaction)(nil),                // 0: blockchain.node.v1.Transaction
+	(*Block)(nil),                      // 1: blockchain.node.v1.Block
+	(*MerkleProof)(nil),                // 2: blockchain.node.v1.MerkleProof
+	(*SubmitTransactionRequest)(nil),   // 3: blockchain.node.v1.SubmitTransactionRequest
+	(*SubmitTransactionResponse)(nil),  // 4: blockchain.node.v1.SubmitTransactionResponse
+	(*SubmitTransactionsRequest)(nil),  // 5: blockchain.node.v1.SubmitTransactionsRequest
+	(*SubmitTransactionsResponse)(nil), // 6: blockchain.node.v1.SubmitTransactionsResponse
+	(*SubmitResult)(nil),               // 7: blockchain.node.v1.SubmitResult
+	(*GetBalanceRequest)(nil),          // 8: blockchain.node.v1.GetBalanceRequest
+	(*GetBalanceResponse)(nil),         // 9: blockchain.node.v1.GetBalanceResponse
+	(*FiatValue)(nil),                  // 10: blockchain.node.v1.FiatValue
+	(*GetBlockRequest)(nil),            // 11: blockchain.node.v1.GetBlockRequest
+	(*StreamBlocksRequest)(nil),        // 12: blockchain.node.v1.StreamBlocksRequest
+	(*GetTransactionProofRequest)(nil), // 13: blockchain.node.v1.GetTransactionProofRequest
+	(*GetConfirmationsRequest)(nil),    // 14: blockchain.node.v1.GetConfirmationsRequest
+	(*ConfirmationStatus)(nil),         // 15: blockchain.node.v1.ConfirmationStatus
+	(*EstimateFeeRequest)(nil),         // 16: blockchain.node.v1.EstimateFeeRequest
+	(*FeeEstimate)(nil),                // 17: blockchain.node.v1.FeeEstimate
+	(*GetFeeMarketRequest)(nil),        // 18: blockchain.node.v1.GetFeeMarketRequest
+	(*FeeHistogramBucket)(nil),         // 19: blockchain.node.v1.FeeHistogramBucket
+	(*BlockFeeStats)(nil),              // 20: blockchain.node.v1.BlockFeeStats
+	(*FeeMarket)(nil),                  // 21: blockchain.node.v1.FeeMarket
+}
+var file_node_proto_depIdxs = []int32{
+	0,  // 0: blockchain.node.v1.Block.transactions:type_name -> blockchain.node.v1.Transaction
+	0,  // 1: blockchain.node.v1.SubmitTransactionRequest.transaction:type_name -> blockchain.node.v1.Transaction
+	0,  // 2: blockchain.node.v1.SubmitTransactionsRequest.transactions:type_name -> blockchain.node.v1.Transaction
+	7,  // 3: blockchain.node.v1.SubmitTransactionsResponse.results:type_name -> blockchain.node.v1.SubmitResult
+	10, // 4: blockchain.node.v1.GetBalanceResponse.fiat:type_name -> blockchain.node.v1.FiatValue
+	19, // 5: blockchain.node.v1.FeeMarket.histogram:type_name -> blockchain.node.v1.FeeHistogramBucket
+	20, // 6: blockchain.node.v1.FeeMarket.recent:type_name -> blockchain.node.v1.BlockFeeStats
+	17, // 7: blockchain.node.v1.FeeMarket.projection:type_name -> blockchain.node.v1.FeeEstimate
+	3,  // 8: blockchain.node.v1.Node.SubmitTransaction:input_type -> blockchain.node.v1.SubmitTransactionRequest
+	5,  // 9: blockchain.node.v1.Node.SubmitTransactions:input_type -> blockchain.node.v1.SubmitTransactionsRequest
+	8,  // 10: blockchain.node.v1.Node.GetBalance:input_type -> blockchain.node.v1.GetBalanceRequest
+	11, // 11: blockchain.node.v1.Node.GetBlock:input_type -> blockchain.node.v1.GetBlockRequest
+	12, // 12: blockchain.node.v1.Node.StreamBlocks:input_type -> blockchain.node.v1.StreamBlocksRequest
+	13, // 13: blockchain.node.v1.Node.GetTransactionProof:input_type -> blockchain.node.v1.GetTransactionProofRequest
+	14, // 14: blockchain.node.v1.Node.GetConfirmations:input_type -> blockchain.node.v1.GetConfirmationsRequest
+	16, // 15: blockchain.node.v1.Node.EstimateFee:input_type -> blockchain.node.v1.EstimateFeeRequest
+	18, // 16: blockchain.node.v1.Node.GetFeeMarket:input_type -> blockchain.node.v1.GetFeeMarketRequest
+	4,  // 17: blockchain.node.v1.Node.SubmitTransaction:output_type -> blockchain.node.v1.SubmitTransactionResponse
+	6,  // 18: blockchain.node.v1.Node.SubmitTransactions:output_type -> blockchain.node.v1.SubmitTransactionsResponse
+	9,  // 19: blockchain.node.v1.Node.GetBalance:output_type -> blockchain.node.v1.GetBalanceResponse
+	1,  // 20: blockchain.node.v1.Node.GetBlock:output_type -> blockchain.node.v1.Block
+	1,  // 21: blockchain.node.v1.Node.StreamBlocks:output_type -> blockchain.node.v1.Block
+	2,  // 22: blockchain.node.v1.Node.GetTransactionProof:output_type -> blockchain.node.v1.MerkleProof
+	15, // 23: blockchain.node.v1.Node.GetConfirmations:output_type -> blockchain.node.v1.ConfirmationStatus
+	17, // 24: blockchain.node.v1.Node.EstimateFee:output_type -> blockchain.node.v1.FeeEstimate
+	21, // 25: blockchain.node.v1.Node.GetFeeMarket:output_type -> blockchain.node.v1.FeeMarket
+	17, // [17:26] is the sub-list for method output_type
+	8,  // [8:17] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_node_proto_init() }
+func file_node_proto_init() {
+	if File_node_proto != nil {
+		return
+	}
+	file_node_proto_msgTypes[11].OneofWrappers = []any{
+		(*GetBlockRequest_Index)(nil),
+		(*GetBlockRequest_Hash)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_node_proto_rawDesc), len(file_node_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   22,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_node_proto_goTypes,
+		DependencyIndexes: file_node_proto_depIdxs,
+		MessageInfos:      file_node_proto_msgTypes,
+	}.Build()
+	File_node_proto = out.File
+	file_node_proto_goTypes = nil
+	file_node_proto_depIdxs = nil
+}